@@ -8,11 +8,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
-	"log"
 	"strings"
 
 	"github.com/ponzu-cms/ponzu/management/editor"
 	"github.com/ponzu-cms/ponzu/system/addon"
+	"github.com/ponzu-cms/ponzu/system/logger"
 )
 
 // Select returns the []byte of a <select> HTML element plus internal <options> with a label.
@@ -22,7 +22,7 @@ import (
 func Select(fieldName string, p interface{}, attrs map[string]string, contentType, tmplString string) []byte {
 	options, err := encodeDataToOptions(contentType, tmplString)
 	if err != nil {
-		log.Println("Error encoding data to options for", contentType, err)
+		logger.Error("Error encoding data to options for", contentType, err)
 		return nil
 	}
 
@@ -40,7 +40,7 @@ func SelectRepeater(fieldName string, p interface{}, attrs map[string]string, co
 	html := bytes.Buffer{}
 	_, err := html.WriteString(`<span class="__ponzu-repeat ` + scope + `">`)
 	if err != nil {
-		log.Println("Error writing HTML string to SelectRepeater buffer")
+		logger.Error("Error writing HTML string to SelectRepeater buffer")
 		return nil
 	}
 
@@ -56,7 +56,7 @@ func SelectRepeater(fieldName string, p interface{}, attrs map[string]string, co
 
 	options, err := encodeDataToOptions(contentType, tmplString)
 	if err != nil {
-		log.Println("Error encoding data to options for", contentType, err)
+		logger.Error("Error encoding data to options for", contentType, err)
 		return nil
 	}
 
@@ -99,14 +99,14 @@ func SelectRepeater(fieldName string, p interface{}, attrs map[string]string, co
 
 		_, err := html.Write(editor.DOMElementWithChildrenSelect(sel, opts))
 		if err != nil {
-			log.Println("Error writing DOMElementWithChildrenSelect to SelectRepeater buffer")
+			logger.Error("Error writing DOMElementWithChildrenSelect to SelectRepeater buffer")
 			return nil
 		}
 	}
 
 	_, err = html.WriteString("</span>")
 	if err != nil {
-		log.Println("Error writing HTML string to SelectRepeater buffer")
+		logger.Error("Error writing HTML string to SelectRepeater buffer")
 		return nil
 	}
 
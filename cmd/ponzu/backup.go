@@ -0,0 +1,300 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/admin/upload"
+
+	"github.com/boltdb/bolt"
+)
+
+// backupDBs are the bolt dbs of a project which are backed up and restored,
+// with the source the admin backup endpoint serves each of them as. Uploads
+// metadata is stored in system.db, and the search index is rebuilt from the
+// content in it rather than backed up.
+var backupDBs = []struct {
+	file   string
+	source string
+}{
+	{"system.db", "system"},
+	{"analytics.db", "analytics"},
+}
+
+// uploadsBackup is the name of the archive of the uploads directory in a
+// backup
+const uploadsBackup = "uploads.tar.gz"
+
+// beforeRestore is added to the names of the dbs and uploads directory which
+// are moved aside when a backup is restored over them
+const beforeRestore = ".before-restore"
+
+// lockTimeout is how long to wait for the lock on a db before deciding a
+// server has it open
+const lockTimeout = time.Second
+
+// errServerRunning is returned when restoring while a server has the dbs open
+var errServerRunning = errors.New("A Ponzu server is running in this project. Stop it before restoring a backup.")
+
+// backupProject writes a snapshot of each db and an archive of the uploads to
+// dir. A db open by a running server is read through the server's backup
+// endpoint, so that the snapshot is consistent while it keeps serving.
+func backupProject(dir string) error {
+	err := os.MkdirAll(dir, os.ModeDir|os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range backupDBs {
+		if _, err := os.Stat(b.file); os.IsNotExist(err) {
+			fmt.Println("Skipping", b.file+", which doesn't exist yet.")
+			continue
+		}
+
+		err := writeAtomic(filepath.Join(dir, b.file), func(w io.Writer) error {
+			return snapshotDB(b.file, b.source, w)
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to back up %s: %v", b.file, err)
+		}
+
+		fmt.Println("Backed up", b.file)
+	}
+
+	err = writeAtomic(filepath.Join(dir, uploadsBackup), upload.Archive)
+	if err != nil {
+		return fmt.Errorf("Failed to back up uploads: %v", err)
+	}
+
+	fmt.Println("Backed up uploads")
+
+	return nil
+}
+
+// snapshotDB writes a consistent copy of the bolt db at path to w, from a
+// single read transaction, or fetches one from the running server if it holds
+// the lock on the db
+func snapshotDB(path, source string, w io.Writer) error {
+	store, err := bolt.Open(path, 0666, &bolt.Options{Timeout: lockTimeout, ReadOnly: true})
+	if err == bolt.ErrTimeout {
+		return fetchBackup(source, w)
+	}
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return store.View(func(tx *bolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+}
+
+// fetchBackup writes the backup of source served by the running server to w
+func fetchBackup(source string, w io.Writer) error {
+	res, err := requestServer(http.MethodGet, "/admin/backup?source="+source)
+	if err != nil {
+		return fmt.Errorf("it is open by a running server, %v", err)
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
+
+// requestServer makes a request to the admin endpoint at path of the server
+// running on the HTTP port, using the backup credentials configured in the
+// admin, which are read from the environment. A response with a status other
+// than 200 is returned as an error.
+func requestServer(method, path string) (*http.Response, error) {
+	user := os.Getenv("PONZU_BACKUP_USER")
+	password := os.Getenv("PONZU_BACKUP_PASSWORD")
+	if user == "" || password == "" {
+		return nil, errors.New("set PONZU_BACKUP_USER and PONZU_BACKUP_PASSWORD to the backup credentials configured in the admin to make the request through the server.")
+	}
+
+	endpoint := fmt.Sprintf("http://localhost:%d%s", port, path)
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(user, password)
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("the server responded with %s", res.Status)
+	}
+
+	return res, nil
+}
+
+// writeAtomic writes the file at path with write, through a temporary file
+// which replaces path once complete so that a failed write leaves no partial
+// file behind
+func writeAtomic(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	err = write(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// restoreProject replaces the dbs and uploads of the project with the backup
+// in dir, moving those there now aside, then rebuilds the search index from
+// the restored content with the project's server
+func restoreProject(dir string) error {
+	if _, err := os.Stat(serverPath()); err != nil {
+		return errors.New("The project must be built with 'ponzu build' before restoring, so its search index can be rebuilt.")
+	}
+
+	// check everything can be restored before changing anything
+	for _, b := range backupDBs {
+		err := checkUnlocked(b.file)
+		if err != nil {
+			return err
+		}
+	}
+
+	var files []string
+	for _, b := range backupDBs {
+		src := filepath.Join(dir, b.file)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+
+		err := checkDB(src)
+		if err != nil {
+			return fmt.Errorf("%s is not a valid backup: %v", src, err)
+		}
+
+		files = append(files, b.file)
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("No backup found in %s", dir)
+	}
+
+	for _, file := range files {
+		err := moveAside(file)
+		if err != nil {
+			return err
+		}
+
+		err = writeAtomic(file, func(w io.Writer) error {
+			src, err := os.Open(filepath.Join(dir, file))
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+
+			_, err = io.Copy(w, src)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to restore %s: %v", file, err)
+		}
+
+		fmt.Println("Restored", file)
+	}
+
+	archive, err := os.Open(filepath.Join(dir, uploadsBackup))
+	if err == nil {
+		defer archive.Close()
+
+		err = moveAside("uploads")
+		if err != nil {
+			return err
+		}
+
+		err = upload.Restore(archive)
+		if err != nil {
+			return fmt.Errorf("Failed to restore uploads: %v", err)
+		}
+
+		fmt.Println("Restored uploads")
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	// the index is rebuilt from scratch so nothing removed since the backup
+	// is left in it
+	err = os.Remove("search.db")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return indexSearch(nil)
+}
+
+// checkUnlocked returns errServerRunning if the bolt db at path is open by
+// another process
+func checkUnlocked(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	store, err := bolt.Open(path, 0666, &bolt.Options{Timeout: lockTimeout})
+	if err == bolt.ErrTimeout {
+		return errServerRunning
+	}
+	if err != nil {
+		return err
+	}
+
+	return store.Close()
+}
+
+// checkDB returns an error if the file at path is not a readable bolt db
+func checkDB(path string) error {
+	store, err := bolt.Open(path, 0666, &bolt.Options{Timeout: lockTimeout, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	return store.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			return nil
+		})
+	})
+}
+
+// moveAside renames path by adding beforeRestore, replacing what was moved
+// aside by an earlier restore, so it can be recovered if needed
+func moveAside(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	aside := path + beforeRestore
+	err := os.RemoveAll(aside)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path, aside)
+}
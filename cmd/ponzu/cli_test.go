@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"os"
+	"testing"
+)
 
 func TestParseType(t *testing.T) {
 	// blog title:string Author:string PostCategory:string content:string some_thing:int
@@ -63,3 +66,24 @@ func TestFieldName(t *testing.T) {
 		}
 	}
 }
+
+func TestEnvDefaults(t *testing.T) {
+	defer os.Unsetenv("PONZU_TEST_PORT")
+	defer os.Unsetenv("PONZU_TEST_HTTPS")
+
+	if envInt("PONZU_TEST_PORT", 8080) != 8080 || envBool("PONZU_TEST_HTTPS", false) {
+		t.Error("Expected the defaults without the environment")
+	}
+
+	os.Setenv("PONZU_TEST_PORT", "9090")
+	os.Setenv("PONZU_TEST_HTTPS", "true")
+	if envInt("PONZU_TEST_PORT", 8080) != 9090 || !envBool("PONZU_TEST_HTTPS", false) {
+		t.Error("Expected the values of the environment")
+	}
+
+	os.Setenv("PONZU_TEST_PORT", "http")
+	os.Setenv("PONZU_TEST_HTTPS", "maybe")
+	if envInt("PONZU_TEST_PORT", 8080) != 8080 || envBool("PONZU_TEST_HTTPS", false) {
+		t.Error("Expected the defaults for values which can't be read")
+	}
+}
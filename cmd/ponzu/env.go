@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+// The defaults of the flags below are read from the environment, so that a
+// container can be configured without changing its command. Flags given on
+// the command line take precedence over the environment.
+const (
+	envHTTPPort   = "PONZU_HTTP_PORT"
+	envHTTPSPort  = "PONZU_HTTPS_PORT"
+	envHealthPort = "PONZU_HEALTH_PORT"
+	envHTTPS      = "PONZU_HTTPS"
+	envLogLevel   = "PONZU_LOG_LEVEL"
+	envLogJSON    = "PONZU_LOG_JSON"
+)
+
+// envString returns the value of the environment variable name, or def if it
+// is not set
+func envString(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+
+	return def
+}
+
+// envInt returns the value of the environment variable name as an int, or def
+// if it is not set or not a whole number
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring %s, which is not a whole number: %q\n", name, v)
+		return def
+	}
+
+	return n
+}
+
+// envBool returns the value of the environment variable name as a bool, or
+// def if it is not set or not true or false
+func envBool(name string, def bool) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring %s, which is not true or false: %q\n", name, v)
+		return def
+	}
+
+	return b
+}
+
+// listenAddr returns the address to listen on for port, on the host of
+// bind_addr, or every interface if it is not set
+func listenAddr(port int) string {
+	host, _ := db.ConfigCache("bind_addr").(string)
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
@@ -1,32 +1,44 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/ponzu-cms/ponzu/system/admin"
 	"github.com/ponzu-cms/ponzu/system/api"
 	"github.com/ponzu-cms/ponzu/system/api/analytics"
+	"github.com/ponzu-cms/ponzu/system/compress"
 	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/health"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/maintenance"
+	"github.com/ponzu-cms/ponzu/system/redirect"
+	"github.com/ponzu-cms/ponzu/system/requestid"
+	"github.com/ponzu-cms/ponzu/system/search"
 	"github.com/ponzu-cms/ponzu/system/tls"
+	"github.com/ponzu-cms/ponzu/system/trace"
 
 	_ "github.com/ponzu-cms/ponzu/content"
 )
 
 var (
 	usage = usageHeader + usageNew + usageGenerate +
-		usageBuild + usageRun + usageUpgrade + usageVersion
-	port      int
-	httpsport int
-	https     bool
-	devhttps  bool
-	cli       bool
+		usageBuild + usageRun + usageBackup + usageRestore + usageReindex +
+		usageMigrate + usageUpgrade + usageVersion
+	port       int
+	httpsport  int
+	healthport int
+	https      bool
+	devhttps   bool
+	cli        bool
+	logLevel   string
+	logJSON    bool
+	dryRun     bool
 
 	// for ponzu internal / core development
 	dev   bool
@@ -39,16 +51,32 @@ func main() {
 		fmt.Println(usage)
 	}
 
-	flag.IntVar(&port, "port", 8080, "port for ponzu to bind its HTTP listener")
-	flag.IntVar(&httpsport, "httpsport", 443, "port for ponzu to bind its HTTPS listener")
-	flag.BoolVar(&https, "https", false, "enable automatic TLS/SSL certificate management")
+	flag.IntVar(&port, "port", envInt(envHTTPPort, 8080), "port for ponzu to bind its HTTP listener")
+	flag.IntVar(&httpsport, "httpsport", envInt(envHTTPSPort, 443), "port for ponzu to bind its HTTPS listener")
+	flag.IntVar(&healthport, "healthport", envInt(envHealthPort, 0), "port to serve /healthz and /readyz on, instead of the HTTP port")
+	flag.BoolVar(&https, "https", envBool(envHTTPS, false), "enable automatic TLS/SSL certificate management")
 	flag.BoolVar(&devhttps, "devhttps", false, "[dev environment] enable automatic TLS/SSL certificate management")
 	flag.BoolVar(&dev, "dev", false, "modify environment for Ponzu core development")
 	flag.BoolVar(&cli, "cli", false, "specify that information should be returned about the CLI, not project")
 	flag.StringVar(&fork, "fork", "", "modify repo source for Ponzu core development")
 	flag.StringVar(&gocmd, "gocmd", "go", "custom go command if using beta or new release of Go")
+	flag.StringVar(&logLevel, "log-level", envString(envLogLevel, "info"), "least important level of log messages to write: debug, info, warn or error")
+	flag.BoolVar(&logJSON, "log-json", envBool(envLogJSON, false), "write log messages as lines of JSON")
+	flag.BoolVar(&dryRun, "dry-run", false, "report what 'ponzu migrate' would change without saving it")
 	flag.Parse()
 
+	level, err := logger.ParseLevel(logLevel)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if logJSON {
+		logger.Set(logger.NewJSON(os.Stderr, level))
+	} else {
+		logger.Set(logger.New(level))
+	}
+
 	args := flag.Args()
 
 	if len(args) < 1 {
@@ -81,6 +109,22 @@ func main() {
 			fmt.Println(usageRun)
 			os.Exit(0)
 
+		case "backup":
+			fmt.Println(usageBackup)
+			os.Exit(0)
+
+		case "restore":
+			fmt.Println(usageRestore)
+			os.Exit(0)
+
+		case "reindex":
+			fmt.Println(usageReindex)
+			os.Exit(0)
+
+		case "migrate":
+			fmt.Println(usageMigrate)
+			os.Exit(0)
+
 		case "upgrade":
 			fmt.Println(usageUpgrade)
 			os.Exit(0)
@@ -147,11 +191,12 @@ func main() {
 			services = "admin,api"
 		}
 
-		name := buildOutputName()
-		buildPathName := strings.Join([]string{".", name}, string(filepath.Separator))
-		serve := exec.Command(buildPathName,
+		serve := exec.Command(serverPath(),
 			fmt.Sprintf("--port=%d", port),
 			fmt.Sprintf("--httpsport=%d", httpsport),
+			fmt.Sprintf("--healthport=%d", healthport),
+			fmt.Sprintf("--log-level=%s", logLevel),
+			fmt.Sprintf("--log-json=%t", logJSON),
 			addTLS,
 			"serve",
 			services,
@@ -159,7 +204,17 @@ func main() {
 		serve.Stderr = os.Stderr
 		serve.Stdout = os.Stdout
 
-		err := serve.Run()
+		err := serve.Start()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		// pass signals on, so the server can shut down gracefully even when
+		// only this process is signaled
+		forwardSignals(serve.Process)
+
+		err = serve.Wait()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -169,7 +224,40 @@ func main() {
 		db.Init()
 		defer db.Close()
 
-		analytics.Init()
+		search.Init()
+		defer search.Close()
+
+		// tracing is optional, so Ponzu serves requests without it if the
+		// exporter can't be set up
+		err := trace.Init()
+		if err != nil {
+			logger.Error("Error initializing tracing, requests won't be traced:", err)
+		}
+		defer trace.Close()
+
+		// IPs making more API requests than the abuse threshold are given a
+		// tighter rate limit
+		abuse, _ := db.ConfigCache("abuse_threshold").(float64)
+
+		// busy sites can record only a sample of API requests
+		sample, _ := db.ConfigCache("analytics_sample_every").(float64)
+		externalSample, _ := db.ConfigCache("analytics_external_sample_every").(float64)
+
+		var bots []string
+		patterns, _ := db.ConfigCache("analytics_bot_patterns").([]interface{})
+		for _, p := range patterns {
+			if s, ok := p.(string); ok {
+				bots = append(bots, s)
+			}
+		}
+
+		analytics.Init(analytics.Options{
+			AbuseThreshold:      int(abuse),
+			OnAbuse:             api.FlagIP,
+			SampleEvery:         int(sample),
+			ExternalSampleEvery: int(externalSample),
+			BotPatterns:         bots,
+		})
 		defer analytics.Close()
 
 		if len(args) > 1 {
@@ -188,17 +276,54 @@ func main() {
 			}
 		}
 
+		// compare the search index with the content stored, in the background
+		// since rebuilding it is safe while serving
+		go db.CheckSearchIndex()
+
+		health.Register("db", db.Ping)
+		health.Register("search", search.Ping)
+		health.Register("analytics", analytics.Ping)
+
+		// probes can be kept off the public port, so they aren't redirected,
+		// compressed or reachable from outside
+		var healthServer *http.Server
+		if healthport > 0 {
+			mux := http.NewServeMux()
+			health.Handle(mux)
+
+			healthServer = &http.Server{Addr: listenAddr(healthport), Handler: mux}
+			go func() {
+				err := healthServer.ListenAndServe()
+				if err != nil && err != http.ErrServerClosed {
+					logger.Fatal(err)
+				}
+			}()
+
+			fmt.Printf("Server listening on %s for health checks...\n", listenAddr(healthport))
+		} else {
+			health.Handle(http.DefaultServeMux)
+		}
+
+		// each request is given an ID to tie its logs together and traced if
+		// tracing is enabled, public requests are turned away in maintenance
+		// mode, requests in the redirect table are redirected before routing,
+		// and responses are compressed for clients which accept it
+		handler := requestid.Handler(trace.Handler(maintenance.Handler(redirect.Handler(compress.Handler(http.DefaultServeMux)))))
+
 		// save the https port the system is listening on
-		err := db.PutConfig("https_port", fmt.Sprintf("%d", httpsport))
+		err = db.PutConfig("https_port", fmt.Sprintf("%d", httpsport))
 		if err != nil {
-			log.Fatalln("System failed to save config. Please try to run again.", err)
+			logger.Fatal("System failed to save config. Please try to run again.", err)
 		}
 
+		// servers are shut down gracefully before the dbs are closed
+		var servers []*http.Server
+
 		// cannot run production HTTPS and development HTTPS together
 		if devhttps {
 			fmt.Println("Enabling self-signed HTTPS... [DEV]")
 
-			go tls.EnableDev()
+			servers = append(servers, tls.EnableDev(handler))
 			fmt.Println("Server listening on https://localhost:10443 for requests... [DEV]")
 			fmt.Println("----")
 			fmt.Println("If your browser rejects HTTPS requests, try allowing insecure connections on localhost.")
@@ -207,20 +332,110 @@ func main() {
 		} else if https {
 			fmt.Println("Enabling HTTPS...")
 
-			go tls.Enable()
-			fmt.Printf("Server listening on :%s for HTTPS requests...\n", db.ConfigCache("https_port").(string))
+			servers = append(servers, tls.Enable(handler))
+			fmt.Printf("Server listening on %s for HTTPS requests...\n", listenAddr(httpsport))
 		}
 
 		// save the https port the system is listening on so internal system can make
 		// HTTP api calls while in dev or production w/o adding more cli flags
 		err = db.PutConfig("http_port", fmt.Sprintf("%d", port))
 		if err != nil {
-			log.Fatalln("System failed to save config. Please try to run again.", err)
+			logger.Fatal("System failed to save config. Please try to run again.", err)
+		}
+
+		// the certificate authority checks the domain over HTTP
+		if https && !devhttps {
+			handler = tls.HTTPHandler(handler)
 		}
 
-		fmt.Printf("Server listening on :%d for HTTP requests...\n", port)
+		server := &http.Server{Addr: listenAddr(port), Handler: handler}
+		servers = append(servers, server)
+		go func() {
+			err := server.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				logger.Fatal(err)
+			}
+		}()
+
+		fmt.Printf("Server listening on %s for HTTP requests...\n", listenAddr(port))
 		fmt.Println("\nvisit `/admin` to get started.")
-		log.Fatalln(http.ListenAndServe(fmt.Sprintf(":%d", port), nil))
+
+		// block until stopped, then let the deferred closes run
+		waitForShutdown(servers, healthServer)
+
+	case "backup":
+		if len(args) < 2 {
+			fmt.Println(usageBackup)
+			os.Exit(0)
+		}
+
+		err := backupProject(args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+	case "restore":
+		if len(args) < 2 {
+			fmt.Println(usageRestore)
+			os.Exit(0)
+		}
+
+		err := restoreProject(args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+	case "reindex":
+		err := reindexProject(args[1:])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+	case "index":
+		// rebuilds the search index in the project's server, which has its
+		// content types registered
+		db.Init()
+		defer db.Close()
+
+		search.Init()
+		defer search.Close()
+
+		err := db.ReindexSearch(args[1:]...)
+		if err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
+
+	case "migrate":
+		err := migrateProject(args[1:], dryRun)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+	case "migrations":
+		// migrates content in the project's server, which has its content
+		// types registered, writing the reports to stdout as json
+		db.Init()
+		defer db.Close()
+
+		search.Init()
+		defer search.Close()
+
+		reports, err := db.MigrateContent(dryRun, args[1:]...)
+		if err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
+
+		err = json.NewEncoder(os.Stdout).Encode(reports)
+		if err != nil {
+			logger.Error(err)
+			os.Exit(1)
+		}
 
 	case "version", "v":
 		// read ponzu.json value to Stdout
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+// migrateProject applies the migrations of the content types, or of every type
+// with migrations if none are given, to the content saved before them, and
+// prints what changed. With dryRun nothing is saved. A running server migrates
+// the content through its migrate endpoint, otherwise the project's server is
+// run to migrate it.
+func migrateProject(types []string, dryRun bool) error {
+	err := checkUnlocked("system.db")
+	if err != errServerRunning {
+		if err != nil {
+			return err
+		}
+
+		return runMigrations(types, dryRun)
+	}
+
+	q := url.Values{"type": types}
+	if dryRun {
+		q.Set("dry_run", "true")
+	}
+
+	res, err := requestServer(http.MethodPost, "/admin/migrate?"+q.Encode())
+	if err != nil {
+		return fmt.Errorf("Failed to migrate content through the running server: %v", err)
+	}
+	defer res.Body.Close()
+
+	return printMigrations(res.Body, dryRun)
+}
+
+// runMigrations runs the project's server to migrate the content of the types,
+// since only it has the project's content types and their migrations
+func runMigrations(types []string, dryRun bool) error {
+	if _, err := os.Stat(serverPath()); err != nil {
+		return errors.New("The project must be built with 'ponzu build' before its content can be migrated.")
+	}
+
+	args := []string{
+		fmt.Sprintf("--log-level=%s", logLevel),
+		fmt.Sprintf("--log-json=%t", logJSON),
+		fmt.Sprintf("--dry-run=%t", dryRun),
+		"migrations",
+	}
+
+	out := &bytes.Buffer{}
+	migrate := exec.Command(serverPath(), append(args, types...)...)
+	migrate.Stderr = os.Stderr
+	migrate.Stdout = out
+
+	err := migrate.Run()
+	if err != nil {
+		return fmt.Errorf("Failed to migrate content: %v", err)
+	}
+
+	return printMigrations(out, dryRun)
+}
+
+// printMigrations prints the json migration reports read from r
+func printMigrations(r io.Reader, dryRun bool) error {
+	var reports []db.MigrationReport
+	err := json.NewDecoder(r).Decode(&reports)
+	if err != nil {
+		return fmt.Errorf("Failed to read the migration report: %v", err)
+	}
+
+	if len(reports) == 0 {
+		fmt.Println("No content types have migrations.")
+		return nil
+	}
+
+	verb := "Migrated"
+	if dryRun {
+		verb = "Would migrate"
+	}
+
+	for _, r := range reports {
+		fmt.Printf("%s %d %s items to version %d\n", verb, r.Items, r.Type, r.Version)
+		for i, n := range r.Changed {
+			fmt.Printf("\tmigration %d: %d items\n", i+1, n)
+		}
+	}
+
+	return nil
+}
@@ -305,7 +305,8 @@ func buildPonzuServer(args []string) error {
 	buildOptions := []string{"build", "-o", buildOutputName()}
 	cmdBuildFiles := []string{
 		"main.go", "options.go", "generate.go",
-		"usage.go", "paths.go",
+		"usage.go", "paths.go", "shutdown.go",
+		"backup.go", "reindex.go", "migrate.go",
 	}
 	var cmdBuildFilePaths []string
 	for _, file := range cmdBuildFiles {
@@ -1,6 +1,10 @@
 package main
 
-import "runtime"
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+)
 
 // buildOutputName returns the correct ponzu-server file name
 // based on the host Operating System
@@ -11,3 +15,8 @@ func buildOutputName() string {
 
 	return "ponzu-server"
 }
+
+// serverPath is the path of the project's server, built by 'ponzu build'
+func serverPath() string {
+	return strings.Join([]string{".", buildOutputName()}, string(filepath.Separator))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+)
+
+// reindexProject rebuilds the search index of the content types, or of every
+// searchable type if none are given. A running server rebuilds it through its
+// reindex endpoint while it keeps serving, otherwise the project's server is
+// run to rebuild it.
+func reindexProject(types []string) error {
+	err := checkUnlocked("system.db")
+	if err != errServerRunning {
+		if err != nil {
+			return err
+		}
+
+		return indexSearch(types)
+	}
+
+	if len(types) == 0 {
+		types = []string{""}
+	}
+
+	for _, t := range types {
+		path := "/admin/reindex"
+		if t != "" {
+			path += "?type=" + url.QueryEscape(t)
+		}
+
+		res, err := requestServer(http.MethodPost, path)
+		if err != nil {
+			return fmt.Errorf("Failed to rebuild the search index through the running server: %v", err)
+		}
+		res.Body.Close()
+	}
+
+	fmt.Println("Rebuilt search index")
+
+	return nil
+}
+
+// indexSearch runs the project's server to rebuild the search index of the
+// types, or of every searchable type if none are given, since only it has the
+// project's content types registered
+func indexSearch(types []string) error {
+	if _, err := os.Stat(serverPath()); err != nil {
+		return errors.New("The project must be built with 'ponzu build' before its search index can be rebuilt.")
+	}
+
+	args := []string{
+		fmt.Sprintf("--log-level=%s", logLevel),
+		fmt.Sprintf("--log-json=%t", logJSON),
+		"index",
+	}
+
+	index := exec.Command(serverPath(), append(args, types...)...)
+	index.Stderr = os.Stderr
+	index.Stdout = os.Stdout
+
+	err := index.Run()
+	if err != nil {
+		return fmt.Errorf("Failed to rebuild the search index: %v", err)
+	}
+
+	fmt.Println("Rebuilt search index")
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/health"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// defaultShutdownTimeout is how long in-flight requests are given to complete
+// when the server is stopped, if shutdown_timeout_seconds is not configured
+const defaultShutdownTimeout = 30 * time.Second
+
+// waitForShutdown blocks until the process is sent SIGINT or SIGTERM, then
+// marks Ponzu as not ready, stops servers accepting new connections and waits
+// for their in-flight requests to complete, for up to the configured timeout.
+// A second signal stops waiting. healthServer, if not nil, is shut down last,
+// so that readiness probes see Ponzu draining.
+func waitForShutdown(servers []*http.Server, healthServer *http.Server) {
+	stop := make(chan os.Signal, 2)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	health.Drain()
+
+	timeout := defaultShutdownTimeout
+	if n, ok := db.ConfigCache("shutdown_timeout_seconds").(float64); ok && n > 0 {
+		timeout = time.Duration(n) * time.Second
+	}
+
+	fmt.Printf("\nShutting down, waiting up to %s for requests to complete...\n", timeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	go func() {
+		<-stop
+		fmt.Println("Stopping without waiting for requests to complete...")
+		cancel()
+	}()
+
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s *http.Server) {
+			defer wg.Done()
+
+			err := s.Shutdown(ctx)
+			if err != nil {
+				logger.Error("Error shutting down server on", s.Addr, err)
+				s.Close()
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	if healthServer != nil {
+		healthServer.Shutdown(ctx)
+	}
+}
+
+// forwardSignals sends the SIGINT and SIGTERM signals this process receives on
+// to p, instead of exiting
+func forwardSignals(p *os.Process) {
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		for s := range sig {
+			p.Signal(s)
+		}
+	}()
+}
@@ -104,7 +104,7 @@ var usageBuild = `
 `
 
 var usageRun = `
-[[-port=8080] [--https|--devhttps]] run <service(,service)>
+[[-port=8080] [-healthport=0] [-log-level=info] [--log-json] [--https|--devhttps]] run <service(,service)>
 
 	Starts the 'ponzu' HTTP server for the JSON API, Admin System, or both.
 	The segments, separated by a comma, describe which services to start, either 
@@ -123,6 +123,21 @@ var usageRun = `
 
 	Defaults to '-port=8080 run admin,api' (running Admin & API on port 8080, without TLS)
 
+	The /healthz (liveness) and /readyz (readiness) endpoints are served on the
+	HTTP port, or only on the port given with -healthport. Readiness fails once
+	the server begins shutting down, so load balancers stop sending it traffic.
+
+	Log messages less important than -log-level (debug, info, warn or error)
+	are not written, and --log-json writes each message as a line of JSON with
+	its time, level and message, for log aggregation.
+
+	The defaults of the flags are read from PONZU_HTTP_PORT, PONZU_HTTPS_PORT,
+	PONZU_HEALTH_PORT, PONZU_HTTPS, PONZU_LOG_LEVEL and PONZU_LOG_JSON, and any
+	setting of the configuration can be overridden by the environment variable
+	named for its key in upper case, e.g. PONZU_BIND_ADDR for bind_addr. Flags
+	take precedence over the environment, which takes precedence over the
+	configuration saved in the admin.
+
 	Note: 
 	Admin and API cannot run on separate processes unless you use a copy of the
 	database, since the first process to open it receives a lock. If you intend
@@ -130,6 +145,96 @@ var usageRun = `
 	'ponzu' command independently.
 
 
+`
+
+var usageBackup = `
+[-port=8080] backup <directory>
+
+	Writes a backup of the project to the directory, creating it if needed:
+	a consistent snapshot of system.db (content, config, users and uploads
+	metadata) and analytics.db, and an archive of the uploads directory,
+	uploads.tar.gz. The search index is not backed up, since it is rebuilt
+	from the content on restore.
+
+	Backups are safe to take while the server is running. A db the server
+	has open is read through its /admin/backup endpoint on the HTTP port, so
+	the env vars PONZU_BACKUP_USER and PONZU_BACKUP_PASSWORD must be set to
+	the backup credentials configured in the admin.
+
+	Example:
+	$ ponzu backup backups/2017-05-01
+	(or)
+	$ PONZU_BACKUP_USER=admin PONZU_BACKUP_PASSWORD=secret ponzu -port=8888 backup backups/latest
+
+
+`
+
+var usageRestore = `
+restore <directory>
+
+	Restores a backup written by 'ponzu backup' from the directory, then
+	rebuilds the search index from the restored content. The server must be
+	stopped, and the project built with 'ponzu build'. The dbs and uploads
+	directory being replaced are kept with '.before-restore' added to their
+	names, until the next restore.
+
+	Example:
+	$ ponzu restore backups/2017-05-01
+
+
+`
+
+var usageReindex = `
+[-port=8080] reindex [type ...]
+
+	Drops and rebuilds the search index of the content types, or of every
+	searchable content type if none are given, from the content stored, such
+	as when search results are missing or stale after a crash. The project must
+	be built with 'ponzu build'.
+
+	Reindexing is safe while the server is running. Each type is rebuilt into
+	a temporary index which replaces the current one once complete, through
+	the server's /admin/reindex endpoint on the HTTP port, so the env vars
+	PONZU_BACKUP_USER and PONZU_BACKUP_PASSWORD must be set to the backup
+	credentials configured in the admin.
+
+	The search index can also be compared with the content stored each time
+	the server starts, to log a warning or rebuild the index if they differ,
+	by setting the search index check in the admin configuration.
+
+	Example:
+	$ ponzu reindex
+	(or)
+	$ ponzu reindex post review
+
+
+`
+
+var usageMigrate = `
+[-port=8080] [--dry-run] migrate [type ...]
+
+	Applies the migrations of the content types, or of every content type
+	with migrations if none are given, to the content saved before them, and
+	saves it at the current schema version of its type. The project must be
+	built with 'ponzu build'.
+
+	A content type declares its migrations by implementing item.Migratable,
+	adding one to the end of Migrations for each change to its struct.
+	Content not yet migrated is migrated as it is read, so this only makes
+	the change lasting. With --dry-run nothing is saved, and the number of
+	items each migration would change is reported.
+
+	Migrating is safe while the server is running, through the server's
+	/admin/migrate endpoint on the HTTP port, so the env vars
+	PONZU_BACKUP_USER and PONZU_BACKUP_PASSWORD must be set to the backup
+	credentials configured in the admin.
+
+	Example:
+	$ ponzu --dry-run migrate
+	(or)
+	$ ponzu migrate post review
+
+
 `
 
 var usageUpgrade = `
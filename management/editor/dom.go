@@ -3,8 +3,9 @@ package editor
 import (
 	"bytes"
 	"html"
-	"log"
 	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
 )
 
 // Element is a basic struct for representing DOM elements
@@ -35,7 +36,7 @@ func NewElement(tagName, label, fieldName string, p interface{}, attrs map[strin
 func DOMElementSelfClose(e *Element) []byte {
 	_, err := e.ViewBuf.WriteString(`<div class="input-field col s12">`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementSelfClose")
+		logger.Error("Error writing HTML string to buffer: DOMElementSelfClose")
 		return nil
 	}
 
@@ -45,39 +46,39 @@ func DOMElementSelfClose(e *Element) []byte {
 				strings.Join(strings.Split(e.Label, " "), "-") + `">` + e.Label +
 				`</label>`)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElementSelfClose")
+			logger.Error("Error writing HTML string to buffer: DOMElementSelfClose")
 			return nil
 		}
 	}
 
 	_, err = e.ViewBuf.WriteString(`<` + e.TagName + ` value="`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementSelfClose")
+		logger.Error("Error writing HTML string to buffer: DOMElementSelfClose")
 		return nil
 	}
 
 	_, err = e.ViewBuf.WriteString(html.EscapeString(e.Data) + `" `)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementSelfClose")
+		logger.Error("Error writing HTML string to buffer: DOMElementSelfClose")
 		return nil
 	}
 
 	for attr, value := range e.Attrs {
 		_, err := e.ViewBuf.WriteString(attr + `="` + value + `" `)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElementSelfClose")
+			logger.Error("Error writing HTML string to buffer: DOMElementSelfClose")
 			return nil
 		}
 	}
 	_, err = e.ViewBuf.WriteString(` name="` + e.Name + `" />`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementSelfClose")
+		logger.Error("Error writing HTML string to buffer: DOMElementSelfClose")
 		return nil
 	}
 
 	_, err = e.ViewBuf.WriteString(`</div>`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementSelfClose")
+		logger.Error("Error writing HTML string to buffer: DOMElementSelfClose")
 		return nil
 	}
 
@@ -89,26 +90,26 @@ func DOMElementSelfClose(e *Element) []byte {
 func DOMElementCheckbox(e *Element) []byte {
 	_, err := e.ViewBuf.WriteString(`<p class="col s6">`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementCheckbox")
+		logger.Error("Error writing HTML string to buffer: DOMElementCheckbox")
 		return nil
 	}
 
 	_, err = e.ViewBuf.WriteString(`<` + e.TagName + ` `)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementCheckbox")
+		logger.Error("Error writing HTML string to buffer: DOMElementCheckbox")
 		return nil
 	}
 
 	for attr, value := range e.Attrs {
 		_, err := e.ViewBuf.WriteString(attr + `="` + value + `" `)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElementCheckbox")
+			logger.Error("Error writing HTML string to buffer: DOMElementCheckbox")
 			return nil
 		}
 	}
 	_, err = e.ViewBuf.WriteString(` name="` + e.Name + `" />`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementCheckbox")
+		logger.Error("Error writing HTML string to buffer: DOMElementCheckbox")
 		return nil
 	}
 
@@ -118,14 +119,14 @@ func DOMElementCheckbox(e *Element) []byte {
 				strings.Join(strings.Split(e.Label, " "), "-") + `">` +
 				e.Label + `</label>`)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElementCheckbox")
+			logger.Error("Error writing HTML string to buffer: DOMElementCheckbox")
 			return nil
 		}
 	}
 
 	_, err = e.ViewBuf.WriteString(`</p>`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementCheckbox")
+		logger.Error("Error writing HTML string to buffer: DOMElementCheckbox")
 		return nil
 	}
 
@@ -136,7 +137,7 @@ func DOMElementCheckbox(e *Element) []byte {
 func DOMElement(e *Element) []byte {
 	_, err := e.ViewBuf.WriteString(`<div class="input-field col s12">`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElement")
+		logger.Error("Error writing HTML string to buffer: DOMElement")
 		return nil
 	}
 
@@ -146,45 +147,45 @@ func DOMElement(e *Element) []byte {
 				strings.Join(strings.Split(e.Label, " "), "-") + `">` + e.Label +
 				`</label>`)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElement")
+			logger.Error("Error writing HTML string to buffer: DOMElement")
 			return nil
 		}
 	}
 
 	_, err = e.ViewBuf.WriteString(`<` + e.TagName + ` `)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElement")
+		logger.Error("Error writing HTML string to buffer: DOMElement")
 		return nil
 	}
 
 	for attr, value := range e.Attrs {
 		_, err = e.ViewBuf.WriteString(attr + `="` + string(value) + `" `)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElement")
+			logger.Error("Error writing HTML string to buffer: DOMElement")
 			return nil
 		}
 	}
 	_, err = e.ViewBuf.WriteString(` name="` + e.Name + `" >`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElement")
+		logger.Error("Error writing HTML string to buffer: DOMElement")
 		return nil
 	}
 
 	_, err = e.ViewBuf.WriteString(html.EscapeString(e.Data))
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElement")
+		logger.Error("Error writing HTML string to buffer: DOMElement")
 		return nil
 	}
 
 	_, err = e.ViewBuf.WriteString(`</` + e.TagName + `>`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElement")
+		logger.Error("Error writing HTML string to buffer: DOMElement")
 		return nil
 	}
 
 	_, err = e.ViewBuf.WriteString(`</div>`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElement")
+		logger.Error("Error writing HTML string to buffer: DOMElement")
 		return nil
 	}
 
@@ -194,26 +195,26 @@ func DOMElement(e *Element) []byte {
 func DOMElementWithChildrenSelect(e *Element, children []*Element) []byte {
 	_, err := e.ViewBuf.WriteString(`<div class="input-field col s6">`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
+		logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
 		return nil
 	}
 
 	_, err = e.ViewBuf.WriteString(`<` + e.TagName + ` `)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
+		logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
 		return nil
 	}
 
 	for attr, value := range e.Attrs {
 		_, err = e.ViewBuf.WriteString(attr + `="` + value + `" `)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
+			logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
 			return nil
 		}
 	}
 	_, err = e.ViewBuf.WriteString(` name="` + e.Name + `" >`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
+		logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
 		return nil
 	}
 
@@ -221,28 +222,28 @@ func DOMElementWithChildrenSelect(e *Element, children []*Element) []byte {
 	for _, child := range children {
 		_, err = e.ViewBuf.Write(DOMElement(child))
 		if err != nil {
-			log.Println("Error writing HTML DOMElement to buffer: DOMElementWithChildrenSelect")
+			logger.Error("Error writing HTML DOMElement to buffer: DOMElementWithChildrenSelect")
 			return nil
 		}
 	}
 
 	_, err = e.ViewBuf.WriteString(`</` + e.TagName + `>`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
+		logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
 		return nil
 	}
 
 	if e.Label != "" {
 		_, err = e.ViewBuf.WriteString(`<label class="active">` + e.Label + `</label>`)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
+			logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
 			return nil
 		}
 	}
 
 	_, err = e.ViewBuf.WriteString(`</div>`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
+		logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenSelect")
 		return nil
 	}
 
@@ -252,28 +253,28 @@ func DOMElementWithChildrenSelect(e *Element, children []*Element) []byte {
 func DOMElementWithChildrenCheckbox(e *Element, children []*Element) []byte {
 	_, err := e.ViewBuf.WriteString(`<` + e.TagName + ` `)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
+		logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
 		return nil
 	}
 
 	for attr, value := range e.Attrs {
 		_, err = e.ViewBuf.WriteString(attr + `="` + value + `" `)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
+			logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
 			return nil
 		}
 	}
 
 	_, err = e.ViewBuf.WriteString(` >`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
+		logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
 		return nil
 	}
 
 	if e.Label != "" {
 		_, err = e.ViewBuf.WriteString(`<label class="active">` + e.Label + `</label>`)
 		if err != nil {
-			log.Println("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
+			logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
 			return nil
 		}
 	}
@@ -282,14 +283,14 @@ func DOMElementWithChildrenCheckbox(e *Element, children []*Element) []byte {
 	for _, child := range children {
 		_, err = e.ViewBuf.Write(DOMElementCheckbox(child))
 		if err != nil {
-			log.Println("Error writing HTML DOMElementCheckbox to buffer: DOMElementWithChildrenCheckbox")
+			logger.Error("Error writing HTML DOMElementCheckbox to buffer: DOMElementWithChildrenCheckbox")
 			return nil
 		}
 	}
 
 	_, err = e.ViewBuf.WriteString(`</` + e.TagName + `><div class="clear padding">&nbsp;</div>`)
 	if err != nil {
-		log.Println("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
+		logger.Error("Error writing HTML string to buffer: DOMElementWithChildrenCheckbox")
 		return nil
 	}
 
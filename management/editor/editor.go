@@ -4,8 +4,9 @@ package editor
 
 import (
 	"bytes"
-	"log"
 	"net/http"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
 )
 
 // Editable ensures data is editable
@@ -40,7 +41,7 @@ func Form(post Editable, fields ...Field) ([]byte, error) {
 	editor.ViewBuf = &bytes.Buffer{}
 	_, err := editor.ViewBuf.WriteString(`<table><tbody class="row"><tr class="col s8 editor-fields"><td class="col s12">`)
 	if err != nil {
-		log.Println("Error writing HTML string to editor Form buffer")
+		logger.Error("Error writing HTML string to editor Form buffer")
 		return nil, err
 	}
 
@@ -50,14 +51,14 @@ func Form(post Editable, fields ...Field) ([]byte, error) {
 
 	_, err = editor.ViewBuf.WriteString(`</td></tr>`)
 	if err != nil {
-		log.Println("Error writing HTML string to editor Form buffer")
+		logger.Error("Error writing HTML string to editor Form buffer")
 		return nil, err
 	}
 
 	// content items with Item embedded have some default fields we need to render
 	_, err = editor.ViewBuf.WriteString(`<tr class="col s4 default-fields"><td class="col s12">`)
 	if err != nil {
-		log.Println("Error writing HTML string to editor Form buffer")
+		logger.Error("Error writing HTML string to editor Form buffer")
 		return nil, err
 	}
 
@@ -112,7 +113,7 @@ func Form(post Editable, fields ...Field) ([]byte, error) {
 
 	_, err = editor.ViewBuf.WriteString(publishTime)
 	if err != nil {
-		log.Println("Error writing HTML string to editor Form buffer")
+		logger.Error("Error writing HTML string to editor Form buffer")
 		return nil, err
 	}
 
@@ -125,6 +126,7 @@ func Form(post Editable, fields ...Field) ([]byte, error) {
 <div class="input-field post-controls">
 	<button class="right waves-effect waves-light btn green save-post" type="submit">Save</button>
 	<button class="right waves-effect waves-light btn red delete-post" type="submit">Delete</button>
+	<button class="right waves-effect waves-light btn blue-grey preview-post" type="submit">Preview</button>
 </div>
 `
 	_, ok := post.(Mergeable)
@@ -147,6 +149,7 @@ func Form(post Editable, fields ...Field) ([]byte, error) {
 		var form = $('form'),
 			save = form.find('button.save-post'),
 			del = form.find('button.delete-post'),
+			preview = form.find('button.preview-post'),
 			external = form.find('.post-controls.external'),
 			id = form.find('input[name=id]'),
 			timestamp = $('.__ponzu.content-only'),
@@ -158,6 +161,11 @@ func Form(post Editable, fields ...Field) ([]byte, error) {
 			external.hide();
 		}
 
+		// only content can be previewed
+		if (form.attr('action') !== '/admin/edit') {
+			preview.hide();
+		}
+
 		// hide approval if not on a pending content item
 		if (getParam('status') !== 'pending') {
 			external.hide();
@@ -180,6 +188,28 @@ func Form(post Editable, fields ...Field) ([]byte, error) {
 			form.submit();
 		});
 
+		preview.on('click', function(e) {
+			e.preventDefault();
+
+			// the window is opened now, as browsers block windows not opened
+			// by a click
+			var win = window.open('', '_blank');
+			$.ajax({
+				url: '/admin/edit/preview',
+				method: 'POST',
+				data: new FormData(form[0]),
+				processData: false,
+				contentType: false,
+				success: function(data) {
+					win.location = data.url;
+				},
+				error: function() {
+					win.close();
+					alert("[Ponzu] The preview could not be made.\n\nPlease check the content for errors and try again.");
+				}
+			});
+		});
+
 		del.on('click', function(e) {
 			e.preventDefault();
 			var action = form.attr('action');
@@ -215,7 +245,7 @@ func Form(post Editable, fields ...Field) ([]byte, error) {
 `
 	_, err = editor.ViewBuf.WriteString(submit + script + `</td></tr></tbody></table>`)
 	if err != nil {
-		log.Println("Error writing HTML string to editor Form buffer")
+		logger.Error("Error writing HTML string to editor Form buffer")
 		return nil, err
 	}
 
@@ -225,7 +255,7 @@ func Form(post Editable, fields ...Field) ([]byte, error) {
 func addFieldToEditorView(e *Editor, f Field) error {
 	_, err := e.ViewBuf.Write(f.View)
 	if err != nil {
-		log.Println("Error writing field view to editor view buffer")
+		logger.Error("Error writing field view to editor view buffer")
 		return err
 	}
 
@@ -238,8 +268,7 @@ func addPostDefaultFieldsToEditorView(p Editable, e *Editor) error {
 			View: Input("Slug", p, map[string]string{
 				"label":       "URL Slug",
 				"type":        "text",
-				"disabled":    "true",
-				"placeholder": "Will be set automatically",
+				"placeholder": "Leave empty to set automatically",
 			}),
 		},
 		{
@@ -254,6 +283,27 @@ func addPostDefaultFieldsToEditorView(p Editable, e *Editor) error {
 				"class": "updated __ponzu",
 			}),
 		},
+		{
+			View: Input("Version", p, map[string]string{
+				"type":  "hidden",
+				"class": "version __ponzu",
+			}),
+		},
+		{
+			View: []byte(`
+<div class="row content-only __ponzu">
+	<div class="input-field col s12">
+		<label class="active">Publish At (leave empty to publish immediately)</label>
+		<input class="publish-at-picker __ponzu" type="datetime-local" />
+	</div>
+</div>`),
+		},
+		{
+			View: Timestamp("PublishAt", p, map[string]string{
+				"type":  "hidden",
+				"class": "publish-at __ponzu",
+			}),
+		},
 	}
 
 	for _, f := range defaults {
@@ -2,8 +2,13 @@ package editor
 
 import (
 	"bytes"
+	"fmt"
 	"html"
+	"reflect"
+	"strconv"
 	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/item"
 )
 
 // Input returns the []byte of an <input> HTML element with a label.
@@ -211,6 +216,8 @@ func File(fieldName string, p interface{}, attrs map[string]string) []byte {
 }
 
 // Richtext returns the []byte of a rich text editor (provided by http://summernote.org/) with a label.
+// The editor produces HTML, so content types using it should implement
+// item.Sanitizable to have the field sanitized before it is saved.
 // IMPORTANT:
 // The `fieldName` argument will cause a panic if it is not exactly the string
 // form of the struct field that this editor input is representing
@@ -277,7 +284,7 @@ func Richtext(fieldName string, p interface{}, attrs map[string]string) []byte {
 					$.ajax({
 						data: data,
 						type: 'POST',
-						url: '/admin/edit/upload',
+						url: '/admin/edit/upload' + location.search,
 						cache: false,
 						contentType: false,
 						processData: false,
@@ -288,6 +295,9 @@ func Richtext(fieldName string, p interface{}, attrs map[string]string) []byte {
 						},
 						error: function(xhr, status, err) {
 							console.log(status, err);
+							if (xhr.responseJSON && xhr.responseJSON.error) {
+								Materialize.toast(xhr.responseJSON.error, 6000);
+							}
 						}
 					})
 
@@ -316,6 +326,211 @@ func Richtext(fieldName string, p interface{}, attrs map[string]string) []byte {
 	return append(iso, []byte(script)...)
 }
 
+// Color returns the []byte of a native color picker, plus a text <input> to
+// enter the hex color by hand, with a label. The struct field should be of type
+// item.Color, so the value is validated and stored as #rrggbb when saved.
+// IMPORTANT:
+// The `fieldName` argument will cause a panic if it is not exactly the string
+// form of the struct field that this editor input is representing
+// 	type Theme struct {
+//		item.Item
+//		editor editor.Editor
+//
+// 		Accent item.Color `json:"accent"`
+//		//...
+// 	}
+//
+// 	func (t *Theme) MarshalEditor() ([]byte, error) {
+// 		view, err := editor.Form(t,
+// 			editor.Field{
+// 				View: editor.Color("Accent", t, map[string]string{
+// 					"label":       "Accent",
+// 					"placeholder": "#ff8800",
+// 				}),
+// 			}
+// 		)
+// 	}
+func Color(fieldName string, p interface{}, attrs map[string]string) []byte {
+	name := TagNameFromStructField(fieldName, p)
+	value := html.EscapeString(ValueFromStructField(fieldName, p))
+
+	// the native picker needs a full #rrggbb value to show, so default to black
+	swatch := value
+	if swatch == "" {
+		swatch = "#000000"
+	}
+
+	tmpl := `
+	<div class="col s12 __ponzu-color ` + name + `">
+		<label class="active">` + attrs["label"] + `</label>
+		<div class="row">
+			<div class="input-field col s2">
+				<input class="color-swatch" type="color" value="` + swatch + `"/>
+			</div>
+			<div class="input-field col s10">
+				<input class="color-hex" type="text" name="` + name + `" value="` + value + `" placeholder="` + attrs["placeholder"] + `" pattern="#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})"/>
+			</div>
+		</div>
+	</div>`
+
+	script := `
+	<script>
+		$(function() {
+			var color = $('.__ponzu-color.` + name + `'),
+				swatch = color.find('input.color-swatch'),
+				hex = color.find('input.color-hex');
+
+			swatch.on('input change', function() {
+				hex.val(swatch.val());
+			});
+
+			// update the picker when a valid #rrggbb or #rgb color is typed
+			hex.on('input', function() {
+				var v = hex.val().trim().replace(/^#/, '');
+				if (/^[0-9a-f]{3}$/i.test(v)) {
+					v = v.replace(/(.)/g, '$1$1');
+				}
+				if (/^[0-9a-f]{6}$/i.test(v)) {
+					swatch.val('#' + v.toLowerCase());
+				}
+			});
+		});
+	</script>
+	`
+
+	return []byte(tmpl + script)
+}
+
+// Geolocation returns the []byte of a map (provided by https://leafletjs.com/)
+// on which a marker can be placed, with a label, plus number inputs for the
+// latitude and longitude which are used to enter them by hand when the map is
+// not available. The struct field must be of type item.Location, so the
+// coordinates are validated when saved.
+// IMPORTANT:
+// The `fieldName` argument will cause a panic if it is not exactly the string
+// form of the struct field that this editor input is representing
+// 	type Venue struct {
+//		item.Item
+//		editor editor.Editor
+//
+// 		Location item.Location `json:"location"`
+//		//...
+// 	}
+//
+// 	func (v *Venue) MarshalEditor() ([]byte, error) {
+// 		view, err := editor.Form(v,
+// 			editor.Field{
+// 				View: editor.Geolocation("Location", v, map[string]string{
+// 					"label": "Location",
+// 				}),
+// 			}
+// 		)
+// 	}
+func Geolocation(fieldName string, p interface{}, attrs map[string]string) []byte {
+	name := TagNameFromStructField(fieldName, p)
+
+	if row, ok := p.(*groupRow); ok {
+		p = row.value
+	}
+	field := reflect.Indirect(reflect.ValueOf(p)).FieldByName(fieldName)
+	loc, ok := field.Interface().(item.Location)
+	if !ok {
+		panic(fmt.Sprintf("Ponzu: Type '%s' for field '%s' not supported by Geolocation, it must be item.Location.", field.Type(), fieldName))
+	}
+
+	// leave the inputs empty for a new location, rather than at 0, 0
+	var lat, lng string
+	if loc != (item.Location{}) {
+		lat = strconv.FormatFloat(loc.Lat, 'f', -1, 64)
+		lng = strconv.FormatFloat(loc.Lng, 'f', -1, 64)
+	}
+
+	tmpl := `
+	<div class="col s12 __ponzu-geo ` + name + `">
+		<label class="active">` + attrs["label"] + `</label>
+		<div class="geo-map" style="height: 300px; margin: 1rem 0;"></div>
+		<div class="row">
+			<div class="input-field col s6">
+				<label class="active">Latitude</label>
+				<input class="geo-lat" type="number" step="any" min="-90" max="90" name="` + name + `.lat" value="` + lat + `"/>
+			</div>
+			<div class="input-field col s6">
+				<label class="active">Longitude</label>
+				<input class="geo-lng" type="number" step="any" min="-180" max="180" name="` + name + `.lng" value="` + lng + `"/>
+			</div>
+		</div>
+	</div>`
+
+	script := `
+	<link rel="stylesheet" href="https://unpkg.com/leaflet@1.9.4/dist/leaflet.css"/>
+	<script src="https://unpkg.com/leaflet@1.9.4/dist/leaflet.js"></script>
+	<script>
+		$(function() {
+			var geo = $('.__ponzu-geo.` + name + `'),
+				lat = geo.find('input.geo-lat'),
+				lng = geo.find('input.geo-lng'),
+				el = geo.find('.geo-map');
+
+			// keep only the inputs if the map could not be loaded
+			if (typeof L === 'undefined') {
+				el.hide();
+				return;
+			}
+
+			var map = L.map(el[0]),
+				marker = null;
+
+			L.tileLayer('https://{s}.tile.openstreetmap.org/{z}/{x}/{y}.png', {
+				maxZoom: 19,
+				attribution: '&copy; OpenStreetMap contributors'
+			}).addTo(map);
+
+			var place = function(latlng) {
+				if (marker) {
+					marker.setLatLng(latlng);
+					return;
+				}
+
+				marker = L.marker(latlng, {draggable: true}).addTo(map);
+				marker.on('dragend', function() {
+					var pos = marker.getLatLng();
+					lat.val(pos.lat.toFixed(6));
+					lng.val(pos.lng.toFixed(6));
+				});
+			};
+
+			if (lat.val() !== '' && lng.val() !== '') {
+				var start = [parseFloat(lat.val()), parseFloat(lng.val())];
+				map.setView(start, 13);
+				place(start);
+			} else {
+				map.setView([0, 0], 1);
+			}
+
+			map.on('click', function(e) {
+				var pos = e.latlng.wrap();
+				lat.val(pos.lat.toFixed(6));
+				lng.val(pos.lng.toFixed(6));
+				place(pos);
+			});
+
+			// move the marker when coordinates are entered by hand
+			geo.find('input').on('change', function() {
+				var la = parseFloat(lat.val()), ln = parseFloat(lng.val());
+				if (isNaN(la) || isNaN(ln)) {
+					return;
+				}
+
+				place([la, ln]);
+				map.panTo([la, ln]);
+			});
+		});
+	</script>
+	`
+
+	return []byte(tmpl + script)
+}
+
 // Select returns the []byte of a <select> HTML element plus internal <options> with a label.
 // IMPORTANT:
 // The `fieldName` argument will cause a panic if it is not exactly the string
@@ -426,6 +641,8 @@ func Checkbox(fieldName string, p interface{}, attrs, options map[string]string)
 }
 
 // Tags returns the []byte of a tag input (in the style of Materialze 'Chips') with a label.
+// Existing tags of content types which implement item.Taggable are suggested
+// as they are typed, so that tags are reused rather than misspelled.
 // IMPORTANT:
 // The `fieldName` argument will cause a panic if it is not exactly the string
 // form of the struct field that this editor input is representing
@@ -470,6 +687,23 @@ func Tags(fieldName string, p interface{}, attrs map[string]string) []byte {
 
 			// handle events specific to tags
 			var chips = tags.find('.chips');
+
+			// suggest existing tags matching what is typed
+			var list = $('<datalist>').attr('id', '__ponzu-tags-` + name + `'),
+				timer;
+			tags.append(list);
+			chips.find('input').attr({list: list.attr('id'), autocomplete: 'off'}).on('input', function() {
+				var q = $(this).val();
+				clearTimeout(timer);
+				timer = setTimeout(function() {
+					$.getJSON('/admin/tags/suggest' + location.search, {q: q}, function(resp) {
+						list.empty();
+						resp.data.forEach(function(tag) {
+							list.append($('<option>').val(tag));
+						});
+					});
+				}, 200);
+			});
 			
 			chips.on('chip.add', function(e, chip) {
 				chips.parent().find('.empty-tag').remove();
@@ -518,3 +752,78 @@ func Tags(fieldName string, p interface{}, attrs map[string]string) []byte {
 
 	return []byte(html + script)
 }
+
+// Reference returns the []byte of a <select> HTML element with a label, whose
+// options are the items of the content type named by contentType, and an input
+// to search them by name. The value stored is the path of the selected item in
+// the content API, e.g. /api/content?type=Author&id=1. Content types using a
+// Reference field should implement item.Referenceable, so the API can include
+// the referenced items.
+// IMPORTANT:
+// The `fieldName` argument will cause a panic if it is not exactly the string
+// form of the struct field that this editor input is representing
+func Reference(fieldName string, p interface{}, attrs map[string]string, contentType string) []byte {
+	name := TagNameFromStructField(fieldName, p)
+	value := html.EscapeString(ValueFromStructField(fieldName, p))
+
+	tmpl := `
+	<div class="col s12 __ponzu-reference ` + name + `">
+		<label class="active">` + attrs["label"] + `</label>
+		<div class="row">
+			<div class="input-field col s6">
+				<input class="reference-search" type="text" placeholder="Search ` + contentType + `..."/>
+			</div>
+			<div class="input-field col s6">
+				<select class="browser-default" name="` + name + `">
+					<option value="">None</option>`
+
+	if value != "" {
+		tmpl += `
+					<option value="` + value + `" selected="true">` + value + `</option>`
+	}
+
+	tmpl += `
+				</select>
+			</div>
+		</div>
+	</div>`
+
+	script := `
+	<script>
+		$(function() {
+			var ref = $('.__ponzu-reference.` + name + `'),
+				search = ref.find('input.reference-search'),
+				sel = ref.find('select'),
+				timer;
+
+			// load the items matching the search, keeping the selected item
+			var load = function(q) {
+				$.getJSON('/admin/references', {type: '` + contentType + `', q: q, selected: sel.val()}, function(resp) {
+					var current = sel.val();
+					sel.find('option').filter(function() {
+						return this.value !== '' && this.value !== current;
+					}).remove();
+
+					resp.data.forEach(function(item) {
+						if (item.value === current) {
+							sel.find('option:selected').text(item.label);
+							return;
+						}
+
+						sel.append($('<option>').val(item.value).text(item.label));
+					});
+				});
+			};
+
+			search.on('input', function() {
+				clearTimeout(timer);
+				timer = setTimeout(function() { load(search.val()); }, 250);
+			});
+
+			load('');
+		});
+	</script>
+	`
+
+	return []byte(tmpl + script)
+}
@@ -3,8 +3,10 @@ package editor
 import (
 	"bytes"
 	"fmt"
-	"log"
+	"reflect"
 	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
 )
 
 // InputRepeater returns the []byte of an <input> HTML element with a label.
@@ -42,7 +44,7 @@ func InputRepeater(fieldName string, p interface{}, attrs map[string]string) []b
 
 	_, err := html.WriteString(`<span class="__ponzu-repeat ` + scope + `">`)
 	if err != nil {
-		log.Println("Error writing HTML string to InputRepeater buffer")
+		logger.Error("Error writing HTML string to InputRepeater buffer")
 		return nil
 	}
 
@@ -62,13 +64,13 @@ func InputRepeater(fieldName string, p interface{}, attrs map[string]string) []b
 
 		_, err := html.Write(DOMElementSelfClose(el))
 		if err != nil {
-			log.Println("Error writing DOMElementSelfClose to InputRepeater buffer")
+			logger.Error("Error writing DOMElementSelfClose to InputRepeater buffer")
 			return nil
 		}
 	}
 	_, err = html.WriteString(`</span>`)
 	if err != nil {
-		log.Println("Error writing HTML string to InputRepeater buffer")
+		logger.Error("Error writing HTML string to InputRepeater buffer")
 		return nil
 	}
 
@@ -88,7 +90,7 @@ func SelectRepeater(fieldName string, p interface{}, attrs, options map[string]s
 	html := bytes.Buffer{}
 	_, err := html.WriteString(`<span class="__ponzu-repeat ` + scope + `">`)
 	if err != nil {
-		log.Println("Error writing HTML string to SelectRepeater buffer")
+		logger.Error("Error writing HTML string to SelectRepeater buffer")
 		return nil
 	}
 
@@ -155,7 +157,7 @@ func SelectRepeater(fieldName string, p interface{}, attrs, options map[string]s
 
 			_, err := html.Write(DOMElementWithChildrenSelect(sel, opts))
 			if err != nil {
-				log.Println("Error writing DOMElementWithChildrenSelect to SelectRepeater buffer")
+				logger.Error("Error writing DOMElementWithChildrenSelect to SelectRepeater buffer")
 				return nil
 			}
 		}
@@ -163,7 +165,7 @@ func SelectRepeater(fieldName string, p interface{}, attrs, options map[string]s
 
 	_, err = html.WriteString(`</span>`)
 	if err != nil {
-		log.Println("Error writing HTML string to SelectRepeater buffer")
+		logger.Error("Error writing HTML string to SelectRepeater buffer")
 		return nil
 	}
 
@@ -260,7 +262,7 @@ func FileRepeater(fieldName string, p interface{}, attrs map[string]string) []by
 	html := bytes.Buffer{}
 	_, err := html.WriteString(`<span class="__ponzu-repeat ` + name + `">`)
 	if err != nil {
-		log.Println("Error writing HTML string to FileRepeater buffer")
+		logger.Error("Error writing HTML string to FileRepeater buffer")
 		return nil
 	}
 
@@ -270,19 +272,19 @@ func FileRepeater(fieldName string, p interface{}, attrs map[string]string) []by
 
 		_, err := html.WriteString(fmt.Sprintf(tmpl, nameidx, addLabelFirst(i, attrs["label"]), val, className, fieldName))
 		if err != nil {
-			log.Println("Error writing HTML string to FileRepeater buffer")
+			logger.Error("Error writing HTML string to FileRepeater buffer")
 			return nil
 		}
 
 		_, err = html.WriteString(fmt.Sprintf(script, nameidx, className))
 		if err != nil {
-			log.Println("Error writing HTML string to FileRepeater buffer")
+			logger.Error("Error writing HTML string to FileRepeater buffer")
 			return nil
 		}
 	}
 	_, err = html.WriteString(`</span>`)
 	if err != nil {
-		log.Println("Error writing HTML string to FileRepeater buffer")
+		logger.Error("Error writing HTML string to FileRepeater buffer")
 		return nil
 	}
 
@@ -440,3 +442,400 @@ func RepeatController(fieldName string, p interface{}, inputSelector, cloneSelec
 
 	return []byte(script)
 }
+
+// FieldGroup returns the []byte of a repeatable group of fields, for a struct
+// field which is a slice of structs. The fields func is called for each row of
+// the group and should return the views of its elements, passing the row it is
+// given in place of the content. Rows can be added and removed in the editor,
+// and rows left empty are dropped when the content is saved. Input, Textarea
+// and Select elements are supported within a row.
+// IMPORTANT:
+// The `fieldName` argument will cause a panic if it is not exactly the string
+// form of the struct field that this editor input is representing
+// 	type FAQ struct {
+// 		Question string `json:"question"`
+// 		Answer   string `json:"answer"`
+// 	}
+//
+// 	type Page struct {
+//		item.Item
+//		editor editor.Editor
+//
+// 		FAQs []FAQ `json:"faqs"`
+//		//...
+// 	}
+//
+// 	func (p *Page) MarshalEditor() ([]byte, error) {
+// 		view, err := editor.Form(p,
+// 			editor.Field{
+// 				View: editor.FieldGroup("FAQs", p, map[string]string{
+// 					"label": "FAQs",
+// 				}, func(row interface{}) []byte {
+// 					return append(
+// 						editor.Input("Question", row, map[string]string{"label": "Question", "type": "text"}),
+// 						editor.Textarea("Answer", row, map[string]string{"label": "Answer"})...,
+// 					)
+// 				}),
+// 			}
+// 		)
+// 	}
+func FieldGroup(fieldName string, p interface{}, attrs map[string]string, fields func(row interface{}) []byte) []byte {
+	scope := TagNameFromStructField(fieldName, p)
+
+	field := reflect.Indirect(reflect.ValueOf(p)).FieldByName(fieldName)
+	if field.Kind() != reflect.Slice || field.Type().Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("Ponzu: Type '%s' for field '%s' not supported by FieldGroup, it must be a slice of structs.", field.Type(), fieldName))
+	}
+
+	html := bytes.Buffer{}
+	_, err := html.WriteString(`<div class="col s12 __ponzu-group ` + scope + `">`)
+	if err != nil {
+		logger.Error("Error writing HTML string to FieldGroup buffer")
+		return nil
+	}
+
+	if attrs["label"] != "" {
+		_, err = html.WriteString(`<label class="active">` + attrs["label"] + `</label>`)
+		if err != nil {
+			logger.Error("Error writing HTML string to FieldGroup buffer")
+			return nil
+		}
+	}
+
+	// render a single empty row when there are none, so one can be filled in
+	rows := field.Len()
+	if rows == 0 {
+		rows = 1
+	}
+
+	for i := 0; i < rows; i++ {
+		value := reflect.New(field.Type().Elem())
+		if i < field.Len() {
+			value.Elem().Set(field.Index(i))
+		}
+
+		row := &groupRow{
+			prefix: fmt.Sprintf("%s.%d.", scope, i),
+			value:  value.Interface(),
+		}
+
+		_, err = html.WriteString(`<div class="row __ponzu-group-row">`)
+		if err != nil {
+			logger.Error("Error writing HTML string to FieldGroup buffer")
+			return nil
+		}
+
+		_, err = html.Write(fields(row))
+		if err != nil {
+			logger.Error("Error writing row fields to FieldGroup buffer")
+			return nil
+		}
+
+		_, err = html.WriteString(`</div>`)
+		if err != nil {
+			logger.Error("Error writing HTML string to FieldGroup buffer")
+			return nil
+		}
+	}
+
+	_, err = html.WriteString(`</div>`)
+	if err != nil {
+		logger.Error("Error writing HTML string to FieldGroup buffer")
+		return nil
+	}
+
+	script := `
+    <script>
+        $(function() {
+            var scope = $('.__ponzu-group.` + scope + `');
+
+            var getRows = function() {
+                return scope.children('.__ponzu-group-row');
+            }
+
+            // set the names of each row's elements to ` + scope + `.i.field
+            // where i is the current index of the row
+            var resetFieldNames = function() {
+                getRows().each(function(i, row) {
+                    $(row).find('input, select, textarea').each(function(j, elem) {
+                        var $elem = $(elem);
+                        var name = $elem.attr('name') || '';
+                        $elem.attr('name', name.replace(/^` + scope + `\.\d+\./, '` + scope + `.' + i + '.'));
+                    });
+                });
+            }
+
+            var clearRow = function(row) {
+                row.find('input, textarea').val('');
+                row.find('select').prop('selectedIndex', 0);
+            }
+
+            var addRow = function(e) {
+                e.preventDefault();
+
+                var source = $(e.target).closest('.__ponzu-group-row');
+                var clone = source.clone();
+
+                clone.find('.controls').remove();
+                clearRow(clone);
+
+                source.after(clone);
+                applyControls(clone);
+                resetFieldNames();
+            }
+
+            var delRow = function(e) {
+                e.preventDefault();
+
+                var row = $(e.target).closest('.__ponzu-group-row');
+
+                // keep the only row, but empty it
+                if (getRows().length === 1) {
+                    clearRow(row);
+                    return;
+                }
+
+                row.remove();
+                resetFieldNames();
+            }
+
+            var applyControls = function(row) {
+                var add = $('<button>+</button>');
+                add.addClass('btn-flat waves-effect waves-green');
+                add.on('click', addRow);
+
+                var del = $('<button>-</button>');
+                del.addClass('btn-flat waves-effect waves-red');
+                del.on('click', delRow);
+
+                var controls = $('<span></span>');
+                controls.addClass('controls right');
+                controls.append(add);
+                controls.append(del);
+
+                row.append(controls);
+            }
+
+            getRows().each(function(i, row) {
+                applyControls($(row));
+            });
+        });
+    </script>
+    `
+
+	return append(html.Bytes(), script...)
+}
+
+// MultiFile returns the []byte of an area onto which several files can be
+// dropped (or chosen) at once and uploaded, with a label. Each file is stored
+// as it is added, showing its progress, and the uploaded files can be reordered
+// by dragging them or removed before the content is saved. The struct field
+// should be a []string, which holds the URL paths of the uploads in order.
+// IMPORTANT:
+// The `fieldName` argument will cause a panic if it is not exactly the string
+// form of the struct field that this editor input is representing
+// 	type Gallery struct {
+//		item.Item
+//		editor editor.Editor
+//
+// 		Photos []string `json:"photos"`
+//		//...
+// 	}
+//
+// 	func (g *Gallery) MarshalEditor() ([]byte, error) {
+// 		view, err := editor.Form(g,
+// 			editor.Field{
+// 				View: editor.MultiFile("Photos", g, map[string]string{
+// 					"label": "Photos",
+// 				}),
+// 			}
+// 		)
+// 	}
+func MultiFile(fieldName string, p interface{}, attrs map[string]string) []byte {
+	name := TagNameFromStructField(fieldName, p)
+	vals := strings.Split(ValueFromStructField(fieldName, p), "__ponzu")
+
+	html := bytes.Buffer{}
+	_, err := html.WriteString(`
+	<div class="col s12 __ponzu-multifile ` + name + `">
+		<label class="active">` + attrs["label"] + `</label>
+		<div class="dropzone card-panel grey lighten-4 center-align" style="border: 2px dashed #9e9e9e; cursor: pointer;">
+			<p>Drop files here, or click to choose them</p>
+			<input class="upload" type="file" multiple style="display: none;"/>
+		</div>
+		<ul class="collection uploads">`)
+	if err != nil {
+		logger.Error("Error writing HTML string to MultiFile buffer")
+		return nil
+	}
+
+	for i, val := range vals {
+		if val == "" {
+			continue
+		}
+
+		_, err = html.WriteString(`
+			<li class="collection-item upload-item" draggable="true">
+				<a href="` + val + `" target="_blank">` + val + `</a>
+				<input type="hidden" name="` + fmt.Sprintf("%s.%d", name, i) + `" value="` + val + `"/>
+				<a href="#" class="secondary-content remove"><i class="material-icons">close</i></a>
+			</li>`)
+		if err != nil {
+			logger.Error("Error writing HTML string to MultiFile buffer")
+			return nil
+		}
+	}
+
+	_, err = html.WriteString(`
+		</ul>
+	</div>`)
+	if err != nil {
+		logger.Error("Error writing HTML string to MultiFile buffer")
+		return nil
+	}
+
+	script := `
+	<script>
+		$(function() {
+			var multi = $('.__ponzu-multifile.` + name + `'),
+				dropzone = multi.find('.dropzone'),
+				upload = multi.find('input.upload'),
+				list = multi.find('ul.uploads'),
+				dragging = null;
+
+			// set the names of the stored uploads to ` + name + `.i where i is
+			// their position in the list
+			var resetFieldNames = function() {
+				list.find('input[type=hidden]').each(function(i, input) {
+					$(input).attr('name', '` + name + `.' + i);
+				});
+			};
+
+			var bindItem = function(li) {
+				li.find('a.remove').on('click', function(e) {
+					e.preventDefault();
+					li.remove();
+					resetFieldNames();
+				});
+
+				li.on('dragstart', function(e) {
+					dragging = li;
+					e.originalEvent.dataTransfer.effectAllowed = 'move';
+					e.originalEvent.dataTransfer.setData('text/plain', '');
+				});
+
+				li.on('dragover', function(e) {
+					if (!dragging) {
+						return;
+					}
+					e.preventDefault();
+
+					var rect = li[0].getBoundingClientRect();
+					if (e.originalEvent.clientY < rect.top + rect.height / 2) {
+						li.before(dragging);
+					} else {
+						li.after(dragging);
+					}
+				});
+
+				li.on('dragend', function() {
+					dragging = null;
+					resetFieldNames();
+				});
+			};
+
+			// upload a single file, showing its progress until it is stored
+			var send = function(file) {
+				var li = $('<li class="collection-item upload-item" draggable="true"></li>'),
+					label = $('<span></span>').text(file.name),
+					progress = $('<div class="progress"><div class="determinate" style="width: 0%"></div></div>');
+
+				li.append(label, progress);
+				list.append(li);
+
+				var data = new FormData();
+				data.append('file', file);
+
+				var xhr = new XMLHttpRequest();
+				xhr.open('POST', '/admin/edit/upload' + location.search);
+				xhr.upload.onprogress = function(e) {
+					if (e.lengthComputable) {
+						progress.find('.determinate').css('width', (e.loaded / e.total * 100) + '%');
+					}
+				};
+				xhr.onload = function() {
+					progress.remove();
+
+					if (xhr.status !== 200) {
+						var reason = 'upload failed';
+						try {
+							reason = JSON.parse(xhr.responseText).error || reason;
+						} catch (err) {}
+
+						label.text(file.name + ' (' + reason + ')').addClass('red-text');
+						li.append('<a href="#" class="secondary-content remove"><i class="material-icons">close</i></a>');
+						li.find('a.remove').on('click', function(e) {
+							e.preventDefault();
+							li.remove();
+						});
+						return;
+					}
+
+					var url = JSON.parse(xhr.responseText).data[0].url;
+					label.replaceWith($('<a target="_blank"></a>').attr('href', url).text(url));
+					li.append($('<input type="hidden"/>').val(url));
+					li.append('<a href="#" class="secondary-content remove"><i class="material-icons">close</i></a>');
+					bindItem(li);
+					resetFieldNames();
+				};
+				xhr.onerror = xhr.onload;
+				xhr.send(data);
+			};
+
+			var sendAll = function(files) {
+				for (var i = 0; i < files.length; i++) {
+					send(files[i]);
+				}
+			};
+
+			dropzone.on('click', function(e) {
+				if (e.target !== upload[0]) {
+					upload.click();
+				}
+			});
+
+			upload.on('change', function() {
+				sendAll(upload[0].files);
+				upload.val('');
+			});
+
+			dropzone.on('dragover dragenter', function(e) {
+				if (dragging) {
+					return;
+				}
+				e.preventDefault();
+				dropzone.css('border-color', '#26a69a');
+			});
+
+			dropzone.on('dragleave drop', function() {
+				dropzone.css('border-color', '');
+			});
+
+			dropzone.on('drop', function(e) {
+				if (dragging) {
+					return;
+				}
+				e.preventDefault();
+				sendAll(e.originalEvent.dataTransfer.files);
+			});
+
+			list.find('li.upload-item').each(function(i, li) {
+				bindItem($(li));
+			});
+			resetFieldNames();
+		});
+	</script>
+	`
+
+	return append(html.Bytes(), script...)
+}
@@ -15,6 +15,10 @@ func TagNameFromStructField(name string, post interface{}) string {
 		return name
 	}
 
+	if row, ok := post.(*groupRow); ok {
+		return row.prefix + TagNameFromStructField(name, row.value)
+	}
+
 	field, ok := reflect.TypeOf(post).Elem().FieldByName(name)
 	if !ok {
 		panic("Couldn't get struct field for: " + name + ". Make sure you pass the right field name to editor field elements.")
@@ -41,6 +45,10 @@ func TagNameFromStructFieldMulti(name string, i int, post interface{}) string {
 
 // ValueFromStructField returns the string value of a field in a struct
 func ValueFromStructField(name string, post interface{}) string {
+	if row, ok := post.(*groupRow); ok {
+		return ValueFromStructField(name, row.value)
+	}
+
 	field := reflect.Indirect(reflect.ValueOf(post)).FieldByName(name)
 
 	switch field.Kind() {
@@ -76,3 +84,10 @@ func ValueFromStructField(name string, post interface{}) string {
 		panic(fmt.Sprintf("Ponzu: Type '%s' for field '%s' not supported.", field.Type(), name))
 	}
 }
+
+// groupRow stands in for the content passed to the elements of a row in a
+// FieldGroup, so that their names are scoped to the row, e.g. faqs.0.question
+type groupRow struct {
+	prefix string
+	value  interface{}
+}
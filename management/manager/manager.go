@@ -85,6 +85,19 @@ const managerHTML = `
 			}
 
 			setDefaultTimeAndDate(getFields(), time);
+
+			// show the scheduled publish time, if any, in the local time zone
+			var publishAt = $('input.__ponzu.publish-at'),
+				publishAtPicker = $('input.__ponzu.publish-at-picker'),
+				pad = function(n) {
+					return n < 10 ? '0' + n : '' + n;
+				};
+
+			if (publishAt.val() !== "" && publishAt.val() !== "0") {
+				var at = new Date(parseInt(publishAt.val()));
+				publishAtPicker.val(at.getFullYear() + '-' + pad(at.getMonth()+1) + '-' + pad(at.getDate()) +
+					'T' + pad(at.getHours()) + ':' + pad(at.getMinutes()));
+			}
 			
 			var timeUpdated = false;
 			$('form').on('submit', function(e) {
@@ -98,6 +111,13 @@ const managerHTML = `
 				updateTimestamp(getFields(), timestamp);
 				updated.val((new Date()).getTime());
 
+				// datetime-local values are parsed as local time
+				if (publishAtPicker.val() !== "") {
+					publishAt.val((new Date(publishAtPicker.val())).getTime());
+				} else {
+					publishAt.val(0);
+				}
+
 				timeUpdated = true;
 				$('form').submit();
 			});
@@ -5,11 +5,11 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"time"
 
 	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
 )
 
 // QueryOptions is a mirror of the same struct in db package and are re-declared
@@ -25,7 +25,7 @@ func ContentAll(namespace string) []byte {
 
 	j, err := Get(URL)
 	if err != nil {
-		log.Println("Error in ContentAll for reference HTTP request:", URL)
+		logger.Error("Error in ContentAll for reference HTTP request:", URL)
 		return nil
 	}
 
@@ -42,7 +42,7 @@ func Query(namespace string, opts QueryOptions) []byte {
 
 	j, err := Get(URL)
 	if err != nil {
-		log.Println("Error in Query for reference HTTP request:", URL)
+		logger.Error("Error in Query for reference HTTP request:", URL)
 		return nil
 	}
 
@@ -56,7 +56,7 @@ func Get(endpoint string) ([]byte, error) {
 
 	req, err := http.NewRequest(http.MethodGet, endpoint, r)
 	if err != nil {
-		log.Println("Error creating reference HTTP request:", endpoint)
+		logger.Error("Error creating reference HTTP request:", endpoint)
 		return nil, err
 	}
 
@@ -65,14 +65,14 @@ func Get(endpoint string) ([]byte, error) {
 	}
 	res, err := c.Do(req)
 	if err != nil {
-		log.Println("Error making reference HTTP request:", endpoint)
+		logger.Error("Error making reference HTTP request:", endpoint)
 		return nil, err
 	}
 	defer res.Body.Close()
 
 	j, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		log.Println("Error reading body for reference HTTP request:", endpoint)
+		logger.Error("Error reading body for reference HTTP request:", endpoint)
 		return nil, err
 	}
 
@@ -0,0 +1,89 @@
+package addon
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+
+	"github.com/tidwall/gjson"
+)
+
+// Widget is a card shown on the admin dashboard. Its content is either written
+// by Handler, or is the html/template fragment Template executed with the
+// result of Data, which may be nil. Both are given the request for the
+// dashboard, and can read from the db and analytics packages like any other
+// handler.
+type Widget struct {
+	Title string
+
+	// Width is the number of the 12 columns of the dashboard grid the card
+	// spans, 6 by default
+	Width int
+
+	Handler http.Handler
+
+	Template string
+	Data     func(req *http.Request) (interface{}, error)
+
+	// Addon is the addon_reverse_dns of the addon contributing the widget. If
+	// set, the widget is only shown while the addon is enabled.
+	Addon string
+}
+
+var (
+	widgetsMu sync.RWMutex
+	widgets   []Widget
+)
+
+// RegisterWidget adds w to the admin dashboard, after any widgets registered
+// before it. It should be called from an init func, i.e. to show the most
+// requested API endpoints of the last day:
+//	func init() {
+//		addon.RegisterWidget(addon.Widget{
+//			Title: "Today's Top Endpoints",
+//			Template: `<ul>{{ range . }}<li>{{ .Endpoint }}: {{ .Count }}</li>{{ end }}</ul>`,
+//			Data: func(req *http.Request) (interface{}, error) {
+//				now := time.Now()
+//				return analytics.TopEndpoints(now.Add(-24*time.Hour), now, 5)
+//			},
+//		})
+//	}
+func RegisterWidget(w Widget) {
+	widgetsMu.Lock()
+	defer widgetsMu.Unlock()
+
+	if w.Width < 1 || w.Width > 12 {
+		w.Width = 6
+	}
+
+	widgets = append(widgets, w)
+}
+
+// Widgets returns the registered dashboard widgets which should be shown, in
+// the order they were registered
+func Widgets() []Widget {
+	widgetsMu.RLock()
+	defer widgetsMu.RUnlock()
+
+	var shown []Widget
+	for _, w := range widgets {
+		if w.Addon != "" && !enabled(w.Addon) {
+			continue
+		}
+
+		shown = append(shown, w)
+	}
+
+	return shown
+}
+
+// enabled reports whether the addon with the addon_reverse_dns key is enabled
+func enabled(key string) bool {
+	data, err := db.Addon(key)
+	if err != nil {
+		return false
+	}
+
+	return gjson.GetBytes(data, "addon_status").String() == StatusEnabled
+}
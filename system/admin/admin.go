@@ -8,11 +8,19 @@ import (
 	"fmt"
 	"html/template"
 	"net/http"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/ponzu-cms/ponzu/system/addon"
 	"github.com/ponzu-cms/ponzu/system/admin/user"
 	"github.com/ponzu-cms/ponzu/system/api/analytics"
 	"github.com/ponzu-cms/ponzu/system/db"
 	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/maintenance"
+	"github.com/ponzu-cms/ponzu/system/scheduler"
+	"github.com/ponzu-cms/ponzu/system/webhook"
 )
 
 var startAdminHTML = `<!doctype html>
@@ -57,22 +65,41 @@ var mainAdminHTML = `
                     <div class="card-title">Content</div>
                                     
                     {{ range $t, $f := .Types }}
+                    {{ if $.Role.Can $t "read" }}
                     <div class="row collection-item">
                         <li><a class="col s12" href="/admin/contents?type={{ $t }}"><i class="tiny left material-icons">playlist_add</i>{{ $t }}</a></li>
                     </div>
                     {{ end }}
+                    {{ end }}
+
+                    {{ if .Review }}
+                    <div class="row collection-item">
+                        <li><a class="col s12" href="/admin/review"><i class="tiny left material-icons">rate_review</i>Review Queue</a></li>
+                    </div>
+                    {{ end }}
 
                     <div class="card-title">System</div>                                
                     <div class="row collection-item">
-                        <li><a class="col s12" href="/admin/configure"><i class="tiny left material-icons">settings</i>Configuration</a></li>
-                        <li><a class="col s12" href="/admin/configure/users"><i class="tiny left material-icons">supervisor_account</i>Admin Users</a></li>
-                        <li><a class="col s12" href="/admin/addons"><i class="tiny left material-icons">settings_input_svideo</i>Addons</a></li>
+                        {{ if .Role.Manages "config" }}<li><a class="col s12" href="/admin/configure"><i class="tiny left material-icons">settings</i>Configuration</a></li>{{ end }}
+                        {{ if .User }}<li><a class="col s12" href="/admin/configure/users"><i class="tiny left material-icons">supervisor_account</i>{{ if .Role.Manages "users" }}Admin Users{{ else }}My Account{{ end }}</a></li>{{ end }}
+                        {{ if .Role.Manages "users" }}<li><a class="col s12" href="/admin/configure/roles"><i class="tiny left material-icons">lock</i>Roles</a></li>{{ end }}
+                        {{ if .Role.Manages "addons" }}<li><a class="col s12" href="/admin/addons"><i class="tiny left material-icons">settings_input_svideo</i>Addons</a></li>{{ end }}
+                        {{ if .Role.Manages "apikeys" }}<li><a class="col s12" href="/admin/apikeys"><i class="tiny left material-icons">vpn_key</i>API Keys</a></li>{{ end }}
+                        {{ if .Role.Manages "graphql" }}<li><a class="col s12" href="/admin/graphql"><i class="tiny left material-icons">code</i>GraphQL</a></li>{{ end }}
+                        {{ if .Role.Manages "webhooks" }}<li><a class="col s12" href="/admin/webhooks"><i class="tiny left material-icons">call_made</i>Webhooks</a></li>{{ end }}
+                        {{ if .Role.Manages "redirects" }}<li><a class="col s12" href="/admin/redirects"><i class="tiny left material-icons">call_split</i>Redirects</a></li>{{ end }}
+                        {{ if .Role.Manages "tags" }}<li><a class="col s12" href="/admin/tags"><i class="tiny left material-icons">label</i>Tags</a></li>{{ end }}
+                        {{ if .Role.Manages "trash" }}<li><a class="col s12" href="/admin/trash"><i class="tiny left material-icons">delete</i>Trash</a></li>{{ end }}
+                        {{ if .Role.Manages "audit" }}<li><a class="col s12" href="/admin/audit"><i class="tiny left material-icons">history</i>Audit Log</a></li>{{ end }}
                     </div>
                 </ul>
                 </div>
             </div>
             {{ if .Subview}}
             <div class="subview col s9">
+                {{ if .Maintenance }}
+                <div class="card-panel amber lighten-4">Maintenance mode is on: the public site and API respond with 503 Service Unavailable. {{ if .Role.Manages "config" }}Turn it off in the <a href="/admin/configure">Configuration</a>, or remove the maintenance.flag file.{{ end }}</div>
+                {{ end }}
                 {{ .Subview }}
             </div>
             {{ end }}`
@@ -88,13 +115,18 @@ var endAdminHTML = `
 </html>`
 
 type admin struct {
-	Logo    string
-	Types   map[string]func() interface{}
-	Subview template.HTML
+	Logo        string
+	Types       map[string]func() interface{}
+	Subview     template.HTML
+	User        bool
+	Role        user.Role
+	Review      bool
+	Maintenance bool
 }
 
-// Admin ...
-func Admin(view []byte) (_ []byte, err error) {
+// Admin renders view within the admin layout, with the navigation limited to
+// the sections the role of the user making req can access
+func Admin(req *http.Request, view []byte) (_ []byte, err error) {
 	cfg, err := db.Config("name")
 	if err != nil {
 		return
@@ -110,6 +142,14 @@ func Admin(view []byte) (_ []byte, err error) {
 		Subview: template.HTML(view),
 	}
 
+	// error pages may be shown before logging in, with no sections to access
+	role, roleErr := db.CurrentRole(req)
+	if roleErr == nil {
+		a.User, a.Role = true, role
+		a.Review = workflowEnabled() && canReview(role)
+		a.Maintenance = maintenance.On()
+	}
+
 	buf := &bytes.Buffer{}
 	html := startAdminHTML + mainAdminHTML + endAdminHTML
 	tmpl := template.Must(template.New("admin").Parse(html))
@@ -200,6 +240,7 @@ var loginAdminHTML = `
 <div class="card-content">
     <div class="card-title">Welcome!</div>
     <blockquote>Please log in to the system using your email address and password.</blockquote>
+    {{ if .Error }}<p class="red-text">{{ .Error }}</p>{{ end }}
     <form method="post" action="/admin/login" class="row">
         <div class="input-field col s12">
             <input placeholder="Enter your email address e.g. you@example.com" class="validate required" type="email" id="email" name="email"/>
@@ -210,6 +251,10 @@ var loginAdminHTML = `
             <a href="/admin/recover">Forgot password?</a>            
             <label for="password" class="active">Password</label>  
         </div>
+        <p class="col s12">
+            <input type="checkbox" class="filled-in" id="remember" name="remember" value="true"/>
+            <label for="remember">Remember me</label>
+        </p>
         <button class="btn waves-effect waves-light right">Log in</button>
     </form>
 </div>
@@ -222,8 +267,8 @@ var loginAdminHTML = `
 </script>
 `
 
-// Login ...
-func Login() ([]byte, error) {
+// Login returns the login view, showing message if it is not empty
+func Login(message string) ([]byte, error) {
 	html := startAdminHTML + loginAdminHTML + endAdminHTML
 
 	cfg, err := db.Config("name")
@@ -235,13 +280,74 @@ func Login() ([]byte, error) {
 		cfg = []byte("")
 	}
 
-	a := admin{
-		Logo: string(cfg),
+	data := struct {
+		admin
+		Error string
+	}{
+		admin: admin{Logo: string(cfg)},
+		Error: message,
 	}
 
 	buf := &bytes.Buffer{}
 	tmpl := template.Must(template.New("login").Parse(html))
-	err = tmpl.Execute(buf, a)
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var loginTwoFactorHTML = `
+<div class="init col s5">
+<div class="card">
+<div class="card-content">
+    <div class="card-title">Two-Factor Authentication</div>
+    <blockquote>Enter the 6-digit code from your authenticator app, or one of your recovery codes.</blockquote>
+    {{ if .Error }}<p class="red-text">{{ .Error }}</p>{{ end }}
+    <form method="post" action="/admin/login/2fa" class="row">
+        <div class="input-field col s12">
+            <input placeholder="e.g. 123456" class="validate required" type="text" id="code" name="code" autocomplete="one-time-code" autofocus/>
+            <label for="code" class="active">Code</label>
+        </div>
+        <a href="/admin/login">Log in as someone else</a>
+        <button class="btn waves-effect waves-light right">Verify</button>
+    </form>
+</div>
+</div>
+</div>
+<script>
+    $(function() {
+        $('.nav-wrapper ul.right').hide();
+    });
+</script>
+`
+
+// LoginTwoFactor returns the view asking a user who has given their password
+// for the code of their second factor, showing message if it is not empty
+func LoginTwoFactor(message string) ([]byte, error) {
+	html := startAdminHTML + loginTwoFactorHTML + endAdminHTML
+
+	cfg, err := db.Config("name")
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		cfg = []byte("")
+	}
+
+	data := struct {
+		admin
+		Error string
+	}{
+		admin: admin{Logo: string(cfg)},
+		Error: message,
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("login2fa").Parse(html))
+	err = tmpl.Execute(buf, data)
 	if err != nil {
 		return nil, err
 	}
@@ -254,16 +360,21 @@ var forgotPasswordHTML = `
 <div class="card">
 <div class="card-content">
     <div class="card-title">Account Recovery</div>
+    {{ if .Sent }}
+    <blockquote>If there is an account for the address you entered, a link to reset its password has been sent to it. Check your spam folder in case the message was flagged.</blockquote>
+    <a href="/admin/login">Back to login</a>
+    {{ else }}
     <blockquote>Please enter the email for your account and a recovery message will be sent to you at this address. Check your spam folder in case the message was flagged.</blockquote>
+    {{ if .Error }}<p class="red-text">{{ .Error }}</p>{{ end }}
     <form method="post" action="/admin/recover" class="row" enctype="multipart/form-data">
         <div class="input-field col s12">
             <input placeholder="Enter your email address e.g. you@example.com" class="validate required" type="email" id="email" name="email"/>
             <label for="email" class="active">Email</label>
         </div>
         
-        <a href="/admin/recover/key">Already have a recovery key?</a>
         <button class="btn waves-effect waves-light right">Send Recovery Email</button>
     </form>
+    {{ end }}
 </div>
 </div>
 </div>
@@ -274,8 +385,10 @@ var forgotPasswordHTML = `
 </script>
 `
 
-// ForgotPassword ...
-func ForgotPassword() ([]byte, error) {
+// ForgotPassword returns the view where a user requests a link to reset their
+// password, showing message if it is not empty. If sent is true the view says
+// the link has been sent instead.
+func ForgotPassword(sent bool, message string) ([]byte, error) {
 	html := startAdminHTML + forgotPasswordHTML + endAdminHTML
 
 	cfg, err := db.Config("name")
@@ -287,13 +400,19 @@ func ForgotPassword() ([]byte, error) {
 		cfg = []byte("")
 	}
 
-	a := admin{
-		Logo: string(cfg),
+	data := struct {
+		admin
+		Sent  bool
+		Error string
+	}{
+		admin: admin{Logo: string(cfg)},
+		Sent:  sent,
+		Error: message,
 	}
 
 	buf := &bytes.Buffer{}
 	tmpl := template.Must(template.New("forgotPassword").Parse(html))
-	err = tmpl.Execute(buf, a)
+	err = tmpl.Execute(buf, data)
 	if err != nil {
 		return nil, err
 	}
@@ -301,23 +420,83 @@ func ForgotPassword() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-var recoveryKeyHTML = `
+var acceptInviteHTML = `
 <div class="init col s5">
 <div class="card">
 <div class="card-content">
-    <div class="card-title">Account Recovery</div>
-    <blockquote>Please check for your recovery key inside an email sent to the address you provided. Check your spam folder in case the message was flagged.</blockquote>
-    <form method="post" action="/admin/recover/key" class="row" enctype="multipart/form-data">
+    <div class="card-title">Accept Invitation</div>
+    {{ if .Email }}
+    <blockquote>Choose a password to finish setting up your account, {{ .Email }}.</blockquote>
+    {{ if .Error }}<p class="red-text">{{ .Error }}</p>{{ end }}
+    <form method="post" action="/admin/invite" class="row" enctype="multipart/form-data">
+        <input type="hidden" name="token" value="{{ .Token }}"/>
         <div class="input-field col s12">
-            <input placeholder="Enter your recovery key" class="validate required" type="text" id="key" name="key"/>
-            <label for="key" class="active">Recovery Key</label>
+            <input placeholder="Enter your password" class="validate required" type="password" id="password" name="password"/>
+            <label for="password" class="active">Password</label>
         </div>
 
-        <div class="input-field col s12">
-            <input placeholder="Enter your email address e.g. you@example.com" class="validate required" type="email" id="email" name="email"/>
-            <label for="email" class="active">Email</label>
-        </div>
+        <button class="btn waves-effect waves-light right">Create Account</button>
+    </form>
+    {{ else }}
+    <blockquote>This invitation has expired or is no longer valid. Please ask for a new one.</blockquote>
+    {{ end }}
+</div>
+</div>
+</div>
+<script>
+    $(function() {
+        $('.nav-wrapper ul.right').hide();
+    });
+</script>
+`
+
+// AcceptInvite returns the view where the invitee of email sets their password
+// to accept the invitation sent with token, showing message if it is not empty.
+// If email is empty the view says the invitation is no longer valid.
+func AcceptInvite(token, email, message string) ([]byte, error) {
+	html := startAdminHTML + acceptInviteHTML + endAdminHTML
+
+	cfg, err := db.Config("name")
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		cfg = []byte("")
+	}
+
+	data := struct {
+		admin
+		Token string
+		Email string
+		Error string
+	}{
+		admin: admin{Logo: string(cfg)},
+		Token: token,
+		Email: email,
+		Error: message,
+	}
 
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("acceptInvite").Parse(html))
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var recoveryKeyHTML = `
+<div class="init col s5">
+<div class="card">
+<div class="card-content">
+    <div class="card-title">Account Recovery</div>
+    {{ if .Token }}
+    <blockquote>Please choose a new password for your account.</blockquote>
+    {{ if .Error }}<p class="red-text">{{ .Error }}</p>{{ end }}
+    <form method="post" action="/admin/recover/key" class="row" enctype="multipart/form-data">
+        <input type="hidden" name="token" value="{{ .Token }}"/>
         <div class="input-field col s12">
             <input placeholder="Enter your password" class="validate required" type="password" id="password" name="password"/>
             <label for="password" class="active">New Password</label>
@@ -325,6 +504,10 @@ var recoveryKeyHTML = `
         
         <button class="btn waves-effect waves-light right">Update Account</button>
     </form>
+    {{ else }}
+    <blockquote>This password reset link has expired or has already been used. Reset links also stop working once a newer one is sent, or the password is changed.</blockquote>
+    <a href="/admin/recover">Request a new link</a>
+    {{ end }}
 </div>
 </div>
 </div>
@@ -335,8 +518,10 @@ var recoveryKeyHTML = `
 </script>
 `
 
-// RecoveryKey ...
-func RecoveryKey() ([]byte, error) {
+// RecoveryKey returns the view where a user sets a new password with the reset
+// token they were emailed, showing message if it is not empty. If token is
+// empty the view says the reset link is no longer valid.
+func RecoveryKey(token, message string) ([]byte, error) {
 	html := startAdminHTML + recoveryKeyHTML + endAdminHTML
 
 	cfg, err := db.Config("name")
@@ -348,13 +533,19 @@ func RecoveryKey() ([]byte, error) {
 		cfg = []byte("")
 	}
 
-	a := admin{
-		Logo: string(cfg),
+	data := struct {
+		admin
+		Token string
+		Error string
+	}{
+		admin: admin{Logo: string(cfg)},
+		Token: token,
+		Error: message,
 	}
 
 	buf := &bytes.Buffer{}
 	tmpl := template.Must(template.New("recoveryKey").Parse(html))
-	err = tmpl.Execute(buf, a)
+	err = tmpl.Execute(buf, data)
 	if err != nil {
 		return nil, err
 	}
@@ -390,6 +581,21 @@ func UsersList(req *http.Request) ([]byte, error) {
             </div>
         </form>
 
+        <div class="row">
+            <div class="col s9">
+                Two-factor authentication: {{ if .User.HasTOTP }}enabled{{ else }}disabled{{ end }}
+                <a class="right" href="/admin/configure/2fa">Manage</a>
+            </div>
+        </div>
+
+        <div class="row">
+            <div class="col s9">
+                Active sessions
+                <a class="right" href="/admin/configure/users/sessions?email={{ .User.Email }}">Manage</a>
+            </div>
+        </div>
+
+        {{ if .Manage }}
         <div class="card-title">Add a new user:</div>        
         <form class="row" enctype="multipart/form-data" action="/admin/configure/users" method="post">
             <div class="input-feild col s9">
@@ -402,24 +608,84 @@ func UsersList(req *http.Request) ([]byte, error) {
                 <input type="password" name="password"/>
             </div>
 
+            <div class="input-feild col s9">
+                <label class="active">Role</label>
+                <select class="browser-default" name="role">
+                    <option value="{{ $.SuperAdmin }}">{{ $.SuperAdmin }}</option>
+                    {{ range .Roles }}<option value="{{ .Name }}">{{ .Name }}</option>{{ end }}
+                </select>
+            </div>
+
             <div class="input-feild col s9">            
                 <button class="btn waves-effect waves-light green right" type="submit">Add User</button>
             </div>   
         </form>        
 
-        <div class="card-title">Remove Admin Users</div>        
+        <div class="card-title">Invite a new user:</div>
+        <form class="row" enctype="multipart/form-data" action="/admin/configure/users/invite" method="post">
+            <div class="input-feild col s9">
+                <label class="active">Email Address</label>
+                <input type="email" name="email" value=""/>
+            </div>
+
+            <div class="input-feild col s9">
+                <label class="active">Role</label>
+                <select class="browser-default" name="role">
+                    <option value="{{ $.SuperAdmin }}">{{ $.SuperAdmin }}</option>
+                    {{ range .Roles }}<option value="{{ .Name }}">{{ .Name }}</option>{{ end }}
+                </select>
+            </div>
+
+            <div class="input-feild col s9">
+                <button class="btn waves-effect waves-light green right" type="submit">Send Invitation</button>
+            </div>
+        </form>
+
+        {{ if .Invites }}
+        <div class="card-title">Pending Invitations</div>
+        <ul class="invites row">
+            {{ range .Invites }}
+            <li class="col s9">
+                {{ .Email }}
+                <span class="grey-text">{{ if .Role }}{{ .Role }}{{ else }}{{ $.SuperAdmin }}{{ end }}, invited by {{ .InvitedBy }},
+                {{ if .Expired }}expired{{ else }}expires {{ .ExpiresAt }}{{ end }}</span>
+                <form enctype="multipart/form-data" class="revoke-invite __ponzu right" action="/admin/configure/users/invite/revoke" method="post">
+                    <span>Revoke</span>
+                    <input type="hidden" name="email" value="{{ .Email }}"/>
+                </form>
+                <form enctype="multipart/form-data" class="resend-invite __ponzu right" action="/admin/configure/users/invite" method="post">
+                    <span>Resend</span>
+                    <input type="hidden" name="email" value="{{ .Email }}"/>
+                    <input type="hidden" name="role" value="{{ if .Role }}{{ .Role }}{{ else }}{{ $.SuperAdmin }}{{ end }}"/>
+                </form>
+            </li>
+            {{ end }}
+        </ul>
+        {{ end }}
+
+        <div class="card-title">Manage Admin Users</div>        
         <ul class="users row">
             {{ range .Users }}
             <li class="col s9">
                 {{ .Email }}
+                <a class="right" href="/admin/configure/users/sessions?email={{ .Email }}">Sessions</a>
                 <form enctype="multipart/form-data" class="delete-user __ponzu right" action="/admin/configure/users/delete" method="post">
                     <span>Delete</span>
                     <input type="hidden" name="email" value="{{ .Email }}"/>
                     <input type="hidden" name="id" value="{{ .ID }}"/>
                 </form>
+                <form enctype="multipart/form-data" class="user-role __ponzu right" action="/admin/configure/users/role" method="post">
+                    <input type="hidden" name="email" value="{{ .Email }}"/>
+                    <select class="browser-default" name="role">
+                        <option value="{{ $.SuperAdmin }}">{{ $.SuperAdmin }}</option>
+                        {{ $role := .Role }}
+                        {{ range $.Roles }}<option value="{{ .Name }}"{{ if eq .Name $role }} selected{{ end }}>{{ .Name }}</option>{{ end }}
+                    </select>
+                </form>
             </li>
             {{ end }}
         </ul>
+        {{ end }}
     </div>
     `
 	script := `
@@ -431,6 +697,14 @@ func UsersList(req *http.Request) ([]byte, error) {
                     $(e.target).parent().submit();
                 }
             });
+
+            $('.user-role.__ponzu select').on('change', function(e) {
+                $(e.target).parent().submit();
+            });
+
+            $('.revoke-invite.__ponzu span, .resend-invite.__ponzu span').on('click', function(e) {
+                $(e.target).parent().submit();
+            });
         });
     </script>
     `
@@ -465,11 +739,41 @@ func UsersList(req *http.Request) ([]byte, error) {
 	}
 
 	// make buffer to execute html into then pass buffer's bytes to Admin
+	role, err := db.CurrentRole(req)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := db.Roles()
+	if err != nil {
+		return nil, err
+	}
+
+	invites, err := db.Invites()
+	if err != nil {
+		return nil, err
+	}
+
+	type inviteView struct {
+		db.Invite
+		ExpiresAt string
+	}
+
+	var pending []inviteView
+	for _, inv := range invites {
+		exp := time.Unix(0, inv.Expires*int64(time.Millisecond))
+		pending = append(pending, inviteView{Invite: inv, ExpiresAt: exp.Format("Jan 2, 2006 3:04 PM")})
+	}
+
 	buf := &bytes.Buffer{}
 	tmpl := template.Must(template.New("users").Parse(html + script))
 	data := map[string]interface{}{
-		"User":  usr,
-		"Users": usrs,
+		"User":       usr,
+		"Users":      usrs,
+		"Manage":     role.Manages(user.SectionUsers),
+		"Roles":      roles,
+		"Invites":    pending,
+		"SuperAdmin": user.SuperAdminRole,
 	}
 
 	err = tmpl.Execute(buf, data)
@@ -477,120 +781,1816 @@ func UsersList(req *http.Request) ([]byte, error) {
 		return nil, err
 	}
 
-	return Admin(buf.Bytes())
+	return Admin(req, buf.Bytes())
 }
 
-var analyticsHTML = `
-<div class="analytics">
-<div class="card">
+var sessionsHTML = `
+<div class="card sessions">
 <div class="card-content">
-    <p class="right">Data range: {{ .from }} - {{ .to }} (UTC)</p>
-    <div class="card-title">API Requests</div>
-    <canvas id="analytics-chart"></canvas>
-    <script>
-    var target = document.getElementById("analytics-chart");
-    Chart.defaults.global.defaultFontColor = '#212121';
-    Chart.defaults.global.defaultFontFamily = "'Roboto', 'Helvetica Neue', 'Helvetica', 'Arial', 'sans-serif'";
-    Chart.defaults.global.title.position = 'right';
-    var chart = new Chart(target, {
-        type: 'bar',
-        data: {
-            labels: [{{ range $date := .dates }} "{{ $date }}",  {{ end }}],
-            datasets: [{
-                type: 'line',
-                label: 'Unique Clients',
-                data: $.parseJSON({{ .unique }}),
-                backgroundColor: 'rgba(76, 175, 80, 0.2)',
-                borderColor: 'rgba(76, 175, 80, 1)',
-                borderWidth: 1
-            },
-            {
-                type: 'bar',
-                label: 'Total Requests',
-                data: $.parseJSON({{ .total }}),
-                backgroundColor: 'rgba(33, 150, 243, 0.2)',
-                borderColor: 'rgba(33, 150, 243, 1)',
-                borderWidth: 1
-            }]
-        },
-        options: {
-            scales: {
-                yAxes: [{
-                    ticks: {
-                        beginAtZero:true
-                    }
-                }]
-            }
-        }
-    });
-    </script>
-</div>
+    <div class="card-title">Sessions of {{ .Email }}</div>
+    {{ if .Sessions }}
+    <table class="striped">
+        <thead>
+            <tr><th>Logged In</th><th>Last Used</th><th>Expires</th><th>IP</th><th>Browser</th><th></th></tr>
+        </thead>
+        <tbody>
+        {{ range .Sessions }}
+        <tr>
+            <td>{{ .Created }}</td>
+            <td>{{ .LastSeen }}</td>
+            <td>{{ .Expires }}{{ if .Remember }} (remembered){{ end }}</td>
+            <td>{{ .IP }}</td>
+            <td>{{ .UserAgent }}</td>
+            <td>
+                {{ if .Current }}Current session{{ else }}
+                <form enctype="multipart/form-data" action="/admin/configure/users/sessions?email={{ $.Email }}" method="post">
+                    <input type="hidden" name="email" value="{{ $.Email }}"/>
+                    <input type="hidden" name="id" value="{{ .ID }}"/>
+                    <button class="btn-flat waves-effect waves-light red-text" type="submit">Revoke</button>
+                </form>
+                {{ end }}
+            </td>
+        </tr>
+        {{ end }}
+        </tbody>
+    </table>
+    <form class="row" enctype="multipart/form-data" action="/admin/configure/users/sessions?email={{ .Email }}" method="post">
+        <input type="hidden" name="email" value="{{ .Email }}"/>
+        <input type="hidden" name="id" value="all"/>
+        <button class="btn waves-effect waves-light red right" type="submit">Revoke All{{ if .Own }} Other{{ end }} Sessions</button>
+    </form>
+    {{ else }}
+    <p>There are no active sessions.</p>
+    {{ end }}
 </div>
 </div>
 `
 
-// Dashboard returns the admin view with analytics dashboard
-func Dashboard() ([]byte, error) {
+// Sessions returns the admin view of the active sessions of the user with
+// email, each with a button to revoke it. The session of the request is marked
+// as the current session.
+func Sessions(req *http.Request, email string, sessions []db.Session) ([]byte, error) {
+	type sessionView struct {
+		ID        string
+		Created   string
+		LastSeen  string
+		Expires   string
+		Remember  bool
+		IP        string
+		UserAgent string
+		Current   bool
+	}
+
+	cur, _ := db.CurrentSession(req)
+	format := func(ms int64) string {
+		return time.Unix(0, ms*int64(time.Millisecond)).Format("Jan 2, 2006 3:04 PM")
+	}
+
+	var list []sessionView
+	for _, s := range sessions {
+		list = append(list, sessionView{
+			ID:        s.ID,
+			Created:   format(s.Created),
+			LastSeen:  format(s.LastSeen),
+			Expires:   format(s.Expires),
+			Remember:  s.Remember,
+			IP:        s.IP,
+			UserAgent: s.UserAgent,
+			Current:   s.ID == cur.ID,
+		})
+	}
+
 	buf := &bytes.Buffer{}
-	data, err := analytics.ChartData()
-	if err != nil {
-		return nil, err
+	tmpl := template.Must(template.New("sessions").Parse(sessionsHTML))
+	data := map[string]interface{}{
+		"Email":    email,
+		"Own":      email == cur.User,
+		"Sessions": list,
 	}
 
-	tmpl := template.Must(template.New("analytics").Parse(analyticsHTML))
-	err = tmpl.Execute(buf, data)
+	err := tmpl.Execute(buf, data)
 	if err != nil {
 		return nil, err
 	}
-	return Admin(buf.Bytes())
+
+	return Admin(req, buf.Bytes())
 }
 
-var err400HTML = []byte(`
-<div class="error-page e400 col s6">
-<div class="card">
+var twoFactorHTML = `
+<div class="card two-factor">
 <div class="card-content">
-    <div class="card-title"><b>400</b> Error: Bad Request</div>
-    <blockquote>Sorry, the request was unable to be completed.</blockquote>
-</div>
+    <div class="card-title">Two-Factor Authentication</div>
+    {{ if .Error }}<p class="red-text">{{ .Error }}</p>{{ end }}
+    {{ if .Codes }}
+    <blockquote>Two-factor authentication is now enabled. Save these recovery codes
+    somewhere safe: each can be used once to log in if you lose access to your
+    authenticator app, and they will not be shown again.</blockquote>
+    <ul class="recovery-codes row">
+        {{ range .Codes }}<li class="col s6"><code>{{ . }}</code></li>{{ end }}
+    </ul>
+    <a class="btn waves-effect waves-light" href="/admin">Continue</a>
+    {{ else if .Enabled }}
+    <blockquote>Two-factor authentication is enabled for your account. You have
+    {{ .RecoveryCodes }} unused recovery codes.</blockquote>
+    {{ if .Required }}
+    <p>Two-factor authentication is required for all users, so it can't be disabled.</p>
+    {{ else }}
+    <div class="card-title">Disable two-factor authentication:</div>
+    <form class="row" enctype="multipart/form-data" action="/admin/configure/2fa" method="post">
+        <input type="hidden" name="action" value="disable"/>
+        <div class="input-field col s6">
+            <label class="active">Current Password</label>
+            <input type="password" name="password" required/>
+        </div>
+        <div class="input-field col s6">
+            <label class="active">Code</label>
+            <input type="text" name="code" autocomplete="one-time-code" required/>
+        </div>
+        <div class="input-field col s12">
+            <button class="btn waves-effect waves-light red right" type="submit">Disable</button>
+        </div>
+    </form>
+    {{ end }}
+    {{ else }}
+    {{ if .Required }}<p>Two-factor authentication is required for all users. Please enroll to continue.</p>{{ end }}
+    <blockquote>Scan the QR code with an authenticator app, or enter the key below
+    into it, then enter the 6-digit code it shows to enable two-factor
+    authentication.</blockquote>
+    <div class="row">
+        <div class="col s12 m5"><div class="totp-qr" data-url="{{ .URL }}"></div></div>
+        <div class="col s12 m7">
+            <label class="active">Key</label>
+            <p><code>{{ .Secret }}</code></p>
+            <form enctype="multipart/form-data" action="/admin/configure/2fa" method="post">
+                <input type="hidden" name="action" value="enroll"/>
+                <input type="hidden" name="secret" value="{{ .Encrypted }}"/>
+                <div class="input-field">
+                    <label class="active">Code</label>
+                    <input type="text" name="code" placeholder="e.g. 123456" autocomplete="one-time-code" required/>
+                </div>
+                <button class="btn waves-effect waves-light green right" type="submit">Enable</button>
+            </form>
+        </div>
+    </div>
+    <script src="https://cdnjs.cloudflare.com/ajax/libs/qrcodejs/1.0.0/qrcode.min.js"></script>
+    <script>
+        $(function() {
+            var qr = $('.totp-qr');
+            if (typeof QRCode === 'undefined') {
+                return;
+            }
+
+            new QRCode(qr[0], {text: qr.data('url'), width: 200, height: 200});
+        });
+    </script>
+    {{ end }}
 </div>
 </div>
-`)
+`
 
-// Error400 creates a subview for a 400 error page
-func Error400() ([]byte, error) {
-	return Admin(err400HTML)
+// twoFactorView is the state of a user's enrollment in two-factor
+// authentication. Secret, URL and Encrypted are the new secret to enroll with,
+// and Codes the recovery codes just generated for them.
+type twoFactorView struct {
+	Enabled       bool
+	Required      bool
+	RecoveryCodes int
+	Secret        string
+	URL           string
+	Encrypted     string
+	Codes         []string
+	Error         string
 }
 
-var err404HTML = []byte(`
-<div class="error-page e404 col s6">
-<div class="card">
-<div class="card-content">
-    <div class="card-title"><b>404</b> Error: Not Found</div>
-    <blockquote>Sorry, the page you requested could not be found.</blockquote>
-</div>
-</div>
-</div>
-`)
+// TwoFactor returns the admin view to enroll in, or disable, two-factor
+// authentication
+func TwoFactor(req *http.Request, v twoFactorView) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("2fa").Parse(twoFactorHTML))
+	err := tmpl.Execute(buf, v)
+	if err != nil {
+		return nil, err
+	}
 
-// Error404 creates a subview for a 404 error page
-func Error404() ([]byte, error) {
-	return Admin(err404HTML)
+	return Admin(req, buf.Bytes())
 }
 
-var err405HTML = []byte(`
-<div class="error-page e405 col s6">
-<div class="card">
+var rolesHTML = `
+<div class="card roles">
 <div class="card-content">
-    <div class="card-title"><b>405</b> Error: Method Not Allowed</div>
-    <blockquote>Sorry, the method of your request is not allowed.</blockquote>
-</div>
-</div>
-</div>
-`)
-
+    <div class="card-title">Roles</div>
+    <blockquote>Users with the {{ .SuperAdmin }} role, or no role, can do everything.
+    Users with any other role can only perform the actions checked for each content
+    type, and manage the sections checked.</blockquote>
+    {{ range .Roles }}
+    <form class="row role" enctype="multipart/form-data" action="/admin/configure/roles" method="post">
+        <div class="input-field col s12">
+            {{ if .New }}
+            <label class="active">New Role</label>
+            <input type="text" name="name" placeholder="e.g. editor" required/>
+            {{ else }}
+            <label class="active">Role</label>
+            <input type="text" name="name" value="{{ .Name }}" readonly/>
+            {{ end }}
+        </div>
+        <table class="col s12">
+            <thead>
+                <tr><th>Content Type</th>{{ range $.Actions }}<th>{{ . }}</th>{{ end }}</tr>
+            </thead>
+            <tbody>
+                {{ range .Rows }}
+                <tr>
+                    <td>{{ .Label }}</td>
+                    {{ $t := .Type }}
+                    {{ range .Checks }}
+                    <td><label><input type="checkbox" class="filled-in" name="perm.{{ $t }}" value="{{ .Name }}"{{ if .Checked }} checked{{ end }}/><span></span></label></td>
+                    {{ end }}
+                </tr>
+                {{ end }}
+            </tbody>
+        </table>
+        <div class="col s12">
+            <label class="active">Sections</label>
+            {{ range .Sections }}
+            <label><input type="checkbox" class="filled-in" name="section" value="{{ .Name }}"{{ if .Checked }} checked{{ end }}/><span>{{ .Name }}</span></label>
+            {{ end }}
+        </div>
+        <div class="col s12 input-field">
+            <button class="btn waves-effect waves-light green right" type="submit">{{ if .New }}Add{{ else }}Save{{ end }} Role</button>
+            {{ if not .New }}<button class="btn-flat red-text right delete-role" type="submit" formaction="/admin/configure/roles/delete">Delete</button>{{ end }}
+        </div>
+    </form>
+    {{ end }}
+</div>
+</div>
+<script>
+    $(function() {
+        $('.roles .delete-role').on('click', function(e) {
+            if (!confirm("[Ponzu] Please confirm:\n\nAre you sure you want to delete this role?\nUsers with the role will have no access until given another.")) {
+                e.preventDefault();
+            }
+        });
+    });
+</script>
+`
+
+type roleCheck struct {
+	Name    string
+	Checked bool
+}
+
+type rolePermissions struct {
+	Type   string
+	Label  string
+	Checks []roleCheck
+}
+
+type roleView struct {
+	Name     string
+	New      bool
+	Rows     []rolePermissions
+	Sections []roleCheck
+}
+
+// newRoleView returns the form to edit role r, or to add a new role if r has
+// no name
+func newRoleView(r user.Role) roleView {
+	v := roleView{Name: r.Name, New: r.Name == ""}
+
+	types := []string{"*"}
+	for t := range item.Types {
+		types = append(types, t)
+	}
+	sort.Strings(types[1:])
+
+	for _, t := range types {
+		row := rolePermissions{Type: t, Label: t}
+		if t == "*" {
+			row.Label = "All content types"
+		}
+
+		for _, a := range user.Actions {
+			checked := false
+			for _, allowed := range r.Permissions[t] {
+				checked = checked || allowed == a
+			}
+
+			row.Checks = append(row.Checks, roleCheck{Name: string(a), Checked: checked})
+		}
+
+		v.Rows = append(v.Rows, row)
+	}
+
+	for _, sec := range user.Sections {
+		v.Sections = append(v.Sections, roleCheck{Name: sec, Checked: r.Manages(sec)})
+	}
+
+	return v
+}
+
+// Roles returns the admin view to add, edit and delete roles
+func Roles(req *http.Request) ([]byte, error) {
+	roles, err := db.Roles()
+	if err != nil {
+		return nil, err
+	}
+
+	var views []roleView
+	for _, r := range roles {
+		views = append(views, newRoleView(r))
+	}
+	views = append(views, newRoleView(user.Role{}))
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("roles").Parse(rolesHTML))
+	err = tmpl.Execute(buf, map[string]interface{}{
+		"Roles":      views,
+		"Actions":    user.Actions,
+		"SuperAdmin": user.SuperAdminRole,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+var historyHTML = `
+<div class="card history">
+<div class="card-content">
+    <div class="card-title">History</div>
+    {{ if .Revisions }}
+    <ul class="revisions row">
+        {{ range .Revisions }}
+        <li class="col s12">
+            {{ .Saved }} <span class="grey-text">{{ .Slug }}</span>
+            <form enctype="multipart/form-data" class="restore-revision __ponzu right" action="/admin/edit/restore" method="post">
+                <span>Restore</span>
+                <input type="hidden" name="type" value="{{ $.Type }}"/>
+                <input type="hidden" name="id" value="{{ $.ID }}"/>
+                <input type="hidden" name="revision" value="{{ .Timestamp }}"/>
+            </form>
+        </li>
+        {{ end }}
+    </ul>
+    {{ else }}
+    <p>No previous revisions have been saved.</p>
+    {{ end }}
+</div>
+</div>
+<script>
+    $(function() {
+        var restore = $('.restore-revision.__ponzu span');
+        restore.on('click', function(e) {
+            if (confirm("[Ponzu] Please confirm:\n\nAre you sure you want to restore this revision?\nThe current content will be kept in its history.")) {
+                $(e.target).parent().submit();
+            }
+        });
+    });
+</script>
+`
+
+// History returns a view listing the saved revisions of the content item
+// t:id, each with a button to restore it. Unlike most views, it is not wrapped
+// with Admin so that it can be added to the editor.
+func History(t, id string) ([]byte, error) {
+	revisions, err := db.Revisions(t, id)
+	if err != nil {
+		return nil, err
+	}
+
+	type revision struct {
+		Timestamp int64
+		Saved     string
+		Slug      string
+	}
+
+	var revs []revision
+	for i := range revisions {
+		var data struct {
+			Slug string `json:"slug"`
+		}
+		json.Unmarshal(revisions[i].Data, &data)
+
+		revs = append(revs, revision{
+			Timestamp: revisions[i].Timestamp,
+			Saved:     revisions[i].Time().Format("Jan 2, 2006 3:04:05 PM"),
+			Slug:      data.Slug,
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("history").Parse(historyHTML))
+	data := map[string]interface{}{
+		"Type":      t,
+		"ID":        id,
+		"Revisions": revs,
+	}
+
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var workflowHTML = `
+<div class="card workflow">
+<div class="card-content">
+    <div class="card-title">Workflow</div>
+    <p>This content is <b>{{ .State }}</b>.</p>
+    <div class="row workflow-actions __ponzu">
+        {{ if ne .State "draft" }}{{ if or .CanPublish (eq .State "pending-review") }}<button class="btn grey darken-2" data-state="draft">Save as Draft</button>{{ end }}{{ end }}
+        {{ if ne .State "pending-review" }}{{ if or .CanPublish (ne .State "published") }}<button class="btn blue" data-state="pending-review">Submit for Review</button>{{ end }}{{ end }}
+        {{ if .CanPublish }}{{ if ne .State "published" }}<button class="btn green" data-state="published">Publish</button>{{ end }}{{ end }}
+    </div>
+    {{ if .History }}
+    <ul class="state-history row">
+        {{ range .History }}
+        <li class="col s12">{{ .State }} <span class="grey-text">by {{ .User }}, {{ .When }}</span></li>
+        {{ end }}
+    </ul>
+    {{ end }}
+</div>
+</div>
+<script>
+    $(function() {
+        var form = $('form[action="/admin/edit"]');
+        $('.workflow-actions.__ponzu button').on('click', function(e) {
+            e.preventDefault();
+            form.find('input[name=workflow]').remove();
+            form.append($('<input type="hidden" name="workflow"/>').val($(e.target).data('state')));
+            form.submit();
+        });
+    });
+</script>
+`
+
+// Workflow returns a view of the workflow state of content and the history of
+// who moved it to each state, with buttons to move it to the states canPublish
+// allows. Unlike most views, it is not wrapped with Admin so that it
+// can be added to the editor.
+func Workflow(state string, history []item.StateChange, canPublish bool) ([]byte, error) {
+	type change struct {
+		State string
+		User  string
+		When  string
+	}
+
+	var changes []change
+	for i := len(history) - 1; i >= 0; i-- {
+		changes = append(changes, change{
+			State: history[i].State,
+			User:  history[i].User,
+			When:  time.Unix(0, history[i].Time*int64(time.Millisecond)).Format("Jan 2, 2006 3:04 PM"),
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("workflow").Parse(workflowHTML))
+	data := map[string]interface{}{
+		"State":      state,
+		"History":    changes,
+		"CanPublish": canPublish,
+	}
+
+	err := tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var translationsHTML = `
+<div class="translations __ponzu" style="display:none;">
+    {{ range $i, $t := .Translations }}
+    <div class="input-field col s12 translation" data-field="{{ $t.Field }}" data-locale="{{ $t.Locale }}">
+        <input type="hidden" name="translations.{{ $i }}.locale" value="{{ $t.Locale }}"/>
+        <input type="hidden" name="translations.{{ $i }}.field" value="{{ $t.Field }}"/>
+        {{ if $t.Editable }}
+        <label class="active">{{ $t.Field }} ({{ $t.Locale }})</label>
+        <textarea class="materialize-textarea" name="translations.{{ $i }}.value">{{ $t.Value }}</textarea>
+        {{ else }}
+        <input type="hidden" name="translations.{{ $i }}.value" value="{{ $t.Value }}"/>
+        {{ end }}
+    </div>
+    {{ end }}
+</div>
+<script>
+    $(function() {
+        var form = $('form[action="/admin/edit"]');
+        var def = {{ .Default }};
+        var locales = {{ .Locales }};
+        var translations = $('.translations.__ponzu .translation');
+
+        // translations into locales no longer configured are kept as they are
+        translations.filter(function() {
+            return locales.indexOf($(this).data('locale')) < 0;
+        }).hide().appendTo(form);
+
+        $.each({{ .Fields }}, function(i, field) {
+            var input = form.find('[name="' + field + '"]').first();
+            if (!input.length) {
+                return;
+            }
+
+            var container = input.closest('.input-field, .col');
+            var fieldTranslations = translations.filter(function() {
+                return $(this).data('field') === field && locales.indexOf($(this).data('locale')) >= 0;
+            }).hide().insertAfter(container);
+
+            var switcher = $('<select class="browser-default locale-switcher"></select>');
+            $.each([def].concat(locales), function(j, locale) {
+                switcher.append($('<option></option>').val(locale).text(locale));
+            });
+            switcher.insertBefore(container);
+
+            switcher.on('change', function() {
+                var locale = switcher.val();
+                container.toggle(locale === def);
+                fieldTranslations.each(function() {
+                    $(this).toggle($(this).data('locale') === locale);
+                });
+            });
+        });
+    });
+</script>
+`
+
+// Translations returns the inputs for the translations of the fields of
+// Localizable content into each of the other locales, with a switcher placed
+// before each field in the editor to choose which locale is edited.
+// Translations into locales no longer configured are kept hidden, so saving
+// the content doesn't lose them. Unlike most views, it is not wrapped with
+// Admin so that it can be added to the editor.
+func Translations(fields []string, translations []item.Translation) ([]byte, error) {
+	type translation struct {
+		item.Translation
+		Editable bool
+	}
+
+	def, others := db.Locales()
+	configured := make(map[string]bool)
+	for _, l := range others {
+		configured[l] = true
+	}
+
+	existing := make(map[string]string)
+	var entries []translation
+	for _, t := range translations {
+		if configured[t.Locale] {
+			existing[t.Locale+":"+t.Field] = t.Value
+			continue
+		}
+
+		entries = append(entries, translation{Translation: t})
+	}
+
+	for _, f := range fields {
+		for _, l := range others {
+			entries = append(entries, translation{
+				Translation: item.Translation{Locale: l, Field: f, Value: existing[l+":"+f]},
+				Editable:    true,
+			})
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("translations").Parse(translationsHTML))
+	data := map[string]interface{}{
+		"Default":      def,
+		"Locales":      append([]string{}, others...),
+		"Fields":       fields,
+		"Translations": entries,
+	}
+
+	err := tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var editorErrorsHTML = `
+<div class="card editor-errors">
+<div class="card-content">
+    <div class="card-title red-text">The content was not saved</div>
+    <p>Please correct the fields below and save again.</p>
+    <ul class="row">
+        {{ range $field, $msg := .Fields }}
+        <li class="col s12"><b>{{ $field }}</b>: {{ $msg }}</li>
+        {{ end }}
+    </ul>
+</div>
+</div>
+<script>
+    $(function() {
+        var errs = {{ .Fields }};
+        $.each(errs, function(field, msg) {
+            var input = $('form[action="/admin/edit"] [name="' + field + '"], form[action="/admin/edit"] [name^="' + field + '."]').first();
+            if (input.length === 0) {
+                return;
+            }
+
+            input.addClass('invalid');
+            input.closest('.input-field, .row').append($('<span class="red-text validation-error"></span>').text(msg));
+        });
+    });
+</script>
+`
+
+// EditorErrors returns a view listing the problems which kept content from
+// being saved, keyed by field name, which also marks each field inline. Unlike
+// most views, it is not wrapped with Admin so that it can be added to the
+// editor.
+func EditorErrors(fields map[string]string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("editorErrors").Parse(editorErrorsHTML))
+	err := tmpl.Execute(buf, map[string]interface{}{
+		"Fields": fields,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var reviewHTML = `
+<div class="col s9 card review">
+<div class="card-content">
+    <div class="card-title">Review Queue</div>
+    {{ if .Items }}
+    <table class="striped">
+        <thead>
+            <tr><th>Content</th><th>Type</th><th>Submitted By</th><th>Submitted</th></tr>
+        </thead>
+        <tbody>
+            {{ range .Items }}
+            <tr>
+                <td><a href="/admin/edit?type={{ .Type }}&id={{ .ID }}">{{ .Title }}</a></td>
+                <td>{{ .Type }}</td>
+                <td>{{ .By }}</td>
+                <td>{{ .Submitted }}</td>
+            </tr>
+            {{ end }}
+        </tbody>
+    </table>
+    {{ else }}
+    <p>No content is waiting for review.</p>
+    {{ end }}
+</div>
+</div>
+`
+
+// ReviewQueue returns the view listing content pending review which the role
+// of the user making req allows publishing
+func ReviewQueue(req *http.Request) ([]byte, error) {
+	role, err := db.CurrentRole(req)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("review").Parse(reviewHTML))
+	err = tmpl.Execute(buf, map[string]interface{}{
+		"Items": reviewQueue(role),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+var auditHTML = `
+<div class="col s9 card audit">
+<div class="card-content">
+    <div class="card-title">Audit Log</div>
+    <p>Actions taken in the admin are kept here for {{ .Days }} days.</p>
+    <form class="row" action="/admin/audit" method="get">
+        <div class="input-field col s3">
+            <input id="audit-user" type="text" name="user" value="{{ .Query.User }}"/>
+            <label for="audit-user" class="active">User</label>
+        </div>
+        <div class="input-field col s3">
+            <input id="audit-action" type="text" name="action" value="{{ .Query.Action }}" placeholder="e.g. content or user.login"/>
+            <label for="audit-action" class="active">Action</label>
+        </div>
+        <div class="input-field col s2">
+            <input id="audit-target" type="text" name="target" value="{{ .Query.Target }}"/>
+            <label for="audit-target" class="active">Target</label>
+        </div>
+        <div class="input-field col s2">
+            <input id="audit-since" type="date" name="since" value="{{ .Since }}"/>
+            <label for="audit-since" class="active">From</label>
+        </div>
+        <div class="input-field col s2">
+            <input id="audit-until" type="date" name="until" value="{{ .Until }}"/>
+            <label for="audit-until" class="active">To</label>
+        </div>
+        <button class="btn waves-effect waves-light right" type="submit">Filter</button>
+    </form>
+    {{ if .Entries }}
+    <table class="striped">
+        <thead>
+            <tr><th>Time</th><th>User</th><th>Action</th><th>Target</th><th>IP</th><th>Details</th></tr>
+        </thead>
+        <tbody>
+        {{ range .Entries }}
+        <tr>
+            <td>{{ .Time.Format "Jan 2, 2006 3:04:05 PM" }}</td>
+            <td>{{ .User }}</td>
+            <td>{{ .Action }}</td>
+            <td>{{ .Target }}</td>
+            <td>{{ .IP }}</td>
+            <td>{{ range $k, $v := .Details }}<span class="grey-text">{{ $k }}:</span> {{ $v }}<br/>{{ end }}</td>
+        </tr>
+        {{ end }}
+        </tbody>
+    </table>
+    {{ if .More }}<p>Showing the newest {{ len .Entries }} matching entries. Narrow the filters to see older ones.</p>{{ end }}
+    {{ else }}
+    <p>No actions match the filters.</p>
+    {{ end }}
+</div>
+</div>
+`
+
+// AuditLog returns the admin view of the entries in the audit log matching q,
+// with a form to filter them
+func AuditLog(req *http.Request, q db.AuditQuery) ([]byte, error) {
+	entries, err := db.AuditLog(q)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{
+		"Days":    db.AuditRetentionDays(),
+		"Query":   q,
+		"Since":   req.URL.Query().Get("since"),
+		"Until":   req.URL.Query().Get("until"),
+		"Entries": entries,
+		"More":    q.Limit > 0 && len(entries) >= q.Limit,
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("audit").Parse(auditHTML))
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+var trashHTML = `
+<div class="col s9 card trash">
+<div class="card-content">
+    <div class="card-title">Trash</div>
+    <p>Deleted content is kept here for {{ .Days }} days, and can be restored until then.</p>
+    {{ if .Items }}
+    <ul class="posts row">
+        {{ range .Items }}
+        <li class="col s12">
+            {{ .Type }}: {{ .ID }} <span class="grey-text">{{ .Slug }}</span>
+            <span class="post-detail">Deleted: {{ .Deleted }}</span>
+            <form enctype="multipart/form-data" class="quick-delete-post __ponzu right" action="/admin/trash/delete" method="post">
+                <span>Delete Forever</span>
+                <input type="hidden" name="type" value="{{ .Type }}"/>
+                <input type="hidden" name="id" value="{{ .ID }}"/>
+            </form>
+            <form enctype="multipart/form-data" class="restore-trash __ponzu right" action="/admin/trash/restore" method="post">
+                <span>Restore</span>
+                <input type="hidden" name="type" value="{{ .Type }}"/>
+                <input type="hidden" name="id" value="{{ .ID }}"/>
+            </form>
+        </li>
+        {{ end }}
+    </ul>
+    {{ else }}
+    <p>The trash is empty.</p>
+    {{ end }}
+    {{ if .Dangling }}
+    <div class="card-title">Broken References</div>
+    <p>This content refers to items which no longer exist. Restore the items, or edit the content to change its references.</p>
+    <ul class="posts row">
+        {{ range .Dangling }}
+        <li class="col s12">
+            <a href="/admin/edit?type={{ .Type }}&id={{ .ID }}">{{ .Type }}: {{ .ID }}</a>
+            <span class="post-detail">{{ .Field }} refers to {{ .Reference }}</span>
+        </li>
+        {{ end }}
+    </ul>
+    {{ end }}
+</div>
+</div>
+<script>
+    $(function() {
+        $('.restore-trash.__ponzu span').on('click', function(e) {
+            $(e.target).parent().submit();
+        });
+
+        $('.quick-delete-post.__ponzu span').on('click', function(e) {
+            if (confirm("[Ponzu] Please confirm:\n\nAre you sure you want to permanently delete this content?\nThis cannot be undone.")) {
+                $(e.target).parent().submit();
+            }
+        });
+    });
+</script>
+`
+
+// Trash returns the admin view listing content in the trash, each with buttons
+// to restore it or delete it permanently, and any content with references to
+// items which no longer exist
+func Trash(req *http.Request) ([]byte, error) {
+	items, err := db.Trash()
+	if err != nil {
+		return nil, err
+	}
+
+	type trashed struct {
+		Type    string
+		ID      string
+		Slug    string
+		Deleted string
+	}
+
+	var list []trashed
+	for i := range items {
+		list = append(list, trashed{
+			Type:    items[i].Type,
+			ID:      items[i].ID,
+			Slug:    items[i].Slug(),
+			Deleted: items[i].Time().Format("Jan 2, 2006 3:04:05 PM"),
+		})
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("trash").Parse(trashHTML))
+	data := map[string]interface{}{
+		"Days":     db.TrashRetentionDays(),
+		"Items":    list,
+		"Dangling": db.DanglingReferences(),
+	}
+
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+var apiKeysHTML = `
+<div class="col s9 card apikeys">
+<div class="card-content">
+    <div class="card-title">API Keys</div>
+    <p>Send a key with API requests in an "Authorization: Bearer &lt;key&gt;" or "X-API-Key: &lt;key&gt;" header. Keys can be required for all API requests in the Configuration.</p>
+    {{ if .NewKey }}
+    <div class="card-panel green lighten-4">
+        <p>Your new API key is shown below. Copy it now, since it will not be shown again.</p>
+        <p><code>{{ .NewKey }}</code></p>
+    </div>
+    {{ end }}
+    {{ if .Keys }}
+    <ul class="posts row">
+        {{ range .Keys }}
+        <li class="col s12">
+            {{ .Name }} <span class="grey-text">{{ .Prefix }}&hellip; {{ .Scope }}{{ if .Types }} ({{ range $i, $t := .Types }}{{ if $i }}, {{ end }}{{ $t }}{{ end }}){{ end }}{{ if .RateLimit }}, {{ .RateLimit }}/min{{ end }}</span>
+            <form enctype="multipart/form-data" class="quick-delete-post __ponzu right" action="/admin/apikeys/revoke" method="post">
+                <span>Revoke</span>
+                <input type="hidden" name="id" value="{{ .ID }}"/>
+            </form>
+        </li>
+        {{ end }}
+    </ul>
+    {{ else }}
+    <p>No API keys have been created.</p>
+    {{ end }}
+</div>
+</div>
+<div class="col s9 card">
+<div class="card-content">
+    <div class="card-title">Create API Key</div>
+    <form enctype="multipart/form-data" class="row" action="/admin/apikeys" method="post">
+        <div class="input-field col s12">
+            <input placeholder="e.g. Static site build" class="validate required" type="text" id="name" name="name" required/>
+            <label for="name" class="active">Name</label>
+        </div>
+        <div class="input-field col s12">
+            <label class="active">Scope</label>
+            <select class="browser-default" name="scope">
+                <option value="read">Read only</option>
+                <option value="write">Read and write</option>
+                <option value="audit">Audit log only</option>
+            </select>
+        </div>
+        <div class="input-field col s12">
+            <p>Content Types (none checked allows all types)</p>
+            {{ range $t, $f := .Types }}
+            <input type="checkbox" id="type-{{ $t }}" name="types" value="{{ $t }}"/>
+            <label for="type-{{ $t }}">{{ $t }}</label>
+            {{ end }}
+        </div>
+        <div class="input-field col s12">
+            <input placeholder="Requests per minute, 0 for no limit" type="number" min="0" id="rate_limit" name="rate_limit"/>
+            <label for="rate_limit" class="active">Rate Limit</label>
+        </div>
+        <button class="btn waves-effect waves-light right" type="submit">Create API Key</button>
+    </form>
+</div>
+</div>
+<script>
+    $(function() {
+        $('.quick-delete-post.__ponzu span').on('click', function(e) {
+            if (confirm("[Ponzu] Please confirm:\n\nAre you sure you want to revoke this API key?\nClients using it will no longer have access.")) {
+                $(e.target).parent().submit();
+            }
+        });
+    });
+</script>
+`
+
+// APIKeys returns the admin view listing API keys, with a form to create a new
+// one. If newKey is not empty, it is shown as the key just created.
+func APIKeys(req *http.Request, newKey string) ([]byte, error) {
+	keys, err := db.APIKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("apikeys").Parse(apiKeysHTML))
+	data := map[string]interface{}{
+		"NewKey": newKey,
+		"Keys":   keys,
+		"Types":  item.Types,
+	}
+
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+var webhooksHTML = `
+<div class="col s9 card webhooks">
+<div class="card-content">
+    <div class="card-title">Webhooks</div>
+    <p>Each webhook URL is sent a POST request with a JSON payload when content it is registered for is created, updated or deleted. If a secret is set, the payload is signed with it, and the HMAC-SHA256 signature is sent in the {{ .Header }} header.</p>
+    {{ if .Webhooks }}
+    <ul class="posts row">
+        {{ range .Webhooks }}
+        <li class="col s12">
+            {{ .URL }} <span class="grey-text">{{ if eq .Type "*" }}All types{{ else }}{{ .Type }}{{ end }}: {{ range $i, $e := .Events }}{{ if $i }}, {{ end }}{{ $e }}{{ end }}</span>
+            <form enctype="multipart/form-data" class="quick-delete-post __ponzu right" action="/admin/webhooks/delete" method="post">
+                <span>Delete</span>
+                <input type="hidden" name="id" value="{{ .ID }}"/>
+            </form>
+        </li>
+        {{ end }}
+    </ul>
+    {{ else }}
+    <p>No webhooks have been added.</p>
+    {{ end }}
+</div>
+</div>
+<div class="col s9 card">
+<div class="card-content">
+    <div class="card-title">Add Webhook</div>
+    <form enctype="multipart/form-data" class="row" action="/admin/webhooks" method="post">
+        <div class="input-field col s12">
+            <input placeholder="e.g. https://example.com/rebuild" class="validate required" type="url" id="url" name="url" required/>
+            <label for="url" class="active">URL</label>
+        </div>
+        <div class="input-field col s12">
+            <label class="active">Content Type</label>
+            <select class="browser-default" name="type">
+                <option value="*">All types</option>
+                {{ range $t, $f := .Types }}
+                <option value="{{ $t }}">{{ $t }}</option>
+                {{ end }}
+            </select>
+        </div>
+        <div class="input-field col s12">
+            {{ range .Events }}
+            <input type="checkbox" id="event-{{ . }}" name="events" value="{{ . }}" checked/>
+            <label for="event-{{ . }}">{{ . }}</label>
+            {{ end }}
+        </div>
+        <div class="input-field col s12">
+            <input placeholder="Shared secret used to sign payloads (optional)" type="text" id="secret" name="secret"/>
+            <label for="secret" class="active">Secret</label>
+        </div>
+        <button class="btn waves-effect waves-light right" type="submit">Add Webhook</button>
+    </form>
+</div>
+</div>
+<script>
+    $(function() {
+        $('.quick-delete-post.__ponzu span').on('click', function(e) {
+            if (confirm("[Ponzu] Please confirm:\n\nAre you sure you want to delete this webhook?")) {
+                $(e.target).parent().submit();
+            }
+        });
+    });
+</script>
+`
+
+// Webhooks returns the admin view listing registered webhooks, with a form to
+// add a new one
+func Webhooks(req *http.Request) ([]byte, error) {
+	hooks, err := db.Webhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("webhooks").Parse(webhooksHTML))
+	data := map[string]interface{}{
+		"Header":   webhook.SignatureHeader,
+		"Webhooks": hooks,
+		"Types":    item.Types,
+		"Events":   webhook.Events,
+	}
+
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+var redirectsHTML = `
+<div class="col s9 card redirects">
+<div class="card-content">
+    <div class="card-title">Redirects <a class="btn-flat right" href="/admin/redirects/export">Export</a></div>
+    <p>Requests for a path in the table are redirected before they reach Ponzu. A path ending with <code>*</code> matches every path starting with it, and when its target also ends with <code>*</code>, the rest of the requested path is added in its place. A redirect from an exact path is used over one from a prefix of it. Requests to the admin are never redirected.</p>
+    {{ if .Redirects }}
+    <ul class="posts row">
+        {{ range .Redirects }}
+        <li class="col s12">
+            {{ .From }} <span class="grey-text">&rarr;</span> {{ .To }} <span class="grey-text">{{ if eq .Status 301 }}301 Permanent{{ else }}302 Temporary{{ end }}</span>
+            <form enctype="multipart/form-data" class="quick-delete-post __ponzu right" action="/admin/redirects/delete" method="post">
+                <span>Delete</span>
+                <input type="hidden" name="from" value="{{ .From }}"/>
+            </form>
+        </li>
+        {{ end }}
+    </ul>
+    {{ else }}
+    <p>No redirects have been added.</p>
+    {{ end }}
+</div>
+</div>
+<div class="col s9 card">
+<div class="card-content">
+    <div class="card-title">Add Redirect</div>
+    <form enctype="multipart/form-data" class="row" action="/admin/redirects" method="post">
+        <div class="input-field col s12">
+            <input placeholder="e.g. /old-page or /old-section/*" class="validate required" type="text" id="from" name="from" required/>
+            <label for="from" class="active">From</label>
+        </div>
+        <div class="input-field col s12">
+            <input placeholder="e.g. /new-page, /new-section/* or https://example.com" class="validate required" type="text" id="to" name="to" required/>
+            <label for="to" class="active">To</label>
+        </div>
+        <div class="input-field col s12">
+            <label class="active">Status</label>
+            <select class="browser-default" name="status">
+                <option value="301">301 Permanent</option>
+                <option value="302">302 Temporary</option>
+            </select>
+        </div>
+        <button class="btn waves-effect waves-light right" type="submit">Add Redirect</button>
+    </form>
+</div>
+</div>
+<div class="col s9 card">
+<div class="card-content">
+    <div class="card-title">Import Redirects</div>
+    <p>Upload a JSON file in the format of an export. Redirects from the same paths as existing ones replace them, and if any redirect in the file is not valid, none are imported.</p>
+    <form enctype="multipart/form-data" class="row" action="/admin/redirects/import" method="post">
+        <div class="file-field input-field col s12">
+            <div class="btn">
+                <span>JSON File</span>
+                <input type="file" name="json" accept=".json,application/json" required/>
+            </div>
+            <div class="file-path-wrapper">
+                <input class="file-path validate" type="text"/>
+            </div>
+        </div>
+        <button class="btn waves-effect waves-light right" type="submit">Import</button>
+    </form>
+</div>
+</div>
+<script>
+    $(function() {
+        $('.quick-delete-post.__ponzu span').on('click', function(e) {
+            if (confirm("[Ponzu] Please confirm:\n\nAre you sure you want to delete this redirect?")) {
+                $(e.target).parent().submit();
+            }
+        });
+    });
+</script>
+`
+
+// Redirects returns the admin view listing the redirect table, with forms to
+// add a redirect and import redirects
+func Redirects(req *http.Request) ([]byte, error) {
+	rs, err := db.Redirects()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("redirects").Parse(redirectsHTML))
+	err = tmpl.Execute(buf, map[string]interface{}{"Redirects": rs})
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+var tagsHTML = `
+<div class="col s9 card tags">
+<div class="card-content">
+    <div class="card-title">Tags</div>
+    <p>Tags are shared by every content type with tag fields. A tag is added here when content is first saved with it, and content saved with a tag in another case is given the spelling here. Renaming a tag renames it in all content tagged with it, and renaming it to another existing tag merges the two.</p>
+    {{ if .Tags }}
+    <ul class="posts row">
+        {{ range .Tags }}
+        <li class="col s12">
+            <form enctype="multipart/form-data" class="row" action="/admin/tags" method="post">
+                <div class="col s5">{{ .Name }} <span class="grey-text">{{ .Count }} items</span></div>
+                <div class="input-field inline col s5">
+                    <input type="hidden" name="from" value="{{ .Name }}"/>
+                    <input type="text" name="to" value="{{ .Name }}" required/>
+                </div>
+                <button class="btn-flat waves-effect col s2" type="submit">Rename</button>
+            </form>
+        </li>
+        {{ end }}
+    </ul>
+    {{ else }}
+    <p>No content has been tagged yet.</p>
+    {{ end }}
+</div>
+</div>
+`
+
+// Tags returns the admin view listing the canonical tags and the number of
+// items tagged with each, with a form to rename each
+func Tags(req *http.Request) ([]byte, error) {
+	names, err := db.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := db.TagCounts()
+
+	type tag struct {
+		Name  string
+		Count int
+	}
+
+	var tags []tag
+	for _, name := range names {
+		tags = append(tags, tag{Name: name, Count: counts[strings.ToLower(name)]})
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("tags").Parse(tagsHTML))
+	err = tmpl.Execute(buf, map[string]interface{}{"Tags": tags})
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+var importHTML = `
+<div class="col s9 card import">
+<div class="card-content">
+    <div class="card-title">Import {{ .Type }} from CSV</div>
+    {{ if not .Header }}
+    <p>Upload a CSV file with a header row. Its columns are matched to the fields of {{ .Type }} by name, and the mapping can be changed before importing. Values of list fields are separated by <code>{{ .Separator }}</code> within a column.</p>
+    <form enctype="multipart/form-data" class="row" action="/admin/contents/import?type={{ .Type }}" method="post">
+        <div class="file-field input-field col s12">
+            <div class="btn">
+                <span>CSV File</span>
+                <input type="file" name="csv" accept=".csv,text/csv" required/>
+            </div>
+            <div class="file-path-wrapper">
+                <input class="file-path validate" type="text" placeholder="Choose a .csv file"/>
+            </div>
+        </div>
+        <button class="btn waves-effect waves-light right" type="submit">Preview</button>
+    </form>
+    {{ else }}
+    <form enctype="multipart/form-data" class="row" action="/admin/contents/import?type={{ .Type }}" method="post">
+        <textarea name="data" style="display: none;">{{ .Data }}</textarea>
+        <table class="col s12">
+            <thead><tr><th>Column</th><th>Field</th><th>Preview</th></tr></thead>
+            <tbody>
+            {{ range $i, $col := .Mapping }}
+            <tr>
+                <td>{{ $col.Header }}</td>
+                <td>
+                    <select class="browser-default" name="column">
+                        <option value="">Skip this column</option>
+                        {{ range $.Fields }}
+                        <option value="{{ .Name }}"{{ if eq .Name $col.Field }} selected{{ end }}>{{ .Label }} ({{ .Name }}){{ if .List }}, list{{ end }}</option>
+                        {{ end }}
+                    </select>
+                </td>
+                <td class="grey-text">{{ range $j, $v := $col.Preview }}{{ if $j }}, {{ end }}{{ $v }}{{ end }}</td>
+            </tr>
+            {{ end }}
+            </tbody>
+        </table>
+        <p class="col s12">{{ .Rows }} rows to import.</p>
+        <div class="col s12">
+            <button class="btn waves-effect waves-light right" type="submit" name="step" value="import">Import</button>
+            <button class="btn-flat waves-effect right" type="submit" name="step" value="dryrun">Dry Run</button>
+        </div>
+    </form>
+    {{ end }}
+</div>
+</div>
+{{ if .Results }}
+<div class="col s9 card import-results">
+<div class="card-content">
+    <div class="card-title">{{ if .DryRun }}Dry Run {{ end }}Results</div>
+    <p>{{ .Succeeded }} of {{ len .Results }} rows {{ if .DryRun }}are valid{{ else }}were imported{{ end }}{{ if .Failed }}, {{ .Failed }} failed{{ end }}.</p>
+    {{ if .Failed }}
+    <table>
+        <thead><tr><th>Row</th><th>Error</th></tr></thead>
+        <tbody>
+        {{ range .Results }}{{ if .Err }}
+        <tr><td>{{ .Row }}</td><td class="red-text">{{ .Err }}</td></tr>
+        {{ end }}{{ end }}
+        </tbody>
+    </table>
+    {{ end }}
+    {{ if not .DryRun }}<a href="/admin/contents?type={{ .Type }}" class="btn-flat">View {{ .Type }} Items</a>{{ end }}
+</div>
+</div>
+{{ end }}
+`
+
+// importColumn is a column of an uploaded CSV, with the field it is mapped to
+// and some of its values
+type importColumn struct {
+	Header  string
+	Field   string
+	Preview []string
+}
+
+// maxImportPreview is the number of values of each column shown when mapping
+// columns to fields
+const maxImportPreview = 3
+
+// Import returns the admin view to import content of type t from CSV. Before a
+// file is uploaded, header is nil and the view shows an upload form. After, it
+// maps the columns of header to fields, using columns if set or else matching
+// header names, and shows the results of any run of the import.
+func Import(req *http.Request, t string, header []string, rows [][]string, data string, columns []string, results []db.ImportResult, dryRun bool) ([]byte, error) {
+	fields, err := db.ImportFields(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var mapping []importColumn
+	for i, h := range header {
+		col := importColumn{Header: h}
+		if i < len(columns) {
+			col.Field = columns[i]
+		} else {
+			col.Field = matchImportField(h, fields)
+		}
+
+		for _, row := range rows {
+			if len(col.Preview) == maxImportPreview {
+				break
+			}
+			if i < len(row) && row[i] != "" {
+				col.Preview = append(col.Preview, row[i])
+			}
+		}
+
+		mapping = append(mapping, col)
+	}
+
+	var failed int
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	tmpl := template.Must(template.New("import").Parse(importHTML))
+	view := map[string]interface{}{
+		"Type":      t,
+		"Separator": db.ImportListSeparator,
+		"Fields":    fields,
+		"Header":    header,
+		"Mapping":   mapping,
+		"Rows":      len(rows),
+		"Data":      data,
+		"Results":   results,
+		"DryRun":    dryRun,
+		"Failed":    failed,
+		"Succeeded": len(results) - failed,
+	}
+
+	err = tmpl.Execute(buf, view)
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+// matchImportField returns the name of the field a CSV column header refers
+// to, ignoring case, spaces and underscores, or "" if there is none
+func matchImportField(header string, fields []db.ImportField) string {
+	norm := func(s string) string {
+		return strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(s))
+	}
+
+	h := norm(header)
+	for _, f := range fields {
+		if h == norm(f.Name) || h == norm(f.Label) {
+			return f.Name
+		}
+	}
+
+	return ""
+}
+
+var analyticsHTML = `
+<div class="analytics">
+<div class="card">
+<div class="card-content">
+    <p class="right">Data range: {{ .from }} - {{ .to }} ({{ .timezone }})</p>
+    <div class="card-title">API Requests</div>
+    <canvas id="analytics-chart"></canvas>
+    <script>
+    var target = document.getElementById("analytics-chart");
+    Chart.defaults.global.defaultFontColor = '#212121';
+    Chart.defaults.global.defaultFontFamily = "'Roboto', 'Helvetica Neue', 'Helvetica', 'Arial', 'sans-serif'";
+    Chart.defaults.global.title.position = 'right';
+    var chart = new Chart(target, {
+        type: 'bar',
+        data: {
+            labels: [{{ range $date := .dates }} "{{ $date }}",  {{ end }}],
+            datasets: [{
+                type: 'line',
+                label: 'Unique Clients',
+                data: $.parseJSON({{ .unique }}),
+                backgroundColor: 'rgba(76, 175, 80, 0.2)',
+                borderColor: 'rgba(76, 175, 80, 1)',
+                borderWidth: 1
+            },
+            {
+                type: 'bar',
+                label: 'Total Requests',
+                data: $.parseJSON({{ .total }}),
+                backgroundColor: 'rgba(33, 150, 243, 0.2)',
+                borderColor: 'rgba(33, 150, 243, 1)',
+                borderWidth: 1
+            },
+            {
+                type: 'bar',
+                label: 'External Requests',
+                data: $.parseJSON({{ .external_total }}),
+                backgroundColor: 'rgba(255, 152, 0, 0.2)',
+                borderColor: 'rgba(255, 152, 0, 1)',
+                borderWidth: 1
+            }{{ if not .exclude_bots }},
+            {
+                type: 'bar',
+                label: 'Bot Requests',
+                data: $.parseJSON({{ .bot_total }}),
+                backgroundColor: 'rgba(158, 158, 158, 0.2)',
+                borderColor: 'rgba(158, 158, 158, 1)',
+                borderWidth: 1
+            }{{ end }}]
+        },
+        options: {
+            scales: {
+                yAxes: [{
+                    ticks: {
+                        beginAtZero:true
+                    }
+                }]
+            }
+        }
+    });
+    </script>
+</div>
+<div class="card-action">
+    {{ if .exclude_bots }}<a href="/admin">Include bots</a>{{ else }}<a href="/admin?bots=exclude">Exclude bots</a>{{ end }}
+    <a href="/admin/analytics/export?format=csv">Export CSV</a>
+    <a href="/admin/analytics/export?format=json">Export JSON</a>
+</div>
+</div>
+<div class="card">
+<div class="card-content">
+    <p class="right" id="live-status">Connecting...</p>
+    <div class="card-title">Live Requests</div>
+    <table class="striped">
+        <thead>
+            <tr><th>Time</th><th>Method</th><th>URL</th><th>Status</th><th class="right-align">Duration</th><th>IP</th></tr>
+        </thead>
+        <tbody id="live-requests"></tbody>
+    </table>
+    <script>
+    (function() {
+        var rows = $('#live-requests');
+        var status = $('#live-status');
+        var scheme = location.protocol === 'https:' ? 'wss://' : 'ws://';
+        var socket = new WebSocket(scheme + location.host + '/admin/analytics/live');
+
+        socket.onopen = function() {
+            status.text('Live');
+        };
+
+        socket.onclose = function() {
+            status.text('Disconnected');
+        };
+
+        socket.onmessage = function(e) {
+            var r = JSON.parse(e.data);
+            var row = $('<tr/>');
+            row.append($('<td/>').text(new Date(r.timestamp).toLocaleTimeString()));
+            row.append($('<td/>').text(r.http_method));
+            row.append($('<td/>').text(r.url + (r.bot ? ' (bot)' : '')));
+            row.append($('<td/>').text(r.status || ''));
+            row.append($('<td class="right-align"/>').text(r.duration_ms ? r.duration_ms.toFixed(1) + 'ms' : ''));
+            row.append($('<td/>').text(r.ip_address));
+
+            rows.prepend(row);
+            rows.children().slice(20).remove();
+        };
+    })();
+    </script>
+</div>
+</div>
+<div class="card">
+<div class="card-content">
+    <p class="right">Data range: {{ .from }} - {{ .to }} ({{ .timezone }})</p>
+    <div class="card-title">API Requests by Method</div>
+    <canvas id="analytics-methods-chart"></canvas>
+    <script>
+    (function() {
+        var colors = {
+            GET: '33, 150, 243',
+            POST: '76, 175, 80',
+            PUT: '255, 152, 0',
+            DELETE: '244, 67, 54',
+            other: '158, 158, 158'
+        };
+
+        var datasets = $.parseJSON({{ .methods }}).map(function(s) {
+            return {
+                label: s.method,
+                data: s.data,
+                backgroundColor: 'rgba(' + colors[s.method] + ', 0.2)',
+                borderColor: 'rgba(' + colors[s.method] + ', 1)',
+                borderWidth: 1
+            };
+        });
+
+        new Chart(document.getElementById("analytics-methods-chart"), {
+            type: 'bar',
+            data: {
+                labels: [{{ range $date := .dates }} "{{ $date }}",  {{ end }}],
+                datasets: datasets
+            },
+            options: {
+                scales: {
+                    xAxes: [{ stacked: true }],
+                    yAxes: [{
+                        stacked: true,
+                        ticks: {
+                            beginAtZero:true
+                        }
+                    }]
+                }
+            }
+        });
+    })();
+    </script>
+</div>
+</div>
+<div class="card">
+<div class="card-content">
+    <p class="right">Last 7 days</p>
+    <div class="card-title">Top Endpoints</div>
+    <table class="striped">
+        <thead>
+            <tr><th>Endpoint</th><th class="right-align">Requests</th></tr>
+        </thead>
+        <tbody>
+            {{ range .endpoints }}
+            <tr><td>{{ .Endpoint }}</td><td class="right-align">{{ .Count }}</td></tr>
+            {{ else }}
+            <tr><td colspan="2">No API requests recorded.</td></tr>
+            {{ end }}
+        </tbody>
+    </table>
+</div>
+</div>
+<div class="card">
+<div class="card-content">
+    <p class="right">Last 7 days</p>
+    <div class="card-title">Top Origins</div>
+    <table class="striped">
+        <thead>
+            <tr><th>Origin</th><th class="right-align">Requests</th></tr>
+        </thead>
+        <tbody>
+            {{ range .origins }}
+            <tr><td>{{ .Origin }}</td><td class="right-align">{{ .Count }}</td></tr>
+            {{ else }}
+            <tr><td colspan="2">No API requests recorded.</td></tr>
+            {{ end }}
+        </tbody>
+    </table>
+</div>
+</div>
+</div>
+`
+
+var widgetsHTML = `
+<div class="dashboard-widgets row">
+{{ range . }}
+<div class="col s12 m{{ .Width }}">
+<div class="card">
+<div class="card-content">
+    <div class="card-title">{{ .Title }}</div>
+    {{ if .Err }}
+    <p class="red-text">Error: {{ .Err }}</p>
+    {{ else }}
+    {{ .Content }}
+    {{ end }}
+</div>
+</div>
+</div>
+{{ end }}
+</div>
+`
+
+var jobsHTML = `
+<div class="dashboard-jobs row">
+<div class="col s12">
+<div class="card">
+<div class="card-content">
+    <div class="card-title">Scheduled jobs</div>
+    <table class="striped">
+    <thead>
+        <tr>
+            <th>Job</th>
+            <th>Schedule</th>
+            <th>Last run</th>
+            <th>Duration</th>
+            <th>Runs</th>
+            <th>Next run</th>
+        </tr>
+    </thead>
+    <tbody>
+    {{ range . }}
+        <tr>
+            <td>{{ .Name }}</td>
+            <td>{{ .Schedule }}</td>
+            <td>
+                {{ if .Running }}<span class="green-text">running</span>
+                {{ else if .LastRun.IsZero }}never
+                {{ else }}{{ .LastRun.Format "Jan 2, 15:04:05" }}{{ end }}
+                {{ if .LastError }}<br/><span class="red-text">Error: {{ .LastError }}</span>{{ end }}
+            </td>
+            <td>{{ if not .LastRun.IsZero }}{{ .LastDuration }}{{ end }}</td>
+            <td>{{ .Runs }}{{ if .Failures }} ({{ .Failures }} failed){{ end }}{{ if .Skipped }} ({{ .Skipped }} skipped){{ end }}</td>
+            <td>{{ .NextRun.Format "Jan 2, 15:04:05" }}</td>
+        </tr>
+    {{ end }}
+    </tbody>
+    </table>
+</div>
+</div>
+</div>
+</div>
+`
+
+type dashboardWidget struct {
+	Title   string
+	Width   int
+	Content template.HTML
+	Err     error
+}
+
+// widgetResponse collects the content a widget's Handler writes
+type widgetResponse struct {
+	header http.Header
+	status int
+	bytes.Buffer
+}
+
+func (w *widgetResponse) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *widgetResponse) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// renderWidget returns the content of w for the dashboard requested by req
+func renderWidget(w addon.Widget, req *http.Request) (template.HTML, error) {
+	if w.Handler != nil {
+		res := &widgetResponse{}
+		w.Handler.ServeHTTP(res, req)
+		if res.status >= http.StatusBadRequest {
+			return "", fmt.Errorf("%d %s", res.status, http.StatusText(res.status))
+		}
+
+		return template.HTML(res.String()), nil
+	}
+
+	var data interface{}
+	if w.Data != nil {
+		var err error
+		data, err = w.Data(req)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	tmpl, err := template.New("widget").Parse(w.Template)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(buf.String()), nil
+}
+
+// Dashboard returns the admin view with analytics dashboard, followed by the
+// widgets registered by addons for the request
+func Dashboard(req *http.Request) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	role, err := db.CurrentRole(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// analytics are only shown to users whose role allows viewing them
+	if role.Manages(user.SectionAnalytics) {
+		err = dashboardAnalytics(buf, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var widgets []dashboardWidget
+	var tmpl *template.Template
+	for _, w := range addon.Widgets() {
+		dw := dashboardWidget{Title: w.Title, Width: w.Width}
+		dw.Content, dw.Err = renderWidget(w, req)
+		if dw.Err != nil {
+			logger.For(req).Error("Error rendering dashboard widget", w.Title+":", dw.Err)
+		}
+
+		widgets = append(widgets, dw)
+	}
+
+	if len(widgets) > 0 {
+		tmpl = template.Must(template.New("widgets").Parse(widgetsHTML))
+		err = tmpl.Execute(buf, widgets)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if jobs := scheduler.Jobs(); len(jobs) > 0 {
+		tmpl = template.Must(template.New("jobs").Parse(jobsHTML))
+		err = tmpl.Execute(buf, jobs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return Admin(req, buf.Bytes())
+}
+
+// dashboardAnalytics writes the analytics charts and breakdowns of the
+// dashboard to buf
+func dashboardAnalytics(buf *bytes.Buffer, req *http.Request) error {
+	// bots are charted unless the admin chooses to see only people
+	excludeBots := req.URL.Query().Get("bots") == "exclude"
+	data, err := analytics.ChartDataWithOptions(analytics.ChartOptions{ExcludeBots: excludeBots})
+	if err != nil {
+		return err
+	}
+	data["exclude_bots"] = excludeBots
+
+	now := time.Now()
+	data["endpoints"], err = analytics.TopEndpoints(now.AddDate(0, 0, -7), now, 10)
+	if err != nil {
+		return err
+	}
+
+	data["origins"], err = analytics.OriginBreakdown(now.AddDate(0, 0, -7), now, 10)
+	if err != nil {
+		return err
+	}
+
+	tmpl := template.Must(template.New("analytics").Parse(analyticsHTML))
+	return tmpl.Execute(buf, data)
+}
+
+var err400HTML = []byte(`
+<div class="error-page e400 col s6">
+<div class="card">
+<div class="card-content">
+    <div class="card-title"><b>400</b> Error: Bad Request</div>
+    <blockquote>Sorry, the request was unable to be completed.</blockquote>
+</div>
+</div>
+</div>
+`)
+
+// Error400 creates a subview for a 400 error page
+func Error400(req *http.Request) ([]byte, error) {
+	return Admin(req, err400HTML)
+}
+
+var err403HTML = []byte(`
+<div class="error-page e403 col s6">
+<div class="card">
+<div class="card-content">
+    <div class="card-title"><b>403</b> Error: Forbidden</div>
+    <blockquote>Sorry, your role does not allow you to access this page.</blockquote>
+</div>
+</div>
+</div>
+`)
+
+// Error403 creates a subview for a 403 error page
+func Error403(req *http.Request) ([]byte, error) {
+	return Admin(req, err403HTML)
+}
+
+var err404HTML = []byte(`
+<div class="error-page e404 col s6">
+<div class="card">
+<div class="card-content">
+    <div class="card-title"><b>404</b> Error: Not Found</div>
+    <blockquote>Sorry, the page you requested could not be found.</blockquote>
+</div>
+</div>
+</div>
+`)
+
+// Error404 creates a subview for a 404 error page
+func Error404(req *http.Request) ([]byte, error) {
+	return Admin(req, err404HTML)
+}
+
+var err405HTML = []byte(`
+<div class="error-page e405 col s6">
+<div class="card">
+<div class="card-content">
+    <div class="card-title"><b>405</b> Error: Method Not Allowed</div>
+    <blockquote>Sorry, the method of your request is not allowed.</blockquote>
+</div>
+</div>
+</div>
+`)
+
 // Error405 creates a subview for a 405 error page
-func Error405() ([]byte, error) {
-	return Admin(err405HTML)
+func Error405(req *http.Request) ([]byte, error) {
+	return Admin(req, err405HTML)
 }
 
 var err500HTML = []byte(`
@@ -605,8 +2605,8 @@ var err500HTML = []byte(`
 `)
 
 // Error500 creates a subview for a 500 error page
-func Error500() ([]byte, error) {
-	return Admin(err500HTML)
+func Error500(req *http.Request) ([]byte, error) {
+	return Admin(req, err500HTML)
 }
 
 var errMessageHTML = `
@@ -620,10 +2620,40 @@ var errMessageHTML = `
 </div>
 `
 
-// ErrorMessage is a generic error message container, similar to Error500() and
+// ErrorMessage is a generic error message container, similar to Error500(req) and
 // others in this package, ecxept it expects the caller to provide a title and
 // message to describe to a view why the error is being shown
-func ErrorMessage(title, message string) ([]byte, error) {
+func ErrorMessage(req *http.Request, title, message string) ([]byte, error) {
 	eHTML := fmt.Sprintf(errMessageHTML, title, message)
-	return Admin([]byte(eHTML))
+	return Admin(req, []byte(eHTML))
+}
+
+var graphiqlHTML = `
+<div class="col s9 card graphiql">
+<div class="card-content">
+    <div class="card-title">GraphQL</div>
+    <p>Explore and query your content with GraphQL. Queries are sent to /api/graphql, which is read-only. If API keys are required, add an "Authorization: Bearer &lt;key&gt;" header in the Headers tab.</p>
+    <div id="graphiql" style="height: 75vh; margin-top: 1rem;"></div>
+</div>
+</div>
+<link rel="stylesheet" href="https://unpkg.com/graphiql@1.4.7/graphiql.min.css" />
+<script src="https://unpkg.com/react@17/umd/react.production.min.js"></script>
+<script src="https://unpkg.com/react-dom@17/umd/react-dom.production.min.js"></script>
+<script src="https://unpkg.com/graphiql@1.4.7/graphiql.min.js"></script>
+<script>
+    $(function() {
+        var fetcher = GraphiQL.createFetcher({ url: '/api/graphql' });
+
+        ReactDOM.render(
+            React.createElement(GraphiQL, { fetcher: fetcher, headerEditorEnabled: true }),
+            document.getElementById('graphiql')
+        );
+    });
+</script>
+`
+
+// GraphiQL returns the admin view of GraphiQL, an in-browser editor for
+// queries of the GraphQL API
+func GraphiQL(req *http.Request) ([]byte, error) {
+	return Admin(req, []byte(graphiqlHTML))
 }
@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/api/analytics"
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/requestid"
+
+	"github.com/tidwall/gjson"
+)
+
+// auditPageSize is the number of entries shown at once in the audit log view
+const auditPageSize = 100
+
+// audit records action taken on target by the current user in the audit log.
+// Failing to record an entry is logged, and doesn't fail the action.
+func audit(req *http.Request, action, target string, details map[string]string) {
+	var email string
+	j, err := db.CurrentUser(req)
+	if err == nil {
+		email = gjson.GetBytes(j, "email").String()
+	}
+
+	auditAs(req, email, action, target, details)
+}
+
+// auditAs records action in the audit log as taken by the user with email,
+// for actions such as logging in where there is no current user yet
+func auditAs(req *http.Request, email, action, target string, details map[string]string) {
+	err := db.Audit(db.AuditEntry{
+		User:      email,
+		Action:    action,
+		Target:    target,
+		IP:        analytics.ClientIP(req),
+		Details:   details,
+		RequestID: requestid.Get(req),
+	})
+	if err != nil {
+		logger.For(req).Error("Error recording", action, "in audit log:", err)
+	}
+}
+
+// changedKeys returns the names of the top-level values which differ between
+// the JSON objects before and after, so changes can be logged without values
+func changedKeys(before, after []byte) []string {
+	var changed []string
+	seen := make(map[string]bool)
+	gjson.ParseBytes(after).ForEach(func(k, v gjson.Result) bool {
+		seen[k.String()] = true
+		if gjson.GetBytes(before, k.String()).Raw != v.Raw {
+			changed = append(changed, k.String())
+		}
+		return true
+	})
+
+	gjson.ParseBytes(before).ForEach(func(k, v gjson.Result) bool {
+		if !seen[k.String()] {
+			changed = append(changed, k.String())
+		}
+		return true
+	})
+
+	return changed
+}
+
+// auditQuery returns the audit log query of the filters in the request's query
+// string. Dates are given as YYYY-MM-DD, and include the whole day of until.
+func auditQuery(req *http.Request) db.AuditQuery {
+	q := req.URL.Query()
+	aq := db.AuditQuery{
+		User:   q.Get("user"),
+		Action: q.Get("action"),
+		Target: q.Get("target"),
+		Limit:  auditPageSize,
+	}
+
+	if since, err := time.Parse("2006-01-02", q.Get("since")); err == nil {
+		aq.Since = since.UnixNano() / int64(time.Millisecond)
+	}
+
+	if until, err := time.Parse("2006-01-02", q.Get("until")); err == nil {
+		aq.Until = until.AddDate(0, 0, 1).UnixNano()/int64(time.Millisecond) - 1
+	}
+
+	return aq
+}
+
+func auditHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	view, err := AuditLog(req, auditQuery(req))
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/html")
+	res.Write(view)
+}
@@ -0,0 +1,496 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/management/editor"
+	"github.com/ponzu-cms/ponzu/system/admin/user"
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/gorilla/schema"
+	"github.com/tidwall/gjson"
+)
+
+// errBatchNotFound is reported for an item of a batch which doesn't exist
+var errBatchNotFound = errors.New("Content not found.")
+
+// batchResult is the outcome of a batch action for one item
+type batchResult struct {
+	ID    string `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// batchReport is the outcome of a batch action for each item it was applied to
+type batchReport struct {
+	Action    string        `json:"action"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []batchResult `json:"results"`
+}
+
+func (r *batchReport) add(id string, err error) {
+	if err != nil {
+		r.Failed++
+		r.Results = append(r.Results, batchResult{ID: id, Error: err.Error()})
+		return
+	}
+
+	r.Succeeded++
+	r.Results = append(r.Results, batchResult{ID: id, OK: true})
+}
+
+// batchIDs returns the ids of the items of the bucket ns, or only those which
+// contain search, matching the same way as searchHandler
+func batchIDs(ns, search string) []string {
+	search = strings.ToLower(search)
+
+	var ids []string
+	for _, j := range db.ContentAll(ns) {
+		if search != "" && !strings.Contains(strings.ToLower(string(j)), search) {
+			continue
+		}
+
+		ids = append(ids, gjson.GetBytes(j, "id").String())
+	}
+
+	return ids
+}
+
+// batchRequest returns a copy of req for running the hooks of one item of a
+// batch, with data as its form
+func batchRequest(req *http.Request, data url.Values) *http.Request {
+	r := req.WithContext(req.Context())
+	r.Form, r.PostForm = data, data
+
+	return r
+}
+
+// batchHandler applies an action to many items of a content type at once, from
+// the bulk-action bar of the content list. The items are those given by id, or
+// with all set every item of the list, or of the search results for q. The
+// action is delete, moving the items to the trash, approve, making pending
+// external content public, or state, moving the items to the workflow state
+// given as state. Each item goes through the hooks run for it when the action
+// is done to a single item, and the response reports which items succeeded
+// and why any others failed. Deleted items are removed in a single
+// transaction.
+func batchHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	t := req.FormValue("type")
+	pt := strings.Split(t, "__")[0]
+	if _, ok := item.Types[pt]; !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ids := req.Form["id"]
+	if req.FormValue("all") == "true" {
+		ids = batchIDs(t, req.FormValue("q"))
+	}
+
+	role, err := db.CurrentRole(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	action := req.FormValue("action")
+	report := batchReport{Action: action, Results: []batchResult{}}
+
+	switch action {
+	case "delete":
+		if !role.Can(pt, user.ActionDelete) {
+			res.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		batchDelete(req, t, ids, &report)
+
+	case "approve":
+		if pt == t || !role.Can(pt, user.ActionCreate) {
+			res.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		for _, id := range ids {
+			report.add(id, batchApprove(req, t, id))
+		}
+
+	case "state":
+		if pt != t || !workflowEnabled() || !role.Can(pt, user.ActionUpdate) {
+			res.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		state := req.FormValue("state")
+		for _, id := range ids {
+			report.add(id, batchState(req, t, id, state))
+		}
+
+	default:
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	j, err := json.Marshal(report)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(j)
+}
+
+// batchDelete moves the items ids of t to the trash, together, leaving out
+// those rejected by their BeforeDelete hook
+func batchDelete(req *http.Request, t string, ids []string, report *batchReport) {
+	hook, ok := item.Types[strings.Split(t, "__")[0]]().(item.Hookable)
+	if !ok {
+		for _, id := range ids {
+			report.add(id, fmt.Errorf("%s does not implement item.Hookable", t))
+		}
+		return
+	}
+
+	var deleting []string
+	requests := make(map[string]*http.Request)
+	for _, id := range ids {
+		j, err := db.Content(t + ":" + id)
+		if err == nil && len(j) == 0 {
+			err = errBatchNotFound
+		}
+		if err != nil {
+			report.add(id, err)
+			continue
+		}
+
+		r := batchRequest(req, url.Values{"id": {id}, "type": {t}})
+		err = hook.BeforeDelete(&discardResponse{}, r)
+		if err != nil {
+			report.add(id, err)
+			continue
+		}
+
+		deleting = append(deleting, id)
+		requests[id] = r
+	}
+
+	err := db.DeleteContents(t, deleting)
+	for _, id := range deleting {
+		if err != nil {
+			report.add(id, err)
+			continue
+		}
+
+		report.add(id, nil)
+
+		err := hook.AfterDelete(&discardResponse{}, requests[id])
+		if err != nil {
+			logger.For(req).Error("Error running AfterDelete method in batchHandler for:", t, err)
+		}
+
+		audit(req, "content.delete", t+":"+id, map[string]string{"batch": "true"})
+	}
+}
+
+// batchApprove makes the pending external content id of t public, the same as
+// approving it from the editor, or a draft if the workflow is enabled and the
+// user isn't allowed to publish it
+func batchApprove(req *http.Request, t, id string) error {
+	pt := strings.Split(t, "__")[0]
+	post := item.Types[pt]()
+
+	hook, ok := post.(item.Hookable)
+	if !ok {
+		return fmt.Errorf("%s does not implement item.Hookable", pt)
+	}
+
+	m, ok := post.(editor.Mergeable)
+	if !ok {
+		return fmt.Errorf("%s must implement editor.Mergeable before it can be approved", pt)
+	}
+
+	j, err := db.Content(t + ":" + id)
+	if err == nil && len(j) == 0 {
+		err = errBatchNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := db.ImportJSONValues(j)
+	if err != nil {
+		return err
+	}
+	data.Set("id", id)
+	data.Set("type", t)
+
+	dec := schema.NewDecoder()
+	dec.IgnoreUnknownKeys(true)
+	dec.SetAliasTag("json")
+	err = dec.Decode(post, data)
+	if err != nil {
+		return err
+	}
+
+	r := batchRequest(req, data)
+	res := &discardResponse{}
+
+	err = hook.BeforeApprove(res, r)
+	if err != nil {
+		return err
+	}
+
+	err = m.Approve(res, r)
+	if err != nil {
+		return err
+	}
+
+	err = hook.AfterApprove(res, r)
+	if err != nil {
+		return err
+	}
+
+	// approved content enters the workflow as new content
+	_, _, err = applyWorkflow(r, pt, data, nil, approvalState(r, pt))
+	if err != nil {
+		return err
+	}
+
+	err = hook.BeforeSave(res, r)
+	if err != nil {
+		return err
+	}
+
+	saved, err := db.SetContent(pt+":-1", data)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.WithValue(r.Context(), "target", fmt.Sprintf("%s:%d", pt, saved))
+	err = hook.AfterSave(res, r.WithContext(ctx))
+	if err != nil {
+		logger.For(req).Error("Error running AfterSave hook in batchHandler for:", pt, err)
+	}
+
+	err = db.PurgeContent(t+":"+id, data)
+	if err != nil {
+		logger.For(req).Error("Failed to remove content after approval:", err)
+	}
+
+	audit(req, "content.approve", fmt.Sprintf("%s:%d", pt, saved), map[string]string{
+		"pending": id,
+		"batch":   "true",
+	})
+
+	return nil
+}
+
+// batchState moves the content id of t to the workflow state, the same as
+// saving it from the editor with that state requested
+func batchState(req *http.Request, t, id, state string) error {
+	hook, ok := item.Types[t]().(item.Hookable)
+	if !ok {
+		return fmt.Errorf("%s does not implement item.Hookable", t)
+	}
+
+	existing, err := db.Content(t + ":" + id)
+	if err == nil && len(existing) == 0 {
+		err = errBatchNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := db.ImportJSONValues(existing)
+	if err != nil {
+		return err
+	}
+
+	from, to, err := applyWorkflow(req, t, data, existing, state)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+
+	data.Set("updated", strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10))
+
+	r := batchRequest(req, data)
+	err = hook.BeforeSave(&discardResponse{}, r)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.SetContent(t+":"+id, data)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.WithValue(r.Context(), "target", t+":"+id)
+	err = hook.AfterSave(&discardResponse{}, r.WithContext(ctx))
+	if err != nil {
+		logger.For(req).Error("Error running AfterSave method in batchHandler for:", t, err)
+	}
+
+	audit(req, "content.state", t+":"+id, map[string]string{
+		"from":  from,
+		"to":    to,
+		"batch": "true",
+	})
+
+	return nil
+}
+
+// batchSelect is the checkbox selecting the item id in the content list for a
+// batch action
+func batchSelect(id string) string {
+	return `<input type="checkbox" class="filled-in __ponzu batch-select" id="batch-select-` + id + `" value="` + id + `" />
+				<label for="batch-select-` + id + `"></label>`
+}
+
+// batchBar returns the bulk-action bar of a content list of type t with the
+// status public or pending, which lists a page of the total items matching it,
+// or the search for q. Only the actions the list's items can take are offered.
+func batchBar(t, status, q string, total int) string {
+	ns := t
+	if status == "pending" {
+		ns = t + "__pending"
+	}
+
+	options := `<option value="delete">Move to trash</option>`
+	if status == "pending" {
+		options += `<option value="approve">Approve (make public)</option>`
+	} else if workflowEnabled() {
+		for _, s := range item.States {
+			options += `<option value="state:` + s + `">Move to ` + s + `</option>`
+		}
+	}
+
+	return `
+	<div class="row batch-actions __ponzu">
+		<div class="col s4">
+			<input type="checkbox" class="filled-in __ponzu batch-select-page" id="batch-select-page" />
+			<label for="batch-select-page">Select page</label>
+			<a href="#" class="batch-select-all hide" data-total="` + strconv.Itoa(total) + `">Select all ` + strconv.Itoa(total) + ` matching</a>
+		</div>
+		<div class="col s5">
+			<select class="browser-default __ponzu batch-action">` + options + `</select>
+		</div>
+		<div class="col s3">
+			<button class="btn-flat waves-effect batch-apply" type="button" data-type="` + html.EscapeString(ns) + `" data-q="` + html.EscapeString(q) + `" disabled>Apply</button>
+		</div>
+	</div>
+	<script>
+		$(function() {
+			var page = $('.batch-select-page.__ponzu'),
+				all = $('.batch-select-all'),
+				apply = $('.batch-apply'),
+				boxes = $('.batch-select.__ponzu'),
+				everything = false;
+
+			var update = function() {
+				var checked = boxes.filter(':checked').length;
+				apply.prop('disabled', checked === 0);
+				page.prop('checked', checked > 0 && checked === boxes.length);
+
+				if (checked !== boxes.length) {
+					everything = false;
+				}
+
+				var total = parseInt(all.data('total'), 10);
+				all.toggleClass('hide', checked !== boxes.length || total <= boxes.length);
+				all.text(everything ? 'All ' + total + ' matching selected' : 'Select all ' + total + ' matching');
+			};
+
+			page.on('change', function() {
+				boxes.prop('checked', page.prop('checked'));
+				update();
+			});
+
+			boxes.on('change', update);
+
+			all.on('click', function(e) {
+				e.preventDefault();
+				everything = true;
+				update();
+			});
+
+			apply.on('click', function() {
+				var action = $('select.__ponzu.batch-action').val().split(':'),
+					count = everything ? all.data('total') : boxes.filter(':checked').length;
+
+				if (!confirm("[Ponzu] Please confirm:\n\nApply '" + $('select.__ponzu.batch-action option:selected').text() + "' to " + count + " items?")) {
+					return;
+				}
+
+				var data = new FormData();
+				data.append('type', apply.data('type'));
+				data.append('action', action[0]);
+				if (action.length > 1) {
+					data.append('state', action[1]);
+				}
+
+				if (everything) {
+					data.append('all', 'true');
+					data.append('q', apply.data('q'));
+				} else {
+					boxes.filter(':checked').each(function() {
+						data.append('id', $(this).val());
+					});
+				}
+
+				apply.prop('disabled', true);
+				$.ajax({
+					url: '/admin/contents/batch',
+					type: 'POST',
+					data: data,
+					processData: false,
+					contentType: false,
+					success: function(report) {
+						var msg = report.succeeded + ' succeeded, ' + report.failed + ' failed.';
+						$.each(report.results, function(i, r) {
+							if (!r.ok) {
+								msg += '\n' + r.id + ': ' + r.error;
+							}
+						});
+
+						alert('[Ponzu] ' + msg);
+						window.location.reload();
+					},
+					error: function(xhr) {
+						alert('[Ponzu] The batch action failed: ' + xhr.statusText);
+						apply.prop('disabled', false);
+					}
+				});
+			});
+		});
+	</script>`
+}
@@ -13,14 +13,90 @@ type Config struct {
 	Domain                  string   `json:"domain"`
 	HTTPPort                string   `json:"http_port"`
 	HTTPSPort               string   `json:"https_port"`
+	BindAddr                string   `json:"bind_addr"`
+	TLSMinVersion           string   `json:"tls_min_version"`
+	TLSCipherSuites         []string `json:"tls_cipher_suites"`
+	DisableHTTP2            bool     `json:"http2_disabled"`
+	ShutdownTimeout         int      `json:"shutdown_timeout_seconds"`
+	MaintenanceMode         bool     `json:"maintenance_mode"`
+	MaintenanceRetryAfter   int      `json:"maintenance_retry_after_seconds"`
+	MaintenanceMessage      string   `json:"maintenance_message"`
+	MaintenancePage         string   `json:"maintenance_page"`
+	TracingExporter         string   `json:"tracing_exporter"`
+	TracingEndpoint         string   `json:"tracing_endpoint"`
+	TracingServiceName      string   `json:"tracing_service_name"`
+	ACMEDirectoryURL        string   `json:"acme_directory_url"`
+	ACMEChallenge           string   `json:"acme_challenge"`
+	ACMEDomains             []string `json:"acme_domains"`
+	ACMEDNSProvider         string   `json:"acme_dns_provider"`
+	ACMEDNSExec             string   `json:"acme_dns_exec"`
+	ACMEDNSPropagation      int      `json:"acme_dns_propagation_seconds"`
 	AdminEmail              string   `json:"admin_email"`
 	ClientSecret            string   `json:"client_secret"`
 	Etag                    string   `json:"etag"`
 	DisableCORS             bool     `json:"cors_disabled"`
 	DisableGZIP             bool     `json:"gzip_disabled"`
+	CompressionMinBytes     int      `json:"compression_min_bytes"`
+	CORSAllowedOrigins      []string `json:"cors_allowed_origins"`
+	APIKeyRequired          bool     `json:"api_key_required"`
+	RateLimitRPS            int      `json:"rate_limit_rps"`
+	RateLimitBurst          int      `json:"rate_limit_burst"`
+	AbuseThreshold          int      `json:"abuse_threshold"`
+	SampleEvery             int      `json:"analytics_sample_every"`
+	ExternalSampleEvery     int      `json:"analytics_external_sample_every"`
+	BotPatterns             []string `json:"analytics_bot_patterns"`
 	CacheInvalidate         []string `json:"cache"`
 	BackupBasicAuthUser     string   `json:"backup_basic_auth_user"`
 	BackupBasicAuthPassword string   `json:"backup_basic_auth_password"`
+	MaxRevisions            int      `json:"max_revisions"`
+	PublishInterval         int      `json:"publish_interval"`
+	WorkflowEnabled         bool     `json:"workflow_enabled"`
+	SlugRedirects           bool     `json:"slug_redirects"`
+	SlugRedirectPath        string   `json:"slug_redirect_path"`
+	FeedItems               int      `json:"feed_items"`
+	SearchIndexCheck        string   `json:"search_index_check"`
+	DefaultLocale           string   `json:"default_locale"`
+	Locales                 []string `json:"locales"`
+	SearchDisabledTypes     []string `json:"search_disabled_types"`
+	SearchSynonyms          string   `json:"search_synonyms"`
+	SearchAnalyzers         []string `json:"search_analyzers"`
+	SearchStopWords         []string `json:"search_stop_words"`
+	PreviewURL              string   `json:"preview_url"`
+	ResponseCacheDisabled   bool     `json:"response_cache_disabled"`
+	ResponseCacheEntries    int      `json:"response_cache_entries"`
+	ResponseCacheSeconds    int      `json:"response_cache_seconds"`
+	TrashRetentionDays      int      `json:"trash_retention_days"`
+	AuditRetentionDays      int      `json:"audit_retention_days"`
+	ImageVariants           string   `json:"image_variants"`
+	UploadStorage           string   `json:"upload_storage"`
+	S3Endpoint              string   `json:"s3_endpoint"`
+	S3Region                string   `json:"s3_region"`
+	S3Bucket                string   `json:"s3_bucket"`
+	S3AccessKeyID           string   `json:"s3_access_key_id"`
+	S3SecretAccessKey       string   `json:"s3_secret_access_key"`
+	Require2FA              bool     `json:"require_2fa"`
+	LoginMaxAttempts        int      `json:"login_max_attempts"`
+	LoginMaxAttemptsIP      int      `json:"login_max_attempts_ip"`
+	LoginLockoutMinutes     int      `json:"login_lockout_minutes"`
+	PasswordMinLength       int      `json:"password_min_length"`
+	PasswordRequireUpper    bool     `json:"password_require_upper"`
+	PasswordRequireLower    bool     `json:"password_require_lower"`
+	PasswordRequireDigit    bool     `json:"password_require_digit"`
+	PasswordRequireSymbol   bool     `json:"password_require_symbol"`
+	BcryptCost              int      `json:"bcrypt_cost"`
+	BcryptUpgrade           bool     `json:"bcrypt_upgrade"`
+	PasswordResetMinutes    int      `json:"password_reset_minutes"`
+	PasswordResetMaxRequest int      `json:"password_reset_max_requests"`
+	SessionIdleMinutes      int      `json:"session_idle_minutes"`
+	SessionMaxHours         int      `json:"session_max_hours"`
+	SessionRememberDays     int      `json:"session_remember_days"`
+	InviteExpiryHours       int      `json:"invite_expiry_hours"`
+	SMTPHost                string   `json:"smtp_host"`
+	SMTPPort                int      `json:"smtp_port"`
+	SMTPUsername            string   `json:"smtp_username"`
+	SMTPPassword            string   `json:"smtp_password"`
+	SMTPTLS                 string   `json:"smtp_tls"`
+	SMTPFrom                string   `json:"smtp_from"`
 }
 
 const (
@@ -28,6 +104,38 @@ const (
 		<p class="flow-text">Database Backup Credentials:</p>
 		<p>Add a user name and password to download a backup of your data via HTTP.</p>
 	`
+
+	emailInfo = `
+		<p class="flow-text">Email:</p>
+		<p>Email such as invitations is sent through the SMTP server below. Without one, it is delivered directly to the recipient's mail server, which is often rejected as spam.</p>
+	`
+
+	emailTest = `
+		<div class="email-test">
+			<button class="btn waves-effect waves-light" type="button">Send Test Email</button>
+			<span class="email-test-result"></span>
+			<p class="grey-text">Sends to your address using the saved settings, so save any changes first.</p>
+		</div>
+		<script>
+			$(function() {
+				$('.email-test button').on('click', function(e) {
+					var result = $('.email-test-result');
+					result.text('Sending...');
+					$.post('/admin/configure/email/test', function(data) {
+						result.text('Sent to ' + data.to + '.');
+					}).fail(function(xhr) {
+						var data = xhr.responseJSON || {};
+						result.text('Failed: ' + (data.error || xhr.statusText));
+					});
+				});
+			});
+		</script>
+	`
+
+	revisionsInfo = `
+		<p class="flow-text">Content Revisions:</p>
+		<p>Previous versions of content are kept when it is updated, and can be restored from its History.</p>
+	`
 )
 
 // String partially implements item.Identifiable and overrides Item's String()
@@ -58,6 +166,135 @@ func (c *Config) MarshalEditor() ([]byte, error) {
 				"type": "hidden",
 			}),
 		},
+		editor.Field{
+			View: editor.Input("BindAddr", c, map[string]string{
+				"label":       "Address the servers listen on (leave empty for every interface, takes effect on restart)",
+				"placeholder": "e.g. 127.0.0.1",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("ACMEDirectoryURL", c, map[string]string{
+				"label":       "ACME directory URL of the certificate authority (leave empty for Let's Encrypt, takes effect on restart)",
+				"placeholder": "e.g. https://acme-staging-v02.api.letsencrypt.org/directory",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Select("ACMEChallenge", c, map[string]string{
+				"label": "How control of the domain is proven for certificates (takes effect on restart)",
+			}, map[string]string{
+				"http-01": "HTTP-01, served on the HTTP port (the default)",
+				"dns-01":  "DNS-01, with a TXT record created by a DNS provider (needed for wildcards)",
+			}),
+		},
+		editor.Field{
+			View: editor.InputRepeater("ACMEDomains", c, map[string]string{
+				"label":       "Other names to include in the certificate, with wildcards needing DNS-01",
+				"type":        "text",
+				"placeholder": "e.g. *.example.com",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("ACMEDNSProvider", c, map[string]string{
+				"label":       "DNS provider for DNS-01 (leave empty for exec, which runs the program below; env PONZU_ACME_DNS_PROVIDER)",
+				"placeholder": "e.g. exec",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("ACMEDNSExec", c, map[string]string{
+				"label":       "Program run by the exec DNS provider as: program present|cleanup fqdn value (env PONZU_ACME_DNS_EXEC)",
+				"placeholder": "e.g. /usr/local/bin/update-dns",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("ACMEDNSPropagation", c, map[string]string{
+				"label":       "Seconds to wait for a DNS-01 record to be visible before it is checked (0 uses the default of 60)",
+				"placeholder": "e.g. 60",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Select("TLSMinVersion", c, map[string]string{
+				"label": "Oldest TLS version accepted over HTTPS (takes effect on restart)",
+			}, map[string]string{
+				"1.2": "TLS 1.2 (the default)",
+				"1.3": "TLS 1.3",
+				"1.1": "TLS 1.1 (insecure)",
+				"1.0": "TLS 1.0 (insecure)",
+			}),
+		},
+		editor.Field{
+			View: editor.InputRepeater("TLSCipherSuites", c, map[string]string{
+				"label":       "TLS cipher suites for TLS 1.2 and older (leave empty for secure defaults, takes effect on restart)",
+				"type":        "text",
+				"placeholder": "e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("DisableHTTP2", c, map[string]string{
+				"label": "Disable HTTP/2 over HTTPS (takes effect on restart)",
+			}, map[string]string{
+				"true": "Disable HTTP/2",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("ShutdownTimeout", c, map[string]string{
+				"label":       "Seconds in-flight requests are given to complete when Ponzu is stopped (0 uses the default of 30)",
+				"placeholder": "e.g. 30",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("MaintenanceMode", c, map[string]string{
+				"label": "Maintenance mode: public API and content requests get 503 Service Unavailable while the admin stays reachable (also on while a maintenance.flag file exists where Ponzu is run)",
+			}, map[string]string{
+				"true": "Enable Maintenance Mode",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("MaintenanceRetryAfter", c, map[string]string{
+				"label":       "Seconds clients are told to wait before retrying in maintenance mode (0 uses the default of 300, -1 leaves out the Retry-After header)",
+				"placeholder": "e.g. 300",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("MaintenanceMessage", c, map[string]string{
+				"label":       "Message sent in maintenance mode, as the error of API responses",
+				"placeholder": "e.g. We'll be back shortly.",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Textarea("MaintenancePage", c, map[string]string{
+				"label":       "HTML page shown in maintenance mode for requests outside the API (leave blank to send the message as text)",
+				"placeholder": "e.g. <h1>Down for maintenance</h1>",
+			}),
+		},
+		editor.Field{
+			View: editor.Select("TracingExporter", c, map[string]string{
+				"label": "Export traces of requests to OpenTelemetry, disabled with None (takes effect on restart, env OTEL_TRACES_EXPORTER)",
+			}, map[string]string{
+				"otlp": "OTLP over HTTP, to an OpenTelemetry collector",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("TracingEndpoint", c, map[string]string{
+				"label":       "URL traces are sent to (leave empty for http://localhost:4318/v1/traces, env OTEL_EXPORTER_OTLP_ENDPOINT)",
+				"placeholder": "e.g. https://collector.example.com/v1/traces",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("TracingServiceName", c, map[string]string{
+				"label":       "Service name traces are recorded under (leave empty for ponzu, env OTEL_SERVICE_NAME)",
+				"placeholder": "e.g. ponzu",
+				"type":        "text",
+			}),
+		},
 		editor.Field{
 			View: editor.Input("AdminEmail", c, map[string]string{
 				"label": "Adminstrator Email (notified of internal system information)",
@@ -92,13 +329,97 @@ func (c *Config) MarshalEditor() ([]byte, error) {
 				"true": "Disable CORS",
 			}),
 		},
+		editor.Field{
+			View: editor.InputRepeater("CORSAllowedOrigins", c, map[string]string{
+				"label":       "Allowed Origins (leave empty to allow all, unless CORS is disabled)",
+				"type":        "text",
+				"placeholder": "e.g. https://example.com or *.example.com",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("APIKeyRequired", c, map[string]string{
+				"label": "Require API Keys (so only clients with a key can use the API)",
+			}, map[string]string{
+				"true": "Require API Keys",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("RateLimitRPS", c, map[string]string{
+				"label":       "API requests per second allowed from each client IP (0 for no limit)",
+				"placeholder": "e.g. 10",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("RateLimitBurst", c, map[string]string{
+				"label":       "API requests a client IP can make at once above its rate limit (0 uses the requests per second)",
+				"placeholder": "e.g. 20",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("AbuseThreshold", c, map[string]string{
+				"label":       "API requests per minute above which a client IP is flagged and given a tighter rate limit (0 to disable, takes effect on restart)",
+				"placeholder": "e.g. 600",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("SampleEvery", c, map[string]string{
+				"label":       "Record 1 in this many API requests in analytics, scaling the charted totals to match (0 or 1 records every request, takes effect on restart)",
+				"placeholder": "e.g. 10",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("ExternalSampleEvery", c, map[string]string{
+				"label":       "Record 1 in this many requests submitting external content in analytics (0 uses the rate above, takes effect on restart)",
+				"placeholder": "e.g. 1",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.InputRepeater("BotPatterns", c, map[string]string{
+				"label":       "User agent substrings of bots to mark in analytics, in addition to well known crawlers (takes effect on restart)",
+				"type":        "text",
+				"placeholder": "e.g. my-monitor",
+			}),
+		},
 		editor.Field{
 			View: editor.Checkbox("DisableGZIP", c, map[string]string{
-				"label": "Disable GZIP (will increase server speed, but also bandwidth)",
+				"label": "Disable GZIP and other response compression (will increase server speed, but also bandwidth)",
 			}, map[string]string{
 				"true": "Disable GZIP",
 			}),
 		},
+		editor.Field{
+			View: editor.Input("CompressionMinBytes", c, map[string]string{
+				"label":       "Smallest response in bytes which is compressed (0 uses the default of 1024)",
+				"placeholder": "e.g. 1024",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("ResponseCacheDisabled", c, map[string]string{
+				"label": "Disable the in-memory cache of content API responses",
+			}, map[string]string{
+				"true": "Disable Response Cache",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("ResponseCacheEntries", c, map[string]string{
+				"label":       "Content API responses kept in the response cache (0 uses the default of 1000)",
+				"placeholder": "e.g. 1000",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("ResponseCacheSeconds", c, map[string]string{
+				"label":       "Seconds a response is kept in the response cache, if content doesn't change first (0 uses the default of 60)",
+				"placeholder": "e.g. 60",
+				"type":        "number",
+			}),
+		},
 		editor.Field{
 			View: editor.Checkbox("CacheInvalidate", c, map[string]string{
 				"label": "Invalidate cache on save",
@@ -123,6 +444,337 @@ func (c *Config) MarshalEditor() ([]byte, error) {
 				"type":        "password",
 			}),
 		},
+		editor.Field{
+			View: []byte(revisionsInfo),
+		},
+		editor.Field{
+			View: editor.Input("MaxRevisions", c, map[string]string{
+				"label":       "Revisions kept per item (0 uses the default of 10)",
+				"placeholder": "e.g. 10",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("PublishInterval", c, map[string]string{
+				"label":       "Seconds between checks for scheduled content to publish (0 uses the default of 60)",
+				"placeholder": "e.g. 60",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("WorkflowEnabled", c, map[string]string{
+				"label": "Content must be approved by a role allowed to publish it, moving from draft to pending review to published",
+			}, map[string]string{
+				"true": "Enable Approval Workflow",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("SlugRedirects", c, map[string]string{
+				"label": "When the slug of content is changed, requests for its old slug are redirected to the new one",
+			}, map[string]string{
+				"true": "Redirect Changed Slugs",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("SlugRedirectPath", c, map[string]string{
+				"label":       "Path of content on your site, with {slug} in place of its slug, to add a redirect from when a slug is changed",
+				"placeholder": "e.g. /blog/{slug}",
+			}),
+		},
+		editor.Field{
+			View: editor.Select("SearchIndexCheck", c, map[string]string{
+				"label": "On startup, compare the search index with the content stored, unchecked with None",
+			}, map[string]string{
+				"warn":    "Log a warning if they differ",
+				"reindex": "Rebuild the index if they differ",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("SearchDisabledTypes", c, map[string]string{
+				"label": "Searchable content types to leave out of the search index (their index is removed, and rebuilt once they are searchable again)",
+			}, searchableTypes()),
+		},
+		editor.Field{
+			View: editor.Textarea("SearchSynonyms", c, map[string]string{
+				"label":       "Search synonyms, one rule per line: comma separated words match each other, and words left of => also match those on the right",
+				"placeholder": "e.g. car, automobile or sneakers => shoes",
+			}),
+		},
+		editor.Field{
+			View: editor.InputRepeater("SearchAnalyzers", c, map[string]string{
+				"label":       "Search languages, as Type=language for a content type, Type.field=language for a field or Type@locale=language for a translation (* for every type), from english, french, german, spanish, italian, portuguese or standard (the changed types are reindexed)",
+				"type":        "text",
+				"placeholder": "e.g. Post@fr=french",
+			}),
+		},
+		editor.Field{
+			View: editor.InputRepeater("SearchStopWords", c, map[string]string{
+				"label":       "Words left out of the search index and queries, in addition to the common words of each language (all content is reindexed)",
+				"type":        "text",
+				"placeholder": "e.g. ponzu",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("DefaultLocale", c, map[string]string{
+				"label":       "Locale content is written in (leave empty for en)",
+				"type":        "text",
+				"placeholder": "e.g. en",
+			}),
+		},
+		editor.Field{
+			View: editor.InputRepeater("Locales", c, map[string]string{
+				"label":       "Other locales content can be translated into, served by the API for ?locale= or Accept-Language",
+				"type":        "text",
+				"placeholder": "e.g. fr",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("PreviewURL", c, map[string]string{
+				"label":       "Public site URL to open previews of content at, with {type}, {id} and {token} replaced (leave empty to open the content API)",
+				"type":        "text",
+				"placeholder": "e.g. https://example.com/preview?type={type}&id={id}&token={token}",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("FeedItems", c, map[string]string{
+				"label":       "Items in the RSS and Atom feeds of content types (0 uses the default of 20)",
+				"placeholder": "e.g. 20",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("TrashRetentionDays", c, map[string]string{
+				"label":       "Days deleted content is kept in the Trash (0 uses the default of 30)",
+				"placeholder": "e.g. 30",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("AuditRetentionDays", c, map[string]string{
+				"label":       "Days actions are kept in the Audit Log (0 uses the default of 90)",
+				"placeholder": "e.g. 90",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("ImageVariants", c, map[string]string{
+				"label":       "Resized copies made of uploaded images, as name=WIDTHxHEIGHT (0 leaves a side unconstrained), requested with ?variant=name",
+				"placeholder": "e.g. thumb=150x150, medium=800x0",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Select("UploadStorage", c, map[string]string{
+				"label": "Upload storage",
+			}, map[string]string{
+				"local": "Local disk (uploads directory, the default)",
+				"s3":    "S3-compatible object storage",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("S3Endpoint", c, map[string]string{
+				"label":       "S3 endpoint (leave empty for Amazon S3)",
+				"placeholder": "e.g. http://localhost:9000",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("S3Region", c, map[string]string{
+				"label":       "S3 region",
+				"placeholder": "e.g. us-east-1",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("S3Bucket", c, map[string]string{
+				"label":       "S3 bucket",
+				"placeholder": "Enter the name of the bucket for uploads",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("S3AccessKeyID", c, map[string]string{
+				"label":       "S3 access key ID",
+				"placeholder": "Enter the access key ID",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("S3SecretAccessKey", c, map[string]string{
+				"label":       "S3 secret access key",
+				"placeholder": "Enter the secret access key",
+				"type":        "password",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("Require2FA", c, map[string]string{
+				"label": "Require two-factor authentication (users must enroll before using the admin)",
+			}, map[string]string{
+				"true": "Require Two-Factor Authentication",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("LoginMaxAttempts", c, map[string]string{
+				"label":       "Failed logins allowed for an account before it is locked (0 uses the default of 5)",
+				"placeholder": "e.g. 5",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("LoginMaxAttemptsIP", c, map[string]string{
+				"label":       "Failed logins allowed from an IP address before it is locked (0 uses the default of 20)",
+				"placeholder": "e.g. 20",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("LoginLockoutMinutes", c, map[string]string{
+				"label":       "Minutes of the first lockout, doubling with each lockout after it (0 uses the default of 5)",
+				"placeholder": "e.g. 5",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("PasswordMinLength", c, map[string]string{
+				"label":       "Minimum length of new passwords (0 uses the default of 8)",
+				"placeholder": "e.g. 12",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("PasswordRequireUpper", c, map[string]string{
+				"label": "New passwords must contain",
+			}, map[string]string{
+				"true": "An uppercase letter",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("PasswordRequireLower", c, map[string]string{
+				"label": "New passwords must contain",
+			}, map[string]string{
+				"true": "A lowercase letter",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("PasswordRequireDigit", c, map[string]string{
+				"label": "New passwords must contain",
+			}, map[string]string{
+				"true": "A number",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("PasswordRequireSymbol", c, map[string]string{
+				"label": "New passwords must contain",
+			}, map[string]string{
+				"true": "A symbol",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("BcryptCost", c, map[string]string{
+				"label":       "bcrypt cost of password hashes, from 4 to 31 (0 uses the default of 10)",
+				"placeholder": "e.g. 12",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Checkbox("BcryptUpgrade", c, map[string]string{
+				"label": "Rehash passwords with a different cost when users next log in",
+			}, map[string]string{
+				"true": "Upgrade Password Hashes",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("PasswordResetMinutes", c, map[string]string{
+				"label":       "Minutes a password reset link can be used for (0 uses the default of 60)",
+				"placeholder": "e.g. 60",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("PasswordResetMaxRequest", c, map[string]string{
+				"label":       "Password reset emails which can be requested for an account per hour (0 uses the default of 3)",
+				"placeholder": "e.g. 3",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("SessionIdleMinutes", c, map[string]string{
+				"label":       "Minutes an admin session can go unused before the user must log in again (0 uses the default of 60)",
+				"placeholder": "e.g. 60",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("SessionMaxHours", c, map[string]string{
+				"label":       "Hours an admin session can last at most (0 uses the default of 24)",
+				"placeholder": "e.g. 24",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("SessionRememberDays", c, map[string]string{
+				"label":       "Days a \"remember me\" session lasts, which does not end when unused (0 uses the default of 30)",
+				"placeholder": "e.g. 30",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("InviteExpiryHours", c, map[string]string{
+				"label":       "Hours an invitation to become an admin user can be accepted for (0 uses the default of 72)",
+				"placeholder": "e.g. 72",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: []byte(emailInfo),
+		},
+		editor.Field{
+			View: editor.Input("SMTPHost", c, map[string]string{
+				"label":       "SMTP server host",
+				"placeholder": "e.g. smtp.example.com",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("SMTPPort", c, map[string]string{
+				"label":       "SMTP server port (0 uses 587, or 465 with TLS)",
+				"placeholder": "e.g. 587",
+				"type":        "number",
+			}),
+		},
+		editor.Field{
+			View: editor.Select("SMTPTLS", c, map[string]string{
+				"label": "SMTP encryption (unset uses STARTTLS if the server offers it)",
+			}, map[string]string{
+				"starttls": "STARTTLS (usually port 587)",
+				"tls":      "TLS (usually port 465)",
+				"none":     "None (not recommended)",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("SMTPUsername", c, map[string]string{
+				"label":       "SMTP user name (leave blank if the server doesn't require login)",
+				"placeholder": "Enter the user name",
+				"type":        "text",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("SMTPPassword", c, map[string]string{
+				"label":       "SMTP password",
+				"placeholder": "Enter the password",
+				"type":        "password",
+			}),
+		},
+		editor.Field{
+			View: editor.Input("SMTPFrom", c, map[string]string{
+				"label":       "Address email is sent from (blank uses ponzu@ the domain)",
+				"placeholder": "e.g. cms@example.com",
+				"type":        "email",
+			}),
+		},
+		editor.Field{
+			View: []byte(emailTest),
+		},
 	)
 	if err != nil {
 		return nil, err
@@ -169,3 +821,16 @@ func (c *Config) MarshalEditor() ([]byte, error) {
 
 	return view, nil
 }
+
+// searchableTypes returns the names of the content types which index their
+// content for search, as options of a checkbox
+func searchableTypes() map[string]string {
+	types := make(map[string]string)
+	for name, it := range item.Types {
+		if s, ok := it().(item.Searchable); ok && s.IndexContent() {
+			types[name] = name
+		}
+	}
+
+	return types
+}
@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
-	"log"
+	"html"
+	"io"
+	"io/ioutil"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -21,18 +26,21 @@ import (
 	"github.com/ponzu-cms/ponzu/system/api"
 	"github.com/ponzu-cms/ponzu/system/api/analytics"
 	"github.com/ponzu-cms/ponzu/system/db"
+	emailer "github.com/ponzu-cms/ponzu/system/email"
 	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/scheduler"
+	"github.com/ponzu-cms/ponzu/system/search"
 
 	"github.com/gorilla/schema"
-	emailer "github.com/nilslice/email"
 	"github.com/nilslice/jwt"
 	"github.com/tidwall/gjson"
 )
 
 func adminHandler(res http.ResponseWriter, req *http.Request) {
-	view, err := Dashboard()
+	view, err := Dashboard(req)
 	if err != nil {
-		log.Println(err)
+		logger.For(req).Error(err)
 		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -51,7 +59,7 @@ func initHandler(res http.ResponseWriter, req *http.Request) {
 	case http.MethodGet:
 		view, err := Init()
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -61,7 +69,7 @@ func initHandler(res http.ResponseWriter, req *http.Request) {
 	case http.MethodPost:
 		err := req.ParseForm()
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -78,16 +86,21 @@ func initHandler(res http.ResponseWriter, req *http.Request) {
 		// create and save admin user
 		email := strings.ToLower(req.FormValue("email"))
 		password := req.FormValue("password")
-		usr, err := user.New(email, password)
+		usr, err := newUser(email, password)
+		if _, ok := err.(*user.PasswordError); ok {
+			res.WriteHeader(http.StatusBadRequest)
+			res.Write([]byte(html.EscapeString(err.Error()) + " Please go back and try again."))
+			return
+		}
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		_, err = db.SetUser(usr)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -100,27 +113,19 @@ func initHandler(res http.ResponseWriter, req *http.Request) {
 		req.Form.Set("admin_email", email)
 		err = db.SetConfig(req.Form)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		// add _token cookie for login persistence
-		week := time.Now().Add(time.Hour * 24 * 7)
-		claims := map[string]interface{}{
-			"exp":  week.Unix(),
-			"user": usr.Email,
-		}
-
+		// log in the new admin user, with a token signed by the new secret
 		jwt.Secret([]byte(secret))
-		token, err := jwt.New(claims)
-
-		http.SetCookie(res, &http.Cookie{
-			Name:    "_token",
-			Value:   token,
-			Expires: week,
-			Path:    "/",
-		})
+		err = setLoginToken(res, req, usr.Email, false)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
 		redir := strings.TrimSuffix(req.URL.String(), "/init")
 		http.Redirect(res, req, redir, http.StatusFound)
@@ -135,7 +140,7 @@ func configHandler(res http.ResponseWriter, req *http.Request) {
 	case http.MethodGet:
 		data, err := db.ConfigAll()
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -144,21 +149,33 @@ func configHandler(res http.ResponseWriter, req *http.Request) {
 
 		err = json.Unmarshal(data, c)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		cfg, err := c.MarshalEditor()
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		adminView, err := Admin(cfg)
+		// the form shows the stored config, which the environment overrides
+		if keys := db.EnvOverrides(); len(keys) > 0 {
+			vars := make([]string, 0, len(keys))
+			for _, k := range keys {
+				vars = append(vars, "<code>"+html.EscapeString(db.EnvVar(k))+"</code>")
+			}
+
+			notice := `<div class="card-panel amber lighten-4">These settings are set by environment variables, which take precedence over the values saved here: ` +
+				strings.Join(vars, ", ") + `</div>`
+			cfg = append([]byte(notice), cfg...)
+		}
+
+		adminView, err := Admin(req, cfg)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -169,18 +186,32 @@ func configHandler(res http.ResponseWriter, req *http.Request) {
 	case http.MethodPost:
 		err := req.ParseForm()
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		before, err := db.ConfigAll()
+		if err != nil {
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		err = db.SetConfig(req.Form)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
+		after, err := db.ConfigAll()
+		if err == nil {
+			audit(req, "config.update", "config", map[string]string{
+				"changed": strings.Join(changedKeys(before, after), ", "),
+			})
+		}
+
 		http.Redirect(res, req, req.URL.String(), http.StatusFound)
 
 	default:
@@ -189,12 +220,57 @@ func configHandler(res http.ResponseWriter, req *http.Request) {
 
 }
 
+// configEmailTestHandler sends a test email to the current user with the saved
+// email settings, writing the address it was sent to or the error as JSON
+func configEmailTestHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	j, err := db.CurrentUser(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	to := gjson.GetBytes(j, "email").String()
+	domain, _ := db.ConfigCache("domain").(string)
+	body := fmt.Sprintf(`
+This is a test email from Ponzu CMS at %s, sent from the Configuration page.
+
+If you can read it, email is set up correctly.
+
+`, domain)
+
+	result := map[string]string{"to": to}
+	err = emailer.Send(to, fmt.Sprintf("Test Email [%s]", domain), body)
+	if err != nil {
+		logger.For(req).Error("Failed to send test email to:", to, "Error:", err)
+		result["error"] = err.Error()
+	}
+
+	resp, err := json.Marshal(result)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	if _, failed := result["error"]; failed {
+		res.WriteHeader(http.StatusBadGateway)
+	}
+	res.Write(resp)
+}
+
 func backupHandler(res http.ResponseWriter, req *http.Request) {
 	switch req.URL.Query().Get("source") {
 	case "system":
 		err := db.Backup(res)
 		if err != nil {
-			log.Println("Failed to run backup on system:", err)
+			logger.For(req).Error("Failed to run backup on system:", err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -202,7 +278,7 @@ func backupHandler(res http.ResponseWriter, req *http.Request) {
 	case "analytics":
 		err := analytics.Backup(res)
 		if err != nil {
-			log.Println("Failed to run backup on analytics:", err)
+			logger.For(req).Error("Failed to run backup on analytics:", err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -210,7 +286,7 @@ func backupHandler(res http.ResponseWriter, req *http.Request) {
 	case "uploads":
 		err := upload.Backup(res)
 		if err != nil {
-			log.Println("Failed to run backup on uploads:", err)
+			logger.For(req).Error("Failed to run backup on uploads:", err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -220,14 +296,149 @@ func backupHandler(res http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// reindexHandler rebuilds the search index of the content type in the type
+// query param, or of every searchable type without it, while serving
+func reindexHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var types []string
+	if t := req.URL.Query().Get("type"); t != "" {
+		if !search.Searchable(t) {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		types = append(types, t)
+	}
+
+	err := db.ReindexSearch(types...)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.WriteHeader(http.StatusOK)
+}
+
+// migrateHandler migrates the content of the types given by the type query
+// param, or of every type with migrations, and responds with the reports of
+// each as json. With dry_run=true nothing is saved.
+func migrateHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := req.URL.Query()
+	types := q["type"]
+	for _, t := range types {
+		it, ok := item.Types[t]
+		if !ok {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := it().(item.Migratable); !ok {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	reports, err := db.MigrateContent(q.Get("dry_run") == "true", types...)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	j, err := json.Marshal(reports)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(j)
+}
+
+// metricsHandler writes the API request metrics and those of the scheduled
+// jobs in the Prometheus text exposition format
+func metricsHandler(res http.ResponseWriter, req *http.Request) {
+	analytics.MetricsHandler().ServeHTTP(res, req)
+	scheduler.WriteMetrics(res)
+}
+
+func analyticsExportHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := req.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	if !analytics.ValidExportFormat(format) {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// from and to are optional dates (YYYY-MM-DD), and default to exporting
+	// all stored analytics
+	var from, to time.Time
+	var err error
+	if q.Get("from") != "" {
+		from, err = time.Parse("2006-01-02", q.Get("from"))
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	if q.Get("to") != "" {
+		to, err = time.Parse("2006-01-02", q.Get("to"))
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// include all requests made on the to date
+		to = to.AddDate(0, 0, 1)
+	}
+
+	contentType := "text/csv"
+	if format == "json" {
+		contentType = "application/json"
+	}
+
+	ts := time.Now().Unix()
+	filename := fmt.Sprintf("analytics-%d.%s", ts, format)
+
+	res.Header().Set("Content-Type", contentType)
+	res.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	err = analytics.Export(res, format, from, to)
+	if err != nil {
+		logger.For(req).Error("Failed to export analytics:", err)
+		return
+	}
+}
+
 func configUsersHandler(res http.ResponseWriter, req *http.Request) {
 	switch req.Method {
 	case http.MethodGet:
 		view, err := UsersList(req)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -242,9 +453,9 @@ func configUsersHandler(res http.ResponseWriter, req *http.Request) {
 		// create new user
 		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -258,7 +469,19 @@ func configUsersHandler(res http.ResponseWriter, req *http.Request) {
 
 		if email == "" || password == "" {
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		usr, err := newUser(email, password)
+		if _, ok := err.(*user.PasswordError); ok {
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := ErrorMessage(req, "Password not accepted", html.EscapeString(err.Error()))
 			if err != nil {
 				return
 			}
@@ -266,19 +489,36 @@ func configUsersHandler(res http.ResponseWriter, req *http.Request) {
 			res.Write(errView)
 			return
 		}
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-		usr, err := user.New(email, password)
+		caller, err := db.CurrentRole(req)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		usr.Role, err = roleName(caller, req.PostFormValue("role"))
+		if err != nil {
+			roleError(res, req, err)
 			return
 		}
 
 		_, err = db.SetUser(usr)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -287,6 +527,8 @@ func configUsersHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
+		audit(req, "user.create", usr.Email, map[string]string{"role": usr.Role})
+
 		http.Redirect(res, req, req.URL.String(), http.StatusFound)
 
 	default:
@@ -299,9 +541,9 @@ func configUsersEditHandler(res http.ResponseWriter, req *http.Request) {
 	case http.MethodPost:
 		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -313,9 +555,9 @@ func configUsersEditHandler(res http.ResponseWriter, req *http.Request) {
 		// check if user to be edited is current user
 		j, err := db.CurrentUser(req)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -327,9 +569,9 @@ func configUsersEditHandler(res http.ResponseWriter, req *http.Request) {
 		usr := &user.User{}
 		err = json.Unmarshal(j, usr)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -342,9 +584,9 @@ func configUsersEditHandler(res http.ResponseWriter, req *http.Request) {
 		password := req.PostFormValue("password")
 
 		if !user.IsUser(usr, password) {
-			log.Println("Unexpected user/password combination for", usr.Email)
+			logger.For(req).Warn("Unexpected user/password combination for", usr.Email)
 			res.WriteHeader(http.StatusBadRequest)
-			errView, err := Error405()
+			errView, err := Error405(req)
 			if err != nil {
 				return
 			}
@@ -357,30 +599,43 @@ func configUsersEditHandler(res http.ResponseWriter, req *http.Request) {
 		newPassword := req.PostFormValue("new_password")
 		var updatedUser *user.User
 		if newPassword != "" {
-			updatedUser, err = user.New(email, newPassword)
+			updatedUser, err = newUser(email, newPassword)
+			if _, ok := err.(*user.PasswordError); ok {
+				res.WriteHeader(http.StatusBadRequest)
+				errView, err := ErrorMessage(req, "Password not accepted", html.EscapeString(err.Error()))
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 				res.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 		} else {
-			updatedUser, err = user.New(email, password)
+			updatedUser, err = user.NewWithCost(email, password, bcryptCost())
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 				res.WriteHeader(http.StatusInternalServerError)
 				return
 			}
 		}
 
-		// set the ID to the same ID as current user
+		// keep the ID, role and second factor of the current user
 		updatedUser.ID = usr.ID
+		updatedUser.Role = usr.Role
+		updatedUser.TOTPSecret = usr.TOTPSecret
+		updatedUser.RecoveryCodes = usr.RecoveryCodes
 
 		// set user in db
 		err = db.UpdateUser(usr, updatedUser)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -389,17 +644,33 @@ func configUsersEditHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// create new token
-		week := time.Now().Add(time.Hour * 24 * 7)
-		claims := map[string]interface{}{
-			"exp":  week,
-			"user": updatedUser.Email,
+		details := map[string]string{}
+		if updatedUser.Email != usr.Email {
+			details["email"] = updatedUser.Email
+		}
+		if newPassword != "" {
+			details["password"] = "changed"
+
+			err = db.DeleteRecoveryKey(usr.Email)
+			if err != nil {
+				logger.For(req).Error("Error removing recovery key of user:", err)
+			}
+		}
+		audit(req, "user.update", usr.Email, details)
+
+		// end the user's sessions, which belong to their old email or password,
+		// and start a new one
+		sess, err := db.CurrentSession(req)
+		if err == nil {
+			err = db.RevokeSessions(usr.Email, "")
+		}
+		if err == nil {
+			err = setLoginToken(res, req, updatedUser.Email, sess.Remember)
 		}
-		token, err := jwt.New(claims)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -408,18 +679,6 @@ func configUsersEditHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		// add token to cookie +1 week expiration
-		cookie := &http.Cookie{
-			Name:    "_token",
-			Value:   token,
-			Expires: week,
-			Path:    "/",
-		}
-		http.SetCookie(res, cookie)
-
-		// add new token cookie to the request
-		req.AddCookie(cookie)
-
 		http.Redirect(res, req, strings.TrimSuffix(req.URL.String(), "/edit"), http.StatusFound)
 
 	default:
@@ -432,9 +691,9 @@ func configUsersDeleteHandler(res http.ResponseWriter, req *http.Request) {
 	case http.MethodPost:
 		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -446,9 +705,9 @@ func configUsersDeleteHandler(res http.ResponseWriter, req *http.Request) {
 		// do not allow current user to delete themselves
 		j, err := db.CurrentUser(req)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -460,9 +719,9 @@ func configUsersDeleteHandler(res http.ResponseWriter, req *http.Request) {
 		usr := &user.User{}
 		err = json.Unmarshal(j, &usr)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -474,9 +733,23 @@ func configUsersDeleteHandler(res http.ResponseWriter, req *http.Request) {
 		email := strings.ToLower(req.PostFormValue("email"))
 
 		if usr.Email == email {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusBadRequest)
-			errView, err := Error405()
+			errView, err := Error405(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		// a user can't delete a user whose role allows more than their own
+		caller, err := db.CurrentRole(req)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -485,12 +758,39 @@ func configUsersDeleteHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
+		target, err := db.User(email)
+		if err == nil {
+			current, err := db.Role(gjson.GetBytes(target, "role").String())
+			if err != nil && err != db.ErrNoRoleExists {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			if !caller.Covers(current) {
+				res.WriteHeader(http.StatusForbidden)
+				errView, err := Error403(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+		}
+
 		// delete existing user
 		err = db.DeleteUser(email)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -499,6 +799,13 @@ func configUsersDeleteHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
+		err = db.RevokeSessions(email, "")
+		if err != nil {
+			logger.For(req).Error("Error ending sessions of deleted user:", err)
+		}
+
+		audit(req, "user.delete", email, nil)
+
 		http.Redirect(res, req, strings.TrimSuffix(req.URL.String(), "/delete"), http.StatusFound)
 
 	default:
@@ -506,127 +813,156 @@ func configUsersDeleteHandler(res http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func loginHandler(res http.ResponseWriter, req *http.Request) {
-	if !db.SystemInitComplete() {
-		redir := req.URL.Scheme + req.URL.Host + "/admin/init"
-		http.Redirect(res, req, redir, http.StatusFound)
+// configUsersRoleHandler assigns a role to another user
+func configUsersRoleHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	switch req.Method {
-	case http.MethodGet:
-		if user.IsValid(req) {
-			http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin", http.StatusFound)
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
 			return
 		}
 
-		view, err := Login()
+		res.Write(errView)
+		return
+	}
+
+	caller, err := db.CurrentRole(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
 		if err != nil {
-			log.Println(err)
-			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
-		res.Header().Set("Content-Type", "text/html")
-		res.Write(view)
+		res.Write(errView)
+		return
+	}
 
-	case http.MethodPost:
-		if user.IsValid(req) {
-			http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin", http.StatusFound)
-			return
-		}
+	email := strings.ToLower(req.PostFormValue("email"))
+	role, err := roleName(caller, req.PostFormValue("role"))
+	if err != nil {
+		roleError(res, req, err)
+		return
+	}
 
-		err := req.ParseForm()
+	// do not allow current user to change their own role, and lose access to
+	// user management
+	cur, err := db.CurrentUser(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
 		if err != nil {
-			log.Println(err)
-			http.Redirect(res, req, req.URL.String(), http.StatusFound)
 			return
 		}
 
-		// check email & password
-		j, err := db.User(strings.ToLower(req.FormValue("email")))
+		res.Write(errView)
+		return
+	}
+
+	if gjson.GetBytes(cur, "email").String() == email {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := ErrorMessage(req, "Role not changed", "You can't change your own role.")
 		if err != nil {
-			log.Println(err)
-			http.Redirect(res, req, req.URL.String(), http.StatusFound)
 			return
 		}
 
-		if j == nil {
-			http.Redirect(res, req, req.URL.String(), http.StatusFound)
-			return
-		}
+		res.Write(errView)
+		return
+	}
 
-		usr := &user.User{}
-		err = json.Unmarshal(j, usr)
+	j, err := db.User(email)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusNotFound)
+		errView, err := Error404(req)
 		if err != nil {
-			log.Println(err)
-			http.Redirect(res, req, req.URL.String(), http.StatusFound)
 			return
 		}
 
-		if !user.IsUser(usr, req.FormValue("password")) {
-			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+		res.Write(errView)
+		return
+	}
+
+	usr := &user.User{}
+	err = json.Unmarshal(j, usr)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
 			return
 		}
-		// create new token
-		week := time.Now().Add(time.Hour * 24 * 7)
-		claims := map[string]interface{}{
-			"exp":  week,
-			"user": usr.Email,
-		}
-		token, err := jwt.New(claims)
+
+		res.Write(errView)
+		return
+	}
+
+	// a user can't take away a role which allows more than their own, such
+	// as demoting a super admin
+	current, err := db.Role(usr.Role)
+	if err != nil && err != db.ErrNoRoleExists {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
 		if err != nil {
-			log.Println(err)
-			http.Redirect(res, req, req.URL.String(), http.StatusFound)
 			return
 		}
 
-		// add it to cookie +1 week expiration
-		http.SetCookie(res, &http.Cookie{
-			Name:    "_token",
-			Value:   token,
-			Expires: week,
-			Path:    "/",
-		})
-
-		http.Redirect(res, req, strings.TrimSuffix(req.URL.String(), "/login"), http.StatusFound)
+		res.Write(errView)
+		return
 	}
-}
 
-func logoutHandler(res http.ResponseWriter, req *http.Request) {
-	http.SetCookie(res, &http.Cookie{
-		Name:    "_token",
-		Expires: time.Unix(0, 0),
-		Value:   "",
-		Path:    "/",
-	})
+	if !caller.Covers(current) {
+		res.WriteHeader(http.StatusForbidden)
+		errView, err := Error403(req)
+		if err != nil {
+			return
+		}
 
-	http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin/login", http.StatusFound)
-}
+		res.Write(errView)
+		return
+	}
 
-func forgotPasswordHandler(res http.ResponseWriter, req *http.Request) {
-	switch req.Method {
-	case http.MethodGet:
-		view, err := ForgotPassword()
-		if err != nil {
-			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
-			if err != nil {
-				return
-			}
+	updated := *usr
+	updated.Role = role
 
-			res.Write(errView)
+	err = db.UpdateUser(usr, &updated)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
 			return
 		}
 
-		res.Write(view)
+		res.Write(errView)
+		return
+	}
 
-	case http.MethodPost:
+	audit(req, "user.role", email, map[string]string{"from": usr.Role, "to": role})
+
+	http.Redirect(res, req, strings.TrimSuffix(req.URL.String(), "/role"), http.StatusFound)
+}
+
+// configUsersSessionsHandler shows the active sessions of a user, and revokes
+// one or all of them. Users can manage their own sessions, and those who manage
+// users can manage anyone's.
+func configUsersSessionsHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPost {
 		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -634,81 +970,101 @@ func forgotPasswordHandler(res http.ResponseWriter, req *http.Request) {
 			res.Write(errView)
 			return
 		}
+	}
 
-		// check email for user, if no user return Error
-		email := strings.ToLower(req.FormValue("email"))
-		if email == "" {
-			res.WriteHeader(http.StatusBadRequest)
-			log.Println("Failed account recovery. No email address submitted.")
-			return
-		}
-
-		_, err = db.User(email)
-		if err == db.ErrNoUserExists {
-			res.WriteHeader(http.StatusBadRequest)
-			log.Println("No user exists.", err)
+	email := strings.ToLower(req.FormValue("email"))
+	cur, err := db.CurrentSession(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
 			return
 		}
 
-		if err != db.ErrNoUserExists && err != nil {
-			res.WriteHeader(http.StatusInternalServerError)
-			log.Println("Error:", err)
-			return
-		}
+		res.Write(errView)
+		return
+	}
 
-		// create temporary key to verify user
-		key, err := db.SetRecoveryKey(email)
+	role, err := db.CurrentRole(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
 		if err != nil {
-			res.WriteHeader(http.StatusInternalServerError)
-			log.Println("Failed to set account recovery key.", err)
 			return
 		}
 
-		domain, err := db.Config("domain")
+		res.Write(errView)
+		return
+	}
+
+	if email != cur.User && !role.Manages(user.SectionUsers) {
+		res.WriteHeader(http.StatusForbidden)
+		errView, err := Error403(req)
 		if err != nil {
-			res.WriteHeader(http.StatusInternalServerError)
-			log.Println("Failed to get domain from configuration.", err)
 			return
 		}
 
-		body := fmt.Sprintf(`
-There has been an account recovery request made for the user with email:
-%s
-
-To recover your account, please go to http://%s/admin/recover/key and enter 
-this email address along with the following secret key:
-
-%s
-
-If you did not make the request, ignore this message and your password 
-will remain as-is.
-
-
-Thank you,
-Ponzu CMS at %s
+		res.Write(errView)
+		return
+	}
 
-`, email, domain, key, domain)
+	switch req.Method {
+	case http.MethodGet:
 
-		msg := emailer.Message{
-			To:      email,
-			From:    fmt.Sprintf("ponzu@%s", domain),
-			Subject: fmt.Sprintf("Account Recovery [%s]", domain),
-			Body:    body,
+	case http.MethodPost:
+		id := req.PostFormValue("id")
+		if id == "all" {
+			// keep the current session when revoking the user's own
+			err = db.RevokeSessions(email, cur.ID)
+		} else {
+			var s db.Session
+			s, err = db.GetSession(id)
+			if err == nil && s.User != email {
+				err = db.ErrNoSession
+			}
+			if err == nil {
+				err = db.RevokeSession(id)
+			}
 		}
+		if err == db.ErrNoSession {
+			res.WriteHeader(http.StatusNotFound)
+			errView, err := Error404(req)
+			if err != nil {
+				return
+			}
 
-		go func() {
-			err = msg.Send()
+			res.Write(errView)
+			return
+		}
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
 			if err != nil {
-				log.Println("Failed to send message to:", msg.To, "about", msg.Subject, "Error:", err)
+				return
 			}
-		}()
 
-		// redirect to /admin/recover/key and send email with key and URL
-		http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin/recover/key", http.StatusFound)
+			res.Write(errView)
+			return
+		}
+
+		audit(req, "user.session.revoke", email, map[string]string{"session": id})
+
+		http.Redirect(res, req, req.URL.String(), http.StatusFound)
+		return
 
 	default:
 		res.WriteHeader(http.StatusMethodNotAllowed)
-		errView, err := Error405()
+		return
+	}
+
+	sessions, err := db.Sessions(email)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
 		if err != nil {
 			return
 		}
@@ -716,115 +1072,113 @@ Ponzu CMS at %s
 		res.Write(errView)
 		return
 	}
-}
 
-func recoveryKeyHandler(res http.ResponseWriter, req *http.Request) {
-	switch req.Method {
-	case http.MethodGet:
-		view, err := RecoveryKey()
-		if err != nil {
-			res.WriteHeader(http.StatusInternalServerError)
-			return
+	var active []db.Session
+	now := time.Now()
+	for _, s := range sessions {
+		if db.SessionActive(s, now) {
+			active = append(active, s)
 		}
+	}
 
-		res.Write(view)
-
-	case http.MethodPost:
-		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	view, err := Sessions(req, email, active)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
 		if err != nil {
-			log.Println("Error parsing recovery key form:", err)
-
-			res.WriteHeader(http.StatusInternalServerError)
-			res.Write([]byte("Error, please go back and try again."))
 			return
 		}
 
-		// check for email & key match
-		email := strings.ToLower(req.FormValue("email"))
-		key := req.FormValue("key")
-
-		var actual string
-		if actual, err = db.RecoveryKey(email); err != nil || actual == "" {
-			log.Println("Error getting recovery key from database:", err)
+		res.Write(errView)
+		return
+	}
 
-			res.WriteHeader(http.StatusInternalServerError)
-			res.Write([]byte("Error, please go back and try again."))
-			return
-		}
+	res.Write(view)
+}
 
-		if key != actual {
-			log.Println("Bad recovery key submitted:", key)
+// configUsersInviteHandler invites the owner of the email in the form to become
+// an admin user with the role in the form, sending them an email to accept it
+func configUsersInviteHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
 
-			res.WriteHeader(http.StatusBadRequest)
-			res.Write([]byte("Error, please go back and try again."))
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
 			return
 		}
 
-		// set user with new password
-		password := req.FormValue("password")
-		usr := &user.User{}
-		u, err := db.User(email)
-		if err != nil {
-			log.Println("Error finding user by email:", email, err)
+		res.Write(errView)
+		return
+	}
 
-			res.WriteHeader(http.StatusInternalServerError)
-			res.Write([]byte("Error, please go back and try again."))
+	caller, err := db.CurrentRole(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
 			return
 		}
 
-		if u == nil {
-			log.Println("No user found with email:", email)
+		res.Write(errView)
+		return
+	}
 
-			res.WriteHeader(http.StatusBadRequest)
-			res.Write([]byte("Error, please go back and try again."))
-			return
-		}
+	email := strings.ToLower(strings.TrimSpace(req.PostFormValue("email")))
+	role, err := roleName(caller, req.PostFormValue("role"))
+	if err != nil {
+		roleError(res, req, err)
+		return
+	}
 
-		err = json.Unmarshal(u, usr)
+	if !strings.Contains(email, "@") {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
 		if err != nil {
-			log.Println("Error decoding user from database:", err)
-
-			res.WriteHeader(http.StatusInternalServerError)
-			res.Write([]byte("Error, please go back and try again."))
 			return
 		}
 
-		update, err := user.New(email, password)
-		if err != nil {
-			log.Println(err)
+		res.Write(errView)
+		return
+	}
 
-			res.WriteHeader(http.StatusInternalServerError)
-			res.Write([]byte("Error, please go back and try again."))
+	_, err = db.User(email)
+	if err == nil {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := ErrorMessage(req, "Invitation not sent", html.EscapeString(email)+" already has an account.")
+		if err != nil {
 			return
 		}
 
-		update.ID = usr.ID
+		res.Write(errView)
+		return
+	}
 
-		err = db.UpdateUser(usr, update)
+	cur, err := db.CurrentUser(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
 		if err != nil {
-			log.Println("Error updating user:", err)
-
-			res.WriteHeader(http.StatusInternalServerError)
-			res.Write([]byte("Error, please go back and try again."))
 			return
 		}
 
-		// redirect to /admin/login
-		redir := req.URL.Scheme + req.URL.Host + "/admin/login"
-		http.Redirect(res, req, redir, http.StatusFound)
-
-	default:
-		res.WriteHeader(http.StatusMethodNotAllowed)
+		res.Write(errView)
 		return
 	}
-}
 
-func contentsHandler(res http.ResponseWriter, req *http.Request) {
-	q := req.URL.Query()
-	t := q.Get("type")
-	if t == "" {
-		res.WriteHeader(http.StatusBadRequest)
-		errView, err := Error400()
+	err = sendInvite(email, role, gjson.GetBytes(cur, "email").String())
+	if err != nil {
+		logger.For(req).Error("Failed to send invitation to:", email, "Error:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := ErrorMessage(req, "Invitation not sent", "The invitation email couldn't be sent: "+html.EscapeString(err.Error()))
 		if err != nil {
 			return
 		}
@@ -833,16 +1187,24 @@ func contentsHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	order := strings.ToLower(q.Get("order"))
-	if order != "asc" {
-		order = "desc"
-	}
+	audit(req, "user.invite", email, map[string]string{"role": role})
 
-	status := q.Get("status")
+	http.Redirect(res, req, strings.TrimSuffix(req.URL.String(), "/invite"), http.StatusFound)
+}
 
-	if _, ok := item.Types[t]; !ok {
-		res.WriteHeader(http.StatusBadRequest)
-		errView, err := Error400()
+// configUsersInviteRevokeHandler revokes the invitation sent to the email in
+// the form, so it can no longer be accepted
+func configUsersInviteRevokeHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
 		if err != nil {
 			return
 		}
@@ -851,12 +1213,12 @@ func contentsHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	pt := item.Types[t]()
-
-	p, ok := pt.(editor.Editable)
-	if !ok {
+	email := strings.ToLower(req.PostFormValue("email"))
+	err = db.DeleteInvite(email)
+	if err != nil {
+		logger.For(req).Error(err)
 		res.WriteHeader(http.StatusInternalServerError)
-		errView, err := Error500()
+		errView, err := Error500(req)
 		if err != nil {
 			return
 		}
@@ -865,427 +1227,2485 @@ func contentsHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	var hasExt bool
-	_, ok = pt.(api.Externalable)
-	if ok {
-		hasExt = true
-	}
+	audit(req, "user.invite.revoke", email, nil)
 
-	count, err := strconv.Atoi(q.Get("count")) // int: determines number of posts to return (10 default, -1 is all)
-	if err != nil {
-		if q.Get("count") == "" {
-			count = 10
-		} else {
-			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
-			if err != nil {
-				return
-			}
+	http.Redirect(res, req, strings.TrimSuffix(req.URL.String(), "/invite/revoke"), http.StatusFound)
+}
 
-			res.Write(errView)
-			return
+// inviteHandler lets the invitee of the invitation sent with the token in the
+// request choose their password, creating their account and logging them in
+func inviteHandler(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		token := req.URL.Query().Get("token")
+		inv, err := inviteFromToken(token)
+		if err != nil && err != db.ErrNoInvite {
+			logger.For(req).Error(err)
 		}
-	}
 
-	offset, err := strconv.Atoi(q.Get("offset")) // int: multiplier of count for pagination (0 default)
+		view, err := AcceptInvite(token, inv.Email, "")
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		res.Header().Set("Content-Type", "text/html")
+		res.Write(view)
+
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		token := req.PostFormValue("token")
+		inv, err := inviteFromToken(token)
+		if err != nil {
+			if err != db.ErrNoInvite {
+				logger.For(req).Error(err)
+			}
+
+			view, err := AcceptInvite(token, "", "")
+			if err != nil {
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			res.WriteHeader(http.StatusBadRequest)
+			res.Write(view)
+			return
+		}
+
+		usr, err := newUser(inv.Email, req.PostFormValue("password"))
+		if _, ok := err.(*user.PasswordError); ok {
+			view, err := AcceptInvite(token, inv.Email, err.Error())
+			if err != nil {
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			res.WriteHeader(http.StatusBadRequest)
+			res.Write(view)
+			return
+		}
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		usr.Role = inv.Role
+		_, err = db.SetUser(usr)
+		if err != nil {
+			logger.For(req).Error("Error creating user from invitation:", err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		err = db.DeleteInvite(inv.Email)
+		if err != nil {
+			logger.For(req).Error("Error removing accepted invitation:", err)
+		}
+
+		auditAs(req, usr.Email, "user.invite.accept", usr.Email, map[string]string{"invited_by": inv.InvitedBy})
+
+		err = setLoginToken(res, req, usr.Email, false)
+		if err != nil {
+			logger.For(req).Error(err)
+			http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin/login", http.StatusFound)
+			return
+		}
+
+		http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin", http.StatusFound)
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// configRolesHandler shows the roles, and saves a role from the submitted
+// checkboxes of actions for each content type and of sections
+func configRolesHandler(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		view, err := Roles(req)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		res.Write(view)
+
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		role := user.Role{
+			Name:        strings.TrimSpace(req.PostFormValue("name")),
+			Permissions: make(map[string][]user.Action),
+			Sections:    req.PostForm["section"],
+		}
+
+		for k, vals := range req.PostForm {
+			if !strings.HasPrefix(k, "perm.") {
+				continue
+			}
+
+			t := strings.TrimPrefix(k, "perm.")
+			if _, ok := item.Types[t]; !ok && t != "*" {
+				continue
+			}
+
+			for _, v := range vals {
+				role.Permissions[t] = append(role.Permissions[t], user.Action(v))
+			}
+		}
+
+		err = db.SetRole(role)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := ErrorMessage(req, "Role not saved", html.EscapeString(err.Error()))
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		audit(req, "role.save", role.Name, nil)
+
+		http.Redirect(res, req, req.URL.String(), http.StatusFound)
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// configRolesDeleteHandler deletes the role named in the form
+func configRolesDeleteHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	err = db.DeleteRole(req.PostFormValue("name"))
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	audit(req, "role.delete", req.PostFormValue("name"), nil)
+
+	http.Redirect(res, req, strings.TrimSuffix(req.URL.String(), "/delete"), http.StatusFound)
+}
+
+func loginHandler(res http.ResponseWriter, req *http.Request) {
+	if !db.SystemInitComplete() {
+		redir := req.URL.Scheme + req.URL.Host + "/admin/init"
+		http.Redirect(res, req, redir, http.StatusFound)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		if loggedIn(req) {
+			http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin", http.StatusFound)
+			return
+		}
+
+		var msg string
+		if req.URL.Query().Get("expired") == "true" {
+			msg = "Your session has expired. Please log in again."
+		}
+
+		view, err := Login(msg)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		res.Header().Set("Content-Type", "text/html")
+		res.Write(view)
+
+	case http.MethodPost:
+		if loggedIn(req) {
+			http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin", http.StatusFound)
+			return
+		}
+
+		err := req.ParseForm()
+		if err != nil {
+			logger.For(req).Error(err)
+			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+			return
+		}
+
+		email := strings.ToLower(req.FormValue("email"))
+		ip := analytics.ClientIP(req)
+		if wait := loginLockedFor(email, ip); wait > 0 {
+			writeLockedOut(res, wait, Login)
+			return
+		}
+
+		// check email & password
+		j, err := db.User(email)
+		if err != nil && err != db.ErrNoUserExists {
+			logger.For(req).Error(err)
+			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+			return
+		}
+
+		if j == nil {
+			auditAs(req, email, "user.login.failed", "", map[string]string{"reason": "unknown user"})
+			if wait := loginFailed(email, ip); wait > 0 {
+				auditAs(req, email, "user.login.locked", email, nil)
+				writeLockedOut(res, wait, Login)
+				return
+			}
+
+			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+			return
+		}
+
+		usr := &user.User{}
+		err = json.Unmarshal(j, usr)
+		if err != nil {
+			logger.For(req).Error(err)
+			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+			return
+		}
+
+		if !user.IsUser(usr, req.FormValue("password")) {
+			auditAs(req, usr.Email, "user.login.failed", usr.Email, map[string]string{"reason": "wrong password"})
+			if wait := loginFailed(email, ip); wait > 0 {
+				auditAs(req, usr.Email, "user.login.locked", usr.Email, nil)
+				writeLockedOut(res, wait, Login)
+				return
+			}
+
+			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+			return
+		}
+
+		rehashPassword(usr, req.FormValue("password"))
+		remember := req.FormValue("remember") == "true"
+
+		// users with two-factor authentication must give a code before
+		// they are logged in
+		if usr.HasTOTP() {
+			err = setTwoFactorToken(res, usr.Email, remember)
+			if err != nil {
+				logger.For(req).Error(err)
+				http.Redirect(res, req, req.URL.String(), http.StatusFound)
+				return
+			}
+
+			http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin/login/2fa", http.StatusFound)
+			return
+		}
+
+		err = setLoginToken(res, req, usr.Email, remember)
+		if err != nil {
+			logger.For(req).Error(err)
+			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+			return
+		}
+
+		loginSucceeded(email, ip)
+		auditAs(req, usr.Email, "user.login", usr.Email, nil)
+
+		http.Redirect(res, req, strings.TrimSuffix(req.URL.String(), "/login"), http.StatusFound)
+	}
+}
+
+// loginTwoFactorHandler asks a user who has given their password for the code
+// of their second factor, and logs them in once it is given
+func loginTwoFactorHandler(res http.ResponseWriter, req *http.Request) {
+	usr, remember, ok := twoFactorUser(req)
+	if !ok {
+		http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin/login", http.StatusFound)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		view, err := LoginTwoFactor("")
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		res.Header().Set("Content-Type", "text/html")
+		res.Write(view)
+
+	case http.MethodPost:
+		err := req.ParseForm()
+		if err != nil {
+			logger.For(req).Error(err)
+			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+			return
+		}
+
+		ip := analytics.ClientIP(req)
+		if wait := loginLockedFor(usr.Email, ip); wait > 0 {
+			writeLockedOut(res, wait, LoginTwoFactor)
+			return
+		}
+
+		ok, used := checkSecondFactor(usr, req.PostFormValue("code"))
+		if !ok {
+			auditAs(req, usr.Email, "user.login.failed", usr.Email, map[string]string{"reason": "wrong second factor"})
+			if wait := loginFailed(usr.Email, ip); wait > 0 {
+				auditAs(req, usr.Email, "user.login.locked", usr.Email, nil)
+				clearTwoFactorToken(res)
+				writeLockedOut(res, wait, LoginTwoFactor)
+				return
+			}
+
+			view, err := LoginTwoFactor("The code is incorrect or has expired, please try again.")
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			res.WriteHeader(http.StatusUnauthorized)
+			res.Write(view)
+			return
+		}
+
+		if used {
+			err = db.UpdateUser(usr, usr)
+			if err != nil {
+				logger.For(req).Error("Error removing used recovery code:", err)
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		err = setLoginToken(res, req, usr.Email, remember)
+		if err != nil {
+			logger.For(req).Error(err)
+			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+			return
+		}
+		clearTwoFactorToken(res)
+
+		details := map[string]string{"factor": "totp"}
+		if used {
+			details["factor"] = "recovery"
+		}
+		loginSucceeded(usr.Email, ip)
+		auditAs(req, usr.Email, "user.login", usr.Email, details)
+
+		http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin", http.StatusFound)
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// configTwoFactorHandler lets the current user enroll in two-factor
+// authentication by confirming a code of a new secret, or disable it by giving
+// their password and a code
+func configTwoFactorHandler(res http.ResponseWriter, req *http.Request) {
+	j, err := db.CurrentUser(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	usr := &user.User{}
+	err = json.Unmarshal(j, usr)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	required, _ := db.ConfigCache("require_2fa").(bool)
+	v := twoFactorView{
+		Enabled:       usr.HasTOTP(),
+		Required:      required,
+		RecoveryCodes: len(usr.RecoveryCodes),
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		// show the current state of enrollment below
+
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		code := req.PostFormValue("code")
+		switch req.PostFormValue("action") {
+		case "enroll":
+			enc := req.PostFormValue("secret")
+			secret, err := user.DecryptSecret(enc, secretKey())
+			if err != nil || v.Enabled {
+				res.WriteHeader(http.StatusBadRequest)
+				errView, err := Error400(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			if !user.ValidTOTP(secret, code, time.Now()) {
+				v.Error = "The code is incorrect or has expired, please try again."
+				v.Secret, v.Encrypted = secret, enc
+				break
+			}
+
+			codes, hashes, err := user.NewRecoveryCodes()
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			updated := *usr
+			updated.TOTPSecret = enc
+			updated.RecoveryCodes = hashes
+			err = db.UpdateUser(usr, &updated)
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			audit(req, "user.2fa.enroll", usr.Email, nil)
+			v = twoFactorView{Enabled: true, Codes: codes}
+
+		case "disable":
+			if !v.Enabled || required {
+				res.WriteHeader(http.StatusBadRequest)
+				errView, err := Error400(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			check := *usr
+			ok, _ := checkSecondFactor(&check, code)
+			if !ok || !user.IsUser(usr, req.PostFormValue("password")) {
+				v.Error = "The password or code is incorrect, please try again."
+				break
+			}
+
+			updated := *usr
+			updated.TOTPSecret = ""
+			updated.RecoveryCodes = nil
+			err = db.UpdateUser(usr, &updated)
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			audit(req, "user.2fa.disable", usr.Email, nil)
+			http.Redirect(res, req, req.URL.String(), http.StatusFound)
+			return
+
+		default:
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := Error400(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// offer a new secret to enroll with, unless retrying with the last one
+	if !v.Enabled && v.Secret == "" {
+		v.Secret, err = user.NewTOTPSecret()
+		if err == nil {
+			v.Encrypted, err = user.EncryptSecret(v.Secret, secretKey())
+		}
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+	}
+
+	if !v.Enabled {
+		issuer, _ := db.ConfigCache("name").(string)
+		if issuer == "" {
+			issuer = "Ponzu"
+		}
+		v.URL = user.TOTPURL(issuer, usr.Email, v.Secret)
+	}
+
+	view, err := TwoFactor(req, v)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	res.Write(view)
+}
+
+func logoutHandler(res http.ResponseWriter, req *http.Request) {
+	if s, err := db.CurrentSession(req); err == nil {
+		audit(req, "user.logout", "", nil)
+
+		err = db.RevokeSession(s.ID)
+		if err != nil {
+			logger.For(req).Error("Error ending session at logout:", err)
+		}
+	}
+
+	clearLoginToken(res)
+
+	http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin/login", http.StatusFound)
+}
+
+func forgotPasswordHandler(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		view, err := ForgotPassword(req.URL.Query().Get("sent") == "true", "")
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		res.Write(view)
+
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		email := strings.ToLower(req.FormValue("email"))
+		if email == "" {
+			logger.For(req).Error("Failed account recovery. No email address submitted.")
+			view, err := ForgotPassword(false, "Please enter the email address of your account.")
+			if err != nil {
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			res.WriteHeader(http.StatusBadRequest)
+			res.Write(view)
+			return
+		}
+
+		ok, wait := resetRequestAllowed(email)
+		if !ok {
+			logger.For(req).Warn("Too many account recovery requests for:", email)
+			minutes := int(math.Ceil(wait.Minutes()))
+			msg := "Too many recovery emails have been requested for this address. Please try again in 1 minute."
+			if minutes > 1 {
+				msg = fmt.Sprintf("Too many recovery emails have been requested for this address. Please try again in %d minutes.", minutes)
+			}
+
+			view, err := ForgotPassword(false, msg)
+			if err != nil {
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			res.WriteHeader(http.StatusTooManyRequests)
+			res.Write(view)
+			return
+		}
+
+		// respond the same way whether or not the user exists, so that the
+		// form can't be used to find which addresses have accounts
+		redir := req.URL.Scheme + req.URL.Host + "/admin/recover?sent=true"
+
+		j, err := db.User(email)
+		if err == db.ErrNoUserExists || (err == nil && j == nil) {
+			logger.For(req).Warn("Account recovery requested for unknown user:", email)
+			http.Redirect(res, req, redir, http.StatusFound)
+			return
+		}
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			logger.For(req).Error("Error:", err)
+			return
+		}
+
+		usr := &user.User{}
+		err = json.Unmarshal(j, usr)
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			logger.For(req).Error("Error decoding user from database:", err)
+			return
+		}
+
+		go func() {
+			err := sendPasswordReset(usr)
+			if err != nil {
+				logger.For(req).Error("Failed to send account recovery message to:", email, "Error:", err)
+			}
+		}()
+
+		auditAs(req, email, "user.recover.request", email, nil)
+
+		http.Redirect(res, req, redir, http.StatusFound)
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		errView, err := Error405(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+}
+
+func recoveryKeyHandler(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		token := req.URL.Query().Get("token")
+		_, err := resetUserFromToken(token)
+		if err != nil && err != errResetInvalid {
+			logger.For(req).Error("Error checking password reset token:", err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err == errResetInvalid {
+			token = ""
+		}
+
+		view, err := RecoveryKey(token, "")
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		res.Write(view)
+
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error("Error parsing recovery key form:", err)
+
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write([]byte("Error, please go back and try again."))
+			return
+		}
+
+		token := req.FormValue("token")
+		usr, err := resetUserFromToken(token)
+		if err == errResetInvalid {
+			logger.For(req).Error("Invalid password reset token submitted")
+
+			view, err := RecoveryKey("", "")
+			if err != nil {
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			res.WriteHeader(http.StatusBadRequest)
+			res.Write(view)
+			return
+		}
+		if err != nil {
+			logger.For(req).Error("Error checking password reset token:", err)
+
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write([]byte("Error, please go back and try again."))
+			return
+		}
+
+		// set user with new password
+		email := usr.Email
+		update, err := newUser(email, req.FormValue("password"))
+		if _, ok := err.(*user.PasswordError); ok {
+			view, err := RecoveryKey(token, err.Error())
+			if err != nil {
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			res.WriteHeader(http.StatusBadRequest)
+			res.Write(view)
+			return
+		}
+		if err != nil {
+			logger.For(req).Error(err)
+
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write([]byte("Error, please go back and try again."))
+			return
+		}
+
+		update.ID = usr.ID
+		update.Role = usr.Role
+		update.TOTPSecret = usr.TOTPSecret
+		update.RecoveryCodes = usr.RecoveryCodes
+
+		// remove the key first, so the link can't be used twice
+		err = db.DeleteRecoveryKey(email)
+		if err != nil {
+			logger.For(req).Error("Error removing used recovery key:", err)
+
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write([]byte("Error, please go back and try again."))
+			return
+		}
+
+		err = db.UpdateUser(usr, update)
+		if err != nil {
+			logger.For(req).Error("Error updating user:", err)
+
+			res.WriteHeader(http.StatusInternalServerError)
+			res.Write([]byte("Error, please go back and try again."))
+			return
+		}
+
+		err = db.RevokeSessions(email, "")
+		if err != nil {
+			logger.For(req).Error("Error ending sessions of recovered user:", err)
+		}
+
+		auditAs(req, email, "user.recover", email, nil)
+
+		// redirect to /admin/login
+		redir := req.URL.Scheme + req.URL.Host + "/admin/login"
+		http.Redirect(res, req, redir, http.StatusFound)
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+func contentsHandler(res http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	t := q.Get("type")
+	if t == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	order := strings.ToLower(q.Get("order"))
+	if order != "asc" {
+		order = "desc"
+	}
+
+	status := q.Get("status")
+
+	if _, ok := item.Types[t]; !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	pt := item.Types[t]()
+
+	// manually ordered content is listed, and can be dragged, in its order
+	manual := strings.ToLower(q.Get("order")) == "order" && item.IsOrderable(pt) && status != "pending"
+	if manual {
+		order = "order"
+	}
+
+	p, ok := pt.(editor.Editable)
+	if !ok {
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	var hasExt bool
+	_, ok = pt.(api.Externalable)
+	if ok {
+		hasExt = true
+	}
+
+	count, err := strconv.Atoi(q.Get("count")) // int: determines number of posts to return (10 default, -1 is all)
+	if err != nil {
+		if q.Get("count") == "" {
+			count = 10
+		} else {
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+	}
+
+	offset, err := strconv.Atoi(q.Get("offset")) // int: multiplier of count for pagination (0 default)
+	if err != nil {
+		if q.Get("offset") == "" {
+			offset = 0
+		} else {
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+	}
+
+	opts := db.QueryOptions{
+		Count:  count,
+		Offset: offset,
+		Order:  order,
+	}
+
+	var specifier string
+	if status == "public" || status == "" {
+		specifier = "__sorted"
+	} else if status == "pending" {
+		specifier = "__pending"
+	}
+
+	b := &bytes.Buffer{}
+	var total int
+	var posts [][]byte
+
+	var orderOption string
+	if item.IsOrderable(pt) && status != "pending" {
+		orderOption = `<option value="ORDER">Manual order</option>`
+	}
+
+	html := `<div class="col s9 card">		
+					<div class="card-content">
+					<div class="row">
+					<div class="col s8">
+						<div class="row">
+							<div class="card-title col s7">` + t + ` Items</div>
+							<div class="col s5 input-field inline">
+								<select class="browser-default __ponzu sort-order">
+									<option value="DESC">New to Old</option>
+									<option value="ASC">Old to New</option>` + orderOption + `
+								</select>
+								<label class="active">Sort:</label>
+							</div>	
+							<script>
+								$(function() {
+									var sort = $('select.__ponzu.sort-order');
+
+									sort.on('change', function() {
+										var path = window.location.pathname;
+										var s = sort.val();
+										var t = getParam('type');
+
+										window.location.replace(path + '?type=' + t + '&order=' + s)
+									});
+
+									var order = getParam('order');
+									if (order !== '') {
+										sort.val(order);
+									}
+									
+								});
+							</script>
+						</div>
+					</div>
+					<form class="col s4" action="/admin/contents/search" method="get">
+						<div class="input-field post-search inline">
+							<label class="active">Search:</label>
+							<i class="right material-icons search-icon">search</i>
+							<input class="search" name="q" type="text" placeholder="Within all ` + t + ` fields" class="search"/>
+							<input type="hidden" name="type" value="` + t + `" />
+							<input type="hidden" name="status" value="` + status + `" />
+						</div>
+                    </form>	
+					</div>`
+	if hasExt {
+		if status == "" {
+			q.Set("status", "public")
+		}
+
+		// always start from top of results when changing public/pending
+		q.Del("count")
+		q.Del("offset")
+
+		q.Set("status", "public")
+		publicURL := req.URL.Path + "?" + q.Encode()
+
+		q.Set("status", "pending")
+		pendingURL := req.URL.Path + "?" + q.Encode()
+
+		switch status {
+		case "public", "":
+			// get __sorted posts of type t from the db
+			total, posts = listContents(t, specifier, opts, manual)
+
+			html += `<div class="row externalable">
+					<span class="description">Status:</span> 
+					<span class="active">Public</span>
+					&nbsp;&vert;&nbsp;
+					<a href="` + pendingURL + `">Pending</a>
+				</div>`
+
+			for i := range posts {
+				err := json.Unmarshal(posts[i], &p)
+				if err != nil {
+					logger.For(req).Error("Error unmarshal json into", t, err, string(posts[i]))
+
+					post := `<li class="col s12">Error decoding data. Possible file corruption.</li>`
+					_, err := b.Write([]byte(post))
+					if err != nil {
+						logger.For(req).Error(err)
+
+						res.WriteHeader(http.StatusInternalServerError)
+						errView, err := Error500(req)
+						if err != nil {
+							logger.For(req).Error(err)
+						}
+
+						res.Write(errView)
+						return
+					}
+
+					continue
+				}
+
+				post := adminPostListItem(p, t, status)
+				_, err = b.Write(post)
+				if err != nil {
+					logger.For(req).Error(err)
+
+					res.WriteHeader(http.StatusInternalServerError)
+					errView, err := Error500(req)
+					if err != nil {
+						logger.For(req).Error(err)
+					}
+
+					res.Write(errView)
+					return
+				}
+			}
+
+		case "pending":
+			// get __pending posts of type t from the db
+			total, posts = db.Query(t+"__pending", opts)
+
+			html += `<div class="row externalable">
+					<span class="description">Status:</span> 
+					<a href="` + publicURL + `">Public</a>
+					&nbsp;&vert;&nbsp;
+					<span class="active">Pending</span>					
+				</div>`
+
+			for i := len(posts) - 1; i >= 0; i-- {
+				err := json.Unmarshal(posts[i], &p)
+				if err != nil {
+					logger.For(req).Error("Error unmarshal json into", t, err, string(posts[i]))
+
+					post := `<li class="col s12">Error decoding data. Possible file corruption.</li>`
+					_, err := b.Write([]byte(post))
+					if err != nil {
+						logger.For(req).Error(err)
+
+						res.WriteHeader(http.StatusInternalServerError)
+						errView, err := Error500(req)
+						if err != nil {
+							logger.For(req).Error(err)
+						}
+
+						res.Write(errView)
+						return
+					}
+					continue
+				}
+
+				post := adminPostListItem(p, t, status)
+				_, err = b.Write(post)
+				if err != nil {
+					logger.For(req).Error(err)
+
+					res.WriteHeader(http.StatusInternalServerError)
+					errView, err := Error500(req)
+					if err != nil {
+						logger.For(req).Error(err)
+					}
+
+					res.Write(errView)
+					return
+				}
+			}
+		}
+
+	} else {
+		total, posts = listContents(t, specifier, opts, manual)
+
+		for i := range posts {
+			err := json.Unmarshal(posts[i], &p)
+			if err != nil {
+				logger.For(req).Error("Error unmarshal json into", t, err, string(posts[i]))
+
+				post := `<li class="col s12">Error decoding data. Possible file corruption.</li>`
+				_, err := b.Write([]byte(post))
+				if err != nil {
+					logger.For(req).Error(err)
+
+					res.WriteHeader(http.StatusInternalServerError)
+					errView, err := Error500(req)
+					if err != nil {
+						logger.For(req).Error(err)
+					}
+
+					res.Write(errView)
+					return
+				}
+				continue
+			}
+
+			post := adminPostListItem(p, t, status)
+			_, err = b.Write(post)
+			if err != nil {
+				logger.For(req).Error(err)
+
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					logger.For(req).Error(err)
+				}
+
+				res.Write(errView)
+				return
+			}
+		}
+	}
+
+	html += batchBar(t, status, "", total) + `<ul class="posts row">`
+
+	_, err = b.Write([]byte(`</ul>`))
+	if err != nil {
+		logger.For(req).Error(err)
+
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			logger.For(req).Error(err)
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	statusDisabled := "disabled"
+	prevStatus := ""
+	nextStatus := ""
+	// total may be less than 10 (default count), so reset count to match total
+	if total < count {
+		count = total
+	}
+	// nothing previous to current list
+	if offset == 0 {
+		prevStatus = statusDisabled
+	}
+	// nothing after current list
+	if (offset+1)*count >= total {
+		nextStatus = statusDisabled
+	}
+
+	// set up pagination values
+	urlFmt := req.URL.Path + "?count=%d&offset=%d&&order=%s&status=%s&type=%s"
+	prevURL := fmt.Sprintf(urlFmt, count, offset-1, order, status, t)
+	nextURL := fmt.Sprintf(urlFmt, count, offset+1, order, status, t)
+	start := 1 + count*offset
+	end := start + count - 1
+
+	if total < end {
+		end = total
+	}
+
+	pagination := fmt.Sprintf(`
+	<ul class="pagination row">
+		<li class="col s2 waves-effect %s"><a href="%s"><i class="material-icons">chevron_left</i></a></li>
+		<li class="col s8">%d to %d of %d</li>
+		<li class="col s2 waves-effect %s"><a href="%s"><i class="material-icons">chevron_right</i></a></li>
+	</ul>
+	`, prevStatus, prevURL, start, end, total, nextStatus, nextURL)
+
+	// show indicator that a collection of items will be listed implicitly, but
+	// that none are created yet
+	if total < 1 {
+		pagination = `
+		<ul class="pagination row">
+			<li class="col s2 waves-effect disabled"><a href="#"><i class="material-icons">chevron_left</i></a></li>
+			<li class="col s8">0 to 0 of 0</li>
+			<li class="col s2 waves-effect disabled"><a href="#"><i class="material-icons">chevron_right</i></a></li>
+		</ul>
+		`
+	}
+
+	_, err = b.Write([]byte(pagination + `</div></div>`))
+	if err != nil {
+		logger.For(req).Error(err)
+
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			logger.For(req).Error(err)
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	script := `
+	<script>
+		$(function() {
+			var del = $('.quick-delete-post.__ponzu span');
+			del.on('click', function(e) {
+				if (confirm("[Ponzu] Please confirm:\n\nAre you sure you want to delete this post?\nThis cannot be undone.")) {
+					$(e.target).parent().submit();
+				}
+			});
+		});
+
+		// disable link from being clicked if parent is 'disabled'
+		$(function() {
+			$('ul.pagination li.disabled a').on('click', function(e) {
+				e.preventDefault();
+			});
+		});
+	</script>
+	`
+
+	btn := `<div class="col s3"><a href="/admin/edit?type=` + t + `" class="btn new-post waves-effect waves-light">New ` + t + `</a>
+		<a href="/admin/contents/import?type=` + t + `" class="btn-flat waves-effect">Import CSV</a>
+		<a href="/admin/contents/export?type=` + t + `" class="btn-flat waves-effect">Export JSON</a></div></div>`
+	if manual {
+		script += orderScript(t)
+	}
+
+	html = html + b.String() + script + btn
+
+	adminView, err := Admin(req, []byte(html))
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/html")
+	res.Write(adminView)
+}
+
+// adminPostListItem is a helper to create the li containing a post.
+// p is the asserted post as an Editable, t is the Type of the post.
+// specifier is passed to append a name to a namespace like __pending
+func adminPostListItem(e editor.Editable, typeName, status string) []byte {
+	s, ok := e.(item.Sortable)
+	if !ok {
+		logger.Error("Content type", typeName, "doesn't implement item.Sortable")
+		post := `<li class="col s12">Error retreiving data. Your data type doesn't implement necessary interfaces. (item.Sortable)</li>`
+		return []byte(post)
+	}
+
+	i, ok := e.(item.Identifiable)
+	if !ok {
+		logger.Error("Content type", typeName, "doesn't implement item.Identifiable")
+		post := `<li class="col s12">Error retreiving data. Your data type doesn't implement necessary interfaces. (item.Identifiable)</li>`
+		return []byte(post)
+	}
+
+	// use sort to get other info to display in admin UI post list
+	tsTime := time.Unix(int64(s.Time()/1000), 0)
+	upTime := time.Unix(int64(s.Touch()/1000), 0)
+	updatedTime := upTime.Format("01/02/06 03:04 PM")
+	publishTime := tsTime.Format("01/02/06")
+
+	cid := fmt.Sprintf("%d", i.ItemID())
+
+	switch status {
+	case "public", "":
+		status = ""
+	default:
+		status = "__" + status
+	}
+
+	post := `
+			<li class="col s12">
+				` + batchSelect(cid) + `
+				<a href="/admin/edit?type=` + typeName + `&status=` + strings.TrimPrefix(status, "__") + `&id=` + cid + `">` + i.String() + `</a>
+				<span class="post-detail">Updated: ` + updatedTime + `</span>
+				<span class="publish-date right">` + publishTime + `</span>
+
+				<form enctype="multipart/form-data" class="quick-delete-post __ponzu right" action="/admin/edit/delete" method="post">
+					<span>Delete</span>
+					<input type="hidden" name="id" value="` + cid + `" />
+					<input type="hidden" name="type" value="` + typeName + status + `" />
+				</form>
+			</li>`
+
+	return []byte(post)
+}
+
+func approveContentHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		errView, err := Error405(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	pendingID := req.FormValue("id")
+
+	t := req.FormValue("type")
+	if strings.Contains(t, "__") {
+		t = strings.Split(t, "__")[0]
+	}
+
+	post := item.Types[t]()
+
+	// run hooks
+	hook, ok := post.(item.Hookable)
+	if !ok {
+		logger.For(req).Error("Type", t, "does not implement item.Hookable or embed item.Item.")
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	// check if we have a Mergeable
+	m, ok := post.(editor.Mergeable)
+	if !ok {
+		logger.For(req).Error("Content type", t, "must implement editor.Mergeable before it can be approved.")
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	dec := schema.NewDecoder()
+	dec.IgnoreUnknownKeys(true)
+	dec.SetAliasTag("json")
+	err = dec.Decode(post, req.Form)
+	if err != nil {
+		logger.For(req).Error("Error decoding post form for content approval:", t, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	err = hook.BeforeApprove(res, req)
+	if err != nil {
+		logger.For(req).Error("Error running BeforeApprove hook in approveContentHandler for:", t, err)
+		return
+	}
+
+	// call its Approve method
+	err = m.Approve(res, req)
+	if err != nil {
+		logger.For(req).Error("Error running Approve method in approveContentHandler for:", t, err)
+		return
+	}
+
+	err = hook.AfterApprove(res, req)
+	if err != nil {
+		logger.For(req).Error("Error running AfterApprove hook in approveContentHandler for:", t, err)
+		return
+	}
+
+	// approved content enters the workflow as new content, and is only
+	// published by users allowed to publish it
+	_, _, err = applyWorkflow(req, t, req.Form, nil, approvalState(req, t))
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	err = hook.BeforeSave(res, req)
+	if err != nil {
+		logger.For(req).Error("Error running BeforeSave hook in approveContentHandler for:", t, err)
+		return
+	}
+
+	// Store the content in the bucket t
+	id, err := db.SetContent(t+":-1", req.Form)
+	if err != nil {
+		logger.For(req).Error("Error storing content in approveContentHandler for:", t, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	// set the target in the context so user can get saved value from db in hook
+	ctx := context.WithValue(req.Context(), "target", fmt.Sprintf("%s:%d", t, id))
+	req = req.WithContext(ctx)
+
+	err = hook.AfterSave(res, req)
+	if err != nil {
+		logger.For(req).Error("Error running AfterSave hook in approveContentHandler for:", t, err)
+		return
+	}
+
+	if pendingID != "" {
+		err = db.PurgeContent(req.FormValue("type")+":"+pendingID, req.Form)
+		if err != nil {
+			logger.For(req).Error("Failed to remove content after approval:", err)
+		}
+	}
+
+	audit(req, "content.approve", fmt.Sprintf("%s:%d", t, id), map[string]string{"pending": pendingID})
+
+	// redirect to the new approved content's editor
+	redir := req.URL.Scheme + req.URL.Host + strings.TrimSuffix(req.URL.Path, "/approve")
+	redir += fmt.Sprintf("?type=%s&id=%d", t, id)
+	http.Redirect(res, req, redir, http.StatusFound)
+}
+
+// orderFormValues replaces the values of multi-value fields in form, such as
+// checkbox fields, submitted as fieldX.0: value1, fieldX.1: value2 with
+// fieldX: []string{value1, value2} for db storage
+func orderFormValues(form url.Values) {
+	fieldOrderValue := make(map[string]map[string][]string)
+	ordVal := make(map[string][]string)
+	for k, v := range form {
+		// field group values, i.e. faqs.0.question, and nested struct values,
+		// i.e. location.lat, are left for gorilla/schema to decode
+		fo := strings.Split(k, ".")
+		if len(fo) != 2 {
+			continue
+		}
+		if _, err := strconv.Atoi(fo[1]); err != nil {
+			continue
+		}
+
+		// put the order and the field value into map
+		field := string(fo[0])
+		order := string(fo[1])
+		fieldOrderValue[field] = ordVal
+
+		// orderValue is 0:[?type=Thing&id=1]
+		orderValue := fieldOrderValue[field]
+		orderValue[order] = v
+		fieldOrderValue[field] = orderValue
+
+		// discard the post form value with name.N
+		form.Del(k)
+	}
+
+	// add/set the key & value to the post form in order
+	for f, ov := range fieldOrderValue {
+		for i := 0; i < len(ov); i++ {
+			position := fmt.Sprintf("%d", i)
+			fieldValue := ov[position]
+
+			if form.Get(f) == "" {
+				for i, fv := range fieldValue {
+					if i == 0 {
+						form.Set(f, fv)
+					} else {
+						form.Add(f, fv)
+					}
+				}
+			} else {
+				for _, fv := range fieldValue {
+					form.Add(f, fv)
+				}
+			}
+		}
+	}
+}
+
+func editHandler(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		q := req.URL.Query()
+		i := q.Get("id")
+		t := q.Get("type")
+		status := q.Get("status")
+
+		contentType, ok := item.Types[t]
+		if !ok {
+			fmt.Fprintf(res, item.ErrTypeNotRegistered.Error(), t)
+			return
+		}
+		post := contentType()
+
+		if i != "" {
+			if status == "pending" {
+				t = t + "__pending"
+			}
+
+			data, err := db.Content(t + ":" + i)
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			if len(data) < 1 || data == nil {
+				res.WriteHeader(http.StatusNotFound)
+				errView, err := Error404(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			err = json.Unmarshal(data, post)
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+		} else {
+			item, ok := post.(item.Identifiable)
+			if !ok {
+				logger.For(req).Error("Content type", t, "doesn't implement item.Identifiable")
+				return
+			}
+
+			item.SetItemID(-1)
+		}
+
+		m, err := manager.Manage(post.(editor.Editable), t)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		// show the revision history of existing content below the editor
+		if i != "" {
+			history, err := History(t, i)
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			m = append(m, history...)
+		}
+
+		// show the workflow state of content, which new content starts as a
+		// draft of
+		if w, ok := post.(item.Workflowable); ok && workflowEnabled() && status != "pending" {
+			state := w.WorkflowState()
+			if i == "" {
+				state = item.StateDraft
+			}
+
+			role, err := db.CurrentRole(req)
+			var view []byte
+			if err == nil {
+				view, err = Workflow(state, w.WorkflowHistory(), role.Can(t, user.ActionPublish))
+			}
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			m = append(m, view...)
+		}
+
+		// show the translations of localized fields, edited in place of the
+		// fields from the locale switcher above each
+		if l, ok := post.(item.Localizable); ok {
+			var translations []item.Translation
+			if tr, ok := post.(interface {
+				ItemTranslations() []item.Translation
+			}); ok {
+				translations = tr.ItemTranslations()
+			}
+
+			if _, others := db.Locales(); len(others) > 0 || len(translations) > 0 {
+				view, err := Translations(l.Localize(), translations)
+				if err != nil {
+					logger.For(req).Error(err)
+					res.WriteHeader(http.StatusInternalServerError)
+					errView, err := Error500(req)
+					if err != nil {
+						return
+					}
+
+					res.Write(errView)
+					return
+				}
+
+				m = append(m, view...)
+			}
+		}
+
+		adminView, err := Admin(req, m)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		res.Header().Set("Content-Type", "text/html")
+		res.Write(adminView)
+
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		cid := req.FormValue("id")
+		t := req.FormValue("type")
+		ts := req.FormValue("timestamp")
+		up := req.FormValue("updated")
+
+		// create a timestamp if one was not set
+		if ts == "" {
+			ts = fmt.Sprintf("%d", int64(time.Nanosecond)*time.Now().UnixNano()/int64(time.Millisecond))
+			req.PostForm.Set("timestamp", ts)
+		}
+
+		if up == "" {
+			req.PostForm.Set("updated", ts)
+		}
+
+		urlPaths, err := upload.StoreFiles(req)
+		if rejected, ok := err.(*upload.RejectedError); ok {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := ErrorMessage(req, "Upload rejected", html.EscapeString(rejected.Error()))
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		for name, urlPath := range urlPaths {
+			req.PostForm.Set(name, urlPath)
+		}
+
+		orderFormValues(req.PostForm)
+
+		pt := t
+		if strings.Contains(t, "__") {
+			pt = strings.Split(t, "__")[0]
+		}
+
+		p, ok := item.Types[pt]
+		if !ok {
+			logger.For(req).Error("Type", t, "is not a content type. Cannot edit or save.")
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := Error400(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		post := p()
+		hook, ok := post.(item.Hookable)
+		if !ok {
+			logger.For(req).Error("Type", pt, "does not implement item.Hookable or embed item.Item.")
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := Error400(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		// move content through the workflow, leaving external content pending
+		// approval as it is
+		var fromState, toState string
+		if pt == t {
+			var existing []byte
+			if cid != "" && cid != "-1" {
+				existing, err = db.Content(t + ":" + cid)
+				if err != nil {
+					logger.For(req).Error(err)
+					res.WriteHeader(http.StatusInternalServerError)
+					errView, err := Error500(req)
+					if err != nil {
+						return
+					}
+
+					res.Write(errView)
+					return
+				}
+			}
+
+			fromState, toState, err = applyWorkflow(req, t, req.PostForm, existing, req.FormValue("workflow"))
+			if err == errWorkflowForbidden || err == errWorkflowState {
+				res.WriteHeader(http.StatusForbidden)
+				errView, err := ErrorMessage(req, "Not allowed", err.Error())
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+		}
+
+		err = hook.BeforeSave(res, req)
+		if err != nil {
+			logger.For(req).Error("Error running BeforeSave method in editHandler for:", t, err)
+			return
+		}
+
+		id, err := db.SetContent(t+":"+cid, req.PostForm)
+		if fields, ok := item.FieldErrors(err); ok {
+			view, err := invalidEditor(req, pt, t, cid, fields)
+			if err != nil {
+				logger.For(req).Error(err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
+
+				res.Write(errView)
+				return
+			}
+
+			res.Header().Set("Content-Type", "text/html")
+			res.WriteHeader(http.StatusBadRequest)
+			res.Write(view)
+			return
+		}
+		if err == db.ErrVersionConflict {
+			res.WriteHeader(http.StatusConflict)
+			errView, err := ErrorMessage(req, "Edit conflict", err.Error())
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		// set the target in the context so user can get saved value from db in hook
+		ctx := context.WithValue(req.Context(), "target", fmt.Sprintf("%s:%d", t, id))
+		req = req.WithContext(ctx)
+
+		err = hook.AfterSave(res, req)
+		if err != nil {
+			logger.For(req).Error("Error running AfterSave method in editHandler for:", t, err)
+			return
+		}
+
+		action := "content.update"
+		if cid == "" || cid == "-1" {
+			action = "content.create"
+		}
+		audit(req, action, fmt.Sprintf("%s:%d", t, id), nil)
+
+		if fromState != toState && (fromState != "" || toState != item.StatePublished) {
+			audit(req, "content.state", fmt.Sprintf("%s:%d", t, id), map[string]string{
+				"from": fromState,
+				"to":   toState,
+			})
+		}
+
+		scheme := req.URL.Scheme
+		host := req.URL.Host
+		path := req.URL.Path
+		sid := fmt.Sprintf("%d", id)
+		redir := scheme + host + path + "?type=" + pt + "&id=" + sid
+
+		if req.URL.Query().Get("status") == "pending" {
+			redir += "&status=pending"
+		}
+
+		http.Redirect(res, req, redir, http.StatusFound)
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func restoreHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	id := req.FormValue("id")
+	t := req.FormValue("type")
+	rev, err := strconv.ParseInt(req.FormValue("revision"), 10, 64)
+	if id == "" || t == "" || err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	err = db.RestoreRevision(t, id, rev)
 	if err != nil {
-		if q.Get("offset") == "" {
-			offset = 0
-		} else {
-			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
-			if err != nil {
-				return
-			}
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
 
-			res.Write(errView)
+		res.Write(errView)
+		return
+	}
+
+	audit(req, "content.revision.restore", t+":"+id, map[string]string{"revision": strconv.FormatInt(rev, 10)})
+
+	// return to the editor for the content, keeping its status if pending
+	ct := t
+	status := ""
+	if strings.Contains(t, "__") {
+		spec := strings.Split(t, "__")
+		ct = spec[0]
+		status = "&status=" + spec[1]
+	}
+
+	redir := strings.TrimSuffix(req.URL.Scheme+req.URL.Host+req.URL.Path, "/restore")
+	redir = redir + "?type=" + ct + "&id=" + id + status
+	http.Redirect(res, req, redir, http.StatusFound)
+}
+
+func graphiqlHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	view, err := GraphiQL(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
 			return
 		}
+
+		res.Write(errView)
+		return
 	}
 
-	opts := db.QueryOptions{
-		Count:  count,
-		Offset: offset,
-		Order:  order,
+	res.Header().Set("Content-Type", "text/html")
+	res.Write(view)
+}
+
+func trashHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
 	}
 
-	var specifier string
-	if status == "public" || status == "" {
-		specifier = "__sorted"
-	} else if status == "pending" {
-		specifier = "__pending"
+	view, err := Trash(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
 	}
 
-	b := &bytes.Buffer{}
-	var total int
-	var posts [][]byte
+	res.Header().Set("Content-Type", "text/html")
+	res.Write(view)
+}
 
-	html := `<div class="col s9 card">		
-					<div class="card-content">
-					<div class="row">
-					<div class="col s8">
-						<div class="row">
-							<div class="card-title col s7">` + t + ` Items</div>
-							<div class="col s5 input-field inline">
-								<select class="browser-default __ponzu sort-order">
-									<option value="DESC">New to Old</option>
-									<option value="ASC">Old to New</option>
-								</select>
-								<label class="active">Sort:</label>
-							</div>	
-							<script>
-								$(function() {
-									var sort = $('select.__ponzu.sort-order');
+func trashRestoreHandler(res http.ResponseWriter, req *http.Request) {
+	trashAction(res, req, "trash.restore", db.Restore)
+}
 
-									sort.on('change', function() {
-										var path = window.location.pathname;
-										var s = sort.val();
-										var t = getParam('type');
+func trashDeleteHandler(res http.ResponseWriter, req *http.Request) {
+	trashAction(res, req, "trash.delete", db.PurgeTrash)
+}
 
-										window.location.replace(path + '?type=' + t + '&order=' + s)
-									});
+// trashAction calls fn with the type and id of content in the trash posted in
+// the request form, records action in the audit log, then returns to the trash
+// view
+func trashAction(res http.ResponseWriter, req *http.Request, action string, fn func(t, id string) error) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
 
-									var order = getParam('order');
-									if (order !== '') {
-										sort.val(order);
-									}
-									
-								});
-							</script>
-						</div>
-					</div>
-					<form class="col s4" action="/admin/contents/search" method="get">
-						<div class="input-field post-search inline">
-							<label class="active">Search:</label>
-							<i class="right material-icons search-icon">search</i>
-							<input class="search" name="q" type="text" placeholder="Within all ` + t + ` fields" class="search"/>
-							<input type="hidden" name="type" value="` + t + `" />
-							<input type="hidden" name="status" value="` + status + `" />
-						</div>
-                    </form>	
-					</div>`
-	if hasExt {
-		if status == "" {
-			q.Set("status", "public")
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
 		}
 
-		// always start from top of results when changing public/pending
-		q.Del("count")
-		q.Del("offset")
+		res.Write(errView)
+		return
+	}
 
-		q.Set("status", "public")
-		publicURL := req.URL.Path + "?" + q.Encode()
+	id := req.FormValue("id")
+	t := req.FormValue("type")
+	if id == "" || t == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
 
-		q.Set("status", "pending")
-		pendingURL := req.URL.Path + "?" + q.Encode()
+		res.Write(errView)
+		return
+	}
 
-		switch status {
-		case "public", "":
-			// get __sorted posts of type t from the db
-			total, posts = db.Query(t+specifier, opts)
+	err = fn(t, id)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
 
-			html += `<div class="row externalable">
-					<span class="description">Status:</span> 
-					<span class="active">Public</span>
-					&nbsp;&vert;&nbsp;
-					<a href="` + pendingURL + `">Pending</a>
-				</div>`
+		res.Write(errView)
+		return
+	}
 
-			for i := range posts {
-				err := json.Unmarshal(posts[i], &p)
-				if err != nil {
-					log.Println("Error unmarshal json into", t, err, string(posts[i]))
+	audit(req, action, t+":"+id, nil)
 
-					post := `<li class="col s12">Error decoding data. Possible file corruption.</li>`
-					_, err := b.Write([]byte(post))
-					if err != nil {
-						log.Println(err)
+	redir := req.URL.Scheme + req.URL.Host + "/admin/trash"
+	http.Redirect(res, req, redir, http.StatusFound)
+}
 
-						res.WriteHeader(http.StatusInternalServerError)
-						errView, err := Error500()
-						if err != nil {
-							log.Println(err)
-						}
+func apiKeysHandler(res http.ResponseWriter, req *http.Request) {
+	var newKey string
 
-						res.Write(errView)
-						return
-					}
+	switch req.Method {
+	case http.MethodGet:
 
-					continue
-				}
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
 
-				post := adminPostListItem(p, t, status)
-				_, err = b.Write(post)
-				if err != nil {
-					log.Println(err)
+			res.Write(errView)
+			return
+		}
 
-					res.WriteHeader(http.StatusInternalServerError)
-					errView, err := Error500()
-					if err != nil {
-						log.Println(err)
-					}
+		rate := 0
+		if r := req.FormValue("rate_limit"); r != "" {
+			rate, err = strconv.Atoi(r)
+		}
 
-					res.Write(errView)
-					return
-				}
+		k := db.APIKey{
+			Name:      strings.TrimSpace(req.FormValue("name")),
+			Scope:     req.FormValue("scope"),
+			Types:     req.Form["types"],
+			RateLimit: rate,
+		}
+
+		if err != nil || rate < 0 || k.Name == "" {
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := Error400(req)
+			if err != nil {
+				return
 			}
 
-		case "pending":
-			// get __pending posts of type t from the db
-			total, posts = db.Query(t+"__pending", opts)
+			res.Write(errView)
+			return
+		}
+
+		var saved db.APIKey
+		newKey, saved, err = db.NewAPIKey(k)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		audit(req, "apikey.create", fmt.Sprintf("apikey:%d", saved.ID), map[string]string{
+			"name":  saved.Name,
+			"scope": saved.Scope,
+		})
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// the new key is shown in the response, rather than after a redirect,
+	// since it is not stored and cannot be shown again
+	view, err := APIKeys(req, newKey)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/html")
+	res.Header().Set("Cache-Control", "no-store")
+	res.Write(view)
+}
+
+func apiKeysRevokeHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
 
-			html += `<div class="row externalable">
-					<span class="description">Status:</span> 
-					<a href="` + publicURL + `">Public</a>
-					&nbsp;&vert;&nbsp;
-					<span class="active">Pending</span>					
-				</div>`
+		res.Write(errView)
+		return
+	}
 
-			for i := len(posts) - 1; i >= 0; i-- {
-				err := json.Unmarshal(posts[i], &p)
-				if err != nil {
-					log.Println("Error unmarshal json into", t, err, string(posts[i]))
+	id, err := strconv.Atoi(req.FormValue("id"))
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
 
-					post := `<li class="col s12">Error decoding data. Possible file corruption.</li>`
-					_, err := b.Write([]byte(post))
-					if err != nil {
-						log.Println(err)
+		res.Write(errView)
+		return
+	}
 
-						res.WriteHeader(http.StatusInternalServerError)
-						errView, err := Error500()
-						if err != nil {
-							log.Println(err)
-						}
+	err = db.RevokeAPIKey(id)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
 
-						res.Write(errView)
-						return
-					}
-					continue
-				}
+		res.Write(errView)
+		return
+	}
 
-				post := adminPostListItem(p, t, status)
-				_, err = b.Write(post)
-				if err != nil {
-					log.Println(err)
+	audit(req, "apikey.revoke", fmt.Sprintf("apikey:%d", id), nil)
 
-					res.WriteHeader(http.StatusInternalServerError)
-					errView, err := Error500()
-					if err != nil {
-						log.Println(err)
-					}
+	redir := strings.TrimSuffix(req.URL.Scheme+req.URL.Host+req.URL.Path, "/revoke")
+	http.Redirect(res, req, redir, http.StatusFound)
+}
 
-					res.Write(errView)
-					return
-				}
+func webhooksHandler(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		view, err := Webhooks(req)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
 			}
+
+			res.Write(errView)
+			return
 		}
 
-	} else {
-		total, posts = db.Query(t+specifier, opts)
+		res.Header().Set("Content-Type", "text/html")
+		res.Write(view)
 
-		for i := range posts {
-			err := json.Unmarshal(posts[i], &p)
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
 			if err != nil {
-				log.Println("Error unmarshal json into", t, err, string(posts[i]))
-
-				post := `<li class="col s12">Error decoding data. Possible file corruption.</li>`
-				_, err := b.Write([]byte(post))
-				if err != nil {
-					log.Println(err)
+				return
+			}
 
-					res.WriteHeader(http.StatusInternalServerError)
-					errView, err := Error500()
-					if err != nil {
-						log.Println(err)
-					}
+			res.Write(errView)
+			return
+		}
 
-					res.Write(errView)
-					return
-				}
-				continue
-			}
+		w := db.Webhook{
+			URL:    strings.TrimSpace(req.FormValue("url")),
+			Type:   req.FormValue("type"),
+			Events: req.Form["events"],
+			Secret: req.FormValue("secret"),
+		}
 
-			post := adminPostListItem(p, t, status)
-			_, err = b.Write(post)
+		u, err := url.Parse(w.URL)
+		_, known := item.Types[w.Type]
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			(w.Type != "*" && !known) || len(w.Events) == 0 {
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := Error400(req)
 			if err != nil {
-				log.Println(err)
+				return
+			}
 
-				res.WriteHeader(http.StatusInternalServerError)
-				errView, err := Error500()
-				if err != nil {
-					log.Println(err)
-				}
+			res.Write(errView)
+			return
+		}
 
-				res.Write(errView)
+		wid, err := db.SetWebhook(w)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
 				return
 			}
+
+			res.Write(errView)
+			return
 		}
+
+		audit(req, "webhook.create", fmt.Sprintf("webhook:%d", wid), map[string]string{
+			"url":    w.URL,
+			"type":   w.Type,
+			"events": strings.Join(w.Events, ", "),
+		})
+
+		http.Redirect(res, req, req.URL.String(), http.StatusFound)
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
 	}
+}
 
-	html += `<ul class="posts row">`
+func webhooksDeleteHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
 
-	_, err = b.Write([]byte(`</ul>`))
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 	if err != nil {
-		log.Println(err)
-
+		logger.For(req).Error(err)
 		res.WriteHeader(http.StatusInternalServerError)
-		errView, err := Error500()
+		errView, err := Error500(req)
 		if err != nil {
-			log.Println(err)
+			return
 		}
 
 		res.Write(errView)
 		return
 	}
 
-	statusDisabled := "disabled"
-	prevStatus := ""
-	nextStatus := ""
-	// total may be less than 10 (default count), so reset count to match total
-	if total < count {
-		count = total
-	}
-	// nothing previous to current list
-	if offset == 0 {
-		prevStatus = statusDisabled
-	}
-	// nothing after current list
-	if (offset+1)*count >= total {
-		nextStatus = statusDisabled
-	}
-
-	// set up pagination values
-	urlFmt := req.URL.Path + "?count=%d&offset=%d&&order=%s&status=%s&type=%s"
-	prevURL := fmt.Sprintf(urlFmt, count, offset-1, order, status, t)
-	nextURL := fmt.Sprintf(urlFmt, count, offset+1, order, status, t)
-	start := 1 + count*offset
-	end := start + count - 1
-
-	if total < end {
-		end = total
-	}
-
-	pagination := fmt.Sprintf(`
-	<ul class="pagination row">
-		<li class="col s2 waves-effect %s"><a href="%s"><i class="material-icons">chevron_left</i></a></li>
-		<li class="col s8">%d to %d of %d</li>
-		<li class="col s2 waves-effect %s"><a href="%s"><i class="material-icons">chevron_right</i></a></li>
-	</ul>
-	`, prevStatus, prevURL, start, end, total, nextStatus, nextURL)
+	id, err := strconv.Atoi(req.FormValue("id"))
+	if err != nil {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
 
-	// show indicator that a collection of items will be listed implicitly, but
-	// that none are created yet
-	if total < 1 {
-		pagination = `
-		<ul class="pagination row">
-			<li class="col s2 waves-effect disabled"><a href="#"><i class="material-icons">chevron_left</i></a></li>
-			<li class="col s8">0 to 0 of 0</li>
-			<li class="col s2 waves-effect disabled"><a href="#"><i class="material-icons">chevron_right</i></a></li>
-		</ul>
-		`
+		res.Write(errView)
+		return
 	}
 
-	_, err = b.Write([]byte(pagination + `</div></div>`))
+	err = db.DeleteWebhook(id)
 	if err != nil {
-		log.Println(err)
-
+		logger.For(req).Error(err)
 		res.WriteHeader(http.StatusInternalServerError)
-		errView, err := Error500()
+		errView, err := Error500(req)
 		if err != nil {
-			log.Println(err)
+			return
 		}
 
 		res.Write(errView)
 		return
 	}
 
-	script := `
-	<script>
-		$(function() {
-			var del = $('.quick-delete-post.__ponzu span');
-			del.on('click', function(e) {
-				if (confirm("[Ponzu] Please confirm:\n\nAre you sure you want to delete this post?\nThis cannot be undone.")) {
-					$(e.target).parent().submit();
-				}
-			});
-		});
+	audit(req, "webhook.delete", fmt.Sprintf("webhook:%d", id), nil)
 
-		// disable link from being clicked if parent is 'disabled'
-		$(function() {
-			$('ul.pagination li.disabled a').on('click', function(e) {
-				e.preventDefault();
-			});
-		});
-	</script>
-	`
+	redir := strings.TrimSuffix(req.URL.Scheme+req.URL.Host+req.URL.Path, "/delete")
+	http.Redirect(res, req, redir, http.StatusFound)
+}
 
-	btn := `<div class="col s3"><a href="/admin/edit?type=` + t + `" class="btn new-post waves-effect waves-light">New ` + t + `</a></div></div>`
-	html = html + b.String() + script + btn
+func redirectsHandler(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		view, err := Redirects(req)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
 
-	adminView, err := Admin([]byte(html))
-	if err != nil {
-		log.Println(err)
-		res.WriteHeader(http.StatusInternalServerError)
-		return
-	}
+			res.Write(errView)
+			return
+		}
 
-	res.Header().Set("Content-Type", "text/html")
-	res.Write(adminView)
-}
+		res.Header().Set("Content-Type", "text/html")
+		res.Write(view)
 
-// adminPostListItem is a helper to create the li containing a post.
-// p is the asserted post as an Editable, t is the Type of the post.
-// specifier is passed to append a name to a namespace like __pending
-func adminPostListItem(e editor.Editable, typeName, status string) []byte {
-	s, ok := e.(item.Sortable)
-	if !ok {
-		log.Println("Content type", typeName, "doesn't implement item.Sortable")
-		post := `<li class="col s12">Error retreiving data. Your data type doesn't implement necessary interfaces. (item.Sortable)</li>`
-		return []byte(post)
-	}
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
 
-	i, ok := e.(item.Identifiable)
-	if !ok {
-		log.Println("Content type", typeName, "doesn't implement item.Identifiable")
-		post := `<li class="col s12">Error retreiving data. Your data type doesn't implement necessary interfaces. (item.Identifiable)</li>`
-		return []byte(post)
-	}
+			res.Write(errView)
+			return
+		}
+
+		status, _ := strconv.Atoi(req.FormValue("status"))
+		r := db.Redirect{
+			From:   strings.TrimSpace(req.FormValue("from")),
+			To:     strings.TrimSpace(req.FormValue("to")),
+			Status: status,
+		}
+
+		err = db.SetRedirect(r)
+		if err == db.ErrInvalidRedirect {
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := ErrorMessage(req, "Redirect not added", html.EscapeString(err.Error()))
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
 
-	// use sort to get other info to display in admin UI post list
-	tsTime := time.Unix(int64(s.Time()/1000), 0)
-	upTime := time.Unix(int64(s.Touch()/1000), 0)
-	updatedTime := upTime.Format("01/02/06 03:04 PM")
-	publishTime := tsTime.Format("01/02/06")
+			res.Write(errView)
+			return
+		}
 
-	cid := fmt.Sprintf("%d", i.ItemID())
+		audit(req, "redirect.create", "redirect:"+r.From, map[string]string{
+			"to":     r.To,
+			"status": strconv.Itoa(r.Status),
+		})
+
+		http.Redirect(res, req, req.URL.String(), http.StatusFound)
 
-	switch status {
-	case "public", "":
-		status = ""
 	default:
-		status = "__" + status
+		res.WriteHeader(http.StatusMethodNotAllowed)
 	}
-
-	post := `
-			<li class="col s12">
-				<a href="/admin/edit?type=` + typeName + `&status=` + strings.TrimPrefix(status, "__") + `&id=` + cid + `">` + i.String() + `</a>
-				<span class="post-detail">Updated: ` + updatedTime + `</span>
-				<span class="publish-date right">` + publishTime + `</span>
-
-				<form enctype="multipart/form-data" class="quick-delete-post __ponzu right" action="/admin/edit/delete" method="post">
-					<span>Delete</span>
-					<input type="hidden" name="id" value="` + cid + `" />
-					<input type="hidden" name="type" value="` + typeName + status + `" />
-				</form>
-			</li>`
-
-	return []byte(post)
 }
 
-func approveContentHandler(res http.ResponseWriter, req *http.Request) {
+func redirectsDeleteHandler(res http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		res.WriteHeader(http.StatusMethodNotAllowed)
-		errView, err := Error405()
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
 		if err != nil {
 			return
 		}
@@ -1294,10 +3714,23 @@ func approveContentHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	from := req.FormValue("from")
+	if from == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	err = db.DeleteRedirect(from)
 	if err != nil {
+		logger.For(req).Error(err)
 		res.WriteHeader(http.StatusInternalServerError)
-		errView, err := Error500()
+		errView, err := Error500(req)
 		if err != nil {
 			return
 		}
@@ -1306,21 +3739,75 @@ func approveContentHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	pendingID := req.FormValue("id")
+	audit(req, "redirect.delete", "redirect:"+from, nil)
 
-	t := req.FormValue("type")
-	if strings.Contains(t, "__") {
-		t = strings.Split(t, "__")[0]
+	redir := strings.TrimSuffix(req.URL.Scheme+req.URL.Host+req.URL.Path, "/delete")
+	http.Redirect(res, req, redir, http.StatusFound)
+}
+
+// redirectsExportHandler writes the redirect table as a JSON array, to be
+// restored with redirectsImportHandler
+func redirectsExportHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
 	}
 
-	post := item.Types[t]()
+	rs, err := db.Redirects()
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if rs == nil {
+		rs = []db.Redirect{}
+	}
 
-	// run hooks
-	hook, ok := post.(item.Hookable)
-	if !ok {
-		log.Println("Type", t, "does not implement item.Hookable or embed item.Item.")
+	j, err := json.Marshal(rs)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("redirects-%d.json", time.Now().Unix())
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	res.Write(j)
+}
+
+// redirectsImportHandler adds the redirects in an uploaded JSON array, in the
+// format written by redirectsExportHandler, replacing those from the same paths
+func redirectsImportHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body io.Reader = req.Body
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		f, _, err := req.FormFile("json")
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := Error400(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+		defer f.Close()
+
+		body = f
+	}
+
+	var rs []db.Redirect
+	err := json.NewDecoder(body).Decode(&rs)
+	if err != nil {
+		logger.For(req).Error("Error decoding redirects import:", err)
 		res.WriteHeader(http.StatusBadRequest)
-		errView, err := Error400()
+		errView, err := ErrorMessage(req, "Redirects not imported", "The file is not a JSON array of redirects.")
 		if err != nil {
 			return
 		}
@@ -1329,12 +3816,10 @@ func approveContentHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// check if we have a Mergeable
-	m, ok := post.(editor.Mergeable)
-	if !ok {
-		log.Println("Content type", t, "must implement editor.Mergeable before it can be approved.")
+	err = db.ImportRedirects(rs)
+	if err == db.ErrInvalidRedirect {
 		res.WriteHeader(http.StatusBadRequest)
-		errView, err := Error400()
+		errView, err := ErrorMessage(req, "Redirects not imported", html.EscapeString(err.Error()))
 		if err != nil {
 			return
 		}
@@ -1342,15 +3827,10 @@ func approveContentHandler(res http.ResponseWriter, req *http.Request) {
 		res.Write(errView)
 		return
 	}
-
-	dec := schema.NewDecoder()
-	dec.IgnoreUnknownKeys(true)
-	dec.SetAliasTag("json")
-	err = dec.Decode(post, req.Form)
 	if err != nil {
-		log.Println("Error decoding post form for content approval:", t, err)
+		logger.For(req).Error(err)
 		res.WriteHeader(http.StatusInternalServerError)
-		errView, err := Error500()
+		errView, err := Error500(req)
 		if err != nil {
 			return
 		}
@@ -1359,37 +3839,53 @@ func approveContentHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	err = hook.BeforeApprove(res, req)
-	if err != nil {
-		log.Println("Error running BeforeApprove hook in approveContentHandler for:", t, err)
+	audit(req, "redirect.import", "redirects", map[string]string{
+		"count": strconv.Itoa(len(rs)),
+	})
+
+	redir := strings.TrimSuffix(req.URL.Scheme+req.URL.Host+req.URL.Path, "/import")
+	http.Redirect(res, req, redir, http.StatusFound)
+}
+
+func deleteHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	// call its Approve method
-	err = m.Approve(res, req)
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 	if err != nil {
-		log.Println("Error running Approve method in approveContentHandler for:", t, err)
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
 		return
 	}
 
-	err = hook.AfterApprove(res, req)
-	if err != nil {
-		log.Println("Error running AfterApprove hook in approveContentHandler for:", t, err)
+	id := req.FormValue("id")
+	t := req.FormValue("type")
+	ct := t
+
+	if id == "" || t == "" {
+		res.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	err = hook.BeforeSave(res, req)
-	if err != nil {
-		log.Println("Error running BeforeSave hook in approveContentHandler for:", t, err)
-		return
+	// catch specifier suffix from delete form value
+	if strings.Contains(t, "__") {
+		spec := strings.Split(t, "__")
+		ct = spec[0]
 	}
 
-	// Store the content in the bucket t
-	id, err := db.SetContent(t+":-1", req.Form)
-	if err != nil {
-		log.Println("Error storing content in approveContentHandler for:", t, err)
-		res.WriteHeader(http.StatusInternalServerError)
-		errView, err := Error500()
+	p, ok := item.Types[ct]
+	if !ok {
+		logger.For(req).Error("Type", t, "does not implement item.Hookable or embed item.Item.")
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
 		if err != nil {
 			return
 		}
@@ -1398,100 +3894,120 @@ func approveContentHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	// set the target in the context so user can get saved value from db in hook
-	ctx := context.WithValue(req.Context(), "target", fmt.Sprintf("%s:%d", t, id))
-	req = req.WithContext(ctx)
+	post := p()
+	hook, ok := post.(item.Hookable)
+	if !ok {
+		logger.For(req).Error("Type", t, "does not implement item.Hookable or embed item.Item.")
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
+		}
 
-	err = hook.AfterSave(res, req)
-	if err != nil {
-		log.Println("Error running AfterSave hook in approveContentHandler for:", t, err)
+		res.Write(errView)
 		return
 	}
 
-	if pendingID != "" {
-		err = db.DeleteContent(req.FormValue("type")+":"+pendingID, req.Form)
+	reject := req.URL.Query().Get("reject")
+	if reject == "true" {
+		err = hook.BeforeReject(res, req)
 		if err != nil {
-			log.Println("Failed to remove content after approval:", err)
+			logger.For(req).Error("Error running BeforeReject method in deleteHandler for:", t, err)
+			return
 		}
 	}
 
-	// redirect to the new approved content's editor
-	redir := req.URL.Scheme + req.URL.Host + strings.TrimSuffix(req.URL.Path, "/approve")
-	redir += fmt.Sprintf("?type=%s&id=%d", t, id)
-	http.Redirect(res, req, redir, http.StatusFound)
-}
+	err = hook.BeforeDelete(res, req)
+	if err != nil {
+		logger.For(req).Error("Error running BeforeDelete method in deleteHandler for:", t, err)
+		return
+	}
 
-func editHandler(res http.ResponseWriter, req *http.Request) {
-	switch req.Method {
-	case http.MethodGet:
-		q := req.URL.Query()
-		i := q.Get("id")
-		t := q.Get("type")
-		status := q.Get("status")
+	err = db.DeleteContent(t+":"+id, req.Form)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
-		contentType, ok := item.Types[t]
-		if !ok {
-			fmt.Fprintf(res, item.ErrTypeNotRegistered.Error(), t)
+	err = hook.AfterDelete(res, req)
+	if err != nil {
+		logger.For(req).Error("Error running AfterDelete method in deleteHandler for:", t, err)
+		return
+	}
+
+	if reject == "true" {
+		err = hook.AfterReject(res, req)
+		if err != nil {
+			logger.For(req).Error("Error running AfterReject method in deleteHandler for:", t, err)
 			return
 		}
-		post := contentType()
-
-		if i != "" {
-			if status == "pending" {
-				t = t + "__pending"
-			}
+	}
 
-			data, err := db.Content(t + ":" + i)
-			if err != nil {
-				log.Println(err)
-				res.WriteHeader(http.StatusInternalServerError)
-				errView, err := Error500()
-				if err != nil {
-					return
-				}
+	action := "content.delete"
+	if reject == "true" {
+		action = "content.reject"
+	}
+	audit(req, action, t+":"+id, nil)
 
-				res.Write(errView)
-				return
-			}
+	redir := strings.TrimSuffix(req.URL.Scheme+req.URL.Host+req.URL.Path, "/edit/delete")
+	redir = redir + "/contents?type=" + ct
+	http.Redirect(res, req, redir, http.StatusFound)
+}
 
-			if len(data) < 1 || data == nil {
-				res.WriteHeader(http.StatusNotFound)
-				errView, err := Error404()
-				if err != nil {
-					return
-				}
+func editUploadHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
 
-				res.Write(errView)
-				return
-			}
+	urlPaths, err := upload.StoreFiles(req)
+	if rejected, ok := err.(*upload.RejectedError); ok {
+		logger.For(req).Error(err)
+		j, err := json.Marshal(map[string]string{"error": rejected.Error()})
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
-			err = json.Unmarshal(data, post)
-			if err != nil {
-				log.Println(err)
-				res.WriteHeader(http.StatusInternalServerError)
-				errView, err := Error500()
-				if err != nil {
-					return
-				}
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusBadRequest)
+		res.Write(j)
+		return
+	}
+	if err != nil {
+		logger.For(req).Error("Couldn't store file uploads.", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
 
-				res.Write(errView)
-				return
-			}
-		} else {
-			item, ok := post.(item.Identifiable)
-			if !ok {
-				log.Println("Content type", t, "doesn't implement item.Identifiable")
-				return
-			}
+	res.Header().Set("Content-Type", "application/json")
+	res.Write([]byte(`{"data": [{"url": "` + urlPaths["file"] + `"}]}`))
+}
 
-			item.SetItemID(-1)
+// importHandler imports content of a type from an uploaded CSV file. The file
+// is first previewed so its columns can be mapped to fields, then each row is
+// created as an item, or only validated for a dry run.
+func importHandler(res http.ResponseWriter, req *http.Request) {
+	t := req.URL.Query().Get("type")
+	if _, ok := item.Types[t]; !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		errView, err := Error400(req)
+		if err != nil {
+			return
 		}
 
-		m, err := manager.Manage(post.(editor.Editable), t)
+		res.Write(errView)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		view, err := Import(req, t, nil, nil, "", nil, nil, false)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -1500,22 +4016,15 @@ func editHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		adminView, err := Admin(m)
-		if err != nil {
-			log.Println(err)
-			res.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
 		res.Header().Set("Content-Type", "text/html")
-		res.Write(adminView)
+		res.Write(view)
 
 	case http.MethodPost:
 		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -1524,26 +4033,30 @@ func editHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		cid := req.FormValue("id")
-		t := req.FormValue("type")
-		ts := req.FormValue("timestamp")
-		up := req.FormValue("updated")
+		// the CSV is uploaded to preview it, then sent back with the mapping
+		data := req.FormValue("data")
+		if f, _, err := req.FormFile("csv"); err == nil {
+			b, err := ioutil.ReadAll(f)
+			f.Close()
+			if err != nil {
+				logger.For(req).Error("Error reading CSV upload for import:", err)
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
 
-		// create a timestamp if one was not set
-		if ts == "" {
-			ts = fmt.Sprintf("%d", int64(time.Nanosecond)*time.Now().UnixNano()/int64(time.Millisecond))
-			req.PostForm.Set("timestamp", ts)
+			data = string(b)
 		}
+		data = strings.TrimPrefix(data, "\ufeff")
 
-		if up == "" {
-			req.PostForm.Set("updated", ts)
+		r := csv.NewReader(strings.NewReader(data))
+		r.FieldsPerRecord = -1
+		records, err := r.ReadAll()
+		if err == nil && len(records) == 0 {
+			err = fmt.Errorf("The file is empty")
 		}
-
-		urlPaths, err := upload.StoreFiles(req)
 		if err != nil {
-			log.Println(err)
-			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := ErrorMessage(req, "Invalid CSV", html.EscapeString(err.Error()))
 			if err != nil {
 				return
 			}
@@ -1552,67 +4065,53 @@ func editHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		for name, urlPath := range urlPaths {
-			req.PostForm.Set(name, urlPath)
-		}
-
-		// check for any multi-value fields (ex. checkbox fields)
-		// and correctly format for db storage. Essentially, we need
-		// fieldX.0: value1, fieldX.1: value2 => fieldX: []string{value1, value2}
-		fieldOrderValue := make(map[string]map[string][]string)
-		ordVal := make(map[string][]string)
-		for k, v := range req.PostForm {
-			if strings.Contains(k, ".") {
-				fo := strings.Split(k, ".")
-
-				// put the order and the field value into map
-				field := string(fo[0])
-				order := string(fo[1])
-				fieldOrderValue[field] = ordVal
-
-				// orderValue is 0:[?type=Thing&id=1]
-				orderValue := fieldOrderValue[field]
-				orderValue[order] = v
-				fieldOrderValue[field] = orderValue
+		header, rows := records[0], records[1:]
+		columns := req.PostForm["column"]
+		step := req.FormValue("step")
+
+		var results []db.ImportResult
+		if step == "dryrun" || step == "import" {
+			// imported rows enter the workflow as new content, the same as
+			// items created in the editor
+			results, err = db.ImportRowsWithOptions(t, columns, rows, db.ImportOptions{
+				DryRun: step == "dryrun",
+				Prepare: func(data url.Values) error {
+					_, _, err := applyWorkflow(req, t, data, nil, "")
+					return err
+				},
+			})
+			if err != nil {
+				logger.For(req).Error("Error importing CSV for:", t, err)
+				res.WriteHeader(http.StatusInternalServerError)
+				errView, err := Error500(req)
+				if err != nil {
+					return
+				}
 
-				// discard the post form value with name.N
-				req.PostForm.Del(k)
+				res.Write(errView)
+				return
 			}
 
-		}
-
-		// add/set the key & value to the post form in order
-		for f, ov := range fieldOrderValue {
-			for i := 0; i < len(ov); i++ {
-				position := fmt.Sprintf("%d", i)
-				fieldValue := ov[position]
-
-				if req.PostForm.Get(f) == "" {
-					for i, fv := range fieldValue {
-						if i == 0 {
-							req.PostForm.Set(f, fv)
-						} else {
-							req.PostForm.Add(f, fv)
-						}
-					}
-				} else {
-					for _, fv := range fieldValue {
-						req.PostForm.Add(f, fv)
+			if step == "import" {
+				imported := 0
+				for _, r := range results {
+					if r.Err == nil {
+						imported++
 					}
 				}
+				audit(req, "content.import", t, map[string]string{
+					"format":   "csv",
+					"imported": strconv.Itoa(imported),
+					"rows":     strconv.Itoa(len(rows)),
+				})
 			}
 		}
 
-		pt := t
-		if strings.Contains(t, "__") {
-			pt = strings.Split(t, "__")[0]
-		}
-
-		p, ok := item.Types[pt]
-		if !ok {
-			log.Println("Type", t, "is not a content type. Cannot edit or save.")
-			res.WriteHeader(http.StatusBadRequest)
-			errView, err := Error400()
+		view, err := Import(req, t, header, rows, data, columns, results, step == "dryrun")
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -1621,183 +4120,277 @@ func editHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		post := p()
-		hook, ok := post.(item.Hookable)
-		if !ok {
-			log.Println("Type", pt, "does not implement item.Hookable or embed item.Item.")
-			res.WriteHeader(http.StatusBadRequest)
-			errView, err := Error400()
-			if err != nil {
-				return
-			}
-
-			res.Write(errView)
-			return
-		}
+		res.Header().Set("Content-Type", "text/html")
+		res.Write(view)
 
-		err = hook.BeforeSave(res, req)
-		if err != nil {
-			log.Println("Error running BeforeSave method in editHandler for:", t, err)
-			return
-		}
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
 
-		id, err := db.SetContent(t+":"+cid, req.PostForm)
-		if err != nil {
-			log.Println(err)
-			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
-			if err != nil {
-				return
-			}
+// exportHandler writes all items of a content type as a JSON array, to be
+// restored with importJSONHandler
+func exportHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
 
-			res.Write(errView)
-			return
-		}
+	t := req.URL.Query().Get("type")
+	if _, ok := item.Types[t]; !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
 
-		// set the target in the context so user can get saved value from db in hook
-		ctx := context.WithValue(req.Context(), "target", fmt.Sprintf("%s:%d", t, id))
-		req = req.WithContext(ctx)
+	filename := fmt.Sprintf("%s-%d.json", strings.ToLower(t), time.Now().Unix())
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
 
-		err = hook.AfterSave(res, req)
-		if err != nil {
-			log.Println("Error running AfterSave method in editHandler for:", t, err)
-			return
+	res.Write([]byte("["))
+	for i, j := range db.ContentAll(t) {
+		if i > 0 {
+			res.Write([]byte(","))
 		}
+		res.Write(j)
+	}
+	res.Write([]byte("]"))
+}
 
-		scheme := req.URL.Scheme
-		host := req.URL.Host
-		path := req.URL.Path
-		sid := fmt.Sprintf("%d", id)
-		redir := scheme + host + path + "?type=" + pt + "&id=" + sid
+// importSummary is the response of importJSONHandler
+type importSummary struct {
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Skipped int           `json:"skipped"`
+	Errors  []importError `json:"errors"`
+}
 
-		if req.URL.Query().Get("status") == "pending" {
-			redir += "&status=pending"
-		}
+type importError struct {
+	Index int    `json:"index"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
 
-		http.Redirect(res, req, redir, http.StatusFound)
+// discardResponse is given to the hooks of items saved in bulk, which have no
+// response of their own to write to
+type discardResponse struct {
+	header http.Header
+}
 
-	default:
-		res.WriteHeader(http.StatusMethodNotAllowed)
+func (d *discardResponse) Header() http.Header {
+	if d.header == nil {
+		d.header = http.Header{}
 	}
+	return d.header
 }
 
-func deleteHandler(res http.ResponseWriter, req *http.Request) {
+func (d *discardResponse) Write(b []byte) (int, error) { return len(b), nil }
+
+func (d *discardResponse) WriteHeader(int) {}
+
+// importJSONHandler imports a JSON array of items of a content type, as written
+// by exportHandler, from the request body or an uploaded file named json. Items
+// with the id of an existing item update it, and others are created with a new
+// id. Each item is saved through the BeforeSave and AfterSave hooks, and those
+// which are invalid or rejected by a hook are skipped.
+func importJSONHandler(res http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodPost {
 		res.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
-	if err != nil {
-		log.Println(err)
-		res.WriteHeader(http.StatusInternalServerError)
-		errView, err := Error500()
+	t := req.URL.Query().Get("type")
+	it, ok := item.Types[t]
+	if !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var body io.Reader = req.Body
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		f, _, err := req.FormFile("json")
 		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		defer f.Close()
 
-		res.Write(errView)
-		return
+		body = f
 	}
 
-	id := req.FormValue("id")
-	t := req.FormValue("type")
-	ct := t
-
-	if id == "" || t == "" {
+	var items []json.RawMessage
+	err := json.NewDecoder(body).Decode(&items)
+	if err != nil {
+		logger.For(req).Error("Error decoding JSON import for:", t, err)
 		res.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	// catch specifier suffix from delete form value
-	if strings.Contains(t, "__") {
-		spec := strings.Split(t, "__")
-		ct = spec[0]
+	summary := importSummary{Errors: []importError{}}
+	skip := func(i int, id string, err error) {
+		summary.Skipped++
+		summary.Errors = append(summary.Errors, importError{Index: i, ID: id, Error: err.Error()})
 	}
 
-	p, ok := item.Types[ct]
-	if !ok {
-		log.Println("Type", t, "does not implement item.Hookable or embed item.Item.")
-		res.WriteHeader(http.StatusBadRequest)
-		errView, err := Error400()
+	ts := fmt.Sprintf("%d", int64(time.Nanosecond)*time.Now().UnixNano()/int64(time.Millisecond))
+	for i, j := range items {
+		data, err := db.ImportJSONValues(j)
 		if err != nil {
-			return
+			skip(i, "", err)
+			continue
 		}
 
-		res.Write(errView)
-		return
-	}
+		// keep the item's id if it exists, otherwise create it as new
+		id := data.Get("id")
+		target := t + ":-1"
+		var existing []byte
+		if id != "" {
+			existing, err = db.Content(t + ":" + id)
+			if err == nil && len(existing) > 0 {
+				target = t + ":" + id
+			}
+		}
+		if target == t+":-1" {
+			data.Del("id")
+			data.Del("uuid")
+			existing = nil
+		}
 
-	post := p()
-	hook, ok := post.(item.Hookable)
-	if !ok {
-		log.Println("Type", t, "does not implement item.Hookable or embed item.Item.")
-		res.WriteHeader(http.StatusBadRequest)
-		errView, err := Error400()
+		// imported items move through the workflow to the state they were
+		// exported in, as if saved in the editor
+		_, _, err = applyWorkflow(req, t, data, existing, data.Get("state"))
 		if err != nil {
-			return
+			skip(i, id, err)
+			continue
 		}
 
-		res.Write(errView)
-		return
-	}
+		if data.Get("timestamp") == "" {
+			data.Set("timestamp", ts)
+		}
+		data.Set("updated", ts)
 
-	reject := req.URL.Query().Get("reject")
-	if reject == "true" {
-		err = hook.BeforeReject(res, req)
+		// imported items replace what is stored, whichever version it is
+		data.Del("version")
+
+		hook, ok := it().(item.Hookable)
+		if !ok {
+			skip(i, id, fmt.Errorf("%s does not implement item.Hookable", t))
+			continue
+		}
+
+		// hooks are given the values of the item as the form of the request
+		r := req.WithContext(req.Context())
+		r.Form, r.PostForm = data, data
+
+		err = hook.BeforeSave(&discardResponse{}, r)
 		if err != nil {
-			log.Println("Error running BeforeReject method in deleteHandler for:", t, err)
-			return
+			skip(i, id, err)
+			continue
+		}
+
+		saved, err := db.SetContent(target, data)
+		if err != nil {
+			skip(i, id, err)
+			continue
+		}
+
+		if target == t+":-1" {
+			summary.Created++
+		} else {
+			summary.Updated++
+		}
+
+		ctx := context.WithValue(r.Context(), "target", fmt.Sprintf("%s:%d", t, saved))
+		err = hook.AfterSave(&discardResponse{}, r.WithContext(ctx))
+		if err != nil {
+			logger.For(req).Error("Error running AfterSave method in importJSONHandler for:", t, err)
 		}
 	}
 
-	err = hook.BeforeDelete(res, req)
+	audit(req, "content.import", t, map[string]string{
+		"format":  "json",
+		"created": strconv.Itoa(summary.Created),
+		"updated": strconv.Itoa(summary.Updated),
+		"skipped": strconv.Itoa(summary.Skipped),
+	})
+
+	j, err := json.Marshal(summary)
 	if err != nil {
-		log.Println("Error running BeforeDelete method in deleteHandler for:", t, err)
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	err = db.DeleteContent(t+":"+id, req.Form)
-	if err != nil {
-		log.Println(err)
-		res.WriteHeader(http.StatusInternalServerError)
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(j)
+}
+
+// maxReferenceOptions is the number of items offered at once by a Reference
+// editor field, which can be narrowed by searching
+const maxReferenceOptions = 50
+
+// referencesHandler writes the items of a content type which a Reference editor
+// field can refer to, each with its reference path and name. Items are found by
+// a case-insensitive match of q in their name, and the selected item is always
+// included.
+func referencesHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	err = hook.AfterDelete(res, req)
-	if err != nil {
-		log.Println("Error running AfterDelete method in deleteHandler for:", t, err)
+	q := req.URL.Query()
+	t := q.Get("type")
+	search := strings.ToLower(q.Get("q"))
+	selected := q.Get("selected")
+
+	it, ok := item.Types[t]
+	if !ok {
+		res.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	if reject == "true" {
-		err = hook.AfterReject(res, req)
+	type option struct {
+		Value string `json:"value"`
+		Label string `json:"label"`
+	}
+
+	var opts = []option{}
+	for _, post := range db.ContentAll(t) {
+		p := it()
+		err := json.Unmarshal(post, p)
 		if err != nil {
-			log.Println("Error running AfterReject method in deleteHandler for:", t, err)
-			return
+			logger.For(req).Error("Error decoding content for reference:", t, err)
+			continue
 		}
-	}
 
-	redir := strings.TrimSuffix(req.URL.Scheme+req.URL.Host+req.URL.Path, "/edit/delete")
-	redir = redir + "/contents?type=" + ct
-	http.Redirect(res, req, redir, http.StatusFound)
-}
+		id, ok := p.(item.Identifiable)
+		if !ok {
+			continue
+		}
 
-func editUploadHandler(res http.ResponseWriter, req *http.Request) {
-	if req.Method != http.MethodPost {
-		res.WriteHeader(http.StatusMethodNotAllowed)
-		return
+		opt := option{
+			Value: item.ReferencePath(t, id.ItemID()),
+			Label: id.String(),
+		}
+
+		if opt.Value != selected {
+			if len(opts) >= maxReferenceOptions || !strings.Contains(strings.ToLower(opt.Label), search) {
+				continue
+			}
+		}
+
+		opts = append(opts, opt)
 	}
 
-	urlPaths, err := upload.StoreFiles(req)
+	j, err := json.Marshal(map[string][]option{"data": opts})
 	if err != nil {
-		log.Println("Couldn't store file uploads.", err)
+		logger.For(req).Error(err)
 		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	res.Header().Set("Content-Type", "application/json")
-	res.Write([]byte(`{"data": [{"url": "` + urlPaths["file"] + `"}]}`))
+	res.Write(j)
 }
 
 func searchHandler(res http.ResponseWriter, req *http.Request) {
@@ -1833,9 +4426,9 @@ func searchHandler(res http.ResponseWriter, req *http.Request) {
 							<input type="hidden" name="status" value="` + status + `" />
 						</div>
                     </form>	
-					</div>
-					<ul class="posts row">`
+					</div>`
 
+	var matches int
 	for i := range posts {
 		// skip posts that don't have any matching search criteria
 		match := strings.ToLower(search)
@@ -1846,17 +4439,17 @@ func searchHandler(res http.ResponseWriter, req *http.Request) {
 
 		err := json.Unmarshal(posts[i], &p)
 		if err != nil {
-			log.Println("Error unmarshal search result json into", t, err, posts[i])
+			logger.For(req).Error("Error unmarshal search result json into", t, err, posts[i])
 
 			post := `<li class="col s12">Error decoding data. Possible file corruption.</li>`
 			_, err = b.Write([]byte(post))
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 
 				res.WriteHeader(http.StatusInternalServerError)
-				errView, err := Error500()
+				errView, err := Error500(req)
 				if err != nil {
-					log.Println(err)
+					logger.For(req).Error(err)
 				}
 
 				res.Write(errView)
@@ -1865,15 +4458,16 @@ func searchHandler(res http.ResponseWriter, req *http.Request) {
 			continue
 		}
 
+		matches++
 		post := adminPostListItem(p, t, status)
 		_, err = b.Write([]byte(post))
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 			}
 
 			res.Write(errView)
@@ -1883,12 +4477,12 @@ func searchHandler(res http.ResponseWriter, req *http.Request) {
 
 	_, err := b.WriteString(`</ul></div></div>`)
 	if err != nil {
-		log.Println(err)
+		logger.For(req).Error(err)
 
 		res.WriteHeader(http.StatusInternalServerError)
-		errView, err := Error500()
+		errView, err := Error500(req)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 		}
 
 		res.Write(errView)
@@ -1896,11 +4490,11 @@ func searchHandler(res http.ResponseWriter, req *http.Request) {
 	}
 
 	btn := `<div class="col s3"><a href="/admin/edit?type=` + t + `" class="btn new-post waves-effect waves-light">New ` + t + `</a></div></div>`
-	html = html + b.String() + btn
+	html = html + batchBar(t, status, search, matches) + `<ul class="posts row">` + b.String() + btn
 
-	adminView, err := Admin([]byte(html))
+	adminView, err := Admin(req, []byte(html))
 	if err != nil {
-		log.Println(err)
+		logger.For(req).Error(err)
 		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -1919,11 +4513,11 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 			v := adminAddonListItem(all[i])
 			_, err := list.Write(v)
 			if err != nil {
-				log.Println("Error writing bytes to addon list view:", err)
+				logger.For(req).Error("Error writing bytes to addon list view:", err)
 				res.WriteHeader(http.StatusInternalServerError)
-				errView, err := Error500()
+				errView, err := Error500(req)
 				if err != nil {
-					log.Println(err)
+					logger.For(req).Error(err)
 					return
 				}
 
@@ -1942,11 +4536,11 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 
 		_, err := html.WriteString(open)
 		if err != nil {
-			log.Println("Error writing open html to addon html view:", err)
+			logger.For(req).Error("Error writing open html to addon html view:", err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 				return
 			}
 
@@ -1956,11 +4550,11 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 
 		_, err = html.Write(list.Bytes())
 		if err != nil {
-			log.Println("Error writing list bytes to addon html view:", err)
+			logger.For(req).Error("Error writing list bytes to addon html view:", err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 				return
 			}
 
@@ -1970,11 +4564,11 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 
 		_, err = html.WriteString(`</ul></div></div>`)
 		if err != nil {
-			log.Println("Error writing close html to addon html view:", err)
+			logger.For(req).Error("Error writing close html to addon html view:", err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 				return
 			}
 
@@ -1985,11 +4579,11 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 		if html.Len() == 0 {
 			_, err := html.WriteString(`<p>No addons available.</p>`)
 			if err != nil {
-				log.Println("Error writing default addon html to admin view:", err)
+				logger.For(req).Error("Error writing default addon html to admin view:", err)
 				res.WriteHeader(http.StatusInternalServerError)
-				errView, err := Error500()
+				errView, err := Error500(req)
 				if err != nil {
-					log.Println(err)
+					logger.For(req).Error(err)
 					return
 				}
 
@@ -1998,13 +4592,13 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 			}
 		}
 
-		view, err := Admin(html.Bytes())
+		view, err := Admin(req, html.Bytes())
 		if err != nil {
-			log.Println("Error writing addon html to admin view:", err)
+			logger.For(req).Error("Error writing addon html to admin view:", err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 				return
 			}
 
@@ -2017,9 +4611,9 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 	case http.MethodPost:
 		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -2033,9 +4627,9 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 
 		_, err = db.Addon(id)
 		if err == db.ErrNoAddonExists {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusNotFound)
-			errView, err := Error404()
+			errView, err := Error404(req)
 			if err != nil {
 				return
 			}
@@ -2044,9 +4638,9 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -2059,9 +4653,9 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 		case "enable":
 			err := addon.Enable(id)
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 				res.WriteHeader(http.StatusInternalServerError)
-				errView, err := Error500()
+				errView, err := Error500(req)
 				if err != nil {
 					return
 				}
@@ -2072,9 +4666,9 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 		case "disable":
 			err := addon.Disable(id)
 			if err != nil {
-				log.Println(err)
+				logger.For(req).Error(err)
 				res.WriteHeader(http.StatusInternalServerError)
-				errView, err := Error500()
+				errView, err := Error500(req)
 				if err != nil {
 					return
 				}
@@ -2084,7 +4678,7 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 			}
 		default:
 			res.WriteHeader(http.StatusBadRequest)
-			errView, err := Error400()
+			errView, err := Error400(req)
 			if err != nil {
 				return
 			}
@@ -2093,13 +4687,15 @@ func addonsHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
+		audit(req, "addon."+action, id, nil)
+
 		http.Redirect(res, req, req.URL.String(), http.StatusFound)
 
 	default:
 		res.WriteHeader(http.StatusBadRequest)
-		errView, err := Error400()
+		errView, err := Error400(req)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			return
 		}
 
@@ -2115,9 +4711,9 @@ func addonHandler(res http.ResponseWriter, req *http.Request) {
 
 		data, err := db.Addon(id)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -2128,9 +4724,9 @@ func addonHandler(res http.ResponseWriter, req *http.Request) {
 
 		_, ok := addon.Types[id]
 		if !ok {
-			log.Println("Addon: ", id, "is not found in addon.Types map")
+			logger.For(req).Error("Addon: ", id, "is not found in addon.Types map")
 			res.WriteHeader(http.StatusNotFound)
-			errView, err := Error404()
+			errView, err := Error404(req)
 			if err != nil {
 				return
 			}
@@ -2141,9 +4737,9 @@ func addonHandler(res http.ResponseWriter, req *http.Request) {
 
 		m, err := addon.Manage(data, id)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -2152,9 +4748,9 @@ func addonHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
-		addonView, err := Admin(m)
+		addonView, err := Admin(req, m)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -2166,9 +4762,9 @@ func addonHandler(res http.ResponseWriter, req *http.Request) {
 		// save req.Form
 		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -2182,9 +4778,9 @@ func addonHandler(res http.ResponseWriter, req *http.Request) {
 
 		at, ok := addon.Types[id]
 		if !ok {
-			log.Println("Error: addon", name, "has no record in addon.Types map at", id)
+			logger.For(req).Error("Error: addon", name, "has no record in addon.Types map at", id)
 			res.WriteHeader(http.StatusBadRequest)
-			errView, err := Error400()
+			errView, err := Error400(req)
 			if err != nil {
 				return
 			}
@@ -2198,16 +4794,16 @@ func addonHandler(res http.ResponseWriter, req *http.Request) {
 		if ok {
 			err := h.BeforeSave(res, req)
 			if err != nil {
-				log.Println("Error running BeforeSave method in addonHandler for:", id, err)
+				logger.For(req).Error("Error running BeforeSave method in addonHandler for:", id, err)
 				return
 			}
 		}
 
 		err = db.SetAddon(req.Form, at())
 		if err != nil {
-			log.Println("Error saving addon:", name, err)
+			logger.For(req).Error("Error saving addon:", name, err)
 			res.WriteHeader(http.StatusInternalServerError)
-			errView, err := Error500()
+			errView, err := Error500(req)
 			if err != nil {
 				return
 			}
@@ -2216,13 +4812,15 @@ func addonHandler(res http.ResponseWriter, req *http.Request) {
 			return
 		}
 
+		audit(req, "addon.config", id, nil)
+
 		http.Redirect(res, req, "/admin/addon?id="+id, http.StatusFound)
 
 	default:
 		res.WriteHeader(http.StatusBadRequest)
-		errView, err := Error405()
+		errView, err := Error405(req)
 		if err != nil {
-			log.Println(err)
+			logger.For(req).Error(err)
 			return
 		}
 
@@ -2270,3 +4868,38 @@ func adminAddonListItem(data []byte) []byte {
 
 	return []byte(a)
 }
+
+// invalidEditor returns the editor for content of type pt, saved as t:cid,
+// filled in with the values submitted in the form of req so that they can be
+// corrected, and marked with the problems in fields which kept it from being
+// saved
+func invalidEditor(req *http.Request, pt, t, cid string, fields map[string]string) ([]byte, error) {
+	post := item.Types[pt]()
+
+	// values which don't convert to their field are left empty
+	dec := schema.NewDecoder()
+	dec.IgnoreUnknownKeys(true)
+	dec.SetAliasTag("json")
+	dec.Decode(post, req.PostForm)
+
+	if cid == "" {
+		cid = "-1"
+	}
+	id, err := strconv.Atoi(cid)
+	if err != nil {
+		return nil, err
+	}
+	post.(item.Identifiable).SetItemID(id)
+
+	m, err := manager.Manage(post.(editor.Editable), t)
+	if err != nil {
+		return nil, err
+	}
+
+	errs, err := EditorErrors(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return Admin(req, append(errs, m...))
+}
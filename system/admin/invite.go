@@ -0,0 +1,106 @@
+package admin
+
+import (
+	crand "crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	emailer "github.com/ponzu-cms/ponzu/system/email"
+
+	"github.com/nilslice/jwt"
+)
+
+// defaultInviteHours is how long an invitation can be accepted for, if
+// invite_expiry_hours is not configured
+const defaultInviteHours = 72
+
+// sendInvite invites the owner of email to become an admin user with role,
+// replacing any invitation already sent to them, and emails them a link to
+// accept it which is signed and expires with the invitation
+func sendInvite(email, role, invitedBy string) error {
+	buf := make([]byte, 16)
+	_, err := crand.Read(buf)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	exp := now.Add(configDuration("invite_expiry_hours", defaultInviteHours, time.Hour))
+	inv := db.Invite{
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		Nonce:     hex.EncodeToString(buf),
+		Created:   now.UnixNano() / int64(time.Millisecond),
+		Expires:   exp.UnixNano() / int64(time.Millisecond),
+	}
+
+	token, err := jwt.New(map[string]interface{}{
+		"exp":    exp.Unix(),
+		"invite": inv.Email,
+		"nonce":  inv.Nonce,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = db.SetInvite(inv)
+	if err != nil {
+		return err
+	}
+
+	domain, _ := db.ConfigCache("domain").(string)
+	name, _ := db.ConfigCache("name").(string)
+	if name == "" {
+		name = domain
+	}
+
+	body := fmt.Sprintf(`
+%s has invited you to become an admin of %s.
+
+To accept the invitation and choose your password, please go to:
+http://%s/admin/invite?token=%s
+
+The invitation expires on %s. If you weren't expecting it, you can ignore
+this message.
+
+
+Thank you,
+Ponzu CMS at %s
+
+`, invitedBy, name, domain, url.QueryEscape(token), exp.Format("Jan 2, 2006 at 3:04 PM MST"), domain)
+
+	return emailer.Send(email, fmt.Sprintf("Invitation to %s [%s]", name, domain), body)
+}
+
+// inviteFromToken returns the invitation which token was sent for, if the token
+// is signed, and its invitation has neither expired nor been replaced, revoked
+// or accepted
+func inviteFromToken(token string) (db.Invite, error) {
+	if !jwt.Passes(token) {
+		return db.Invite{}, db.ErrNoInvite
+	}
+
+	claims := jwt.GetClaims(token)
+	email, _ := claims["invite"].(string)
+	nonce, _ := claims["nonce"].(string)
+	exp, _ := claims["exp"].(float64)
+	if email == "" || time.Now().Unix() > int64(exp) {
+		return db.Invite{}, db.ErrNoInvite
+	}
+
+	inv, err := db.GetInvite(email)
+	if err != nil {
+		return db.Invite{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(inv.Nonce), []byte(nonce)) != 1 {
+		return db.Invite{}, db.ErrNoInvite
+	}
+
+	return inv, nil
+}
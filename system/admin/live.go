@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/api/analytics"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// liveBuffer is the number of requests queued for each live analytics client,
+// beyond which requests are dropped for that client until it catches up
+const liveBuffer = 256
+
+// livePingInterval is how often a live analytics client is pinged, so that
+// proxies keep the socket open and a dead connection is noticed
+const livePingInterval = 30 * time.Second
+
+// liveRequest is the summary of a recorded API request sent to live analytics
+// clients
+type liveRequest struct {
+	Timestamp  int64   `json:"timestamp"`
+	Method     string  `json:"http_method"`
+	URL        string  `json:"url"`
+	Status     int     `json:"status,omitempty"`
+	DurationMs float64 `json:"duration_ms,omitempty"`
+	IP         string  `json:"ip_address"`
+	Country    string  `json:"country,omitempty"`
+	External   bool    `json:"external_content,omitempty"`
+	Bot        bool    `json:"bot,omitempty"`
+}
+
+// liveAnalyticsHandler streams each API request as it is recorded to a
+// websocket, as a json liveRequest message
+func liveAnalyticsHandler(res http.ResponseWriter, req *http.Request) {
+	ws := upgradeWebsocket(res, req)
+	if ws == nil {
+		return
+	}
+	defer ws.Close()
+
+	sub := analytics.Subscribe(liveBuffer)
+	defer sub.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		ws.ReadLoop()
+		close(closed)
+	}()
+
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case r := <-sub.Requests():
+			j, err := json.Marshal(liveRequest{
+				Timestamp:  r.Timestamp,
+				Method:     r.Method,
+				URL:        r.URL,
+				Status:     r.Status,
+				DurationMs: r.DurationMs,
+				IP:         r.RemoteAddr,
+				Country:    r.Country,
+				External:   r.External,
+				Bot:        r.Bot,
+			})
+			if err != nil {
+				logger.For(req).Error("Error encoding live analytics request:", err)
+				continue
+			}
+
+			err = ws.WriteText(j)
+			if err != nil {
+				return
+			}
+
+		case <-ping.C:
+			err := ws.write(wsPing, nil)
+			if err != nil {
+				return
+			}
+
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package admin
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/api"
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+const (
+	// defaultLoginAttempts is the number of failed logins allowed for an
+	// account before it is locked, if login_max_attempts is not configured
+	defaultLoginAttempts = 5
+	// defaultLoginAttemptsIP is the number of failed logins allowed from a
+	// client IP before it is locked, if login_max_attempts_ip is not configured
+	defaultLoginAttemptsIP = 20
+	// defaultLockoutMinutes is the length of the first lockout, if
+	// login_lockout_minutes is not configured. Each lockout after it, before
+	// the failures are forgotten, is twice as long as the one before.
+	defaultLockoutMinutes = 5
+	// maxLockout is the longest a lockout can become by backing off
+	maxLockout = 24 * time.Hour
+	// loginFailureTTL is how long failures and lockouts are remembered after
+	// the last of them
+	loginFailureTTL = 24 * time.Hour
+)
+
+// loginFailures counts the failed logins of an account or client IP
+type loginFailures struct {
+	count    int
+	lockouts int
+	until    time.Time
+	last     time.Time
+}
+
+// failedLogins holds the failures of accounts, keyed by "user:" and the email,
+// and of client IPs, keyed by "ip:" and the IP
+var failedLogins = struct {
+	sync.Mutex
+	keys map[string]*loginFailures
+}{keys: make(map[string]*loginFailures)}
+
+// loginLimit returns the config value of name if it is set, otherwise def
+func loginLimit(name string, def int) int {
+	n, ok := db.ConfigCache(name).(float64)
+	if !ok || n < 1 {
+		return def
+	}
+
+	return int(n)
+}
+
+// loginLockedFor returns how much longer logins to the account with email or
+// from ip are locked out, which is 0 if neither is
+func loginLockedFor(email, ip string) time.Duration {
+	now := time.Now()
+
+	failedLogins.Lock()
+	defer failedLogins.Unlock()
+
+	var wait time.Duration
+	for _, key := range []string{"user:" + email, "ip:" + ip} {
+		f, ok := failedLogins.keys[key]
+		if ok && f.until.After(now) && f.until.Sub(now) > wait {
+			wait = f.until.Sub(now)
+		}
+	}
+
+	return wait
+}
+
+// loginFailed counts a failed login to the account with email from ip, and
+// locks out either if it has reached its limit of failures. Client IPs flagged
+// by abuse detection are allowed half as many failures, and an IP locked out
+// is flagged so its API requests are limited too. It returns how long logins
+// are now locked out, which is 0 if they are not.
+func loginFailed(email, ip string) time.Duration {
+	ipLimit := loginLimit("login_max_attempts_ip", defaultLoginAttemptsIP)
+	if api.IsFlagged(ip) {
+		ipLimit = (ipLimit + 1) / 2
+	}
+
+	base := time.Duration(loginLimit("login_lockout_minutes", defaultLockoutMinutes)) * time.Minute
+	now := time.Now()
+
+	failedLogins.Lock()
+	defer failedLogins.Unlock()
+
+	// forget failures which have not been added to in a while
+	for k, f := range failedLogins.keys {
+		if now.Sub(f.last) > loginFailureTTL && now.After(f.until) {
+			delete(failedLogins.keys, k)
+		}
+	}
+
+	var wait time.Duration
+	limits := map[string]int{
+		"user:" + email: loginLimit("login_max_attempts", defaultLoginAttempts),
+		"ip:" + ip:      ipLimit,
+	}
+	for key, limit := range limits {
+		f, ok := failedLogins.keys[key]
+		if !ok {
+			f = &loginFailures{}
+			failedLogins.keys[key] = f
+		}
+
+		f.count++
+		f.last = now
+		if f.count < limit {
+			continue
+		}
+
+		// back off exponentially with each lockout
+		d := time.Duration(float64(base) * math.Pow(2, float64(f.lockouts)))
+		if d > maxLockout || d <= 0 {
+			d = maxLockout
+		}
+
+		f.count = 0
+		f.lockouts++
+		f.until = now.Add(d)
+		if d > wait {
+			wait = d
+		}
+
+		if key == "ip:"+ip {
+			api.FlagIP(ip, limit)
+		}
+	}
+
+	return wait
+}
+
+// loginSucceeded forgets the failed logins to the account with email and from
+// ip
+func loginSucceeded(email, ip string) {
+	failedLogins.Lock()
+	delete(failedLogins.keys, "user:"+email)
+	delete(failedLogins.keys, "ip:"+ip)
+	failedLogins.Unlock()
+}
+
+// writeLockedOut responds to a login attempt while logins are locked out for
+// wait, telling the user when to try again
+func writeLockedOut(res http.ResponseWriter, wait time.Duration, view func(message string) ([]byte, error)) {
+	minutes := int(math.Ceil(wait.Minutes()))
+	msg := "Too many failed login attempts. Please try again in 1 minute."
+	if minutes > 1 {
+		msg = fmt.Sprintf("Too many failed login attempts. Please try again in %d minutes.", minutes)
+	}
+
+	v, err := view(msg)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/html")
+	res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	res.WriteHeader(http.StatusTooManyRequests)
+	res.Write(v)
+}
@@ -0,0 +1,136 @@
+package admin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// listContents returns the total content of type t and the page of it given by
+// opts, from the bucket with specifier, or in the order set in the admin if
+// manual is set
+func listContents(t, specifier string, opts db.QueryOptions, manual bool) (int, [][]byte) {
+	if !manual {
+		return db.Query(t+specifier, opts)
+	}
+
+	q := db.NewQuery(t).OrderBy("order", "asc")
+	if opts.Count > 0 {
+		q.Limit(opts.Count).Offset(opts.Count * opts.Offset)
+	}
+
+	posts, err := q.Raw()
+	if err != nil {
+		logger.Error("Error listing content in order:", t, err)
+		return 0, nil
+	}
+
+	total, ok := db.Total(t)
+	if !ok {
+		total = len(posts)
+	}
+
+	return total, posts
+}
+
+// orderHandler saves the order of content of a manually ordered type, from the
+// ids of a page of the content list in the order they were dragged into.
+// Content on other pages keeps its position.
+func orderHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	t := req.FormValue("type")
+	it, ok := item.Types[t]
+	if !ok || !item.IsOrderable(it()) {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ids := req.Form["id"]
+	err = db.Reorder(t, ids)
+	if err != nil {
+		logger.For(req).Error("Error reordering content:", t, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	audit(req, "content.reorder", t, map[string]string{"ids": strings.Join(ids, ",")})
+
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// orderScript makes the items of a content list of type t draggable, saving
+// their order each time one is dropped
+func orderScript(t string) string {
+	return `
+	<script>
+		$(function() {
+			var list = $('ul.posts'),
+				dragging = null;
+
+			list.before('<p class="description">Drag items to reorder them.</p>');
+
+			var save = function() {
+				var data = new FormData();
+				data.append('type', '` + t + `');
+				list.children('li').each(function() {
+					data.append('id', $(this).find('.batch-select').val());
+				});
+
+				$.ajax({
+					url: '/admin/contents/order',
+					type: 'POST',
+					data: data,
+					processData: false,
+					contentType: false,
+					error: function(xhr) {
+						alert('[Ponzu] The new order could not be saved: ' + xhr.statusText);
+						window.location.reload();
+					}
+				});
+			};
+
+			list.children('li').attr('draggable', 'true')
+				.on('dragstart', function(e) {
+					dragging = this;
+					e.originalEvent.dataTransfer.effectAllowed = 'move';
+					e.originalEvent.dataTransfer.setData('text/plain', '');
+				})
+				.on('dragover', function(e) {
+					e.preventDefault();
+					if (!dragging || dragging === this) {
+						return;
+					}
+
+					var rect = this.getBoundingClientRect();
+					if (e.originalEvent.clientY < rect.top + rect.height / 2) {
+						$(this).before(dragging);
+					} else {
+						$(this).after(dragging);
+					}
+				})
+				.on('drop', function(e) {
+					e.preventDefault();
+				})
+				.on('dragend', function() {
+					if (dragging) {
+						dragging = null;
+						save();
+					}
+				});
+		});
+	</script>`
+}
@@ -0,0 +1,70 @@
+package admin
+
+import (
+	"github.com/ponzu-cms/ponzu/system/admin/user"
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// passwordPolicy returns the rules for new passwords set in the config
+func passwordPolicy() user.PasswordPolicy {
+	min, _ := db.ConfigCache("password_min_length").(float64)
+	upper, _ := db.ConfigCache("password_require_upper").(bool)
+	lower, _ := db.ConfigCache("password_require_lower").(bool)
+	digit, _ := db.ConfigCache("password_require_digit").(bool)
+	symbol, _ := db.ConfigCache("password_require_symbol").(bool)
+
+	return user.PasswordPolicy{
+		MinLength:     int(min),
+		RequireUpper:  upper,
+		RequireLower:  lower,
+		RequireDigit:  digit,
+		RequireSymbol: symbol,
+	}
+}
+
+// bcryptCost returns the bcrypt cost set in the config to hash passwords with
+func bcryptCost() int {
+	cost, _ := db.ConfigCache("bcrypt_cost").(float64)
+	return user.ValidCost(int(cost))
+}
+
+// newUser creates a user with the configured bcrypt cost, if password follows
+// the password policy. Otherwise the error is a *user.PasswordError to show.
+func newUser(email, password string) (*user.User, error) {
+	err := passwordPolicy().Check(password)
+	if err != nil {
+		return nil, err
+	}
+
+	return user.NewWithCost(email, password, bcryptCost())
+}
+
+// rehashPassword hashes the password of usr again with the configured bcrypt
+// cost, if it was hashed with another and upgrading hashes is enabled. It is
+// called once usr has logged in with password.
+func rehashPassword(usr *user.User, password string) {
+	if upgrade, _ := db.ConfigCache("bcrypt_upgrade").(bool); !upgrade {
+		return
+	}
+
+	cost := bcryptCost()
+	if !usr.NeedsRehash(cost) {
+		return
+	}
+
+	updated := *usr
+	err := updated.SetPassword(password, cost)
+	if err != nil {
+		logger.Error("Error rehashing password of", usr.Email+":", err)
+		return
+	}
+
+	err = db.UpdateUser(usr, &updated)
+	if err != nil {
+		logger.Error("Error saving rehashed password of", usr.Email+":", err)
+		return
+	}
+
+	*usr = updated
+}
@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// previewURL returns the URL at which the content t:id previewed with token is
+// seen: the configured preview URL of the public site, with {type}, {id} and
+// {token} replaced, or else the content API
+func previewURL(t, id, token string) string {
+	tmpl, _ := db.ConfigCache("preview_url").(string)
+	if tmpl == "" {
+		return "/api/content?" + url.Values{
+			"type":    {t},
+			"id":      {id},
+			"preview": {token},
+		}.Encode()
+	}
+
+	return strings.NewReplacer(
+		"{type}", url.QueryEscape(t),
+		"{id}", url.QueryEscape(id),
+		"{token}", url.QueryEscape(token),
+	).Replace(tmpl)
+}
+
+// previewHandler keeps the content being edited, sent as the editor's form
+// without saving it, as a preview and responds with the URL to see it at
+func previewHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// content pending approval is previewed as it would be once approved
+	t := strings.Split(req.FormValue("type"), "__")[0]
+	if _, ok := item.Types[t]; !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	id := req.FormValue("id")
+	if id == "" || req.FormValue("type") != t {
+		id = "-1"
+	}
+
+	orderFormValues(req.PostForm)
+
+	j, err := db.PreviewJSON(t, req.PostForm)
+	if fields, ok := item.FieldErrors(err); ok {
+		j, err := json.Marshal(map[string]interface{}{
+			"errors": fields,
+		})
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusBadRequest)
+		res.Write(j)
+		return
+	}
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	token, err := db.NewPreview(t, id, j)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	j, err = json.Marshal(map[string]string{
+		"url": previewURL(t, id, token),
+	})
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(j)
+}
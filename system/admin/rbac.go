@@ -0,0 +1,158 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/admin/user"
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// permit wraps next so that it is only served to users whose role is allowed
+// the request, responding 403 Forbidden to others
+func permit(next http.HandlerFunc, allowed func(role user.Role, req *http.Request) bool) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		role, err := db.CurrentRole(req)
+		if err != nil {
+			logger.For(req).Error("Error getting role of current user:", err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		if !allowed(role, req) {
+			res.WriteHeader(http.StatusForbidden)
+			errView, err := Error403(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		next(res, req)
+	}
+}
+
+// permitContent allows requests by users whose role allows each of actions on
+// the content type of the request
+func permitContent(next http.HandlerFunc, actions ...user.Action) http.HandlerFunc {
+	return permit(next, func(role user.Role, req *http.Request) bool {
+		t := requestType(req)
+		for _, a := range actions {
+			if !role.Can(t, a) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// permitEdit allows requests to the editor by users whose role allows reading
+// or creating content of the type, depending on whether an item is opened, and
+// updating or creating it when it is saved
+func permitEdit(next http.HandlerFunc) http.HandlerFunc {
+	return permit(next, func(role user.Role, req *http.Request) bool {
+		t := requestType(req)
+		id := req.FormValue("id")
+		isNew := id == "" || id == "-1"
+
+		switch {
+		case req.Method == http.MethodPost && !isNew:
+			return role.Can(t, user.ActionUpdate)
+		case isNew:
+			return role.Can(t, user.ActionCreate)
+		default:
+			return role.Can(t, user.ActionRead)
+		}
+	})
+}
+
+// permitSection allows requests by users whose role manages the admin section
+func permitSection(section string, next http.HandlerFunc) http.HandlerFunc {
+	return permit(next, func(role user.Role, req *http.Request) bool {
+		return role.Manages(section)
+	})
+}
+
+// requestType returns the content type named by the type of a request's query
+// or form, without the suffix of external or pending content
+func requestType(req *http.Request) string {
+	if req.Method == http.MethodPost {
+		// the handler reports any error parsing the form itself
+		req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	}
+
+	t := req.FormValue("type")
+	if i := strings.Index(t, "__"); i >= 0 {
+		t = t[:i]
+	}
+
+	return t
+}
+
+// errRoleRequired is returned by roleName when no role is submitted
+var errRoleRequired = errors.New("No role was given.")
+
+// errRoleNotAllowed is returned by roleName for a role which allows more than
+// the role of the user assigning it
+var errRoleNotAllowed = errors.New("Role allows more than the current user's role.")
+
+// roleName returns the name of the role to assign to a user given the name
+// submitted in a form, which must be a saved role, or the super admin role,
+// which is stored as no role. The role must not allow more than caller, the
+// role of the user assigning it, so only super admins can make super admins.
+func roleName(caller user.Role, name string) (string, error) {
+	if name == "" {
+		return "", errRoleRequired
+	}
+
+	r, err := db.Role(name)
+	if err != nil {
+		return "", err
+	}
+
+	if !caller.Covers(r) {
+		return "", errRoleNotAllowed
+	}
+
+	if r.IsSuper() {
+		return "", nil
+	}
+
+	return name, nil
+}
+
+// roleError responds to a request whose submitted role is rejected by
+// roleName, with 403 Forbidden if the role allows too much, and 400 Bad
+// Request otherwise
+func roleError(res http.ResponseWriter, req *http.Request, err error) {
+	logger.For(req).Error(err)
+	if err == errRoleNotAllowed {
+		res.WriteHeader(http.StatusForbidden)
+		errView, err := Error403(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	res.WriteHeader(http.StatusBadRequest)
+	errView, err := Error400(req)
+	if err != nil {
+		return
+	}
+
+	res.Write(errView)
+}
@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/ponzu-cms/ponzu/system/admin/user"
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+func TestRoleName(t *testing.T) {
+	// the db is opened as system.db in the working directory
+	dir, err := ioutil.TempDir("", "ponzu-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.Init()
+	defer db.Close()
+
+	manager := user.Role{
+		Name:        "manager",
+		Permissions: map[string][]user.Action{"*": {user.ActionRead, user.ActionUpdate}},
+		Sections:    []string{user.SectionUsers},
+	}
+	reader := user.Role{
+		Name:        "reader",
+		Permissions: map[string][]user.Action{"Post": {user.ActionRead}},
+	}
+	admin := user.Role{
+		Name:        "admin",
+		Permissions: map[string][]user.Action{"*": user.Actions},
+		Sections:    user.Sections,
+	}
+	for _, r := range []user.Role{manager, reader, admin} {
+		err := db.SetRole(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		caller user.Role
+		name   string
+		want   string
+		err    error
+	}{
+		{user.SuperAdmin(), user.SuperAdminRole, "", nil},
+		{user.SuperAdmin(), "admin", "admin", nil},
+		{user.SuperAdmin(), "", "", errRoleRequired},
+		{user.SuperAdmin(), "missing", "", db.ErrNoRoleExists},
+		{manager, "reader", "reader", nil},
+		{manager, "manager", "manager", nil},
+		{manager, "", "", errRoleRequired},
+		{manager, user.SuperAdminRole, "", errRoleNotAllowed},
+		{manager, "admin", "", errRoleNotAllowed},
+		{reader, "manager", "", errRoleNotAllowed},
+		{user.Role{Name: "deleted"}, "reader", "", errRoleNotAllowed},
+	}
+
+	for _, c := range cases {
+		got, err := roleName(c.caller, c.name)
+		if got != c.want || err != c.err {
+			t.Errorf("%s assigning %q: expected %q, %v, got %q, %v", c.caller.Name, c.name, c.want, c.err, got, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/admin/user"
+	"github.com/ponzu-cms/ponzu/system/db"
+	emailer "github.com/ponzu-cms/ponzu/system/email"
+
+	"github.com/nilslice/jwt"
+)
+
+const (
+	// defaultResetMinutes is how long a password reset link can be used for,
+	// if password_reset_minutes is not configured
+	defaultResetMinutes = 60
+	// defaultResetRequests is the number of password resets which can be
+	// requested for an account within resetRequestWindow, if
+	// password_reset_max_requests is not configured
+	defaultResetRequests = 3
+	// resetRequestWindow is the period over which password reset requests for
+	// an account are counted
+	resetRequestWindow = time.Hour
+)
+
+// errResetInvalid is returned for a password reset token which isn't signed,
+// has expired, or has been used or replaced, or was sent before the user's
+// password was last changed
+var errResetInvalid = errors.New("Password reset link is no longer valid")
+
+// resetRequests holds the times password resets were requested for each
+// account within the last resetRequestWindow, keyed by email
+var resetRequests = struct {
+	sync.Mutex
+	times map[string][]time.Time
+}{times: make(map[string][]time.Time)}
+
+// resetRequestAllowed counts a password reset request for the account with
+// email and reports whether it is within the limit of requests for the
+// account, and if not, how long until another request is allowed. Requests
+// are counted whether or not the account exists, so the limit doesn't reveal
+// which addresses have accounts.
+func resetRequestAllowed(email string) (bool, time.Duration) {
+	limit := loginLimit("password_reset_max_requests", defaultResetRequests)
+	now := time.Now()
+
+	resetRequests.Lock()
+	defer resetRequests.Unlock()
+
+	// forget requests which have left the window
+	for k, times := range resetRequests.times {
+		var recent []time.Time
+		for _, t := range times {
+			if now.Sub(t) < resetRequestWindow {
+				recent = append(recent, t)
+			}
+		}
+
+		if len(recent) == 0 {
+			delete(resetRequests.times, k)
+			continue
+		}
+
+		resetRequests.times[k] = recent
+	}
+
+	times := resetRequests.times[email]
+	if len(times) >= limit {
+		return false, resetRequestWindow - now.Sub(times[0])
+	}
+
+	resetRequests.times[email] = append(times, now)
+	return true, 0
+}
+
+// passwordStamp returns a fingerprint of the password usr has now, which is
+// signed into reset tokens so that they stop working once it is changed
+func passwordStamp(usr *user.User) string {
+	sum := sha256.Sum256([]byte(usr.Hash + usr.Salt))
+	return hex.EncodeToString(sum[:8])
+}
+
+// sendPasswordReset emails usr a link to set a new password, which is signed,
+// expires, and can only be used once. Sending a link makes any sent to usr
+// before it stop working.
+func sendPasswordReset(usr *user.User) error {
+	key, err := db.SetRecoveryKey(usr.Email)
+	if err != nil {
+		return err
+	}
+
+	exp := time.Now().Add(configDuration("password_reset_minutes", defaultResetMinutes, time.Minute))
+	token, err := jwt.New(map[string]interface{}{
+		"exp":     exp.Unix(),
+		"recover": usr.Email,
+		"nonce":   key,
+		"stamp":   passwordStamp(usr),
+	})
+	if err != nil {
+		return err
+	}
+
+	domain, _ := db.ConfigCache("domain").(string)
+
+	body := fmt.Sprintf(`
+There has been an account recovery request made for the user with email:
+%s
+
+To choose a new password, please go to:
+http://%s/admin/recover/key?token=%s
+
+The link can be used once, and expires on %s.
+
+If you did not make the request, ignore this message and your password
+will remain as-is.
+
+
+Thank you,
+Ponzu CMS at %s
+
+`, usr.Email, domain, url.QueryEscape(token), exp.Format("Jan 2, 2006 at 3:04 PM MST"), domain)
+
+	return emailer.Send(usr.Email, fmt.Sprintf("Account Recovery [%s]", domain), body)
+}
+
+// resetUserFromToken returns the user a password reset token was sent to, if
+// the token is signed, has not expired, has not been used or replaced by a
+// newer one, and the user's password has not changed since it was sent
+func resetUserFromToken(token string) (*user.User, error) {
+	if !jwt.Passes(token) {
+		return nil, errResetInvalid
+	}
+
+	claims := jwt.GetClaims(token)
+	email, _ := claims["recover"].(string)
+	nonce, _ := claims["nonce"].(string)
+	stamp, _ := claims["stamp"].(string)
+	exp, _ := claims["exp"].(float64)
+	if email == "" || nonce == "" || time.Now().Unix() > int64(exp) {
+		return nil, errResetInvalid
+	}
+
+	key, err := db.RecoveryKey(email)
+	if err != nil || key == "" {
+		return nil, errResetInvalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key), []byte(nonce)) != 1 {
+		return nil, errResetInvalid
+	}
+
+	j, err := db.User(email)
+	if err == db.ErrNoUserExists || j == nil {
+		return nil, errResetInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usr := &user.User{}
+	err = json.Unmarshal(j, usr)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(passwordStamp(usr)), []byte(stamp)) != 1 {
+		return nil, errResetInvalid
+	}
+
+	return usr, nil
+}
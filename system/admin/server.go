@@ -1,48 +1,105 @@
 package admin
 
 import (
-	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 
 	"github.com/ponzu-cms/ponzu/system"
+	"github.com/ponzu-cms/ponzu/system/admin/upload"
 	"github.com/ponzu-cms/ponzu/system/admin/user"
 	"github.com/ponzu-cms/ponzu/system/api"
 	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
 )
 
 // Run adds Handlers to default http listener for Admin
 func Run() {
-	http.HandleFunc("/admin", user.Auth(adminHandler))
+	http.HandleFunc("/admin", auth(adminHandler))
 
 	http.HandleFunc("/admin/init", initHandler)
 
 	http.HandleFunc("/admin/login", loginHandler)
+	http.HandleFunc("/admin/login/2fa", loginTwoFactorHandler)
 	http.HandleFunc("/admin/logout", logoutHandler)
 
 	http.HandleFunc("/admin/recover", forgotPasswordHandler)
 	http.HandleFunc("/admin/recover/key", recoveryKeyHandler)
+	http.HandleFunc("/admin/invite", inviteHandler)
 
-	http.HandleFunc("/admin/addons", user.Auth(addonsHandler))
-	http.HandleFunc("/admin/addon", user.Auth(addonHandler))
+	http.HandleFunc("/admin/addons", auth(permitSection(user.SectionAddons, addonsHandler)))
+	http.HandleFunc("/admin/addon", auth(permitSection(user.SectionAddons, addonHandler)))
 
-	http.HandleFunc("/admin/configure", user.Auth(configHandler))
-	http.HandleFunc("/admin/configure/users", user.Auth(configUsersHandler))
-	http.HandleFunc("/admin/configure/users/edit", user.Auth(configUsersEditHandler))
-	http.HandleFunc("/admin/configure/users/delete", user.Auth(configUsersDeleteHandler))
+	http.HandleFunc("/admin/analytics/export", auth(permitSection(user.SectionAnalytics, analyticsExportHandler)))
+	http.HandleFunc("/admin/analytics/live", auth(permitSection(user.SectionAnalytics, liveAnalyticsHandler)))
 
-	http.HandleFunc("/admin/contents", user.Auth(contentsHandler))
-	http.HandleFunc("/admin/contents/search", user.Auth(searchHandler))
+	http.HandleFunc("/admin/configure", auth(permitSection(user.SectionConfig, configHandler)))
+	http.HandleFunc("/admin/configure/email/test", auth(permitSection(user.SectionConfig, configEmailTestHandler)))
+	http.HandleFunc("/admin/configure/users", auth(permit(configUsersHandler, func(role user.Role, req *http.Request) bool {
+		// anyone can see their own account, but only managers can add users
+		return req.Method != http.MethodPost || role.Manages(user.SectionUsers)
+	})))
+	http.HandleFunc("/admin/configure/users/edit", auth(configUsersEditHandler))
+	http.HandleFunc("/admin/configure/2fa", user.Auth(requireSession(configTwoFactorHandler)))
+	http.HandleFunc("/admin/configure/users/delete", auth(permitSection(user.SectionUsers, configUsersDeleteHandler)))
+	http.HandleFunc("/admin/configure/users/sessions", auth(configUsersSessionsHandler))
+	http.HandleFunc("/admin/configure/users/invite", auth(permitSection(user.SectionUsers, configUsersInviteHandler)))
+	http.HandleFunc("/admin/configure/users/invite/revoke", auth(permitSection(user.SectionUsers, configUsersInviteRevokeHandler)))
+	http.HandleFunc("/admin/configure/users/role", auth(permitSection(user.SectionUsers, configUsersRoleHandler)))
+	http.HandleFunc("/admin/configure/roles", auth(permit(configRolesHandler, func(role user.Role, req *http.Request) bool {
+		// user managers can see the roles, but only super admins can change them
+		if req.Method == http.MethodPost {
+			return role.IsSuper()
+		}
 
-	http.HandleFunc("/admin/edit", user.Auth(editHandler))
-	http.HandleFunc("/admin/edit/delete", user.Auth(deleteHandler))
-	http.HandleFunc("/admin/edit/approve", user.Auth(approveContentHandler))
-	http.HandleFunc("/admin/edit/upload", user.Auth(editUploadHandler))
+		return role.Manages(user.SectionUsers)
+	})))
+	http.HandleFunc("/admin/configure/roles/delete", auth(permit(configRolesDeleteHandler, func(role user.Role, req *http.Request) bool {
+		return role.IsSuper()
+	})))
+
+	http.HandleFunc("/admin/contents", auth(permitContent(contentsHandler, user.ActionRead)))
+	http.HandleFunc("/admin/contents/search", auth(permitContent(searchHandler, user.ActionRead)))
+	http.HandleFunc("/admin/contents/import", auth(permitContent(importHandler, user.ActionCreate)))
+	http.HandleFunc("/admin/contents/import/json", auth(permitContent(importJSONHandler, user.ActionCreate, user.ActionUpdate)))
+	http.HandleFunc("/admin/contents/export", auth(permitContent(exportHandler, user.ActionRead)))
+	http.HandleFunc("/admin/contents/order", auth(permitContent(orderHandler, user.ActionUpdate)))
+	http.HandleFunc("/admin/contents/batch", auth(permitContent(batchHandler, user.ActionRead)))
+	http.HandleFunc("/admin/references", auth(permitContent(referencesHandler, user.ActionRead)))
+
+	http.HandleFunc("/admin/edit", auth(permitEdit(editHandler)))
+	http.HandleFunc("/admin/edit/delete", auth(permitContent(deleteHandler, user.ActionDelete)))
+	http.HandleFunc("/admin/edit/restore", auth(permitContent(restoreHandler, user.ActionUpdate)))
+	http.HandleFunc("/admin/edit/approve", auth(permitContent(approveContentHandler, user.ActionCreate)))
+	http.HandleFunc("/admin/edit/upload", auth(permitContent(editUploadHandler, user.ActionCreate, user.ActionUpdate)))
+	http.HandleFunc("/admin/edit/preview", auth(permitContent(previewHandler, user.ActionRead)))
+
+	http.HandleFunc("/admin/apikeys", auth(permitSection(user.SectionAPIKeys, apiKeysHandler)))
+	http.HandleFunc("/admin/apikeys/revoke", auth(permitSection(user.SectionAPIKeys, apiKeysRevokeHandler)))
+
+	http.HandleFunc("/admin/graphql", auth(permitSection(user.SectionGraphQL, graphiqlHandler)))
+
+	http.HandleFunc("/admin/webhooks", auth(permitSection(user.SectionWebhooks, webhooksHandler)))
+	http.HandleFunc("/admin/webhooks/delete", auth(permitSection(user.SectionWebhooks, webhooksDeleteHandler)))
+
+	http.HandleFunc("/admin/redirects", auth(permitSection(user.SectionRedirects, redirectsHandler)))
+	http.HandleFunc("/admin/redirects/delete", auth(permitSection(user.SectionRedirects, redirectsDeleteHandler)))
+	http.HandleFunc("/admin/redirects/export", auth(permitSection(user.SectionRedirects, redirectsExportHandler)))
+	http.HandleFunc("/admin/redirects/import", auth(permitSection(user.SectionRedirects, redirectsImportHandler)))
+
+	http.HandleFunc("/admin/tags", auth(permitSection(user.SectionTags, tagsHandler)))
+	http.HandleFunc("/admin/tags/suggest", auth(permitContent(tagSuggestHandler, user.ActionRead)))
+
+	http.HandleFunc("/admin/trash", auth(permitSection(user.SectionTrash, trashHandler)))
+	http.HandleFunc("/admin/trash/restore", auth(permitSection(user.SectionTrash, trashRestoreHandler)))
+	http.HandleFunc("/admin/trash/delete", auth(permitSection(user.SectionTrash, trashDeleteHandler)))
+
+	http.HandleFunc("/admin/audit", auth(permitSection(user.SectionAudit, auditHandler)))
+	http.HandleFunc("/admin/review", auth(reviewHandler))
 
 	pwd, err := os.Getwd()
 	if err != nil {
-		log.Fatalln("Couldn't find current directory for file server.")
+		logger.Fatal("Couldn't find current directory for file server.")
 	}
 
 	staticDir := filepath.Join(pwd, "cmd", "ponzu", "vendor", "github.com", "ponzu-cms", "ponzu", "system")
@@ -51,9 +108,16 @@ func Run() {
 	// API path needs to be registered within server package so that it is handled
 	// even if the API server is not running. Otherwise, images/files uploaded
 	// through the editor will not load within the admin system.
-	uploadsDir := filepath.Join(pwd, "uploads")
-	http.Handle("/api/uploads/", api.Record(api.CORS(db.CacheControl(http.StripPrefix("/api/uploads/", http.FileServer(restrict(http.Dir(uploadsDir))))))))
+	http.Handle("/api/uploads/", api.Record(api.CORS(db.CacheControl(http.StripPrefix("/api/uploads/", http.HandlerFunc(upload.Serve))))))
 
 	// Database & uploads backup via HTTP route registered with Basic Auth middleware.
 	http.HandleFunc("/admin/backup", system.BasicAuth(backupHandler))
+
+	// Search index rebuild, using the same Basic Auth credentials
+	http.HandleFunc("/admin/reindex", system.BasicAuth(reindexHandler))
+	http.HandleFunc("/admin/migrate", system.BasicAuth(migrateHandler))
+
+	// API request and scheduled job metrics for Prometheus, using the same
+	// Basic Auth credentials
+	http.HandleFunc("/admin/metrics", system.BasicAuth(metricsHandler))
 }
@@ -0,0 +1,133 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/api/analytics"
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/nilslice/jwt"
+)
+
+const (
+	// defaultSessionHours is the longest a session can last, if
+	// session_max_hours is not configured
+	defaultSessionHours = 24
+	// defaultRememberDays is the longest a session can last when the user
+	// asks to be remembered, if session_remember_days is not configured
+	defaultRememberDays = 30
+	// sessionTouchInterval is how often the time a session was last used is
+	// saved, so that every request doesn't write to the db
+	sessionTouchInterval = time.Minute
+)
+
+// sessionLimit returns the config value of name as a number of unit if it is
+// set, otherwise def of unit
+func configDuration(name string, def int, unit time.Duration) time.Duration {
+	n, ok := db.ConfigCache(name).(float64)
+	if !ok || n < 1 {
+		n = float64(def)
+	}
+
+	return time.Duration(n) * unit
+}
+
+// sessionLifetime returns the longest a session can last before the user must
+// log in again
+func sessionLifetime(remember bool) time.Duration {
+	if remember {
+		return configDuration("session_remember_days", defaultRememberDays, 24*time.Hour)
+	}
+
+	return configDuration("session_max_hours", defaultSessionHours, time.Hour)
+}
+
+// setLoginToken logs in the user with email by starting a session for them and
+// adding a token referring to it to a cookie. Unless remember is true the
+// cookie ends with the browser session.
+func setLoginToken(res http.ResponseWriter, req *http.Request, email string, remember bool) error {
+	now := time.Now()
+	exp := now.Add(sessionLifetime(remember))
+
+	s, err := db.NewSession(db.Session{
+		User:      email,
+		Expires:   exp.UnixNano() / int64(time.Millisecond),
+		Remember:  remember,
+		IP:        analytics.ClientIP(req),
+		UserAgent: req.UserAgent(),
+	})
+	if err != nil {
+		return err
+	}
+
+	token, err := jwt.New(map[string]interface{}{
+		"exp":     exp.Unix(),
+		"user":    email,
+		"session": s.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	cookie := &http.Cookie{
+		Name:     "_token",
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+	}
+	if remember {
+		cookie.Expires = exp
+	}
+	http.SetCookie(res, cookie)
+
+	return nil
+}
+
+// clearLoginToken removes the cookie set by setLoginToken
+func clearLoginToken(res http.ResponseWriter) {
+	http.SetCookie(res, &http.Cookie{
+		Name:    "_token",
+		Expires: time.Unix(0, 0),
+		Value:   "",
+		Path:    "/",
+	})
+}
+
+// loggedIn reports whether the request has the token of an active session
+func loggedIn(req *http.Request) bool {
+	_, err := db.CurrentSession(req)
+	return err == nil
+}
+
+// requireSession wraps handlers behind user.Auth so that requests are only
+// served while their session is active, and records the session being used.
+// Users whose session has ended are sent to log in again.
+func requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		s, err := db.CurrentSession(req)
+		if err == db.ErrSessionExpired {
+			clearLoginToken(res)
+			http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin/login?expired=true", http.StatusFound)
+			return
+		}
+		if err != nil {
+			logger.For(req).Error("Error getting session:", err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now().UnixNano() / int64(time.Millisecond)
+		if time.Duration(now-s.LastSeen)*time.Millisecond >= sessionTouchInterval {
+			err = db.TouchSession(s.ID, now)
+			if err != nil && err != db.ErrNoSession {
+				logger.For(req).Error("Error saving session use:", err)
+				res.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
+		next(res, req)
+	}
+}
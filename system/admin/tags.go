@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// maxTagSuggestions is the number of existing tags offered at once by a Tags
+// editor field
+const maxTagSuggestions = 20
+
+// tagsHandler lists the canonical tags, and renames one, updating the content
+// tagged with it
+func tagsHandler(res http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		view, err := Tags(req)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		res.Header().Set("Content-Type", "text/html")
+		res.Write(view)
+
+	case http.MethodPost:
+		err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		from := req.FormValue("from")
+		to := strings.TrimSpace(req.FormValue("to"))
+
+		updated, err := db.RenameTag(from, to)
+		if err == db.ErrTagNotFound || err == db.ErrTagEmpty {
+			res.WriteHeader(http.StatusBadRequest)
+			errView, err := ErrorMessage(req, "Tag not renamed", html.EscapeString(err.Error()))
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			errView, err := Error500(req)
+			if err != nil {
+				return
+			}
+
+			res.Write(errView)
+			return
+		}
+
+		audit(req, "tag.rename", "tag:"+from, map[string]string{
+			"to":      to,
+			"updated": strconv.Itoa(updated),
+		})
+
+		http.Redirect(res, req, req.URL.String(), http.StatusFound)
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// tagSuggestHandler writes the canonical tags starting with q, or else
+// containing it, regardless of case, for a Tags editor field to suggest
+func tagSuggestHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	tags, err := db.Tags()
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	q := strings.ToLower(strings.TrimSpace(req.URL.Query().Get("q")))
+
+	var prefixed, contained []string
+	for _, tag := range tags {
+		lower := strings.ToLower(tag)
+		switch {
+		case strings.HasPrefix(lower, q):
+			prefixed = append(prefixed, tag)
+		case strings.Contains(lower, q):
+			contained = append(contained, tag)
+		}
+	}
+
+	suggestions := append(append([]string{}, prefixed...), contained...)
+	if len(suggestions) > maxTagSuggestions {
+		suggestions = suggestions[:maxTagSuggestions]
+	}
+
+	j, err := json.Marshal(map[string][]string{"data": suggestions})
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Write(j)
+}
@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/admin/user"
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/nilslice/jwt"
+)
+
+// twoFactorCookie holds the token of a user who has given their password, and
+// is yet to give the code of their second factor
+const twoFactorCookie = "_2fa"
+
+// twoFactorTimeout is how long a user has to give their code after their
+// password
+const twoFactorTimeout = 5 * time.Minute
+
+// setTwoFactorToken adds a cookie identifying the user with email as having
+// given their password, and whether they asked to be remembered
+func setTwoFactorToken(res http.ResponseWriter, email string, remember bool) error {
+	exp := time.Now().Add(twoFactorTimeout)
+	token, err := jwt.New(map[string]interface{}{
+		"exp":      exp.Unix(),
+		"2fa":      email,
+		"remember": remember,
+	})
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(res, &http.Cookie{
+		Name:     twoFactorCookie,
+		Value:    token,
+		Expires:  exp,
+		Path:     "/admin/login",
+		HttpOnly: true,
+	})
+
+	return nil
+}
+
+// clearTwoFactorToken removes the cookie set by setTwoFactorToken
+func clearTwoFactorToken(res http.ResponseWriter) {
+	http.SetCookie(res, &http.Cookie{
+		Name:    twoFactorCookie,
+		Expires: time.Unix(0, 0),
+		Value:   "",
+		Path:    "/admin/login",
+	})
+}
+
+// twoFactorUser returns the user who gave their password before the token set
+// by setTwoFactorToken expired, and whether they asked to be remembered
+func twoFactorUser(req *http.Request) (usr *user.User, remember, ok bool) {
+	cookie, err := req.Cookie(twoFactorCookie)
+	if err != nil || !jwt.Passes(cookie.Value) {
+		return nil, false, false
+	}
+
+	claims := jwt.GetClaims(cookie.Value)
+	exp, _ := claims["exp"].(float64)
+	email, _ := claims["2fa"].(string)
+	remember, _ = claims["remember"].(bool)
+	if email == "" || time.Now().Unix() > int64(exp) {
+		return nil, false, false
+	}
+
+	j, err := db.User(email)
+	if err != nil {
+		return nil, false, false
+	}
+
+	usr = &user.User{}
+	err = json.Unmarshal(j, usr)
+	if err != nil {
+		logger.For(req).Error("Error decoding user for two-factor login:", err)
+		return nil, false, false
+	}
+
+	return usr, remember, true
+}
+
+// checkSecondFactor reports whether code is the current TOTP code of usr, or
+// one of their recovery codes. A recovery code is removed from usr when used,
+// in which case used is true and usr must be saved.
+func checkSecondFactor(usr *user.User, code string) (ok, used bool) {
+	secret, err := user.DecryptSecret(usr.TOTPSecret, secretKey())
+	if err != nil {
+		logger.Error("Error decrypting TOTP secret of", usr.Email+":", err)
+	} else if user.ValidTOTP(secret, code, time.Now()) {
+		return true, false
+	}
+
+	if usr.UseRecoveryCode(code) {
+		return true, true
+	}
+
+	return false, false
+}
+
+// secretKey is the key TOTP secrets are encrypted with in the user store. It
+// is the client secret, so after the client secret is changed enrolled users
+// can only log in with their recovery codes until they enroll again.
+func secretKey() []byte {
+	secret, _ := db.ConfigCache("client_secret").(string)
+	return []byte(secret)
+}
+
+// requireTwoFactor wraps handlers behind user.Auth so that, when two-factor
+// authentication is required by the configuration, users who have not enrolled
+// are sent to enroll before they can use the admin
+func requireTwoFactor(next http.HandlerFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if required, _ := db.ConfigCache("require_2fa").(bool); !required {
+			next(res, req)
+			return
+		}
+
+		j, err := db.CurrentUser(req)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		usr := user.User{}
+		err = json.Unmarshal(j, &usr)
+		if err != nil {
+			logger.For(req).Error(err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if !usr.HasTOTP() {
+			http.Redirect(res, req, req.URL.Scheme+req.URL.Host+"/admin/configure/2fa", http.StatusFound)
+			return
+		}
+
+		next(res, req)
+	}
+}
+
+// auth is user.Auth, also requiring an active session, and two-factor
+// authentication when configured
+func auth(next http.HandlerFunc) http.HandlerFunc {
+	return user.Auth(requireSession(requireTwoFactor(next)))
+}
@@ -8,11 +8,13 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
 // Backup creates an archive of a project's uploads and writes it
-// to the response as a download
+// to the response as a download. Only uploads stored on disk are included,
+// object storage should be backed up by its own means.
 func Backup(res http.ResponseWriter) error {
 	ts := time.Now().Unix()
 	filename := fmt.Sprintf("uploads-%d.bak.tar.gz", ts)
@@ -24,14 +26,54 @@ func Backup(res http.ResponseWriter) error {
 	if err != nil {
 		return err
 	}
+	defer os.Remove(backup)
+
+	err = Archive(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	// write data to response
+	data, err := os.Open(backup)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
 
-	// loop through directory and gzip files
-	// add all to uploads.bak.tar.gz tarball
-	gz := gzip.NewWriter(f)
+	disposition := `attachment; filename=%s`
+	info, err := data.Stat()
+	if err != nil {
+		return err
+	}
+
+	res.Header().Set("Content-Type", "application/octet-stream")
+	res.Header().Set("Content-Disposition", fmt.Sprintf(disposition, filename))
+	res.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
+
+	_, err = io.Copy(res, data)
+
+	return err
+}
+
+// Archive writes a gzipped tarball of the uploads directory to w. A project
+// without an uploads directory is archived as an empty tarball.
+func Archive(w io.Writer) error {
+	gz := gzip.NewWriter(w)
 	tarball := tar.NewWriter(gz)
 
-	err = filepath.Walk("uploads", func(path string, info os.FileInfo, err error) error {
+	// loop through directory and add each file to the tarball
+	err := filepath.Walk("uploads", func(path string, info os.FileInfo, err error) error {
 		if err != nil {
+			if os.IsNotExist(err) && path == "uploads" {
+				return nil
+			}
+
 			return err
 		}
 
@@ -40,7 +82,7 @@ func Backup(res http.ResponseWriter) error {
 			return err
 		}
 
-		hdr.Name = path
+		hdr.Name = filepath.ToSlash(path)
 
 		err = tarball.WriteHeader(hdr)
 		if err != nil {
@@ -53,17 +95,8 @@ func Backup(res http.ResponseWriter) error {
 				return err
 			}
 			defer src.Close()
-			_, err = io.Copy(tarball, src)
-			if err != nil {
-				return err
-			}
-
-			err = tarball.Flush()
-			if err != nil {
-				return err
-			}
 
-			err = gz.Flush()
+			_, err = io.Copy(tarball, src)
 			if err != nil {
 				return err
 			}
@@ -72,42 +105,65 @@ func Backup(res http.ResponseWriter) error {
 		return nil
 	})
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
 
-	err = gz.Close()
-	if err != nil {
-		return err
-	}
 	err = tarball.Close()
 	if err != nil {
 		return err
 	}
-	err = f.Close()
-	if err != nil {
-		return err
-	}
 
-	// write data to response
-	data, err := os.Open(backup)
-	if err != nil {
-		return err
-	}
-	defer data.Close()
-	defer os.Remove(backup)
+	return gz.Close()
+}
 
-	disposition := `attachment; filename=%s`
-	info, err := data.Stat()
+// Restore extracts a tarball written by Archive from r into the uploads
+// directory, replacing files with the same names. Entries outside of the
+// uploads directory are refused.
+func Restore(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
 	if err != nil {
 		return err
 	}
+	defer gz.Close()
 
-	res.Header().Set("Content-Type", "application/octet-stream")
-	res.Header().Set("Content-Disposition", fmt.Sprintf(disposition, ts))
-	res.Header().Set("Content-Length", fmt.Sprintf("%d", info.Size()))
+	tarball := tar.NewReader(gz)
+	for {
+		hdr, err := tarball.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 
-	_, err = io.Copy(res, data)
+		path := filepath.Clean(filepath.FromSlash(hdr.Name))
+		if path != "uploads" && !strings.HasPrefix(path, "uploads"+string(filepath.Separator)) {
+			return fmt.Errorf("Refusing to restore %s outside of the uploads directory", hdr.Name)
+		}
 
-	return err
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err := os.MkdirAll(path, os.ModeDir|os.ModePerm)
+			if err != nil {
+				return err
+			}
+
+		case tar.TypeReg:
+			err := os.MkdirAll(filepath.Dir(path), os.ModeDir|os.ModePerm)
+			if err != nil {
+				return err
+			}
+
+			dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(dst, tarball)
+			dst.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
 }
@@ -0,0 +1,284 @@
+package upload
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 is the Storage of uploads in a bucket of an S3-compatible object store.
+// Requests are signed with AWS Signature Version 4. If Endpoint is empty the
+// bucket is addressed at Amazon S3 in Region, otherwise it is addressed by
+// path at Endpoint, e.g. http://localhost:9000 for MinIO.
+type S3 struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+
+	// Client is used to make requests, or http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Save implements Storage
+func (s *S3) Save(name string, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, s.objectURL(name), strings.NewReader(string(data)))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(data))
+
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+
+	res, err := s.do(req, sha256Hex(data))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("S3 PUT %s: %s %s", name, res.Status, msg)
+	}
+
+	return nil
+}
+
+// Exists implements Storage
+func (s *S3) Exists(name string) (bool, error) {
+	req, err := http.NewRequest(http.MethodHead, s.objectURL(name), nil)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.do(req, sha256Hex(nil))
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound, http.StatusForbidden:
+		return false, nil
+	}
+
+	return false, fmt.Errorf("S3 HEAD %s: %s", name, res.Status)
+}
+
+// ServeHTTP implements Storage, proxying the object from the bucket so that
+// the bucket does not need to be public
+func (s *S3) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+req.URL.Path), "/")
+	if name == "" {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	objReq, err := http.NewRequest(req.Method, s.objectURL(name), nil)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	for _, h := range []string{"Range", "If-None-Match", "If-Modified-Since"} {
+		if v := req.Header.Get(h); v != "" {
+			objReq.Header.Set(h, v)
+		}
+	}
+
+	objRes, err := s.do(objReq, sha256Hex(nil))
+	if err != nil {
+		res.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer objRes.Body.Close()
+
+	switch objRes.StatusCode {
+	case http.StatusOK, http.StatusPartialContent, http.StatusNotModified:
+	case http.StatusNotFound, http.StatusForbidden:
+		res.WriteHeader(http.StatusNotFound)
+		return
+	default:
+		res.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	for _, h := range []string{"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges", "ETag", "Last-Modified"} {
+		if v := objRes.Header.Get(h); v != "" {
+			res.Header().Set(h, v)
+		}
+	}
+
+	res.WriteHeader(objRes.StatusCode)
+	io.Copy(res, objRes.Body)
+}
+
+// objectURL returns the URL of the object name in the bucket
+func (s *S3) objectURL(name string) string {
+	key := escapePath(strings.TrimPrefix(name, "/"))
+
+	if s.Endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.Bucket, s.region(), key)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.Endpoint, "/"), s.Bucket, key)
+}
+
+func (s *S3) region() string {
+	if s.Region == "" {
+		return "us-east-1"
+	}
+
+	return s.Region
+}
+
+// do signs req with payloadHash, the hex encoded SHA-256 of its body, and
+// sends it
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, time.Now().UTC())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return client.Do(req)
+}
+
+// sign adds the AWS Signature Version 4 Authorization header to req
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	scope := date + "/" + s.region() + "/s3/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// sign the host and all headers set so far
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	toSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonical)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.region())
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalQuery returns the query sorted by key, with keys and values encoded
+// as S3 expects
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var params []string
+	for _, k := range keys {
+		vals := append([]string{}, q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			params = append(params, escape(k)+"="+escape(v))
+		}
+	}
+
+	return strings.Join(params, "&")
+}
+
+// escapePath URI encodes each segment of an object key
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = escape(s)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// escape URI encodes s, leaving only unreserved characters as-is
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
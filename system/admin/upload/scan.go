@@ -0,0 +1,74 @@
+package upload
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"sync"
+)
+
+// Scanner checks uploaded files before they are stored, i.e. for viruses or
+// malware using ClamAV. Scan reads the file named filename from r, and returns
+// an error to reject it, in which case none of the files uploaded with it are
+// stored.
+type Scanner interface {
+	Scan(filename string, r io.Reader) error
+}
+
+// ScannerFunc is an adapter to use a function as a Scanner
+type ScannerFunc func(filename string, r io.Reader) error
+
+// Scan implements Scanner
+func (f ScannerFunc) Scan(filename string, r io.Reader) error {
+	return f(filename, r)
+}
+
+// RejectedError is returned by StoreFiles when a Scanner rejects an upload
+type RejectedError struct {
+	Filename string
+	Err      error
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("Upload %s was rejected: %v", e.Filename, e.Err)
+}
+
+var (
+	scannerMu sync.RWMutex
+
+	// scanner accepts all files by default
+	scanner Scanner = ScannerFunc(func(filename string, r io.Reader) error {
+		return nil
+	})
+)
+
+// SetScanner sets the Scanner which uploads are checked by before they are
+// stored. It should be called before the server is started.
+func SetScanner(s Scanner) {
+	scannerMu.Lock()
+	scanner = s
+	scannerMu.Unlock()
+}
+
+// scan checks each of the files in form, returning a *RejectedError for the
+// first one rejected by the Scanner
+func scan(form *multipart.Form) error {
+	scannerMu.RLock()
+	s := scanner
+	scannerMu.RUnlock()
+
+	for _, fds := range form.File {
+		src, err := fds[0].Open()
+		if err != nil {
+			return fmt.Errorf("Couldn't open uploaded file: %s", err)
+		}
+
+		err = s.Scan(fds[0].Filename, src)
+		src.Close()
+		if err != nil {
+			return &RejectedError{Filename: fds[0].Filename, Err: err}
+		}
+	}
+
+	return nil
+}
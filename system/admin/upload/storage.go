@@ -0,0 +1,145 @@
+package upload
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// Storage saves uploaded files, and serves them from /api/uploads/. Names are
+// paths relative to the root of the uploads, like 2017/01/photo.jpg. The
+// ServeHTTP method serves the upload named by the request's URL path.
+type Storage interface {
+	Save(name string, r io.Reader) error
+	Exists(name string) (bool, error)
+	http.Handler
+}
+
+var (
+	storageMu sync.RWMutex
+	storage   Storage
+)
+
+// SetStorage replaces the Storage chosen by the configuration with s, i.e. to
+// use a backend other than the disk or S3. It should be called before the
+// server is started.
+func SetStorage(s Storage) {
+	storageMu.Lock()
+	storage = s
+	storageMu.Unlock()
+}
+
+// Store returns the Storage in use for uploads, which is the uploads directory
+// on disk unless S3-compatible object storage is set in the configuration
+func Store() Storage {
+	storageMu.RLock()
+	s := storage
+	storageMu.RUnlock()
+	if s != nil {
+		return s
+	}
+
+	if backend, _ := db.ConfigCache("upload_storage").(string); backend == "s3" {
+		endpoint, _ := db.ConfigCache("s3_endpoint").(string)
+		region, _ := db.ConfigCache("s3_region").(string)
+		bucket, _ := db.ConfigCache("s3_bucket").(string)
+		key, _ := db.ConfigCache("s3_access_key_id").(string)
+		secret, _ := db.ConfigCache("s3_secret_access_key").(string)
+
+		return &S3{
+			Endpoint:        endpoint,
+			Region:          region,
+			Bucket:          bucket,
+			AccessKeyID:     key,
+			SecretAccessKey: secret,
+		}
+	}
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		logger.Error("Couldn't find current directory for uploads:", err)
+	}
+
+	return Disk(filepath.Join(pwd, "uploads"))
+}
+
+// Serve serves an upload from the Storage in use, or the variant of it named
+// by the variant query parameter, e.g. /2017/01/photo.jpg?variant=thumb. The
+// original is served if there is no such variant. It expects the /api/uploads/
+// prefix to be stripped from the request's URL path.
+func Serve(res http.ResponseWriter, req *http.Request) {
+	st := Store()
+
+	variant := req.URL.Query().Get("variant")
+	if variant != "" && !strings.ContainsAny(variant, `/\.`) {
+		p := VariantPath(req.URL.Path, variant)
+
+		ok, err := st.Exists(p)
+		if err != nil {
+			logger.For(req).Error("Failed to find upload variant:", p, err)
+		}
+		if ok {
+			req.URL.Path = p
+		}
+	}
+
+	st.ServeHTTP(res, req)
+}
+
+// Disk is the Storage of uploads in a directory on disk, which is the default
+type Disk string
+
+// Save implements Storage
+func (d Disk) Save(name string, r io.Reader) error {
+	p := d.path(name)
+
+	err := os.MkdirAll(filepath.Dir(p), os.ModeDir|os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// Exists implements Storage
+func (d Disk) Exists(name string) (bool, error) {
+	_, err := os.Stat(d.path(name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// ServeHTTP implements Storage, serving files but not directory listings
+func (d Disk) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	info, err := os.Stat(d.path(req.URL.Path))
+	if err != nil || info.IsDir() {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	http.ServeFile(res, req, d.path(req.URL.Path))
+}
+
+// path returns the path on disk of the upload name, which is kept within d
+func (d Disk) path(name string) string {
+	return filepath.Join(string(d), filepath.FromSlash(path.Clean("/"+name)))
+}
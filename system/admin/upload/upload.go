@@ -2,15 +2,17 @@ package upload
 
 import (
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strconv"
 	"time"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
 )
 
-// StoreFiles stores file uploads at paths like /YYYY/MM/filename.ext
+// StoreFiles stores file uploads at paths like /YYYY/MM/filename.ext in the
+// Storage in use, once they have been checked by the Scanner. A *RejectedError
+// is returned if any are rejected.
 func StoreFiles(req *http.Request) (map[string]string, error) {
 	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 	if err != nil {
@@ -28,13 +30,6 @@ func StoreFiles(req *http.Request) (map[string]string, error) {
 	// To use for FormValue name:urlPath
 	urlPaths := make(map[string]string)
 
-	// get or create upload directory to save files from request
-	pwd, err := os.Getwd()
-	if err != nil {
-		err := fmt.Errorf("Failed to locate current directory: %s", err)
-		return nil, err
-	}
-
 	i, err := strconv.ParseInt(ts, 10, 64)
 	if err != nil {
 		return nil, err
@@ -44,11 +39,18 @@ func StoreFiles(req *http.Request) (map[string]string, error) {
 
 	urlPathPrefix := "api"
 	uploadDirName := "uploads"
+	uploadDir := fmt.Sprintf("%d/%02d", tm.Year(), tm.Month())
 
-	uploadDir := filepath.Join(pwd, uploadDirName, fmt.Sprintf("%d", tm.Year()), fmt.Sprintf("%02d", tm.Month()))
-	err = os.MkdirAll(uploadDir, os.ModeDir|os.ModePerm)
+	st := Store()
+	variants := Variants()
 
-	// loop over all files and save them to disk
+	// check all files before any are stored, so none are if one is rejected
+	err = scan(req.MultipartForm)
+	if err != nil {
+		return nil, err
+	}
+
+	// loop over all files and save them to the upload storage
 	for name, fds := range req.MultipartForm.File {
 		filename := fds[0].Filename
 		src, err := fds[0].Open()
@@ -60,28 +62,35 @@ func StoreFiles(req *http.Request) (map[string]string, error) {
 		defer src.Close()
 
 		// check if file at path exists, if so, add timestamp to file
-		absPath := filepath.Join(uploadDir, filename)
-
-		if _, err := os.Stat(absPath); !os.IsNotExist(err) {
+		exists, err := st.Exists(path.Join(uploadDir, filename))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to check for existing upload: %s", err)
+		}
+		if exists {
 			filename = fmt.Sprintf("%d-%s", time.Now().Unix(), filename)
-			absPath = filepath.Join(uploadDir, filename)
 		}
+		uploadPath := path.Join(uploadDir, filename)
 
-		// save to disk (TODO: or check if S3 credentials exist, & save to cloud)
-		dst, err := os.Create(absPath)
+		err = st.Save(uploadPath, src)
 		if err != nil {
-			err := fmt.Errorf("Failed to create destination file for upload: %s", err)
+			err := fmt.Errorf("Failed to save uploaded file: %s", err)
 			return nil, err
 		}
 
-		// copy file from src to dst on disk
-		if _, err = io.Copy(dst, src); err != nil {
-			err := fmt.Errorf("Failed to copy uploaded file to destination: %s", err)
-			return nil, err
+		// save resized copies of images alongside the original
+		if len(variants) > 0 {
+			img, err := fds[0].Open()
+			if err == nil {
+				err = storeVariants(st, uploadPath, img, variants)
+				img.Close()
+			}
+			if err != nil {
+				logger.For(req).Error("Failed to store image variants for upload:", filename, err)
+			}
 		}
 
 		// add name:urlPath to req.PostForm to be inserted into db
-		urlPath := fmt.Sprintf("/%s/%s/%d/%02d/%s", urlPathPrefix, uploadDirName, tm.Year(), tm.Month(), filename)
+		urlPath := fmt.Sprintf("/%s/%s/%s", urlPathPrefix, uploadDirName, uploadPath)
 
 		urlPaths[name] = urlPath
 	}
@@ -0,0 +1,186 @@
+package upload
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+// Variant is a resized copy of an uploaded image, made to fit within Width and
+// Height. A Width or Height of 0 leaves that side unconstrained.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Variants returns the image variants set in the configuration, as a list of
+// name=WIDTHxHEIGHT, e.g. "thumb=150x150, medium=800x0"
+func Variants() []Variant {
+	conf, _ := db.ConfigCache("image_variants").(string)
+
+	return ParseVariants(conf)
+}
+
+// ParseVariants parses a list of variants in the form of name=WIDTHxHEIGHT,
+// separated by commas. Invalid entries are skipped.
+func ParseVariants(s string) []Variant {
+	var variants []Variant
+	for _, entry := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			continue
+		}
+
+		size := strings.SplitN(strings.ToLower(kv[1]), "x", 2)
+		if len(size) != 2 {
+			continue
+		}
+
+		w, err := strconv.Atoi(strings.TrimSpace(size[0]))
+		if err != nil || w < 0 {
+			continue
+		}
+
+		h, err := strconv.Atoi(strings.TrimSpace(size[1]))
+		if err != nil || h < 0 || (w == 0 && h == 0) {
+			continue
+		}
+
+		variants = append(variants, Variant{Name: strings.TrimSpace(kv[0]), Width: w, Height: h})
+	}
+
+	return variants
+}
+
+// VariantPath returns the path of a variant of the upload at p, which is
+// stored alongside it, e.g. /api/uploads/2017/01/photo.jpg with the variant
+// thumb is /api/uploads/2017/01/photo.thumb.jpg
+func VariantPath(p, variant string) string {
+	ext := path.Ext(p)
+
+	return strings.TrimSuffix(p, ext) + "." + variant + ext
+}
+
+// storeVariants saves each of the variants of the image read from src next to
+// the upload name in st. Files which are not decodable images are skipped.
+func storeVariants(st Storage, name string, src io.Reader, variants []Variant) error {
+	img, format, err := image.Decode(src)
+	if err != nil {
+		// not an image, or one of a format we can't decode
+		return nil
+	}
+
+	for _, v := range variants {
+		w, h := fit(img.Bounds().Dx(), img.Bounds().Dy(), v.Width, v.Height)
+
+		buf := &bytes.Buffer{}
+		err = encode(buf, resize(img, w, h), format)
+		if err != nil {
+			return fmt.Errorf("Failed to encode %s variant of %s: %s", v.Name, name, err)
+		}
+
+		err = st.Save(VariantPath(name, v.Name), buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fit returns the size of an image w by h scaled down to fit within maxW by
+// maxH, keeping its aspect ratio. Images are never scaled up.
+func fit(w, h, maxW, maxH int) (int, int) {
+	scale := 1.0
+	if maxW > 0 && w > maxW {
+		scale = float64(maxW) / float64(w)
+	}
+	if maxH > 0 && float64(h)*scale > float64(maxH) {
+		scale = float64(maxH) / float64(h)
+	}
+
+	fw, fh := int(float64(w)*scale+0.5), int(float64(h)*scale+0.5)
+	if fw < 1 {
+		fw = 1
+	}
+	if fh < 1 {
+		fh = 1
+	}
+
+	return fw, fh
+}
+
+// resize scales src to w by h, averaging the source pixels covered by each
+// pixel of the result
+func resize(src image.Image, w, h int) *image.NRGBA {
+	b := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	sx := float64(b.Dx()) / float64(w)
+	sy := float64(b.Dy()) / float64(h)
+
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + int(float64(y)*sy)
+		y1 := b.Min.Y + int(float64(y+1)*sy)
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + int(float64(x)*sx)
+			x1 := b.Min.X + int(float64(x+1)*sx)
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a, n uint64
+			for py := y0; py < y1 && py < b.Max.Y; py++ {
+				for px := x0; px < x1 && px < b.Max.X; px++ {
+					pr, pg, pb, pa := src.At(px, py).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+
+			// the sums are of premultiplied colors, so un-premultiply them
+			c := color.NRGBA64{}
+			if a > 0 {
+				c = color.NRGBA64{
+					R: uint16(r * 0xffff / a),
+					G: uint16(g * 0xffff / a),
+					B: uint16(bl * 0xffff / a),
+					A: uint16(a / n),
+				}
+			}
+			dst.Set(x, y, c)
+		}
+	}
+
+	return dst
+}
+
+// encode writes img in the format the original was decoded from
+func encode(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case "jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "gif":
+		return gif.Encode(w, img, nil)
+	}
+
+	return png.Encode(w, img)
+}
@@ -0,0 +1,53 @@
+package upload
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestParseVariants(t *testing.T) {
+	got := ParseVariants("thumb=150x150, medium=800x0,bad=axb, =10x10, none=0x0")
+	want := []Variant{{"thumb", 150, 150}, {"medium", 800, 0}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFit(t *testing.T) {
+	cases := []struct{ w, h, maxW, maxH, wantW, wantH int }{
+		{1600, 800, 150, 150, 150, 75},
+		{800, 1600, 150, 150, 75, 150},
+		{1600, 800, 800, 0, 800, 400},
+		{100, 50, 800, 800, 100, 50},
+	}
+
+	for _, c := range cases {
+		w, h := fit(c.w, c.h, c.maxW, c.maxH)
+		if w != c.wantW || h != c.wantH {
+			t.Errorf("fit(%d, %d, %d, %d) = %d, %d, expected %d, %d", c.w, c.h, c.maxW, c.maxH, w, h, c.wantW, c.wantH)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 2; y++ {
+			c := color.NRGBA{255, 0, 0, 255}
+			if x >= 2 {
+				c = color.NRGBA{0, 0, 255, 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	dst := resize(src, 2, 1)
+	if got := dst.NRGBAAt(0, 0); got != (color.NRGBA{255, 0, 0, 255}) {
+		t.Errorf("expected red left pixel, got %v", got)
+	}
+	if got := dst.NRGBAAt(1, 0); got != (color.NRGBA{0, 0, 255, 255}) {
+		t.Errorf("expected blue right pixel, got %v", got)
+	}
+}
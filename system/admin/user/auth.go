@@ -4,11 +4,12 @@ import (
 	"bytes"
 	crand "crypto/rand"
 	"encoding/base64"
-	"log"
 	mrand "math/rand"
 	"net/http"
 	"time"
 
+	"github.com/ponzu-cms/ponzu/system/logger"
+
 	"github.com/nilslice/jwt"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -19,31 +20,26 @@ type User struct {
 	Email string `json:"email"`
 	Hash  string `json:"hash"`
 	Salt  string `json:"salt"`
+
+	// Role is the name of the user's role, with no role being a super admin
+	Role string `json:"role,omitempty"`
+
+	// TOTPSecret is the encrypted secret of the user's authenticator app, set
+	// once they enroll in two-factor authentication
+	TOTPSecret string `json:"totp_secret,omitempty"`
+
+	// RecoveryCodes are the hashes of the unused codes which can be entered
+	// in place of a TOTP code
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
 }
 
 var (
 	r = mrand.New(mrand.NewSource(time.Now().Unix()))
 )
 
-// New creates a user
+// New creates a user whose password is hashed with the DefaultCost
 func New(email, password string) (*User, error) {
-	salt, err := randSalt()
-	if err != nil {
-		return nil, err
-	}
-
-	hash, err := hashPassword([]byte(password), salt)
-	if err != nil {
-		return nil, err
-	}
-
-	user := &User{
-		Email: email,
-		Hash:  string(hash),
-		Salt:  base64.StdEncoding.EncodeToString(salt),
-	}
-
-	return user, nil
+	return NewWithCost(email, password, DefaultCost)
 }
 
 // Auth is HTTP middleware to ensure the request has proper token credentials
@@ -66,9 +62,15 @@ func IsValid(req *http.Request) bool {
 	if err != nil {
 		return false
 	}
-	// validate it and allow or redirect request
+	// validate it and allow or redirect request, requiring the user claim so
+	// that tokens issued before a second factor is given are not accepted
 	token := cookie.Value
-	return jwt.Passes(token)
+	if !jwt.Passes(token) {
+		return false
+	}
+
+	_, ok := jwt.GetClaims(token)["user"]
+	return ok
 }
 
 // IsUser checks for consistency in email/pass combination
@@ -80,7 +82,7 @@ func IsUser(usr *User, password string) bool {
 
 	err = checkPassword([]byte(usr.Hash), []byte(password), salt)
 	if err != nil {
-		log.Println("Error checking password:", err)
+		logger.Error("Error checking password:", err)
 		return false
 	}
 
@@ -117,14 +119,14 @@ func saltPassword(password, salt []byte) ([]byte, error) {
 	return salted.Bytes(), nil
 }
 
-// hashPassword encrypts the salted password using bcrypt
-func hashPassword(password, salt []byte) ([]byte, error) {
+// hashPassword encrypts the salted password using bcrypt with cost
+func hashPassword(password, salt []byte, cost int) ([]byte, error) {
 	salted, err := saltPassword(password, salt)
 	if err != nil {
 		return nil, err
 	}
 
-	hash, err := bcrypt.GenerateFromPassword(salted, 10)
+	hash, err := bcrypt.GenerateFromPassword(salted, cost)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,133 @@
+package user
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultCost is the bcrypt cost passwords are hashed with unless another is
+// configured
+const DefaultCost = 10
+
+// DefaultMinLength is the shortest password allowed unless another length is
+// configured
+const DefaultMinLength = 8
+
+// PasswordPolicy is the set of rules a new password must follow
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// PasswordError lists the rules of a PasswordPolicy which a password breaks
+type PasswordError struct {
+	Problems []string
+}
+
+func (e *PasswordError) Error() string {
+	return "Password must " + strings.Join(e.Problems, ", ") + "."
+}
+
+// Check returns a *PasswordError describing each rule of the policy password
+// breaks, or nil if it follows them all
+func (p PasswordPolicy) Check(password string) error {
+	min := p.MinLength
+	if min < 1 {
+		min = DefaultMinLength
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	var problems []string
+	if utf8.RuneCountInString(password) < min {
+		problems = append(problems, fmt.Sprintf("be at least %d characters long", min))
+	}
+	if p.RequireUpper && !upper {
+		problems = append(problems, "contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		problems = append(problems, "contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		problems = append(problems, "contain a number")
+	}
+	if p.RequireSymbol && !symbol {
+		problems = append(problems, "contain a symbol, such as ! or #")
+	}
+
+	if len(problems) > 0 {
+		return &PasswordError{Problems: problems}
+	}
+
+	return nil
+}
+
+// ValidCost returns cost if it is a bcrypt cost, otherwise DefaultCost
+func ValidCost(cost int) int {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return DefaultCost
+	}
+
+	return cost
+}
+
+// NewWithCost creates a user whose password is hashed with the bcrypt cost
+func NewWithCost(email, password string, cost int) (*User, error) {
+	usr := &User{Email: email}
+	err := usr.SetPassword(password, cost)
+	if err != nil {
+		return nil, err
+	}
+
+	return usr, nil
+}
+
+// SetPassword changes the user's password, hashing it with a new salt and the
+// bcrypt cost
+func (u *User) SetPassword(password string, cost int) error {
+	salt, err := randSalt()
+	if err != nil {
+		return err
+	}
+
+	hash, err := hashPassword([]byte(password), salt, ValidCost(cost))
+	if err != nil {
+		return err
+	}
+
+	u.Hash = string(hash)
+	u.Salt = base64.StdEncoding.EncodeToString(salt)
+
+	return nil
+}
+
+// NeedsRehash reports whether the user's password was hashed with a bcrypt cost
+// other than cost, so it should be hashed again the next time it is given
+func (u *User) NeedsRehash(cost int) bool {
+	c, err := bcrypt.Cost([]byte(u.Hash))
+	if err != nil {
+		return false
+	}
+
+	return c != ValidCost(cost)
+}
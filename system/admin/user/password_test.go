@@ -0,0 +1,47 @@
+package user
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	p := PasswordPolicy{MinLength: 10, RequireDigit: true, RequireSymbol: true}
+
+	err := p.Check("short")
+	if err == nil {
+		t.Fatal("expected a short password without a number or symbol to be rejected")
+	}
+
+	pe, ok := err.(*PasswordError)
+	if !ok || len(pe.Problems) != 3 {
+		t.Fatalf("expected 3 problems, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "at least 10 characters") {
+		t.Errorf("expected message to give the minimum length, got %q", err.Error())
+	}
+
+	if err := p.Check("long enough 4 me"); err != nil {
+		t.Errorf("expected password to be accepted, got %v", err)
+	}
+
+	if err := (PasswordPolicy{}).Check("1234567"); err == nil {
+		t.Error("expected the default minimum length to apply")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	usr, err := NewWithCost("test@example.com", "password", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if usr.NeedsRehash(4) || !usr.NeedsRehash(5) {
+		t.Error("expected a rehash only for another cost")
+	}
+
+	if !IsUser(usr, "password") {
+		t.Error("expected password to match after hashing with a configured cost")
+	}
+}
@@ -0,0 +1,133 @@
+package user
+
+// Action is an operation an admin user can perform on content
+type Action string
+
+const (
+	// ActionCreate allows creating new content, including by import
+	ActionCreate Action = "create"
+	// ActionRead allows listing, searching and viewing content
+	ActionRead Action = "read"
+	// ActionUpdate allows saving changes to existing content
+	ActionUpdate Action = "update"
+	// ActionDelete allows deleting content
+	ActionDelete Action = "delete"
+	// ActionPublish allows publishing content, changing published content and
+	// sending content under review back to draft, when the approval workflow
+	// is enabled
+	ActionPublish Action = "publish"
+)
+
+// Actions are all of the actions which can be allowed on content
+var Actions = []Action{ActionCreate, ActionRead, ActionUpdate, ActionDelete, ActionPublish}
+
+// Sections of the admin outside of content, which a role can be allowed to
+// manage
+const (
+	SectionConfig   = "config"
+	SectionUsers    = "users"
+	SectionAddons   = "addons"
+	SectionAPIKeys  = "apikeys"
+	SectionGraphQL  = "graphql"
+	SectionWebhooks = "webhooks"
+	SectionTrash    = "trash"
+	SectionAudit    = "audit"
+	// SectionRedirects allows managing the redirect table
+	SectionRedirects = "redirects"
+	// SectionTags allows renaming the canonical tags of content
+	SectionTags = "tags"
+	// SectionAnalytics allows viewing and exporting the request analytics
+	SectionAnalytics = "analytics"
+)
+
+// Sections are all of the admin sections a role can be allowed to manage
+var Sections = []string{
+	SectionConfig, SectionUsers, SectionAddons, SectionAPIKeys,
+	SectionGraphQL, SectionWebhooks, SectionTrash, SectionAudit,
+	SectionRedirects, SectionTags, SectionAnalytics,
+}
+
+// SuperAdminRole is the name of the role allowed to do anything, which is the
+// role of users without one assigned
+const SuperAdminRole = "super admin"
+
+// Role is a named set of permissions assigned to admin users
+type Role struct {
+	Name string `json:"name"`
+
+	// Permissions maps content type names to the actions allowed on them. The
+	// actions of "*" are allowed on every type.
+	Permissions map[string][]Action `json:"permissions"`
+
+	// Sections lists the admin sections the role can manage
+	Sections []string `json:"sections"`
+}
+
+// SuperAdmin returns the role allowed to do anything
+func SuperAdmin() Role {
+	return Role{Name: SuperAdminRole}
+}
+
+// IsSuper reports whether r is the super admin role
+func (r Role) IsSuper() bool {
+	return r.Name == SuperAdminRole
+}
+
+// Can reports whether r allows action on content of type t
+func (r Role) Can(t string, action Action) bool {
+	if r.IsSuper() {
+		return true
+	}
+
+	for _, a := range append(r.Permissions[t], r.Permissions["*"]...) {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Manages reports whether r allows managing the admin section
+func (r Role) Manages(section string) bool {
+	if r.IsSuper() {
+		return true
+	}
+
+	for _, s := range r.Sections {
+		if s == section {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Covers reports whether r allows everything other allows, so that a user with
+// role r can assign other without granting more than they have themselves
+func (r Role) Covers(other Role) bool {
+	if r.IsSuper() {
+		return true
+	}
+
+	if other.IsSuper() {
+		return false
+	}
+
+	for _, s := range other.Sections {
+		if !r.Manages(s) {
+			return false
+		}
+	}
+
+	for t, actions := range other.Permissions {
+		// actions on "*" are only covered by the same actions on "*"
+		for _, a := range actions {
+			if !r.Can(t, a) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,98 @@
+package user
+
+import "testing"
+
+func TestRole(t *testing.T) {
+	editor := Role{
+		Name: "editor",
+		Permissions: map[string][]Action{
+			"Post": {ActionRead, ActionUpdate},
+			"*":    {ActionRead},
+		},
+		Sections: []string{SectionTrash},
+	}
+
+	cases := []struct {
+		t      string
+		action Action
+		want   bool
+	}{
+		{"Post", ActionUpdate, true},
+		{"Post", ActionDelete, false},
+		{"Page", ActionRead, true},
+		{"Page", ActionCreate, false},
+	}
+
+	for _, c := range cases {
+		if got := editor.Can(c.t, c.action); got != c.want {
+			t.Errorf("Can(%s, %s): expected %v, got %v", c.t, c.action, c.want, got)
+		}
+	}
+
+	if !editor.Manages(SectionTrash) || editor.Manages(SectionUsers) {
+		t.Error("expected editor to manage only the trash")
+	}
+
+	super := SuperAdmin()
+	if !super.Can("Page", ActionDelete) || !super.Manages(SectionUsers) {
+		t.Error("expected super admin to be allowed everything")
+	}
+
+	if (Role{}).Can("Post", ActionRead) {
+		t.Error("expected a role with no permissions to be allowed nothing")
+	}
+}
+
+func TestRoleManages(t *testing.T) {
+	cases := []struct {
+		role    Role
+		section string
+		want    bool
+	}{
+		{Role{Sections: []string{SectionUsers}}, SectionUsers, true},
+		{Role{Sections: []string{SectionUsers}}, SectionAnalytics, false},
+		{Role{}, SectionUsers, false},
+		{SuperAdmin(), SectionAnalytics, true},
+		{Role{Name: "users", Sections: []string{SectionUsers}}, SectionConfig, false},
+	}
+
+	for _, c := range cases {
+		if got := c.role.Manages(c.section); got != c.want {
+			t.Errorf("%+v Manages(%s): expected %v, got %v", c.role, c.section, c.want, got)
+		}
+	}
+}
+
+func TestRoleCovers(t *testing.T) {
+	manager := Role{
+		Name:        "manager",
+		Permissions: map[string][]Action{"Post": {ActionRead, ActionUpdate}, "*": {ActionRead}},
+		Sections:    []string{SectionUsers},
+	}
+
+	cases := []struct {
+		name  string
+		other Role
+		want  bool
+	}{
+		{"itself", manager, true},
+		{"no permissions", Role{Name: "none"}, true},
+		{"fewer actions", Role{Name: "reader", Permissions: map[string][]Action{"Post": {ActionRead}}}, true},
+		{"read on any type", Role{Name: "viewer", Permissions: map[string][]Action{"Page": {ActionRead}, "*": {ActionRead}}}, true},
+		{"more actions", Role{Name: "writer", Permissions: map[string][]Action{"Post": {ActionDelete}}}, false},
+		{"update on any type", Role{Name: "editor", Permissions: map[string][]Action{"*": {ActionUpdate}}}, false},
+		{"another type", Role{Name: "pages", Permissions: map[string][]Action{"Page": {ActionUpdate}}}, false},
+		{"another section", Role{Name: "config", Sections: []string{SectionConfig}}, false},
+		{"super admin", SuperAdmin(), false},
+	}
+
+	for _, c := range cases {
+		if got := manager.Covers(c.other); got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+
+	if !SuperAdmin().Covers(SuperAdmin()) || !SuperAdmin().Covers(manager) {
+		t.Error("expected super admin to cover every role")
+	}
+}
@@ -0,0 +1,201 @@
+package user
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// totpPeriod is the number of seconds each TOTP code is valid for
+	totpPeriod = 30
+	// totpSkew is the number of periods before and after the current one
+	// whose codes are also accepted, to allow for clock drift
+	totpSkew = 1
+	// recoveryCodes is the number of recovery codes generated at enrollment
+	recoveryCodes = 10
+)
+
+// ErrInvalidSecret is returned when an encrypted secret can't be decrypted,
+// such as after the key it was encrypted with has changed
+var ErrInvalidSecret = errors.New("Invalid or corrupt secret")
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// HasTOTP reports whether the user has enrolled in two-factor authentication
+func (u User) HasTOTP() bool {
+	return u.TOTPSecret != ""
+}
+
+// NewTOTPSecret returns a random secret for an authenticator app, encoded in
+// base32 as it is entered into one
+func NewTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	_, err := crand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPURL returns the otpauth URL of secret for the account email, which
+// authenticator apps read from a QR code
+func TOTPURL(issuer, email, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("period", fmt.Sprintf("%d", totpPeriod))
+
+	label := url.PathEscape(issuer + ":" + email)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+// TOTPCode returns the 6-digit code of secret at time t, as defined by RFC 6238
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+
+	return hotp(key, uint64(t.Unix()/totpPeriod)), nil
+}
+
+// ValidTOTP reports whether code is the code of secret at time t, or of the
+// periods just before or after it
+func ValidTOTP(secret, code string, t time.Time) bool {
+	code = strings.Replace(code, " ", "", -1)
+	if len(code) != 6 {
+		return false
+	}
+
+	for i := -totpSkew; i <= totpSkew; i++ {
+		want, err := TOTPCode(secret, t.Add(time.Duration(i*totpPeriod)*time.Second))
+		if err != nil {
+			return false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hotp returns the 6-digit HOTP value of key for counter, as defined by RFC 4226
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0xf
+	n := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%06d", n%1000000)
+}
+
+// EncryptSecret encrypts secret using AES-GCM with a key derived from key, so
+// that it can be stored in the user record
+func EncryptSecret(secret string, key []byte) (string, error) {
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = crand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret decrypts a secret encrypted by EncryptSecret with the same key
+func DecryptSecret(enc string, key []byte) (string, error) {
+	gcm, err := secretCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidSecret
+	}
+
+	n := gcm.NonceSize()
+	secret, err := gcm.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return "", ErrInvalidSecret
+	}
+
+	return string(secret), nil
+}
+
+func secretCipher(key []byte) (cipher.AEAD, error) {
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// NewRecoveryCodes returns a set of single-use codes which can be entered in
+// place of a TOTP code, along with the hashes of them to store
+func NewRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodes; i++ {
+		buf := make([]byte, 5)
+		_, err = crand.Read(buf)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		code := hex.EncodeToString(buf)
+		code = code[:5] + "-" + code[5:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// UseRecoveryCode reports whether code is one of the user's unused recovery
+// codes, and removes it so it can't be used again
+func (u *User) UseRecoveryCode(code string) bool {
+	h := hashRecoveryCode(code)
+	for i, rc := range u.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(rc), []byte(h)) == 1 {
+			u.RecoveryCodes = append(u.RecoveryCodes[:i:i], u.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
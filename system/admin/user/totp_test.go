@@ -0,0 +1,73 @@
+package user
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// the SHA1 test vectors of RFC 6238, truncated to 6 digits
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{20000000000, "353130"},
+	}
+
+	for _, c := range cases {
+		got, err := TOTPCode(secret, time.Unix(c.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != c.want {
+			t.Errorf("TOTPCode at %d: expected %s, got %s", c.unix, c.want, got)
+		}
+	}
+
+	now := time.Unix(1111111109, 0)
+	if !ValidTOTP(secret, "081804", now.Add(25*time.Second)) {
+		t.Error("expected code of the previous period to be valid")
+	}
+	if ValidTOTP(secret, "081804", now.Add(5*time.Minute)) {
+		t.Error("expected code of an old period to be invalid")
+	}
+}
+
+func TestSecretEncryption(t *testing.T) {
+	enc, err := EncryptSecret("JBSWY3DPEHPK3PXP", []byte("client secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DecryptSecret(enc, []byte("client secret"))
+	if err != nil || got != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("expected secret to decrypt, got %q, %v", got, err)
+	}
+
+	if _, err := DecryptSecret(enc, []byte("other secret")); err != ErrInvalidSecret {
+		t.Errorf("expected ErrInvalidSecret with the wrong key, got %v", err)
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &User{RecoveryCodes: hashes}
+	if !u.UseRecoveryCode(codes[3]) {
+		t.Fatal("expected recovery code to be accepted")
+	}
+	if u.UseRecoveryCode(codes[3]) {
+		t.Error("expected recovery code to be used only once")
+	}
+	if len(u.RecoveryCodes) != len(codes)-1 {
+		t.Errorf("expected %d codes left, got %d", len(codes)-1, len(u.RecoveryCodes))
+	}
+}
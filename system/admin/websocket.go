@@ -0,0 +1,219 @@
+package admin
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is appended to the key of a websocket handshake to make its
+// accept key, as in RFC 6455
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// the websocket frame opcodes used by the admin
+const (
+	wsText  = 0x1
+	wsClose = 0x8
+	wsPing  = 0x9
+	wsPong  = 0xA
+)
+
+// wsMaxPayload is the largest frame read from a client, which only sends
+// control frames to the streams served by the admin
+const wsMaxPayload = 4096
+
+// wsWriteTimeout is how long a write to a client may take before the
+// connection is closed
+const wsWriteTimeout = 10 * time.Second
+
+var errWebsocketFrame = errors.New("websocket frame is too large or unmasked")
+
+// websocketConn is a server side websocket connection, which can be written
+// to from more than one goroutine
+type websocketConn struct {
+	conn net.Conn
+	buf  *bufio.ReadWriter
+
+	mu sync.Mutex
+}
+
+// upgradeWebsocket completes the websocket handshake of req, which must be
+// made from the same host the admin is served from, so that other sites can't
+// open a socket with the cookies of a logged in admin user. It writes an error
+// response and returns nil if req is not a valid handshake.
+func upgradeWebsocket(res http.ResponseWriter, req *http.Request) *websocketConn {
+	if req.Method != http.MethodGet ||
+		!headerContains(req.Header, "Connection", "upgrade") ||
+		!headerContains(req.Header, "Upgrade", "websocket") ||
+		req.Header.Get("Sec-WebSocket-Version") != "13" ||
+		req.Header.Get("Sec-WebSocket-Key") == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+
+	origin, err := url.Parse(req.Header.Get("Origin"))
+	if err != nil || !strings.EqualFold(origin.Host, req.Host) {
+		res.WriteHeader(http.StatusForbidden)
+		return nil
+	}
+
+	hj, ok := res.(http.Hijacker)
+	if !ok {
+		res.WriteHeader(http.StatusInternalServerError)
+		return nil
+	}
+
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return nil
+	}
+
+	sum := sha1.Sum([]byte(req.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+	accept := base64.StdEncoding.EncodeToString(sum[:])
+
+	ws := &websocketConn{conn: conn, buf: buf}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + accept + "\r\n\r\n")
+	if buf.Flush() != nil {
+		conn.Close()
+		return nil
+	}
+
+	return ws
+}
+
+// headerContains reports whether the comma separated values of the header key
+// include value, ignoring case
+func headerContains(h http.Header, key, value string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(key)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// WriteText sends p to the client as a text message
+func (ws *websocketConn) WriteText(p []byte) error {
+	return ws.write(wsText, p)
+}
+
+// write sends a single unmasked frame of opcode op with payload p
+func (ws *websocketConn) write(op byte, p []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	header := []byte{0x80 | op, 0}
+	switch n := len(p); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	ws.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	ws.buf.Write(header)
+	ws.buf.Write(p)
+	return ws.buf.Flush()
+}
+
+// ReadLoop reads frames from the client until the connection is closed,
+// answering pings and discarding messages, and returns once the client closes
+// the socket or the connection fails
+func (ws *websocketConn) ReadLoop() {
+	for {
+		op, p, err := ws.readFrame()
+		if err != nil {
+			return
+		}
+
+		switch op {
+		case wsClose:
+			ws.write(wsClose, nil)
+			return
+		case wsPing:
+			ws.write(wsPong, p)
+		}
+	}
+}
+
+// readFrame reads a frame from the client, which must be masked
+func (ws *websocketConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	_, err := io.ReadFull(ws.buf, head[:])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	op := head[0] & 0x0F
+	if head[1]&0x80 == 0 {
+		return 0, nil, errWebsocketFrame
+	}
+
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		_, err = io.ReadFull(ws.buf, ext[:])
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		_, err = io.ReadFull(ws.buf, ext[:])
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if n > wsMaxPayload {
+		return 0, nil, errWebsocketFrame
+	}
+
+	var mask [4]byte
+	_, err = io.ReadFull(ws.buf, mask[:])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	p := make([]byte, n)
+	_, err = io.ReadFull(ws.buf, p)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for i := range p {
+		p[i] ^= mask[i%4]
+	}
+
+	return op, p, nil
+}
+
+// Close closes the connection
+func (ws *websocketConn) Close() error {
+	return ws.conn.Close()
+}
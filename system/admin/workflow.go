@@ -0,0 +1,240 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/admin/user"
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	// errWorkflowForbidden is returned when the current user's role doesn't
+	// allow moving content to the state requested, or changing it in its
+	// current state
+	errWorkflowForbidden = errors.New("Your role doesn't allow publishing this content, or changing it once published.")
+	// errWorkflowState is returned for a request to move content to a state
+	// which isn't part of the workflow
+	errWorkflowState = errors.New("Unknown workflow state.")
+)
+
+// workflowEnabled reports whether content must be approved before publishing.
+// When it isn't, content is published as soon as it is saved.
+func workflowEnabled() bool {
+	enabled, _ := db.ConfigCache("workflow_enabled").(bool)
+	return enabled
+}
+
+// applyWorkflow sets the workflow state and history in form, the values of
+// content of type t being saved by the user making req, replacing any values
+// submitted for them. existing is the content as it is saved now, or nil for
+// new content, and requested is the state the user asked to move it to, with
+// "" keeping its current state. With the workflow disabled saving content
+// publishes it. It returns the state of the content before and after.
+func applyWorkflow(req *http.Request, t string, form url.Values, existing []byte, requested string) (from, to string, err error) {
+	var history []item.StateChange
+	if len(existing) > 0 {
+		it, ok := item.Types[t]
+		if !ok {
+			return "", "", fmt.Errorf(item.ErrTypeNotRegistered.Error(), t)
+		}
+
+		post := it()
+		err := json.Unmarshal(existing, post)
+		if err != nil {
+			return "", "", err
+		}
+
+		w, ok := post.(item.Workflowable)
+		if !ok {
+			return "", "", nil
+		}
+
+		from = w.WorkflowState()
+		history = w.WorkflowHistory()
+	}
+
+	switch {
+	case !workflowEnabled():
+		to = item.StatePublished
+	case requested != "":
+		to = requested
+	case from != "":
+		to = from
+	default:
+		to = item.StateDraft
+	}
+
+	valid := false
+	for _, s := range item.States {
+		if s == to {
+			valid = true
+		}
+	}
+	if !valid {
+		return "", "", errWorkflowState
+	}
+
+	var email string
+	j, err := db.CurrentUser(req)
+	if err == nil {
+		email = gjson.GetBytes(j, "email").String()
+	}
+
+	if workflowEnabled() && (to == item.StatePublished || from == item.StatePublished) {
+		role, err := db.CurrentRole(req)
+		if err != nil {
+			return "", "", err
+		}
+
+		if !role.Can(t, user.ActionPublish) {
+			return "", "", errWorkflowForbidden
+		}
+	}
+
+	// new content published without the workflow keeps no history, the same
+	// as content saved before the workflow existed
+	if to != from && (from != "" || to != item.StatePublished) {
+		history = append(history, item.StateChange{
+			State: to,
+			User:  email,
+			Time:  time.Now().UnixNano() / int64(time.Millisecond),
+		})
+	}
+
+	form.Del("state")
+	for k := range form {
+		if strings.HasPrefix(k, "state_history.") {
+			form.Del(k)
+		}
+	}
+
+	if to != item.StatePublished || len(history) > 0 {
+		form.Set("state", to)
+	}
+	for i, h := range history {
+		form.Set(fmt.Sprintf("state_history.%d.state", i), h.State)
+		form.Set(fmt.Sprintf("state_history.%d.user", i), h.User)
+		form.Set(fmt.Sprintf("state_history.%d.time", i), fmt.Sprintf("%d", h.Time))
+	}
+
+	return from, to, nil
+}
+
+// approvalState returns the workflow state pending external content of type t
+// is moved to when the user making req approves it: published if their role
+// allows publishing it, and a draft to be reviewed otherwise
+func approvalState(req *http.Request, t string) string {
+	role, err := db.CurrentRole(req)
+	if err == nil && role.Can(t, user.ActionPublish) {
+		return item.StatePublished
+	}
+
+	return item.StateDraft
+}
+
+// canReview reports whether role allows publishing content of any type, and
+// so reviewing the content waiting to be published
+func canReview(role user.Role) bool {
+	for t := range item.Types {
+		if role.Can(t, user.ActionPublish) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reviewItem is content waiting in the review queue
+type reviewItem struct {
+	Type      string
+	ID        int
+	Title     string
+	Submitted string
+	By        string
+	time      int64
+}
+
+// reviewQueue returns the content pending review of the types which role
+// allows publishing, longest waiting first
+func reviewQueue(role user.Role) []reviewItem {
+	var queue []reviewItem
+	for t, it := range item.Types {
+		if !role.Can(t, user.ActionPublish) {
+			continue
+		}
+
+		for _, j := range db.ContentAll(t) {
+			if gjson.GetBytes(j, "state").String() != item.StateReview {
+				continue
+			}
+
+			post := it()
+			err := json.Unmarshal(j, post)
+			if err != nil {
+				continue
+			}
+
+			w, ok := post.(item.Workflowable)
+			if !ok {
+				continue
+			}
+
+			r := reviewItem{
+				Type:  t,
+				ID:    int(gjson.GetBytes(j, "id").Int()),
+				Title: post.(item.Identifiable).String(),
+			}
+
+			// the last change moved the content to review
+			history := w.WorkflowHistory()
+			if len(history) > 0 {
+				last := history[len(history)-1]
+				r.By = last.User
+				r.time = last.Time
+				r.Submitted = time.Unix(0, last.Time*int64(time.Millisecond)).Format("Jan 2, 2006 3:04 PM")
+			}
+
+			queue = append(queue, r)
+		}
+	}
+
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].time < queue[j].time
+	})
+
+	return queue
+}
+
+func reviewHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	view, err := ReviewQueue(req)
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		errView, err := Error500(req)
+		if err != nil {
+			return
+		}
+
+		res.Write(errView)
+		return
+	}
+
+	res.Header().Set("Content-Type", "text/html")
+	res.Write(view)
+}
@@ -1,6 +1,7 @@
 package analytics
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -8,9 +9,18 @@ import (
 	"github.com/boltdb/bolt"
 )
 
+// ErrBackupUnsupported is returned by Backup when analytics are not stored in
+// the default bolt Store
+var ErrBackupUnsupported = errors.New("analytics backup is only supported for the default bolt store")
+
 // Backup writes a snapshot of the analytics.db database to an HTTP response
 func Backup(res http.ResponseWriter) error {
-	err := store.View(func(tx *bolt.Tx) error {
+	bs, ok := store.(*boltStore)
+	if !ok {
+		return ErrBackupUnsupported
+	}
+
+	err := bs.db.View(func(tx *bolt.Tx) error {
 		ts := time.Now().Unix()
 		disposition := `attachment; filename="analytics-%d.db.bak"`
 
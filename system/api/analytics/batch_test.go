@@ -0,0 +1,60 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchPrune(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	Init(Options{FlushInterval: time.Hour, DataDir: dir})
+	defer Close()
+
+	threshold := time.Hour * 24 * 3
+	now := time.Now()
+	today := startOfDay(now)
+	cutoff := today.Add(threshold * -1)
+
+	times := []time.Time{
+		cutoff.Add(time.Hour * -48),
+		cutoff.Add(time.Hour * -1),
+		cutoff.Add(time.Hour),
+		now,
+	}
+
+	reqs := make(chan APIRequest, len(times))
+	for _, ts := range times {
+		reqs <- APIRequest{
+			URL:       "/api/contents?type=Post",
+			Timestamp: ts.UnixNano() / int64(time.Millisecond),
+		}
+	}
+
+	err := batchInsert(reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = batchPrune(threshold)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, err := RequestsBetween(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(kept) != 2 {
+		t.Fatalf("Expected 2 requests to remain after prune, got: %d", len(kept))
+	}
+
+	for i, r := range kept {
+		want := times[i+2].UnixNano() / int64(time.Millisecond)
+		if r.Timestamp != want {
+			t.Errorf("Expected kept request %d to have timestamp %d, got: %d", i, want, r.Timestamp)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package analytics
+
+import "strings"
+
+// botPatterns are lower case substrings of the user agents of crawlers, link
+// previewers and other automated clients
+var botPatterns = []string{
+	"bot",
+	"crawl",
+	"spider",
+	"slurp",
+	"mediapartners-google",
+	"facebookexternalhit",
+	"embedly",
+	"headlesschrome",
+	"phantomjs",
+	"lighthouse",
+	"pingdom",
+	"uptime",
+}
+
+// extraBotPatterns are set from Options in Init
+var extraBotPatterns []string
+
+// setBotPatterns sets the patterns matched in addition to botPatterns
+func setBotPatterns(patterns []string) {
+	extraBotPatterns = nil
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p != "" {
+			extraBotPatterns = append(extraBotPatterns, p)
+		}
+	}
+}
+
+// isBot reports whether the user agent ua looks like that of a crawler or
+// another automated client, rather than a person's browser or app
+func isBot(ua string) bool {
+	ua = strings.ToLower(ua)
+	if ua == "" {
+		return false
+	}
+
+	for _, p := range botPatterns {
+		if strings.Contains(ua, p) {
+			return true
+		}
+	}
+
+	for _, p := range extraBotPatterns {
+		if strings.Contains(ua, p) {
+			return true
+		}
+	}
+
+	return false
+}
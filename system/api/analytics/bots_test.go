@@ -0,0 +1,77 @@
+package analytics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsBot(t *testing.T) {
+	setBotPatterns([]string{" My-Monitor "})
+	defer setBotPatterns(nil)
+
+	cases := []struct {
+		ua  string
+		bot bool
+	}{
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", true},
+		{"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)", true},
+		{"my-monitor/1.0", true},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.1 Safari/605.1.15", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		if got := isBot(c.ua); got != c.bot {
+			t.Errorf("isBot(%q): expected %v, got %v", c.ua, c.bot, got)
+		}
+	}
+}
+
+func TestChartDataExcludeBots(t *testing.T) {
+	m := &memStore{}
+	Init(Options{FlushInterval: time.Hour, Store: m})
+
+	for _, ua := range []string{"Googlebot/2.1", "Mozilla/5.0", "Mozilla/5.0"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil)
+		req.Header.Set("User-Agent", ua)
+		Record(req)
+	}
+
+	Close()
+
+	if !m.reqs[0].Bot || m.reqs[0].UserAgent != "Googlebot/2.1" {
+		t.Errorf("Expected the crawler to be recorded as a bot, got: %+v", m.reqs[0])
+	}
+
+	today := func(data map[string]interface{}, key string) int {
+		var series []int
+		err := json.Unmarshal([]byte(data[key].(string)), &series)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return series[len(series)-1]
+	}
+
+	all, err := ChartData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if today(all, "total") != 3 || today(all, "bot_total") != 1 {
+		t.Errorf("Expected 3 requests of which 1 by a bot, got: %d and %d", today(all, "total"), today(all, "bot_total"))
+	}
+
+	people, err := ChartDataWithOptions(ChartOptions{ExcludeBots: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if today(people, "total") != 2 || today(people, "bot_total") != 0 {
+		t.Errorf("Expected 2 requests without bots, got: %d and %d", today(people, "total"), today(people, "bot_total"))
+	}
+}
@@ -0,0 +1,121 @@
+package analytics
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// trustedProxies are the networks from which X-Forwarded-For and X-Real-IP
+// headers are believed, set from Options in Init
+var trustedProxies []*net.IPNet
+
+// parseTrustedProxies converts CIDRs (or single IPs) to networks, logging and
+// skipping any which are invalid
+func parseTrustedProxies(cidrs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				logger.Error("Ignoring invalid trusted proxy for analytics:", cidr)
+				continue
+			}
+
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logger.Error("Ignoring invalid trusted proxy for analytics:", cidr, err)
+			continue
+		}
+
+		nets = append(nets, n)
+	}
+
+	return nets
+}
+
+// isTrusted reports whether ip belongs to one of the trusted proxy networks
+func isTrusted(ip net.IP) bool {
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the address of the client which made req. Unless the
+// request came from a trusted proxy, this is req.RemoteAddr. Otherwise the
+// X-Forwarded-For chain is walked from the right, skipping trusted proxies, so
+// that the first untrusted hop is used and values a client prepends to the
+// header can't be used to spoof its address. X-Real-IP is used if
+// X-Forwarded-For is not set.
+func clientIP(req *http.Request) string {
+	if len(trustedProxies) == 0 {
+		return req.RemoteAddr
+	}
+
+	ip := net.ParseIP(hostOnly(req.RemoteAddr))
+	if ip == nil || !isTrusted(ip) {
+		return req.RemoteAddr
+	}
+
+	var hops []string
+	for _, v := range req.Header["X-Forwarded-For"] {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// a malformed hop can't be trusted, nor can anything before it
+			break
+		}
+
+		// the first untrusted hop from the right is the client as seen by
+		// the outermost trusted proxy
+		if !isTrusted(hop) {
+			return hop.String()
+		}
+	}
+
+	if len(hops) == 0 {
+		realIP := net.ParseIP(strings.TrimSpace(req.Header.Get("X-Real-IP")))
+		if realIP != nil {
+			return realIP.String()
+		}
+	}
+
+	return req.RemoteAddr
+}
+
+// ClientIP returns the IP of the client which made req, without a port. It is
+// resolved the same way as the RemoteAddr of recorded requests, so is only
+// taken from proxy headers if the request came from one of the TrustedProxies
+// passed to Init.
+func ClientIP(req *http.Request) string {
+	return hostOnly(clientIP(req))
+}
+
+// hostOnly returns addr without its port, if it has one
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
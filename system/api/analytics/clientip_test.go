@@ -0,0 +1,45 @@
+package analytics
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	defer func() { trustedProxies = nil }()
+
+	cases := []struct {
+		name    string
+		proxies []string
+		remote  string
+		xff     []string
+		realIP  string
+		want    string
+	}{
+		{"no proxies configured", nil, "10.0.0.1:1234", []string{"1.1.1.1"}, "", "10.0.0.1:1234"},
+		{"untrusted remote", []string{"10.0.0.0/8"}, "8.8.8.8:1234", []string{"1.1.1.1"}, "", "8.8.8.8:1234"},
+		{"single hop", []string{"10.0.0.0/8"}, "10.0.0.1:1234", []string{"1.1.1.1"}, "", "1.1.1.1"},
+		{"spoofed hop ignored", []string{"10.0.0.0/8"}, "10.0.0.1:1234", []string{"6.6.6.6, 1.1.1.1"}, "", "1.1.1.1"},
+		{"chained proxies", []string{"10.0.0.0/8", "192.168.1.5"}, "10.0.0.1:1234", []string{"6.6.6.6, 1.1.1.1", "192.168.1.5"}, "", "1.1.1.1"},
+		{"malformed hop", []string{"10.0.0.0/8"}, "10.0.0.1:1234", []string{"1.1.1.1, bogus"}, "", "10.0.0.1:1234"},
+		{"x-real-ip", []string{"10.0.0.0/8"}, "10.0.0.1:1234", nil, "2.2.2.2", "2.2.2.2"},
+	}
+
+	for _, c := range cases {
+		trustedProxies = parseTrustedProxies(c.proxies)
+
+		req := httptest.NewRequest("GET", "/api/contents?type=Post", nil)
+		req.RemoteAddr = c.remote
+		for _, v := range c.xff {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		if c.realIP != "" {
+			req.Header.Set("X-Real-IP", c.realIP)
+		}
+
+		got := clientIP(req)
+		if got != c.want {
+			t.Errorf("%s: expected %s, got: %s", c.name, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader is the first row written by Export in "csv" format
+var csvHeader = []string{"url", "method", "origin", "protocol", "ip", "timestamp", "external", "user_agent", "bot"}
+
+// ValidExportFormat reports whether format is supported by Export
+func ValidExportFormat(format string) bool {
+	return format == "json" || format == "csv"
+}
+
+// Export writes the APIRequest records stored in the analytics db with a
+// timestamp from (inclusive) up to to (exclusive) to w in the "json" or "csv"
+// format. A zero to time exports all requests after from. Records are written
+// as they are read from the db so memory use does not grow with the dataset
+func Export(w io.Writer, format string, from, to time.Time) error {
+	switch format {
+	case "json":
+		return exportJSON(w, from, to)
+
+	case "csv":
+		return exportCSV(w, from, to)
+
+	default:
+		return fmt.Errorf("unsupported analytics export format: %s", format)
+	}
+}
+
+// exportJSON writes the records as a JSON array, one element at a time
+func exportJSON(w io.Writer, from, to time.Time) error {
+	_, err := io.WriteString(w, "[")
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	first := true
+	err = store.Range(from, to, func(r APIRequest) error {
+		if !first {
+			_, err := io.WriteString(w, ",")
+			if err != nil {
+				return err
+			}
+		}
+		first = false
+
+		return enc.Encode(r)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
+
+// exportCSV writes the records as CSV rows following csvHeader
+func exportCSV(w io.Writer, from, to time.Time) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write(csvHeader)
+	if err != nil {
+		return err
+	}
+
+	err = store.Range(from, to, func(r APIRequest) error {
+		return cw.Write([]string{
+			r.URL,
+			r.Method,
+			r.Origin,
+			r.Proto,
+			r.RemoteAddr,
+			time.Unix(0, r.Timestamp*int64(time.Millisecond)).UTC().Format(time.RFC3339),
+			strconv.FormatBool(r.External),
+			r.UserAgent,
+			strconv.FormatBool(r.Bot),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,105 @@
+package analytics
+
+import (
+	"net"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// geoDB is the optional GeoIP database used to resolve request IPs to country
+// codes, and is nil when no database is configured or it could not be opened
+var geoDB *maxminddb.Reader
+
+// geoRecord is the subset of a MaxMind GeoIP2/GeoLite2 record decoded to find a
+// request's country
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// openGeoIP opens the GeoIP database at path for use by batchInsert. Failing to
+// open it is logged but otherwise ignored so that analytics are still recorded
+func openGeoIP(path string) {
+	if path == "" {
+		return
+	}
+
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		logger.Error("Error opening GeoIP database, country data will not be recorded:", err)
+		return
+	}
+
+	geoDB = db
+}
+
+// closeGeoIP closes the GeoIP database, if one was opened
+func closeGeoIP() {
+	if geoDB == nil {
+		return
+	}
+
+	err := geoDB.Close()
+	if err != nil {
+		logger.Error(err)
+	}
+
+	geoDB = nil
+}
+
+// lookupCountry resolves the ISO country code of the client at addr, which may
+// include a port. It is best-effort and returns an empty string if no GeoIP
+// database is open or the address can't be resolved
+func lookupCountry(addr string) (country string) {
+	if geoDB == nil {
+		return ""
+	}
+
+	ip := net.ParseIP(hostOnly(addr))
+	if ip == nil {
+		return ""
+	}
+
+	// a corrupt database must never cause a batch of requests to be lost
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Warn("Recovered from panic during GeoIP lookup:", r)
+			country = ""
+		}
+	}()
+
+	var rec geoRecord
+	err := geoDB.Lookup(ip, &rec)
+	if err != nil {
+		return ""
+	}
+
+	return rec.Country.ISOCode
+}
+
+// CountryBreakdown returns the number of API requests made between from and to
+// from each country, keyed by ISO country code. Requests which could not be
+// resolved to a country are counted under "unknown". A zero to time returns
+// all requests after from
+func CountryBreakdown(from, to time.Time) (map[string]int, error) {
+	countries := make(map[string]int)
+
+	err := store.Range(from, to, func(r APIRequest) error {
+		country := r.Country
+		if country == "" {
+			country = "unknown"
+		}
+
+		countries[country]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return countries, nil
+}
@@ -4,322 +4,699 @@
 package analytics
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/boltdb/bolt"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/requestid"
+	"github.com/ponzu-cms/ponzu/system/scheduler"
 )
 
-type apiRequest struct {
-	URL        string `json:"url"`
-	Method     string `json:"http_method"`
-	Origin     string `json:"origin"`
-	Proto      string `json:"http_protocol"`
-	RemoteAddr string `json:"ip_address"`
-	Timestamp  int64  `json:"timestamp"`
-	External   bool   `json:"external_content"`
+// APIRequest is the record stored in the analytics db for each API request
+type APIRequest struct {
+	URL        string  `json:"url"`
+	Method     string  `json:"http_method"`
+	Origin     string  `json:"origin"`
+	Proto      string  `json:"http_protocol"`
+	RemoteAddr string  `json:"ip_address"`
+	Timestamp  int64   `json:"timestamp"`
+	External   bool    `json:"external_content"`
+	Country    string  `json:"country,omitempty"`
+	DurationMs float64 `json:"duration_ms,omitempty"`
+	Status     int     `json:"status,omitempty"`
+	RequestID  string  `json:"request_id,omitempty"`
+	UserAgent  string  `json:"user_agent,omitempty"`
+	Bot        bool    `json:"bot,omitempty"`
+
+	// SampledEvery is N for a request recorded as 1 in N requests like it, so
+	// that counts of requests can be scaled back up to the true volume
+	SampledEvery int `json:"sampled_every,omitempty"`
 }
 
-type apiMetric struct {
-	Date   string `json:"date"`
-	Total  int    `json:"total"`
-	Unique int    `json:"unique"`
+// Weight returns the number of requests r stands for, which is more than 1 if
+// it was sampled
+func (r APIRequest) Weight() int {
+	if r.SampledEvery > 1 {
+		return r.SampledEvery
+	}
+
+	return 1
+}
+
+// Options configures the analytics system. The zero value of each field will
+// fall back to the default used by Ponzu.
+type Options struct {
+	// Retention is the duration request analytics are stored before they are
+	// pruned, and determines the number of days displayed in ChartData.
+	// Defaults to RANGE days.
+	Retention time.Duration
+
+	// FlushInterval is how often queued requests are batch inserted into the
+	// analytics db. Defaults to 30 seconds.
+	FlushInterval time.Duration
+
+	// FlushThreshold is the fraction (0 < n <= 1) of the request queue capacity
+	// which, once reached, triggers a batch insert before the next
+	// FlushInterval. Defaults to 0.75.
+	FlushThreshold float64
+
+	// DataDir is the directory in which analytics.db is stored, and is created
+	// if it does not exist. Defaults to the current working directory. Unused
+	// if Store is set.
+	DataDir string
+
+	// GeoIPDatabase is the path to a MaxMind GeoIP2 or GeoLite2 Country/City
+	// database used to record the country of each request. Country data is
+	// not recorded if empty, or if the database cannot be opened.
+	GeoIPDatabase string
+
+	// TrustedProxies is a list of CIDRs (or single IPs) of reverse proxies or
+	// load balancers in front of Ponzu. Requests from these addresses are
+	// recorded using the client IP in their X-Forwarded-For or X-Real-IP
+	// header. If empty, the request's RemoteAddr is always used.
+	TrustedProxies []string
+
+	// Location is the time zone in which requests are bucketed into days for
+	// ChartData and pruning. Defaults to the server's local time zone.
+	Location *time.Location
+
+	// AbuseThreshold is the number of requests per minute from a single IP
+	// above which OnAbuse is called. Detection is disabled if 0.
+	AbuseThreshold int
+
+	// OnAbuse is called once per minute for each IP making more than
+	// AbuseThreshold requests in that minute, with the IP and its count so
+	// far. It is called from the goroutine recording the request, and should
+	// not block.
+	OnAbuse func(ip string, count int)
+
+	// SampleEvery records 1 in every SampleEvery requests to content, to
+	// reduce the writes made on sites with a lot of traffic. Counts of
+	// requests in ChartData and the other queries are scaled back up, but the
+	// counts of unique IPs are only of the requests recorded. The metrics
+	// served by MetricsHandler always count every request. Defaults to 1,
+	// recording every request.
+	SampleEvery int
+
+	// ExternalSampleEvery is like SampleEvery, for requests to submit
+	// external content. Defaults to SampleEvery.
+	ExternalSampleEvery int
+
+	// BotPatterns are substrings of user agents, matched case insensitively,
+	// which mark requests as made by bots, in addition to those of well known
+	// crawlers.
+	BotPatterns []string
+
+	// Store is the backend in which requests are stored. Defaults to a BoltDB
+	// file, analytics.db, in DataDir. Close will close the Store.
+	Store Store
+}
+
+// instance is the request queue of analytics, made each time they are
+// initialized, and served by its own serve() until it is stopped
+type instance struct {
+	requests chan APIRequest
+	flushAt  int
+
+	// flush notifies serve() to batch insert early, quit tells serve() to
+	// stop, and done is closed by serve() once its final batch insert is made
+	flush    chan struct{}
+	quit     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// err is the error analytics failed to initialize with, if they did
+	err error
+
+	// closed is set once Close has closed the store
+	closed bool
+}
+
+// stop tells serve() to insert any queued requests and return. It is safe to
+// call more than once
+func (in *instance) stop() {
+	in.stopOnce.Do(func() {
+		close(in.quit)
+	})
 }
 
 var (
-	store       *bolt.DB
-	requestChan chan apiRequest
+	store Store
+
+	// current is the instance made by the last Init, guarded by mu
+	current *instance
+	mu      sync.Mutex
+
+	// retention, flushInterval, location and the sample rates are set from
+	// Options in Init
+	retention     = time.Hour * 24 * RANGE
+	flushInterval = time.Second * 30
+	location      = time.Local
+	sampleRates   = [2]sampleRate{}
 )
 
-// RANGE determines the number of days ponzu request analytics and metrics are
-// stored and displayed within the system
+// running returns the instance made by the last Init, or nil if analytics have
+// not been initialized
+func running() *instance {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return current
+}
+
+// sampleRate records 1 in every n requests, counting those seen so far
+type sampleRate struct {
+	n    int
+	seen uint64
+}
+
+// sample reports whether r should be recorded, setting the rate it was sampled
+// at if it should
+func sample(r *APIRequest) bool {
+	s := &sampleRates[0]
+	if r.External {
+		s = &sampleRates[1]
+	}
+
+	if s.n <= 1 {
+		return true
+	}
+
+	if (atomic.AddUint64(&s.seen, 1)-1)%uint64(s.n) != 0 {
+		return false
+	}
+
+	r.SampledEvery = s.n
+	return true
+}
+
+// RANGE determines the default number of days ponzu request analytics and
+// metrics are stored and displayed within the system
 const RANGE = 14
 
-// Record queues an apiRequest for metrics
+// Record queues an APIRequest for metrics
 func Record(req *http.Request) {
-	external := strings.Contains(req.URL.Path, "/external/")
+	enqueue(newAPIRequest(req, time.Now()))
+}
 
-	ts := int64(time.Nanosecond) * time.Now().UnixNano() / int64(time.Millisecond)
+// RecordWithDuration queues an APIRequest for metrics which took d to serve.
+// It should be called once the response has been written.
+func RecordWithDuration(req *http.Request, d time.Duration) {
+	r := newAPIRequest(req, time.Now().Add(d*-1))
+	r.DurationMs = float64(d) / float64(time.Millisecond)
 
-	r := apiRequest{
+	enqueue(r)
+}
+
+// RecordResponse queues an APIRequest for metrics which was responded to with
+// the HTTP status code status
+func RecordResponse(req *http.Request, status int) {
+	r := newAPIRequest(req, time.Now())
+	r.Status = status
+
+	enqueue(r)
+}
+
+// RecordResponseWithDuration queues an APIRequest for metrics which was
+// responded to with the HTTP status code status and took d to serve. It should
+// be called once the response has been written.
+func RecordResponseWithDuration(req *http.Request, status int, d time.Duration) {
+	r := newAPIRequest(req, time.Now().Add(d*-1))
+	r.DurationMs = float64(d) / float64(time.Millisecond)
+	r.Status = status
+
+	enqueue(r)
+}
+
+// newAPIRequest creates the APIRequest for req, made at start
+func newAPIRequest(req *http.Request, start time.Time) APIRequest {
+	// external content is submitted to /api/content/external
+	external := strings.Contains(req.URL.Path, "/external/") ||
+		strings.HasSuffix(req.URL.Path, "/external")
+
+	ts := int64(time.Nanosecond) * start.UnixNano() / int64(time.Millisecond)
+
+	return APIRequest{
 		URL:        req.URL.String(),
 		Method:     req.Method,
-		Origin:     req.Header.Get("Origin"),
+		Origin:     origin(req),
 		Proto:      req.Proto,
-		RemoteAddr: req.RemoteAddr,
+		RemoteAddr: clientIP(req),
 		Timestamp:  ts,
 		External:   external,
+		RequestID:  requestid.Get(req),
+		UserAgent:  req.UserAgent(),
+		Bot:        isBot(req.UserAgent()),
+	}
+}
+
+// origin returns the origin req was made from, taken from the Referer header
+// for the requests browsers send without an Origin header, such as plain GETs
+func origin(req *http.Request) string {
+	if o := req.Header.Get("Origin"); o != "" {
+		return o
 	}
 
-	// put r on buffered requestChan to take advantage of batch insertion in DB
-	requestChan <- r
+	ref := req.Referer()
+	if ref == "" {
+		return ""
+	}
+
+	return normalizeOrigin(ref)
+}
+
+// enqueue counts r towards its client's request rate and puts it on the queue
+// to be inserted into the store
+func enqueue(r APIRequest) {
+	countTalker(r.RemoteAddr, time.Unix(0, r.Timestamp*int64(time.Millisecond)))
+
+	// drop the request if analytics have not been started or have been
+	// closed, otherwise put r on the buffered queue to take advantage of batch
+	// insertion in DB, unless it is left out of the sample
+	in := running()
+	if in == nil {
+		return
+	}
+
+	select {
+	case <-in.quit:
+		return
+	default:
+	}
+
+	countRequest(r, 1)
+	publish(r)
+
+	if !sample(&r) {
+		return
+	}
+
+	in.requests <- r
+
+	// notify serve() to insert the batch early if the queue is filling up. the
+	// send is non-blocking since a pending notification is as good as two
+	if len(in.requests) >= in.flushAt {
+		select {
+		case in.flush <- struct{}{}:
+		default:
+		}
+	}
 }
 
 // Close exports the abillity to close our db file. Should be called with defer
-// after call to Init() from the same place.
+// after call to Init() from the same place. Any requests still queued are
+// inserted before the db is closed.
 func Close() {
+	mu.Lock()
+	in := current
+	if in == nil || in.err != nil || in.closed {
+		mu.Unlock()
+		return
+	}
+	in.closed = true
+	mu.Unlock()
+
+	in.stop()
+	<-in.done
+
+	closeGeoIP()
+
 	err := store.Close()
 	if err != nil {
-		log.Println(err)
+		logger.Error(err)
+	}
+}
+
+// Ping reports whether analytics is running, so that API requests recorded are
+// stored
+func Ping() error {
+	in := running()
+	if in == nil {
+		return errors.New("analytics has not been started")
+	}
+
+	if in.err != nil {
+		return in.err
+	}
+
+	select {
+	case <-in.done:
+		return errors.New("analytics has been stopped")
+	default:
+		return nil
 	}
 }
 
 // Init creates a db connection, initializes the db with schema and data and
-// sets up the queue/batching channel
-func Init() {
-	var err error
-	store, err = bolt.Open("analytics.db", 0666, nil)
+// sets up the queue/batching channel. Analytics aren't needed to serve
+// requests, so an error initializing them is logged and requests are no
+// longer recorded, rather than stopping the host process. Ping reports the
+// error.
+func Init(opts Options) {
+	err := InitWithContext(context.Background(), opts)
 	if err != nil {
-		log.Fatalln(err)
+		logger.Error("Error initializing analytics, API requests won't be recorded:", err)
+		disable(err)
 	}
+}
 
-	err = store.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte("__requests"))
-		if err != nil {
-			return err
+// disable leaves analytics stopped after they failed to initialize with err,
+// so that requests are dropped rather than queued and queries return err
+func disable(err error) {
+	store = disabledStore{err: err}
+
+	in := &instance{err: err, quit: make(chan struct{}), done: make(chan struct{})}
+	close(in.quit)
+	close(in.done)
+
+	mu.Lock()
+	current = in
+	mu.Unlock()
+}
+
+// InitWithContext is like Init, but returns any error initializing analytics
+// and stops processing the request queue once ctx is cancelled. Requests still
+// queued when ctx is cancelled are inserted, and any recorded afterwards are
+// dropped. Close should still be called to close the db. Analytics already
+// running are closed first.
+func InitWithContext(ctx context.Context, opts Options) error {
+	Close()
+
+	if opts.Retention > 0 {
+		retention = opts.Retention
+	}
+
+	if opts.FlushInterval > 0 {
+		flushInterval = opts.FlushInterval
+	}
+
+	location = time.Local
+	if opts.Location != nil {
+		location = opts.Location
+	}
+
+	sampleRates = [2]sampleRate{{n: opts.SampleEvery}, {n: opts.ExternalSampleEvery}}
+	if opts.ExternalSampleEvery < 1 {
+		sampleRates[1].n = opts.SampleEvery
+	}
+
+	threshold := opts.FlushThreshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.75
+	}
+
+	if opts.Store != nil {
+		store = opts.Store
+	} else {
+		path := "analytics.db"
+		if opts.DataDir != "" {
+			err := os.MkdirAll(opts.DataDir, os.ModeDir|os.ModePerm)
+			if err != nil {
+				return fmt.Errorf("Error creating analytics data directory: %v", err)
+			}
+
+			path = filepath.Join(opts.DataDir, path)
 		}
 
-		_, err = tx.CreateBucketIfNotExists([]byte("__metrics"))
+		s, err := openBoltStore(path)
 		if err != nil {
 			return err
 		}
 
-		return nil
-	})
+		store = s
+	}
+
+	err := seedCounters()
 	if err != nil {
-		log.Fatalln("Error idempotently creating requests bucket in analytics.db:", err)
+		store.Close()
+		return fmt.Errorf("Error counting requests in analytics store for metrics: %v", err)
 	}
 
-	requestChan = make(chan apiRequest, 1024*64*runtime.NumCPU())
+	openGeoIP(opts.GeoIPDatabase)
+	trustedProxies = parseTrustedProxies(opts.TrustedProxies)
 
-	go serve()
+	setBotPatterns(opts.BotPatterns)
 
-	if err != nil {
-		log.Fatalln(err)
+	abuseThreshold = opts.AbuseThreshold
+	onAbuse = opts.OnAbuse
+	resetTalkers()
+
+	in := &instance{
+		requests: make(chan APIRequest, 1024*64*runtime.NumCPU()),
+		flush:    make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
 	}
+	in.flushAt = int(float64(cap(in.requests)) * threshold)
+
+	mu.Lock()
+	current = in
+	mu.Unlock()
+
+	go serve(in)
+
+	// stop serve() when ctx is cancelled, unless Close stops it first
+	go func() {
+		select {
+		case <-ctx.Done():
+			in.stop()
+		case <-in.quit:
+		}
+	}()
+
+	return nil
 }
 
-func serve() {
-	// make timer to notify select to batch request insert from requestChan
-	// interval: 30 seconds
-	apiRequestTimer := time.NewTicker(time.Second * 30)
+// the names of the scheduled jobs inserting queued requests and removing those
+// older than retention
+const (
+	flushJob = "analytics.flush"
+	pruneJob = "analytics.prune"
+)
+
+// serve inserts the requests queued on in until it is stopped
+func serve(in *instance) {
+	// batch insert requests from the queue every flushInterval (default 30
+	// seconds). batch inserts are only ever run as this job, which never
+	// overlaps itself, so an early flush and a timed flush can never insert
+	// the same request twice
+	scheduler.Register(flushJob, scheduler.Every(flushInterval), func() error {
+		return batchInsert(in.requests)
+	})
 
-	// make timer to notify select to remove analytics older than RANGE days
-	// interval: RANGE/2 days
+	// remove analytics older than retention every retention/2, so no request
+	// is kept longer than 1.5x retention
 	// TODO: enable analytics backup service to cloud
-	pruneThreshold := time.Hour * 24 * RANGE
-	pruneDBTimer := time.NewTicker(pruneThreshold / 2)
+	pruneThreshold := retention
+	scheduler.Register(pruneJob, scheduler.Every(pruneThreshold/2), func() error {
+		return batchPrune(pruneThreshold)
+	})
 
 	for {
 		select {
-		case <-apiRequestTimer.C:
-			err := batchInsert(requestChan)
-			if err != nil {
-				log.Println(err)
+		case <-in.flush:
+			// a flush already running empties the queue as far as it can
+			err := scheduler.Run(flushJob)
+			if err != nil && err != scheduler.ErrRunning {
+				logger.Error(err)
 			}
 
-		case <-pruneDBTimer.C:
-			err := batchPrune(pruneThreshold)
-			if err != nil {
-				log.Println(err)
+		case <-in.quit:
+			// wait for any insert or prune in progress to finish
+			scheduler.Unregister(flushJob)
+			scheduler.Unregister(pruneJob)
+
+			// drain the queue completely, including requests which were
+			// queued while a previous batch was being inserted
+			for len(in.requests) > 0 {
+				err := batchInsert(in.requests)
+				if err != nil {
+					logger.Error(err)
+					break
+				}
 			}
 
-		case <-time.After(time.Second * 30):
-			continue
+			close(in.done)
+			return
 		}
 	}
 }
 
-// ChartData returns the map containing decoded javascript needed to chart RANGE
-// days of data by day
-func ChartData() (map[string]interface{}, error) {
-	// set thresholds for today and the RANGE-1 days preceeding
-	times := [RANGE]time.Time{}
-	dates := [RANGE]string{}
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+// ChartMethods are the HTTP methods charted on their own by ChartData, in the
+// order their series are given. Requests made with any other method are
+// charted together as "other".
+var ChartMethods = []string{
+	http.MethodGet,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodDelete,
+}
 
-	ips := [RANGE]map[string]struct{}{}
-	for i := range ips {
-		ips[i] = make(map[string]struct{})
-	}
+// MethodSeries is the number of requests made with an HTTP method in each
+// bucket charted
+type MethodSeries struct {
+	Method string `json:"method"`
+	Data   []int  `json:"data"`
+}
 
-	total := [RANGE]int{}
-	unique := [RANGE]int{}
+// ChartOptions selects the requests charted
+type ChartOptions struct {
+	// ExcludeBots leaves out requests made by crawlers and other bots, so
+	// that only traffic from people is charted
+	ExcludeBots bool
+}
 
-	for i := range times {
-		// subtract 24 * i hours to make days prior
-		dur := time.Duration(24 * i * -1)
-		day := today.Add(time.Hour * dur)
+// ChartData returns the map containing decoded javascript needed to chart the
+// configured retention period of data by day
+func ChartData() (map[string]interface{}, error) {
+	return ChartDataWithOptions(ChartOptions{})
+}
 
-		// day threshold is [...n-1-i, n-1, n]
-		times[len(times)-1-i] = day
-		dates[len(times)-1-i] = day.Format("01/02")
-	}
+// ChartDataWithOptions is like ChartData, charting the requests selected by
+// opts
+func ChartDataWithOptions(opts ChartOptions) (map[string]interface{}, error) {
+	days := retentionDays()
 
-	// get api request analytics and metrics from db
-	var requests = []apiRequest{}
-	currentMetrics := make(map[string]apiMetric)
+	today := startOfDay(time.Now())
+	from := today.AddDate(0, 0, (days-1)*-1)
+	to := today.AddDate(0, 0, 1)
 
-	err := store.Update(func(tx *bolt.Tx) error {
-		m := tx.Bucket([]byte("__metrics"))
-		b := tx.Bucket([]byte("__requests"))
+	return ChartDataGranularWithOptions(from, to, time.Hour*24, opts)
+}
 
-		err := m.ForEach(func(k, v []byte) error {
-			var metric apiMetric
-			err := json.Unmarshal(v, &metric)
-			if err != nil {
-				log.Println("Error decoding api metric json from analytics db:", err)
-				return nil
-			}
+// ChartDataGranular returns the map containing decoded javascript needed to
+// chart requests made from (inclusive) up to to (exclusive), counted in
+// buckets of the given duration starting at from. Buckets with no requests
+// are included with zero counts. Labels are formatted in from's time zone, as
+// a date for buckets of a day or longer, and as a time of day for shorter
+// buckets. Buckets which are a whole number of days follow calendar days in
+// from's time zone, so they stay aligned to midnight across DST changes. The
+// "methods" series count the requests made with each of ChartMethods, then
+// with any other method, in each bucket, and "bot_total" counts the requests
+// made by bots. Daily buckets of days for which only the totals were kept,
+// from before requests were charted this way, have no methods or bots counted.
+func ChartDataGranular(from, to time.Time, bucket time.Duration) (map[string]interface{}, error) {
+	return ChartDataGranularWithOptions(from, to, bucket, ChartOptions{})
+}
 
-			// add metric to currentMetrics map
-			currentMetrics[metric.Date] = metric
+// ChartDataGranularWithOptions is like ChartDataGranular, charting the
+// requests selected by opts
+func ChartDataGranularWithOptions(from, to time.Time, bucket time.Duration, opts ChartOptions) (map[string]interface{}, error) {
+	if bucket <= 0 {
+		return nil, fmt.Errorf("invalid chart bucket duration: %v", bucket)
+	}
 
-			return nil
-		})
-		if err != nil {
-			return err
+	if !to.After(from) {
+		return nil, fmt.Errorf("invalid chart range: %v to %v", from, to)
+	}
+
+	// bounds holds the start of each bucket followed by the end of the last
+	bounds := []time.Time{from}
+	for t := from; t.Before(to); {
+		if bucket%(time.Hour*24) == 0 {
+			t = t.AddDate(0, 0, int(bucket/(time.Hour*24)))
+		} else {
+			t = t.Add(bucket)
 		}
 
-		err = b.ForEach(func(k, v []byte) error {
-			var r apiRequest
-			err := json.Unmarshal(v, &r)
-			if err != nil {
-				log.Println("Error decoding api request json from analytics db:", err)
-				return nil
-			}
+		bounds = append(bounds, t)
+	}
 
-			// append request to requests for analysis if its timestamp is today
-			// or if its day is not already in cache, otherwise delete it
-			d := time.Unix(r.Timestamp/1000, 0)
-			_, inCache := currentMetrics[d.Format("01/02")]
-			if !d.Before(today) || !inCache {
-				requests = append(requests, r)
-			} else {
-				err := b.Delete(k)
-				if err != nil {
-					return err
-				}
-			}
+	n := len(bounds) - 1
 
-			return nil
-		})
-		if err != nil {
-			return err
+	layout := "01/02"
+	if bucket < time.Hour*24 {
+		layout = "15:04"
+		if to.Sub(from) > time.Hour*24 {
+			layout = "01/02 15:04"
 		}
+	}
 
-		return nil
-	})
-	if err != nil {
-		return nil, err
+	dates := make([]string, n)
+	for i := range dates {
+		dates[i] = bounds[i].Format(layout)
 	}
 
-CHECK_REQUEST:
-	for i := range requests {
-		ts := time.Unix(requests[i].Timestamp/1000, 0)
+	ips := make([]map[string]struct{}, n)
+	externalIPs := make([]map[string]struct{}, n)
+	for i := range ips {
+		ips[i] = make(map[string]struct{})
+		externalIPs[i] = make(map[string]struct{})
+	}
 
-		for j := range times {
-			// if on today, there will be no next iteration to set values for
-			// day prior so all valid requests belong to today
-			if j == len(times)-1 {
-				if ts.After(times[j]) || ts.Equal(times[j]) {
-					// do all record keeping
-					total[j]++
+	total := make([]int, n)
+	unique := make([]int, n)
+	externalTotal := make([]int, n)
+	externalUnique := make([]int, n)
+	botTotal := make([]int, n)
+
+	methods := make([]MethodSeries, len(ChartMethods)+1)
+	methodIndex := make(map[string]int, len(ChartMethods))
+	for i, m := range ChartMethods {
+		methods[i] = MethodSeries{Method: m, Data: make([]int, n)}
+		methodIndex[m] = i
+	}
+	other := len(ChartMethods)
+	methods[other] = MethodSeries{Method: "other", Data: make([]int, n)}
 
-					if _, ok := ips[j][requests[i].RemoteAddr]; !ok {
-						unique[j]++
-						ips[j][requests[i].RemoteAddr] = struct{}{}
-					}
+	// seen marks the buckets with any stored requests
+	seen := make([]bool, n)
 
-					continue CHECK_REQUEST
-				}
-			}
+	err := store.Range(from, to, func(r APIRequest) error {
+		ts := time.Unix(0, r.Timestamp*int64(time.Millisecond))
 
-			if ts.Equal(times[j]) {
-				// increment total count for current time threshold (day)
-				total[j]++
+		// find the last bucket starting on or before ts
+		j := sort.Search(n, func(i int) bool {
+			return bounds[i+1].After(ts)
+		})
+		if j >= n {
+			return nil
+		}
 
-				// if no IP found for current threshold, increment unique and record IP
-				if _, ok := ips[j][requests[i].RemoteAddr]; !ok {
-					unique[j]++
-					ips[j][requests[i].RemoteAddr] = struct{}{}
-				}
+		seen[j] = true
 
-				continue CHECK_REQUEST
+		if r.Bot {
+			if opts.ExcludeBots {
+				return nil
 			}
 
-			if ts.Before(times[j]) {
-				// check if older than earliest threshold
-				if j == 0 {
-					continue CHECK_REQUEST
-				}
-
-				// increment total count for previous time threshold (day)
-				total[j-1]++
-
-				// if no IP found for day prior, increment unique and record IP
-				if _, ok := ips[j-1][requests[i].RemoteAddr]; !ok {
-					unique[j-1]++
-					ips[j-1][requests[i].RemoteAddr] = struct{}{}
-				}
-			}
+			botTotal[j] += r.Weight()
 		}
-	}
 
-	// add data to currentMetrics from total and unique
-	for i := range dates {
-		_, ok := currentMetrics[dates[i]]
-		if !ok {
-			m := apiMetric{
-				Date:   dates[i],
-				Total:  total[i],
-				Unique: unique[i],
-			}
+		total[j] += r.Weight()
 
-			currentMetrics[dates[i]] = m
+		m, ok := methodIndex[r.Method]
+		if !ok {
+			m = other
 		}
-	}
+		methods[m].Data[j] += r.Weight()
 
-	// loop through total and unique to see which dates are accounted for and
-	// insert data from metrics array where dates are not
-	err = store.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("__metrics"))
+		// if no IP found for bucket, increment unique and record IP
+		if _, ok := ips[j][r.RemoteAddr]; !ok {
+			unique[j]++
+			ips[j][r.RemoteAddr] = struct{}{}
+		}
 
-		for i := range dates {
-			// populate total and unique with cached data if needed
-			if total[i] == 0 {
-				total[i] = currentMetrics[dates[i]].Total
-			}
+		if !r.External {
+			return nil
+		}
 
-			if unique[i] == 0 {
-				unique[i] = currentMetrics[dates[i]].Unique
-			}
+		externalTotal[j] += r.Weight()
 
-			// check if we need to insert old data into cache - as long as it
-			// is not today's data
-			if dates[i] != today.Format("01/02") {
-				k := []byte(dates[i])
-				if b.Get(k) == nil {
-					// keep zero counts out of cache in case data is added from
-					// other sources
-					if currentMetrics[dates[i]].Total != 0 {
-						v, err := json.Marshal(currentMetrics[dates[i]])
-						if err != nil {
-							return err
-						}
-
-						err = b.Put(k, v)
-						if err != nil {
-							return err
-						}
-					}
-				}
-			}
+		if _, ok := externalIPs[j][r.RemoteAddr]; !ok {
+			externalUnique[j]++
+			externalIPs[j][r.RemoteAddr] = struct{}{}
 		}
 
 		return nil
@@ -328,6 +705,27 @@ CHECK_REQUEST:
 		return nil, err
 	}
 
+	// days charted before requests were kept for them only have their totals
+	ds, ok := store.(dailyStore)
+	if ok && bucket == time.Hour*24 {
+		totals, err := ds.dailyTotals(bounds[0], bounds[n])
+		if err != nil {
+			return nil, err
+		}
+
+		for j := range dates {
+			d, ok := totals[bounds[j].Format(dailyKeyLayout)]
+			if !ok || seen[j] {
+				continue
+			}
+
+			total[j] = d.Total
+			unique[j] = d.Unique
+			externalTotal[j] = d.ExternalTotal
+			externalUnique[j] = d.ExternalUnique
+		}
+	}
+
 	// marshal array counts to js arrays for output to chart
 	jsUnique, err := json.Marshal(unique)
 	if err != nil {
@@ -339,11 +737,53 @@ CHECK_REQUEST:
 		return nil, err
 	}
 
+	jsExternalUnique, err := json.Marshal(externalUnique)
+	if err != nil {
+		return nil, err
+	}
+
+	jsExternalTotal, err := json.Marshal(externalTotal)
+	if err != nil {
+		return nil, err
+	}
+
+	jsMethods, err := json.Marshal(methods)
+	if err != nil {
+		return nil, err
+	}
+
+	jsBotTotal, err := json.Marshal(botTotal)
+	if err != nil {
+		return nil, err
+	}
+
 	return map[string]interface{}{
-		"dates":  dates,
-		"unique": string(jsUnique),
-		"total":  string(jsTotal),
-		"from":   dates[0],
-		"to":     dates[len(dates)-1],
+		"timezone":        from.Format("MST"),
+		"dates":           dates,
+		"unique":          string(jsUnique),
+		"total":           string(jsTotal),
+		"external_unique": string(jsExternalUnique),
+		"external_total":  string(jsExternalTotal),
+		"methods":         string(jsMethods),
+		"bot_total":       string(jsBotTotal),
+		"from":            dates[0],
+		"to":              dates[len(dates)-1],
 	}, nil
 }
+
+// startOfDay returns midnight of t's day in the configured location
+func startOfDay(t time.Time) time.Time {
+	t = t.In(location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+}
+
+// retentionDays returns the number of whole days within the retention period,
+// which is never less than 1 so that today is always charted
+func retentionDays() int {
+	days := int(retention / (time.Hour * 24))
+	if days < 1 {
+		days = 1
+	}
+
+	return days
+}
@@ -0,0 +1,376 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// tempDir creates a new temporary directory to be used as the analytics
+// DataDir, returning its path and a func to clean it up
+func tempDir(tb testing.TB) (string, func()) {
+	dir, err := ioutil.TempDir("", "ponzu-analytics")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCloseFlushesQueuedRequests(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// use a long flush interval so only Close could have inserted the requests
+	Init(Options{FlushInterval: time.Hour, DataDir: dir})
+
+	n := 250
+	for i := 0; i < n; i++ {
+		Record(httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil))
+	}
+
+	Close()
+
+	db, err := bolt.Open(filepath.Join(dir, "analytics.db"), 0666, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var count int
+	err = db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket([]byte("__requests")).Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != n {
+		t.Errorf("Expected %d persisted requests, got: %d", n, count)
+	}
+}
+
+func TestInitWithContextCancel(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := InitWithContext(ctx, Options{FlushInterval: time.Hour, DataDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	n := 10
+	for i := 0; i < n; i++ {
+		Record(httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil))
+	}
+
+	in := running()
+	cancel()
+
+	select {
+	case <-in.done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Expected serve to stop after context was cancelled")
+	}
+
+	// requests recorded after cancelling should be dropped, not block
+	Record(httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil))
+
+	reqs, err := RequestsBetween(time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reqs) != n {
+		t.Errorf("Expected %d persisted requests, got: %d", n, len(reqs))
+	}
+}
+
+func TestInitAgainAfterContext(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := InitWithContext(ctx, Options{FlushInterval: time.Hour, DataDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := running()
+
+	// initializing again stops the first instance; cancelling its context
+	// afterwards must not stop the second
+	err = InitWithContext(context.Background(), Options{FlushInterval: time.Hour, DataDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	select {
+	case <-old.done:
+	default:
+		t.Fatal("Expected the first instance to be stopped")
+	}
+
+	cancel()
+	time.Sleep(time.Millisecond * 50)
+
+	if err := Ping(); err != nil {
+		t.Fatalf("Expected the second instance to be running, got: %v", err)
+	}
+
+	Record(httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil))
+	if n := len(running().requests); n != 1 {
+		t.Errorf("Expected 1 queued request, got: %d", n)
+	}
+}
+
+func TestChartDataGranularHourly(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	Init(Options{FlushInterval: time.Hour, DataDir: dir})
+	defer Close()
+
+	from := time.Date(2017, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour * 24)
+
+	// 2 requests at 01:xx, 1 at 05:xx and 1 outside the range
+	times := []time.Time{
+		from.Add(time.Hour + time.Minute),
+		from.Add(time.Hour + time.Minute*59),
+		from.Add(time.Hour*5 + time.Minute*30),
+		to.Add(time.Minute),
+	}
+	methods := []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodGet}
+
+	reqs := make(chan APIRequest, len(times))
+	for i, ts := range times {
+		reqs <- APIRequest{
+			URL:        "/api/contents?type=Post",
+			Method:     methods[i],
+			RemoteAddr: "127.0.0.1",
+			Timestamp:  ts.UnixNano() / int64(time.Millisecond),
+		}
+	}
+
+	err := batchInsert(reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ChartDataGranular(from, to, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dates := data["dates"].([]string)
+	if len(dates) != 24 {
+		t.Fatalf("Expected 24 hourly buckets, got: %d", len(dates))
+	}
+
+	if dates[0] != "00:00" || dates[23] != "23:00" {
+		t.Errorf("Expected labels 00:00 to 23:00, got: %s to %s", dates[0], dates[23])
+	}
+
+	var total []int
+	err = json.Unmarshal([]byte(data["total"].(string)), &total)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range total {
+		want := 0
+		switch i {
+		case 1:
+			want = 2
+		case 5:
+			want = 1
+		}
+
+		if total[i] != want {
+			t.Errorf("Expected %d requests in bucket %s, got: %d", want, dates[i], total[i])
+		}
+	}
+
+	var series []MethodSeries
+	err = json.Unmarshal([]byte(data["methods"].(string)), &series)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(series) != len(ChartMethods)+1 || series[len(series)-1].Method != "other" {
+		t.Fatalf("Expected a series for each charted method and other, got: %+v", series)
+	}
+
+	byMethod := make(map[string][]int)
+	for _, ms := range series {
+		byMethod[ms.Method] = ms.Data
+	}
+
+	if byMethod["GET"][1] != 1 || byMethod["POST"][1] != 1 || byMethod["other"][5] != 1 || byMethod["PUT"][1] != 0 {
+		t.Errorf("Unexpected requests by method: %v", byMethod)
+	}
+}
+
+func TestChartDataLocationMidnight(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	// a zone behind UTC, where both requests below fall on the same UTC day
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	Init(Options{FlushInterval: time.Hour, DataDir: dir, Location: zone})
+	defer Close()
+
+	now := time.Now().In(zone)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, zone)
+
+	times := []time.Time{
+		today.Add(time.Minute * -1),
+		today.Add(time.Minute),
+	}
+
+	reqs := make(chan APIRequest, len(times))
+	for _, ts := range times {
+		reqs <- APIRequest{
+			URL:        "/api/contents?type=Post",
+			RemoteAddr: "127.0.0.1",
+			Timestamp:  ts.UnixNano() / int64(time.Millisecond),
+		}
+	}
+
+	err := batchInsert(reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ChartData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dates := data["dates"].([]string)
+	var total []int
+	err = json.Unmarshal([]byte(data["total"].(string)), &total)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := len(total) - 1
+	if dates[last] != today.Format("01/02") {
+		t.Errorf("Expected last bucket to be %s, got: %s", today.Format("01/02"), dates[last])
+	}
+
+	if total[last-1] != 1 || total[last] != 1 {
+		t.Errorf("Expected 1 request yesterday and 1 today, got: %d and %d", total[last-1], total[last])
+	}
+}
+
+func TestChartDataMetricsHistory(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+
+	// an analytics.db from before requests were charted themselves, with only
+	// the totals cached for yesterday
+	db, err := bolt.Open(filepath.Join(dir, "analytics.db"), 0666, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("__metrics"))
+		if err != nil {
+			return err
+		}
+
+		j, err := json.Marshal(apiMetric{Date: yesterday.Format("01/02"), Total: 7, Unique: 3, ExternalTotal: 2, ExternalUnique: 1})
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(yesterday.Format("01/02")), j)
+	})
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Init(Options{FlushInterval: time.Hour, DataDir: dir, Location: time.UTC})
+	defer Close()
+
+	reqs := make(chan APIRequest, 1)
+	reqs <- APIRequest{
+		URL:        "/api/contents?type=Post",
+		RemoteAddr: "127.0.0.1",
+		Timestamp:  today.Add(time.Minute).UnixNano() / int64(time.Millisecond),
+	}
+
+	err = batchInsert(reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ChartData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total, unique, externalTotal []int
+	for key, dst := range map[string]*[]int{"total": &total, "unique": &unique, "external_total": &externalTotal} {
+		err = json.Unmarshal([]byte(data[key].(string)), dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	last := len(total) - 1
+	if total[last-1] != 7 || unique[last-1] != 3 || externalTotal[last-1] != 2 {
+		t.Errorf("Expected yesterday's cached totals 7, 3 and 2, got: %d, %d and %d", total[last-1], unique[last-1], externalTotal[last-1])
+	}
+
+	if total[last] != 1 {
+		t.Errorf("Expected 1 request today, got: %d", total[last])
+	}
+
+	err = store.(*boltStore).db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte("__metrics")) != nil {
+			t.Error("Expected the __metrics bucket to be removed once migrated")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the totals go once the day they stand for is older than retention
+	err = store.Prune(today)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	totals, err := store.(*boltStore).dailyTotals(yesterday, today.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(totals) != 0 {
+		t.Errorf("Expected no daily totals once pruned, got: %v", totals)
+	}
+}
@@ -0,0 +1,81 @@
+package analytics
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// subscriber receives requests as they are recorded, counting those dropped
+// while its buffer was full
+type subscriber struct {
+	reqs    chan APIRequest
+	dropped uint64
+}
+
+// subscribers are the subscribers to live requests
+var subscribers = struct {
+	sync.RWMutex
+	m map[*subscriber]struct{}
+}{m: make(map[*subscriber]struct{})}
+
+// Subscription streams requests as they are recorded
+type Subscription struct {
+	s    *subscriber
+	once sync.Once
+}
+
+// Subscribe returns a Subscription to every request recorded from now on,
+// whether or not it is sampled for storage, with a buffer of n requests.
+// Requests recorded while the buffer is full are dropped rather than slowing
+// down Record, so a slow reader misses requests. Close must be called once the
+// Subscription is no longer read.
+func Subscribe(n int) *Subscription {
+	if n < 1 {
+		n = 1
+	}
+
+	s := &subscriber{reqs: make(chan APIRequest, n)}
+
+	subscribers.Lock()
+	subscribers.m[s] = struct{}{}
+	subscribers.Unlock()
+
+	return &Subscription{s: s}
+}
+
+// Requests returns the channel requests are sent on as they are recorded,
+// which is closed by Close
+func (sub *Subscription) Requests() <-chan APIRequest {
+	return sub.s.reqs
+}
+
+// Dropped returns the number of requests dropped so far as the buffer of the
+// Subscription was full
+func (sub *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&sub.s.dropped)
+}
+
+// Close ends the Subscription. It is safe to call more than once
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		subscribers.Lock()
+		delete(subscribers.m, sub.s)
+		subscribers.Unlock()
+
+		close(sub.s.reqs)
+	})
+}
+
+// publish sends r to every subscriber with room for it in its buffer
+func publish(r APIRequest) {
+	subscribers.RLock()
+	defer subscribers.RUnlock()
+
+	for s := range subscribers.m {
+		select {
+		case s.reqs <- r:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package analytics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	m := &memStore{}
+	Init(Options{FlushInterval: time.Hour, Store: m})
+	defer Close()
+
+	sub := Subscribe(2)
+	slow := Subscribe(1)
+	defer slow.Close()
+
+	for i := 0; i < 2; i++ {
+		Record(httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil))
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-sub.Requests():
+			if r.URL != "/api/contents?type=Post" {
+				t.Errorf("Unexpected request streamed: %+v", r)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the recorded request to be streamed")
+		}
+	}
+
+	if slow.Dropped() != 1 {
+		t.Errorf("Expected 1 request dropped for the full subscriber, got: %d", slow.Dropped())
+	}
+
+	sub.Close()
+	sub.Close()
+
+	Record(httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil))
+	if _, ok := <-sub.Requests(); ok {
+		t.Error("Expected no requests after the subscription was closed")
+	}
+}
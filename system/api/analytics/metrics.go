@@ -0,0 +1,163 @@
+package analytics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// counters holds running totals of API requests which are seeded from the
+// store in Init and then updated as requests are recorded, so that the metrics
+// handler never needs to read the store
+var counters = struct {
+	sync.Mutex
+	total    int
+	external int
+	methods  map[string]int
+	statuses map[int]int
+
+	// ips are the unique client IPs seen on day, in the configured location
+	day time.Time
+	ips map[string]struct{}
+
+	// cacheHits and cacheMisses count API responses served from and added to
+	// the response cache since the system started
+	cacheHits   int
+	cacheMisses int
+}{}
+
+// CountCache counts an API response served from the response cache if hit is
+// true, or built and added to it otherwise
+func CountCache(hit bool) {
+	counters.Lock()
+	if hit {
+		counters.cacheHits++
+	} else {
+		counters.cacheMisses++
+	}
+	counters.Unlock()
+}
+
+// resetCounters clears the running totals
+func resetCounters() {
+	counters.Lock()
+	counters.total = 0
+	counters.external = 0
+	counters.methods = make(map[string]int)
+	counters.statuses = make(map[int]int)
+	counters.day = startOfDay(time.Now())
+	counters.ips = make(map[string]struct{})
+	counters.Unlock()
+}
+
+// seedCounters resets the running totals and counts every request in the store
+func seedCounters() error {
+	resetCounters()
+
+	return store.Range(time.Time{}, time.Time{}, func(r APIRequest) error {
+		countRequest(r, r.Weight())
+		return nil
+	})
+}
+
+// countRequest adds n requests like r to the running totals
+func countRequest(r APIRequest, n int) {
+	ts := time.Unix(0, r.Timestamp*int64(time.Millisecond))
+
+	counters.Lock()
+	defer counters.Unlock()
+
+	counters.total += n
+	counters.methods[r.Method] += n
+	counters.statuses[r.Status] += n
+
+	if r.External {
+		counters.external += n
+	}
+
+	day := startOfDay(ts)
+	if day.After(counters.day) {
+		counters.day = day
+		counters.ips = make(map[string]struct{})
+	}
+
+	if day.Equal(counters.day) {
+		counters.ips[hostOnly(r.RemoteAddr)] = struct{}{}
+	}
+}
+
+// MetricsHandler returns an http.Handler which writes API request counters in
+// the Prometheus text exposition format
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(metricsHandler)
+}
+
+func metricsHandler(res http.ResponseWriter, req *http.Request) {
+	counters.Lock()
+	total := counters.total
+	external := counters.external
+	unique := len(counters.ips)
+	if startOfDay(time.Now()).After(counters.day) {
+		unique = 0
+	}
+
+	methods := make(map[string]int, len(counters.methods))
+	for m, n := range counters.methods {
+		methods[m] = n
+	}
+
+	statuses := make(map[int]int, len(counters.statuses))
+	for s, n := range counters.statuses {
+		statuses[s] = n
+	}
+
+	hits, misses := counters.cacheHits, counters.cacheMisses
+	counters.Unlock()
+
+	res.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(res, "# HELP ponzu_api_requests_total Total API requests recorded.")
+	fmt.Fprintln(res, "# TYPE ponzu_api_requests_total counter")
+	fmt.Fprintf(res, "ponzu_api_requests_total %d\n", total)
+
+	fmt.Fprintln(res, "# HELP ponzu_api_external_requests_total Total API requests made to external endpoints.")
+	fmt.Fprintln(res, "# TYPE ponzu_api_external_requests_total counter")
+	fmt.Fprintf(res, "ponzu_api_external_requests_total %d\n", external)
+
+	fmt.Fprintln(res, "# HELP ponzu_api_requests_by_method_total Total API requests by HTTP method.")
+	fmt.Fprintln(res, "# TYPE ponzu_api_requests_by_method_total counter")
+	var names []string
+	for m := range methods {
+		names = append(names, m)
+	}
+	sort.Strings(names)
+	for _, m := range names {
+		fmt.Fprintf(res, "ponzu_api_requests_by_method_total{method=%s} %d\n", strconv.Quote(m), methods[m])
+	}
+
+	fmt.Fprintln(res, "# HELP ponzu_api_requests_by_status_total Total API requests by HTTP response status, 0 if unknown.")
+	fmt.Fprintln(res, "# TYPE ponzu_api_requests_by_status_total counter")
+	var codes []int
+	for s := range statuses {
+		codes = append(codes, s)
+	}
+	sort.Ints(codes)
+	for _, s := range codes {
+		fmt.Fprintf(res, "ponzu_api_requests_by_status_total{status=\"%d\"} %d\n", s, statuses[s])
+	}
+
+	fmt.Fprintln(res, "# HELP ponzu_api_unique_ips Unique client IPs making API requests today.")
+	fmt.Fprintln(res, "# TYPE ponzu_api_unique_ips gauge")
+	fmt.Fprintf(res, "ponzu_api_unique_ips %d\n", unique)
+
+	fmt.Fprintln(res, "# HELP ponzu_api_cache_hits_total API responses served from the response cache.")
+	fmt.Fprintln(res, "# TYPE ponzu_api_cache_hits_total counter")
+	fmt.Fprintf(res, "ponzu_api_cache_hits_total %d\n", hits)
+
+	fmt.Fprintln(res, "# HELP ponzu_api_cache_misses_total API responses built and added to the response cache.")
+	fmt.Fprintln(res, "# TYPE ponzu_api_cache_misses_total counter")
+	fmt.Fprintf(res, "ponzu_api_cache_misses_total %d\n", misses)
+}
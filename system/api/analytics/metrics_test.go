@@ -0,0 +1,43 @@
+package analytics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	Init(Options{FlushInterval: time.Hour, DataDir: dir})
+
+	// requests stored before Init should be counted as well as new ones
+	Record(httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil))
+	Close()
+
+	Init(Options{FlushInterval: time.Hour, DataDir: dir})
+	defer Close()
+
+	Record(httptest.NewRequest(http.MethodGet, "/api/content/external?type=Post", nil))
+	RecordResponse(httptest.NewRequest(http.MethodPost, "/api/content/external?type=Post", nil), http.StatusCreated)
+
+	res := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+
+	body := res.Body.String()
+	for _, want := range []string{
+		"ponzu_api_requests_total 3\n",
+		"ponzu_api_external_requests_total 2\n",
+		`ponzu_api_requests_by_method_total{method="GET"} 2` + "\n",
+		`ponzu_api_requests_by_method_total{method="POST"} 1` + "\n",
+		`ponzu_api_requests_by_status_total{status="201"} 1` + "\n",
+		"ponzu_api_unique_ips 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package analytics
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RequestsBetween returns the APIRequest records stored in the analytics db
+// with a timestamp from (inclusive) up to to (exclusive)
+func RequestsBetween(from, to time.Time) ([]APIRequest, error) {
+	var requests = []APIRequest{}
+	err := store.Range(from, to, func(r APIRequest) error {
+		requests = append(requests, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// EndpointStat is the number of requests made to an API endpoint, normalized
+// by normalizeEndpoint
+type EndpointStat struct {
+	Endpoint string `json:"endpoint"`
+	Count    int    `json:"count"`
+}
+
+// TopEndpoints returns the most requested API endpoints between from and to,
+// sorted by request count in descending order. A limit of 0 or less returns
+// every endpoint requested within the range.
+func TopEndpoints(from, to time.Time, limit int) ([]EndpointStat, error) {
+	counts := make(map[string]int)
+	err := store.Range(from, to, func(r APIRequest) error {
+		counts[normalizeEndpoint(r.URL)] += r.Weight()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var stats = []EndpointStat{}
+	for endpoint, count := range counts {
+		stats = append(stats, EndpointStat{Endpoint: endpoint, Count: count})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count == stats[j].Count {
+			return stats[i].Endpoint < stats[j].Endpoint
+		}
+
+		return stats[i].Count > stats[j].Count
+	})
+
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+
+	return stats, nil
+}
+
+// OriginStat is the number of requests made from an origin, such as a site
+// embedding content from the API
+type OriginStat struct {
+	Origin string `json:"origin"`
+	Count  int    `json:"count"`
+}
+
+// directOrigin is the origin of requests made without an Origin or Referer,
+// such as those from servers and apps rather than browsers
+const directOrigin = "(direct)"
+
+// OriginBreakdown returns the origins which made the most API requests between
+// from and to, sorted by request count in descending order. Requests without
+// an origin are counted as "(direct)". A limit of 0 or less returns every
+// origin which made requests within the range.
+func OriginBreakdown(from, to time.Time, limit int) ([]OriginStat, error) {
+	counts := make(map[string]int)
+	err := store.Range(from, to, func(r APIRequest) error {
+		counts[normalizeOrigin(r.Origin)] += r.Weight()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var stats = []OriginStat{}
+	for origin, count := range counts {
+		stats = append(stats, OriginStat{Origin: origin, Count: count})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count == stats[j].Count {
+			return stats[i].Origin < stats[j].Origin
+		}
+
+		return stats[i].Count > stats[j].Count
+	})
+
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+
+	return stats, nil
+}
+
+// normalizeOrigin returns the scheme and host of origin, which may be a full
+// URL from a Referer header, and "(direct)" if it is empty, i.e.
+// https://example.com/blog/post?id=1 => https://example.com
+// https://Example.com => https://example.com
+func normalizeOrigin(origin string) string {
+	origin = strings.TrimSpace(origin)
+	if origin == "" {
+		return directOrigin
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return origin
+	}
+
+	return u.Scheme + "://" + strings.ToLower(u.Host)
+}
+
+// StatusBreakdown returns the number of API requests made between from and to
+// responded to with each HTTP status code. Requests recorded without a status
+// are counted under 0.
+func StatusBreakdown(from, to time.Time) (map[int]int, error) {
+	statuses := make(map[int]int)
+	err := store.Range(from, to, func(r APIRequest) error {
+		statuses[r.Status] += r.Weight()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// LatencyPercentiles returns the 50th, 95th and 99th percentile durations in
+// milliseconds of the API requests made between from and to. Only requests
+// recorded with a duration, i.e. by RecordWithDuration, are included. If there
+// are none, all percentiles are 0.
+func LatencyPercentiles(from, to time.Time) (p50, p95, p99 float64, err error) {
+	var durations []float64
+	err = store.Range(from, to, func(r APIRequest) error {
+		if r.DurationMs > 0 {
+			durations = append(durations, r.DurationMs)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	sort.Float64s(durations)
+
+	return percentile(durations, 50), percentile(durations, 95), percentile(durations, 99), nil
+}
+
+// percentile returns the pth percentile of the sorted values, interpolating
+// linearly between the closest ranks
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+
+	frac := rank - float64(lower)
+	return sorted[lower] + (sorted[lower+1]-sorted[lower])*frac
+}
+
+// normalizeEndpoint strips the query string from a request URL, keeping only
+// the content type for the content API endpoints so that requests for any item
+// or page of a type are counted together, i.e.
+// /api/contents?type=Post&count=5 => /api/contents?type=Post
+// /api/content?type=Post&id=2 => /api/content?type=Post
+// /api/content?slug=hello-world => /api/content?slug=*
+// /api/uploads/2017/01/file.jpg => /api/uploads/*
+func normalizeEndpoint(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	q := u.Query()
+	switch {
+	case strings.HasPrefix(u.Path, "/api/uploads/"):
+		return "/api/uploads/*"
+
+	case u.Path == "/api/contents", u.Path == "/api/content", u.Path == "/api/content/external":
+		if t := q.Get("type"); t != "" {
+			return u.Path + "?type=" + t
+		}
+
+		if q.Get("slug") != "" {
+			return u.Path + "?slug=*"
+		}
+	}
+
+	return u.Path
+}
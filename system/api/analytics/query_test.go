@@ -0,0 +1,175 @@
+package analytics
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// insertRequests stores n requests spread evenly over the duration leading up
+// to now, directly through batchInsert
+func insertRequests(tb testing.TB, n int, over time.Duration) {
+	now := time.Now()
+	step := over / time.Duration(n)
+
+	reqs := make(chan APIRequest, n)
+	for i := 0; i < n; i++ {
+		ts := now.Add(step*time.Duration(i-n) + step/2)
+		reqs <- APIRequest{
+			URL:        "/api/contents?type=Post",
+			Method:     "GET",
+			RemoteAddr: "127.0.0.1",
+			Timestamp:  ts.UnixNano() / int64(time.Millisecond),
+		}
+	}
+
+	err := batchInsert(reqs)
+	if err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func TestRequestsBetween(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	Init(Options{FlushInterval: time.Hour, DataDir: dir})
+	defer Close()
+
+	insertRequests(t, 100, time.Hour*100)
+
+	now := time.Now()
+	reqs, err := RequestsBetween(now.Add(time.Hour*-10), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reqs) != 10 {
+		t.Errorf("Expected 10 requests in the last 10 hours, got: %d", len(reqs))
+	}
+}
+
+// benchmarkChartRange compares reading the last day of requests out of 30 days
+// of data using a full bucket scan vs seeking on the timestamp keys
+func benchmarkChartRange(b *testing.B, seek bool) {
+	dir, cleanup := tempDir(b)
+	defer cleanup()
+
+	Init(Options{FlushInterval: time.Hour, DataDir: dir})
+	defer Close()
+
+	insertRequests(b, 30*1000, time.Hour*24*30)
+	from := time.Now().Add(time.Hour * -24)
+	min := from.UnixNano() / int64(time.Millisecond)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var count int
+		var err error
+		if seek {
+			err = store.Range(from, time.Time{}, func(r APIRequest) error {
+				count++
+				return nil
+			})
+		} else {
+			err = store.(*boltStore).db.View(func(tx *bolt.Tx) error {
+				return tx.Bucket([]byte("__requests")).ForEach(func(k, v []byte) error {
+					var r APIRequest
+					err := json.Unmarshal(v, &r)
+					if err != nil {
+						return err
+					}
+
+					if r.Timestamp >= min {
+						count++
+					}
+
+					return nil
+				})
+			})
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkChartRangeScan(b *testing.B) { benchmarkChartRange(b, false) }
+
+func BenchmarkChartRangeSeek(b *testing.B) { benchmarkChartRange(b, true) }
+
+func TestPercentile(t *testing.T) {
+	values := make([]float64, 101)
+	for i := range values {
+		values[i] = float64(i)
+	}
+
+	cases := map[float64]float64{50: 50, 95: 95, 99: 99, 100: 100, 0: 0}
+	for p, want := range cases {
+		got := percentile(values, p)
+		if got != want {
+			t.Errorf("Expected p%v to be %v, got: %v", p, want, got)
+		}
+	}
+
+	if got := percentile([]float64{10, 20}, 50); got != 15 {
+		t.Errorf("Expected interpolated p50 of 15, got: %v", got)
+	}
+
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("Expected p50 of no values to be 0, got: %v", got)
+	}
+}
+
+func TestOriginBreakdown(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	Init(Options{FlushInterval: time.Hour, DataDir: dir})
+	defer Close()
+
+	now := time.Now()
+	origins := []string{
+		"https://partner.example",
+		"https://Partner.example",
+		"https://partner.example/embed/widget.html",
+		"",
+		"",
+		"https://other.example",
+	}
+
+	reqs := make(chan APIRequest, len(origins))
+	for _, o := range origins {
+		reqs <- APIRequest{
+			URL:       "/api/contents?type=Post",
+			Origin:    o,
+			Timestamp: now.Add(time.Minute*-1).UnixNano() / int64(time.Millisecond),
+		}
+	}
+
+	err := batchInsert(reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := OriginBreakdown(now.Add(time.Hour*-1), now, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []OriginStat{
+		{Origin: "https://partner.example", Count: 3},
+		{Origin: "(direct)", Count: 2},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("Expected %d origins, got: %+v", len(want), stats)
+	}
+
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("Expected %+v, got: %+v", want[i], stats[i])
+		}
+	}
+}
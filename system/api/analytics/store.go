@@ -0,0 +1,405 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/boltdb/bolt"
+)
+
+// Store is the storage backend for API request analytics. By default requests
+// are stored in a local BoltDB file, analytics.db, but any Store can be set in
+// Options to keep them elsewhere.
+type Store interface {
+	// Insert stores a batch of requests
+	Insert(reqs []APIRequest) error
+
+	// Range calls fn for every stored request with a timestamp from
+	// (inclusive) up to to (exclusive), in timestamp order. A zero from starts
+	// at the oldest request and a zero to continues through the newest. If fn
+	// returns an error, Range stops and returns it.
+	Range(from, to time.Time, fn func(r APIRequest) error) error
+
+	// Prune removes every stored request with a timestamp before before
+	Prune(before time.Time) error
+
+	// Close releases any resources held by the Store
+	Close() error
+}
+
+// dailyKeyLayout formats the day of a daily total as its key, so that keys
+// sort by day
+const dailyKeyLayout = "2006-01-02"
+
+// apiMetric is the daily total of requests cached in the __metrics bucket by
+// earlier versions
+type apiMetric struct {
+	Date           string `json:"date"`
+	Total          int    `json:"total"`
+	Unique         int    `json:"unique"`
+	ExternalTotal  int    `json:"external_total"`
+	ExternalUnique int    `json:"external_unique"`
+}
+
+// dailyTotal counts the requests of a day for which only the totals are kept,
+// rather than the requests themselves
+type dailyTotal struct {
+	Total          int `json:"total"`
+	Unique         int `json:"unique"`
+	ExternalTotal  int `json:"external_total"`
+	ExternalUnique int `json:"external_unique"`
+}
+
+// dailyStore is implemented by Stores which keep the daily totals of days
+// whose requests aren't stored, to chart them in place of the requests
+type dailyStore interface {
+	dailyTotals(from, to time.Time) (map[string]dailyTotal, error)
+}
+
+// disabledStore is used in place of a Store once analytics fail to
+// initialize, returning the error they failed with
+type disabledStore struct {
+	err error
+}
+
+func (s disabledStore) Insert(reqs []APIRequest) error { return s.err }
+
+func (s disabledStore) Range(from, to time.Time, fn func(r APIRequest) error) error { return s.err }
+
+func (s disabledStore) Prune(before time.Time) error { return s.err }
+
+func (s disabledStore) Close() error { return nil }
+
+// boltStore is the default Store, keeping requests in a __requests bucket
+// keyed by requestKey so that they are ordered by timestamp
+type boltStore struct {
+	db *bolt.DB
+}
+
+// openBoltStore opens or creates the bolt db at path, initializing its schema
+func openBoltStore(path string) (*boltStore, error) {
+	db, err := bolt.Open(path, 0666, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &boltStore{db: db}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte("__requests"))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error idempotently creating requests bucket in analytics.db: %v", err)
+	}
+
+	err = s.migrateRequestKeys()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error migrating requests in analytics.db to timestamp keys: %v", err)
+	}
+
+	err = s.migrateMetrics(time.Now())
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("Error migrating metrics in analytics.db to daily totals: %v", err)
+	}
+
+	return s, nil
+}
+
+// Insert is effectively a specialized version of SetContentMulti from the db
+// package, iterating over a []APIRequest instead of []url.Values
+func (s *boltStore) Insert(reqs []APIRequest) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("__requests"))
+		if err != nil {
+			return err
+		}
+
+		for _, apiReq := range reqs {
+			// get the next available ID to keep keys unique for requests made
+			// within the same millisecond
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			j, err := json.Marshal(apiReq)
+			if err != nil {
+				return err
+			}
+
+			err = b.Put(requestKey(apiReq.Timestamp, id), j)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Range seeks directly to the first matching key rather than scanning the
+// entire bucket
+func (s *boltStore) Range(from, to time.Time, fn func(r APIRequest) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__requests"))
+		if b == nil {
+			return bolt.ErrBucketNotFound
+		}
+
+		// the zero time is before the unix epoch and has no valid key
+		var min []byte
+		if !from.IsZero() {
+			min = timestampKey(from)
+		}
+
+		var max []byte
+		if !to.IsZero() {
+			max = timestampKey(to)
+		}
+
+		c := b.Cursor()
+		k, v := c.First()
+		if min != nil {
+			k, v = c.Seek(min)
+		}
+
+		for ; k != nil; k, v = c.Next() {
+			if max != nil && bytes.Compare(k, max) >= 0 {
+				break
+			}
+
+			var r APIRequest
+			err := json.Unmarshal(v, &r)
+			if err != nil {
+				logger.Error("Error decoding api request json from analytics db:", err)
+				continue
+			}
+
+			err = fn(r)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Prune deletes requests from the first key, since keys are ordered by
+// timestamp and every request before before is found in a contiguous range
+func (s *boltStore) Prune(before time.Time) error {
+	cutoff := timestampKey(before)
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__requests"))
+
+		var keys [][]byte
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.Next() {
+			keys = append(keys, k)
+		}
+
+		for i := range keys {
+			err := b.Delete(keys[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		// daily totals are kept as long as the requests they stand in for,
+		// and dropped with their bucket once the last one is pruned
+		d := tx.Bucket([]byte("__daily"))
+		if d == nil {
+			return nil
+		}
+
+		day := []byte(before.Format(dailyKeyLayout))
+		keys = nil
+		c = d.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, day) < 0; k, _ = c.Next() {
+			keys = append(keys, k)
+		}
+
+		for i := range keys {
+			err := d.Delete(keys[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		if d.Stats().KeyN == len(keys) {
+			return tx.DeleteBucket([]byte("__daily"))
+		}
+
+		return nil
+	})
+}
+
+// dailyTotals returns the daily totals kept in place of requests from from up
+// to to, keyed by their day formatted with dailyKeyLayout
+func (s *boltStore) dailyTotals(from, to time.Time) (map[string]dailyTotal, error) {
+	totals := make(map[string]dailyTotal)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__daily"))
+		if b == nil {
+			return nil
+		}
+
+		min := []byte(from.Format(dailyKeyLayout))
+		max := []byte(to.Format(dailyKeyLayout))
+
+		c := b.Cursor()
+		for k, v := c.Seek(min); k != nil && bytes.Compare(k, max) < 0; k, v = c.Next() {
+			var d dailyTotal
+			err := json.Unmarshal(v, &d)
+			if err != nil {
+				logger.Error("Error decoding daily total json from analytics db:", err)
+				continue
+			}
+
+			totals[string(k)] = d
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// Close closes the bolt db
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+// migrateRequestKeys re-keys any requests stored with the previous sequential
+// string keys to the timestamp ordered keys made by requestKey
+func (s *boltStore) migrateRequestKeys() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__requests"))
+
+		var keys, values [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			if len(k) != 16 {
+				keys = append(keys, k)
+				values = append(values, v)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := range keys {
+			var r APIRequest
+			err := json.Unmarshal(values[i], &r)
+			if err != nil {
+				return err
+			}
+
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			err = b.Put(requestKey(r.Timestamp, id), values[i])
+			if err != nil {
+				return err
+			}
+
+			err = b.Delete(keys[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// migrateMetrics moves the daily totals cached in the __metrics bucket by
+// earlier versions, which charted them in place of the requests of past days,
+// to the __daily bucket, and removes __metrics. The cached days have no year,
+// so each is taken to be the latest one on or before now.
+func (s *boltStore) migrateMetrics(now time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		m := tx.Bucket([]byte("__metrics"))
+		if m == nil {
+			return nil
+		}
+
+		d, err := tx.CreateBucketIfNotExists([]byte("__daily"))
+		if err != nil {
+			return err
+		}
+
+		now = now.UTC()
+		err = m.ForEach(func(k, v []byte) error {
+			var metric apiMetric
+			err := json.Unmarshal(v, &metric)
+			if err != nil {
+				logger.Error("Error decoding api metric json from analytics db:", err)
+				return nil
+			}
+
+			md, err := time.Parse("01/02", metric.Date)
+			if err != nil {
+				logger.Error("Error parsing api metric date from analytics db:", metric.Date, err)
+				return nil
+			}
+
+			day := time.Date(now.Year(), md.Month(), md.Day(), 0, 0, 0, 0, time.UTC)
+			if day.After(now) {
+				day = day.AddDate(-1, 0, 0)
+			}
+
+			j, err := json.Marshal(dailyTotal{
+				Total:          metric.Total,
+				Unique:         metric.Unique,
+				ExternalTotal:  metric.ExternalTotal,
+				ExternalUnique: metric.ExternalUnique,
+			})
+			if err != nil {
+				return err
+			}
+
+			return d.Put([]byte(day.Format(dailyKeyLayout)), j)
+		})
+		if err != nil {
+			return err
+		}
+
+		return tx.DeleteBucket([]byte("__metrics"))
+	})
+}
+
+// requestKey creates a __requests bucket key as the big-endian millisecond
+// timestamp followed by the big-endian sequence id, so that keys sort by time
+func requestKey(ts int64, id uint64) []byte {
+	k := make([]byte, 16)
+	binary.BigEndian.PutUint64(k[:8], uint64(ts))
+	binary.BigEndian.PutUint64(k[8:], id)
+
+	return k
+}
+
+// timestampKey creates an 8 byte key prefix to Seek to the first request made
+// at or after t
+func timestampKey(t time.Time) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()/int64(time.Millisecond)))
+
+	return k
+}
@@ -0,0 +1,112 @@
+package analytics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore is a Store keeping requests in memory, used to test that Init uses
+// a Store set in Options
+type memStore struct {
+	sync.Mutex
+	reqs   []APIRequest
+	closed bool
+}
+
+func (m *memStore) Insert(reqs []APIRequest) error {
+	m.Lock()
+	defer m.Unlock()
+
+	m.reqs = append(m.reqs, reqs...)
+	return nil
+}
+
+func (m *memStore) Range(from, to time.Time, fn func(r APIRequest) error) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, r := range m.reqs {
+		ts := time.Unix(0, r.Timestamp*int64(time.Millisecond))
+		if ts.Before(from) || (!to.IsZero() && !ts.Before(to)) {
+			continue
+		}
+
+		err := fn(r)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *memStore) Prune(before time.Time) error { return nil }
+
+func (m *memStore) Close() error {
+	m.closed = true
+	return nil
+}
+
+func TestCustomStore(t *testing.T) {
+	m := &memStore{}
+	Init(Options{FlushInterval: time.Hour, Store: m})
+
+	n := 5
+	for i := 0; i < n; i++ {
+		Record(httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil))
+	}
+
+	Close()
+
+	if len(m.reqs) != n {
+		t.Errorf("Expected %d requests in custom store, got: %d", n, len(m.reqs))
+	}
+
+	if !m.closed {
+		t.Error("Expected custom store to be closed")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	m := &memStore{}
+	Init(Options{FlushInterval: time.Hour, Store: m, SampleEvery: 10, ExternalSampleEvery: 1})
+
+	for i := 0; i < 100; i++ {
+		Record(httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil))
+	}
+
+	for i := 0; i < 5; i++ {
+		Record(httptest.NewRequest(http.MethodPost, "/api/content/external?type=Post", nil))
+	}
+
+	Close()
+
+	if len(m.reqs) != 15 {
+		t.Fatalf("Expected 10 sampled and 5 external requests stored, got: %d", len(m.reqs))
+	}
+
+	data, err := ChartData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total, external []int
+	if err := json.Unmarshal([]byte(data["total"].(string)), &total); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(data["external_total"].(string)), &external); err != nil {
+		t.Fatal(err)
+	}
+
+	if today := total[len(total)-1]; today != 105 {
+		t.Errorf("Expected the charted total to be scaled to 105, got: %d", today)
+	}
+
+	if today := external[len(external)-1]; today != 5 {
+		t.Errorf("Expected 5 external requests charted, got: %d", today)
+	}
+}
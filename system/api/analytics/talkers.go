@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// talkerMinutes is the number of most recent minutes for which per-IP request
+// counts are kept in memory. TopTalkers reads longer windows from the db.
+const talkerMinutes = 60
+
+// IPStat is the number of requests made by a client IP address
+type IPStat struct {
+	IP    string `json:"ip_address"`
+	Count int    `json:"count"`
+}
+
+var (
+	// talkers holds per-IP request counts keyed by unix minute, which are
+	// updated by Record so that recent request rates are known without
+	// reading the db or waiting for a batch insert
+	talkers = struct {
+		sync.Mutex
+		minutes map[int64]map[string]int
+	}{minutes: make(map[int64]map[string]int)}
+
+	// abuseThreshold and onAbuse are set from Options in Init
+	abuseThreshold int
+	onAbuse        func(ip string, count int)
+)
+
+// countTalker increments the count for the IP of addr in the minute of ts, and
+// calls onAbuse the first time it exceeds abuseThreshold requests in a minute
+func countTalker(addr string, ts time.Time) {
+	ip := hostOnly(addr)
+	minute := ts.Unix() / 60
+
+	talkers.Lock()
+	counts, ok := talkers.minutes[minute]
+	if !ok {
+		counts = make(map[string]int)
+		talkers.minutes[minute] = counts
+
+		// a new minute has started, so drop minutes no longer kept
+		for m := range talkers.minutes {
+			if m <= minute-talkerMinutes {
+				delete(talkers.minutes, m)
+			}
+		}
+	}
+
+	counts[ip]++
+	count := counts[ip]
+	talkers.Unlock()
+
+	if onAbuse != nil && abuseThreshold > 0 && count == abuseThreshold+1 {
+		onAbuse(ip, count)
+	}
+}
+
+// resetTalkers clears all in-memory request counts
+func resetTalkers() {
+	talkers.Lock()
+	talkers.minutes = make(map[int64]map[string]int)
+	talkers.Unlock()
+}
+
+// TopTalkers returns the client IPs which made the most API requests within
+// the window of time leading up to now, sorted by request count in descending
+// order. A limit of 0 or less returns every IP seen within the window. Windows
+// up to an hour are counted from memory by the minute, including requests not
+// yet inserted into the db, otherwise only the window is read from the db.
+func TopTalkers(window time.Duration, limit int) ([]IPStat, error) {
+	now := time.Now()
+	counts := make(map[string]int)
+
+	if window <= time.Minute*talkerMinutes {
+		since := now.Add(window*-1).Unix() / 60
+
+		talkers.Lock()
+		for m, ips := range talkers.minutes {
+			if m < since {
+				continue
+			}
+
+			for ip, n := range ips {
+				counts[ip] += n
+			}
+		}
+		talkers.Unlock()
+	} else {
+		err := store.Range(now.Add(window*-1), time.Time{}, func(r APIRequest) error {
+			counts[hostOnly(r.RemoteAddr)] += r.Weight()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var stats = []IPStat{}
+	for ip, count := range counts {
+		stats = append(stats, IPStat{IP: ip, Count: count})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count == stats[j].Count {
+			return stats[i].IP < stats[j].IP
+		}
+
+		return stats[i].Count > stats[j].Count
+	})
+
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+
+	return stats, nil
+}
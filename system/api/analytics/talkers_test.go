@@ -0,0 +1,52 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTopTalkers(t *testing.T) {
+	resetTalkers()
+	defer resetTalkers()
+
+	var abusers []string
+	abuseThreshold = 3
+	onAbuse = func(ip string, count int) {
+		abusers = append(abusers, ip)
+	}
+	defer func() {
+		abuseThreshold = 0
+		onAbuse = nil
+	}()
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		countTalker("10.0.0.1:5000", now)
+	}
+	countTalker("10.0.0.2:5000", now)
+	countTalker("10.0.0.2:5001", now)
+
+	// outside of the window, and too long ago to be kept
+	countTalker("10.0.0.3:5000", now.Add(time.Minute*-2))
+	countTalker("10.0.0.4:5000", now.Add(time.Minute*talkerMinutes*-2))
+
+	stats, err := TopTalkers(time.Minute, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []IPStat{{IP: "10.0.0.1", Count: 5}, {IP: "10.0.0.2", Count: 2}}
+	if len(stats) != len(want) {
+		t.Fatalf("Expected %v, got: %v", want, stats)
+	}
+
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("Expected %v at %d, got: %v", want[i], i, stats[i])
+		}
+	}
+
+	if len(abusers) != 1 || abusers[0] != "10.0.0.1" {
+		t.Errorf("Expected abuse callback once for 10.0.0.1, got: %v", abusers)
+	}
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// keyLimits limits the rate of requests made with each API key which has a
+// rate limit set
+var keyLimits = newLimiter()
+
+// apiKeyKey is the context key of the APIKey a request was made with
+type apiKeyKey struct{}
+
+// keyAllows reports whether the API key req was made with grants scope on
+// content of type t. Requests made without a key were already allowed by
+// KeyAuth, so they are allowed here too.
+func keyAllows(req *http.Request, scope, t string) bool {
+	k, ok := req.Context().Value(apiKeyKey{}).(db.APIKey)
+	return !ok || k.Allows(scope, t)
+}
+
+// requestKey returns the API key sent with req in an "Authorization: Bearer"
+// or "X-API-Key" header, if any
+func requestKey(req *http.Request) string {
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+
+	return strings.TrimSpace(req.Header.Get("X-API-Key"))
+}
+
+// KeyAuth wraps a HandlerFunc to check the API key sent with a request grants
+// scope on the requested content type, and is within its rate limit. Requests
+// without a key are only allowed if API keys are not required in the config,
+// and never for the audit scope.
+func KeyAuth(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		key := requestKey(req)
+		if key == "" {
+			if required, _ := db.ConfigCache("api_key_required").(bool); required || scope == db.ScopeAudit {
+				res.Header().Set("WWW-Authenticate", `Bearer realm="ponzu"`)
+				res.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		k, err := db.APIKeyFor(key)
+		if err != nil {
+			if err != db.ErrNoAPIKeyExists {
+				logger.For(req).Error("Error finding API key:", err)
+			}
+
+			res.Header().Set("WWW-Authenticate", `Bearer realm="ponzu", error="invalid_token"`)
+			res.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if !k.Allows(scope, req.URL.Query().Get("type")) {
+			res.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if k.RateLimit > 0 {
+			ok, wait := keyLimits.allow(fmt.Sprintf("%d", k.ID), float64(k.RateLimit)/60, k.RateLimit, time.Now())
+			if !ok {
+				res.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+				res.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		// handlers serving content of other types than the requested one,
+		// such as included references, check the key allows them too
+		next.ServeHTTP(res, req.WithContext(context.WithValue(req.Context(), apiKeyKey{}, k)))
+	})
+}
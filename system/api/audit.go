@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+const (
+	// defaultAuditLimit is the number of audit log entries returned if the
+	// request doesn't give a limit
+	defaultAuditLimit = 100
+	// maxAuditLimit is the most audit log entries returned by one request
+	maxAuditLimit = 1000
+)
+
+// auditHandler writes entries of the audit log for collection by external
+// systems such as a SIEM, and requires an audit scoped API key. Entries are
+// filtered by user, action, target, request_id, and since/until in unix
+// milliseconds. To follow the log, a collector passes the id of the last entry
+// it has seen as after (starting at 0), and is given the entries made since
+// then, oldest first. Without after, the newest entries are given first.
+func auditHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := req.URL.Query()
+	aq := db.AuditQuery{
+		User:      q.Get("user"),
+		Action:    q.Get("action"),
+		Target:    q.Get("target"),
+		RequestID: q.Get("request_id"),
+		Limit:     defaultAuditLimit,
+	}
+
+	var err error
+	parse := func(name string, dst *int64) {
+		if v := q.Get(name); v != "" && err == nil {
+			*dst, err = strconv.ParseInt(v, 10, 64)
+		}
+	}
+
+	var after, limit int64
+	parse("after", &after)
+	parse("limit", &limit)
+	parse("since", &aq.Since)
+	parse("until", &aq.Until)
+	if err != nil || after < 0 || limit < 0 {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := q["after"]; ok {
+		aq.Follow, aq.After = true, int(after)
+	}
+
+	if limit > 0 {
+		aq.Limit = int(limit)
+	}
+	if aq.Limit > maxAuditLimit {
+		aq.Limit = maxAuditLimit
+	}
+
+	entries, err := db.AuditLog(aq)
+	if err != nil {
+		logger.For(req).Error("Error reading audit log:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	j, err := json.Marshal(map[string][]db.AuditEntry{"data": entries})
+	if err != nil {
+		logger.For(req).Error("Failed to encode audit log to JSON:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendData(res, req, j)
+}
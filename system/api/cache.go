@@ -0,0 +1,243 @@
+package api
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/api/analytics"
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+const (
+	// defaultCacheEntries is the number of responses kept in the response
+	// cache, if response_cache_entries is not configured
+	defaultCacheEntries = 1000
+	// defaultCacheSeconds is how long a response is kept in the response
+	// cache, if response_cache_seconds is not configured
+	defaultCacheSeconds = 60
+
+	// cacheHeader is the response header which tells whether a response came
+	// from the response cache. Admin users can send it in a request with the
+	// value "bypass" to skip the cache.
+	cacheHeader = "X-Ponzu-Cache"
+)
+
+// cachedResponse is a response in the response cache, with the headers set by
+// the handler which built it
+type cachedResponse struct {
+	key     string
+	header  http.Header
+	body    []byte
+	version uint64
+	expires time.Time
+}
+
+// responses is the response cache, which keeps the most recently used
+// responses, with the least recently used at the back of lru
+var responses = struct {
+	sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}{lru: list.New(), entries: make(map[string]*list.Element)}
+
+// cacheKey returns the key of the response to req in the response cache. The
+// query is encoded with its keys sorted, so the same query in any order shares
+// a response, and the Accept and Accept-Language headers are included as they
+// choose the format and locale.
+func cacheKey(req *http.Request) string {
+	return req.URL.Path + "?" + req.URL.Query().Encode() + "|" + req.Header.Get("Accept") + "|" + req.Header.Get("Accept-Language")
+}
+
+// cachedResponseFor returns the cached response for key if there is one which
+// was built from the current content and has not expired
+func cachedResponseFor(key string) (*cachedResponse, bool) {
+	responses.Lock()
+	defer responses.Unlock()
+
+	e, ok := responses.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c := e.Value.(*cachedResponse)
+	if c.version != db.ContentVersion() || time.Now().After(c.expires) {
+		responses.lru.Remove(e)
+		delete(responses.entries, key)
+		return nil, false
+	}
+
+	responses.lru.MoveToFront(e)
+	return c, true
+}
+
+// cacheResponse adds c to the response cache, removing the least recently used
+// responses to keep it within its configured size
+func cacheResponse(c *cachedResponse) {
+	size := defaultCacheEntries
+	if n, ok := db.ConfigCache("response_cache_entries").(float64); ok && n > 0 {
+		size = int(n)
+	}
+
+	responses.Lock()
+	defer responses.Unlock()
+
+	if e, ok := responses.entries[c.key]; ok {
+		responses.lru.Remove(e)
+	}
+	responses.entries[c.key] = responses.lru.PushFront(c)
+
+	for responses.lru.Len() > size {
+		e := responses.lru.Back()
+		responses.lru.Remove(e)
+		delete(responses.entries, e.Value.(*cachedResponse).key)
+	}
+}
+
+// cacheWriter keeps a copy of a response as it is written, so that it can be
+// added to the response cache
+type cacheWriter struct {
+	http.ResponseWriter
+
+	status int
+	body   bytes.Buffer
+
+	// skip is set for responses which can't be shared between requests, such
+	// as those for content types which are Hideable
+	skip bool
+}
+
+func (cw *cacheWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	cw.body.Write(p)
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *cacheWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := cw.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// uncacheable keeps the response written to res out of the response cache,
+// because it depends on more of the request than the cache key
+func uncacheable(res http.ResponseWriter) {
+	if cw, ok := res.(*cacheWriter); ok {
+		cw.skip = true
+	}
+}
+
+// private reports whether the Cache-Control of a response keeps it from being
+// shared between clients, such as one a BeforeAPIResponse hook built from more
+// of the request than the cache key
+func private(h http.Header) bool {
+	cc := strings.ToLower(h.Get("Cache-Control"))
+	return strings.Contains(cc, "private") || strings.Contains(cc, "no-store")
+}
+
+// Cache wraps a HandlerFunc to serve GET requests from an in-memory cache of
+// the responses to earlier ones, which are kept until any content changes or
+// they expire. Only successful responses which aren't private are cached, and
+// conditional requests are always passed to next so that it can answer them
+// with 304 Not Modified.
+func Cache(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		disabled, _ := db.ConfigCache("response_cache_disabled").(bool)
+		if disabled || req.Method != http.MethodGet ||
+			req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		key := cacheKey(req)
+		bypass := strings.EqualFold(req.Header.Get(cacheHeader), "bypass") && activeSession(req)
+		if !bypass {
+			if c, ok := cachedResponseFor(key); ok {
+				for k, v := range c.header {
+					res.Header()[k] = v
+				}
+				res.Header().Set(cacheHeader, "HIT")
+				res.Write(c.body)
+
+				analytics.CountCache(true)
+				return
+			}
+		}
+
+		// headers already set, such as by CORS, depend on the request rather
+		// than the content, so only those set by next are cached
+		before := make(http.Header, len(res.Header()))
+		for k, v := range res.Header() {
+			before[k] = v
+		}
+
+		ttl := time.Duration(defaultCacheSeconds) * time.Second
+		if n, ok := db.ConfigCache("response_cache_seconds").(float64); ok && n > 0 {
+			ttl = time.Duration(n) * time.Second
+		}
+
+		c := &cachedResponse{
+			key:     key,
+			header:  make(http.Header),
+			version: db.ContentVersion(),
+			expires: time.Now().Add(ttl),
+		}
+
+		if bypass {
+			res.Header().Set(cacheHeader, "BYPASS")
+		} else {
+			res.Header().Set(cacheHeader, "MISS")
+		}
+
+		cw := &cacheWriter{ResponseWriter: res}
+		next.ServeHTTP(cw, req)
+
+		if cw.skip || cw.status != http.StatusOK || private(res.Header()) {
+			return
+		}
+
+		for k, v := range res.Header() {
+			switch k {
+			case cacheHeader, "Content-Encoding", "Content-Length", "Vary":
+				// these depend on how the response is compressed for the client
+				continue
+			case "Etag":
+				// compression makes the ETag next set weak as it writes, but a
+				// hit may be sent uncompressed
+				v = []string{strings.TrimPrefix(res.Header().Get(k), "W/")}
+			}
+			if old, ok := before[k]; ok && strings.Join(old, ",") == strings.Join(v, ",") {
+				continue
+			}
+
+			c.header[k] = v
+		}
+		c.body = cw.body.Bytes()
+
+		cacheResponse(c)
+		analytics.CountCache(false)
+	})
+}
+
+// activeSession reports whether req is made by an admin user whose session is
+// active, the same as is required to use the admin
+func activeSession(req *http.Request) bool {
+	_, err := db.CurrentSession(req)
+	return err == nil
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	calls := 0
+	h := Cache(func(res http.ResponseWriter, req *http.Request) {
+		calls++
+		if req.URL.Query().Get("hidden") != "" {
+			uncacheable(res)
+		}
+		if req.URL.Query().Get("private") != "" {
+			res.Header().Set("Cache-Control", "private")
+		}
+		if req.URL.Query().Get("missing") != "" {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.Write([]byte(`{"data":[]}`))
+	})
+
+	get := func(url string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	first := get("/api/content?type=T&id=1", nil)
+	if first.Header().Get(cacheHeader) != "MISS" || calls != 1 {
+		t.Fatalf("first request: %s, %d calls", first.Header().Get(cacheHeader), calls)
+	}
+
+	// the same query in another order is served from the cache
+	hit := get("/api/content?id=1&type=T", nil)
+	if hit.Header().Get(cacheHeader) != "HIT" || calls != 1 {
+		t.Fatalf("repeated request: %s, %d calls", hit.Header().Get(cacheHeader), calls)
+	}
+	if hit.Body.String() != first.Body.String() || hit.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("cached response differs: %q %q", hit.Body.String(), hit.Header().Get("Content-Type"))
+	}
+
+	// a different format is cached separately
+	get("/api/content?type=T&id=1", map[string]string{"Accept": "application/vnd.api+json"})
+	if calls != 2 {
+		t.Errorf("request with other Accept was served from the cache")
+	}
+
+	// conditional requests, failures, uncacheable and private responses reach
+	// the handler
+	get("/api/content?type=T&id=1", map[string]string{"If-None-Match": `"x"`})
+	for i := 0; i < 2; i++ {
+		get("/api/content?type=T&id=2&missing=1", nil)
+		get("/api/content?type=T&id=3&hidden=1", nil)
+		get("/api/content?type=T&id=4&private=1", nil)
+	}
+	if calls != 9 {
+		t.Errorf("expected 9 calls to the handler, got %d", calls)
+	}
+
+	// the bypass header is ignored without an admin session
+	if get("/api/content?type=T&id=1", map[string]string{cacheHeader: "bypass"}).Header().Get(cacheHeader) != "HIT" {
+		t.Error("bypass header was honoured without an admin session")
+	}
+}
@@ -1,29 +1,120 @@
 package api
 
 import (
-	"log"
 	"net/http"
 	"net/url"
+	"strings"
 
 	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
 )
 
 // sendPreflight is used to respond to a cross-origin "OPTIONS" request
 func sendPreflight(res http.ResponseWriter) {
-	res.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
-	res.Header().Set("Access-Control-Allow-Origin", "*")
+	// an allowed origin has already been set if origins are configured
+	if len(allowedOrigins()) == 0 {
+		res.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, If-Match, X-Request-ID")
+		res.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+
 	res.WriteHeader(200)
 	return
 }
 
+// allowedOrigins returns the configured list of origins allowed to make
+// cross-origin requests
+func allowedOrigins() []string {
+	vals, ok := db.ConfigCache("cors_allowed_origins").([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var origins []string
+	for _, v := range vals {
+		if o, ok := v.(string); ok && strings.TrimSpace(o) != "" {
+			origins = append(origins, strings.TrimSpace(o))
+		}
+	}
+
+	return origins
+}
+
+// originAllowed reports whether origin matches any of the allowed patterns. A
+// pattern is a host such as "example.com" to allow it with any scheme, or a
+// full origin such as "https://example.com". The host may start with "*." to
+// allow any of its subdomains, e.g. "*.example.com". The pattern "*", which
+// allows any origin without credentials, is not matched by any origin here.
+func originAllowed(origin string, allowed []string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	for _, pattern := range allowed {
+		if pattern == "*" {
+			continue
+		}
+
+		host := pattern
+		if i := strings.Index(pattern, "://"); i >= 0 {
+			if pattern[:i] != u.Scheme {
+				continue
+			}
+
+			host = pattern[i+3:]
+		}
+
+		host = strings.TrimSuffix(host, "/")
+		if strings.HasPrefix(host, "*.") {
+			if strings.HasSuffix(u.Host, host[1:]) && len(u.Host) > len(host)-1 {
+				return true
+			}
+
+			continue
+		}
+
+		if u.Host == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowOrigin sets the CORS headers of a response to a request from origin,
+// given the allowed patterns. An origin matching an explicit pattern is echoed
+// so that credentialed requests are allowed. Other origins are allowed without
+// credentials if "*" is, and sent no CORS headers otherwise, which browsers
+// will then block.
+func allowOrigin(h http.Header, origin string, allowed []string) {
+	switch {
+	case origin != "" && originAllowed(origin, allowed):
+		h.Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, If-Match, X-Request-ID")
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Credentials", "true")
+
+	case contains(allowed, "*"):
+		h.Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, If-Match, X-Request-ID")
+		h.Set("Access-Control-Allow-Origin", "*")
+	}
+}
+
 func responseWithCORS(res http.ResponseWriter, req *http.Request) (http.ResponseWriter, bool) {
+	if allowed := allowedOrigins(); len(allowed) > 0 {
+		// responses depend on the origin, so caches must keep them separately
+		res.Header().Add("Vary", "Origin")
+
+		allowOrigin(res.Header(), req.Header.Get("Origin"), allowed)
+		return res, true
+	}
+
 	if db.ConfigCache("cors_disabled").(bool) == true {
 		// check origin matches config domain
 		domain := db.ConfigCache("domain").(string)
 		origin := req.Header.Get("Origin")
 		u, err := url.Parse(origin)
 		if err != nil {
-			log.Println("Error parsing URL from request Origin header:", origin)
+			logger.For(req).Error("Error parsing URL from request Origin header:", origin)
 			return res, false
 		}
 
@@ -39,7 +130,7 @@ func responseWithCORS(res http.ResponseWriter, req *http.Request) (http.Response
 		// in config
 		if origin == domain {
 			// apply limited CORS headers and return
-			res.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
+			res.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, If-Match, X-Request-ID")
 			res.Header().Set("Access-Control-Allow-Origin", domain)
 			return res, true
 		}
@@ -50,7 +141,7 @@ func responseWithCORS(res http.ResponseWriter, req *http.Request) (http.Response
 	}
 
 	// apply full CORS headers and return
-	res.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
+	res.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type, If-Match, X-Request-ID")
 	res.Header().Set("Access-Control-Allow-Origin", "*")
 
 	return res, true
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://app.example.com", "*.tenant.io", "localhost:8080"}
+
+	cases := map[string]bool{
+		"https://app.example.com":  true,
+		"http://app.example.com":   false,
+		"https://example.com":      false,
+		"https://a.tenant.io":      true,
+		"http://b.c.tenant.io":     true,
+		"https://tenant.io":        false,
+		"https://eviltenant.io":    false,
+		"http://localhost:8080":    true,
+		"http://localhost:3000":    false,
+		"not a url":                false,
+		"https://app.example.com/": true,
+	}
+
+	for origin, want := range cases {
+		if got := originAllowed(origin, allowed); got != want {
+			t.Errorf("originAllowed(%q) = %v, want %v", origin, got, want)
+		}
+	}
+
+	if originAllowed("https://anything.com", []string{"*"}) {
+		t.Error("expected * not to be echoed as a matching origin")
+	}
+}
+
+func TestAllowOriginWildcard(t *testing.T) {
+	allowed := []string{"*", "https://app.example.com"}
+
+	cases := []struct {
+		origin, allow, credentials string
+	}{
+		{"https://app.example.com", "https://app.example.com", "true"},
+		{"https://evil.com", "*", ""},
+		{"", "*", ""},
+	}
+
+	for _, c := range cases {
+		h := make(http.Header)
+		allowOrigin(h, c.origin, allowed)
+
+		if got := h.Get("Access-Control-Allow-Origin"); got != c.allow {
+			t.Errorf("%q: expected Access-Control-Allow-Origin %q, got %q", c.origin, c.allow, got)
+		}
+
+		if got := h.Get("Access-Control-Allow-Credentials"); got != c.credentials {
+			t.Errorf("%q: expected Access-Control-Allow-Credentials %q, got %q", c.origin, c.credentials, got)
+		}
+	}
+
+	h := make(http.Header)
+	allowOrigin(h, "https://evil.com", []string{"https://app.example.com"})
+	if len(h) != 0 {
+		t.Errorf("expected no CORS headers for an origin not allowed, got %v", h)
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/trace"
+)
+
+// incrementHandler adds to a counter field of content, listed by the
+// item.Countable content type, in response to requests such as:
+// POST /api/content/increment?type=Post&id=1&field=views
+// The counter goes up by 1, or down by 1 with delta=-1, and its new value is
+// written back. Counters are updated atomically, so concurrent requests are
+// never lost.
+func incrementHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := req.URL.Query()
+	t := q.Get("type")
+	id := q.Get("id")
+	field := q.Get("field")
+	if t == "" || id == "" || field == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	it, ok := item.Types[t]
+	if !ok {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if hide(it(), res, req) {
+		return
+	}
+
+	countable, ok := it().(item.Countable)
+	if !ok || !contains(countable.Counters(), field) {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	delta := int64(1)
+	if d := q.Get("delta"); d != "" {
+		var err error
+		delta, err = strconv.ParseInt(d, 10, 64)
+		if err != nil || (delta != 1 && delta != -1) {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	// unpublished content can't be found through the API, so can't be counted
+	post, err := db.Content(t + ":" + id)
+	if err != nil {
+		logger.For(req).Error("Error finding content to increment:", t+":"+id, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(post) == 0 || unpublished(it, post) {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	_, span := trace.Start(req.Context(), "db.Increment")
+	span.SetAttribute("ponzu.type", t)
+	value, err := db.Increment(t, id, field, delta)
+	span.SetError(err)
+	span.End()
+	switch err {
+	case nil:
+	case db.ErrNoContentExists:
+		res.WriteHeader(http.StatusNotFound)
+		return
+	case db.ErrNotCounter:
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	default:
+		logger.For(req).Error("Error incrementing", field, "of", t+":"+id, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	j, err := json.Marshal(map[string]interface{}{
+		"data": []map[string]interface{}{
+			{"id": id, field: value},
+		},
+	})
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// the new value must never be served from a cache
+	res.Header().Set("Cache-Control", "no-store")
+	sendData(res, req, j)
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CustomPrefix is the path custom routes are served under
+const CustomPrefix = "/api/custom/"
+
+// customRoutes routes requests under CustomPrefix to the handlers registered
+// with HandleCustom
+var customRoutes = http.NewServeMux()
+
+// HandleCustom registers h to serve custom API requests to path, under
+// CustomPrefix, so that HandleCustom("stats/reviews", h) serves
+// /api/custom/stats/reviews. A path ending with "/" serves every path starting
+// with it which isn't registered itself, as with http.ServeMux. It is called
+// from the init function of content or addon code, and like http.Handle it
+// panics if path is already registered.
+//
+// Custom routes have the same middleware as the content API: requests are
+// recorded in analytics with a request ID, given CORS headers if enabled, rate
+// limited, and need an API key with the read scope when API keys are required,
+// with the type query param checked against the key's types if it is sent.
+// Only GET and HEAD requests reach h. Responses are compressed for clients
+// which accept it, and have the ETag and Cache-Control of content responses,
+// which change as content does, so a handler whose response depends on more
+// than content sets its own Cache-Control. They aren't kept in the response
+// cache. Handlers use the db and search packages to read content, such as with
+// db.NewQuery and search.TypeQuery, and can trace them with trace.Start from
+// the request's context.
+func HandleCustom(path string, h http.Handler) {
+	customRoutes.Handle(CustomPrefix+strings.TrimPrefix(path, "/"), h)
+}
+
+// HandleCustomFunc registers the handler function h to serve custom API
+// requests to path, the same as HandleCustom
+func HandleCustomFunc(path string, h func(http.ResponseWriter, *http.Request)) {
+	HandleCustom(path, http.HandlerFunc(h))
+}
+
+// customHandler serves the custom routes, only for methods which read
+func customHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		res.Header().Set("Allow", "GET, HEAD")
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	customRoutes.ServeHTTP(res, req)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCustomHandler(t *testing.T) {
+	HandleCustomFunc("/test/stats", func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte("stats"))
+	})
+	HandleCustomFunc("test/feeds/", func(res http.ResponseWriter, req *http.Request) {
+		res.Write([]byte(req.URL.Path))
+	})
+
+	cases := []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{http.MethodGet, "/api/custom/test/stats", http.StatusOK, "stats"},
+		{http.MethodGet, "/api/custom/test/feeds/latest", http.StatusOK, "/api/custom/test/feeds/latest"},
+		{http.MethodGet, "/api/custom/test/missing", http.StatusNotFound, ""},
+		{http.MethodPost, "/api/custom/test/stats", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, c := range cases {
+		res := httptest.NewRecorder()
+		customHandler(res, httptest.NewRequest(c.method, c.path, nil))
+
+		if res.Code != c.status {
+			t.Errorf("%s %s: expected status %d, got %d", c.method, c.path, c.status, res.Code)
+		}
+
+		if c.body != "" && res.Body.String() != c.body {
+			t.Errorf("%s %s: expected body %q, got %q", c.method, c.path, c.body, res.Body.String())
+		}
+	}
+}
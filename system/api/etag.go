@@ -0,0 +1,59 @@
+package api
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+// etag returns a strong ETag for the response data, which changes whenever
+// any item in the response, or the membership of a list, changes
+func etag(data []byte) string {
+	sum := sha1.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// notModified sets the ETag and Last-Modified headers for the response data,
+// and reports whether the client's copy of it is current according to the
+// If-None-Match or If-Modified-Since request headers, in which case a 304 Not
+// Modified response has been written. If-Modified-Since is compared to the
+// last time any content changed, and is ignored if If-None-Match is present.
+func notModified(res http.ResponseWriter, req *http.Request, data []byte) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	tag := etag(data)
+	modified := db.LastModified().UTC().Truncate(time.Second)
+
+	res.Header().Set("ETag", tag)
+	if !modified.IsZero() {
+		res.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	}
+
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		for _, m := range strings.Split(match, ",") {
+			m = strings.TrimPrefix(strings.TrimSpace(m), "W/")
+			if m == tag || m == "*" {
+				res.WriteHeader(http.StatusNotModified)
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if since := req.Header.Get("If-Modified-Since"); since != "" && !modified.IsZero() {
+		t, err := http.ParseTime(since)
+		if err == nil && !modified.After(t) {
+			res.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ponzu-cms/ponzu/system/admin/upload"
 	"github.com/ponzu-cms/ponzu/system/db"
 	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/trace"
 )
 
 // Externalable accepts or rejects external POST requests to endpoints such as:
@@ -35,7 +38,7 @@ func externalContentHandler(res http.ResponseWriter, req *http.Request) {
 
 	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
 	if err != nil {
-		log.Println("[External] error:", err)
+		logger.For(req).Error("[External] error:", err)
 		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -48,7 +51,7 @@ func externalContentHandler(res http.ResponseWriter, req *http.Request) {
 
 	p, found := item.Types[t]
 	if !found {
-		log.Println("[External] attempt to submit unknown type:", t, "from:", req.RemoteAddr)
+		logger.For(req).Error("[External] attempt to submit unknown type:", t, "from:", req.RemoteAddr)
 		res.WriteHeader(http.StatusNotFound)
 		return
 	}
@@ -57,7 +60,7 @@ func externalContentHandler(res http.ResponseWriter, req *http.Request) {
 
 	ext, ok := post.(Externalable)
 	if !ok {
-		log.Println("[External] rejected non-externalable type:", t, "from:", req.RemoteAddr)
+		logger.For(req).Error("[External] rejected non-externalable type:", t, "from:", req.RemoteAddr)
 		res.WriteHeader(http.StatusBadRequest)
 		return
 	}
@@ -67,8 +70,13 @@ func externalContentHandler(res http.ResponseWriter, req *http.Request) {
 	req.PostForm.Set("updated", ts)
 
 	urlPaths, err := upload.StoreFiles(req)
+	if _, ok := err.(*upload.RejectedError); ok {
+		logger.For(req).Error("[External]", err)
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		log.Println(err)
+		logger.For(req).Error(err)
 		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -77,78 +85,40 @@ func externalContentHandler(res http.ResponseWriter, req *http.Request) {
 		req.PostForm.Set(name, urlPath)
 	}
 
-	// check for any multi-value fields (ex. checkbox fields)
-	// and correctly format for db storage. Essentially, we need
-	// fieldX.0: value1, fieldX.1: value2 => fieldX: []string{value1, value2}
-	fieldOrderValue := make(map[string]map[string][]string)
-	ordVal := make(map[string][]string)
-	for k, v := range req.PostForm {
-		if strings.Contains(k, ".") {
-			fo := strings.Split(k, ".")
-
-			// put the order and the field value into map
-			field := string(fo[0])
-			order := string(fo[1])
-			fieldOrderValue[field] = ordVal
-
-			// orderValue is 0:[?type=Thing&id=1]
-			orderValue := fieldOrderValue[field]
-			orderValue[order] = v
-			fieldOrderValue[field] = orderValue
-
-			// discard the post form value with name.N
-			req.PostForm.Del(k)
-		}
-
-	}
-
-	// add/set the key & value to the post form in order
-	for f, ov := range fieldOrderValue {
-		for i := 0; i < len(ov); i++ {
-			position := fmt.Sprintf("%d", i)
-			fieldValue := ov[position]
-
-			if req.PostForm.Get(f) == "" {
-				for i, fv := range fieldValue {
-					if i == 0 {
-						req.PostForm.Set(f, fv)
-					} else {
-						req.PostForm.Add(f, fv)
-					}
-				}
-			} else {
-				for _, fv := range fieldValue {
-					req.PostForm.Add(f, fv)
-				}
-			}
-		}
-	}
+	orderFormValues(req.PostForm)
 
 	hook, ok := post.(item.Hookable)
 	if !ok {
-		log.Println("[External] error: Type", t, "does not implement item.Hookable or embed item.Item.")
+		logger.For(req).Error("[External] error: Type", t, "does not implement item.Hookable or embed item.Item.")
 		res.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
 	err = hook.BeforeAccept(res, req)
 	if err != nil {
-		log.Println("[External] error calling BeforeAccept:", err)
+		logger.For(req).Error("[External] error calling BeforeAccept:", err)
 		return
 	}
 
 	err = ext.Accept(res, req)
 	if err != nil {
-		log.Println("[External] error calling Accept:", err)
+		logger.For(req).Error("[External] error calling Accept:", err)
 		return
 	}
 
 	err = hook.BeforeSave(res, req)
 	if err != nil {
-		log.Println("[External] error calling BeforeSave:", err)
+		logger.For(req).Error("[External] error calling BeforeSave:", err)
 		return
 	}
 
+	// the workflow state of content is only set from the admin
+	for k := range req.PostForm {
+		if k == "state" || strings.HasPrefix(k, "state_history.") {
+			req.PostForm.Del(k)
+		}
+	}
+
 	// set specifier for db bucket in case content is/isn't Trustable
 	var spec string
 
@@ -160,16 +130,35 @@ func externalContentHandler(res http.ResponseWriter, req *http.Request) {
 	if ok {
 		err := trusted.AutoApprove(res, req)
 		if err != nil {
-			log.Println("[External] error calling AutoApprove:", err)
+			logger.For(req).Error("[External] error calling AutoApprove:", err)
 			return
 		}
 	} else {
 		spec = "__pending"
 	}
 
+	_, span := trace.Start(req.Context(), "db.SetContent")
+	span.SetAttribute("ponzu.type", t)
 	id, err := db.SetContent(t+spec+":-1", req.PostForm)
+	span.SetError(err)
+	span.End()
+	if fields, ok := item.FieldErrors(err); ok {
+		logger.For(req).Error("[External] invalid content submitted:", err)
+		j, err := json.Marshal(map[string]interface{}{
+			"errors": fields,
+		})
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusBadRequest)
+		res.Write(j)
+		return
+	}
 	if err != nil {
-		log.Println("[External] error calling SetContent:", err)
+		logger.For(req).Error("[External] error calling SetContent:", err)
 		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -180,13 +169,13 @@ func externalContentHandler(res http.ResponseWriter, req *http.Request) {
 
 	err = hook.AfterSave(res, req)
 	if err != nil {
-		log.Println("[External] error calling AfterSave:", err)
+		logger.For(req).Error("[External] error calling AfterSave:", err)
 		return
 	}
 
 	err = hook.AfterAccept(res, req)
 	if err != nil {
-		log.Println("[External] error calling AfterAccept:", err)
+		logger.For(req).Error("[External] error calling AfterAccept:", err)
 		return
 	}
 
@@ -215,7 +204,7 @@ func externalContentHandler(res http.ResponseWriter, req *http.Request) {
 
 	j, err := json.Marshal(resp)
 	if err != nil {
-		log.Println("[External] error marshalling response to JSON:", err)
+		logger.For(req).Error("[External] error marshalling response to JSON:", err)
 		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -223,8 +212,62 @@ func externalContentHandler(res http.ResponseWriter, req *http.Request) {
 	res.Header().Set("Content-Type", "application/json")
 	_, err = res.Write(j)
 	if err != nil {
-		log.Println("[External] error writing response:", err)
+		logger.For(req).Error("[External] error writing response:", err)
 		return
 	}
 
 }
+
+// orderFormValues replaces the values of multi-value fields in form, such as
+// checkbox fields, submitted as fieldX.0: value1, fieldX.1: value2 with
+// fieldX: []string{value1, value2} for db storage
+func orderFormValues(form url.Values) {
+	fieldOrderValue := make(map[string]map[string][]string)
+	ordVal := make(map[string][]string)
+	for k, v := range form {
+		// field group values, i.e. faqs.0.question, and nested struct values,
+		// i.e. location.lat, are left for gorilla/schema to decode
+		fo := strings.Split(k, ".")
+		if len(fo) != 2 {
+			continue
+		}
+		if _, err := strconv.Atoi(fo[1]); err != nil {
+			continue
+		}
+
+		// put the order and the field value into map
+		field := string(fo[0])
+		order := string(fo[1])
+		fieldOrderValue[field] = ordVal
+
+		// orderValue is 0:[?type=Thing&id=1]
+		orderValue := fieldOrderValue[field]
+		orderValue[order] = v
+		fieldOrderValue[field] = orderValue
+
+		// discard the post form value with name.N
+		form.Del(k)
+	}
+
+	// add/set the key & value to the post form in order
+	for f, ov := range fieldOrderValue {
+		for i := 0; i < len(ov); i++ {
+			position := fmt.Sprintf("%d", i)
+			fieldValue := ov[position]
+
+			if form.Get(f) == "" {
+				for i, fv := range fieldValue {
+					if i == 0 {
+						form.Set(f, fv)
+					} else {
+						form.Add(f, fv)
+					}
+				}
+			} else {
+				for _, fv := range fieldValue {
+					form.Add(f, fv)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+const (
+	// defaultFeedItems is the number of items in a feed, if feed_items is not
+	// configured
+	defaultFeedItems = 20
+	// feedMaxAge is how long clients and proxies may cache a feed
+	feedMaxAge = 15 * time.Minute
+)
+
+// feedItem is content of a Feedable type with the times it was published and
+// last updated
+type feedItem struct {
+	entry     item.FeedEntry
+	id        string
+	published time.Time
+	updated   time.Time
+}
+
+// rssFeed is an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link,omitempty"`
+	Description string  `xml:"description,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomFeed is an Atom document
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	XMLNS   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Link    atomLink    `xml:"link"`
+	Updated string      `xml:"updated"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title     string    `xml:"title"`
+	ID        string    `xml:"id"`
+	Link      *atomLink `xml:"link,omitempty"`
+	Published string    `xml:"published"`
+	Updated   string    `xml:"updated"`
+	Summary   string    `xml:"summary,omitempty"`
+}
+
+// feedItems returns up to n of the most recent public items of content of the
+// Feedable type t, newest first
+func feedItems(t string, it func() interface{}, n int) []feedItem {
+	now := time.Now()
+	var items []feedItem
+	for offset := 0; len(items) < n; offset++ {
+		_, bb := db.Query(t+"__sorted", db.QueryOptions{
+			Count:  n,
+			Offset: offset,
+			Order:  "desc",
+		})
+		if len(bb) == 0 {
+			break
+		}
+
+		for _, j := range bb {
+			post := it()
+			err := json.Unmarshal(j, post)
+			if err != nil {
+				logger.Error("Error decoding content for feed:", t, err)
+				continue
+			}
+
+			if !item.IsPublic(post, now) {
+				continue
+			}
+
+			fi := feedItem{entry: post.(item.Feedable).Feed()}
+			if id, ok := post.(item.Identifiable); ok {
+				fi.id = "urn:uuid:" + id.UniqueID().String()
+			}
+
+			published := fi.entry.Published
+			if s, ok := post.(item.Schedulable); ok && published == 0 {
+				published = s.PublishTime()
+			}
+			if s, ok := post.(item.Sortable); ok {
+				if published == 0 {
+					published = s.Time()
+				}
+				fi.updated = time.Unix(0, s.Touch()*int64(time.Millisecond))
+			}
+			fi.published = time.Unix(0, published*int64(time.Millisecond))
+			if fi.updated.Before(fi.published) {
+				fi.updated = fi.published
+			}
+
+			items = append(items, fi)
+			if len(items) == n {
+				break
+			}
+		}
+	}
+
+	return items
+}
+
+// feedHandler writes the most recent public content of a Feedable type as an
+// RSS 2.0 feed, or an Atom feed with format=atom
+func feedHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := req.URL.Query()
+	t := q.Get("type")
+	it, ok := item.Types[t]
+	if !ok {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if _, ok := it().(item.Feedable); !ok {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if hide(it(), res, req) {
+		return
+	}
+
+	format := strings.ToLower(q.Get("format"))
+	if format == "" {
+		format = "rss"
+	}
+	if format != "rss" && format != "atom" {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	n := defaultFeedItems
+	if v, ok := db.ConfigCache("feed_items").(float64); ok && v > 0 {
+		n = int(v)
+	}
+
+	site := siteURL(req)
+	link := func(l string) string {
+		if strings.HasPrefix(l, "/") {
+			return site + l
+		}
+
+		return l
+	}
+
+	title := t
+	if name, _ := db.ConfigCache("name").(string); name != "" {
+		title = fmt.Sprintf("%s: %s", name, t)
+	}
+
+	items := feedItems(t, it, n)
+	var updated time.Time
+	for _, fi := range items {
+		if fi.updated.After(updated) {
+			updated = fi.updated
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	var v interface{}
+	contentType := "application/rss+xml; charset=utf-8"
+	switch format {
+	case "atom":
+		contentType = "application/atom+xml; charset=utf-8"
+		feed := atomFeed{
+			XMLNS:   "http://www.w3.org/2005/Atom",
+			Title:   title,
+			ID:      site + req.URL.RequestURI(),
+			Link:    atomLink{Href: site},
+			Updated: updated.UTC().Format(time.RFC3339),
+		}
+		for _, fi := range items {
+			e := atomEntry{
+				Title:     fi.entry.Title,
+				ID:        fi.id,
+				Published: fi.published.UTC().Format(time.RFC3339),
+				Updated:   fi.updated.UTC().Format(time.RFC3339),
+				Summary:   fi.entry.Description,
+			}
+			if fi.entry.Link != "" {
+				e.Link = &atomLink{Href: link(fi.entry.Link)}
+			}
+
+			feed.Entries = append(feed.Entries, e)
+		}
+
+		v = feed
+
+	default:
+		feed := rssFeed{
+			Version: "2.0",
+			Channel: rssChannel{
+				Title:         title,
+				Link:          site,
+				Description:   fmt.Sprintf("The most recent %s", t),
+				LastBuildDate: updated.UTC().Format(time.RFC1123Z),
+			},
+		}
+		for _, fi := range items {
+			feed.Channel.Items = append(feed.Channel.Items, rssItem{
+				Title:       fi.entry.Title,
+				Link:        link(fi.entry.Link),
+				Description: fi.entry.Description,
+				GUID:        rssGUID{Value: fi.id},
+				PubDate:     fi.published.UTC().Format(time.RFC1123Z),
+			})
+		}
+
+		v = feed
+	}
+
+	x, err := xml.Marshal(v)
+	if err != nil {
+		logger.For(req).Error("Error encoding feed:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	buf := bytes.NewBufferString(xml.Header)
+	buf.Write(x)
+
+	res.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public", int(feedMaxAge.Seconds())))
+	if notModified(res, req, buf.Bytes()) {
+		return
+	}
+
+	res.Header().Set("Content-Type", contentType)
+	res.Write(buf.Bytes())
+}
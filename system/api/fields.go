@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// projectFields keeps only the fields of each item in the response j which are
+// listed in the fields query param of req, or removes those listed in its omit
+// param, each separated by commas, so that clients are sent only what they use.
+// Fields which items don't have are ignored, and the id of an item is always
+// kept. The items of a search across all types are in their content, which is
+// projected instead if tagged is true.
+func projectFields(req *http.Request, tagged bool, j []byte) ([]byte, error) {
+	q := req.URL.Query()
+	keep := fieldSet(q.Get("fields"))
+	drop := fieldSet(q.Get("omit"))
+	if keep == nil && drop == nil {
+		return j, nil
+	}
+
+	data := gjson.GetBytes(j, "data")
+	if !strings.HasPrefix(data.Raw, "[") {
+		return j, nil
+	}
+
+	var arr bytes.Buffer
+	arr.WriteByte('[')
+	for i, v := range data.Array() {
+		if i > 0 {
+			arr.WriteByte(',')
+		}
+
+		if !tagged {
+			arr.Write(project(v, keep, drop))
+			continue
+		}
+
+		tag, err := sjson.SetRawBytes([]byte(v.Raw), "content", project(v.Get("content"), keep, drop))
+		if err != nil {
+			return nil, err
+		}
+		arr.Write(tag)
+	}
+	arr.WriteByte(']')
+
+	return sjson.SetRawBytes(j, "data", arr.Bytes())
+}
+
+// fieldSet returns the set of field names in a comma separated list, or nil if
+// there are none
+func fieldSet(list string) map[string]bool {
+	var set map[string]bool
+	for _, f := range strings.Split(list, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+
+		if set == nil {
+			set = make(map[string]bool)
+		}
+		set[f] = true
+	}
+
+	return set
+}
+
+// project returns the json object v with only the fields in keep, if it isn't
+// nil, and without those in drop, keeping its id and the order of its fields
+func project(v gjson.Result, keep, drop map[string]bool) []byte {
+	if !strings.HasPrefix(v.Raw, "{") {
+		return []byte(v.Raw)
+	}
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	v.ForEach(func(k, val gjson.Result) bool {
+		name := k.String()
+		if name != "id" && ((keep != nil && !keep[name]) || drop[name]) {
+			return true
+		}
+
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k.Raw)
+		b.WriteByte(':')
+		b.WriteString(val.Raw)
+
+		return true
+	})
+	b.WriteByte('}')
+
+	return b.Bytes()
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProjectFields(t *testing.T) {
+	j := []byte(`{"data":[{"id":1,"title":"A","slug":"a","body":"x"},{"id":2,"title":"B","slug":"b","body":"y"}],"total":2}`)
+
+	cases := map[string]string{
+		"":                             string(j),
+		"?fields=title,slug":           `{"data":[{"id":1,"title":"A","slug":"a"},{"id":2,"title":"B","slug":"b"}],"total":2}`,
+		"?fields=title,unknown":        `{"data":[{"id":1,"title":"A"},{"id":2,"title":"B"}],"total":2}`,
+		"?omit=body,id":                `{"data":[{"id":1,"title":"A","slug":"a"},{"id":2,"title":"B","slug":"b"}],"total":2}`,
+		"?fields=title,body&omit=body": `{"data":[{"id":1,"title":"A"},{"id":2,"title":"B"}],"total":2}`,
+		"?fields=,":                    string(j),
+	}
+
+	for query, want := range cases {
+		got, err := projectFields(httptest.NewRequest("GET", "/api/contents"+query, nil), false, j)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != want {
+			t.Errorf("%q: expected %s, got %s", query, want, got)
+		}
+	}
+
+	tagged := []byte(`{"data":[{"type":"Post","content":{"id":1,"title":"A","body":"x"}}]}`)
+	got, err := projectFields(httptest.NewRequest("GET", "/api/search?fields=title", nil), true, tagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"data":[{"type":"Post","content":{"id":1,"title":"A"}}]}`; string(got) != want {
+		t.Errorf("tagged: expected %s, got %s", want, got)
+	}
+}
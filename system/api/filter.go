@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/search"
+)
+
+// listParams are the query params of the content list API which are options
+// for the list rather than filters on content fields
+var listParams = map[string]bool{
+	"type":    true,
+	"count":   true,
+	"offset":  true,
+	"after":   true,
+	"order":   true,
+	"format":  true,
+	"include": true,
+	"fields":  true,
+	"omit":    true,
+	"locale":  true,
+	"preview": true,
+	"sort":    true,
+}
+
+// parseFilters returns a search.Filter for each query param in q which is not
+// one of listParams. A param "field=value" matches content where field equals
+// value, and "field[op]=value" compares the field by op, one of eq, gt, gte,
+// lt or lte. ok is false if any op is not supported, or any field is not one
+// of the fields of it, an empty item of the type, or is left out by Omit.
+func parseFilters(q url.Values, it interface{}) (filters []search.Filter, ok bool) {
+	fields := filterFields(it)
+	for k, vals := range q {
+		if listParams[k] {
+			continue
+		}
+
+		f := search.Filter{Field: k, Op: search.OpEqual}
+		if i := strings.Index(k, "["); i > 0 && strings.HasSuffix(k, "]") {
+			f.Field = k[:i]
+			f.Op = k[i+1 : len(k)-1]
+		}
+
+		if !search.ValidOp(f.Op) || !fields.allow(f.Field) {
+			return nil, false
+		}
+
+		for _, v := range vals {
+			f.Value = v
+			filters = append(filters, f)
+		}
+	}
+
+	return filters, true
+}
+
+// filterSet is the fields content can be filtered by, and those left out of
+// responses which it can't
+type filterSet struct {
+	names   map[string]bool
+	omitted []string
+}
+
+// filterFields returns the fields of it, an empty item of a content type, from
+// its struct
+func filterFields(it interface{}) filterSet {
+	fs := filterSet{names: make(map[string]bool)}
+	for _, f := range schemaFields(reflect.TypeOf(it), "", nil) {
+		fs.names[f.Name] = true
+	}
+
+	if om, ok := it.(item.Omittable); ok {
+		fs.omitted = om.Omit()
+	}
+
+	return fs
+}
+
+// allow reports whether content can be filtered by field, which may be the
+// path of a value within a field, like "venue.city"
+func (fs filterSet) allow(field string) bool {
+	if !fs.names[strings.Split(field, ".")[0]] {
+		return false
+	}
+
+	for _, o := range fs.omitted {
+		if field == o || strings.HasPrefix(field, o+".") || strings.HasPrefix(o, field+".") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filteredContentsHandler writes the page of content of type t matching
+// filters, sorted by the field sortBy, as the response of contentsHandler. The
+// content is paged after leaving out what isn't public, so that the total and
+// has_more agree with the pages returned.
+func filteredContentsHandler(res http.ResponseWriter, req *http.Request, t string, it func() interface{}, filters []search.Filter, count, offset int, sortBy, order string) {
+	result, err := filterContent(t, it, filters, sortBy, order)
+	if err != nil {
+		logger.Error("Error filtering content:", t, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	total := len(result)
+	start, end := page(total, count, offset)
+
+	j, err := encodeJSON(pageResponse(total, count, offset, result[start:end]))
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	j, err = omit(it(), j)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	j, ok, err := includeReferences(res, req, it(), j)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sendContent(res, req, t, false, j)
+}
+
+// filterContent returns all public content of type t matching filters, sorted
+// by the field sortBy in order
+func filterContent(t string, it func() interface{}, filters []search.Filter, sortBy, order string) ([]json.RawMessage, error) {
+	q := db.NewQuery(t).OrderBy(sortBy, order)
+	for _, f := range filters {
+		q.Where(f.Field, f.Op, f.Value)
+	}
+
+	posts, err := q.Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	var result = []json.RawMessage{}
+	for i := range posts {
+		// leave out content which is not yet published
+		if unpublished(it, posts[i]) {
+			continue
+		}
+
+		result = append(result, posts[i])
+	}
+
+	return result, nil
+}
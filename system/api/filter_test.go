@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseFilters(t *testing.T) {
+	cases := map[string]bool{
+		"title=A":                 true,
+		"title[gte]=A&count=10":   true,
+		"venue.city=Paris":        true,
+		"timestamp[gt]=1":         true,
+		"title[like]=A":           false,
+		"internal=x":              false,
+		"unknown=x":               false,
+		"_=1500000000":            false,
+		"utm_source=newsletter":   false,
+		"title=A&utm_source=news": false,
+	}
+
+	for query, want := range cases {
+		q, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, ok := parseFilters(q, new(testEvent))
+		if ok != want {
+			t.Errorf("%q: expected ok %v, got %v", query, want, ok)
+		}
+	}
+}
@@ -0,0 +1,532 @@
+package api
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/graphql"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/search"
+)
+
+var (
+	// graphqlSchema is built from item.Types the first time it is needed,
+	// since all content types are registered by then
+	graphqlSchema struct {
+		sync.Once
+		schema *graphql.Schema
+		err    error
+	}
+
+	// graphqlName matches names which are valid in GraphQL
+	graphqlName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	contentFilterType = &graphql.Type{
+		Kind:        graphql.KindInputObject,
+		Name:        "ContentFilter",
+		Description: "Matches content where the value of field compares to value by op, one of eq, gt, gte, lt or lte.",
+		InputFields: []*graphql.Arg{
+			{Name: "field", Type: graphql.NonNull(graphql.String)},
+			{Name: "op", Type: graphql.String, Default: search.OpEqual},
+			{Name: "value", Type: graphql.NonNull(graphql.String)},
+		},
+	}
+)
+
+// graphqlContextKey holds the graphqlRequest in the context passed to
+// resolvers
+type graphqlContextKey struct{}
+
+type graphqlRequest struct {
+	res http.ResponseWriter
+	req *http.Request
+}
+
+func graphqlHandler(res http.ResponseWriter, req *http.Request) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		q := req.URL.Query()
+		params.Query = q.Get("query")
+		params.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			err := json.Unmarshal([]byte(v), &params.Variables)
+			if err != nil {
+				res.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+	case http.MethodPost:
+		body, err := ioutil.ReadAll(http.MaxBytesReader(res, req.Body, 1024*1024))
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/graphql") {
+			params.Query = string(body)
+		} else {
+			err = json.Unmarshal(body, &params)
+			if err != nil {
+				res.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+	default:
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if params.Query == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	graphqlSchema.Do(func() {
+		graphqlSchema.schema, graphqlSchema.err = buildGraphQLSchema()
+	})
+	if graphqlSchema.err != nil {
+		logger.For(req).Error("Error building GraphQL schema:", graphqlSchema.err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	ctx := context.WithValue(req.Context(), graphqlContextKey{}, graphqlRequest{res: res, req: req})
+	result := graphqlSchema.schema.Execute(ctx, params.Query, params.OperationName, params.Variables)
+
+	j, err := json.Marshal(result)
+	if err != nil {
+		logger.For(req).Error("Failed to encode GraphQL result to JSON:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendData(res, req, j)
+}
+
+// buildGraphQLSchema makes an object type for each content type, with fields
+// read from its struct, and a root Query with a field to get a single item and
+// a field to list items of each type. Fields which are Omittable are left out,
+// and Referenceable fields resolve to the content they refer to.
+func buildGraphQLSchema() (*graphql.Schema, error) {
+	var names []string
+	for name := range item.Types {
+		if graphqlName.MatchString(name) && !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	// every content type is made before any fields, so references can
+	// resolve to any of them
+	objects := make(map[string]*graphql.Type)
+	for _, name := range names {
+		objects[name] = &graphql.Type{Kind: graphql.KindObject, Name: name}
+	}
+
+	query := &graphql.Type{Kind: graphql.KindObject, Name: "Query"}
+	nested := make(map[string]*graphql.Type)
+	for _, name := range names {
+		it := item.Types[name]()
+		objects[name].Fields = contentFields(name, it, objects, nested)
+
+		field := lowerFirst(name)
+		query.Fields = append(query.Fields,
+			&graphql.Field{
+				Name:        field,
+				Description: fmt.Sprintf("A single %s, found by its id or slug.", name),
+				Type:        objects[name],
+				Args: []*graphql.Arg{
+					{Name: "id", Type: graphql.ID},
+					{Name: "slug", Type: graphql.String},
+				},
+				Resolve: resolveContent(name),
+			},
+			&graphql.Field{
+				Name:        field + "List",
+				Description: fmt.Sprintf("A list of %s content, sorted by timestamp and optionally filtered by its fields.", name),
+				Type:        graphql.NonNull(graphql.ListOf(graphql.NonNull(objects[name]))),
+				Args: []*graphql.Arg{
+					{Name: "count", Type: graphql.Int, Default: 10, Description: "Number of items to return, or -1 for all."},
+					{Name: "offset", Type: graphql.Int, Default: 0, Description: "Multiplier of count for pagination."},
+					{Name: "order", Type: graphql.String, Default: "desc", Description: "Sort order by timestamp, asc or desc."},
+					{Name: "filter", Type: graphql.ListOf(graphql.NonNull(contentFilterType))},
+				},
+				Resolve: resolveContentList(name, objects[name]),
+			},
+		)
+	}
+
+	return graphql.NewSchema(query)
+}
+
+// contentFields returns the fields of the content type name, from the struct
+// of it
+func contentFields(name string, it interface{}, objects, nested map[string]*graphql.Type) []*graphql.Field {
+	omitted := make(map[string]bool)
+	if om, ok := it.(item.Omittable); ok {
+		for _, f := range om.Omit() {
+			omitted[f] = true
+		}
+	}
+
+	var refs map[string]string
+	if r, ok := it.(item.Referenceable); ok {
+		refs = r.References()
+	}
+
+	var fields []*graphql.Field
+	for _, f := range structFields(name, reflect.TypeOf(it), nested) {
+		if omitted[f.Name] {
+			continue
+		}
+
+		if ref, ok := objects[refs[f.Name]]; ok {
+			if f.Type.Kind == graphql.KindList {
+				f.Type = graphql.ListOf(graphql.NonNull(ref))
+			} else {
+				f.Type = ref
+			}
+			f.Resolve = resolveReference(f.Name)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// structFields returns a field for each json encoded field of the struct rt,
+// including those of embedded structs like item.Item. Nested structs are given
+// object types named after owner and their field.
+func structFields(owner string, rt reflect.Type, nested map[string]*graphql.Type) []*graphql.Field {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	var fields []*graphql.Field
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := strings.Split(sf.Tag.Get("json"), ",")[0]
+
+		if sf.Anonymous && tag == "" && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, structFields(owner, sf.Type, nested)...)
+			continue
+		}
+
+		if sf.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = sf.Name
+		}
+
+		if !graphqlName.MatchString(name) || strings.HasPrefix(name, "__") {
+			continue
+		}
+
+		t := fieldGraphQLType(owner+sf.Name, sf.Type, nested)
+		if t == nil {
+			continue
+		}
+
+		fields = append(fields, &graphql.Field{Name: name, Type: t})
+	}
+
+	return fields
+}
+
+// fieldGraphQLType returns the type of a struct field of type rt, or nil if it
+// can't be represented. Types with their own json encoding, like uuid.UUID,
+// are strings.
+func fieldGraphQLType(name string, rt reflect.Type, nested map[string]*graphql.Type) *graphql.Type {
+	if rt.Implements(marshalerType) || rt.Implements(textMarshalerType) ||
+		reflect.PtrTo(rt).Implements(marshalerType) || reflect.PtrTo(rt).Implements(textMarshalerType) {
+		return graphql.String
+	}
+
+	switch rt.Kind() {
+	case reflect.Ptr:
+		return fieldGraphQLType(name, rt.Elem(), nested)
+	case reflect.String:
+		return graphql.String
+	case reflect.Bool:
+		return graphql.Boolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return graphql.Int
+	case reflect.Float32, reflect.Float64:
+		return graphql.Float
+	case reflect.Slice, reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			return graphql.String
+		}
+
+		elem := fieldGraphQLType(name, rt.Elem(), nested)
+		if elem == nil {
+			return nil
+		}
+		return graphql.ListOf(elem)
+	case reflect.Struct:
+		if t, ok := nested[name]; ok {
+			return t
+		}
+
+		t := &graphql.Type{Kind: graphql.KindObject, Name: name}
+		nested[name] = t
+		t.Fields = structFields(name, rt, nested)
+		if len(t.Fields) == 0 {
+			delete(nested, name)
+			return nil
+		}
+		return t
+	}
+
+	return nil
+}
+
+// graphqlHidden reports whether content of type it should be hidden from the
+// request which made the query of ctx
+func graphqlHidden(ctx context.Context, it interface{}) bool {
+	r, ok := ctx.Value(graphqlContextKey{}).(graphqlRequest)
+	if !ok {
+		return true
+	}
+
+	return hidden(it, r.res, r.req)
+}
+
+// decodeContent decodes the public fields of post, content of type it, for a
+// resolver. It returns nil if post is empty or not yet published.
+func decodeContent(it func() interface{}, post []byte) (map[string]interface{}, error) {
+	if len(post) == 0 || unpublished(it, post) {
+		return nil, nil
+	}
+
+	post, err := omitItem(it(), post)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	err = json.Unmarshal(post, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// resolveContent returns the resolver of a single item of type t
+func resolveContent(t string) func(p graphql.Params) (interface{}, error) {
+	return func(p graphql.Params) (interface{}, error) {
+		it := item.Types[t]
+		if graphqlHidden(p.Context, it()) {
+			return nil, fmt.Errorf("%s content is not available", t)
+		}
+
+		if id, ok := p.Args["id"].(string); ok {
+			post, err := db.Content(t + ":" + id)
+			if err != nil {
+				return nil, err
+			}
+
+			return decodeContent(it, post)
+		}
+
+		if slug, ok := p.Args["slug"].(string); ok {
+			typ, post, err := db.ContentBySlug(slug)
+			if err != nil || typ != t {
+				return nil, nil
+			}
+
+			return decodeContent(it, post)
+		}
+
+		return nil, fmt.Errorf("id or slug is required")
+	}
+}
+
+// filterable reports whether content of the object type obj can be filtered by
+// field, which may be the path of a value within nested objects, like
+// "venue.city". Referenced content is not stored within the item, so can't be
+// filtered by.
+func filterable(obj *graphql.Type, field string) bool {
+	path := strings.Split(field, ".")
+	for i, name := range path {
+		var found *graphql.Field
+		for _, f := range obj.Fields {
+			if f.Name == name {
+				found = f
+				break
+			}
+		}
+
+		if found == nil {
+			return false
+		}
+
+		if i == len(path)-1 {
+			return true
+		}
+
+		if found.Resolve != nil {
+			return false
+		}
+
+		obj = found.Type
+		for obj.Kind == graphql.KindList || obj.Kind == graphql.KindNonNull {
+			obj = obj.OfType
+		}
+	}
+
+	return false
+}
+
+// resolveContentList returns the resolver of a list of items of type t, whose
+// object type is obj. Lists can only be filtered by the fields of obj.
+func resolveContentList(t string, obj *graphql.Type) func(p graphql.Params) (interface{}, error) {
+	return func(p graphql.Params) (interface{}, error) {
+		it := item.Types[t]
+		if graphqlHidden(p.Context, it()) {
+			return nil, fmt.Errorf("%s content is not available", t)
+		}
+
+		count, _ := p.Args["count"].(int)
+		offset, _ := p.Args["offset"].(int)
+		order, _ := p.Args["order"].(string)
+		if order = strings.ToLower(order); order != "asc" {
+			order = "desc"
+		}
+
+		var filters []search.Filter
+		list, _ := p.Args["filter"].([]interface{})
+		for _, v := range list {
+			f := v.(map[string]interface{})
+			filter := search.Filter{Field: f["field"].(string), Op: search.OpEqual, Value: f["value"].(string)}
+			if op, ok := f["op"].(string); ok {
+				filter.Op = op
+			}
+
+			if !search.ValidOp(filter.Op) {
+				return nil, fmt.Errorf("unsupported filter operator: %s", filter.Op)
+			}
+
+			if !filterable(obj, filter.Field) {
+				return nil, fmt.Errorf("%s can't be filtered by field: %s", t, filter.Field)
+			}
+
+			filters = append(filters, filter)
+		}
+
+		// content is paged after leaving out what isn't public, so pages
+		// aren't left short
+		result, err := filterContent(t, it, filters, "timestamp", order)
+		if err != nil {
+			return nil, err
+		}
+
+		start, end := page(len(result), count, offset)
+		posts := result[start:end]
+
+		var items = []interface{}{}
+		for i := range posts {
+			m, err := decodeContent(it, posts[i])
+			if err != nil {
+				return nil, err
+			}
+
+			if m != nil {
+				items = append(items, m)
+			}
+		}
+
+		return items, nil
+	}
+}
+
+// resolveReference returns the resolver of a Referenceable field, which loads
+// the content referred to by the path, or list of paths, stored in the field
+func resolveReference(field string) func(p graphql.Params) (interface{}, error) {
+	return func(p graphql.Params) (interface{}, error) {
+		src, _ := p.Source.(map[string]interface{})
+		switch v := src[field].(type) {
+		case string:
+			return loadReference(p.Context, v)
+
+		case []interface{}:
+			var items = []interface{}{}
+			for i := range v {
+				path, ok := v[i].(string)
+				if !ok {
+					continue
+				}
+
+				m, err := loadReference(p.Context, path)
+				if err != nil {
+					return nil, err
+				}
+
+				if m != nil {
+					items = append(items, m)
+				}
+			}
+			return items, nil
+		}
+
+		return nil, nil
+	}
+}
+
+// loadReference loads the content referred to by path for a resolver. It
+// returns nil if the content does not exist or is not public.
+func loadReference(ctx context.Context, path string) (interface{}, error) {
+	r, ok := ctx.Value(graphqlContextKey{}).(graphqlRequest)
+	if !ok {
+		return nil, nil
+	}
+
+	post, err := referencedContent(r.res, r.req, path)
+	if err != nil || post == nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	err = json.Unmarshal(post, &m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// lowerFirst returns s with its first letter in lower case
+func lowerFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToLower(r)) + s[size:]
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ponzu-cms/ponzu/system/graphql"
+	"github.com/ponzu-cms/ponzu/system/item"
+)
+
+type testAuthor struct {
+	item.Item
+
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func (a *testAuthor) Omit() []string { return []string{"password"} }
+
+type testArticle struct {
+	item.Item
+
+	Title   string   `json:"title"`
+	Rating  float64  `json:"rating"`
+	Author  string   `json:"author"`
+	Editors []string `json:"editors"`
+	Meta    struct {
+		Public bool `json:"public"`
+	} `json:"meta"`
+	Ignored string `json:"-"`
+}
+
+func (a *testArticle) References() map[string]string {
+	return map[string]string{"author": "TestAuthor", "editors": "TestAuthor"}
+}
+
+func TestBuildGraphQLSchema(t *testing.T) {
+	item.Types["TestAuthor"] = func() interface{} { return new(testAuthor) }
+	item.Types["TestArticle"] = func() interface{} { return new(testArticle) }
+	defer delete(item.Types, "TestAuthor")
+	defer delete(item.Types, "TestArticle")
+
+	schema, err := buildGraphQLSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := schema.Execute(context.Background(), `{
+		article: __type(name: "TestArticle") { fields { name type { kind name ofType { name } } } }
+		author: __type(name: "TestAuthor") { fields { name } }
+		query: __type(name: "Query") { fields { name } }
+	}`, "", nil)
+	if len(res.Errors) > 0 {
+		t.Fatal(res.Errors[0])
+	}
+
+	j, err := json.Marshal(res.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(j)
+
+	for _, want := range []string{
+		`{"name":"id","type":{"kind":"SCALAR","name":"Int","ofType":null}}`,
+		`{"name":"uuid","type":{"kind":"SCALAR","name":"String","ofType":null}}`,
+		`{"name":"rating","type":{"kind":"SCALAR","name":"Float","ofType":null}}`,
+		`{"name":"author","type":{"kind":"OBJECT","name":"TestAuthor","ofType":null}}`,
+		`{"name":"editors","type":{"kind":"LIST","name":null,"ofType":{"name":null}}}`,
+		`{"name":"meta","type":{"kind":"OBJECT","name":"TestArticleMeta","ofType":null}}`,
+		`{"name":"testArticle"}`,
+		`{"name":"testArticleList"}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in schema: %s", want, got)
+		}
+	}
+
+	for _, unwanted := range []string{`"Ignored"`, `"password"`} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("unexpected field %s in schema: %s", unwanted, got)
+		}
+	}
+}
+
+func TestGraphQLFilterable(t *testing.T) {
+	objects := map[string]*graphql.Type{
+		"TestAuthor":  {Kind: graphql.KindObject, Name: "TestAuthor"},
+		"TestArticle": {Kind: graphql.KindObject, Name: "TestArticle"},
+	}
+	nested := make(map[string]*graphql.Type)
+	objects["TestAuthor"].Fields = contentFields("TestAuthor", new(testAuthor), objects, nested)
+	objects["TestArticle"].Fields = contentFields("TestArticle", new(testArticle), objects, nested)
+
+	cases := []struct {
+		typ, field string
+		want       bool
+	}{
+		{"TestArticle", "title", true},
+		{"TestArticle", "timestamp", true},
+		{"TestArticle", "meta.public", true},
+		{"TestArticle", "meta.unknown", false},
+		{"TestArticle", "author", true},
+		{"TestArticle", "author.name", false},
+		{"TestArticle", "Ignored", false},
+		{"TestArticle", "utm_source", false},
+		{"TestAuthor", "name", true},
+		{"TestAuthor", "password", false},
+	}
+
+	for _, c := range cases {
+		if got := filterable(objects[c.typ], c.field); got != c.want {
+			t.Errorf("%s %s: expected %v, got %v", c.typ, c.field, c.want, got)
+		}
+	}
+}
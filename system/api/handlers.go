@@ -2,13 +2,15 @@ package api
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/ponzu-cms/ponzu/system/db"
 	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/search"
+	"github.com/ponzu-cms/ponzu/system/trace"
 )
 
 // deprecating from API, but going to provide code here in case someone wants it
@@ -72,19 +74,94 @@ func contentsHandler(res http.ResponseWriter, req *http.Request) {
 		order = "desc"
 	}
 
-	opts := db.QueryOptions{
-		Count:  count,
-		Offset: offset,
-		Order:  order,
+	filters, ok := parseFilters(q, it())
+	if !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// content is sorted by timestamp, or by the order set in the admin for
+	// content which is manually ordered, first to last unless order is given
+	sortBy := "timestamp"
+	switch q.Get("sort") {
+	case "", "timestamp":
+	case "order":
+		if !item.IsOrderable(it()) {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		sortBy = "order"
+		if q.Get("order") == "" {
+			order = "asc"
+		}
+	default:
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// a cursor pages through content by position rather than offset, so
+	// pages don't shift as content is added or removed
+	if _, ok := q["after"]; ok {
+		if sortBy != "timestamp" {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		cursorContentsHandler(res, req, t, it, filters, count, q.Get("after"), order)
+		return
+	}
+
+	filteredContentsHandler(res, req, t, it, filters, count, offset, sortBy, order)
+}
+
+// cursorContentsHandler writes the page of content of type t matching filters
+// after the cursor after, with the cursor of the next page as next_cursor,
+// which is null on the last page. An empty after starts from the first page.
+func cursorContentsHandler(res http.ResponseWriter, req *http.Request, t string, it func() interface{}, filters []search.Filter, count int, after, order string) {
+	q := db.NewQuery(t).OrderBy("timestamp", order).After(after)
+	if count > 0 {
+		q.Limit(count)
+	}
+	for _, f := range filters {
+		q.Where(f.Field, f.Op, f.Value)
+	}
+
+	_, span := trace.Start(req.Context(), "db.NewQuery")
+	span.SetAttribute("ponzu.type", t)
+	posts, next, err := q.Page()
+	span.SetError(err)
+	span.End()
+	if err == db.ErrInvalidCursor {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.For(req).Error("Error paging content:", t, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 
-	_, bb := db.Query(t+"__sorted", opts)
 	var result = []json.RawMessage{}
-	for i := range bb {
-		result = append(result, bb[i])
+	for i := range posts {
+		// leave out content which is not yet published
+		if unpublished(it, posts[i]) {
+			continue
+		}
+
+		result = append(result, posts[i])
 	}
 
-	j, err := fmtJSON(result...)
+	resp := map[string]interface{}{
+		"data":        result,
+		"has_more":    next != "",
+		"next_cursor": nil,
+	}
+	if next != "" {
+		resp["next_cursor"] = next
+	}
+
+	j, err := encodeJSON(resp)
 	if err != nil {
 		res.WriteHeader(http.StatusInternalServerError)
 		return
@@ -96,7 +173,18 @@ func contentsHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	sendData(res, req, j)
+	j, ok, err := includeReferences(res, req, it(), j)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sendContent(res, req, t, false, j)
 }
 
 func contentHandler(res http.ResponseWriter, req *http.Request) {
@@ -125,10 +213,36 @@ func contentHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	post, err := db.Content(t + ":" + id)
-	if err != nil {
-		res.WriteHeader(http.StatusInternalServerError)
-		return
+	var post []byte
+	if token := q.Get("preview"); token != "" {
+		// a preview from the editor is served in place of the content, even
+		// if it isn't published or saved yet, but only to requests for it
+		var err error
+		post, err = db.Preview(token, t, id)
+		if err != nil {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		res.Header().Del("ETag")
+		res.Header().Set("Cache-Control", "private, no-store")
+		res.Header().Set("X-Robots-Tag", "noindex")
+	} else {
+		_, span := trace.Start(req.Context(), "db.Content")
+		span.SetAttribute("ponzu.type", t)
+		var err error
+		post, err = db.Content(t + ":" + id)
+		span.SetError(err)
+		span.End()
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if unpublished(pt, post) {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
 	}
 
 	push(res, req, pt, post)
@@ -145,7 +259,18 @@ func contentHandler(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	sendData(res, req, j)
+	j, ok, err = includeReferences(res, req, pt(), j)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sendContent(res, req, t, true, j)
 }
 
 func contentHandlerBySlug(res http.ResponseWriter, req *http.Request) {
@@ -157,23 +282,58 @@ func contentHandlerBySlug(res http.ResponseWriter, req *http.Request) {
 	}
 
 	// lookup type:id by slug key in __contentIndex
+	_, span := trace.Start(req.Context(), "db.ContentBySlug")
 	t, post, err := db.ContentBySlug(slug)
+	span.SetError(err)
+	span.End()
 	if err != nil {
-		log.Println("Error finding content by slug:", slug, err)
+		logger.For(req).Error("Error finding content by slug:", slug, err)
 		res.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	// send requests for the old slug of content to its new one
+	if t == "" {
+		to, err := db.SlugRedirect(slug)
+		if err != nil {
+			logger.For(req).Error("Error finding redirect for slug:", slug, err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if to == "" {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		q := req.URL.Query()
+		q.Set("slug", to)
+		http.Redirect(res, req, req.URL.Path+"?"+q.Encode(), http.StatusMovedPermanently)
+		return
+	}
+
 	it, ok := item.Types[t]
 	if !ok {
 		res.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	// the API key was checked against the requested type, so content of any
+	// other type is not served by its slug
+	if rt := req.URL.Query().Get("type"); (rt != "" && rt != t) || !keyAllows(req, db.ScopeRead, t) {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	if hide(it(), res, req) {
 		return
 	}
 
+	if unpublished(it, post) {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
 	push(res, req, it, post)
 
 	j, err := fmtJSON(json.RawMessage(post))
@@ -188,5 +348,16 @@ func contentHandlerBySlug(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	sendData(res, req, j)
+	j, ok, err = includeReferences(res, req, it(), j)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	sendContent(res, req, t, true, j)
 }
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+
+	"github.com/tidwall/gjson"
+)
+
+var (
+	dbDir  string
+	dbOnce sync.Once
+)
+
+// openDB opens the db in a temporary directory for the tests which need it.
+// The db can't be opened again once closed, so it is shared by the tests of
+// the package, each using content types of its own, and closed by TestMain.
+// The types are registered here, as content saved by one test may still be
+// sorted and indexed in the background while another runs, which is also why
+// these tests are kept after those changing item.Types.
+func openDB(t *testing.T) {
+	dbOnce.Do(func() {
+		item.Types["TestKeyAuthor"] = func() interface{} { return new(testAuthor) }
+		item.Types["TestKeyArticle"] = func() interface{} { return new(testArticle) }
+		item.Types["TestPagedArticle"] = func() interface{} { return new(testArticle) }
+
+		dir, err := ioutil.TempDir("", "ponzu-api")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the db is opened as system.db in the working directory
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chdir(wd)
+
+		err = os.Chdir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dbDir = dir
+		db.Init()
+	})
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if dbDir != "" {
+		db.Close()
+		os.RemoveAll(dbDir)
+	}
+
+	os.Exit(code)
+}
+
+func TestContentsHandlerPaging(t *testing.T) {
+	openDB(t)
+
+	// of five articles, the second is a draft and the fourth is scheduled,
+	// so only the first, third and fifth are public
+	later := time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+	for i := 1; i <= 5; i++ {
+		data := url.Values{
+			"title":     {fmt.Sprintf("Article %d", i)},
+			"timestamp": {strconv.Itoa(i * 1000)},
+		}
+		switch i {
+		case 2:
+			data.Set("state", item.StateDraft)
+		case 4:
+			data.Set("publish_at", strconv.FormatInt(later, 10))
+		}
+
+		_, err := db.SetContent("TestPagedArticle:-1", data)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db.SortContent("TestPagedArticle")
+
+	cases := []struct {
+		target string
+		ids    string
+		total  int64
+		more   bool
+	}{
+		{"/api/contents?type=TestPagedArticle&count=2", "[5,3]", 3, true},
+		{"/api/contents?type=TestPagedArticle&count=2&offset=1", "[1]", 3, false},
+		{"/api/contents?type=TestPagedArticle&count=-1", "[5,3,1]", 3, false},
+	}
+
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		contentsHandler(rec, httptest.NewRequest(http.MethodGet, c.target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", c.target, rec.Code)
+		}
+
+		body := rec.Body.Bytes()
+		if ids := gjson.GetBytes(body, "data.#.id").Raw; ids != c.ids {
+			t.Errorf("%s: expected ids %s, got %s", c.target, c.ids, ids)
+		}
+
+		if total := gjson.GetBytes(body, "total").Int(); total != c.total {
+			t.Errorf("%s: expected total %d, got %d", c.target, c.total, total)
+		}
+
+		if more := gjson.GetBytes(body, "has_more").Bool(); more != c.more {
+			t.Errorf("%s: expected has_more %v, got %v", c.target, c.more, more)
+		}
+	}
+}
+
+func TestKeyAuthTypes(t *testing.T) {
+	openDB(t)
+
+	author, err := db.SetContent("TestKeyAuthor:-1", url.Values{"name": {"Ann"}, "slug": {"ann"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.SetContent("TestKeyArticle:-1", url.Values{
+		"title":  {"Hello"},
+		"slug":   {"hello"},
+		"author": {item.ReferencePath("TestKeyAuthor", author)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scoped, _, err := db.NewAPIKey(db.APIKey{Name: "articles", Scope: db.ScopeRead, Types: []string{"TestKeyArticle"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, _, err := db.NewAPIKey(db.APIKey{Name: "all", Scope: db.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(key, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-API-Key", key)
+
+		rec := httptest.NewRecorder()
+		KeyAuth(db.ScopeRead, contentHandler)(rec, req)
+		return rec
+	}
+
+	// a key for one type can't read another by its slug
+	if rec := get(scoped, "/api/content?type=TestKeyArticle&slug=ann"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for the slug of another type, got %d", rec.Code)
+	}
+
+	if rec := get(scoped, "/api/content?type=TestKeyAuthor&slug=ann"); rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for a type the key doesn't allow, got %d", rec.Code)
+	}
+
+	rec := get(scoped, "/api/content?type=TestKeyArticle&slug=hello&include=author")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the slug of an allowed type, got %d", rec.Code)
+	}
+
+	// nor include references to it
+	if v := gjson.GetBytes(rec.Body.Bytes(), "data.0.author"); v.Type != gjson.Null {
+		t.Errorf("expected a reference to a type the key doesn't allow to be null, got %s", v.Raw)
+	}
+
+	rec = get(all, "/api/content?type=TestKeyArticle&slug=hello&include=author")
+	if name := gjson.GetBytes(rec.Body.Bytes(), "data.0.author.name").String(); name != "Ann" {
+		t.Errorf("expected the reference to be included for a key allowing all types, got %s", rec.Body.String())
+	}
+}
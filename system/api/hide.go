@@ -9,6 +9,9 @@ import (
 func hide(it interface{}, res http.ResponseWriter, req *http.Request) bool {
 	// check if should be hidden
 	if h, ok := it.(item.Hideable); ok {
+		// whether it is hidden can depend on anything in the request
+		uncacheable(res)
+
 		err := h.Hide(res, req)
 		if err == item.ErrAllowHiddenItem {
 			return false
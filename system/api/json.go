@@ -3,8 +3,9 @@ package api
 import (
 	"bytes"
 	"encoding/json"
-	"log"
 	"net/http"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
 )
 
 func fmtJSON(data ...json.RawMessage) ([]byte, error) {
@@ -21,13 +22,65 @@ func fmtJSON(data ...json.RawMessage) ([]byte, error) {
 	enc := json.NewEncoder(buf)
 	err := enc.Encode(resp)
 	if err != nil {
-		log.Println("Failed to encode data to JSON:", err)
+		logger.Error("Failed to encode data to JSON:", err)
 		return nil, err
 	}
 
 	return buf.Bytes(), nil
 }
 
+// pageResponse makes a response for a page of data selected by count and
+// offset from a list of total items. It adds the total and whether there are
+// more pages after this one to the usual "data" array.
+func pageResponse(total, count, offset int, data []json.RawMessage) map[string]interface{} {
+	if data == nil {
+		data = []json.RawMessage{}
+	}
+
+	return map[string]interface{}{
+		"data":     data,
+		"total":    total,
+		"has_more": count > 0 && (offset+1)*count < total,
+	}
+}
+
+// encodeJSON encodes a response made by pageResponse or similar
+func encodeJSON(resp map[string]interface{}) ([]byte, error) {
+	var buf = &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	err := enc.Encode(resp)
+	if err != nil {
+		logger.Error("Failed to encode data to JSON:", err)
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// page returns the bounds of the page selected by count and offset within a
+// list of n items, where a count of -1 selects every item
+func page(n, count, offset int) (start, end int) {
+	if count < 0 {
+		return 0, n
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	start = count * offset
+	if start > n {
+		start = n
+	}
+
+	end = start + count
+	if end > n {
+		end = n
+	}
+
+	return start, end
+}
+
 func toJSON(data []string) ([]byte, error) {
 	var buf = &bytes.Buffer{}
 	enc := json.NewEncoder(buf)
@@ -37,7 +90,7 @@ func toJSON(data []string) ([]byte, error) {
 
 	err := enc.Encode(resp)
 	if err != nil {
-		log.Println("Failed to encode data to JSON:", err)
+		logger.Error("Failed to encode data to JSON:", err)
 		return nil, err
 	}
 
@@ -45,13 +98,20 @@ func toJSON(data []string) ([]byte, error) {
 }
 
 // sendData should be used any time you want to communicate
-// data back to a foreign client
+// data back to a foreign client. Its Content-Type is application/json, unless
+// another json media type has already been set.
 func sendData(res http.ResponseWriter, req *http.Request, data []byte) {
-	res.Header().Set("Content-Type", "application/json")
-	res.Header().Set("Vary", "Accept-Encoding")
+	if res.Header().Get("Content-Type") == "" {
+		res.Header().Set("Content-Type", "application/json")
+	}
+	res.Header().Add("Vary", "Accept-Encoding")
+
+	if notModified(res, req, data) {
+		return
+	}
 
 	_, err := res.Write(data)
 	if err != nil {
-		log.Println("Error writing to response in sendData")
+		logger.For(req).Error("Error writing to response in sendData")
 	}
 }
@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// jsonAPIMediaType is the media type of JSON:API documents, which a client can
+// send in its Accept header to get responses in JSON:API format
+const jsonAPIMediaType = "application/vnd.api+json"
+
+// wantsJSONAPI reports whether req asked for a response in JSON:API format,
+// either with a "format=jsonapi" query param or in its Accept header
+func wantsJSONAPI(req *http.Request) bool {
+	if req.URL.Query().Get("format") == "jsonapi" {
+		return true
+	}
+
+	return strings.Contains(req.Header.Get("Accept"), jsonAPIMediaType)
+}
+
+// sendContent writes j, a response of content of type t in the usual Ponzu
+// envelope, once the BeforeAPIResponse hook of the type has rewritten it and
+// its fields are projected as the client asked, converted to a JSON:API
+// document if the client asked for one. If single is true, the response is for
+// one item rather than a list. The content of responses from a search across
+// all types is tagged with its own type, so t is empty for those.
+func sendContent(res http.ResponseWriter, req *http.Request, t string, single bool, j []byte) {
+	j, err := localize(res, req, t, j)
+	if err != nil {
+		logger.For(req).Error("Error localizing content for:", t, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if it, ok := item.Types[t]; ok {
+		if hook, ok := it().(item.Hookable); ok {
+			var err error
+			j, err = hook.BeforeAPIResponse(res, req, j)
+			if err != nil {
+				logger.For(req).Error("Error running BeforeAPIResponse method for:", t, err)
+				return
+			}
+		}
+	}
+
+	j, err = projectFields(req, t == "", j)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Add("Vary", "Accept")
+
+	if !wantsJSONAPI(req) {
+		sendData(res, req, j)
+		return
+	}
+
+	doc, err := toJSONAPI(req, t, single, j)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", jsonAPIMediaType)
+	sendData(res, req, doc)
+}
+
+// jsonAPIResource is a JSON:API resource object
+type jsonAPIResource struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id"`
+	Attributes json.RawMessage `json:"attributes"`
+}
+
+// toJSONAPI converts a response in the Ponzu envelope to a JSON:API document.
+// Each item becomes a resource with its id, and every other field of the item
+// as its attributes. Other top-level keys of the envelope, like total, are put
+// in the document's meta, and lists get links to their other pages.
+func toJSONAPI(req *http.Request, t string, single bool, j []byte) ([]byte, error) {
+	env := gjson.ParseBytes(j)
+
+	var resources = []jsonAPIResource{}
+	for _, v := range env.Get("data").Array() {
+		typ, data := t, v
+		if t == "" {
+			typ, data = v.Get("type").String(), v.Get("content")
+		}
+
+		attrs, err := sjson.DeleteBytes([]byte(data.Raw), "id")
+		if err != nil {
+			return nil, err
+		}
+
+		resources = append(resources, jsonAPIResource{
+			Type:       typ,
+			ID:         data.Get("id").String(),
+			Attributes: attrs,
+		})
+	}
+
+	meta := make(map[string]json.RawMessage)
+	env.ForEach(func(k, v gjson.Result) bool {
+		if k.String() != "data" {
+			meta[k.String()] = json.RawMessage(v.Raw)
+		}
+
+		return true
+	})
+
+	doc := map[string]interface{}{
+		"links": jsonAPILinks(req, env.Get("total")),
+	}
+
+	if single {
+		if len(resources) > 0 {
+			doc["data"] = resources[0]
+		} else {
+			doc["data"] = nil
+		}
+	} else {
+		doc["data"] = resources
+	}
+
+	if len(meta) > 0 {
+		doc["meta"] = meta
+	}
+
+	return encodeJSON(doc)
+}
+
+// jsonAPILinks returns the links of a JSON:API document for req. If the total
+// number of items in a list is known, links to its first, previous, next and
+// last pages are added using the count and offset params of req.
+func jsonAPILinks(req *http.Request, total gjson.Result) map[string]interface{} {
+	q := req.URL.Query()
+	links := map[string]interface{}{
+		"self": req.URL.RequestURI(),
+	}
+
+	count, err := strconv.Atoi(q.Get("count"))
+	if err != nil {
+		count = 10
+	}
+
+	offset, err := strconv.Atoi(q.Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	if !total.Exists() || count < 1 {
+		return links
+	}
+
+	pageURL := func(n int) string {
+		q.Set("count", strconv.Itoa(count))
+		q.Set("offset", strconv.Itoa(n))
+		return req.URL.Path + "?" + q.Encode()
+	}
+
+	last := 0
+	if n := int(total.Int()); n > 0 {
+		last = (n - 1) / count
+	}
+
+	links["first"] = pageURL(0)
+	links["last"] = pageURL(last)
+	links["prev"] = nil
+	links["next"] = nil
+
+	if offset > 0 {
+		links["prev"] = pageURL(offset - 1)
+	}
+
+	if offset < last {
+		links["next"] = pageURL(offset + 1)
+	}
+
+	return links
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestToJSONAPI(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/contents?type=Post&count=2&offset=1&format=jsonapi", nil)
+	if !wantsJSONAPI(req) {
+		t.Fatal("expected format=jsonapi to be wanted")
+	}
+
+	j := []byte(`{"data":[{"id":3,"title":"Ponzu"},{"id":4,"title":"Soy"}],"total":5,"has_more":true}`)
+	doc, err := toJSONAPI(req, "Post", false, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := gjson.ParseBytes(doc)
+	if res.Get("data.#").Int() != 2 {
+		t.Fatalf("expected 2 resources, got %s", doc)
+	}
+
+	first := res.Get("data.0")
+	if first.Get("type").String() != "Post" || first.Get("id").String() != "3" {
+		t.Errorf("unexpected resource identity: %s", first.Raw)
+	}
+
+	if first.Get("attributes.id").Exists() || first.Get("attributes.title").String() != "Ponzu" {
+		t.Errorf("unexpected attributes: %s", first.Raw)
+	}
+
+	if res.Get("meta.total").Int() != 5 {
+		t.Errorf("expected total in meta, got %s", doc)
+	}
+
+	links := res.Get("links")
+	if links.Get("prev").String() != "/api/contents?count=2&format=jsonapi&offset=0&type=Post" {
+		t.Errorf("unexpected prev link: %s", links.Raw)
+	}
+
+	if links.Get("last").String() != "/api/contents?count=2&format=jsonapi&offset=2&type=Post" {
+		t.Errorf("unexpected last link: %s", links.Raw)
+	}
+
+	single, err := toJSONAPI(req, "Post", true, []byte(`{"data":[{"id":3,"title":"Ponzu"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if gjson.GetBytes(single, "data.id").String() != "3" {
+		t.Errorf("expected a single resource object, got %s", single)
+	}
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// requestLocale returns the locale content is served in for req, from those
+// configured in the admin
+func requestLocale(req *http.Request) string {
+	def, others := db.Locales()
+	return negotiateLocale(req, def, others)
+}
+
+// negotiateLocale returns the locale requested by the locale query param of
+// req if it is def or one of others, or else the one best matching its
+// Accept-Language header, or else def. A language range matches a locale
+// equal to it, or else one with the same primary language, so that "fr-CA"
+// is served "fr".
+func negotiateLocale(req *http.Request, def string, others []string) string {
+	locales := append([]string{def}, others...)
+
+	requested := strings.ToLower(req.URL.Query().Get("locale"))
+	for _, l := range locales {
+		if l == requested {
+			return l
+		}
+	}
+
+	best, bestQ := def, 0.0
+	for _, r := range strings.Split(req.Header.Get("Accept-Language"), ",") {
+		parts := strings.Split(r, ";")
+		tag := strings.ToLower(strings.TrimSpace(parts[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, p := range parts[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				v, err := strconv.ParseFloat(p[2:], 64)
+				if err == nil {
+					q = v
+				}
+			}
+		}
+
+		if q <= bestQ {
+			continue
+		}
+
+		if l, ok := matchLocale(tag, locales); ok {
+			best, bestQ = l, q
+		}
+	}
+
+	return best
+}
+
+// matchLocale returns the locale of locales matching the language range tag
+func matchLocale(tag string, locales []string) (string, bool) {
+	for _, l := range locales {
+		if l == tag {
+			return l, true
+		}
+	}
+
+	lang := strings.SplitN(tag, "-", 2)[0]
+	for _, l := range locales {
+		if strings.SplitN(l, "-", 2)[0] == lang {
+			return l, true
+		}
+	}
+
+	return "", false
+}
+
+// localize replaces the values of the localized fields of each Localizable
+// item in the response j, of content type t, with their translations into the
+// locale requested by req, and removes the translations from the items.
+// Items of a search across all types are tagged with their type, as when t is
+// "".
+func localize(res http.ResponseWriter, req *http.Request, t string, j []byte) ([]byte, error) {
+	def, others := db.Locales()
+	locale := negotiateLocale(req, def, others)
+
+	// the response differs by locale only once there are others to serve
+	if len(others) > 0 {
+		res.Header().Set("Content-Language", locale)
+		res.Header().Add("Vary", "Accept-Language")
+	}
+
+	for i, v := range gjson.GetBytes(j, "data").Array() {
+		typ, path, data := t, fmt.Sprintf("data.%d", i), v
+		if t == "" {
+			typ, path, data = v.Get("type").String(), path+".content", v.Get("content")
+		}
+
+		it, ok := item.Types[typ]
+		if !ok {
+			continue
+		}
+
+		l, ok := it().(item.Localizable)
+		if !ok {
+			continue
+		}
+
+		translated, err := item.Translate([]byte(data.Raw), l.Localize(), locale)
+		if err != nil {
+			return nil, err
+		}
+
+		j, err = sjson.SetRawBytes(j, path, translated)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return j, nil
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiateLocale(t *testing.T) {
+	others := []string{"fr", "pt-br"}
+
+	cases := []struct {
+		query  string
+		accept string
+		want   string
+	}{
+		{"", "", "en"},
+		{"?locale=fr", "pt-BR", "fr"},
+		{"?locale=FR", "", "fr"},
+		{"?locale=de", "", "en"},
+		{"", "fr-CA,en;q=0.8", "fr"},
+		{"", "de,pt-BR;q=0.5,fr;q=0.4", "pt-br"},
+		{"", "pt", "pt-br"},
+		{"", "en;q=0.9,fr", "fr"},
+		{"", "de,*;q=0.1", "en"},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/api/contents"+c.query, nil)
+		if c.accept != "" {
+			req.Header.Set("Accept-Language", c.accept)
+		}
+
+		if got := negotiateLocale(req, "en", others); got != c.want {
+			t.Errorf("%q %q: expected %s, got %s", c.query, c.accept, c.want, got)
+		}
+	}
+}
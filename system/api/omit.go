@@ -1,9 +1,8 @@
 package api
 
 import (
-	"log"
-
 	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
 
 	"github.com/tidwall/sjson"
 )
@@ -28,7 +27,7 @@ func omitFields(om item.Omittable, data []byte, pathPrefix string) ([]byte, erro
 		var err error
 		omitted, err = sjson.DeleteBytes(omitted, pathPrefix+fields[i])
 		if err != nil {
-			log.Println("Erorr omitting field:", fields[i], "from item.Omittable:", om)
+			logger.Error("Erorr omitting field:", fields[i], "from item.Omittable:", om)
 			return nil, err
 		}
 	}
@@ -1,10 +1,10 @@
 package api
 
 import (
-	"log"
 	"net/http"
 
 	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
 
 	"github.com/tidwall/gjson"
 )
@@ -25,7 +25,7 @@ func push(res http.ResponseWriter, req *http.Request, pt func() interface{}, dat
 				val.ForEach(func(k, v gjson.Result) bool {
 					err := pusher.Push(v.String(), nil)
 					if err != nil {
-						log.Println("Error during Push of value:", v.String())
+						logger.Error("Error during Push of value:", v.String())
 					}
 
 					return true
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/api/analytics"
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// RateLimiter decides whether a request from the client identified by key is
+// allowed, given the client may make rate requests per second with bursts of
+// up to burst requests. If not, it returns the time until the client may make
+// another request. The default RateLimiter keeps its state in memory, so each
+// instance of Ponzu limits clients separately; SetRateLimiter can replace it
+// with one shared between instances.
+type RateLimiter interface {
+	Allow(key string, rate float64, burst int) (bool, time.Duration)
+}
+
+var (
+	ipLimits RateLimiter = newLimiter()
+
+	// flagged holds the time until which each IP flagged by FlagIP is limited
+	// to FlaggedRate
+	flagged = struct {
+		sync.RWMutex
+		until map[string]time.Time
+	}{until: make(map[string]time.Time)}
+
+	// FlagDuration is how long an IP flagged by FlagIP is held to FlaggedRate
+	FlagDuration = time.Hour
+
+	// FlaggedRate is the fraction of the configured rate limit allowed for a
+	// flagged IP, which may make no bursts of requests
+	FlaggedRate = 0.1
+)
+
+// SetRateLimiter replaces the RateLimiter used to limit requests by client IP
+func SetRateLimiter(l RateLimiter) {
+	ipLimits = l
+}
+
+// FlagIP holds ip to a tighter rate limit for FlagDuration. Its signature
+// matches analytics.Options.OnAbuse, so IPs found making too many requests can
+// be flagged as they are detected.
+func FlagIP(ip string, count int) {
+	flagged.Lock()
+	flagged.until[ip] = time.Now().Add(FlagDuration)
+	flagged.Unlock()
+
+	logger.Warn(fmt.Sprintf("Limiting API requests from %s after %d requests in a minute", ip, count))
+}
+
+// IsFlagged reports whether ip has been flagged by FlagIP, and is still held to
+// the tighter rate limit
+func IsFlagged(ip string) bool {
+	return isFlagged(ip, time.Now())
+}
+
+// isFlagged reports whether ip has been flagged and is still within its
+// FlagDuration
+func isFlagged(ip string, now time.Time) bool {
+	flagged.RLock()
+	until, ok := flagged.until[ip]
+	flagged.RUnlock()
+	if !ok {
+		return false
+	}
+
+	if now.After(until) {
+		flagged.Lock()
+		delete(flagged.until, ip)
+		flagged.Unlock()
+		return false
+	}
+
+	return true
+}
+
+// RateLimit wraps a HandlerFunc to limit the requests allowed from each client
+// IP to the rate_limit_rps and rate_limit_burst set in the config. Requests
+// over the limit get a 429 response with a Retry-After header.
+func RateLimit(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		rate, _ := db.ConfigCache("rate_limit_rps").(float64)
+		if rate <= 0 {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		burst, _ := db.ConfigCache("rate_limit_burst").(float64)
+		if burst < 1 {
+			burst = math.Max(rate, 1)
+		}
+
+		ip := analytics.ClientIP(req)
+		if isFlagged(ip, time.Now()) {
+			rate *= FlaggedRate
+			burst = 1
+		}
+
+		ok, wait := ipLimits.Allow(ip, rate, int(burst))
+		if !ok {
+			res.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(wait.Seconds()))))
+			res.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(res, req)
+	})
+}
+
+// limiter holds a token bucket per client key. Each bucket is refilled at a
+// steady rate up to its burst size, and a request is allowed if it can take a
+// token from its bucket.
+type limiter struct {
+	sync.Mutex
+	buckets map[string]*tokenBucket
+	pruned  time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{buckets: make(map[string]*tokenBucket)}
+}
+
+// Allow implements RateLimiter
+func (l *limiter) Allow(key string, rate float64, burst int) (bool, time.Duration) {
+	return l.allow(key, rate, burst, time.Now())
+}
+
+// allow takes a token from the bucket for key, which is refilled with rate
+// tokens per second up to burst. If no token is available, it returns false
+// and the time until one will be.
+func (l *limiter) allow(key string, rate float64, burst int, now time.Time) (bool, time.Duration) {
+	if rate <= 0 || burst < 1 {
+		return true, 0
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	// drop buckets which have been idle long enough to have refilled, since
+	// a new bucket starts full anyway
+	if now.Sub(l.pruned) > time.Minute {
+		full := time.Duration(float64(burst) / rate * float64(time.Second))
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > full {
+				delete(l.buckets, k)
+			}
+		}
+		l.pruned = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, wait
+	}
+
+	b.tokens--
+	return true, 0
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	l := newLimiter()
+	now := time.Now()
+
+	// a burst of 3 is allowed at once, then the 4th must wait for a refill
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("a", 1, 3, now); !ok {
+			t.Fatalf("request %d within burst was limited", i+1)
+		}
+	}
+
+	ok, wait := l.allow("a", 1, 3, now)
+	if ok || wait <= 0 || wait > time.Second {
+		t.Errorf("expected limit with wait up to 1s, got %v %v", ok, wait)
+	}
+
+	// other keys have their own bucket
+	if ok, _ := l.allow("b", 1, 3, now); !ok {
+		t.Error("separate key was limited")
+	}
+
+	// one token is refilled each second
+	if ok, _ := l.allow("a", 1, 3, now.Add(time.Second)); !ok {
+		t.Error("request after refill was limited")
+	}
+}
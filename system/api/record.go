@@ -2,15 +2,66 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/ponzu-cms/ponzu/system/api/analytics"
 )
 
-// Record wraps a HandlerFunc to record API requests for analytical purposes
+// Record wraps a HandlerFunc to record API requests for analytical purposes,
+// including the response status and the time taken to serve them
 func Record(next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-		go analytics.Record(req)
+		start := time.Now()
 
-		next.ServeHTTP(res, req)
+		// only expose Push if the underlying response supports it, so that
+		// handlers checking for an http.Pusher still behave the same
+		sres := &statusResponseWriter{ResponseWriter: res}
+		if pusher, ok := res.(http.Pusher); ok {
+			next.ServeHTTP(statusPusher{sres, pusher}, req)
+		} else {
+			next.ServeHTTP(sres, req)
+		}
+
+		go analytics.RecordResponseWithDuration(req, sres.Status(), time.Since(start))
 	})
 }
+
+// statusResponseWriter keeps the status code written to a response so that it
+// can be recorded once the response is complete
+type statusResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+// statusPusher is a statusResponseWriter for responses supporting HTTP/2 push
+type statusPusher struct {
+	*statusResponseWriter
+	http.Pusher
+}
+
+func (sw *statusResponseWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusResponseWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+
+	return sw.ResponseWriter.Write(p)
+}
+
+// Status returns the status code written to the response, which is 200 OK if
+// the handler wrote nothing at all
+func (sw *statusResponseWriter) Status() int {
+	if sw.status == 0 {
+		return http.StatusOK
+	}
+
+	return sw.status
+}
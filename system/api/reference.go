@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// referencedContent returns the json of the content referred to by path, with
+// any Omittable fields removed. It returns nil if the content no longer exists,
+// is not public, or is of a type the request's API key doesn't allow reading,
+// so that dangling references resolve to null.
+func referencedContent(res http.ResponseWriter, req *http.Request, path string) ([]byte, error) {
+	t, id, ok := item.ParseReference(path)
+	if !ok {
+		return nil, nil
+	}
+
+	it, ok := item.Types[t]
+	if !ok || !keyAllows(req, db.ScopeRead, t) || hidden(it(), res, req) {
+		return nil, nil
+	}
+
+	post, err := db.Content(t + ":" + id)
+	if err != nil {
+		// the type has no content bucket, so nothing can be referenced
+		return nil, nil
+	}
+
+	if len(post) == 0 || unpublished(it, post) {
+		return nil, nil
+	}
+
+	return omitItem(it(), post)
+}
+
+// includes returns the fields named in the include query params of req, which
+// may be repeated or comma separated
+func includes(req *http.Request) []string {
+	var fields []string
+	for _, v := range req.URL.Query()["include"] {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	return fields
+}
+
+// includeReferences replaces the references held by the fields of it named in
+// the include query params of req with the content they refer to, in each item
+// of the response j. ok is false if a field named is not one of the
+// Referenceable fields of it.
+func includeReferences(res http.ResponseWriter, req *http.Request, it interface{}, j []byte) (_ []byte, ok bool, err error) {
+	fields := includes(req)
+	if len(fields) == 0 {
+		return j, true, nil
+	}
+
+	r, isRef := it.(item.Referenceable)
+	if !isRef {
+		return nil, false, nil
+	}
+
+	refs := r.References()
+	for _, f := range fields {
+		if _, ok := refs[f]; !ok {
+			return nil, false, nil
+		}
+	}
+
+	n := int(gjson.GetBytes(j, "data.#").Int())
+	for i := 0; i < n; i++ {
+		for _, f := range fields {
+			path := "data." + strconv.Itoa(i) + "." + f
+			v := gjson.GetBytes(j, path)
+			if !v.Exists() {
+				continue
+			}
+
+			var included []byte
+			if strings.HasPrefix(v.Raw, "[") {
+				included = []byte("[")
+				for k, ref := range v.Array() {
+					if k > 0 {
+						included = append(included, ',')
+					}
+					included = append(included, referenceJSON(res, req, ref.String())...)
+				}
+				included = append(included, ']')
+			} else {
+				included = referenceJSON(res, req, v.String())
+			}
+
+			j, err = sjson.SetRawBytes(j, path, included)
+			if err != nil {
+				return nil, true, err
+			}
+		}
+	}
+
+	return j, true, nil
+}
+
+// referenceJSON returns the json of the content referred to by path, or null
+func referenceJSON(res http.ResponseWriter, req *http.Request, path string) []byte {
+	post, err := referencedContent(res, req, path)
+	if err != nil {
+		logger.For(req).Error("Error finding referenced content:", path, err)
+	}
+
+	if post == nil {
+		return []byte("null")
+	}
+
+	return post
+}
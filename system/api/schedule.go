@@ -0,0 +1,27 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// unpublished reports whether the content data of the type made by pt is not
+// yet public, because it has not been published through the workflow or has a
+// publish time in the future, and so should be left out of API responses
+func unpublished(pt func() interface{}, data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+
+	p := pt()
+	err := json.Unmarshal(data, p)
+	if err != nil {
+		logger.Error("Error decoding content to check whether it is public:", err)
+		return false
+	}
+
+	return !item.IsPublic(p, time.Now())
+}
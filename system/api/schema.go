@@ -0,0 +1,364 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/management/editor"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// contentSchema describes the fields of a content type, as returned by the
+// schema API
+type contentSchema struct {
+	Type   string        `json:"type"`
+	Fields []schemaField `json:"fields"`
+}
+
+// schemaField describes a field of a content type. Type is the JSON type of
+// its value: string, integer, number, boolean, array or object. Items is the
+// schema of the values of an array, and Fields those of an object. Widget is
+// the editor element used to edit the field in the admin, if any.
+type schemaField struct {
+	Name      string        `json:"name"`
+	Type      string        `json:"type"`
+	Format    string        `json:"format,omitempty"`
+	Required  bool          `json:"required"`
+	Widget    string        `json:"widget,omitempty"`
+	Reference string        `json:"reference,omitempty"`
+	Items     *schemaField  `json:"items,omitempty"`
+	Fields    []schemaField `json:"fields,omitempty"`
+}
+
+// editorField is what can be learnt about a field from its editor view
+type editorField struct {
+	widget   string
+	required bool
+}
+
+var (
+	colorType    = reflect.TypeOf(item.Color(""))
+	locationType = reflect.TypeOf(item.Location{})
+
+	editorElement = regexp.MustCompile(`(?i)<(input|textarea|select)\b([^>]*)>`)
+	editorAttr    = regexp.MustCompile(`([\w-]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+
+	// editorWrapper matches the elements wrapping the inputs of editor views
+	// made of several elements, such as editor.Tags, which are named by the
+	// class following their own
+	editorWrapper = regexp.MustCompile(`class="[^"]*\b__ponzu-(tags|multifile|color|geo|reference|group) ([\w.-]+)`)
+	wrapperWidget = map[string]string{
+		"tags":      "tags",
+		"multifile": "multifile",
+		"color":     "color",
+		"geo":       "geolocation",
+		"reference": "reference",
+		"group":     "group",
+	}
+)
+
+func schemaHandler(res http.ResponseWriter, req *http.Request) {
+	t := req.URL.Query().Get("type")
+	if t == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	it, ok := item.Types[t]
+	if !ok {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if hide(it(), res, req) {
+		return
+	}
+
+	sendSchemas(res, req, []contentSchema{buildSchema(t, it())})
+}
+
+func schemasHandler(res http.ResponseWriter, req *http.Request) {
+	var names []string
+	for t := range item.Types {
+		names = append(names, t)
+	}
+	sort.Strings(names)
+
+	schemas := []contentSchema{}
+	for _, t := range names {
+		it := item.Types[t]()
+		if hidden(it, res, req) {
+			continue
+		}
+
+		schemas = append(schemas, buildSchema(t, it))
+	}
+
+	sendSchemas(res, req, schemas)
+}
+
+func sendSchemas(res http.ResponseWriter, req *http.Request, schemas []contentSchema) {
+	j, err := json.Marshal(map[string][]contentSchema{"data": schemas})
+	if err != nil {
+		logger.For(req).Error("Failed to encode content schema to JSON:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendData(res, req, j)
+}
+
+// buildSchema describes content type t from the struct of it, an empty item
+// of the type, and the editor view it renders for itself
+func buildSchema(t string, it interface{}) contentSchema {
+	var omitted []string
+	if om, ok := it.(item.Omittable); ok {
+		omitted = om.Omit()
+	}
+
+	var refs map[string]string
+	if r, ok := it.(item.Referenceable); ok {
+		refs = r.References()
+	}
+
+	var fields map[string]editorField
+	if ed, ok := it.(editor.Editable); ok {
+		view, err := ed.MarshalEditor()
+		if err != nil {
+			logger.Error("Error rendering editor of", t, "for schema:", err)
+		}
+		fields = editorFields(view)
+	}
+
+	s := contentSchema{Type: t, Fields: []schemaField{}}
+	for _, f := range schemaFields(reflect.TypeOf(it), "", fields) {
+		if contains(omitted, f.Name) {
+			continue
+		}
+
+		if ref, ok := refs[f.Name]; ok {
+			f.Reference = ref
+			f.Widget = "reference"
+		}
+
+		s.Fields = append(s.Fields, f)
+	}
+
+	return s
+}
+
+// schemaFields describes the exported fields of struct type rt, including
+// those of embedded structs such as item.Item. The editor fields are keyed by
+// the path of each field from the content type, i.e. faqs.question for the
+// rows of a field group, to which prefix is the path of rt.
+func schemaFields(rt reflect.Type, prefix string, fields map[string]editorField) []schemaField {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	var sfs []schemaField
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := strings.Split(sf.Tag.Get("json"), ",")[0]
+
+		if sf.Anonymous && tag == "" && sf.Type.Kind() == reflect.Struct {
+			sfs = append(sfs, schemaFields(sf.Type, prefix, fields)...)
+			continue
+		}
+
+		if sf.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = sf.Name
+		}
+
+		f, ok := fieldSchema(sf.Type, prefix+name, fields)
+		if !ok {
+			continue
+		}
+		f.Name = name
+
+		sfs = append(sfs, f)
+	}
+
+	return sfs
+}
+
+// fieldSchema describes a value of type rt, or returns false if it has no
+// JSON representation
+func fieldSchema(rt reflect.Type, path string, fields map[string]editorField) (schemaField, bool) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	ef := fields[path]
+	f := schemaField{Widget: ef.widget, Required: ef.required}
+
+	switch {
+	case rt == colorType:
+		f.Type, f.Format = "string", "color"
+		return f, true
+
+	case rt == locationType:
+		f.Type, f.Format = "object", "location"
+		f.Fields = schemaFields(rt, path+".", fields)
+		return f, true
+
+	case rt.Implements(marshalerType) || rt.Implements(textMarshalerType) ||
+		reflect.PtrTo(rt).Implements(marshalerType) || reflect.PtrTo(rt).Implements(textMarshalerType):
+		f.Type = "string"
+		return f, true
+	}
+
+	switch rt.Kind() {
+	case reflect.String:
+		f.Type = "string"
+	case reflect.Bool:
+		f.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		f.Type = "number"
+	case reflect.Slice, reflect.Array:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			f.Type = "string"
+			return f, true
+		}
+
+		items, ok := fieldSchema(rt.Elem(), path, fields)
+		if !ok {
+			return f, false
+		}
+		// the widget and requirement belong to the field, not its values
+		items.Widget, items.Required = "", false
+
+		f.Type, f.Items = "array", &items
+	case reflect.Struct:
+		f.Type = "object"
+		f.Fields = schemaFields(rt, path+".", fields)
+	case reflect.Map:
+		f.Type = "object"
+	default:
+		return f, false
+	}
+
+	return f, true
+}
+
+// editorFields finds the widget used for each named field in an editor view,
+// and whether it is required. Fields are keyed by their path, with the index
+// of repeated values and field group rows left out, e.g. faqs.0.question is
+// faqs.question. A field edited with several inputs, such as a location, is
+// also described by its first input under its own name.
+func editorFields(view []byte) map[string]editorField {
+	fields := make(map[string]editorField)
+
+	for _, m := range editorWrapper.FindAllSubmatch(view, -1) {
+		name := fieldPath(string(m[2]))
+		if _, ok := fields[name]; !ok {
+			fields[name] = editorField{widget: wrapperWidget[string(m[1])]}
+		}
+	}
+
+	for _, m := range editorElement.FindAllSubmatch(view, -1) {
+		attrs := elementAttrs(m[2])
+		name, ok := attrs["name"]
+		if !ok || name == "" {
+			continue
+		}
+		_, required := attrs["required"]
+
+		path := fieldPath(name)
+		ef, ok := fields[path]
+		if !ok {
+			ef.widget = elementWidget(strings.ToLower(string(m[1])), attrs)
+		}
+		ef.required = ef.required || required
+		fields[path] = ef
+
+		// describe a field with several inputs by its first
+		if i := strings.LastIndex(path, "."); i > 0 {
+			parent := path[:i]
+			if pf, ok := fields[parent]; ok {
+				pf.required = pf.required || required
+				fields[parent] = pf
+			} else {
+				fields[parent] = ef
+			}
+		}
+	}
+
+	return fields
+}
+
+// fieldPath removes the numeric parts of the name of an editor input
+func fieldPath(name string) string {
+	var parts []string
+	for _, p := range strings.Split(name, ".") {
+		if _, err := strconv.Atoi(p); err == nil {
+			continue
+		}
+		parts = append(parts, p)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// elementAttrs parses the attributes of an element, given what follows its
+// tag name
+func elementAttrs(b []byte) map[string]string {
+	b = bytes.TrimSuffix(bytes.TrimSpace(b), []byte("/"))
+
+	attrs := make(map[string]string)
+	for _, m := range editorAttr.FindAllSubmatch(b, -1) {
+		key := strings.ToLower(string(m[1]))
+		if _, ok := attrs[key]; ok {
+			continue
+		}
+		attrs[key] = string(m[2]) + string(m[3]) + string(m[4])
+	}
+
+	return attrs
+}
+
+// elementWidget names the editor widget of a single named element
+func elementWidget(tag string, attrs map[string]string) string {
+	classes := strings.Fields(attrs["class"])
+	switch {
+	case contains(classes, "richtext-value"):
+		return "richtext"
+	case contains(classes, "store"):
+		return "file"
+	case tag == "textarea":
+		return "textarea"
+	case tag == "select":
+		return "select"
+	}
+
+	if t := strings.ToLower(attrs["type"]); t != "" {
+		return t
+	}
+
+	return "text"
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
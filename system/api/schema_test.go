@@ -0,0 +1,91 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/ponzu-cms/ponzu/management/editor"
+	"github.com/ponzu-cms/ponzu/system/item"
+)
+
+type testEvent struct {
+	item.Item
+
+	Title    string        `json:"title"`
+	Body     string        `json:"body"`
+	Tags     []string      `json:"tags"`
+	Color    item.Color    `json:"color"`
+	Venue    item.Location `json:"venue"`
+	Speaker  string        `json:"speaker"`
+	Internal string        `json:"internal"`
+}
+
+func (e *testEvent) MarshalEditor() ([]byte, error) {
+	return editor.Form(e,
+		editor.Field{View: editor.Input("Title", e, map[string]string{
+			"label":    "Title",
+			"type":     "text",
+			"required": "true",
+		})},
+		editor.Field{View: editor.Richtext("Body", e, map[string]string{"label": "Body"})},
+		editor.Field{View: editor.Tags("Tags", e, map[string]string{"label": "Tags"})},
+		editor.Field{View: editor.Color("Color", e, map[string]string{"label": "Color"})},
+		editor.Field{View: editor.Geolocation("Venue", e, map[string]string{"label": "Venue"})},
+	)
+}
+
+func (e *testEvent) References() map[string]string {
+	return map[string]string{"speaker": "TestAuthor"}
+}
+
+func (e *testEvent) Omit() []string { return []string{"internal"} }
+
+func TestBuildSchema(t *testing.T) {
+	s := buildSchema("TestEvent", new(testEvent))
+
+	fields := make(map[string]schemaField)
+	for _, f := range s.Fields {
+		fields[f.Name] = f
+	}
+
+	cases := []struct {
+		name     string
+		typ      string
+		widget   string
+		required bool
+	}{
+		{"id", "integer", "", false},
+		{"slug", "string", "text", false},
+		{"timestamp", "integer", "hidden", false},
+		{"title", "string", "text", true},
+		{"body", "string", "richtext", false},
+		{"tags", "array", "tags", false},
+		{"color", "string", "color", false},
+		{"venue", "object", "geolocation", false},
+		{"speaker", "string", "reference", false},
+	}
+
+	for _, c := range cases {
+		f, ok := fields[c.name]
+		if !ok {
+			t.Errorf("expected field %s in schema", c.name)
+			continue
+		}
+
+		if f.Type != c.typ || f.Widget != c.widget || f.Required != c.required {
+			t.Errorf("field %s: expected %s/%s/%v, got %s/%s/%v",
+				c.name, c.typ, c.widget, c.required, f.Type, f.Widget, f.Required)
+		}
+	}
+
+	if f := fields["tags"]; f.Items == nil || f.Items.Type != "string" {
+		t.Errorf("expected tags to be an array of strings, got %+v", f.Items)
+	}
+
+	if f := fields["venue"]; len(f.Fields) != 2 || f.Fields[0].Name != "lat" || f.Fields[0].Type != "number" {
+		t.Errorf("expected venue to have lat and lng, got %+v", f.Fields)
+	}
+
+	if _, ok := fields["internal"]; ok {
+		t.Error("expected omitted field internal to be left out of the schema")
+	}
+}
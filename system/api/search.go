@@ -0,0 +1,362 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/search"
+	"github.com/ponzu-cms/ponzu/system/trace"
+)
+
+// searchHit is a single result of a search across all content types
+type searchHit struct {
+	Type       string              `json:"type"`
+	Content    json.RawMessage     `json:"content"`
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+// defaultFragmentSize is the length in characters of the highlighted fragments
+// of search results if fragment_size is not given
+const defaultFragmentSize = 100
+
+// highlighting is the fields of search results to highlight and the size of
+// their fragments, as given by the highlight and fragment_size params
+type highlighting struct {
+	fields []string
+	size   int
+	opts   search.QueryOptions
+}
+
+// highlight returns the highlighted fragments of post, content of typeName,
+// matching query, or nil if no fields are to be highlighted
+func (h highlighting) highlight(typeName string, post []byte, query string) (map[string][]string, error) {
+	if len(h.fields) == 0 {
+		return nil, nil
+	}
+
+	return search.HighlightType(typeName, post, query, h.opts, h.fields, h.size)
+}
+
+// queryOptions returns the search options given by the mode param, one of
+// "term" (the default), "prefix" or "fuzzy", and the fuzziness param, the
+// edit distance of words matched in fuzzy mode, for the locale of req. It
+// reports false if they are invalid.
+func queryOptions(req *http.Request) (search.QueryOptions, bool) {
+	q := req.URL.Query()
+	opts := search.QueryOptions{
+		Locale: searchLocale(req),
+		Mode:   search.Mode(q.Get("mode")),
+	}
+
+	if f := q.Get("fuzziness"); f != "" {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return opts, false
+		}
+
+		opts.Fuzziness = n
+	}
+
+	return opts, opts.Valid()
+}
+
+// listParam returns the values of the query param key, which may be given as
+// repeated or comma separated params
+func listParam(q url.Values, key string) []string {
+	var values []string
+	for _, v := range q[key] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+
+	return values
+}
+
+func searchContentHandler(res http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	t := q.Get("type")
+	query := q.Get("q")
+	if t == "" || query == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	count, err := strconv.Atoi(q.Get("count")) // int: determines number of posts to return (10 default, -1 is all)
+	if err != nil {
+		if q.Get("count") == "" {
+			count = 10
+		} else {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	offset, err := strconv.Atoi(q.Get("offset")) // int: multiplier of count for pagination (0 default)
+	if err != nil {
+		if q.Get("offset") == "" {
+			offset = 0
+		} else {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	opts, ok := queryOptions(req)
+	if !ok {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	facets := listParam(q, "facet")
+
+	// highlight names the fields of each result to return highlighted
+	// fragments of, or "*" for every field with a match
+	hl := highlighting{fields: listParam(q, "highlight"), size: defaultFragmentSize, opts: opts}
+	if fs := q.Get("fragment_size"); fs != "" {
+		hl.size, err = strconv.Atoi(fs)
+		if err != nil || hl.size < 0 {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	if t == "*" {
+		// facets are counted by field, which differ between types
+		if len(facets) > 0 {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		searchAllHandler(res, req, query, count, offset, hl)
+		return
+	}
+
+	it, ok := item.Types[t]
+	if !ok || !search.Searchable(t) {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if hide(it(), res, req) {
+		return
+	}
+
+	_, span := trace.Start(req.Context(), "search.TypeQuery")
+	span.SetAttribute("ponzu.type", t)
+	ids, err := search.TypeQueryWithOptions(t, query, opts, -1, 0)
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		logger.For(req).Error("Error searching content:", t, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	total := len(ids)
+	start, end := page(total, count, offset)
+
+	var result = []json.RawMessage{}
+	var highlights = []map[string][]string{}
+	for _, id := range ids[start:end] {
+		post, err := db.Content(t + ":" + id)
+		if err != nil {
+			logger.For(req).Error("Error finding search result:", t+":"+id, err)
+			continue
+		}
+
+		// leave out content removed since it was indexed, or not yet published
+		if len(post) == 0 || unpublished(it, post) {
+			continue
+		}
+
+		post, err = omitItem(it(), post)
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// fields are highlighted once omitted ones are removed, so none of
+		// their values are revealed
+		h, err := hl.highlight(t, post, query)
+		if err != nil {
+			logger.For(req).Error("Error highlighting search result:", t+":"+id, err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		result = append(result, post)
+		highlights = append(highlights, h)
+	}
+
+	resp := pageResponse(total, count, offset, result)
+
+	// highlights are in the same order as the results in data
+	if len(hl.fields) > 0 {
+		resp["highlights"] = highlights
+	}
+
+	if len(facets) > 0 {
+		// fields omitted from responses must not have their values counted
+		if om, ok := it().(item.Omittable); ok {
+			omitted := make(map[string]bool)
+			for _, f := range om.Omit() {
+				omitted[f] = true
+			}
+
+			var allowed []string
+			for _, f := range facets {
+				if !omitted[f] {
+					allowed = append(allowed, f)
+				}
+			}
+			facets = allowed
+		}
+
+		_, span := trace.Start(req.Context(), "search.Facets")
+		span.SetAttribute("ponzu.type", t)
+		counts, err := search.FacetsWithOptions(t, query, opts, facets)
+		span.SetError(err)
+		span.End()
+		if err != nil {
+			logger.For(req).Error("Error counting search facets:", t, err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp["facets"] = counts
+	}
+
+	j, err := encodeJSON(resp)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendContent(res, req, t, false, j)
+}
+
+// searchAllHandler writes the results of a search across every searchable
+// content type, each tagged with its type. Results from hidden types and
+// unpublished content are left out before paginating, so that pages are full.
+func searchAllHandler(res http.ResponseWriter, req *http.Request, query string, count, offset int, hl highlighting) {
+	_, span := trace.Start(req.Context(), "search.SearchAll")
+	results, err := search.SearchAllWithOptions(query, hl.opts, -1, 0)
+	span.SetError(err)
+	span.End()
+	if err != nil {
+		logger.For(req).Error("Error searching all content:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	visible := make(map[string]bool)
+	var hits = []searchHit{}
+	for _, r := range results {
+		it, ok := item.Types[r.Type]
+		if !ok {
+			continue
+		}
+
+		shown, checked := visible[r.Type]
+		if !checked {
+			shown = !hidden(it(), res, req)
+			visible[r.Type] = shown
+		}
+
+		if !shown {
+			continue
+		}
+
+		post, err := db.Content(r.Type + ":" + r.ID)
+		if err != nil {
+			logger.For(req).Error("Error finding search result:", r.Type+":"+r.ID, err)
+			continue
+		}
+
+		if len(post) == 0 || unpublished(it, post) {
+			continue
+		}
+
+		post, err = omitItem(it(), post)
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		hits = append(hits, searchHit{Type: r.Type, Content: post})
+	}
+
+	total := len(hits)
+	start, end := page(total, count, offset)
+
+	// only the results on the page are highlighted
+	var data = []json.RawMessage{}
+	for _, hit := range hits[start:end] {
+		hit.Highlights, err = hl.highlight(hit.Type, hit.Content, query)
+		if err != nil {
+			logger.For(req).Error("Error highlighting search result:", hit.Type, err)
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		j, err := json.Marshal(hit)
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data = append(data, j)
+	}
+
+	j, err := encodeJSON(pageResponse(total, count, offset, data))
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendContent(res, req, "", false, j)
+}
+
+// hidden reports whether it is Hideable and should be hidden from req, without
+// writing a response as hide does
+func hidden(it interface{}, res http.ResponseWriter, req *http.Request) bool {
+	h, ok := it.(item.Hideable)
+	if !ok {
+		return false
+	}
+
+	uncacheable(res)
+	return h.Hide(res, req) != item.ErrAllowHiddenItem
+}
+
+// omitItem removes the fields of it which are Omittable from a single item of
+// json data, rather than from a response
+func omitItem(it interface{}, data []byte) ([]byte, error) {
+	om, ok := it.(item.Omittable)
+	if !ok {
+		return data, nil
+	}
+
+	return omitFields(om, data, "")
+}
+
+// searchLocale returns the locale of the search index req is searched in,
+// which is "" for the default locale
+func searchLocale(req *http.Request) string {
+	def, others := db.Locales()
+	locale := negotiateLocale(req, def, others)
+	if locale == def {
+		return ""
+	}
+
+	return locale
+}
@@ -1,12 +1,42 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+)
 
 // Run adds Handlers to default http listener for API
 func Run() {
-	http.HandleFunc("/api/contents", Record(CORS(Gzip(contentsHandler))))
+	http.HandleFunc("/api/contents", Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(Cache(contentsHandler)))))))
+
+	http.HandleFunc("/api/content", Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(Cache(contentHandler)))))))
+
+	http.HandleFunc("/api/schema", Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(schemaHandler))))))
+
+	http.HandleFunc("/api/schemas", Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(schemasHandler))))))
+
+	http.HandleFunc("/api/feed", Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(feedHandler))))))
+
+	http.HandleFunc("/api/search", Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(searchContentHandler))))))
+
+	http.HandleFunc("/api/tags", Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(Cache(tagsHandler)))))))
+
+	http.HandleFunc("/api/tags/content", Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(Cache(tagContentHandler)))))))
+
+	http.HandleFunc("/api/graphql", Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(graphqlHandler))))))
+
+	http.HandleFunc("/api/audit", Record(CORS(RateLimit(KeyAuth(db.ScopeAudit, Gzip(auditHandler))))))
+
+	// the sitemap is for search engines, so is public without an API key
+	http.HandleFunc("/sitemap.xml", Record(CORS(RateLimit(Gzip(sitemapHandler)))))
+
+	http.HandleFunc("/api/content/external", Record(CORS(RateLimit(KeyAuth(db.ScopeWrite, externalContentHandler)))))
+
+	http.HandleFunc("/api/content/update", Record(CORS(RateLimit(KeyAuth(db.ScopeWrite, updateContentHandler)))))
 
-	http.HandleFunc("/api/content", Record(CORS(Gzip(contentHandler))))
+	http.HandleFunc("/api/content/increment", Record(CORS(RateLimit(KeyAuth(db.ScopeWrite, incrementHandler)))))
 
-	http.HandleFunc("/api/content/external", Record(CORS(externalContentHandler)))
+	// routes added by content and addon code with HandleCustom
+	http.HandleFunc(CustomPrefix, Record(CORS(RateLimit(KeyAuth(db.ScopeRead, Gzip(customHandler))))))
 }
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// sitemapLimit is the most URLs listed in one sitemap file. Sites with more are
+// given a sitemap index, linking to as many files as are needed.
+const sitemapLimit = 50000
+
+// sitemapURL is a <url> in a sitemap
+type sitemapURL struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
+}
+
+// sitemapURLSet is a sitemap file
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemapIndex links to each of the files of a sitemap too large for one
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	XMLNS    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapEntry is a <sitemap> in a sitemap index
+type sitemapEntry struct {
+	Loc string `xml:"loc"`
+}
+
+const sitemapXMLNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// siteURL returns the scheme and host which paths on the public site are
+// relative to, from the configured domain, or the host of req if there is none
+func siteURL(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	host, _ := db.ConfigCache("domain").(string)
+	if host == "" {
+		host = req.Host
+	}
+
+	return scheme + "://" + host
+}
+
+// sitemapURLs returns a URL for each item of public content of the
+// Sitemappable types, which are not hidden from req, ordered by type and id
+func sitemapURLs(res http.ResponseWriter, req *http.Request) []sitemapURL {
+	var types []string
+	for t := range item.Types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	site := siteURL(req)
+	now := time.Now()
+
+	var urls []sitemapURL
+	for _, t := range types {
+		it := item.Types[t]
+		if _, ok := it().(item.Sitemappable); !ok || hidden(it(), res, req) {
+			continue
+		}
+
+		var posts []interface{}
+		for _, j := range db.ContentAll(t) {
+			post := it()
+			err := json.Unmarshal(j, post)
+			if err != nil {
+				logger.For(req).Error("Error decoding content for sitemap:", t, err)
+				continue
+			}
+
+			if item.IsPublic(post, now) {
+				posts = append(posts, post)
+			}
+		}
+
+		sort.Slice(posts, func(i, j int) bool {
+			a, _ := posts[i].(item.Identifiable)
+			b, _ := posts[j].(item.Identifiable)
+			return a != nil && b != nil && a.ItemID() < b.ItemID()
+		})
+
+		for _, post := range posts {
+			loc := post.(item.Sitemappable).SitemapURL()
+			if loc == "" {
+				continue
+			}
+			if strings.HasPrefix(loc, "/") {
+				loc = site + loc
+			}
+
+			u := sitemapURL{Loc: loc}
+			if s, ok := post.(item.Sortable); ok && s.Touch() > 0 {
+				u.LastMod = time.Unix(0, s.Touch()*int64(time.Millisecond)).UTC().Format(time.RFC3339)
+			}
+			if h, ok := post.(item.SitemapHinter); ok {
+				u.ChangeFreq = h.SitemapChangeFreq()
+				if p := h.SitemapPriority(); p >= 0 {
+					u.Priority = strconv.FormatFloat(p, 'f', 1, 64)
+				}
+			}
+
+			urls = append(urls, u)
+		}
+	}
+
+	return urls
+}
+
+// sitemapHandler writes the sitemap of public content. When there are more
+// than sitemapLimit URLs it writes a sitemap index instead, linking to each
+// file of the sitemap as /sitemap.xml?page=N, numbered from 1.
+func sitemapHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	urls := sitemapURLs(res, req)
+	pages := (len(urls) + sitemapLimit - 1) / sitemapLimit
+
+	var v interface{}
+	p := req.URL.Query().Get("page")
+	switch {
+	case p != "":
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 || n > pages {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		end := n * sitemapLimit
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		v = sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls[(n-1)*sitemapLimit : end]}
+
+	case pages > 1:
+		index := sitemapIndex{XMLNS: sitemapXMLNS}
+		for n := 1; n <= pages; n++ {
+			index.Sitemaps = append(index.Sitemaps, sitemapEntry{
+				Loc: fmt.Sprintf("%s/sitemap.xml?page=%d", siteURL(req), n),
+			})
+		}
+
+		v = index
+
+	default:
+		v = sitemapURLSet{XMLNS: sitemapXMLNS, URLs: urls}
+	}
+
+	x, err := xml.Marshal(v)
+	if err != nil {
+		logger.For(req).Error("Error encoding sitemap:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	res.Write([]byte(xml.Header))
+	res.Write(x)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/tidwall/gjson"
+)
+
+// tagCount is a canonical tag with the number of public items tagged with it
+type tagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// tagsHandler writes every canonical tag, with the number of public items of
+// the item.Taggable types which aren't hidden from req tagged with it, for
+// tag clouds
+func tagsHandler(res http.ResponseWriter, req *http.Request) {
+	tags, err := db.Tags()
+	if err != nil {
+		logger.For(req).Error("Error listing tags:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	counts := make(map[string]int)
+	for t, it := range item.Types {
+		tg, ok := it().(item.Taggable)
+		if !ok || hidden(it(), res, req) {
+			continue
+		}
+
+		for _, post := range db.ContentAll(t) {
+			if unpublished(it, post) {
+				continue
+			}
+
+			for _, f := range tg.TagFields() {
+				for _, v := range gjson.GetBytes(post, f).Array() {
+					counts[strings.ToLower(v.String())]++
+				}
+			}
+		}
+	}
+
+	var data = []json.RawMessage{}
+	for _, name := range tags {
+		j, err := json.Marshal(tagCount{Name: name, Count: counts[strings.ToLower(name)]})
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data = append(data, j)
+	}
+
+	j, err := encodeJSON(map[string]interface{}{"data": data})
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendData(res, req, j)
+}
+
+// tagContentHandler writes the public content tagged with the tag query param,
+// of every item.Taggable type or only of the type query param, each tagged with
+// its type as in a search across all types, newest first
+func tagContentHandler(res http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	tag := q.Get("tag")
+	t := q.Get("type")
+	if strings.TrimSpace(tag) == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if t != "" {
+		it, ok := item.Types[t]
+		if !ok {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if _, ok := it().(item.Taggable); !ok {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if hide(it(), res, req) {
+			return
+		}
+	}
+
+	count, err := strconv.Atoi(q.Get("count")) // int: determines number of posts to return (10 default, -1 is all)
+	if err != nil {
+		if q.Get("count") == "" {
+			count = 10
+		} else {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	offset, err := strconv.Atoi(q.Get("offset")) // int: multiplier of count for pagination (0 default)
+	if err != nil {
+		if q.Get("offset") == "" {
+			offset = 0
+		} else {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	visible := make(map[string]bool)
+	var hits = []json.RawMessage{}
+	for _, tagged := range db.TaggedContent(tag) {
+		if t != "" && tagged.Type != t {
+			continue
+		}
+
+		it := item.Types[tagged.Type]
+		shown, checked := visible[tagged.Type]
+		if !checked {
+			shown = !hidden(it(), res, req)
+			visible[tagged.Type] = shown
+		}
+
+		if !shown || unpublished(it, tagged.Content) {
+			continue
+		}
+
+		post, err := omitItem(it(), tagged.Content)
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		hit, err := json.Marshal(searchHit{Type: tagged.Type, Content: post})
+		if err != nil {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		hits = append(hits, hit)
+	}
+
+	total := len(hits)
+	start, end := page(total, count, offset)
+
+	j, err := encodeJSON(pageResponse(total, count, offset, hits[start:end]))
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	sendContent(res, req, "", false, j)
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/admin/upload"
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/trace"
+
+	"github.com/tidwall/gjson"
+)
+
+// Updateable accepts or rejects API requests to update content, to endpoints
+// such as: /api/content/update?type=Review&id=1
+type Updateable interface {
+	// Update allows updating content of a specific type through the API
+	Update(http.ResponseWriter, *http.Request) error
+}
+
+// ifMatchVersion returns the content version in the If-Match header of req,
+// which may be sent as an entity tag, like "3" or W/"3"
+func ifMatchVersion(req *http.Request) (string, bool) {
+	v := strings.TrimSpace(req.Header.Get("If-Match"))
+	v = strings.Trim(strings.TrimPrefix(v, "W/"), `"`)
+
+	if _, err := strconv.Atoi(v); err != nil {
+		return "", false
+	}
+
+	return v, true
+}
+
+// updateContentHandler replaces content with the form values of the request,
+// the same as saving it in the editor. The version of the content the update
+// was made from must be sent in the If-Match header, and the update is
+// rejected with 409 Conflict if the content has been saved since, so that no
+// update silently loses another.
+func updateContentHandler(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	version, ok := ifMatchVersion(req)
+	if !ok {
+		res.WriteHeader(http.StatusPreconditionRequired)
+		return
+	}
+
+	err := req.ParseMultipartForm(1024 * 1024 * 4) // maxMemory 4MB
+	if err != nil {
+		logger.For(req).Error("[Update] error:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	q := req.URL.Query()
+	t := q.Get("type")
+	id := q.Get("id")
+	if t == "" || id == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	p, found := item.Types[t]
+	if !found {
+		logger.For(req).Error("[Update] attempt to update unknown type:", t, "from:", req.RemoteAddr)
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	post := p()
+
+	upd, ok := post.(Updateable)
+	if !ok {
+		logger.For(req).Error("[Update] rejected non-updateable type:", t, "from:", req.RemoteAddr)
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	existing, err := db.Content(t + ":" + id)
+	if err != nil {
+		logger.For(req).Error("[Update] error finding content:", t+":"+id, err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(existing) == 0 {
+		res.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// the identity and workflow state of content are kept as they are, and
+	// only set from the admin
+	for k := range req.PostForm {
+		if k == "state" || strings.HasPrefix(k, "state_history.") {
+			req.PostForm.Del(k)
+		}
+	}
+
+	current := gjson.ParseBytes(existing)
+	req.PostForm.Set("id", id)
+	req.PostForm.Set("uuid", current.Get("uuid").String())
+	if s := current.Get("state").String(); s != "" {
+		req.PostForm.Set("state", s)
+	}
+	for i, h := range current.Get("state_history").Array() {
+		req.PostForm.Set(fmt.Sprintf("state_history.%d.state", i), h.Get("state").String())
+		req.PostForm.Set(fmt.Sprintf("state_history.%d.user", i), h.Get("user").String())
+		req.PostForm.Set(fmt.Sprintf("state_history.%d.time", i), h.Get("time").String())
+	}
+
+	if req.PostForm.Get("timestamp") == "" {
+		req.PostForm.Set("timestamp", current.Get("timestamp").String())
+	}
+	req.PostForm.Set("updated", fmt.Sprintf("%d", time.Now().UnixNano()/int64(time.Millisecond)))
+	req.PostForm.Set("version", version)
+
+	urlPaths, err := upload.StoreFiles(req)
+	if _, ok := err.(*upload.RejectedError); ok {
+		logger.For(req).Error("[Update]", err)
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.For(req).Error(err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	for name, urlPath := range urlPaths {
+		req.PostForm.Set(name, urlPath)
+	}
+
+	orderFormValues(req.PostForm)
+
+	hook, ok := post.(item.Hookable)
+	if !ok {
+		logger.For(req).Error("[Update] error: Type", t, "does not implement item.Hookable or embed item.Item.")
+		res.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = upd.Update(res, req)
+	if err != nil {
+		logger.For(req).Error("[Update] error calling Update:", err)
+		return
+	}
+
+	err = hook.BeforeSave(res, req)
+	if err != nil {
+		logger.For(req).Error("[Update] error calling BeforeSave:", err)
+		return
+	}
+
+	_, span := trace.Start(req.Context(), "db.SetContent")
+	span.SetAttribute("ponzu.type", t)
+	_, err = db.SetContent(t+":"+id, req.PostForm)
+	span.SetError(err)
+	span.End()
+	if fields, ok := item.FieldErrors(err); ok {
+		logger.For(req).Error("[Update] invalid content submitted:", err)
+		j, err := json.Marshal(map[string]interface{}{
+			"errors": fields,
+		})
+		if err != nil {
+			res.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.WriteHeader(http.StatusBadRequest)
+		res.Write(j)
+		return
+	}
+	if err == db.ErrVersionConflict {
+		res.WriteHeader(http.StatusConflict)
+		return
+	}
+	if err != nil {
+		logger.For(req).Error("[Update] error calling SetContent:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// set the target in the context so user can get saved value from db in hook
+	ctx := context.WithValue(req.Context(), "target", t+":"+id)
+	req = req.WithContext(ctx)
+
+	err = hook.AfterSave(res, req)
+	if err != nil {
+		logger.For(req).Error("[Update] error calling AfterSave:", err)
+		return
+	}
+
+	// saving sets the version in the form to the one saved
+	saved, err := strconv.Atoi(req.PostForm.Get("version"))
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	cid, _ := strconv.Atoi(id)
+	j, err := json.Marshal(map[string]interface{}{
+		"data": []map[string]interface{}{
+			{
+				"id":      cid,
+				"status":  "public",
+				"type":    t,
+				"version": saved,
+			},
+		},
+	})
+	if err != nil {
+		logger.For(req).Error("[Update] error marshalling response to JSON:", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	_, err = res.Write(j)
+	if err != nil {
+		logger.For(req).Error("[Update] error writing response:", err)
+		return
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	cases := map[string]string{
+		`"3"`:    "3",
+		`W/"12"`: "12",
+		"7":      "7",
+	}
+
+	for header, want := range cases {
+		req := httptest.NewRequest("POST", "/api/content/update?type=Post&id=1", nil)
+		req.Header.Set("If-Match", header)
+
+		got, ok := ifMatchVersion(req)
+		if !ok || got != want {
+			t.Errorf("%s: expected %s, got %s %v", header, want, got, ok)
+		}
+	}
+
+	for _, header := range []string{"", "*", `"abc"`} {
+		req := httptest.NewRequest("POST", "/api/content/update?type=Post&id=1", nil)
+		req.Header.Set("If-Match", header)
+
+		if _, ok := ifMatchVersion(req); ok {
+			t.Errorf("%q: expected no version", header)
+		}
+	}
+}
+
+func TestUpdateRequiresIfMatch(t *testing.T) {
+	rec := httptest.NewRecorder()
+	updateContentHandler(rec, httptest.NewRequest("POST", "/api/content/update?type=Post&id=1", nil))
+
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("expected 428 without If-Match, got %d", rec.Code)
+	}
+}
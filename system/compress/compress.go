@@ -0,0 +1,342 @@
+// Package compress provides the middleware which compresses responses with an
+// encoding the client accepts, such as gzip, once they are large enough for it
+// to save bandwidth.
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+// DefaultMinSize is the smallest response body in bytes which is compressed,
+// if compression_min_bytes is not configured. Smaller bodies are sent as they
+// are, since compressing them saves little and can even make them larger.
+const DefaultMinSize = 1024
+
+// Encoder makes a writer which compresses what is written to w, and writes
+// anything still buffered when closed
+type Encoder func(w io.Writer) io.WriteCloser
+
+// encoders are the encodings responses can be compressed with, in order of
+// preference when a client accepts more than one equally
+var encoders = struct {
+	sync.RWMutex
+	names []string
+	new   map[string]Encoder
+}{
+	names: []string{"gzip"},
+	new: map[string]Encoder{
+		"gzip": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+	},
+}
+
+// Register adds an encoding which responses can be compressed with, preferred
+// over those already registered, such as "br" for brotli. Registering a name
+// again replaces its Encoder.
+func Register(name string, enc Encoder) {
+	encoders.Lock()
+	defer encoders.Unlock()
+
+	if _, ok := encoders.new[name]; !ok {
+		encoders.names = append([]string{name}, encoders.names...)
+	}
+	encoders.new[name] = enc
+}
+
+// negotiate returns the registered encoding the client prefers according to
+// the Accept-Encoding header accept, or "" if it accepts none of them
+func negotiate(accept string) (string, Encoder) {
+	q := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+
+		weight := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				w, err := strconv.ParseFloat(strings.TrimPrefix(f, "q="), 64)
+				if err == nil {
+					weight = w
+				}
+			}
+		}
+
+		q[name] = weight
+	}
+
+	encoders.RLock()
+	defer encoders.RUnlock()
+
+	best, bestQ := "", 0.0
+	for _, name := range encoders.names {
+		w, ok := q[name]
+		if !ok {
+			w, ok = q["*"]
+		}
+
+		if ok && w > bestQ {
+			best, bestQ = name, w
+		}
+	}
+
+	if best == "" {
+		return "", nil
+	}
+
+	return best, encoders.new[best]
+}
+
+// compressible reports whether a response with contentType is worth
+// compressing. Images other than SVG, video, audio and archives are already
+// compressed, so are sent as they are.
+func compressible(contentType string) bool {
+	ct := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+
+	switch {
+	case strings.HasPrefix(ct, "text/"),
+		strings.HasSuffix(ct, "+json"),
+		strings.HasSuffix(ct, "+xml"):
+		return true
+	}
+
+	switch ct {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-javascript", "application/graphql", "image/svg+xml",
+		"application/wasm", "font/ttf", "font/otf", "image/x-icon":
+		return true
+	}
+
+	return false
+}
+
+// minSize returns the smallest response body which is compressed
+func minSize() int {
+	if n, ok := db.ConfigCache("compression_min_bytes").(float64); ok && n > 0 {
+		return int(n)
+	}
+
+	return DefaultMinSize
+}
+
+// handledKey marks requests whose responses are already being compressed, so
+// that nested compression middleware passes them through
+type handledKey struct{}
+
+// Handled reports whether the response to req is compressed by an outer
+// Handler, so that middleware further in should not compress it again
+func Handled(req *http.Request) bool {
+	handled, _ := req.Context().Value(handledKey{}).(bool)
+	return handled
+}
+
+// Handler compresses the responses of next with the encoding the client
+// prefers, unless compression is disabled with gzip_disabled, the body is
+// smaller than compression_min_bytes, its type is already compressed, or next
+// set its own Content-Encoding. A strong ETag on a compressed response is made
+// weak, since the compressed bytes differ from those it was computed for while
+// meaning the same, and conditional requests still match it.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if disabled, _ := db.ConfigCache("gzip_disabled").(bool); disabled || Handled(req) {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		res.Header().Add("Vary", "Accept-Encoding")
+
+		name, enc := negotiate(req.Header.Get("Accept-Encoding"))
+		if enc == nil || req.Method == http.MethodHead {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		req = req.WithContext(context.WithValue(req.Context(), handledKey{}, true))
+
+		cw := &compressWriter{
+			ResponseWriter: res,
+			name:           name,
+			enc:            enc,
+			min:            minSize(),
+		}
+		defer cw.Close()
+
+		next.ServeHTTP(cw, req)
+	})
+}
+
+// compressWriter buffers the start of a response until it knows whether it is
+// large enough to compress, then either compresses it or writes it as it is
+type compressWriter struct {
+	http.ResponseWriter
+
+	name string
+	enc  Encoder
+	min  int
+
+	status  int
+	buf     []byte
+	decided bool
+	w       io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+
+	// responses without a body are never compressed
+	if status == http.StatusNoContent || status == http.StatusNotModified || status < 200 {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.min {
+			return len(p), nil
+		}
+
+		err := cw.decide(true)
+		if err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	}
+
+	if cw.w != nil {
+		return cw.w.Write(p)
+	}
+
+	return cw.ResponseWriter.Write(p)
+}
+
+// decide writes the header, compressing the response if large is true and its
+// type is compressible, and then writes what has been buffered
+func (cw *compressWriter) decide(large bool) error {
+	if cw.decided {
+		return nil
+	}
+	cw.decided = true
+
+	h := cw.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	// partial content is a range of the uncompressed body, so can't be
+	// compressed on its own
+	partial := cw.status == http.StatusPartialContent || h.Get("Content-Range") != ""
+
+	if large && !partial && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", cw.name)
+		h.Del("Content-Length")
+		if tag := h.Get("ETag"); strings.HasPrefix(tag, `"`) {
+			h.Set("ETag", "W/"+tag)
+		}
+
+		cw.w = cw.enc(cw.ResponseWriter)
+	}
+
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.w != nil {
+		_, err = cw.w.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+
+	return err
+}
+
+// Close writes the rest of the response, once the handler has returned
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// nothing was written, so let net/http write its default response
+			cw.decided = true
+			return nil
+		}
+
+		err := cw.decide(false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cw.w != nil {
+		return cw.w.Close()
+	}
+
+	return nil
+}
+
+// Flush sends what has been written so far, deciding whether to compress the
+// response with what has been buffered, for handlers which stream
+func (cw *compressWriter) Flush() {
+	cw.decide(len(cw.buf) >= cw.min)
+
+	if f, ok := cw.w.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Push(target string, opts *http.PushOptions) error {
+	pusher, ok := cw.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+
+	if opts == nil {
+		opts = &http.PushOptions{}
+	}
+	if opts.Header == nil {
+		opts.Header = make(http.Header)
+	}
+	opts.Header.Set("Accept-Encoding", cw.name)
+
+	return pusher.Push(target, opts)
+}
+
+// Hijack hands the connection to the handler, such as for a websocket, which
+// is then never compressed
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("compress: response does not support hijacking")
+	}
+
+	cw.decided = true
+	return hj.Hijack()
+}
@@ -0,0 +1,124 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	cases := map[string]string{
+		"":                      "",
+		"gzip":                  "gzip",
+		"deflate, gzip;q=0.5":   "gzip",
+		"gzip;q=0":              "",
+		"*":                     "gzip",
+		"br, identity":          "",
+		"GZIP;q=1.0, identity":  "gzip",
+		"identity;q=1, *;q=0.1": "gzip",
+	}
+
+	for accept, want := range cases {
+		if got, _ := negotiate(accept); got != want {
+			t.Errorf("negotiate(%q) = %q, want %q", accept, got, want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	large := bytes.Repeat([]byte(`{"title":"Hello"},`), 200)
+
+	serve := func(contentType string, body []byte) *httptest.ResponseRecorder {
+		h := Handler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if !Handled(req) {
+				t.Error("request was not marked as handled")
+			}
+
+			res.Header().Set("Content-Type", contentType)
+			res.Header().Set("ETag", `"abc"`)
+			res.Write(body)
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/contents", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("application/json", large)
+	if rec.Header().Get("Content-Encoding") != "gzip" || rec.Header().Get("ETag") != `W/"abc"` {
+		t.Fatalf("large JSON not compressed: %v", rec.Header())
+	}
+
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadAll(gz)
+	if err != nil || !bytes.Equal(body, large) {
+		t.Errorf("decompressed body differs from the original: %v", err)
+	}
+
+	rec = serve("application/json", []byte(`{"data":[]}`))
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != `{"data":[]}` {
+		t.Errorf("small response was compressed: %v", rec.Header())
+	}
+
+	rec = serve("image/png", large)
+	if rec.Header().Get("Content-Encoding") != "" || !bytes.Equal(rec.Body.Bytes(), large) {
+		t.Errorf("image was compressed: %v", rec.Header())
+	}
+}
+
+// listResponse returns a response of the content list API with n items, much
+// like those of a blog
+func listResponse(n int) []byte {
+	var data []map[string]interface{}
+	for i := 0; i < n; i++ {
+		data = append(data, map[string]interface{}{
+			"uuid":      fmt.Sprintf("5ce3f32e-4b6e-4a5a-9c4f-%012d", i),
+			"id":        i + 1,
+			"slug":      fmt.Sprintf("post-%d", i+1),
+			"timestamp": 1500000000000 + i,
+			"updated":   1500000000000 + i,
+			"title":     fmt.Sprintf("Post number %d", i+1),
+			"body":      "<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua.</p>",
+			"author":    "Ponzu",
+			"tags":      []string{"news", "release"},
+		})
+	}
+
+	j, _ := json.Marshal(map[string]interface{}{"data": data})
+	return j
+}
+
+// BenchmarkListResponse compresses a list of 50 items, and reports the size of
+// the compressed response as a percentage of the original
+func BenchmarkListResponse(b *testing.B) {
+	list := listResponse(50)
+	h := Handler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json")
+		res.Write(list)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	var size int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		size = rec.Body.Len()
+	}
+
+	b.ReportMetric(float64(len(list)), "original-bytes")
+	b.ReportMetric(float64(size), "compressed-bytes")
+	b.ReportMetric(100*float64(size)/float64(len(list)), "%-of-original")
+}
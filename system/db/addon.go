@@ -5,9 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/url"
 
+	"github.com/ponzu-cms/ponzu/system/logger"
+
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/schema"
 )
@@ -107,7 +108,7 @@ func AddonAll() [][]byte {
 		return nil
 	})
 	if err != nil {
-		log.Println("Error finding addons in db with db.AddonAll:", err)
+		logger.Error("Error finding addons in db with db.AddonAll:", err)
 		return nil
 	}
 
@@ -157,7 +158,7 @@ func AddonExists(key string) bool {
 		return nil
 	})
 	if err != nil {
-		log.Println("Error checking existence of addon with key:", key, "-", err)
+		logger.Error("Error checking existence of addon with key:", key, "-", err)
 		return false
 	}
 
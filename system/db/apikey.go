@@ -0,0 +1,192 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/boltdb/bolt"
+)
+
+// Scopes which an API key can be granted
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+
+	// ScopeAudit only allows reading the audit log, which no other scope can
+	ScopeAudit = "audit"
+)
+
+// ErrNoAPIKeyExists is returned when an API key is not found
+var ErrNoAPIKeyExists = errors.New("No API key exists.")
+
+// APIKey grants machine access to the API. Only a hash of the key itself is
+// stored, so the key is shown to the admin once, when it is created.
+type APIKey struct {
+	ID        int      `json:"id"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"` // first characters of the key, to identify it
+	Hash      string   `json:"hash"`
+	Scope     string   `json:"scope"`      // ScopeRead, ScopeWrite or ScopeAudit
+	Types     []string `json:"types"`      // content types the key may access, empty for all
+	RateLimit int      `json:"rate_limit"` // requests per minute, 0 for no limit
+	Created   int64    `json:"created"`
+}
+
+// Allows reports whether the key grants scope on content of type t. A write
+// scoped key may also read. A key limited to certain types does not allow
+// requests without a type, which could return content of any type. An audit
+// scoped key allows nothing but reading the audit log.
+func (k APIKey) Allows(scope, t string) bool {
+	if scope == ScopeAudit || k.Scope == ScopeAudit {
+		return scope == k.Scope
+	}
+
+	if scope == ScopeWrite && k.Scope != ScopeWrite {
+		return false
+	}
+
+	if len(k.Types) == 0 {
+		return true
+	}
+
+	for i := range k.Types {
+		if k.Types[i] == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hashAPIKey returns the hex encoded hash of key under which it is stored
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIKey generates and stores a new API key from the fields of k, returning
+// the key, which cannot be retrieved again, and the stored APIKey
+func NewAPIKey(k APIKey) (string, APIKey, error) {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", k, err
+	}
+
+	key := hex.EncodeToString(b)
+	k.Prefix = key[:8]
+	k.Hash = hashAPIKey(key)
+	k.Created = time.Now().UnixNano() / int64(time.Millisecond)
+	if k.Scope != ScopeWrite && k.Scope != ScopeAudit {
+		k.Scope = ScopeRead
+	}
+
+	err = store.Update(func(tx *bolt.Tx) error {
+		keys, err := tx.CreateBucketIfNotExists([]byte("__apikeys"))
+		if err != nil {
+			return err
+		}
+
+		id, err := keys.NextSequence()
+		if err != nil {
+			return err
+		}
+		k.ID = int(id)
+
+		j, err := json.Marshal(k)
+		if err != nil {
+			return err
+		}
+
+		return keys.Put([]byte(k.Hash), j)
+	})
+	if err != nil {
+		return "", k, err
+	}
+
+	return key, k, nil
+}
+
+// APIKeyFor returns the stored APIKey for key
+func APIKeyFor(key string) (APIKey, error) {
+	var k APIKey
+	err := store.View(func(tx *bolt.Tx) error {
+		keys := tx.Bucket([]byte("__apikeys"))
+		if keys == nil {
+			return ErrNoAPIKeyExists
+		}
+
+		v := keys.Get([]byte(hashAPIKey(key)))
+		if v == nil {
+			return ErrNoAPIKeyExists
+		}
+
+		return json.Unmarshal(v, &k)
+	})
+
+	return k, err
+}
+
+// APIKeys returns all stored API keys
+func APIKeys() ([]APIKey, error) {
+	var all = []APIKey{}
+	err := store.View(func(tx *bolt.Tx) error {
+		keys := tx.Bucket([]byte("__apikeys"))
+		if keys == nil {
+			return nil
+		}
+
+		return keys.ForEach(func(h, v []byte) error {
+			var k APIKey
+			err := json.Unmarshal(v, &k)
+			if err != nil {
+				logger.Error("Error decoding API key:", err)
+				return nil
+			}
+
+			all = append(all, k)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// RevokeAPIKey deletes the API key with id, so it can no longer be used
+func RevokeAPIKey(id int) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket([]byte("__apikeys"))
+		if keys == nil {
+			return ErrNoAPIKeyExists
+		}
+
+		var hash []byte
+		err := keys.ForEach(func(h, v []byte) error {
+			var k APIKey
+			err := json.Unmarshal(v, &k)
+			if err == nil && k.ID == id {
+				hash = append([]byte{}, h...)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if hash == nil {
+			return ErrNoAPIKeyExists
+		}
+
+		return keys.Delete(hash)
+	})
+}
@@ -0,0 +1,220 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/boltdb/bolt"
+)
+
+// defaultAuditRetention is the number of days entries are kept in the audit
+// log if audit_retention_days is not configured
+const defaultAuditRetention = 90
+
+// maxAuditDetail is the length at which the values of audit details are cut
+const maxAuditDetail = 200
+
+// sensitiveDetail matches the names of details whose values are not logged
+var sensitiveDetail = regexp.MustCompile(`(?i)password|secret|token|hash|salt|key|code`)
+
+// AuditEntry records an action taken in the admin. User is the email of the
+// acting user, and Target what was acted on, such as Post:12 for content.
+// Timestamp is in unix milliseconds. RequestID is the ID of the request the
+// action was taken in, matching its log lines.
+type AuditEntry struct {
+	ID        int               `json:"id"`
+	Timestamp int64             `json:"timestamp"`
+	User      string            `json:"user"`
+	Action    string            `json:"action"`
+	Target    string            `json:"target"`
+	IP        string            `json:"ip"`
+	Details   map[string]string `json:"details,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// Time returns the time at which the action was taken
+func (e AuditEntry) Time() time.Time {
+	return time.Unix(0, e.Timestamp*int64(time.Millisecond))
+}
+
+// AuditQuery filters the entries returned by AuditLog. Action matches entries
+// whose action is, or begins with, Action followed by a dot, so "content"
+// matches "content.update". RequestID matches the entries recorded while
+// handling one request. Since and Until are in unix milliseconds. If Follow is
+// set, the entries after the entry with ID After are returned oldest first,
+// otherwise the newest entries are returned first.
+type AuditQuery struct {
+	User      string
+	Action    string
+	Target    string
+	RequestID string
+	Since     int64
+	Until     int64
+	Follow    bool
+	After     int
+	Limit     int
+}
+
+func (q AuditQuery) matches(e AuditEntry) bool {
+	switch {
+	case q.User != "" && !strings.EqualFold(e.User, q.User):
+		return false
+	case q.Action != "" && e.Action != q.Action && !strings.HasPrefix(e.Action, q.Action+"."):
+		return false
+	case q.Target != "" && !strings.Contains(strings.ToLower(e.Target), strings.ToLower(q.Target)):
+		return false
+	case q.RequestID != "" && e.RequestID != q.RequestID:
+		return false
+	case q.Since > 0 && e.Timestamp < q.Since:
+		return false
+	case q.Until > 0 && e.Timestamp > q.Until:
+		return false
+	}
+
+	return true
+}
+
+// AuditRetentionDays returns the configured number of days to keep entries in
+// the audit log
+func AuditRetentionDays() int {
+	n, ok := ConfigCache("audit_retention_days").(float64)
+	if !ok || n < 1 {
+		return defaultAuditRetention
+	}
+
+	return int(n)
+}
+
+// auditKey returns the key of the entry with id, which keeps entries in the
+// order they were made
+func auditKey(id int) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, uint64(id))
+	return k
+}
+
+// Audit adds e to the audit log, setting its ID and, if unset, its Timestamp.
+// The values of details with sensitive names, such as passwords, are redacted
+// and long values are cut short.
+func Audit(e AuditEntry) error {
+	if e.Timestamp == 0 {
+		e.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+
+	details := make(map[string]string, len(e.Details))
+	for k, v := range e.Details {
+		if sensitiveDetail.MatchString(k) {
+			v = "[redacted]"
+		} else if len(v) > maxAuditDetail {
+			v = v[:maxAuditDetail] + "…"
+		}
+		details[k] = v
+	}
+	e.Details = details
+
+	return store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("__audit"))
+		if err != nil {
+			return err
+		}
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		e.ID = int(id)
+
+		j, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(auditKey(e.ID), j)
+	})
+}
+
+// AuditLog returns the entries of the audit log matching q, up to q.Limit
+// entries if it is set
+func AuditLog(q AuditQuery) ([]AuditEntry, error) {
+	var entries = []AuditEntry{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__audit"))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		k, v := c.Last()
+		next := c.Prev
+		if q.Follow {
+			k, v = c.Seek(auditKey(q.After + 1))
+			next = c.Next
+		}
+
+		for ; k != nil; k, v = next() {
+			var e AuditEntry
+			err := json.Unmarshal(v, &e)
+			if err != nil {
+				logger.Error("Error decoding audit entry:", err)
+				continue
+			}
+
+			if !q.matches(e) {
+				continue
+			}
+
+			entries = append(entries, e)
+			if q.Limit > 0 && len(entries) >= q.Limit {
+				break
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// pruneAuditLog deletes entries older than the configured retention, and is
+// run hourly
+func pruneAuditLog() error {
+	cutoff := time.Now().AddDate(0, 0, AuditRetentionDays()*-1).UnixNano() / int64(time.Millisecond)
+
+	return store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__audit"))
+		if b == nil {
+			return nil
+		}
+
+		// entries are in the order they were made, so stop at the first
+		// which is recent enough to keep
+		var expired [][]byte
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var e AuditEntry
+			err := json.Unmarshal(v, &e)
+			if err == nil && e.Timestamp >= cutoff {
+				break
+			}
+
+			expired = append(expired, k)
+		}
+
+		for _, k := range expired {
+			err := b.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/webhook"
+
+	"github.com/boltdb/bolt"
+)
+
+// DeleteContents moves the items ids of the bucket ns to the trash, the same
+// as DeleteContent, in a single transaction so that either all of them are
+// deleted or none are
+func DeleteContents(ns string, ids []string) error {
+	deleted := make([][]byte, len(ids))
+	err := store.Update(func(tx *bolt.Tx) error {
+		for i, id := range ids {
+			var err error
+			deleted[i], err = deleteItem(tx, ns, id, "", true)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(ns, "__") {
+		for i, id := range ids {
+			go deleteSearchIndex(ns, id)
+			go notifyWebhooks(webhook.EventDelete, ns, id, deleted[i])
+		}
+	}
+
+	err = InvalidateCache()
+	if err != nil {
+		return err
+	}
+
+	SortContent(ns)
+
+	return nil
+}
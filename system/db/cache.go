@@ -5,9 +5,33 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// lastModified is the time of the last call to InvalidateCache, which is made
+// whenever content changes and on system start
+var lastModified = struct {
+	sync.RWMutex
+	t time.Time
+}{}
+
+// contentVersion is incremented each time content or the sorted lists of it
+// change, so that caches of responses can tell when they are stale
+var contentVersion uint64
+
+// contentChanged marks cached responses built from content as stale
+func contentChanged() {
+	atomic.AddUint64(&contentVersion, 1)
+}
+
+// ContentVersion returns a number which changes whenever content changes, for
+// caches to compare against the number from when they were filled
+func ContentVersion() uint64 {
+	return atomic.LoadUint64(&contentVersion)
+}
+
 // CacheControl sets the default cache policy on static asset responses
 func CacheControl(next http.Handler) http.HandlerFunc {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
@@ -37,10 +61,25 @@ func NewEtag() string {
 
 // InvalidateCache sets a new Etag for http responses
 func InvalidateCache() error {
+	contentChanged()
+
 	err := PutConfig("etag", NewEtag())
 	if err != nil {
 		return err
 	}
 
+	lastModified.Lock()
+	lastModified.t = time.Now()
+	lastModified.Unlock()
+
 	return nil
 }
+
+// LastModified returns the time content last changed, or the system started if
+// it has not changed since
+func LastModified() time.Time {
+	lastModified.RLock()
+	defer lastModified.RUnlock()
+
+	return lastModified.t
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ponzu-cms/ponzu/system/admin/config"
 
@@ -13,7 +16,12 @@ import (
 	"github.com/gorilla/schema"
 )
 
-var configCache map[string]interface{}
+var (
+	// configCache is replaced rather than changed when the config is saved,
+	// so the mutex only guards the map it points to
+	configCache   map[string]interface{}
+	configCacheMu sync.RWMutex
+)
 
 func init() {
 	configCache = make(map[string]interface{})
@@ -110,13 +118,25 @@ func SetConfig(data url.Values) error {
 		return err
 	}
 
-	configCache = kv
+	applyEnvConfig(kv)
+
+	searchDisabled := searchDisabledTypes()
+	analyzers, stopWords := searchAnalyzers(), searchStopWords()
+	setConfigCache(kv)
+	applySearchDisabled(searchDisabled)
+	applySearchSynonyms()
+	applySearchAnalyzers(analyzers, stopWords)
 
 	return nil
 }
 
-// Config gets the value of a key in the configuration from the db
+// Config gets the value of a key in the configuration from the db, or from
+// its environment variable if it is set, as EnvVar names it
 func Config(key string) ([]byte, error) {
+	if v := os.Getenv(EnvVar(key)); v != "" && !notFromEnv[key] {
+		return []byte(v), nil
+	}
+
 	kv := make(map[string]interface{})
 
 	cfg, err := ConfigAll()
@@ -185,6 +205,9 @@ func PutConfig(key string, value interface{}) error {
 	data := make(url.Values)
 	for k, v := range kv {
 		switch v.(type) {
+		case nil:
+			// unset lists are left unset, rather than given the value "<nil>"
+
 		case string:
 			data.Set(k, v.(string))
 
@@ -194,6 +217,17 @@ func PutConfig(key string, value interface{}) error {
 				data.Add(k, vv[i])
 			}
 
+		case []interface{}:
+			// lists decoded from the stored json
+			for _, vv := range v.([]interface{}) {
+				data.Add(k, fmt.Sprintf("%v", vv))
+			}
+
+		case float64:
+			// numbers decoded from the stored json, which must not be
+			// formatted with an exponent to be decoded back into ints
+			data.Set(k, strconv.FormatFloat(v.(float64), 'f', -1, 64))
+
 		default:
 			data.Set(k, fmt.Sprintf("%v", v))
 		}
@@ -210,9 +244,19 @@ func PutConfig(key string, value interface{}) error {
 // ConfigCache is a in-memory cache of the Configs for quicker lookups
 // 'key' is the JSON tag associated with the config field
 func ConfigCache(key string) interface{} {
+	configCacheMu.RLock()
+	defer configCacheMu.RUnlock()
+
 	return configCache[key]
 }
 
+// setConfigCache replaces the config read by ConfigCache with kv
+func setConfigCache(kv map[string]interface{}) {
+	configCacheMu.Lock()
+	configCache = kv
+	configCacheMu.Unlock()
+}
+
 // LoadCacheConfig loads the config into a cache to be accessed by ConfigCache()
 func LoadCacheConfig() error {
 	c, err := ConfigAll()
@@ -227,6 +271,19 @@ func LoadCacheConfig() error {
 		}
 	}
 
+	// decode through the Config struct so that any settings added since the
+	// config was stored are present in the cache with their zero value
+	cfg := &config.Config{}
+	err = json.Unmarshal(c, cfg)
+	if err != nil {
+		return err
+	}
+
+	c, err = json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
 	// convert json => map[string]interface{}
 	var kv map[string]interface{}
 	err = json.Unmarshal(c, &kv)
@@ -234,7 +291,8 @@ func LoadCacheConfig() error {
 		return err
 	}
 
-	configCache = kv
+	applyEnvConfig(kv)
+	setConfigCache(kv)
 
 	return nil
 }
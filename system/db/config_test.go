@@ -0,0 +1,41 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestPutConfigKeepsLists(t *testing.T) {
+	defer tempStore(t)()
+
+	err := SetConfig(url.Values{
+		"cors_allowed_origins":  {"https://a.com", "https://b.com"},
+		"compression_min_bytes": {"1000000"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = PutConfig("etag", "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = LoadCacheConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fmt.Sprint(ConfigCache("cors_allowed_origins")); got != "[https://a.com https://b.com]" {
+		t.Errorf("expected the allowed origins to be kept, got %s", got)
+	}
+
+	if got := ConfigCache("compression_min_bytes"); got != float64(1000000) {
+		t.Errorf("expected a large number to be kept, got %v", got)
+	}
+
+	if got := ConfigCache("search_analyzers"); got != nil {
+		t.Errorf("expected an unset list to stay empty, got %v", got)
+	}
+}
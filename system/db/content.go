@@ -4,13 +4,18 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
 	"net/url"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/sanitize"
+	"github.com/ponzu-cms/ponzu/system/webhook"
 
 	"github.com/boltdb/bolt"
 	"github.com/gorilla/schema"
@@ -19,6 +24,9 @@ import (
 
 // SetContent inserts or updates values in the database.
 // The `target` argument is a string made up of namespace:id (string:int)
+// Each save of content is a new version of it. An update with a version in
+// data, the version of the content it was edited from, returns
+// ErrVersionConflict if the content has been saved since.
 func SetContent(target string, data url.Values) (int, error) {
 	t := strings.Split(target, ":")
 	ns, id := t[0], t[1]
@@ -49,30 +57,83 @@ func update(ns, id string, data url.Values) (int, error) {
 		return 0, err
 	}
 
-	j, err := postToJSON(ns, data)
-	if err != nil {
-		return 0, err
+	// if type has a specifier, add it to data for downstream processing
+	if specifier != "" {
+		data.Set("__specifier", specifier)
 	}
 
+	var j []byte
 	err = store.Update(func(tx *bolt.Tx) error {
 		b, err := tx.CreateBucketIfNotExists([]byte(ns + specifier))
 		if err != nil {
 			return err
 		}
 
-		err = b.Put([]byte(fmt.Sprintf("%d", cid)), j)
+		k := []byte(fmt.Sprintf("%d", cid))
+		current := b.Get(k)
+
+		err = setVersion(data, current)
+		if err != nil {
+			return err
+		}
+
+		ci := tx.Bucket([]byte("__contentIndex"))
+		j, err = postToJSON(ns, data, func(slug string) string {
+			if ci == nil {
+				return slug
+			}
+
+			return uniqueSlug(ci, slug, fmt.Sprintf("%s:%d", ns, cid))
+		})
+		if err != nil {
+			return err
+		}
+
+		j, err = keepCounters(ns, current, j)
+		if err != nil {
+			return err
+		}
+
+		j, err = keepOrder(ns, current, j)
+		if err != nil {
+			return err
+		}
+
+		if specifier == "" {
+			j, err = canonicalTags(tx, ns, j)
+			if err != nil {
+				return err
+			}
+		}
+
+		// keep the previous state of the content as a revision
+		err = saveRevision(tx, ns+specifier, string(k), current)
 		if err != nil {
 			return err
 		}
 
+		err = b.Put(k, j)
+		if err != nil {
+			return err
+		}
+
+		if specifier == "" {
+			err = indexSlug(tx, ns, string(k), slugOf(current), data.Get("slug"))
+			if err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
-		return 0, nil
+		return 0, err
 	}
 
 	if specifier == "" {
 		go SortContent(ns)
+		go updateSearchIndex(ns, id, j)
+		go notifyWebhooks(webhook.EventUpdate, ns, id, j)
 	}
 
 	// update changes data, so invalidate client caching
@@ -86,6 +147,7 @@ func update(ns, id string, data url.Values) (int, error) {
 
 func insert(ns string, data url.Values) (int, error) {
 	var effectedID int
+	var j []byte
 	var specifier string // i.e. __pending, __sorted, etc.
 	if strings.Contains(ns, "__") {
 		spec := strings.Split(ns, "__")
@@ -116,16 +178,43 @@ func insert(ns string, data url.Values) (int, error) {
 		uid := uuid.NewV4()
 		data.Set("uuid", uid.String())
 
+		// new content starts at the first version, whatever it was copied from
+		data.Del("version")
+		err = setVersion(data, nil)
+		if err != nil {
+			return err
+		}
+
+		// new content is placed last, and ids only grow, so they are free
+		// positions which never collide with those of other content
+		if orderable(ns) && data.Get("order") == "" {
+			data.Set("order", cid)
+		}
+
 		// if type has a specifier, add it to data for downstream processing
 		if specifier != "" {
 			data.Set("__specifier", specifier)
 		}
 
-		j, err := postToJSON(ns, data)
+		ci := tx.Bucket([]byte("__contentIndex"))
+		if ci == nil {
+			return bolt.ErrBucketNotFound
+		}
+
+		j, err = postToJSON(ns, data, func(slug string) string {
+			return uniqueSlug(ci, slug, "")
+		})
 		if err != nil {
 			return err
 		}
 
+		if specifier == "" {
+			j, err = canonicalTags(tx, ns, j)
+			if err != nil {
+				return err
+			}
+		}
+
 		err = b.Put([]byte(cid), j)
 		if err != nil {
 			return err
@@ -133,14 +222,7 @@ func insert(ns string, data url.Values) (int, error) {
 
 		// store the slug,type:id in contentIndex if public content
 		if specifier == "" {
-			ci := tx.Bucket([]byte("__contentIndex"))
-			if ci == nil {
-				return bolt.ErrBucketNotFound
-			}
-
-			k := []byte(data.Get("slug"))
-			v := []byte(fmt.Sprintf("%s:%d", ns, effectedID))
-			err := ci.Put(k, v)
+			err := indexSlug(tx, ns, cid, "", data.Get("slug"))
 			if err != nil {
 				return err
 			}
@@ -154,6 +236,8 @@ func insert(ns string, data url.Values) (int, error) {
 
 	if specifier == "" {
 		go SortContent(ns)
+		go updateSearchIndex(ns, strconv.Itoa(effectedID), j)
+		go notifyWebhooks(webhook.EventCreate, ns, strconv.Itoa(effectedID), j)
 	}
 
 	// insert changes data, so invalidate client caching
@@ -165,43 +249,38 @@ func insert(ns string, data url.Values) (int, error) {
 	return effectedID, nil
 }
 
-// DeleteContent removes an item from the database. Deleting a non-existent item
+// DeleteContent moves an item from the database to the trash, from which it can
+// be restored with Restore until it is purged. Deleting a non-existent item
 // will return a nil error.
 func DeleteContent(target string, data url.Values) error {
+	return deleteContent(target, data, true)
+}
+
+// PurgeContent permanently removes an item from the database, without moving
+// it to the trash. Purging a non-existent item will return a nil error.
+func PurgeContent(target string, data url.Values) error {
+	return deleteContent(target, data, false)
+}
+
+func deleteContent(target string, data url.Values, trash bool) error {
 	t := strings.Split(target, ":")
 	ns, id := t[0], t[1]
 
+	var deleted []byte
 	err := store.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(ns))
-		if b == nil {
-			return bolt.ErrBucketNotFound
-		}
-
-		err := b.Delete([]byte(id))
-		if err != nil {
-			return err
-		}
-
-		// if content has a slug, also delete it from __contentIndex
-		slug := data.Get("slug")
-		if slug != "" {
-			ci := tx.Bucket([]byte("__contentIndex"))
-			if ci == nil {
-				return bolt.ErrBucketNotFound
-			}
-
-			err := ci.Delete([]byte(slug))
-			if err != nil {
-				return err
-			}
-		}
-
-		return nil
+		var err error
+		deleted, err = deleteItem(tx, ns, id, data.Get("slug"), trash)
+		return err
 	})
 	if err != nil {
 		return err
 	}
 
+	if !strings.Contains(ns, "__") {
+		go deleteSearchIndex(ns, id)
+		go notifyWebhooks(webhook.EventDelete, ns, id, deleted)
+	}
+
 	// delete changes data, so invalidate client caching
 	err = InvalidateCache()
 	if err != nil {
@@ -217,6 +296,60 @@ func DeleteContent(target string, data url.Values) error {
 	return nil
 }
 
+// deleteItem removes the item id from the bucket ns in tx, moving it to the
+// trash if trash is set, and returns the content it removed. slug is used to
+// remove the item from __contentIndex if its content has no slug.
+func deleteItem(tx *bolt.Tx, ns, id, slug string, trash bool) ([]byte, error) {
+	b := tx.Bucket([]byte(ns))
+	if b == nil {
+		return nil, bolt.ErrBucketNotFound
+	}
+
+	// keep a copy of the content to send to webhooks
+	var deleted []byte
+	if v := b.Get([]byte(id)); v != nil {
+		deleted = make([]byte, len(v))
+		copy(deleted, v)
+	}
+
+	if trash {
+		err := putTrash(tx, ns, id, b.Get([]byte(id)))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err := deleteRevisions(tx, ns, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := b.Delete([]byte(id))
+	if err != nil {
+		return nil, err
+	}
+
+	// if content has a slug, also delete it from __contentIndex
+	if s := slugOf(deleted); s != "" {
+		slug = s
+	}
+	if slug != "" {
+		ci := tx.Bucket([]byte("__contentIndex"))
+		if ci == nil {
+			return nil, bolt.ErrBucketNotFound
+		}
+
+		if string(ci.Get([]byte(slug))) == ns+":"+id {
+			err := ci.Delete([]byte(slug))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
 // Content retrives one item from the database. Non-existent values will return an empty []byte
 // The `target` argument is a string made up of namespace:id (string:int)
 func Content(target string) ([]byte, error) {
@@ -230,9 +363,9 @@ func Content(target string) ([]byte, error) {
 			return bolt.ErrBucketNotFound
 		}
 
-		_, err := val.Write(b.Get([]byte(id)))
+		_, err := val.Write(migrated(ns, b.Get([]byte(id))))
 		if err != nil {
-			log.Println(err)
+			logger.Error(err)
 			return err
 		}
 
@@ -247,7 +380,8 @@ func Content(target string) ([]byte, error) {
 
 // ContentBySlug does a lookup in the content index to find the type and id of
 // the requested content. Subsequently, issues the lookup in the type bucket and
-// returns the the type and data at that ID or nil if nothing exists.
+// returns the the type and data at that ID or nil if nothing exists. The type
+// is "" if no content has the slug.
 func ContentBySlug(slug string) (string, []byte, error) {
 	val := &bytes.Buffer{}
 	var t, id string
@@ -257,22 +391,22 @@ func ContentBySlug(slug string) (string, []byte, error) {
 			return bolt.ErrBucketNotFound
 		}
 		idx := b.Get([]byte(slug))
+		if idx == nil {
+			return nil
+		}
 
-		if idx != nil {
-			tid := strings.Split(string(idx), ":")
-
-			if len(tid) < 2 {
-				return fmt.Errorf("Bad data in content index for slug: %s", slug)
-			}
-
-			t, id = tid[0], tid[1]
+		tid := strings.Split(string(idx), ":")
+		if len(tid) < 2 {
+			return fmt.Errorf("Bad data in content index for slug: %s", slug)
 		}
 
+		t, id = tid[0], tid[1]
+
 		c := tx.Bucket([]byte(t))
 		if c == nil {
 			return bolt.ErrBucketNotFound
 		}
-		_, err := val.Write(c.Get([]byte(id)))
+		_, err := val.Write(migrated(t, c.Get([]byte(id))))
 		if err != nil {
 			return err
 		}
@@ -299,7 +433,7 @@ func ContentAll(namespace string) [][]byte {
 		posts = make([][]byte, 0, numKeys)
 
 		b.ForEach(func(k, v []byte) error {
-			posts = append(posts, v)
+			posts = append(posts, migrated(namespace, v))
 
 			return nil
 		})
@@ -310,6 +444,34 @@ func ContentAll(namespace string) [][]byte {
 	return posts
 }
 
+// Total returns the number of public items of the content type namespace,
+// leaving out those not yet published. The count is kept when
+// content is sorted, so it is read without scanning the content. If the type
+// has not yet been sorted, ok is false.
+func Total(namespace string) (total int, ok bool) {
+	store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__counts"))
+		if b == nil {
+			return nil
+		}
+
+		v := b.Get([]byte(namespace))
+		if v == nil {
+			return nil
+		}
+
+		n, err := strconv.Atoi(string(v))
+		if err != nil {
+			return err
+		}
+
+		total, ok = n, true
+		return nil
+	})
+
+	return
+}
+
 // QueryOptions holds options for a query
 type QueryOptions struct {
 	Count  int
@@ -381,7 +543,7 @@ func Query(namespace string, opts QueryOptions) (int, [][]byte) {
 					break
 				}
 
-				posts = append(posts, v)
+				posts = append(posts, migrated(namespace, v))
 				i++
 				cur++
 			}
@@ -397,7 +559,7 @@ func Query(namespace string, opts QueryOptions) (int, [][]byte) {
 					break
 				}
 
-				posts = append(posts, v)
+				posts = append(posts, migrated(namespace, v))
 				i++
 				cur++
 			}
@@ -414,7 +576,7 @@ func Query(namespace string, opts QueryOptions) (int, [][]byte) {
 					break
 				}
 
-				posts = append(posts, v)
+				posts = append(posts, migrated(namespace, v))
 				i++
 				cur++
 			}
@@ -445,7 +607,7 @@ func SortContent(namespace string) {
 
 		err := json.Unmarshal(j, &post)
 		if err != nil {
-			log.Println("Error decoding json while sorting", namespace, ":", err)
+			logger.Error("Error decoding json while sorting", namespace, ":", err)
 			return
 		}
 
@@ -455,13 +617,31 @@ func SortContent(namespace string) {
 	// sort posts
 	sort.Sort(posts)
 
+	// count the public posts, leaving out those not yet published, and find
+	// when the next scheduled post is published
+	now := time.Now()
+	public := 0
+	var next int64
+	for i := range posts {
+		if item.IsPublic(posts[i], now) {
+			public++
+		}
+
+		if item.IsScheduled(posts[i], now) {
+			at := posts[i].(item.Schedulable).PublishTime()
+			if next == 0 || at < next {
+				next = at
+			}
+		}
+	}
+
 	// marshal posts to json
 	var bb [][]byte
 	for i := range posts {
 		j, err := json.Marshal(posts[i])
 		if err != nil {
 			// log error and kill sort so __sorted is not in invalid state
-			log.Println("Error marshal post to json in SortContent:", err)
+			logger.Error("Error marshal post to json in SortContent:", err)
 			return
 		}
 
@@ -481,21 +661,47 @@ func SortContent(namespace string) {
 			return err
 		}
 
-		// encode to json and store as 'i:post.Time()':post
 		for i := range bb {
-			cid := fmt.Sprintf("%d:%d", i, posts[i].Time())
-			err = b.Put([]byte(cid), bb[i])
+			id := i
+			if ident, ok := posts[i].(item.Identifiable); ok {
+				id = ident.ItemID()
+			}
+
+			err = b.Put(sortKey(posts[i].Time(), id), bb[i])
 			if err != nil {
 				return err
 			}
 		}
 
-		return nil
+		counts, err := tx.CreateBucketIfNotExists([]byte("__counts"))
+		if err != nil {
+			return err
+		}
+
+		return counts.Put([]byte(namespace), []byte(strconv.Itoa(public)))
 	})
 	if err != nil {
-		log.Println("Error while updating db with sorted", namespace, err)
+		logger.Error("Error while updating db with sorted", namespace, err)
+		return
 	}
 
+	setNextPublish(namespace, next)
+
+	// lists of content are read from the sorted bucket, which is only now up
+	// to date with the change that invalidated the cache
+	contentChanged()
+}
+
+// sortKey is the key of content in its __sorted bucket, which orders content
+// from newest to oldest by timestamp ts, and content with the same timestamp
+// by id. Keys don't depend on the position of the content, so a cursor from a
+// key stays valid as other content changes.
+func sortKey(ts int64, id int) []byte {
+	if ts < 0 {
+		ts = 0
+	}
+
+	return []byte(fmt.Sprintf("%019d:%019d", math.MaxInt64-ts, id))
 }
 
 type sortableContent []item.Sortable
@@ -512,7 +718,7 @@ func (s sortableContent) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
 
-func postToJSON(ns string, data url.Values) ([]byte, error) {
+func postToJSON(ns string, data url.Values, unique func(slug string) string) ([]byte, error) {
 	// find the content type and decode values into it
 	t, ok := item.Types[ns]
 	if !ok {
@@ -523,22 +729,62 @@ func postToJSON(ns string, data url.Values) ([]byte, error) {
 	dec := schema.NewDecoder()
 	dec.SetAliasTag("json")     // allows simpler struct tagging when creating a content type
 	dec.IgnoreUnknownKeys(true) // will skip over form values submitted, but not in struct
+	dec.RegisterConverter(item.Color(""), convertColor)
 	err := dec.Decode(post, data)
+	if err != nil {
+		return nil, fieldError(err)
+	}
+
+	err = checkFieldGroups(post)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkLocations(post)
+	if err != nil {
+		return nil, err
+	}
+
+	// strip fields holding HTML of any tags or attributes not allowed
+	var html []string
+	if s, ok := post.(item.Sanitizable); ok {
+		html = s.Sanitize()
+		sanitizeFields(post, html)
+	}
+
+	// translations are kept only for the fields which can be translated
+	if l, ok := post.(item.Localizable); ok {
+		cleanTranslations(post, l.Localize(), html)
+	}
+
+	err = item.Validate(post)
 	if err != nil {
 		return nil, err
 	}
 
-	// if the content has no slug, and has no specifier, create a slug, check it
-	// for duplicates, and add it to our values
-	if data.Get("slug") == "" && data.Get("__specifier") == "" {
-		slug, err := item.Slug(post.(item.Identifiable))
+	setSchemaVersion(post)
+
+	// give content without a specifier a slug, generating it from the title
+	// of the content if none was given, and make it unique if unique is set
+	if data.Get("__specifier") == "" {
+		slug, err := item.Slugify(data.Get("slug"))
 		if err != nil {
 			return nil, err
 		}
 
-		slug, err = checkSlugForDuplicate(slug)
-		if err != nil {
-			return nil, err
+		if slug == "" {
+			slug, err = item.Slug(post.(item.Identifiable))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if slug == "" {
+			slug = fmt.Sprintf("%s-%s", strings.ToLower(ns), data.Get("id"))
+		}
+
+		if unique != nil {
+			slug = unique(slug)
 		}
 
 		post.(item.Sluggable).SetSlug(slug)
@@ -554,32 +800,131 @@ func postToJSON(ns string, data url.Values) ([]byte, error) {
 	return j, nil
 }
 
-func checkSlugForDuplicate(slug string) (string, error) {
-	// check for existing slug in __contentIndex
-	err := store.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte("__contentIndex"))
-		if b == nil {
-			return bolt.ErrBucketNotFound
+// convertColor is the gorilla/schema converter for item.Color fields, which
+// normalizes a hex color or returns an invalid reflect.Value
+func convertColor(value string) reflect.Value {
+	c, err := item.ParseColor(value)
+	if err != nil {
+		return reflect.Value{}
+	}
+
+	return reflect.ValueOf(c)
+}
+
+// fieldError returns an *item.FieldError for the first form value which could
+// not be decoded into its field, or err as-is if it is not a conversion error
+func fieldError(err error) error {
+	errs, ok := err.(schema.MultiError)
+	if !ok {
+		return err
+	}
+
+	for _, e := range errs {
+		conv, ok := e.(schema.ConversionError)
+		if !ok {
+			continue
+		}
+
+		reason := conv.Err
+		switch {
+		case conv.Type == reflect.TypeOf(item.Color("")):
+			reason = item.ErrInvalidColor
+		case reason == nil:
+			reason = fmt.Errorf("not a valid %s", conv.Type)
+		}
+
+		return &item.FieldError{Field: conv.Key, Err: reason}
+	}
+
+	return err
+}
+
+// checkLocations returns an *item.FieldError for the first item.Location field
+// in post with coordinates out of range
+func checkLocations(post interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(post))
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Field(i).CanInterface() {
+			continue
 		}
-		original := slug
-		exists := true
-		i := 0
-		for exists {
-			s := b.Get([]byte(slug))
-			if s == nil {
-				exists = false
-				return nil
+
+		loc, ok := v.Field(i).Interface().(item.Location)
+		if !ok {
+			continue
+		}
+
+		err := loc.Valid()
+		if err != nil {
+			tag := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+			return &item.FieldError{Field: tag, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// checkFieldGroups drops the empty rows of the field groups (slices of structs)
+// in post, and returns an *item.RowError for the first row left which fails
+// its item.RowValidator check
+func checkFieldGroups(post interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(post))
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() != reflect.Slice || field.Len() == 0 || !field.CanSet() ||
+			field.Type().Elem().Kind() != reflect.Struct {
+			continue
+		}
+
+		empty := reflect.Zero(field.Type().Elem()).Interface()
+		rows := reflect.MakeSlice(field.Type(), 0, field.Len())
+		for j := 0; j < field.Len(); j++ {
+			if !reflect.DeepEqual(field.Index(j).Interface(), empty) {
+				rows = reflect.Append(rows, field.Index(j))
 			}
+		}
+		field.Set(rows)
 
-			i++
-			slug = fmt.Sprintf("%s-%d", original, i)
+		for j := 0; j < rows.Len(); j++ {
+			row, ok := rows.Index(j).Addr().Interface().(item.RowValidator)
+			if !ok {
+				break
+			}
+
+			err := row.ValidateRow()
+			if err != nil {
+				tag := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+				return &item.RowError{Field: tag, Row: j, Err: err}
+			}
 		}
+	}
 
-		return nil
-	})
-	if err != nil {
-		return "", err
+	return nil
+}
+
+// sanitizeFields runs the string or []string fields of post with the json tag
+// names in fields through the HTML sanitizer
+func sanitizeFields(post interface{}, fields []string) {
+	names := make(map[string]bool)
+	for _, f := range fields {
+		names[f] = true
 	}
 
-	return slug, nil
+	v := reflect.Indirect(reflect.ValueOf(post))
+	for i := 0; i < v.NumField(); i++ {
+		tag := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if !names[tag] || !v.Field(i).CanSet() {
+			continue
+		}
+
+		field := v.Field(i)
+		switch {
+		case field.Kind() == reflect.String:
+			field.SetString(sanitize.HTML(field.String()))
+
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+			for j := 0; j < field.Len(); j++ {
+				field.Index(j).SetString(sanitize.HTML(field.Index(j).String()))
+			}
+		}
+	}
 }
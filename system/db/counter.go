@@ -0,0 +1,125 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+
+	"github.com/boltdb/bolt"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+var (
+	// ErrNoContentExists is returned when incrementing a counter of content
+	// which doesn't exist
+	ErrNoContentExists = errors.New("No content exists.")
+	// ErrNotCounter is returned when incrementing a field whose value isn't a
+	// whole number
+	ErrNotCounter = errors.New("Only whole number fields can be incremented.")
+)
+
+// Increment adds delta to the number field of the content namespace:id and
+// returns its new value. The content is read and written within a single
+// transaction, so that concurrent increments, such as of a view count, are
+// never lost. A field without a value starts from 0. Unlike updating content,
+// incrementing keeps no revision and notifies no webhooks.
+func Increment(namespace, id, field string, delta int64) (int64, error) {
+	var value int64
+	var j []byte
+	err := store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(namespace))
+		if b == nil {
+			return ErrNoContentExists
+		}
+
+		current := b.Get([]byte(id))
+		if current == nil {
+			return ErrNoContentExists
+		}
+
+		v := gjson.GetBytes(current, field)
+		switch {
+		case !v.Exists() || v.Type == gjson.Null:
+		case v.Type == gjson.Number && v.Num == float64(v.Int()):
+			value = v.Int()
+		default:
+			return ErrNotCounter
+		}
+		value += delta
+
+		var err error
+		j, err = sjson.SetBytes(current, field, value)
+		if err != nil {
+			return err
+		}
+
+		err = b.Put([]byte(id), j)
+		if err != nil {
+			return err
+		}
+
+		// keep the sorted copy of the content up to date too, so lists show
+		// the new value without sorting the content again
+		sorted := tx.Bucket([]byte(namespace + "__sorted"))
+		if sorted == nil {
+			return nil
+		}
+
+		key := sortKey(gjson.GetBytes(current, "timestamp").Int(), int(gjson.GetBytes(current, "id").Int()))
+		if sorted.Get(key) == nil {
+			return nil
+		}
+
+		return sorted.Put(key, j)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if !strings.Contains(namespace, "__") {
+		go updateSearchIndex(namespace, id, j)
+	}
+
+	// counters change too often to set a new etag for each, but cached
+	// responses and Last-Modified must still reflect them
+	contentChanged()
+	lastModified.Lock()
+	lastModified.t = time.Now()
+	lastModified.Unlock()
+
+	return value, nil
+}
+
+// keepCounters returns the content json j being saved over current with the
+// values of its counter fields from current, if its type is item.Countable.
+// Counters are only changed by Increment, so saving content edited from a copy
+// read before an increment doesn't lose it.
+func keepCounters(ns string, current, j []byte) ([]byte, error) {
+	it, ok := item.Types[ns]
+	if !ok || len(current) == 0 {
+		return j, nil
+	}
+
+	countable, ok := it().(item.Countable)
+	if !ok {
+		return j, nil
+	}
+
+	for _, field := range countable.Counters() {
+		v := gjson.GetBytes(current, field)
+		if !v.Exists() {
+			continue
+		}
+
+		var err error
+		j, err = sjson.SetRawBytes(j, field, []byte(v.Raw))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return j, nil
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/admin/config"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// EnvPrefix is the prefix of the environment variables which override config
+// values, followed by the config key in upper case, e.g. PONZU_BIND_ADDR for
+// bind_addr
+const EnvPrefix = "PONZU_"
+
+// notFromEnv are the config keys which can't be overridden from the
+// environment: those Ponzu keeps up to date itself, and the ports, which are
+// saved from the command line flags each time the server starts
+var notFromEnv = map[string]bool{
+	"etag":       true,
+	"cache":      true,
+	"http_port":  true,
+	"https_port": true,
+}
+
+// EnvVar returns the name of the environment variable which overrides the
+// config value key
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// EnvKeys returns the config keys which can be overridden from the
+// environment, in the order the settings are shown in the admin
+func EnvKeys() []string {
+	var keys []string
+	for _, f := range configFields() {
+		keys = append(keys, f.key)
+	}
+
+	return keys
+}
+
+// configField is a setting of config.Config, by its json key
+type configField struct {
+	key  string
+	kind reflect.Type
+}
+
+// configFields returns the settings of config.Config which can be overridden
+// from the environment
+func configFields() []configField {
+	var fields []configField
+	t := reflect.TypeOf(config.Config{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			continue
+		}
+
+		key := strings.Split(f.Tag.Get("json"), ",")[0]
+		if key == "" || key == "-" || notFromEnv[key] {
+			continue
+		}
+
+		fields = append(fields, configField{key: key, kind: f.Type})
+	}
+
+	return fields
+}
+
+// envConfig returns the config values set in the environment, as they are
+// decoded from the stored json: strings, numbers as float64, booleans, and
+// lists, which are comma separated in the environment, as []interface{}.
+// Variables which are empty are ignored, as are those which can't be read as
+// the type of their setting, with an error returned for each.
+func envConfig() (map[string]interface{}, []error) {
+	values := make(map[string]interface{})
+	var invalid []error
+	for _, f := range configFields() {
+		v := os.Getenv(EnvVar(f.key))
+		if v == "" {
+			continue
+		}
+
+		switch f.kind.Kind() {
+		case reflect.String:
+			values[f.key] = v
+
+		case reflect.Bool:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				invalid = append(invalid, fmt.Errorf("Ignoring %s, which is not true or false: %q", EnvVar(f.key), v))
+				continue
+			}
+			values[f.key] = b
+
+		case reflect.Int:
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				invalid = append(invalid, fmt.Errorf("Ignoring %s, which is not a whole number: %q", EnvVar(f.key), v))
+				continue
+			}
+			values[f.key] = float64(n)
+
+		case reflect.Slice:
+			var list []interface{}
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					list = append(list, s)
+				}
+			}
+			values[f.key] = list
+		}
+	}
+
+	return values, invalid
+}
+
+// applyEnvConfig replaces the config values in kv with those set in the
+// environment, which take precedence over the stored config, logging a warning
+// for each variable which can't be read
+func applyEnvConfig(kv map[string]interface{}) {
+	values, invalid := envConfig()
+	for _, err := range invalid {
+		logger.Warn(err)
+	}
+
+	for k, v := range values {
+		kv[k] = v
+	}
+}
+
+// EnvOverrides returns the config keys whose stored values are overridden
+// from the environment, sorted
+func EnvOverrides() []string {
+	values, _ := envConfig()
+
+	var keys []string
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,214 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+)
+
+// ImportField is a field of a content type which values can be imported into
+type ImportField struct {
+	// Name is the json tag name of the field
+	Name string
+	// Label is the name of the struct field
+	Label string
+	// List is true for slice fields, whose values are separated by
+	// ImportListSeparator in a single column
+	List bool
+}
+
+// ImportListSeparator separates the values of list fields in imported columns
+const ImportListSeparator = "|"
+
+// ImportResult is the outcome of importing a row, where Row is the 1-based
+// number of the row after the header. ID is the id of the created item, or 0
+// for a dry run or when Err is set.
+type ImportResult struct {
+	Row int
+	ID  int
+	Err error
+}
+
+// ImportFields returns the fields of content type ns which can be imported,
+// in the order they are declared. Fields which are set by the system, such as
+// id and uuid, and the workflow state, and those of nested structs are left
+// out.
+func ImportFields(ns string) ([]ImportField, error) {
+	t, ok := item.Types[ns]
+	if !ok {
+		return nil, fmt.Errorf(item.ErrTypeNotRegistered.Error(), ns)
+	}
+
+	return importFields(reflect.Indirect(reflect.ValueOf(t())).Type()), nil
+}
+
+func importFields(t reflect.Type) []ImportField {
+	var fields []ImportField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, importFields(f.Type)...)
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.PkgPath != "" || tag == "" || tag == "-" || tag == "id" || tag == "uuid" || tag == "state" {
+			continue
+		}
+
+		kind := f.Type.Kind()
+		list := kind == reflect.Slice
+		if list {
+			kind = f.Type.Elem().Kind()
+		}
+		if kind == reflect.Struct || kind == reflect.Map || kind == reflect.Interface ||
+			kind == reflect.Ptr || kind == reflect.Slice {
+			continue
+		}
+
+		fields = append(fields, ImportField{Name: tag, Label: f.Name, List: list})
+	}
+
+	return fields
+}
+
+// ImportOptions change how ImportRowsWithOptions creates items
+type ImportOptions struct {
+	// DryRun only validates rows, and creates no items
+	DryRun bool
+
+	// Prepare is called with the values of each row before it is validated
+	// or saved, and may change them. Rows it returns an error for are not
+	// imported.
+	Prepare func(data url.Values) error
+}
+
+// ImportRows creates an item of content type ns for each of rows, taking the
+// value of each column for the field named at the same position in columns.
+// Columns mapped to an empty field name are skipped. Rows which fail to decode
+// or save are reported in their ImportResult and do not stop the import. If
+// dryRun is true rows are only validated, and no items are created.
+func ImportRows(ns string, columns []string, rows [][]string, dryRun bool) ([]ImportResult, error) {
+	return ImportRowsWithOptions(ns, columns, rows, ImportOptions{DryRun: dryRun})
+}
+
+// ImportRowsWithOptions is like ImportRows, with the options in opts
+func ImportRowsWithOptions(ns string, columns []string, rows [][]string, opts ImportOptions) ([]ImportResult, error) {
+	fields, err := ImportFields(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]ImportField)
+	for _, f := range fields {
+		known[f.Name] = f
+	}
+
+	results := make([]ImportResult, 0, len(rows))
+	for i, row := range rows {
+		res := ImportResult{Row: i + 1}
+
+		data, err := importValues(known, columns, row)
+		if err == nil && opts.Prepare != nil {
+			err = opts.Prepare(data)
+		}
+
+		if err == nil && opts.DryRun {
+			_, err = postToJSON(ns, data, nil)
+		} else if err == nil {
+			res.ID, err = SetContent(ns+":-1", data)
+		}
+
+		res.Err = err
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// importValues returns the form values for a row, as they would be submitted
+// from the editor
+func importValues(fields map[string]ImportField, columns, row []string) (url.Values, error) {
+	if len(row) > len(columns) {
+		return nil, fmt.Errorf("Row has %d columns, but the header has %d", len(row), len(columns))
+	}
+
+	data := url.Values{}
+	for i, val := range row {
+		f, ok := fields[columns[i]]
+		if !ok {
+			continue
+		}
+
+		val = strings.TrimSpace(val)
+		if !f.List {
+			data.Set(f.Name, val)
+			continue
+		}
+
+		for _, v := range strings.Split(val, ImportListSeparator) {
+			if v = strings.TrimSpace(v); v != "" {
+				data.Add(f.Name, v)
+			}
+		}
+	}
+
+	ts := fmt.Sprintf("%d", time.Now().UnixNano()/int64(time.Millisecond))
+	if data.Get("timestamp") == "" {
+		data.Set("timestamp", ts)
+	}
+	if data.Get("updated") == "" {
+		data.Set("updated", ts)
+	}
+
+	return data, nil
+}
+
+// ImportJSONValues returns the form values for an item given as a JSON object,
+// such as one exported from the content API, as they would be submitted from
+// the editor. Arrays of values become multiple values of a field, and nested
+// objects are flattened to field.key, or field.N.key for arrays of objects.
+func ImportJSONValues(j []byte) (url.Values, error) {
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+
+	var obj map[string]interface{}
+	err := dec.Decode(&obj)
+	if err != nil {
+		return nil, err
+	}
+
+	data := url.Values{}
+	for k, v := range obj {
+		addJSONValues(data, k, v)
+	}
+
+	return data, nil
+}
+
+func addJSONValues(data url.Values, key string, v interface{}) {
+	switch x := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, val := range x {
+			addJSONValues(data, key+"."+k, val)
+		}
+	case []interface{}:
+		for i, val := range x {
+			if _, ok := val.(map[string]interface{}); ok {
+				addJSONValues(data, fmt.Sprintf("%s.%d", key, i), val)
+				continue
+			}
+			addJSONValues(data, key, val)
+		}
+	default:
+		data.Add(key, fmt.Sprint(x))
+	}
+}
@@ -1,9 +1,12 @@
 package db
 
 import (
-	"log"
+	"time"
 
 	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/scheduler"
+	"github.com/ponzu-cms/ponzu/system/search"
 
 	"github.com/boltdb/bolt"
 	"github.com/nilslice/jwt"
@@ -16,10 +19,21 @@ var store *bolt.DB
 func Close() {
 	err := store.Close()
 	if err != nil {
-		log.Println(err)
+		logger.Error(err)
 	}
 }
 
+// Ping reports whether the db is open and can be read
+func Ping() error {
+	if store == nil {
+		return bolt.ErrDatabaseNotOpen
+	}
+
+	return store.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
 // Init creates a db connection, initializes db with required info, sets secrets
 func Init() {
 	if store != nil {
@@ -29,7 +43,7 @@ func Init() {
 	var err error
 	store, err = bolt.Open("system.db", 0666, nil)
 	if err != nil {
-		log.Fatalln(err)
+		logger.Fatal(err)
 	}
 
 	err = store.Update(func(tx *bolt.Tx) error {
@@ -58,14 +72,18 @@ func Init() {
 		return nil
 	})
 	if err != nil {
-		log.Fatalln("Coudn't initialize db with buckets.", err)
+		logger.Fatal("Coudn't initialize db with buckets.", err)
 	}
 
 	err = LoadCacheConfig()
 	if err != nil {
-		log.Fatalln("Failed to load config cache.", err)
+		logger.Fatal("Failed to load config cache.", err)
 	}
 
+	search.SetDisabled(searchDisabledTypes())
+	applySearchSynonyms()
+	applySearchAnalyzers(searchAnalyzers(), searchStopWords())
+
 	clientSecret := ConfigCache("client_secret").(string)
 
 	if clientSecret != "" {
@@ -75,7 +93,7 @@ func Init() {
 	// invalidate cache on system start
 	err = InvalidateCache()
 	if err != nil {
-		log.Fatalln("Failed to invalidate cache.", err)
+		logger.Fatal("Failed to invalidate cache.", err)
 	}
 
 	go func() {
@@ -83,6 +101,22 @@ func Init() {
 			SortContent(t)
 		}
 	}()
+
+	go indexTags()
+
+	scheduler.Register("content.publish", scheduler.EveryFunc(publishInterval), publishScheduled())
+
+	// the hourly jobs also run once at startup, to catch up on the time the
+	// server was stopped for
+	hourly := map[string]func() error{
+		"trash.purge":    purgeExpiredTrash,
+		"audit.prune":    pruneAuditLog,
+		"sessions.prune": pruneSessions,
+	}
+	for name, run := range hourly {
+		scheduler.Register(name, scheduler.Every(time.Hour), run)
+		go scheduler.Run(name)
+	}
 }
 
 // SystemInitComplete checks if there is at least 1 admin user in the db which
@@ -108,7 +142,7 @@ func SystemInitComplete() bool {
 	})
 	if err != nil {
 		complete = false
-		log.Fatalln(err)
+		logger.Fatal(err)
 	}
 
 	return complete
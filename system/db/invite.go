@@ -0,0 +1,124 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrNoInvite is returned for an invitation which doesn't exist, has been
+// revoked or accepted, or has expired
+var ErrNoInvite = errors.New("Error. No invitation exists.")
+
+// Invite is an invitation sent to the owner of Email to become an admin user
+// with Role. Nonce is sent in the signed token of the invitation link, so that
+// the link stops working once the invitation is revoked or sent again. Times
+// are in unix milliseconds.
+type Invite struct {
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	InvitedBy string `json:"invited_by"`
+	Nonce     string `json:"nonce"`
+	Created   int64  `json:"created"`
+	Expires   int64  `json:"expires"`
+}
+
+// Expired reports whether the invitation can no longer be accepted
+func (i Invite) Expired() bool {
+	return time.Now().UnixNano()/int64(time.Millisecond) >= i.Expires
+}
+
+// SetInvite stores inv, replacing any invitation already sent to its email
+func SetInvite(inv Invite) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("__invites"))
+		if err != nil {
+			return err
+		}
+
+		j, err := json.Marshal(inv)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(inv.Email), j)
+	})
+}
+
+// GetInvite returns the invitation sent to email, if it has not expired
+func GetInvite(email string) (Invite, error) {
+	var inv Invite
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__invites"))
+		if b == nil {
+			return ErrNoInvite
+		}
+
+		j := b.Get([]byte(email))
+		if j == nil {
+			return ErrNoInvite
+		}
+
+		return json.Unmarshal(j, &inv)
+	})
+	if err != nil {
+		return Invite{}, err
+	}
+
+	if inv.Expired() {
+		return Invite{}, ErrNoInvite
+	}
+
+	return inv, nil
+}
+
+// DeleteInvite removes the invitation sent to email, once it is accepted or
+// revoked
+func DeleteInvite(email string) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__invites"))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(email))
+	})
+}
+
+// Invites returns every invitation which has not been accepted or revoked,
+// including those which have expired, newest first
+func Invites() ([]Invite, error) {
+	var invites []Invite
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__invites"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var inv Invite
+			err := json.Unmarshal(v, &inv)
+			if err != nil {
+				logger.Error("Error decoding invitation:", err)
+				return nil
+			}
+
+			invites = append(invites, inv)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		return invites[i].Created > invites[j].Created
+	})
+
+	return invites, nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/sanitize"
+	"github.com/ponzu-cms/ponzu/system/search"
+)
+
+// defaultLocale is the locale content is saved in, if default_locale is not
+// configured
+const defaultLocale = "en"
+
+// Locales returns the locale content is saved in and the other locales which
+// Localizable content can be translated into, from the config. others is
+// empty if no other locales are configured, so content isn't localized.
+func Locales() (def string, others []string) {
+	def, _ = ConfigCache("default_locale").(string)
+	def = strings.ToLower(strings.TrimSpace(def))
+	if def == "" {
+		def = defaultLocale
+	}
+
+	seen := map[string]bool{def: true}
+	vals, _ := ConfigCache("locales").([]interface{})
+	for _, v := range vals {
+		l, _ := v.(string)
+		l = strings.ToLower(strings.TrimSpace(l))
+		if l == "" || seen[l] {
+			continue
+		}
+		seen[l] = true
+
+		others = append(others, l)
+	}
+
+	return def, others
+}
+
+// cleanTranslations drops the translations of post which are empty or for
+// fields not in fields, and runs those of the fields in html through the HTML
+// sanitizer, the same as the fields themselves
+func cleanTranslations(post interface{}, fields, html []string) {
+	v := reflect.Indirect(reflect.ValueOf(post)).FieldByName("Translations")
+	translations, ok := v.Interface().([]item.Translation)
+	if !ok || !v.CanSet() {
+		return
+	}
+
+	localized := make(map[string]bool)
+	for _, f := range fields {
+		localized[f] = true
+	}
+
+	sanitized := make(map[string]bool)
+	for _, f := range html {
+		sanitized[f] = true
+	}
+
+	var kept []item.Translation
+	for _, t := range translations {
+		if t.Locale == "" || t.Value == "" || !localized[t.Field] {
+			continue
+		}
+
+		if sanitized[t.Field] {
+			t.Value = sanitize.HTML(t.Value)
+		}
+
+		kept = append(kept, t)
+	}
+
+	v.Set(reflect.ValueOf(kept))
+}
+
+// searchDocs returns the documents the content ns:id with json data is added
+// to the search index as, by the id each is indexed with. Localizable content
+// is indexed once in the default locale, without its translations, and again
+// translated into each other configured locale, so that each locale is
+// searched by its own values. localized is true for Localizable content.
+func searchDocs(ns, id string, data []byte) (docs map[string][]byte, localized bool, err error) {
+	docs = map[string][]byte{id: data}
+
+	it, ok := item.Types[ns]
+	if !ok {
+		return docs, false, nil
+	}
+
+	l, ok := it().(item.Localizable)
+	if !ok {
+		return docs, false, nil
+	}
+
+	def, others := Locales()
+	for _, locale := range append([]string{def}, others...) {
+		j, err := item.Translate(data, l.Localize(), locale)
+		if err != nil {
+			return nil, true, err
+		}
+
+		if locale == def {
+			docs[id] = j
+			continue
+		}
+
+		docs[search.LocaleID(id, locale)] = j
+	}
+
+	return docs, true, nil
+}
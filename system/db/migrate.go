@@ -0,0 +1,201 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/search"
+
+	"github.com/boltdb/bolt"
+	"github.com/tidwall/gjson"
+)
+
+// MigrationReport is the result of migrating the content of a content type,
+// or of a dry run of it
+type MigrationReport struct {
+	Type string `json:"type"`
+
+	// Version is the schema version content is migrated to, the number of
+	// migrations of the type
+	Version int `json:"version"`
+
+	// Items is the number of items below Version, including those pending
+	// approval
+	Items int `json:"items"`
+
+	// Changed is the number of items each migration changed, or would change
+	// in a dry run, in the order of the migrations
+	Changed []int `json:"changed"`
+}
+
+// migrations returns the migrations of the content type of the bucket ns,
+// which may have a specifier such as __pending, if it is item.Migratable
+func migrations(ns string) []item.Migration {
+	it, ok := item.Types[strings.Split(ns, "__")[0]]
+	if !ok {
+		return nil
+	}
+
+	m, ok := it().(item.Migratable)
+	if !ok {
+		return nil
+	}
+
+	return m.Migrations()
+}
+
+// migrated returns the content json data read from the bucket ns with the
+// migrations of its type it hasn't been through applied, so that content saved
+// before a change to its type is read in its current shape. Content which
+// fails to migrate is logged and returned as it is.
+func migrated(ns string, data []byte) []byte {
+	ms := migrations(ns)
+	if len(ms) == 0 || len(data) == 0 {
+		return data
+	}
+
+	// most content is up to date, so is checked without decoding it all
+	if int(gjson.GetBytes(data, "schema_version").Int()) >= len(ms) {
+		return data
+	}
+
+	j, _, err := item.Migrate(data, ms)
+	if err != nil {
+		logger.Error("Error migrating content of:", ns, err)
+		return data
+	}
+
+	return j
+}
+
+// setSchemaVersion records in post, saved from the editor or API in the
+// current shape of its type, that it needs none of the migrations of the type
+func setSchemaVersion(post interface{}) {
+	m, ok := post.(item.Migratable)
+	if !ok {
+		return
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(post)).FieldByName("SchemaVersion")
+	if v.IsValid() && v.CanSet() {
+		v.SetInt(int64(len(m.Migrations())))
+	}
+}
+
+// MigrateContent applies the migrations of each of the content types, or of
+// every item.Migratable type if none are given, to the content of the type
+// saved before them, including content pending approval, and saves it at the
+// current schema version of the type. The sorted content and search index of
+// each type changed are rebuilt. With dryRun nothing is saved, and the reports
+// tell what would change.
+func MigrateContent(dryRun bool, types ...string) ([]MigrationReport, error) {
+	if len(types) == 0 {
+		for t := range item.Types {
+			if len(migrations(t)) > 0 {
+				types = append(types, t)
+			}
+		}
+
+		sort.Strings(types)
+	}
+
+	var reports []MigrationReport
+	for _, t := range types {
+		if _, ok := item.Types[t]; !ok {
+			return reports, fmt.Errorf(item.ErrTypeNotRegistered.Error(), t)
+		}
+
+		ms := migrations(t)
+		if len(ms) == 0 {
+			return reports, fmt.Errorf("Content type %s has no migrations", t)
+		}
+
+		report := MigrationReport{
+			Type:    t,
+			Version: len(ms),
+			Changed: make([]int, len(ms)),
+		}
+
+		run := store.Update
+		if dryRun {
+			run = store.View
+		}
+
+		for _, ns := range []string{t, t + "__pending"} {
+			err := run(func(tx *bolt.Tx) error {
+				b := tx.Bucket([]byte(ns))
+				if b == nil {
+					return nil
+				}
+
+				// bolt doesn't allow changing a bucket while iterating it
+				updates := make(map[string][]byte)
+				err := b.ForEach(func(k, v []byte) error {
+					j, changed, err := item.Migrate(v, ms)
+					if err != nil {
+						return fmt.Errorf("Failed to migrate %s:%s: %v", ns, k, err)
+					}
+
+					if string(j) == string(v) {
+						return nil
+					}
+
+					for _, i := range changed {
+						report.Changed[i]++
+					}
+					updates[string(k)] = j
+
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				report.Items += len(updates)
+				if dryRun {
+					return nil
+				}
+
+				for k, j := range updates {
+					err := b.Put([]byte(k), j)
+					if err != nil {
+						return err
+					}
+				}
+
+				return nil
+			})
+			if err != nil {
+				return reports, err
+			}
+		}
+
+		reports = append(reports, report)
+
+		if dryRun || report.Items == 0 {
+			continue
+		}
+
+		SortContent(t)
+
+		if search.Searchable(t) {
+			err := ReindexSearch(t)
+			if err != nil {
+				return reports, err
+			}
+		}
+	}
+
+	if !dryRun {
+		err := InvalidateCache()
+		if err != nil {
+			return reports, err
+		}
+	}
+
+	return reports, nil
+}
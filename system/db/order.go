@@ -0,0 +1,108 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/webhook"
+
+	"github.com/boltdb/bolt"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// orderable reports whether the content type ns is manually ordered
+func orderable(ns string) bool {
+	it, ok := item.Types[ns]
+	return ok && item.IsOrderable(it())
+}
+
+// keepOrder returns the json j of content of type ns with the position of
+// current, the content it replaces, since the order is only changed by Reorder
+func keepOrder(ns string, current, j []byte) ([]byte, error) {
+	if len(current) == 0 || !orderable(ns) {
+		return j, nil
+	}
+
+	v := gjson.GetBytes(current, "order")
+	if !v.Exists() {
+		return j, nil
+	}
+
+	return sjson.SetRawBytes(j, "order", []byte(v.Raw))
+}
+
+// orderOf returns the position of the content j, which is its id if it was
+// saved before its type was ordered
+func orderOf(j []byte) int {
+	if o := gjson.GetBytes(j, "order").Int(); o != 0 {
+		return int(o)
+	}
+
+	return int(gjson.GetBytes(j, "id").Int())
+}
+
+// Reorder puts the content ids of the Orderable type ns in the order given, by
+// sharing out the positions they have between them, so that the content around
+// them keeps its position and only the content which moves is saved
+func Reorder(ns string, ids []string) error {
+	if !orderable(ns) {
+		return fmt.Errorf("Content type %s is not manually ordered", ns)
+	}
+
+	changed := make(map[string][]byte)
+	err := store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(ns))
+		if b == nil {
+			return bolt.ErrBucketNotFound
+		}
+
+		positions := make([]int, len(ids))
+		for i, id := range ids {
+			j := b.Get([]byte(id))
+			if j == nil {
+				return fmt.Errorf("No %s content with id %s", ns, id)
+			}
+
+			positions[i] = orderOf(j)
+		}
+
+		current := append([]int(nil), positions...)
+		sort.Ints(positions)
+
+		for i, id := range ids {
+			if current[i] == positions[i] {
+				continue
+			}
+
+			j, err := sjson.SetBytes(b.Get([]byte(id)), "order", positions[i])
+			if err != nil {
+				return err
+			}
+
+			err = b.Put([]byte(id), j)
+			if err != nil {
+				return err
+			}
+
+			changed[id] = j
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for id, j := range changed {
+		go updateSearchIndex(ns, id, j)
+		go notifyWebhooks(webhook.EventUpdate, ns, id, j)
+	}
+
+	if len(changed) == 0 {
+		return nil
+	}
+
+	return InvalidateCache()
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// previewTTL is how long a preview token can be used after it is made
+const previewTTL = 30 * time.Minute
+
+// ErrPreviewInvalid is returned for a preview token which is expired, not
+// signed by this server, or made for other content
+var ErrPreviewInvalid = errors.New("The preview has expired or is not for this content")
+
+// preview is content previewed from the editor, before it is saved
+type preview struct {
+	target  string
+	data    []byte
+	expires time.Time
+}
+
+// previews are the previews which haven't expired, by the nonce of their token
+var previews = struct {
+	sync.Mutex
+	m map[string]preview
+}{m: make(map[string]preview)}
+
+// PreviewJSON returns the json content of type t would be saved as with the
+// form values data, without saving it
+func PreviewJSON(t string, data url.Values) ([]byte, error) {
+	return postToJSON(t, data, nil)
+}
+
+// NewPreview keeps data, the json of content ns:id as it is being edited, and
+// returns a token with which the content API serves it in place of the content
+// stored, whether that is published or not, for previewTTL. New content has
+// the id -1. The token is signed with the client secret, and is only valid for
+// the content it was made for.
+func NewPreview(ns, id string, data []byte) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := base64.RawURLEncoding.EncodeToString(b)
+	expires := time.Now().Add(previewTTL)
+	target := ns + ":" + id
+
+	previews.Lock()
+	defer previews.Unlock()
+
+	// previews are few, so expired ones are dropped as new ones are made
+	for n, p := range previews.m {
+		if time.Now().After(p.expires) {
+			delete(previews.m, n)
+		}
+	}
+
+	previews.m[nonce] = preview{target: target, data: data, expires: expires}
+
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return nonce + "." + exp + "." + previewSignature(nonce, exp, target), nil
+}
+
+// Preview returns the json of the content previewed with token, if it is a
+// valid token for the content ns:id
+func Preview(token, ns, id string) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrPreviewInvalid
+	}
+
+	nonce, exp, sig := parts[0], parts[1], parts[2]
+	target := ns + ":" + id
+	if !hmac.Equal([]byte(sig), []byte(previewSignature(nonce, exp, target))) {
+		return nil, ErrPreviewInvalid
+	}
+
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return nil, ErrPreviewInvalid
+	}
+
+	previews.Lock()
+	defer previews.Unlock()
+
+	p, ok := previews.m[nonce]
+	if !ok || p.target != target {
+		return nil, ErrPreviewInvalid
+	}
+
+	return p.data, nil
+}
+
+// previewSignature returns the signature of a preview token of content target
+func previewSignature(nonce, exp, target string) string {
+	secret, _ := ConfigCache("client_secret").(string)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.%s.%s", nonce, exp, target)
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,452 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/search"
+
+	"github.com/boltdb/bolt"
+	"github.com/tidwall/gjson"
+)
+
+// ErrQueryDestination is returned by All when it isn't given a pointer to a
+// slice to decode content into
+var ErrQueryDestination = errors.New("query results must be decoded into a pointer to a slice")
+
+// ErrInvalidCursor is returned for a query after a cursor which wasn't
+// returned by Page, or for content ordered by a field other than timestamp
+var ErrInvalidCursor = errors.New("Invalid cursor. Cursors are only valid for content ordered by timestamp.")
+
+// queryOps are the operators accepted by Where, and the search.Filter
+// operators they compare with
+var queryOps = map[string]string{
+	"=":   search.OpEqual,
+	"==":  search.OpEqual,
+	"eq":  search.OpEqual,
+	">":   search.OpGreater,
+	"gt":  search.OpGreater,
+	">=":  search.OpGreaterEqual,
+	"gte": search.OpGreaterEqual,
+	"<":   search.OpLess,
+	"lt":  search.OpLess,
+	"<=":  search.OpLessEqual,
+	"lte": search.OpLessEqual,
+}
+
+// ContentQuery finds content of one type matching conditions on its fields,
+// and decodes it into typed values, such as:
+//
+//	var posts []content.Post
+//	err := db.NewQuery("Post").Where("category", "=", "news").
+//		OrderBy("timestamp", "desc").Limit(10).Offset(20).All(&posts)
+//
+// Conditions on searchable types are looked up in their search index, while
+// other types are filtered by reading all of their content. Without
+// conditions, content ordered by timestamp is read straight from the sorted
+// content.
+//
+// Content ordered by timestamp can also be paged through with cursors, which
+// stay valid as content is added or removed, unlike offsets:
+//
+//	posts, next, err := db.NewQuery("Post").Limit(10).After(cursor).Page()
+type ContentQuery struct {
+	namespace string
+	filters   []search.Filter
+	orderBy   string
+	order     string
+	limit     int
+	offset    int
+	after     []byte
+	err       error
+}
+
+// NewQuery starts a query for content of the type namespace, ordered by
+// timestamp from newest to oldest unless OrderBy is called
+func NewQuery(namespace string) *ContentQuery {
+	return &ContentQuery{namespace: namespace, orderBy: "timestamp", order: "desc"}
+}
+
+// Where keeps only content where field compares to value by op, one of =, >,
+// >=, < or <= (or eq, gt, gte, lt or lte). Nested fields are named by their
+// dot separated path, and a list field matches if any of its values match.
+// Values are compared as numbers, dates or strings, as they are by
+// search.Filter.
+func (q *ContentQuery) Where(field, op string, value interface{}) *ContentQuery {
+	fop, ok := queryOps[op]
+	if !ok {
+		q.err = fmt.Errorf("Unsupported query operator: %s", op)
+		return q
+	}
+
+	var v string
+	switch value := value.(type) {
+	case string:
+		v = value
+	case time.Time:
+		v = value.Format(time.RFC3339)
+	default:
+		v = fmt.Sprint(value)
+	}
+
+	q.filters = append(q.filters, search.Filter{Field: field, Op: fop, Value: v})
+	return q
+}
+
+// OrderBy sorts content by field, in order "asc" or "desc"
+func (q *ContentQuery) OrderBy(field, order string) *ContentQuery {
+	q.orderBy = field
+	q.order = order
+	return q
+}
+
+// Limit keeps at most n of the content found, or all of it if n is 0
+func (q *ContentQuery) Limit(n int) *ContentQuery {
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n of the content found
+func (q *ContentQuery) Offset(n int) *ContentQuery {
+	q.offset = n
+	return q
+}
+
+// After keeps only the content after the position of cursor, returned by Page
+// for an earlier page of the same query. An empty cursor starts from the
+// first content.
+func (q *ContentQuery) After(cursor string) *ContentQuery {
+	if cursor == "" {
+		q.after = nil
+		return q
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(key) == 0 {
+		q.err = ErrInvalidCursor
+		return q
+	}
+
+	q.after = key
+	return q
+}
+
+// Raw returns the JSON of the content found
+func (q *ContentQuery) Raw() ([][]byte, error) {
+	posts, _, err := q.Page()
+	return posts, err
+}
+
+// Page returns the JSON of the content found, and the cursor to pass to After
+// for the next page, which is "" if there is no more content
+func (q *ContentQuery) Page() ([][]byte, string, error) {
+	if q.err != nil {
+		return nil, "", q.err
+	}
+
+	if q.after != nil && q.orderBy != "timestamp" {
+		return nil, "", ErrInvalidCursor
+	}
+
+	if len(q.filters) == 0 && q.orderBy == "timestamp" {
+		return q.sorted()
+	}
+
+	posts, err := q.match()
+	if err != nil {
+		return nil, "", err
+	}
+
+	q.sort(posts)
+
+	if q.after != nil {
+		posts = q.cursorPosts(posts)
+	}
+
+	posts, more := q.page(posts)
+	if !more || q.orderBy != "timestamp" {
+		return posts, "", nil
+	}
+
+	last := posts[len(posts)-1]
+	return posts, cursor(sortKeyOf(last)), nil
+}
+
+// Count returns the number of content matching the conditions of the query,
+// before Limit and Offset are applied
+func (q *ContentQuery) Count() (int, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+
+	if len(q.filters) == 0 {
+		total, ok := Total(q.namespace)
+		if ok {
+			return total, nil
+		}
+	}
+
+	posts, err := q.match()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(posts), nil
+}
+
+// All decodes the content found into dst, a pointer to a slice of content
+// type values or pointers to them, such as *[]content.Post or *[]*content.Post
+func (q *ContentQuery) All(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return ErrQueryDestination
+	}
+
+	posts, err := q.Raw()
+	if err != nil {
+		return err
+	}
+
+	slice := v.Elem()
+	elem := slice.Type().Elem()
+	ptr := elem.Kind() == reflect.Ptr
+	if ptr {
+		elem = elem.Elem()
+	}
+
+	out := reflect.MakeSlice(slice.Type(), 0, len(posts))
+	for _, post := range posts {
+		p := reflect.New(elem)
+		err := json.Unmarshal(post, p.Interface())
+		if err != nil {
+			return err
+		}
+
+		if ptr {
+			out = reflect.Append(out, p)
+		} else {
+			out = reflect.Append(out, p.Elem())
+		}
+	}
+
+	slice.Set(out)
+	return nil
+}
+
+// sorted reads a page of content straight from the sorted content, which is
+// kept newest first, seeking to the cursor of the query if it has one
+func (q *ContentQuery) sorted() ([][]byte, string, error) {
+	var posts [][]byte
+	var next string
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(q.namespace + "__sorted"))
+		if b == nil {
+			return nil
+		}
+
+		c := b.Cursor()
+		asc := q.order == "asc"
+		move := c.Next
+		if asc {
+			move = c.Prev
+		}
+
+		var k, v []byte
+		switch {
+		case q.after == nil && asc:
+			k, v = c.Last()
+		case q.after == nil:
+			k, v = c.First()
+		default:
+			// Seek finds the first key at or after the cursor, which newest
+			// first is the next page unless it is the cursor itself, and
+			// oldest first has been passed already
+			k, v = c.Seek(q.after)
+			switch {
+			case k == nil && asc:
+				k, v = c.Last()
+			case asc:
+				k, v = c.Prev()
+			case bytes.Equal(k, q.after):
+				k, v = c.Next()
+			}
+		}
+
+		var last []byte
+		for i := 0; k != nil; k, v = move() {
+			if i < q.offset {
+				i++
+				continue
+			}
+
+			if q.limit > 0 && len(posts) >= q.limit {
+				next = cursor(last)
+				break
+			}
+
+			posts = append(posts, migrated(q.namespace, append([]byte(nil), v...)))
+			last = append(last[:0], k...)
+			i++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return posts, next, nil
+}
+
+// cursorPosts returns posts, sorted by the query, after its cursor
+func (q *ContentQuery) cursorPosts(posts [][]byte) [][]byte {
+	for i, post := range posts {
+		c := bytes.Compare(sortKeyOf(post), q.after)
+		if (q.order == "asc" && c < 0) || (q.order != "asc" && c > 0) {
+			return posts[i:]
+		}
+	}
+
+	return nil
+}
+
+// cursor encodes the __sorted key of content as a cursor for After
+func cursor(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(key)
+}
+
+// sortKeyOf returns the __sorted key of the content json post
+func sortKeyOf(post []byte) []byte {
+	return sortKey(gjson.GetBytes(post, "timestamp").Int(), int(gjson.GetBytes(post, "id").Int()))
+}
+
+// match returns all content matching the conditions of the query, using the
+// search index of its type if it has one
+func (q *ContentQuery) match() ([][]byte, error) {
+	var posts [][]byte
+	if len(q.filters) == 0 || !search.Searchable(q.namespace) {
+		for _, post := range ContentAll(q.namespace) {
+			if matchFilters(post, q.filters) {
+				posts = append(posts, post)
+			}
+		}
+
+		return posts, nil
+	}
+
+	ids, err := search.FilterIDs(q.namespace, q.filters)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		post, err := Content(q.namespace + ":" + id)
+		if err != nil {
+			return nil, err
+		}
+
+		// index entries for removed content are skipped, and the index is
+		// checked against the content in case it is behind an update
+		if len(post) == 0 || !matchFilters(post, q.filters) {
+			continue
+		}
+
+		posts = append(posts, post)
+	}
+
+	return posts, nil
+}
+
+// sort orders posts by the field the query is ordered by, keeping content
+// without the field last. Content ordered by timestamp is ordered as it is in
+// its __sorted bucket, so that cursors are the same for both.
+func (q *ContentQuery) sort(posts [][]byte) {
+	if q.orderBy == "timestamp" {
+		sort.SliceStable(posts, func(i, j int) bool {
+			c := bytes.Compare(sortKeyOf(posts[i]), sortKeyOf(posts[j]))
+			if q.order == "asc" {
+				return c > 0
+			}
+
+			return c < 0
+		})
+
+		return
+	}
+
+	// content of a manually ordered type saved before it was ordered is in
+	// the position of its id
+	if q.orderBy == "order" && orderable(q.namespace) {
+		sort.SliceStable(posts, func(i, j int) bool {
+			if q.order == "asc" {
+				return orderOf(posts[i]) < orderOf(posts[j])
+			}
+
+			return orderOf(posts[i]) > orderOf(posts[j])
+		})
+
+		return
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		a := gjson.GetBytes(posts[i], q.orderBy)
+		b := gjson.GetBytes(posts[j], q.orderBy)
+		if !a.Exists() || !b.Exists() {
+			return a.Exists()
+		}
+
+		if q.order == "asc" {
+			return search.Filter{Op: search.OpLess, Value: b.String()}.Match(a.String())
+		}
+
+		return search.Filter{Op: search.OpGreater, Value: b.String()}.Match(a.String())
+	})
+}
+
+// page returns the posts within the offset and limit of the query, and
+// whether there are more posts after them
+func (q *ContentQuery) page(posts [][]byte) ([][]byte, bool) {
+	if q.offset >= len(posts) {
+		return nil, false
+	}
+	posts = posts[q.offset:]
+
+	if q.limit > 0 && q.limit < len(posts) {
+		return posts[:q.limit], true
+	}
+
+	return posts, false
+}
+
+// matchFilters reports whether the content json data matches every filter
+func matchFilters(data []byte, filters []search.Filter) bool {
+	for _, f := range filters {
+		v := gjson.GetBytes(data, f.Field)
+		if !v.Exists() {
+			return false
+		}
+
+		// Array returns a scalar value alone, or each value of a list
+		values := v.Array()
+
+		match := false
+		for i := range values {
+			if f.Match(values[i].String()) {
+				match = true
+				break
+			}
+		}
+
+		if !match {
+			return false
+		}
+	}
+
+	return true
+}
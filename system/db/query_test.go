@@ -0,0 +1,148 @@
+package db
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/tidwall/gjson"
+)
+
+// tempStore opens a new bolt db in a temporary directory as the store, with
+// the buckets made by Init, returning a func to close and remove it
+func tempStore(tb testing.TB) func() {
+	dir, err := ioutil.TempDir("", "ponzu-db")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	store, err = bolt.Open(filepath.Join(dir, "system.db"), 0666, nil)
+	if err != nil {
+		os.RemoveAll(dir)
+		tb.Fatal(err)
+	}
+
+	err = store.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{"__config", "__users", "__contentIndex", "__addons"} {
+			_, err := tx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return func() {
+		store.Close()
+		store = nil
+		os.RemoveAll(dir)
+	}
+}
+
+// putTestContent stores an item of the type ns, as it is kept both by its id
+// and in the sorted content
+func putTestContent(tb testing.TB, ns string, id int, ts int64) {
+	j := []byte(fmt.Sprintf(`{"id":%d,"timestamp":%d,"title":"Item %d"}`, id, ts, id))
+	err := store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(ns))
+		if err != nil {
+			return err
+		}
+
+		err = b.Put([]byte(strconv.Itoa(id)), j)
+		if err != nil {
+			return err
+		}
+
+		sorted, err := tx.CreateBucketIfNotExists([]byte(ns + "__sorted"))
+		if err != nil {
+			return err
+		}
+
+		return sorted.Put(sortKey(ts, id), j)
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// ids returns the id of each of posts
+func ids(posts [][]byte) []int64 {
+	var ids []int64
+	for _, post := range posts {
+		ids = append(ids, gjson.GetBytes(post, "id").Int())
+	}
+
+	return ids
+}
+
+func TestContentQueryPageCursor(t *testing.T) {
+	defer tempStore(t)()
+
+	for id := 1; id <= 5; id++ {
+		putTestContent(t, "T", id, int64(id*1000))
+	}
+
+	cases := []struct {
+		name  string
+		query func() *ContentQuery
+		order string
+		pages [][]int64
+	}{
+		{"sorted newest first", func() *ContentQuery { return NewQuery("T") }, "desc", [][]int64{{5, 4}, {3, 2}, {1}}},
+		{"sorted oldest first", func() *ContentQuery { return NewQuery("T") }, "asc", [][]int64{{1, 2}, {3, 4}, {5}}},
+		{"filtered newest first", func() *ContentQuery { return NewQuery("T").Where("id", ">", 0) }, "desc", [][]int64{{5, 4}, {3, 2}, {1}}},
+		{"filtered oldest first", func() *ContentQuery { return NewQuery("T").Where("id", ">", 0) }, "asc", [][]int64{{1, 2}, {3, 4}, {5}}},
+	}
+
+	for _, c := range cases {
+		var after string
+		for i, want := range c.pages {
+			posts, next, err := c.query().OrderBy("timestamp", c.order).Limit(2).After(after).Page()
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+
+			if got := ids(posts); fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%s: page %d: expected %v, got %v", c.name, i, want, got)
+			}
+
+			if last := i == len(c.pages)-1; last != (next == "") {
+				t.Errorf("%s: page %d: unexpected next cursor %q", c.name, i, next)
+			}
+
+			after = next
+		}
+	}
+
+	// a cursor keeps its place as content is added before it, unlike an
+	// offset, which would return item 4 again
+	_, next, err := NewQuery("T").Limit(2).Page()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	putTestContent(t, "T", 6, 6000)
+
+	posts, _, err := NewQuery("T").Limit(2).After(next).Page()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ids(posts); fmt.Sprint(got) != "[3 2]" {
+		t.Errorf("expected the page after the cursor to be [3 2] once content was added, got %v", got)
+	}
+
+	_, _, err = NewQuery("T").OrderBy("title", "asc").After(next).Page()
+	if err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor for a cursor on content not ordered by timestamp, got %v", err)
+	}
+}
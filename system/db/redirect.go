@@ -0,0 +1,263 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrInvalidRedirect is returned for a redirect which can't be saved, because
+// its paths or status are not valid
+var ErrInvalidRedirect = errors.New("Redirects must be from a path starting with /, to a different path or an http(s) URL, with a status of 301 or 302. Only a trailing * is allowed as a wildcard.")
+
+// Redirect sends requests for the path From to To, with the HTTP status
+// Status, which is 301 (permanent) or 302 (temporary). A From ending with "*"
+// matches every path starting with what comes before it, and a To ending with
+// "*" has the rest of the matched path added in its place, so /old/* to /new/*
+// redirects /old/a/b to /new/a/b. Created is in unix milliseconds.
+type Redirect struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Status  int    `json:"status"`
+	Created int64  `json:"created"`
+}
+
+// IsPrefix reports whether the redirect matches every path starting with
+// its From
+func (r Redirect) IsPrefix() bool {
+	return strings.HasSuffix(r.From, "*")
+}
+
+// Target returns where the redirect sends a request for path, which it must
+// match
+func (r Redirect) Target(path string) string {
+	if !r.IsPrefix() || !strings.HasSuffix(r.To, "*") {
+		return r.To
+	}
+
+	rest := strings.TrimPrefix(path, strings.TrimSuffix(r.From, "*"))
+	return strings.TrimSuffix(r.To, "*") + rest
+}
+
+// valid reports whether the redirect can be saved
+func (r Redirect) valid() bool {
+	if !strings.HasPrefix(r.From, "/") || r.From == r.To {
+		return false
+	}
+	if strings.Contains(strings.TrimSuffix(r.From, "*"), "*") {
+		return false
+	}
+	if strings.Contains(strings.TrimSuffix(r.To, "*"), "*") {
+		return false
+	}
+
+	if !strings.HasPrefix(r.To, "/") && !strings.HasPrefix(r.To, "http://") &&
+		!strings.HasPrefix(r.To, "https://") {
+		return false
+	}
+
+	return r.Status == http.StatusMovedPermanently || r.Status == http.StatusFound
+}
+
+// redirects caches the redirects so that requests are matched without reading
+// the db. It is reloaded when stale, after redirects are changed.
+var redirects = struct {
+	sync.RWMutex
+	stale  bool
+	exact  map[string]Redirect
+	prefix []Redirect
+}{stale: true}
+
+// putRedirect stores r in the transaction tx, replacing any redirect from the
+// same path
+func putRedirect(tx *bolt.Tx, r Redirect) error {
+	if !r.valid() {
+		return ErrInvalidRedirect
+	}
+
+	if r.Created == 0 {
+		r.Created = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+
+	b, err := tx.CreateBucketIfNotExists([]byte("__redirects"))
+	if err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	redirectsChanged()
+	return b.Put([]byte(r.From), j)
+}
+
+// deleteRedirect removes the redirect from the path from in the transaction tx
+func deleteRedirect(tx *bolt.Tx, from string) error {
+	b := tx.Bucket([]byte("__redirects"))
+	if b == nil {
+		return nil
+	}
+
+	redirectsChanged()
+	return b.Delete([]byte(from))
+}
+
+// redirectsChanged marks the cache of redirects to be reloaded. It is called
+// both as redirects are changed within a transaction and once it is committed,
+// so that the cache isn't left holding what was read before the commit.
+func redirectsChanged() {
+	redirects.Lock()
+	redirects.stale = true
+	redirects.Unlock()
+}
+
+// SetRedirect stores r, replacing any redirect from the same path
+func SetRedirect(r Redirect) error {
+	err := store.Update(func(tx *bolt.Tx) error {
+		return putRedirect(tx, r)
+	})
+	if err != nil {
+		return err
+	}
+
+	redirectsChanged()
+	return nil
+}
+
+// ImportRedirects stores each of rs, replacing redirects from the same paths.
+// If any of them is not valid none are stored.
+func ImportRedirects(rs []Redirect) error {
+	err := store.Update(func(tx *bolt.Tx) error {
+		for _, r := range rs {
+			err := putRedirect(tx, r)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	redirectsChanged()
+	return nil
+}
+
+// DeleteRedirect removes the redirect from the path from
+func DeleteRedirect(from string) error {
+	err := store.Update(func(tx *bolt.Tx) error {
+		return deleteRedirect(tx, from)
+	})
+	if err != nil {
+		return err
+	}
+
+	redirectsChanged()
+	return nil
+}
+
+// Redirects returns every redirect, ordered by the path they are from
+func Redirects() ([]Redirect, error) {
+	var rs []Redirect
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__redirects"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var r Redirect
+			err := json.Unmarshal(v, &r)
+			if err != nil {
+				logger.Error("Error decoding redirect:", err)
+				return nil
+			}
+
+			rs = append(rs, r)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+// MatchRedirect returns the redirect for a request for path, if there is one.
+// A redirect from exactly the path is used over one from a prefix of it, and
+// the redirect from the longest prefix is used over those from shorter ones.
+func MatchRedirect(path string) (Redirect, bool) {
+	redirects.RLock()
+	stale := redirects.stale
+	redirects.RUnlock()
+
+	if stale {
+		err := loadRedirects()
+		if err != nil {
+			logger.Error("Error loading redirects:", err)
+			return Redirect{}, false
+		}
+	}
+
+	redirects.RLock()
+	defer redirects.RUnlock()
+
+	if r, ok := redirects.exact[path]; ok {
+		return r, true
+	}
+
+	for _, r := range redirects.prefix {
+		if strings.HasPrefix(path, strings.TrimSuffix(r.From, "*")) {
+			return r, true
+		}
+	}
+
+	return Redirect{}, false
+}
+
+// loadRedirects fills the cache of redirects from the db
+func loadRedirects() error {
+	redirects.Lock()
+	redirects.stale = false
+	redirects.Unlock()
+
+	rs, err := Redirects()
+	if err != nil {
+		return err
+	}
+
+	exact := make(map[string]Redirect)
+	var prefix []Redirect
+	for _, r := range rs {
+		if r.IsPrefix() {
+			prefix = append(prefix, r)
+			continue
+		}
+
+		exact[r.From] = r
+	}
+
+	// longest prefixes first, so the most specific is matched
+	sort.Slice(prefix, func(i, j int) bool {
+		return len(prefix[i].From) > len(prefix[j].From)
+	})
+
+	redirects.Lock()
+	redirects.exact, redirects.prefix = exact, prefix
+	redirects.Unlock()
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// DanglingReference is a reference held by content to an item which no longer
+// exists, such as one which has been deleted
+type DanglingReference struct {
+	Type      string // type of the content holding the reference
+	ID        string
+	Field     string
+	Reference string // path of the missing item
+}
+
+// DanglingReferences returns the references held by the Referenceable fields
+// of all content to items which do not exist
+func DanglingReferences() []DanglingReference {
+	var types []string
+	for t := range item.Types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	var dangling []DanglingReference
+	for _, t := range types {
+		r, ok := item.Types[t]().(item.Referenceable)
+		if !ok {
+			continue
+		}
+
+		refs := r.References()
+		for _, post := range ContentAll(t) {
+			var fields map[string]interface{}
+			err := json.Unmarshal(post, &fields)
+			if err != nil {
+				logger.Error("Error decoding content to check references:", t, err)
+				continue
+			}
+
+			id, _ := json.Marshal(fields["id"])
+			for f := range refs {
+				for _, path := range referencePaths(fields[f]) {
+					if referenceExists(path) {
+						continue
+					}
+
+					dangling = append(dangling, DanglingReference{
+						Type:      t,
+						ID:        string(id),
+						Field:     f,
+						Reference: path,
+					})
+				}
+			}
+		}
+	}
+
+	return dangling
+}
+
+// referencePaths returns the reference paths held by the decoded json value of
+// a Referenceable field, a single path or a list
+func referencePaths(v interface{}) []string {
+	switch x := v.(type) {
+	case string:
+		if x != "" {
+			return []string{x}
+		}
+	case []interface{}:
+		var paths []string
+		for i := range x {
+			if s, ok := x[i].(string); ok && s != "" {
+				paths = append(paths, s)
+			}
+		}
+		return paths
+	}
+
+	return nil
+}
+
+// referenceExists reports whether the content referred to by path exists
+func referenceExists(path string) bool {
+	t, id, ok := item.ParseReference(path)
+	if !ok {
+		return false
+	}
+
+	post, err := Content(t + ":" + id)
+	return err == nil && len(post) > 0
+}
@@ -0,0 +1,261 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/webhook"
+
+	"github.com/boltdb/bolt"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// defaultMaxRevisions is the number of revisions kept for each content item if
+// max_revisions is not configured
+const defaultMaxRevisions = 10
+
+// Revision is a previous state of a content item, saved before it was updated
+type Revision struct {
+	Timestamp int64           `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Time returns the time at which the revision was saved
+func (r Revision) Time() time.Time {
+	return time.Unix(0, r.Timestamp)
+}
+
+// maxRevisions returns the configured number of revisions to keep per item
+func maxRevisions() int {
+	n, ok := ConfigCache("max_revisions").(float64)
+	if !ok || n < 1 {
+		return defaultMaxRevisions
+	}
+
+	return int(n)
+}
+
+// revisionPrefix returns the __revisions key prefix for all revisions of an item
+func revisionPrefix(ns, id string) []byte {
+	return []byte(fmt.Sprintf("%s:%s:", ns, id))
+}
+
+// revisionKey returns the __revisions key for a revision of an item made at ts,
+// zero padded so that keys for an item sort by time
+func revisionKey(ns, id string, ts int64) []byte {
+	return []byte(fmt.Sprintf("%s:%s:%020d", ns, id, ts))
+}
+
+// saveRevision stores prev as the latest revision of the item ns:id within tx,
+// removing the oldest revisions beyond the configured limit
+func saveRevision(tx *bolt.Tx, ns, id string, prev []byte) error {
+	if len(prev) == 0 {
+		return nil
+	}
+
+	b, err := tx.CreateBucketIfNotExists([]byte("__revisions"))
+	if err != nil {
+		return err
+	}
+
+	err = b.Put(revisionKey(ns, id, time.Now().UnixNano()), prev)
+	if err != nil {
+		return err
+	}
+
+	prefix := revisionPrefix(ns, id)
+	var keys [][]byte
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, k)
+	}
+
+	// keys are in ascending time order, so the oldest are removed first
+	max := maxRevisions()
+	for i := 0; i < len(keys)-max; i++ {
+		err := b.Delete(keys[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteRevisions removes all saved revisions of the item ns:id within tx
+func deleteRevisions(tx *bolt.Tx, ns, id string) error {
+	b := tx.Bucket([]byte("__revisions"))
+	if b == nil {
+		return nil
+	}
+
+	prefix := revisionPrefix(ns, id)
+	var keys [][]byte
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, k)
+	}
+
+	for i := range keys {
+		err := b.Delete(keys[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Revisions returns the saved revisions of a content item, most recent first
+func Revisions(contentType, id string) ([]Revision, error) {
+	var revisions = []Revision{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__revisions"))
+		if b == nil {
+			return nil
+		}
+
+		prefix := revisionPrefix(contentType, id)
+		c := b.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			ts, err := strconv.ParseInt(string(k[len(prefix):]), 10, 64)
+			if err != nil {
+				return err
+			}
+
+			// v is only valid during the transaction, so copy it
+			data := make([]byte, len(v))
+			copy(data, v)
+
+			revisions = append([]Revision{{Timestamp: ts, Data: data}}, revisions...)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// RestoreRevision replaces a content item with its revision saved at
+// revisionTS. The item's current state is saved as a new revision first, so a
+// restore can itself be undone. The item keeps its current workflow state, so
+// restoring an older revision never publishes or unpublishes it, and its
+// current counters and order.
+func RestoreRevision(contentType, id string, revisionTS int64) error {
+	ns := contentType
+	if strings.Contains(ns, "__") {
+		ns = strings.Split(ns, "__")[0]
+	}
+
+	var rev []byte
+	err := store.Update(func(tx *bolt.Tx) error {
+		revs := tx.Bucket([]byte("__revisions"))
+		if revs == nil {
+			return bolt.ErrBucketNotFound
+		}
+
+		data := revs.Get(revisionKey(contentType, id, revisionTS))
+		if data == nil {
+			return fmt.Errorf("No revision of %s:%s found at %d", contentType, id, revisionTS)
+		}
+
+		// copy the revision before saveRevision modifies the bucket
+		rev = make([]byte, len(data))
+		copy(rev, data)
+
+		b := tx.Bucket([]byte(contentType))
+		if b == nil {
+			return bolt.ErrBucketNotFound
+		}
+
+		var current []byte
+		if v := b.Get([]byte(id)); v != nil {
+			current = make([]byte, len(v))
+			copy(current, v)
+		}
+
+		err := saveRevision(tx, contentType, id, current)
+		if err != nil {
+			return err
+		}
+
+		// point the content index at the restored slug if it changed, keeping
+		// it unique
+		if contentType == ns && slugOf(rev) != "" && slugOf(rev) != slugOf(current) {
+			rev, err = restoreSlug(tx, ns, id, slugOf(current), rev)
+			if err != nil {
+				return err
+			}
+		}
+
+		rev, err = keepState(current, rev)
+		if err != nil {
+			return err
+		}
+
+		// counters and the manual order aren't part of a revision, so the
+		// current values are kept
+		rev, err = keepCounters(ns, current, rev)
+		if err != nil {
+			return err
+		}
+
+		rev, err = keepOrder(ns, current, rev)
+		if err != nil {
+			return err
+		}
+
+		// the restore is a new version, so edits of the one it replaces
+		// conflict with it
+		rev, err = sjson.SetBytes(rev, "version", versionOf(current)+1)
+		if err != nil {
+			return err
+		}
+
+		err = b.Put([]byte(id), rev)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if contentType == ns {
+		go SortContent(ns)
+		go updateSearchIndex(ns, id, rev)
+		go notifyWebhooks(webhook.EventUpdate, ns, id, rev)
+	}
+
+	// restore changes data, so invalidate client caching
+	return InvalidateCache()
+}
+
+// keepState returns rev with the workflow state and its history taken from
+// current, so that content is only moved between states by the workflow
+func keepState(current, rev []byte) ([]byte, error) {
+	var err error
+	for _, key := range []string{"state", "state_history"} {
+		v := gjson.GetBytes(current, key)
+		if v.Exists() {
+			rev, err = sjson.SetRawBytes(rev, key, []byte(v.Raw))
+		} else {
+			rev, err = sjson.DeleteBytes(rev, key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rev, nil
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/ponzu-cms/ponzu/system/admin/user"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrNoRoleExists is returned for a role name which has not been saved
+var ErrNoRoleExists = errors.New("No role exists.")
+
+// SetRole stores r in the __roles bucket by its name, replacing any role
+// already saved with the name. The super admin role can't be changed.
+func SetRole(r user.Role) error {
+	if r.Name == "" || r.IsSuper() {
+		return errors.New("Role must have a name other than " + user.SuperAdminRole)
+	}
+
+	return store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("__roles"))
+		if err != nil {
+			return err
+		}
+
+		j, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(r.Name), j)
+	})
+}
+
+// DeleteRole removes the role named name from the __roles bucket. Users
+// assigned the role are left without permissions until given another.
+func DeleteRole(name string) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__roles"))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(name))
+	})
+}
+
+// Role returns the role named name. The super admin role is always found.
+func Role(name string) (user.Role, error) {
+	if name == "" || name == user.SuperAdminRole {
+		return user.SuperAdmin(), nil
+	}
+
+	var r user.Role
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__roles"))
+		if b == nil {
+			return ErrNoRoleExists
+		}
+
+		j := b.Get([]byte(name))
+		if j == nil {
+			return ErrNoRoleExists
+		}
+
+		return json.Unmarshal(j, &r)
+	})
+	if err != nil {
+		return user.Role{}, err
+	}
+
+	return r, nil
+}
+
+// Roles returns the saved roles, sorted by name, not including the super
+// admin role
+func Roles() ([]user.Role, error) {
+	var roles = []user.Role{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__roles"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var r user.Role
+			err := json.Unmarshal(v, &r)
+			if err != nil {
+				logger.Error("Error decoding role:", string(k), err)
+				return nil
+			}
+
+			roles = append(roles, r)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+
+	return roles, nil
+}
+
+// CurrentRole returns the role of the user making req. A user assigned a role
+// which no longer exists is given a role with no permissions.
+func CurrentRole(req *http.Request) (user.Role, error) {
+	j, err := CurrentUser(req)
+	if err != nil {
+		return user.Role{}, err
+	}
+
+	var usr user.User
+	err = json.Unmarshal(j, &usr)
+	if err != nil {
+		return user.Role{}, err
+	}
+
+	r, err := Role(usr.Role)
+	if err == ErrNoRoleExists {
+		return user.Role{Name: usr.Role}, nil
+	}
+
+	return r, err
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultPublishInterval is how often scheduled content is checked for if
+// publish_interval is not configured
+const defaultPublishInterval = time.Minute
+
+// publishInterval returns the configured interval between checks for
+// scheduled content which has become public
+func publishInterval() time.Duration {
+	n, ok := ConfigCache("publish_interval").(float64)
+	if !ok || n < 1 {
+		return defaultPublishInterval
+	}
+
+	return time.Duration(n) * time.Second
+}
+
+// nextPublish holds the earliest publish time, in milliseconds, of the
+// scheduled content of each type, found when the type was last sorted. Types
+// without scheduled content are left out.
+var nextPublish = struct {
+	sync.Mutex
+	at map[string]int64
+}{at: make(map[string]int64)}
+
+// setNextPublish records at as the earliest publish time of the scheduled
+// content of type t, where 0 means it has none
+func setNextPublish(t string, at int64) {
+	nextPublish.Lock()
+	defer nextPublish.Unlock()
+
+	if at == 0 {
+		delete(nextPublish.at, t)
+		return
+	}
+
+	nextPublish.at[t] = at
+}
+
+// duePublish returns the types with scheduled content whose publish time has
+// passed by now. They are left out of nextPublish until they are sorted again.
+func duePublish(now time.Time) []string {
+	ms := now.UnixNano() / int64(time.Millisecond)
+
+	nextPublish.Lock()
+	defer nextPublish.Unlock()
+
+	var due []string
+	for t, at := range nextPublish.at {
+		if at <= ms {
+			due = append(due, t)
+			delete(nextPublish.at, t)
+		}
+	}
+
+	return due
+}
+
+// publishScheduled returns the job which, on each run, makes content whose
+// publish time has passed since the last run public. Scheduled content is
+// already stored with the rest of its type and is only left out of API
+// responses until its publish time, so publishing it means invalidating the
+// cache so that clients holding an Etag will fetch the new content. The next
+// publish time of each type is found when it is sorted, so a run only reads
+// the content of types with content due.
+func publishScheduled() func() error {
+	return func() error {
+		due := duePublish(time.Now())
+		for _, t := range due {
+			// recount the public content of the type, finding its next
+			// publish time
+			SortContent(t)
+		}
+
+		if len(due) == 0 {
+			return nil
+		}
+
+		err := InvalidateCache()
+		if err != nil {
+			return fmt.Errorf("invalidating cache for published content: %v", err)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+
+	"github.com/boltdb/bolt"
+)
+
+func TestDuePublish(t *testing.T) {
+	defer tempStore(t)()
+
+	item.Types["Scheduled"] = func() interface{} { return new(item.Item) }
+	defer delete(item.Types, "Scheduled")
+
+	// one public item, and two published in an hour and in two hours
+	now := time.Now()
+	ms := func(d time.Duration) int64 { return now.Add(d).UnixNano() / int64(time.Millisecond) }
+	publishAt := []int64{0, ms(time.Hour), ms(time.Hour * 2)}
+	err := store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("Scheduled"))
+		if err != nil {
+			return err
+		}
+
+		for i, at := range publishAt {
+			j := fmt.Sprintf(`{"id":%d,"timestamp":%d,"publish_at":%d}`, i+1, (i+1)*1000, at)
+			err := b.Put([]byte(strconv.Itoa(i+1)), []byte(j))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SortContent("Scheduled")
+
+	if total, _ := Total("Scheduled"); total != 1 {
+		t.Errorf("expected 1 public item, got %d", total)
+	}
+
+	if due := duePublish(now); len(due) != 0 {
+		t.Errorf("expected nothing due before the first publish time, got %v", due)
+	}
+
+	if due := duePublish(now.Add(time.Minute * 90)); fmt.Sprint(due) != "[Scheduled]" {
+		t.Errorf("expected the type to be due once its first publish time passed, got %v", due)
+	}
+
+	// a type isn't due again until it has been sorted
+	if due := duePublish(now.Add(time.Minute * 90)); len(due) != 0 {
+		t.Errorf("expected nothing due until the type is sorted again, got %v", due)
+	}
+}
@@ -0,0 +1,309 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/search"
+
+	"github.com/boltdb/bolt"
+	"github.com/tidwall/gjson"
+)
+
+// updateSearchIndex updates the search index for the public content ns:id,
+// logging any error since it is called in its own goroutine
+func updateSearchIndex(ns, id string, data []byte) {
+	docs, localized, err := searchDocs(ns, id, data)
+	if err != nil {
+		logger.Error("Error updating search index for:", ns+":"+id, err)
+		return
+	}
+
+	// translations into locales no longer configured are removed
+	if localized {
+		deleteSearchIndex(ns, id)
+	}
+
+	for docID, doc := range docs {
+		err := search.UpdateIndex(ns, docID, doc)
+		if err != nil {
+			logger.Error("Error updating search index for:", ns+":"+docID, err)
+		}
+	}
+}
+
+// deleteSearchIndex removes the public content ns:id from the search index
+func deleteSearchIndex(ns, id string) {
+	err := search.DeleteIndex(ns, id)
+	if err != nil {
+		logger.Error("Error removing from search index:", ns+":"+id, err)
+	}
+}
+
+// ReindexSearch rebuilds the search index of each of the content types, or of
+// every searchable type if none are given, from the content stored for them.
+// Searches use the current index of a type until its rebuild is complete, so
+// it is safe to reindex while serving.
+func ReindexSearch(types ...string) error {
+	if len(types) == 0 {
+		for t := range item.Types {
+			if search.Searchable(t) {
+				types = append(types, t)
+			}
+		}
+	}
+
+	for _, t := range types {
+		err := search.Rebuild(t, func(add func(id string, data []byte) error) error {
+			for _, j := range ContentAll(t) {
+				docs, _, err := searchDocs(t, gjson.GetBytes(j, "id").String(), j)
+				if err != nil {
+					return err
+				}
+
+				for id, doc := range docs {
+					err := add(id, doc)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to rebuild search index of %s: %v", t, err)
+		}
+	}
+
+	return nil
+}
+
+// searchDisabledTypes returns the searchable content types configured to be
+// left out of the search index
+func searchDisabledTypes() []string {
+	var types []string
+	vals, _ := ConfigCache("search_disabled_types").([]interface{})
+	for _, v := range vals {
+		if t, ok := v.(string); ok {
+			types = append(types, t)
+		}
+	}
+
+	return types
+}
+
+// applySearchDisabled leaves the content types configured to be out of the
+// search index out of it, given those which were before the config changed.
+// The index of a type newly left out is removed, so its content can't be
+// found, and a type searchable again has its content indexed in the
+// background.
+func applySearchDisabled(previous []string) {
+	current := searchDisabledTypes()
+	search.SetDisabled(current)
+
+	was := make(map[string]bool)
+	for _, t := range previous {
+		was[t] = true
+	}
+
+	now := make(map[string]bool)
+	for _, t := range current {
+		now[t] = true
+
+		if !was[t] {
+			err := search.Drop(t)
+			if err != nil {
+				logger.Error("Error removing search index of:", t, err)
+			}
+		}
+	}
+
+	for t := range was {
+		if now[t] || !search.Searchable(t) {
+			continue
+		}
+
+		go func(t string) {
+			err := ReindexSearch(t)
+			if err != nil {
+				logger.Error(err)
+				return
+			}
+
+			logger.Info("Indexed content of", t, "for search")
+		}(t)
+	}
+}
+
+// applySearchSynonyms sets the synonyms search queries are expanded with from
+// the config, logging a warning for rules which can't be read
+func applySearchSynonyms() {
+	rules, _ := ConfigCache("search_synonyms").(string)
+	err := search.SetSynonyms(rules)
+	if err != nil {
+		logger.Warn(err)
+	}
+}
+
+// searchAnalyzers returns the rules of the search analyzers of content types,
+// from the config
+func searchAnalyzers() []string {
+	var rules []string
+	vals, _ := ConfigCache("search_analyzers").([]interface{})
+	for _, v := range vals {
+		if r, ok := v.(string); ok {
+			rules = append(rules, r)
+		}
+	}
+
+	return rules
+}
+
+// searchStopWords returns the words left out of search, from the config
+func searchStopWords() []string {
+	var words []string
+	vals, _ := ConfigCache("search_stop_words").([]interface{})
+	for _, v := range vals {
+		if w, ok := v.(string); ok {
+			words = append(words, w)
+		}
+	}
+
+	return words
+}
+
+// applySearchAnalyzers sets the search analyzers and stop words from the
+// config, given the analyzer rules and stop words before it changed. Content
+// is analyzed as it is indexed, so the content types whose rules changed are
+// reindexed in the background, or every type if a rule for every type or the
+// stop words changed.
+func applySearchAnalyzers(previous, previousStopWords []string) {
+	current := searchAnalyzers()
+	err := search.SetAnalyzers(current)
+	if err != nil {
+		logger.Warn(err)
+	}
+
+	stopWords := searchStopWords()
+	search.SetStopWords(stopWords)
+
+	was := make(map[string]bool)
+	for _, r := range previous {
+		was[strings.TrimSpace(r)] = true
+	}
+
+	now := make(map[string]bool)
+	for _, r := range current {
+		now[strings.TrimSpace(r)] = true
+	}
+
+	changed := make(map[string]bool)
+	for r := range was {
+		if !now[r] {
+			changed[analyzedType(r)] = true
+		}
+	}
+	for r := range now {
+		if !was[r] {
+			changed[analyzedType(r)] = true
+		}
+	}
+
+	var types []string
+	if changed["*"] || strings.Join(stopWords, "\n") != strings.Join(previousStopWords, "\n") {
+		for t := range item.Types {
+			if search.Searchable(t) {
+				types = append(types, t)
+			}
+		}
+	} else {
+		for t := range changed {
+			if search.Searchable(t) {
+				types = append(types, t)
+			}
+		}
+	}
+
+	if len(types) == 0 {
+		return
+	}
+
+	go func() {
+		err := ReindexSearch(types...)
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+
+		logger.Info("Indexed content of", strings.Join(types, ", "), "for search with its new analyzers")
+	}()
+}
+
+// analyzedType returns the content type a search analyzer rule is for
+func analyzedType(rule string) string {
+	if i := strings.IndexAny(rule, ".@="); i >= 0 {
+		rule = rule[:i]
+	}
+
+	return strings.TrimSpace(rule)
+}
+
+// CheckSearchIndex compares the number of items in the search index of each
+// searchable type with the number stored, if search_index_check is set. Types
+// which differ are logged with "warn", and also have their index rebuilt with
+// "reindex".
+func CheckSearchIndex() {
+	check, _ := ConfigCache("search_index_check").(string)
+	if check == "" {
+		return
+	}
+
+	for t := range item.Types {
+		if !search.Searchable(t) {
+			continue
+		}
+
+		indexed, err := search.Indexed(t)
+		if err != nil {
+			logger.Error("Error checking search index of:", t, err)
+			continue
+		}
+
+		stored := count(t)
+		if indexed == stored {
+			continue
+		}
+
+		logger.Warn(fmt.Sprintf("Search index of %s has %d items, but %d are stored", t, indexed, stored))
+		if check != "reindex" {
+			continue
+		}
+
+		err = ReindexSearch(t)
+		if err != nil {
+			logger.Error(err)
+			continue
+		}
+
+		logger.Info("Rebuilt search index of:", t)
+	}
+}
+
+// count returns the number of items stored in the namespace
+func count(namespace string) int {
+	var n int
+	store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(namespace))
+		if b == nil {
+			return nil
+		}
+
+		n = b.Stats().KeyN
+		return nil
+	})
+
+	return n
+}
@@ -0,0 +1,288 @@
+package db
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/admin/user"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/boltdb/bolt"
+	"github.com/nilslice/jwt"
+)
+
+// ErrNoSession is returned for a session which doesn't exist, or has been
+// revoked
+var ErrNoSession = errors.New("Error. No session exists.")
+
+// ErrSessionExpired is returned for the session of a request which has been
+// revoked, has expired, or has gone unused for too long
+var ErrSessionExpired = errors.New("Session expired")
+
+// defaultIdleMinutes is how long a session can go unused before it ends, if
+// session_idle_minutes is not configured
+const defaultIdleMinutes = 60
+
+// Session is the record of an admin user's login, referred to by the token in
+// their cookie so that it can be ended before the token expires. Times are in
+// unix milliseconds.
+type Session struct {
+	ID        string `json:"id"`
+	User      string `json:"user"`
+	Created   int64  `json:"created"`
+	LastSeen  int64  `json:"last_seen"`
+	Expires   int64  `json:"expires"`
+	Remember  bool   `json:"remember"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+}
+
+// NewSession stores s with a new random ID, which it returns in the stored
+// Session
+func NewSession(s Session) (Session, error) {
+	buf := make([]byte, 16)
+	_, err := crand.Read(buf)
+	if err != nil {
+		return Session{}, err
+	}
+
+	s.ID = hex.EncodeToString(buf)
+	if s.Created == 0 {
+		s.Created = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	if s.LastSeen == 0 {
+		s.LastSeen = s.Created
+	}
+
+	err = putSession(s)
+	if err != nil {
+		return Session{}, err
+	}
+
+	return s, nil
+}
+
+func putSession(s Session) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("__sessions"))
+		if err != nil {
+			return err
+		}
+
+		j, err := json.Marshal(s)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(s.ID), j)
+	})
+}
+
+// GetSession returns the session with id
+func GetSession(id string) (Session, error) {
+	var s Session
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__sessions"))
+		if b == nil {
+			return ErrNoSession
+		}
+
+		j := b.Get([]byte(id))
+		if j == nil {
+			return ErrNoSession
+		}
+
+		return json.Unmarshal(j, &s)
+	})
+	if err != nil {
+		return Session{}, err
+	}
+
+	return s, nil
+}
+
+// TouchSession sets the time the session with id was last used to t, in unix
+// milliseconds
+func TouchSession(id string, t int64) error {
+	s, err := GetSession(id)
+	if err != nil {
+		return err
+	}
+
+	s.LastSeen = t
+	return putSession(s)
+}
+
+// RevokeSession ends the session with id
+func RevokeSession(id string) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__sessions"))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(id))
+	})
+}
+
+// RevokeSessions ends every session of the user with email, except the session
+// with the ID except if it is not empty
+func RevokeSessions(email, except string) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__sessions"))
+		if b == nil {
+			return nil
+		}
+
+		var revoked [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var s Session
+			err := json.Unmarshal(v, &s)
+			if err == nil && s.User == email && s.ID != except {
+				revoked = append(revoked, k)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range revoked {
+			err := b.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Sessions returns the sessions of the user with email which have not expired,
+// most recently used first
+func Sessions(email string) ([]Session, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	var sessions []Session
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__sessions"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var s Session
+			err := json.Unmarshal(v, &s)
+			if err != nil {
+				logger.Error("Error decoding session:", err)
+				return nil
+			}
+
+			if s.User == email && s.Expires > now {
+				sessions = append(sessions, s)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen > sessions[j].LastSeen
+	})
+
+	return sessions, nil
+}
+
+// pruneSessions deletes sessions which have expired, and is run hourly
+func pruneSessions() error {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	return store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__sessions"))
+		if b == nil {
+			return nil
+		}
+
+		var expired [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var s Session
+			err := json.Unmarshal(v, &s)
+			if err != nil || s.Expires <= now {
+				expired = append(expired, k)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			err := b.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// CurrentSession returns the session of the request's token, or
+// ErrSessionExpired if it has ended
+func CurrentSession(req *http.Request) (Session, error) {
+	cookie, err := req.Cookie("_token")
+	if err != nil || !user.IsValid(req) {
+		return Session{}, ErrSessionExpired
+	}
+
+	claims := jwt.GetClaims(cookie.Value)
+	id, _ := claims["session"].(string)
+	email, _ := claims["user"].(string)
+	if id == "" {
+		return Session{}, ErrSessionExpired
+	}
+
+	s, err := GetSession(id)
+	if err == ErrNoSession {
+		return Session{}, ErrSessionExpired
+	}
+	if err != nil {
+		return Session{}, err
+	}
+
+	if !SessionActive(s, time.Now()) || s.User != email {
+		return Session{}, ErrSessionExpired
+	}
+
+	return s, nil
+}
+
+// SessionActive reports whether s has neither expired nor, unless the user
+// asked to be remembered, gone unused for longer than the idle timeout at now
+func SessionActive(s Session, now time.Time) bool {
+	ms := now.UnixNano() / int64(time.Millisecond)
+	if ms >= s.Expires {
+		return false
+	}
+
+	idle := time.Duration(defaultIdleMinutes) * time.Minute
+	if n, ok := ConfigCache("session_idle_minutes").(float64); ok && n >= 1 {
+		idle = time.Duration(n) * time.Minute
+	}
+
+	if !s.Remember && time.Duration(ms-s.LastSeen)*time.Millisecond > idle {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+// maxSlugRedirects is the longest chain of slug redirects followed, in case
+// one loops
+const maxSlugRedirects = 10
+
+// uniqueSlug returns slug if it is not in the content index ci, or is the slug
+// of the content owner ("type:id"), otherwise slug with the lowest number from
+// 2 appended, e.g. my-post-2, which is free. Slugs are unique across every
+// content type, so that content can be found by slug alone.
+func uniqueSlug(ci *bolt.Bucket, slug, owner string) string {
+	original := slug
+	for i := 2; ; i++ {
+		v := ci.Get([]byte(slug))
+		if v == nil || (owner != "" && string(v) == owner) {
+			return slug
+		}
+
+		slug = fmt.Sprintf("%s-%d", original, i)
+	}
+}
+
+// slugOf returns the slug of content data, or "" if it has none
+func slugOf(data []byte) string {
+	var v struct {
+		Slug string `json:"slug"`
+	}
+	json.Unmarshal(data, &v)
+
+	return v.Slug
+}
+
+// slugRedirectsEnabled reports whether changing the slug of content should
+// keep a redirect from the old slug to the new one
+func slugRedirectsEnabled() bool {
+	enabled, _ := ConfigCache("slug_redirects").(bool)
+	return enabled
+}
+
+// slugRedirectPath returns the path at which content is found by its slug,
+// with "{slug}" in place of the slug, which is used to add a redirect when a
+// slug is changed. It is "" if slug_redirect_path isn't configured.
+func slugRedirectPath() string {
+	path, _ := ConfigCache("slug_redirect_path").(string)
+	if !strings.HasPrefix(path, "/") || !strings.Contains(path, "{slug}") {
+		return ""
+	}
+
+	return path
+}
+
+// indexSlug points slug at the content ns:id in the content index, removing
+// the old slug of the content if it changed, and keeping a redirect from the
+// old slug if slug redirects are enabled, as well as from the old path of the
+// content if slug_redirect_path is configured
+func indexSlug(tx *bolt.Tx, ns, id, old, slug string) error {
+	ci := tx.Bucket([]byte("__contentIndex"))
+	if ci == nil {
+		return bolt.ErrBucketNotFound
+	}
+
+	owner := ns + ":" + id
+	if old != "" && old != slug && string(ci.Get([]byte(old))) == owner {
+		err := ci.Delete([]byte(old))
+		if err != nil {
+			return err
+		}
+	}
+
+	err := ci.Put([]byte(slug), []byte(owner))
+	if err != nil {
+		return err
+	}
+
+	r, err := tx.CreateBucketIfNotExists([]byte("__slugRedirects"))
+	if err != nil {
+		return err
+	}
+
+	// the slug is in use again, so it no longer redirects
+	err = r.Delete([]byte(slug))
+	if err != nil {
+		return err
+	}
+
+	// a path redirect from the slug would now hide its content
+	path := slugRedirectPath()
+	if path != "" {
+		err = deleteRedirect(tx, strings.Replace(path, "{slug}", slug, -1))
+		if err != nil {
+			return err
+		}
+	}
+
+	if old == "" || old == slug || !slugRedirectsEnabled() {
+		return nil
+	}
+
+	if path != "" {
+		err := putRedirect(tx, Redirect{
+			From:   strings.Replace(path, "{slug}", old, -1),
+			To:     strings.Replace(path, "{slug}", slug, -1),
+			Status: http.StatusMovedPermanently,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// point redirects to the old slug straight at the new one
+	var moved [][]byte
+	err = r.ForEach(func(k, v []byte) error {
+		if string(v) == old {
+			moved = append(moved, k)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range append(moved, []byte(old)) {
+		err := r.Put(k, []byte(slug))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SlugRedirect returns the slug of the content which has taken the place of
+// content with slug, for slugs which have been changed, or "" if there is none
+func SlugRedirect(slug string) (string, error) {
+	var to string
+	err := store.View(func(tx *bolt.Tx) error {
+		r := tx.Bucket([]byte("__slugRedirects"))
+		ci := tx.Bucket([]byte("__contentIndex"))
+		if r == nil || ci == nil {
+			return nil
+		}
+
+		next := slug
+		for i := 0; i < maxSlugRedirects; i++ {
+			v := r.Get([]byte(next))
+			if v == nil {
+				return nil
+			}
+
+			next = string(v)
+			if ci.Get(v) != nil {
+				to = next
+				return nil
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return to, nil
+}
@@ -0,0 +1,345 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/search"
+
+	"github.com/boltdb/bolt"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+var (
+	// ErrTagNotFound is returned when renaming a tag which isn't a canonical
+	// tag
+	ErrTagNotFound = errors.New("Tag not found")
+	// ErrTagEmpty is returned when renaming a tag to a blank name
+	ErrTagEmpty = errors.New("A tag can't be renamed to nothing")
+)
+
+// Tagged is content found by a tag, with the content type it is of
+type Tagged struct {
+	Type    string
+	Content []byte
+}
+
+// tagKey returns the key of the canonical tag matching tag, which is the same
+// whatever its case or surrounding space
+func tagKey(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// tagFields returns the fields holding tags of the content type ns, if it is
+// item.Taggable
+func tagFields(ns string) []string {
+	it, ok := item.Types[ns]
+	if !ok {
+		return nil
+	}
+
+	t, ok := it().(item.Taggable)
+	if !ok {
+		return nil
+	}
+
+	return t.TagFields()
+}
+
+// canonicalTags returns the content json j of type ns with each tag of its tag
+// fields in the spelling of the canonical tag matching it, adding new tags to
+// the canonical tags in tx. Blank and repeated tags are dropped.
+func canonicalTags(tx *bolt.Tx, ns string, j []byte) ([]byte, error) {
+	fields := tagFields(ns)
+	if len(fields) == 0 {
+		return j, nil
+	}
+
+	b, err := tx.CreateBucketIfNotExists([]byte("__tags"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		values := gjson.GetBytes(j, f).Array()
+		if len(values) == 0 {
+			continue
+		}
+
+		tags := []string{}
+		seen := make(map[string]bool)
+		for _, v := range values {
+			name := strings.TrimSpace(v.String())
+			key := tagKey(name)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if c := b.Get([]byte(key)); c != nil {
+				name = string(c)
+			} else {
+				err := b.Put([]byte(key), []byte(name))
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			tags = append(tags, name)
+		}
+
+		j, err = sjson.SetBytes(j, f, tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return j, nil
+}
+
+// hasTag reports whether the content json j has the tag with key in one of
+// fields
+func hasTag(j []byte, fields []string, key string) bool {
+	for _, f := range fields {
+		for _, v := range gjson.GetBytes(j, f).Array() {
+			if tagKey(v.String()) == key {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Tags returns the canonical tags, in alphabetical order regardless of case
+func Tags() ([]string, error) {
+	var tags []string
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__tags"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			tags = append(tags, string(v))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tagKey(tags[i]) < tagKey(tags[j])
+	})
+
+	return tags, nil
+}
+
+// TagCounts returns the number of items tagged with each canonical tag, by the
+// tag in lower case, counting content of every status except pending approval
+func TagCounts() map[string]int {
+	counts := make(map[string]int)
+	for t := range item.Types {
+		fields := tagFields(t)
+		for _, j := range ContentAll(t) {
+			for _, f := range fields {
+				for _, v := range gjson.GetBytes(j, f).Array() {
+					counts[tagKey(v.String())]++
+				}
+			}
+		}
+	}
+
+	return counts
+}
+
+// TaggedContent returns the content of every item.Taggable type with tag, in
+// any case, in one of its tag fields, newest first. Content pending approval
+// isn't included.
+func TaggedContent(tag string) []Tagged {
+	key := tagKey(tag)
+
+	var tagged []Tagged
+	for t := range item.Types {
+		fields := tagFields(t)
+		if len(fields) == 0 {
+			continue
+		}
+
+		for _, j := range ContentAll(t) {
+			if hasTag(j, fields, key) {
+				tagged = append(tagged, Tagged{Type: t, Content: j})
+			}
+		}
+	}
+
+	sort.SliceStable(tagged, func(i, j int) bool {
+		a, b := tagged[i], tagged[j]
+		ta := gjson.GetBytes(a.Content, "timestamp").Int()
+		tb := gjson.GetBytes(b.Content, "timestamp").Int()
+		if ta != tb {
+			return ta > tb
+		}
+
+		return a.Type < b.Type
+	})
+
+	return tagged
+}
+
+// RenameTag renames the canonical tag from to to, and updates every item of
+// the item.Taggable types tagged with it, including those pending approval, in
+// a single transaction. Renaming to an existing tag merges the two. It returns
+// the number of items updated.
+func RenameTag(from, to string) (int, error) {
+	fromKey, toKey := tagKey(from), tagKey(to)
+	to = strings.TrimSpace(to)
+	if toKey == "" {
+		return 0, ErrTagEmpty
+	}
+
+	changed := make(map[string]bool)
+	var updated int
+	err := store.Update(func(tx *bolt.Tx) error {
+		tags := tx.Bucket([]byte("__tags"))
+		if tags == nil || tags.Get([]byte(fromKey)) == nil {
+			return ErrTagNotFound
+		}
+
+		// merging into another tag keeps its spelling
+		if existing := tags.Get([]byte(toKey)); existing != nil && toKey != fromKey {
+			to = string(existing)
+		}
+
+		err := tags.Delete([]byte(fromKey))
+		if err != nil {
+			return err
+		}
+
+		err = tags.Put([]byte(toKey), []byte(to))
+		if err != nil {
+			return err
+		}
+
+		for t := range item.Types {
+			fields := tagFields(t)
+			if len(fields) == 0 {
+				continue
+			}
+
+			for _, ns := range []string{t, t + "__pending"} {
+				b := tx.Bucket([]byte(ns))
+				if b == nil {
+					continue
+				}
+
+				// bolt doesn't allow changing a bucket while iterating it
+				updates := make(map[string][]byte)
+				err := b.ForEach(func(k, v []byte) error {
+					if !hasTag(v, fields, fromKey) {
+						return nil
+					}
+
+					j := append([]byte(nil), v...)
+					for _, f := range fields {
+						var renamed []string
+						seen := make(map[string]bool)
+						for _, v := range gjson.GetBytes(j, f).Array() {
+							name := v.String()
+							if tagKey(name) == fromKey {
+								name = to
+							}
+
+							if !seen[tagKey(name)] {
+								seen[tagKey(name)] = true
+								renamed = append(renamed, name)
+							}
+						}
+
+						if renamed == nil {
+							continue
+						}
+
+						var err error
+						j, err = sjson.SetBytes(j, f, renamed)
+						if err != nil {
+							return err
+						}
+					}
+
+					updates[string(k)] = j
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				for k, j := range updates {
+					err := b.Put([]byte(k), j)
+					if err != nil {
+						return err
+					}
+				}
+
+				if len(updates) > 0 && ns == t {
+					changed[t] = true
+				}
+				updated += len(updates)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for t := range changed {
+		SortContent(t)
+
+		if search.Searchable(t) {
+			err := ReindexSearch(t)
+			if err != nil {
+				return updated, err
+			}
+		}
+	}
+
+	return updated, InvalidateCache()
+}
+
+// indexTags adds the tags of content saved before its type was item.Taggable,
+// or before canonical tags were kept, to the canonical tags
+func indexTags() {
+	err := store.Update(func(tx *bolt.Tx) error {
+		for t := range item.Types {
+			if len(tagFields(t)) == 0 {
+				continue
+			}
+
+			b := tx.Bucket([]byte(t))
+			if b == nil {
+				continue
+			}
+
+			// the content itself is left as it is until it is next saved
+			err := b.ForEach(func(k, v []byte) error {
+				_, err := canonicalTags(tx, t, v)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.Error("Error indexing tags:", err)
+	}
+}
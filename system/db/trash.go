@@ -0,0 +1,270 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/webhook"
+
+	"github.com/boltdb/bolt"
+)
+
+// defaultTrashRetention is the number of days content is kept in the trash if
+// trash_retention_days is not configured
+const defaultTrashRetention = 30
+
+// TrashItem is a content item which has been deleted, and can be restored
+// until it is purged from the trash
+type TrashItem struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id"`
+	DeletedAt int64           `json:"deleted_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Slug returns the slug of the deleted content, if it has one
+func (t TrashItem) Slug() string {
+	var data struct {
+		Slug string `json:"slug"`
+	}
+	json.Unmarshal(t.Data, &data)
+
+	return data.Slug
+}
+
+// Time returns the time at which the content was deleted
+func (t TrashItem) Time() time.Time {
+	return time.Unix(0, t.DeletedAt*int64(time.Millisecond))
+}
+
+// TrashRetentionDays returns the configured number of days to keep items in
+// the trash before they are purged
+func TrashRetentionDays() int {
+	n, ok := ConfigCache("trash_retention_days").(float64)
+	if !ok || n < 1 {
+		return defaultTrashRetention
+	}
+
+	return int(n)
+}
+
+// trashKey returns the __trash key for the content item ns:id
+func trashKey(ns, id string) []byte {
+	return []byte(ns + ":" + id)
+}
+
+// putTrash stores the content data of ns:id in the trash within tx
+func putTrash(tx *bolt.Tx, ns, id string, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	b, err := tx.CreateBucketIfNotExists([]byte("__trash"))
+	if err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(TrashItem{
+		Type:      ns,
+		ID:        id,
+		DeletedAt: time.Now().UnixNano() / int64(time.Millisecond),
+		Data:      json.RawMessage(data),
+	})
+	if err != nil {
+		return err
+	}
+
+	return b.Put(trashKey(ns, id), j)
+}
+
+// Trash returns all content items in the trash, most recently deleted first
+func Trash() ([]TrashItem, error) {
+	var items = []TrashItem{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__trash"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var ti TrashItem
+			err := json.Unmarshal(v, &ti)
+			if err != nil {
+				logger.Error("Error decoding trash item:", string(k), err)
+				return nil
+			}
+
+			items = append(items, ti)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].DeletedAt > items[j].DeletedAt
+	})
+
+	return items, nil
+}
+
+// Restore moves a content item from the trash back into the database. If its
+// slug has since been taken by other content, a new unique slug is used.
+func Restore(contentType, id string) error {
+	ns := contentType
+	if strings.Contains(ns, "__") {
+		ns = strings.Split(ns, "__")[0]
+	}
+
+	var data []byte
+	err := store.Update(func(tx *bolt.Tx) error {
+		trash := tx.Bucket([]byte("__trash"))
+		if trash == nil {
+			return bolt.ErrBucketNotFound
+		}
+
+		k := trashKey(contentType, id)
+		v := trash.Get(k)
+		if v == nil {
+			return fmt.Errorf("No content %s:%s found in trash", contentType, id)
+		}
+
+		var ti TrashItem
+		err := json.Unmarshal(v, &ti)
+		if err != nil {
+			return err
+		}
+
+		b, err := tx.CreateBucketIfNotExists([]byte(contentType))
+		if err != nil {
+			return err
+		}
+
+		if b.Get([]byte(id)) != nil {
+			return fmt.Errorf("Content %s:%s already exists", contentType, id)
+		}
+
+		data = []byte(ti.Data)
+
+		// only public content is in the content index
+		if contentType == ns {
+			data, err = restoreSlug(tx, ns, id, "", data)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = b.Put([]byte(id), data)
+		if err != nil {
+			return err
+		}
+
+		return trash.Delete(k)
+	})
+	if err != nil {
+		return err
+	}
+
+	// restore changes data, so invalidate client caching
+	err = InvalidateCache()
+	if err != nil {
+		return err
+	}
+
+	SortContent(ns)
+
+	if contentType == ns {
+		go updateSearchIndex(ns, id, data)
+		go notifyWebhooks(webhook.EventCreate, ns, id, data)
+	}
+
+	return nil
+}
+
+// restoreSlug adds restored content to the content index in place of its old
+// slug, if it had one, giving it a new unique slug if its restored one is in
+// use by other content, and returns the content data
+func restoreSlug(tx *bolt.Tx, ns, id, old string, data []byte) ([]byte, error) {
+	ci := tx.Bucket([]byte("__contentIndex"))
+	if ci == nil {
+		return nil, bolt.ErrBucketNotFound
+	}
+
+	t, ok := item.Types[ns]
+	if !ok {
+		return nil, fmt.Errorf(item.ErrTypeNotRegistered.Error(), ns)
+	}
+
+	post := t()
+	err := json.Unmarshal(data, post)
+	if err != nil {
+		return nil, err
+	}
+
+	s, ok := post.(item.Sluggable)
+	if !ok || s.ItemSlug() == "" {
+		return data, nil
+	}
+
+	original := s.ItemSlug()
+	slug := uniqueSlug(ci, original, ns+":"+id)
+
+	err = indexSlug(tx, ns, id, old, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if slug == original {
+		return data, nil
+	}
+
+	s.SetSlug(slug)
+	return json.Marshal(post)
+}
+
+// PurgeTrash permanently removes a content item and its revisions from the
+// trash
+func PurgeTrash(contentType, id string) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		trash := tx.Bucket([]byte("__trash"))
+		if trash == nil {
+			return nil
+		}
+
+		err := deleteRevisions(tx, contentType, id)
+		if err != nil {
+			return err
+		}
+
+		return trash.Delete(trashKey(contentType, id))
+	})
+}
+
+// purgeExpiredTrash purges items which have been in the trash longer than the
+// configured retention, and is run hourly
+func purgeExpiredTrash() error {
+	items, err := Trash()
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, TrashRetentionDays()*-1)
+	for _, ti := range items {
+		if ti.Time().After(cutoff) {
+			continue
+		}
+
+		err := PurgeTrash(ti.Type, ti.ID)
+		if err != nil {
+			logger.Error("Error purging expired item from trash:", ti.Type, ti.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -2,12 +2,12 @@ package db
 
 import (
 	"bytes"
+	crand "crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
 	"net/http"
-	"time"
 
 	"github.com/ponzu-cms/ponzu/system/admin/user"
 
@@ -215,12 +215,17 @@ func CurrentUser(req *http.Request) ([]byte, error) {
 }
 
 // SetRecoveryKey generates and saves a random secret key to verify an email
-// address submitted in order to recover/reset an account password
+// address submitted in order to recover/reset an account password, replacing
+// any key already set for it
 func SetRecoveryKey(email string) (string, error) {
-	r := rand.New(rand.NewSource(time.Now().Unix()))
-	key := fmt.Sprintf("%d", r.Int63())
+	buf := make([]byte, 16)
+	_, err := crand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(buf)
 
-	err := store.Update(func(tx *bolt.Tx) error {
+	err = store.Update(func(tx *bolt.Tx) error {
 		b, err := tx.CreateBucketIfNotExists([]byte("__recoveryKeys"))
 		if err != nil {
 			return err
@@ -264,3 +269,16 @@ func RecoveryKey(email string) (string, error) {
 
 	return key.String(), nil
 }
+
+// DeleteRecoveryKey removes the recovery key set for email, so that it can't
+// be used again
+func DeleteRecoveryKey(email string) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__recoveryKeys"))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(email))
+	})
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+)
+
+// ErrVersionConflict is returned when content is saved from a copy of a version
+// other than the one stored, since saving it would lose the changes saved
+// since the copy was read
+var ErrVersionConflict = errors.New("This content has been changed since it was opened. Reload it to see the changes, then make your edits again.")
+
+// versionOf returns the version of the content json data, which is 0 for
+// content saved before versions were kept
+func versionOf(data []byte) int {
+	return int(gjson.GetBytes(data, "version").Int())
+}
+
+// setVersion sets the version in data, the values content is being saved
+// with, to the one after that of current, the content stored now. If data
+// holds the version it was edited from, ErrVersionConflict is returned unless
+// it is the version of current.
+func setVersion(data url.Values, current []byte) error {
+	if v := data.Get("version"); v != "" {
+		edited, err := strconv.Atoi(v)
+		if err != nil || edited != versionOf(current) {
+			return ErrVersionConflict
+		}
+	}
+
+	data.Set("version", strconv.Itoa(versionOf(current)+1))
+	return nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"github.com/ponzu-cms/ponzu/system/webhook"
+
+	"github.com/boltdb/bolt"
+)
+
+// Webhook is a URL which is notified when content of a type changes
+type Webhook struct {
+	ID     int      `json:"id"`
+	URL    string   `json:"url"`
+	Type   string   `json:"type"` // content type name, or "*" for all types
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// Wants reports whether the webhook is registered for event on content of
+// type ns
+func (w Webhook) Wants(event, ns string) bool {
+	if w.Type != "*" && w.Type != ns {
+		return false
+	}
+
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetWebhook stores w in the __webhooks bucket, assigning it a new ID if it
+// has none, and returns the ID
+func SetWebhook(w Webhook) (int, error) {
+	err := store.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("__webhooks"))
+		if err != nil {
+			return err
+		}
+
+		if w.ID == 0 {
+			id, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			w.ID = int(id)
+		}
+
+		j, err := json.Marshal(w)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(strconv.Itoa(w.ID)), j)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return w.ID, nil
+}
+
+// DeleteWebhook removes the webhook with id from the __webhooks bucket
+func DeleteWebhook(id int) error {
+	return store.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__webhooks"))
+		if b == nil {
+			return nil
+		}
+
+		return b.Delete([]byte(strconv.Itoa(id)))
+	})
+}
+
+// Webhooks returns all registered webhooks
+func Webhooks() ([]Webhook, error) {
+	var hooks = []Webhook{}
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("__webhooks"))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var w Webhook
+			err := json.Unmarshal(v, &w)
+			if err != nil {
+				logger.Error("Error decoding webhook:", string(k), err)
+				return nil
+			}
+
+			hooks = append(hooks, w)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// notifyWebhooks sends event for the public content ns:id to every webhook
+// registered for it. Deliveries are made in the background, so it does not
+// block the change which triggered it.
+func notifyWebhooks(event, ns, id string, data []byte) {
+	if strings.Contains(ns, "__") {
+		return
+	}
+
+	hooks, err := Webhooks()
+	if err != nil {
+		logger.Error("Error reading webhooks to notify:", err)
+		return
+	}
+
+	for _, w := range hooks {
+		if !w.Wants(event, ns) {
+			continue
+		}
+
+		webhook.Send(w.URL, w.Secret, webhook.Payload{
+			Event: event,
+			Type:  ns,
+			ID:    id,
+			Data:  json.RawMessage(data),
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Package email sends email from Ponzu, such as account invitations, through a
+// Sender which can be replaced.
+package email
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+
+	emailer "github.com/nilslice/email"
+)
+
+// Sender delivers an email
+type Sender interface {
+	Send(to, subject, body string) error
+}
+
+var (
+	mu     sync.RWMutex
+	sender Sender = configSender{}
+)
+
+// SetSender replaces the Sender used by Send
+func SetSender(s Sender) {
+	mu.Lock()
+	sender = s
+	mu.Unlock()
+}
+
+// Send delivers an email to the address to using the current Sender
+func Send(to, subject, body string) error {
+	mu.RLock()
+	s := sender
+	mu.RUnlock()
+
+	return s.Send(to, subject, body)
+}
+
+// From returns the address email is sent from, which is smtp_from in the
+// config if it is set, otherwise ponzu@ the configured domain
+func From() string {
+	if from, _ := db.ConfigCache("smtp_from").(string); from != "" {
+		return from
+	}
+
+	domain, _ := db.ConfigCache("domain").(string)
+	return fmt.Sprintf("ponzu@%s", domain)
+}
+
+// configSender sends email through the SMTP server set in the config, or if
+// there is none, straight to the mail servers of the recipient's domain
+type configSender struct{}
+
+func (configSender) Send(to, subject, body string) error {
+	host, _ := db.ConfigCache("smtp_host").(string)
+	if host == "" {
+		return directSender{}.Send(to, subject, body)
+	}
+
+	port, _ := db.ConfigCache("smtp_port").(float64)
+	username, _ := db.ConfigCache("smtp_username").(string)
+	password, _ := db.ConfigCache("smtp_password").(string)
+	mode, _ := db.ConfigCache("smtp_tls").(string)
+
+	s := SMTPSender{
+		Host:     host,
+		Port:     int(port),
+		Username: username,
+		Password: password,
+		TLS:      mode,
+		From:     From(),
+	}
+
+	return s.Send(to, subject, body)
+}
+
+// directSender delivers email straight to the mail servers of the recipient's
+// domain
+type directSender struct{}
+
+func (directSender) Send(to, subject, body string) error {
+	msg := emailer.Message{
+		To:      to,
+		From:    From(),
+		Subject: subject,
+		Body:    body,
+	}
+
+	return msg.Send()
+}
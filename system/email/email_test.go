@@ -0,0 +1,48 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)
+	msg, err := buildMessage("ponzu@example.com", "you@example.com", "Hello", "line one\nline two", date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "From: ponzu@example.com\r\n" +
+		"To: you@example.com\r\n" +
+		"Subject: Hello\r\n" +
+		"Date: Mon, 02 Jan 2017 15:04:05 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"line one\r\nline two"
+	if string(msg) != want {
+		t.Errorf("expected message:\n%q\ngot:\n%q", want, msg)
+	}
+
+	_, err = buildMessage("ponzu@example.com", "you@example.com", "Hi\r\nBcc: them@example.com", "", date)
+	if err == nil {
+		t.Error("expected a subject with a line break to be refused")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	rec := &Recorder{}
+	SetSender(rec)
+	defer SetSender(configSender{})
+
+	err := Send("you@example.com", "Invitation", "Welcome")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := rec.Messages()
+	if len(msgs) != 1 || msgs[0].To != "you@example.com" || !strings.Contains(msgs[0].Body, "Welcome") {
+		t.Errorf("expected the sent email to be recorded, got %+v", msgs)
+	}
+}
@@ -0,0 +1,39 @@
+package email
+
+import "sync"
+
+// Message is an email given to a Recorder
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Recorder is a Sender which keeps the email it is given instead of sending
+// it, so tests can check what would have been sent:
+//
+//	rec := &email.Recorder{}
+//	email.SetSender(rec)
+//	// ...
+//	msgs := rec.Messages()
+type Recorder struct {
+	mu   sync.Mutex
+	msgs []Message
+}
+
+// Send records the email
+func (r *Recorder) Send(to, subject, body string) error {
+	r.mu.Lock()
+	r.msgs = append(r.msgs, Message{To: to, Subject: subject, Body: body})
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Messages returns the email recorded so far, oldest first
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Message(nil), r.msgs...)
+}
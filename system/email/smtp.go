@@ -0,0 +1,147 @@
+package email
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLS modes of an SMTPSender
+// If the mode is unset, STARTTLS is used when the server offers it.
+const (
+	// TLSNone sends over a plain connection
+	TLSNone = "none"
+	// TLSStartTLS upgrades a plain connection with STARTTLS, failing if the
+	// server doesn't offer it
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects with TLS from the start, usually on port 465
+	TLSImplicit = "tls"
+)
+
+// dialTimeout is how long an SMTPSender waits to connect to its server
+const dialTimeout = 10 * time.Second
+
+// SMTPSender sends email through an SMTP server, which it logs in to if
+// Username is set
+type SMTPSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	TLS      string
+	From     string
+}
+
+// Send delivers an email to the address to through the SMTP server
+func (s SMTPSender) Send(to, subject, body string) error {
+	msg, err := buildMessage(s.From, to, subject, body, time.Now())
+	if err != nil {
+		return err
+	}
+
+	port := s.Port
+	if port == 0 {
+		port = 587
+		if s.TLS == TLSImplicit {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: s.Host}
+
+	var conn net.Conn
+	if s.TLS == TLSImplicit {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, dialTimeout)
+	}
+	if err != nil {
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if s.TLS == TLSStartTLS || s.TLS == "" {
+		ok, _ := c.Extension("STARTTLS")
+		if !ok && s.TLS == TLSStartTLS {
+			return fmt.Errorf("SMTP server %s doesn't support STARTTLS", s.Host)
+		}
+
+		if ok {
+			err = c.StartTLS(tlsConfig)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if s.Username != "" {
+		err = c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(s.From)
+	if err != nil {
+		return err
+	}
+
+	err = c.Rcpt(to)
+	if err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// buildMessage returns the plain text email message of body with its headers,
+// refusing header values which could add headers of their own
+func buildMessage(from, to, subject, body string, date time.Time) ([]byte, error) {
+	for _, v := range []string{from, to, subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, fmt.Errorf("Invalid email header value: %q", v)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	fmt.Fprintf(buf, "To: %s\r\n", to)
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+
+	// lines of the body end in CRLF, as SMTP requires
+	body = strings.Replace(body, "\r\n", "\n", -1)
+	buf.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+
+	return buf.Bytes(), nil
+}
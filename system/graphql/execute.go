@@ -0,0 +1,609 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Result is the response to a query. Data is null if the query could not be
+// executed, or a non-null field at its root resolved to null.
+type Result struct {
+	Data   interface{} `json:"data"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// Error is an error found while executing a query, with the path of the field
+// of the response in which it occurred, if any
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Execute runs query against s, passing ctx to resolvers. If the query holds
+// more than one operation, operationName selects the one to run. Variables
+// are the values of the variables the operation declares, as decoded from
+// json.
+func (s *Schema) Execute(ctx context.Context, query, operationName string, variables map[string]interface{}) *Result {
+	doc, err := parse(query)
+	if err != nil {
+		return &Result{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	var op *operation
+	for _, o := range doc.operations {
+		if operationName == "" || o.name == operationName {
+			if op != nil {
+				return &Result{Errors: []*Error{{Message: "Must provide operation name if query contains multiple operations."}}}
+			}
+			op = o
+		}
+	}
+
+	if op == nil {
+		return &Result{Errors: []*Error{{Message: fmt.Sprintf("Unknown operation named %q.", operationName)}}}
+	}
+
+	if op.kind != "query" {
+		return &Result{Errors: []*Error{{Message: fmt.Sprintf("Schema is not configured for %ss.", op.kind)}}}
+	}
+
+	vars := make(map[string]interface{})
+	for _, v := range op.vars {
+		if val, ok := variables[v.name]; ok {
+			vars[v.name] = val
+		} else if v.def != nil {
+			vars[v.name] = v.def.resolve(nil)
+		}
+	}
+
+	e := &executor{schema: s, doc: doc, vars: vars, ctx: ctx}
+	data, ok := e.selectionSet(s.Query, nil, op.selections, nil)
+
+	res := &Result{Errors: e.errors}
+	if ok {
+		res.Data = data
+	}
+
+	return res
+}
+
+type executor struct {
+	schema *Schema
+	doc    *document
+	vars   map[string]interface{}
+	ctx    context.Context
+	errors []*Error
+}
+
+func (e *executor) errorf(path []interface{}, format string, a ...interface{}) {
+	e.errors = append(e.errors, &Error{
+		Message: fmt.Sprintf(format, a...),
+		Path:    append([]interface{}{}, path...),
+	})
+}
+
+// fieldGroup holds the selections of a field under the same response key,
+// which are merged
+type fieldGroup struct {
+	key   string
+	nodes []*selection
+}
+
+// collect groups the fields selected on an object of type t, following
+// fragments which apply to t
+func (e *executor) collect(t *Type, sels []*selection, groups []*fieldGroup, visited map[string]bool) []*fieldGroup {
+	for _, s := range sels {
+		if !e.included(s.directives) {
+			continue
+		}
+
+		switch {
+		case s.spread != "":
+			if visited[s.spread] {
+				continue
+			}
+			visited[s.spread] = true
+
+			f, ok := e.doc.fragments[s.spread]
+			if !ok {
+				e.errorf(nil, "Unknown fragment %q.", s.spread)
+				continue
+			}
+
+			if f.on == t.Name {
+				groups = e.collect(t, f.selections, groups, visited)
+			}
+
+		case s.inline:
+			if s.on == "" || s.on == t.Name {
+				groups = e.collect(t, s.selections, groups, visited)
+			}
+
+		default:
+			merged := false
+			for _, g := range groups {
+				if g.key == s.key() {
+					g.nodes = append(g.nodes, s)
+					merged = true
+					break
+				}
+			}
+
+			if !merged {
+				groups = append(groups, &fieldGroup{key: s.key(), nodes: []*selection{s}})
+			}
+		}
+	}
+
+	return groups
+}
+
+// included applies the @skip and @include directives
+func (e *executor) included(dirs []*directive) bool {
+	for _, d := range dirs {
+		var cond bool
+		for _, a := range d.args {
+			if a.name == "if" {
+				cond, _ = a.val.resolve(e.vars).(bool)
+			}
+		}
+
+		if (d.name == "skip" && cond) || (d.name == "include" && !cond) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// selectionSet resolves the fields in sels of source, an object of type t. ok
+// is false if a non-null field resolved to null, which makes the object null.
+func (e *executor) selectionSet(t *Type, source interface{}, sels []*selection, path []interface{}) (*object, bool) {
+	obj := &object{values: make(map[string]interface{})}
+	for _, g := range e.collect(t, sels, nil, make(map[string]bool)) {
+		v, ok := e.field(t, source, g, append(path[:len(path):len(path)], g.key))
+		if !ok {
+			return nil, false
+		}
+
+		obj.set(g.key, v)
+	}
+
+	return obj, true
+}
+
+func (e *executor) field(t *Type, source interface{}, g *fieldGroup, path []interface{}) (interface{}, bool) {
+	node := g.nodes[0]
+	if node.name == "__typename" {
+		return t.Name, true
+	}
+
+	def := t.field(node.name)
+	if t == e.schema.Query {
+		switch node.name {
+		case "__schema":
+			def = schemaField
+			source = e.schema
+		case "__type":
+			def = typeField
+			source = e.schema
+		}
+	}
+
+	if def == nil {
+		e.errorf(path, "Cannot query field %q on type %q.", node.name, t.Name)
+		return nil, true
+	}
+
+	args, err := e.coerceArgs(def.Args, node.args)
+	if err != nil {
+		e.errorf(path, "%s", err)
+		return nil, def.Type.Kind != KindNonNull
+	}
+
+	var val interface{}
+	if def.Resolve != nil {
+		val, err = def.Resolve(Params{Context: e.ctx, Source: source, Args: args})
+		if err != nil {
+			e.errorf(path, "%s", err)
+			return nil, def.Type.Kind != KindNonNull
+		}
+	} else if m, ok := source.(map[string]interface{}); ok {
+		val = m[def.Name]
+	}
+
+	var sels []*selection
+	for _, n := range g.nodes {
+		sels = append(sels, n.selections...)
+	}
+
+	return e.complete(def.Type, val, sels, path)
+}
+
+// complete converts the resolved value v of a field to its type t, resolving
+// the fields selected by sels of objects
+func (e *executor) complete(t *Type, v interface{}, sels []*selection, path []interface{}) (interface{}, bool) {
+	if t.Kind == KindNonNull {
+		r, ok := e.complete(t.OfType, v, sels, path)
+		if !ok {
+			return nil, false
+		}
+
+		if r == nil {
+			if isNull(v) {
+				e.errorf(path, "Cannot return null for non-nullable field.")
+			}
+			return nil, false
+		}
+
+		return r, true
+	}
+
+	if isNull(v) {
+		return nil, true
+	}
+
+	switch t.Kind {
+	case KindList:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.errorf(path, "Expected a list of %s.", t.OfType)
+			return nil, true
+		}
+
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			item, ok := e.complete(t.OfType, rv.Index(i).Interface(), sels, append(path[:len(path):len(path)], i))
+			if !ok {
+				return nil, true
+			}
+			list[i] = item
+		}
+
+		return list, true
+
+	case KindObject:
+		if len(sels) == 0 {
+			e.errorf(path, "Field of type %q must have a selection of subfields.", t.Name)
+			return nil, true
+		}
+
+		obj, ok := e.selectionSet(t, v, sels, path)
+		if !ok {
+			return nil, true
+		}
+
+		return obj, true
+	}
+
+	if len(sels) > 0 {
+		e.errorf(path, "Field of type %q must not have a selection since it has no subfields.", t.Name)
+		return nil, true
+	}
+
+	r, err := serialize(t, v)
+	if err != nil {
+		e.errorf(path, "%s", err)
+		return nil, true
+	}
+
+	return r, true
+}
+
+func (e *executor) coerceArgs(defs []*Arg, args []*argument) (map[string]interface{}, error) {
+	given := make(map[string]*value)
+	for _, a := range args {
+		known := false
+		for _, d := range defs {
+			if d.Name == a.name {
+				known = true
+			}
+		}
+
+		if !known {
+			return nil, fmt.Errorf("Unknown argument %q.", a.name)
+		}
+		given[a.name] = a.val
+	}
+
+	out := make(map[string]interface{})
+	for _, d := range defs {
+		node, present := given[d.Name]
+
+		var v interface{}
+		if present {
+			if node.kind == valVar {
+				v, present = e.vars[node.raw]
+			} else {
+				v = node.resolve(e.vars)
+			}
+		}
+
+		if !present {
+			if d.Default != nil {
+				out[d.Name] = d.Default
+			} else if d.Type.Kind == KindNonNull {
+				return nil, fmt.Errorf("Argument %q of required type %q was not provided.", d.Name, d.Type)
+			}
+			continue
+		}
+
+		c, err := coerceInput(d.Type, v)
+		if err != nil {
+			return nil, fmt.Errorf("Argument %q has invalid value: %s", d.Name, err)
+		}
+		out[d.Name] = c
+	}
+
+	return out, nil
+}
+
+// coerceInput converts an argument value v, from a query literal or
+// variable, to type t
+func coerceInput(t *Type, v interface{}) (interface{}, error) {
+	if t.Kind == KindNonNull {
+		if v == nil {
+			return nil, fmt.Errorf("expected non-null %s", t.OfType)
+		}
+		return coerceInput(t.OfType, v)
+	}
+
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t.Kind {
+	case KindList:
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+
+		list := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			c, err := coerceInput(t.OfType, item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, c)
+		}
+		return list, nil
+
+	case KindInputObject:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object of %s", t.Name)
+		}
+
+		for k := range m {
+			known := false
+			for _, f := range t.InputFields {
+				if f.Name == k {
+					known = true
+				}
+			}
+
+			if !known {
+				return nil, fmt.Errorf("%q is not a field of %s", k, t.Name)
+			}
+		}
+
+		obj := make(map[string]interface{})
+		for _, f := range t.InputFields {
+			fv, ok := m[f.Name]
+			if !ok {
+				if f.Default != nil {
+					obj[f.Name] = f.Default
+				} else if f.Type.Kind == KindNonNull {
+					return nil, fmt.Errorf("field %q of %s is required", f.Name, t.Name)
+				}
+				continue
+			}
+
+			c, err := coerceInput(f.Type, fv)
+			if err != nil {
+				return nil, err
+			}
+			obj[f.Name] = c
+		}
+		return obj, nil
+
+	case KindEnum:
+		s, ok := v.(string)
+		if ok {
+			for _, ev := range t.EnumValues {
+				if ev == s {
+					return s, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("expected a value of %s", t.Name)
+	}
+
+	switch t {
+	case Int:
+		switch n := v.(type) {
+		case int:
+			return n, nil
+		case float64:
+			if n == math.Trunc(n) {
+				return int(n), nil
+			}
+		}
+
+	case Float:
+		switch n := v.(type) {
+		case int:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		}
+
+	case String:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+
+	case Boolean:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+
+	case ID:
+		switch n := v.(type) {
+		case string:
+			return n, nil
+		case int:
+			return strconv.Itoa(n), nil
+		case float64:
+			if n == math.Trunc(n) {
+				return strconv.FormatInt(int64(n), 10), nil
+			}
+		}
+
+	default:
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("expected a value of %s", t.Name)
+}
+
+// serialize converts the resolved value v of a scalar or enum to type t
+func serialize(t *Type, v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	switch t {
+	case Int, ID:
+		var n int64
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = rv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = int64(rv.Uint())
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			if f != math.Trunc(f) {
+				return nil, fmt.Errorf("%s cannot represent non-integer value: %v", t.Name, f)
+			}
+			n = int64(f)
+		case reflect.String:
+			if t == ID {
+				return rv.String(), nil
+			}
+			i, err := strconv.ParseInt(rv.String(), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Int cannot represent value: %q", rv.String())
+			}
+			n = i
+		default:
+			return nil, fmt.Errorf("%s cannot represent value: %v", t.Name, v)
+		}
+
+		if t == ID {
+			return strconv.FormatInt(n, 10), nil
+		}
+		return n, nil
+
+	case Float:
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(rv.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(rv.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			return rv.Float(), nil
+		}
+		return nil, fmt.Errorf("Float cannot represent value: %v", v)
+
+	case String:
+		switch rv.Kind() {
+		case reflect.String:
+			return rv.String(), nil
+		case reflect.Bool:
+			return strconv.FormatBool(rv.Bool()), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(rv.Int(), 10), nil
+		case reflect.Float32, reflect.Float64:
+			return strconv.FormatFloat(rv.Float(), 'f', -1, 64), nil
+		}
+
+		if s, ok := v.(fmt.Stringer); ok {
+			return s.String(), nil
+		}
+		return nil, fmt.Errorf("String cannot represent value: %v", v)
+
+	case Boolean:
+		if rv.Kind() == reflect.Bool {
+			return rv.Bool(), nil
+		}
+		return nil, fmt.Errorf("Boolean cannot represent value: %v", v)
+	}
+
+	return v, nil
+}
+
+// isNull reports whether v is nil, or a nil pointer, map, slice or interface
+func isNull(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+
+	return false
+}
+
+// object is a resolved object of the response, which keeps its fields in the
+// order they were selected
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *object) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+
+	o.values[key] = v
+}
+
+// MarshalJSON implements json.Marshaler
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+
+		val, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,152 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func testSchema(t *testing.T) *Schema {
+	author := &Type{Kind: KindObject, Name: "Author", Fields: []*Field{
+		{Name: "name", Type: NonNull(String)},
+	}}
+
+	post := &Type{Kind: KindObject, Name: "Post", Fields: []*Field{
+		{Name: "id", Type: NonNull(ID)},
+		{Name: "title", Type: String},
+		{Name: "views", Type: Int},
+		{Name: "tags", Type: ListOf(String)},
+		{Name: "author", Type: author},
+	}}
+
+	posts := []interface{}{
+		map[string]interface{}{"id": float64(1), "title": "Ponzu", "views": float64(10), "tags": []interface{}{"citrus"}, "author": map[string]interface{}{"name": "Steve"}},
+		map[string]interface{}{"id": float64(2), "title": "Soy", "views": float64(3), "author": map[string]interface{}{}},
+	}
+
+	query := &Type{Kind: KindObject, Name: "Query", Fields: []*Field{
+		{
+			Name: "post",
+			Type: post,
+			Args: []*Arg{{Name: "id", Type: NonNull(ID)}},
+			Resolve: func(p Params) (interface{}, error) {
+				for _, v := range posts {
+					if id, _ := serialize(ID, v.(map[string]interface{})["id"]); id == p.Args["id"] {
+						return v, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+		{
+			Name: "posts",
+			Type: ListOf(post),
+			Args: []*Arg{{Name: "count", Type: Int, Default: 10}},
+			Resolve: func(p Params) (interface{}, error) {
+				n := p.Args["count"].(int)
+				if n > len(posts) {
+					n = len(posts)
+				}
+				return posts[:n], nil
+			},
+		},
+	}}
+
+	s, err := NewSchema(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func execute(t *testing.T, s *Schema, query string, vars map[string]interface{}) string {
+	j, err := json.Marshal(s.Execute(context.Background(), query, "", vars))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(j)
+}
+
+func TestExecute(t *testing.T) {
+	s := testSchema(t)
+
+	cases := []struct {
+		query string
+		vars  map[string]interface{}
+		want  string
+	}{
+		{
+			`{ post(id: 1) { id title tags } }`,
+			nil,
+			`{"data":{"post":{"id":"1","title":"Ponzu","tags":["citrus"]}}}`,
+		},
+		{
+			`query Q($id: ID!) { first: post(id: $id) { ...fields } }
+			 fragment fields on Post { title author { name } }`,
+			map[string]interface{}{"id": "1"},
+			`{"data":{"first":{"title":"Ponzu","author":{"name":"Steve"}}}}`,
+		},
+		{
+			`query ($count: Int = 1, $more: Boolean = false) { posts(count: $count) { title views @include(if: $more) } }`,
+			nil,
+			`{"data":{"posts":[{"title":"Ponzu"}]}}`,
+		},
+		{
+			`{ posts { __typename ... on Post { id } } }`,
+			nil,
+			`{"data":{"posts":[{"__typename":"Post","id":"1"},{"__typename":"Post","id":"2"}]}}`,
+		},
+		{
+			// a null non-null field makes its parent null
+			`{ post(id: "2") { author { name } } }`,
+			nil,
+			`{"data":{"post":{"author":null}},"errors":[{"message":"Cannot return null for non-nullable field.","path":["post","author","name"]}]}`,
+		},
+		{
+			`{ post(id: 1) { missing } }`,
+			nil,
+			`{"data":{"post":{"missing":null}},"errors":[{"message":"Cannot query field \"missing\" on type \"Post\".","path":["post","missing"]}]}`,
+		},
+	}
+
+	for _, c := range cases {
+		got := execute(t, s, c.query, c.vars)
+		if got != c.want {
+			t.Errorf("query %s:\nexpected %s\ngot      %s", c.query, c.want, got)
+		}
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	s := testSchema(t)
+
+	for _, q := range []string{
+		`{ post(id: 1) { title }`,
+		`mutation { post(id: 1) { title } }`,
+		`{ post { title } }`,
+	} {
+		res := s.Execute(context.Background(), q, "", nil)
+		if len(res.Errors) == 0 {
+			t.Errorf("expected an error for query %s", q)
+		}
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	s := testSchema(t)
+
+	got := execute(t, s, `{ __type(name: "Post") { name kind fields { name type { kind name ofType { name } } } } }`, nil)
+	if !strings.Contains(got, `{"name":"id","type":{"kind":"NON_NULL","name":null,"ofType":{"name":"ID"}}}`) {
+		t.Errorf("unexpected introspection of Post: %s", got)
+	}
+
+	got = execute(t, s, `{ __schema { queryType { name } types { name } directives { name args { name defaultValue } } } }`, nil)
+	for _, want := range []string{`"queryType":{"name":"Query"}`, `{"name":"Author"}`, `{"name":"__Schema"}`, `"name":"include"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %s in schema introspection: %s", want, got)
+		}
+	}
+}
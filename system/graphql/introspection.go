@@ -0,0 +1,253 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// directiveDef describes a directive supported in queries
+type directiveDef struct {
+	name        string
+	description string
+	locations   []string
+	args        []*Arg
+}
+
+var directives = []*directiveDef{
+	{
+		name:        "include",
+		description: "Directs the executor to include this field or fragment only when the `if` argument is true.",
+		locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		args:        []*Arg{{Name: "if", Description: "Included when true.", Type: NonNull(Boolean)}},
+	},
+	{
+		name:        "skip",
+		description: "Directs the executor to skip this field or fragment when the `if` argument is true.",
+		locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		args:        []*Arg{{Name: "if", Description: "Skipped when true.", Type: NonNull(Boolean)}},
+	},
+}
+
+// The types of the introspection system, which describe a Schema to clients
+var (
+	schemaType      = &Type{Kind: KindObject, Name: "__Schema"}
+	typeType        = &Type{Kind: KindObject, Name: "__Type"}
+	fieldType       = &Type{Kind: KindObject, Name: "__Field"}
+	inputValueType  = &Type{Kind: KindObject, Name: "__InputValue"}
+	enumValueType   = &Type{Kind: KindObject, Name: "__EnumValue"}
+	directiveType   = &Type{Kind: KindObject, Name: "__Directive"}
+	typeKindType    = &Type{Kind: KindEnum, Name: "__TypeKind"}
+	directiveLocate = &Type{Kind: KindEnum, Name: "__DirectiveLocation"}
+
+	// schemaField and typeField are the introspection fields of the root
+	// Query type
+	schemaField = &Field{
+		Name: "__schema",
+		Type: NonNull(schemaType),
+		Resolve: func(p Params) (interface{}, error) {
+			return p.Source, nil
+		},
+	}
+
+	typeField = &Field{
+		Name: "__type",
+		Type: typeType,
+		Args: []*Arg{{Name: "name", Type: NonNull(String)}},
+		Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*Schema).Type(p.Args["name"].(string)), nil
+		},
+	}
+)
+
+func init() {
+	typeKindType.EnumValues = []string{
+		string(KindScalar), string(KindObject), "INTERFACE", "UNION", string(KindEnum),
+		string(KindInputObject), string(KindList), string(KindNonNull),
+	}
+
+	directiveLocate.EnumValues = []string{
+		"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION",
+		"FRAGMENT_SPREAD", "INLINE_FRAGMENT", "VARIABLE_DEFINITION",
+	}
+
+	includeDeprecated := []*Arg{{Name: "includeDeprecated", Type: Boolean, Default: false}}
+	notDeprecated := []*Field{
+		{Name: "isDeprecated", Type: NonNull(Boolean), Resolve: func(p Params) (interface{}, error) { return false, nil }},
+		{Name: "deprecationReason", Type: String, Resolve: func(p Params) (interface{}, error) { return nil, nil }},
+	}
+
+	schemaType.Fields = []*Field{
+		{Name: "description", Type: String, Resolve: func(p Params) (interface{}, error) { return nil, nil }},
+		{Name: "types", Type: NonNull(ListOf(NonNull(typeType))), Resolve: func(p Params) (interface{}, error) {
+			s := p.Source.(*Schema)
+			names := append([]string{}, s.names...)
+			sort.Strings(names)
+
+			var types []*Type
+			for _, n := range names {
+				types = append(types, s.types[n])
+			}
+			return types, nil
+		}},
+		{Name: "queryType", Type: NonNull(typeType), Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*Schema).Query, nil
+		}},
+		{Name: "mutationType", Type: typeType, Resolve: func(p Params) (interface{}, error) { return nil, nil }},
+		{Name: "subscriptionType", Type: typeType, Resolve: func(p Params) (interface{}, error) { return nil, nil }},
+		{Name: "directives", Type: NonNull(ListOf(NonNull(directiveType))), Resolve: func(p Params) (interface{}, error) {
+			return directives, nil
+		}},
+	}
+
+	typeType.Fields = []*Field{
+		{Name: "kind", Type: NonNull(typeKindType), Resolve: func(p Params) (interface{}, error) {
+			return string(p.Source.(*Type).Kind), nil
+		}},
+		{Name: "name", Type: String, Resolve: func(p Params) (interface{}, error) {
+			return optional(p.Source.(*Type).Name), nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p Params) (interface{}, error) {
+			return optional(p.Source.(*Type).Description), nil
+		}},
+		{Name: "specifiedByURL", Type: String, Resolve: func(p Params) (interface{}, error) { return nil, nil }},
+		{Name: "fields", Type: ListOf(NonNull(fieldType)), Args: includeDeprecated, Resolve: func(p Params) (interface{}, error) {
+			t := p.Source.(*Type)
+			if t.Kind != KindObject {
+				return nil, nil
+			}
+			return t.Fields, nil
+		}},
+		{Name: "interfaces", Type: ListOf(NonNull(typeType)), Resolve: func(p Params) (interface{}, error) {
+			if p.Source.(*Type).Kind != KindObject {
+				return nil, nil
+			}
+			return []*Type{}, nil
+		}},
+		{Name: "possibleTypes", Type: ListOf(NonNull(typeType)), Resolve: func(p Params) (interface{}, error) { return nil, nil }},
+		{Name: "enumValues", Type: ListOf(NonNull(enumValueType)), Args: includeDeprecated, Resolve: func(p Params) (interface{}, error) {
+			t := p.Source.(*Type)
+			if t.Kind != KindEnum {
+				return nil, nil
+			}
+			return t.EnumValues, nil
+		}},
+		{Name: "inputFields", Type: ListOf(NonNull(inputValueType)), Args: includeDeprecated, Resolve: func(p Params) (interface{}, error) {
+			t := p.Source.(*Type)
+			if t.Kind != KindInputObject {
+				return nil, nil
+			}
+			return t.InputFields, nil
+		}},
+		{Name: "ofType", Type: typeType, Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*Type).OfType, nil
+		}},
+		{Name: "isOneOf", Type: Boolean, Resolve: func(p Params) (interface{}, error) {
+			if p.Source.(*Type).Kind != KindInputObject {
+				return nil, nil
+			}
+			return false, nil
+		}},
+	}
+
+	fieldType.Fields = append([]*Field{
+		{Name: "name", Type: NonNull(String), Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*Field).Name, nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p Params) (interface{}, error) {
+			return optional(p.Source.(*Field).Description), nil
+		}},
+		{Name: "args", Type: NonNull(ListOf(NonNull(inputValueType))), Args: includeDeprecated, Resolve: func(p Params) (interface{}, error) {
+			args := p.Source.(*Field).Args
+			if args == nil {
+				args = []*Arg{}
+			}
+			return args, nil
+		}},
+		{Name: "type", Type: NonNull(typeType), Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*Field).Type, nil
+		}},
+	}, notDeprecated...)
+
+	inputValueType.Fields = append([]*Field{
+		{Name: "name", Type: NonNull(String), Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*Arg).Name, nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p Params) (interface{}, error) {
+			return optional(p.Source.(*Arg).Description), nil
+		}},
+		{Name: "type", Type: NonNull(typeType), Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*Arg).Type, nil
+		}},
+		{Name: "defaultValue", Type: String, Resolve: func(p Params) (interface{}, error) {
+			a := p.Source.(*Arg)
+			if a.Default == nil {
+				return nil, nil
+			}
+			return literal(a.Default), nil
+		}},
+	}, notDeprecated...)
+
+	enumValueType.Fields = append([]*Field{
+		{Name: "name", Type: NonNull(String), Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(string), nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p Params) (interface{}, error) { return nil, nil }},
+	}, notDeprecated...)
+
+	directiveType.Fields = []*Field{
+		{Name: "name", Type: NonNull(String), Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*directiveDef).name, nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p Params) (interface{}, error) {
+			return optional(p.Source.(*directiveDef).description), nil
+		}},
+		{Name: "locations", Type: NonNull(ListOf(NonNull(directiveLocate))), Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*directiveDef).locations, nil
+		}},
+		{Name: "args", Type: NonNull(ListOf(NonNull(inputValueType))), Args: includeDeprecated, Resolve: func(p Params) (interface{}, error) {
+			return p.Source.(*directiveDef).args, nil
+		}},
+		{Name: "isRepeatable", Type: NonNull(Boolean), Resolve: func(p Params) (interface{}, error) { return false, nil }},
+	}
+}
+
+// optional returns s, or nil if it is empty so that it resolves to null
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+// literal writes v as a GraphQL input value, as used for default values
+func literal(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(x)
+	case []interface{}:
+		items := make([]string, 0, len(x))
+		for _, item := range x {
+			items = append(items, literal(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fields := make([]string, 0, len(x))
+		for _, k := range keys {
+			fields = append(fields, k+": "+literal(x[k]))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+
+	return fmt.Sprint(v)
+}
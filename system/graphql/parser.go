@@ -0,0 +1,619 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// document is a parsed query, holding its operations and the fragments they
+// may spread
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string // query, mutation or subscription
+	name       string
+	vars       []*varDef
+	selections []*selection
+}
+
+type varDef struct {
+	name string
+	def  *value
+}
+
+type fragment struct {
+	name       string
+	on         string
+	selections []*selection
+}
+
+// selection is a field, a fragment spread (if spread is set) or an inline
+// fragment (if inline is true)
+type selection struct {
+	alias, name string
+	args        []*argument
+	directives  []*directive
+	selections  []*selection
+
+	spread string
+	inline bool
+	on     string
+}
+
+// key returns the key of a field in the response
+func (s *selection) key() string {
+	if s.alias != "" {
+		return s.alias
+	}
+
+	return s.name
+}
+
+type argument struct {
+	name string
+	val  *value
+}
+
+type directive struct {
+	name string
+	args []*argument
+}
+
+type valueKind int
+
+const (
+	valVar valueKind = iota
+	valInt
+	valFloat
+	valString
+	valBool
+	valNull
+	valEnum
+	valList
+	valObject
+)
+
+type value struct {
+	kind   valueKind
+	raw    string
+	list   []*value
+	fields []*argument
+}
+
+// resolve returns the Go value of v, taking variables from vars
+func (v *value) resolve(vars map[string]interface{}) interface{} {
+	switch v.kind {
+	case valVar:
+		return vars[v.raw]
+	case valInt:
+		n, err := strconv.ParseInt(v.raw, 10, 64)
+		if err != nil {
+			f, _ := strconv.ParseFloat(v.raw, 64)
+			return f
+		}
+		return int(n)
+	case valFloat:
+		f, _ := strconv.ParseFloat(v.raw, 64)
+		return f
+	case valString, valEnum:
+		return v.raw
+	case valBool:
+		return v.raw == "true"
+	case valList:
+		list := make([]interface{}, 0, len(v.list))
+		for _, item := range v.list {
+			list = append(list, item.resolve(vars))
+		}
+		return list
+	case valObject:
+		obj := make(map[string]interface{}, len(v.fields))
+		for _, f := range v.fields {
+			obj[f.name] = f.val.resolve(vars)
+		}
+		return obj
+	}
+
+	return nil
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// parse parses a query document. Type system definitions are not supported.
+func parse(query string) (*document, error) {
+	p := &parser{lex: &lexer{src: query}}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.tok.kind != tokEOF {
+		if p.peek("{") {
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: sels})
+			continue
+		}
+
+		if p.tok.kind != tokName {
+			return nil, p.unexpected()
+		}
+
+		switch p.tok.value {
+		case "query", "mutation", "subscription":
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+
+		case "fragment":
+			f, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := doc.fragments[f.name]; ok {
+				return nil, fmt.Errorf("There can be only one fragment named %q.", f.name)
+			}
+			doc.fragments[f.name] = f
+
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("Must provide an operation.")
+	}
+
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	return fmt.Errorf("Syntax Error: unexpected %s at %d", p.tok, p.tok.pos)
+}
+
+// peek reports whether the current token is the punctuator punct
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.value == punct
+}
+
+// expect consumes the punctuator punct
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return p.unexpected()
+	}
+
+	return p.advance()
+}
+
+// skip consumes the punctuator punct if it is the current token, reporting
+// whether it was
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.peek(punct) {
+		return false, nil
+	}
+
+	return true, p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected()
+	}
+
+	name := p.tok.value
+	return name, p.advance()
+}
+
+// keyword consumes the name kw
+func (p *parser) keyword(kw string) error {
+	if p.tok.kind != tokName || p.tok.value != kw {
+		return p.unexpected()
+	}
+
+	return p.advance()
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.tok.value}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokName {
+		op.name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peek(")") {
+			v, err := p.varDef()
+			if err != nil {
+				return nil, err
+			}
+			op.vars = append(op.vars, v)
+		}
+
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+
+	op.selections, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+func (p *parser) varDef() (*varDef, error) {
+	err := p.expect("$")
+	if err != nil {
+		return nil, err
+	}
+
+	v := &varDef{}
+	v.name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expect(":")
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.typeRef()
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		v.def, err = p.value(true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = p.directives()
+	return v, err
+}
+
+// typeRef consumes the type of a variable. Arguments are coerced to the types
+// of the schema, so the declared type is not kept.
+func (p *parser) typeRef() error {
+	if ok, err := p.skip("["); err != nil {
+		return err
+	} else if ok {
+		err = p.typeRef()
+		if err != nil {
+			return err
+		}
+
+		err = p.expect("]")
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err = p.name()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := p.skip("!")
+	return err
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	err := p.keyword("fragment")
+	if err != nil {
+		return nil, err
+	}
+
+	f := &fragment{}
+	f.name, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.name == "on" {
+		return nil, fmt.Errorf("Syntax Error: a fragment can not be named \"on\"")
+	}
+
+	err = p.keyword("on")
+	if err != nil {
+		return nil, err
+	}
+
+	f.on, err = p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+
+	f.selections, err = p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (p *parser) selectionSet() ([]*selection, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+
+	var sels []*selection
+	for !p.peek("}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, s)
+	}
+
+	if len(sels) == 0 {
+		return nil, p.unexpected()
+	}
+
+	return sels, p.advance()
+}
+
+func (p *parser) selection() (*selection, error) {
+	s := &selection{}
+
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.tok.kind == tokName && p.tok.value != "on" {
+			s.spread, err = p.name()
+			if err != nil {
+				return nil, err
+			}
+
+			s.directives, err = p.directives()
+			return s, err
+		}
+
+		s.inline = true
+		if p.tok.kind == tokName {
+			err = p.advance()
+			if err != nil {
+				return nil, err
+			}
+
+			s.on, err = p.name()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		s.directives, err = p.directives()
+		if err != nil {
+			return nil, err
+		}
+
+		s.selections, err = p.selectionSet()
+		return s, err
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		s.alias = name
+		name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.name = name
+
+	s.args, err = p.arguments(false)
+	if err != nil {
+		return nil, err
+	}
+
+	s.directives, err = p.directives()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek("{") {
+		s.selections, err = p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (p *parser) arguments(constant bool) ([]*argument, error) {
+	ok, err := p.skip("(")
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var args []*argument
+	for !p.peek(")") {
+		arg := &argument{}
+		arg.name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect(":")
+		if err != nil {
+			return nil, err
+		}
+
+		arg.val, err = p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg)
+	}
+
+	return args, p.advance()
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var dirs []*directive
+	for p.peek("@") {
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+
+		d := &directive{}
+		d.name, err = p.name()
+		if err != nil {
+			return nil, err
+		}
+
+		d.args, err = p.arguments(false)
+		if err != nil {
+			return nil, err
+		}
+
+		dirs = append(dirs, d)
+	}
+
+	return dirs, nil
+}
+
+// value parses an input value. Variables are not allowed in constant values,
+// such as the defaults of variables.
+func (p *parser) value(constant bool) (*value, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokInt:
+		return &value{kind: valInt, raw: tok.value}, p.advance()
+
+	case tokFloat:
+		return &value{kind: valFloat, raw: tok.value}, p.advance()
+
+	case tokString:
+		return &value{kind: valString, raw: tok.value}, p.advance()
+
+	case tokName:
+		v := &value{kind: valEnum, raw: tok.value}
+		switch tok.value {
+		case "true", "false":
+			v.kind = valBool
+		case "null":
+			v.kind = valNull
+		}
+		return v, p.advance()
+
+	case tokPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				return nil, p.unexpected()
+			}
+
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+
+			name, err := p.name()
+			return &value{kind: valVar, raw: name}, err
+
+		case "[":
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+
+			v := &value{kind: valList}
+			for !p.peek("]") {
+				item, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				v.list = append(v.list, item)
+			}
+			return v, p.advance()
+
+		case "{":
+			err := p.advance()
+			if err != nil {
+				return nil, err
+			}
+
+			v := &value{kind: valObject}
+			for !p.peek("}") {
+				f := &argument{}
+				f.name, err = p.name()
+				if err != nil {
+					return nil, err
+				}
+
+				err = p.expect(":")
+				if err != nil {
+					return nil, err
+				}
+
+				f.val, err = p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+
+				v.fields = append(v.fields, f)
+			}
+			return v, p.advance()
+		}
+	}
+
+	return nil, p.unexpected()
+}
@@ -0,0 +1,181 @@
+// Package graphql executes read-only GraphQL queries against a schema of
+// object types and their resolvers. It supports the query language used by
+// clients to select fields, including aliases, arguments, variables,
+// fragments, the @include and @skip directives and introspection, but not
+// mutations or subscriptions.
+package graphql
+
+import (
+	"context"
+	"fmt"
+)
+
+// Kind is the kind of a Type, as reported by introspection
+type Kind string
+
+// Kinds of Type
+const (
+	KindScalar      Kind = "SCALAR"
+	KindObject      Kind = "OBJECT"
+	KindInputObject Kind = "INPUT_OBJECT"
+	KindEnum        Kind = "ENUM"
+	KindList        Kind = "LIST"
+	KindNonNull     Kind = "NON_NULL"
+)
+
+// Type is a type of the schema. Object types have Fields, input objects used
+// as arguments have InputFields, and list and non-null types wrap OfType.
+type Type struct {
+	Kind        Kind
+	Name        string
+	Description string
+	Fields      []*Field
+	InputFields []*Arg
+	EnumValues  []string
+	OfType      *Type
+}
+
+// field returns the field of t named name, if any
+func (t *Type) field(name string) *Field {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// String returns the type as it is written in a query, e.g. [String!]
+func (t *Type) String() string {
+	switch t.Kind {
+	case KindList:
+		return "[" + t.OfType.String() + "]"
+	case KindNonNull:
+		return t.OfType.String() + "!"
+	}
+
+	return t.Name
+}
+
+// Field is a field of an object type. If Resolve is nil the value of the field
+// is taken from the parent value, a map[string]interface{}, by its Name.
+type Field struct {
+	Name        string
+	Description string
+	Type        *Type
+	Args        []*Arg
+	Resolve     func(p Params) (interface{}, error)
+}
+
+// Arg is an argument of a field, or a field of an input object. Default is
+// used if the argument is not given, unless it is nil.
+type Arg struct {
+	Name        string
+	Description string
+	Type        *Type
+	Default     interface{}
+}
+
+// Params are passed to a field's Resolve func. Source is the value of the
+// object the field belongs to, and Args holds the field's arguments coerced to
+// their types.
+type Params struct {
+	Context context.Context
+	Source  interface{}
+	Args    map[string]interface{}
+}
+
+// Built-in scalar types
+var (
+	String  = &Type{Kind: KindScalar, Name: "String", Description: "UTF-8 text."}
+	Int     = &Type{Kind: KindScalar, Name: "Int", Description: "A whole number."}
+	Float   = &Type{Kind: KindScalar, Name: "Float", Description: "A double precision floating point number."}
+	Boolean = &Type{Kind: KindScalar, Name: "Boolean", Description: "true or false."}
+	ID      = &Type{Kind: KindScalar, Name: "ID", Description: "A unique identifier, serialized as a string."}
+)
+
+// ListOf returns the type of lists of t
+func ListOf(t *Type) *Type {
+	return &Type{Kind: KindList, OfType: t}
+}
+
+// NonNull returns the type of non-null values of t
+func NonNull(t *Type) *Type {
+	return &Type{Kind: KindNonNull, OfType: t}
+}
+
+// Schema holds the root Query type and every type reachable from it
+type Schema struct {
+	Query *Type
+	types map[string]*Type
+	names []string
+}
+
+// NewSchema returns the Schema of query, an object type whose fields are the
+// entry points of queries. Every named type reachable from query must have a
+// unique name.
+func NewSchema(query *Type) (*Schema, error) {
+	if query == nil || query.Kind != KindObject {
+		return nil, fmt.Errorf("graphql: query must be an object type")
+	}
+
+	s := &Schema{Query: query, types: make(map[string]*Type)}
+	for _, t := range []*Type{String, Int, Float, Boolean, ID, query, schemaType} {
+		err := s.add(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// add adds t and the types of its fields and arguments to s
+func (s *Schema) add(t *Type) error {
+	for t.OfType != nil {
+		t = t.OfType
+	}
+
+	if existing, ok := s.types[t.Name]; ok {
+		if existing != t {
+			return fmt.Errorf("graphql: more than one type named %q", t.Name)
+		}
+		return nil
+	}
+
+	if t.Name == "" {
+		return fmt.Errorf("graphql: named type has no name")
+	}
+
+	s.types[t.Name] = t
+	s.names = append(s.names, t.Name)
+
+	for _, f := range t.Fields {
+		err := s.add(f.Type)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range f.Args {
+			err = s.add(a.Type)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, a := range t.InputFields {
+		err := s.add(a.Type)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Type returns the named type of s, or nil
+func (s *Schema) Type(name string) *Type {
+	return s.types[name]
+}
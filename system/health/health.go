@@ -0,0 +1,109 @@
+// Package health provides the liveness and readiness endpoints which load
+// balancers and orchestrators such as Kubernetes use to check whether Ponzu
+// is running and able to serve requests.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// checks are the components which must pass for Ponzu to be ready, by name
+var checks = struct {
+	sync.RWMutex
+	m map[string]func() error
+}{m: make(map[string]func() error)}
+
+// draining is set once shutdown starts, so that traffic moves elsewhere while
+// in-flight requests complete
+var draining = struct {
+	sync.RWMutex
+	v bool
+}{}
+
+// Register adds a component named name to the readiness check, which is ready
+// while check returns nil
+func Register(name string, check func() error) {
+	checks.Lock()
+	checks.m[name] = check
+	checks.Unlock()
+}
+
+// Drain makes the readiness check fail from now on, as the server is shutting
+// down
+func Drain() {
+	draining.Lock()
+	draining.v = true
+	draining.Unlock()
+}
+
+// status is the JSON body of the health endpoints
+type status struct {
+	Status     string            `json:"status"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+func send(res http.ResponseWriter, code int, s status) {
+	j, err := json.Marshal(s)
+	if err != nil {
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Cache-Control", "no-store")
+	res.WriteHeader(code)
+	res.Write(j)
+}
+
+// Liveness responds 200 OK as long as the process can serve requests at all
+func Liveness(res http.ResponseWriter, req *http.Request) {
+	send(res, http.StatusOK, status{Status: "ok"})
+}
+
+// Readiness responds 200 OK if every registered component passes its check,
+// and 503 Service Unavailable if any fails or the server is shutting down,
+// listing the status of each component
+func Readiness(res http.ResponseWriter, req *http.Request) {
+	checks.RLock()
+	var names []string
+	for name := range checks.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := status{Status: "ok", Components: make(map[string]string)}
+	for _, name := range names {
+		err := checks.m[name]()
+		if err != nil {
+			s.Status = "unavailable"
+			s.Components[name] = err.Error()
+			continue
+		}
+
+		s.Components[name] = "ok"
+	}
+	checks.RUnlock()
+
+	draining.RLock()
+	if draining.v {
+		s.Status = "draining"
+	}
+	draining.RUnlock()
+
+	if s.Status != "ok" {
+		send(res, http.StatusServiceUnavailable, s)
+		return
+	}
+
+	send(res, http.StatusOK, s)
+}
+
+// Handle adds the liveness and readiness endpoints to mux at /healthz and
+// /readyz
+func Handle(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", Liveness)
+	mux.HandleFunc("/readyz", Readiness)
+}
@@ -0,0 +1,38 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadiness(t *testing.T) {
+	ready := func() (int, status) {
+		rec := httptest.NewRecorder()
+		Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		var s status
+		json.Unmarshal(rec.Body.Bytes(), &s)
+		return rec.Code, s
+	}
+
+	var dbErr error
+	Register("db", func() error { return dbErr })
+
+	if code, s := ready(); code != http.StatusOK || s.Components["db"] != "ok" {
+		t.Errorf("expected ready, got %d %v", code, s)
+	}
+
+	dbErr = errors.New("database not open")
+	if code, s := ready(); code != http.StatusServiceUnavailable || s.Components["db"] != "database not open" {
+		t.Errorf("expected unavailable db, got %d %v", code, s)
+	}
+
+	dbErr = nil
+	Drain()
+	if code, s := ready(); code != http.StatusServiceUnavailable || s.Status != "draining" {
+		t.Errorf("expected draining, got %d %v", code, s)
+	}
+}
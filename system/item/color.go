@@ -0,0 +1,52 @@
+package item
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidColor is returned when a value is not a hex color
+var ErrInvalidColor = errors.New("not a valid hex color, e.g. #ff8800")
+
+// Color is a hex color, stored in the normalized #rrggbb form. Fields of type
+// Color are validated when content is saved, and can be edited using the
+// editor.Color field.
+type Color string
+
+// ParseColor returns the Color for a hex color given as #rgb or #rrggbb, with
+// or without the leading #
+func ParseColor(s string) (Color, error) {
+	hex := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	for _, c := range hex {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return "", ErrInvalidColor
+		}
+	}
+
+	switch len(hex) {
+	case 3:
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	case 6:
+	default:
+		return "", ErrInvalidColor
+	}
+
+	return Color("#" + hex), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, so that a Color decoded
+// from JSON is validated and normalized. An empty value is left empty.
+func (c *Color) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*c = ""
+		return nil
+	}
+
+	color, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+
+	*c = color
+	return nil
+}
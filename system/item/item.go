@@ -3,8 +3,12 @@ package item
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 	"unicode"
 
 	uuid "github.com/satori/go.uuid"
@@ -21,6 +25,13 @@ type Sluggable interface {
 	ItemSlug() string
 }
 
+// SlugSource lets a content type name the field its slug is generated from,
+// by the json tag name of the field, instead of generating it from the String
+// method of the content. The field must hold a string.
+type SlugSource interface {
+	SlugField() string
+}
+
 // Identifiable enables a struct to have its ID set/get. Typically this is done
 // to set an ID to -1 indicating it is new for DB inserts, since by default
 // a newly initialized struct would have an ID of 0, the int zero-value, and
@@ -38,6 +49,21 @@ type Sortable interface {
 	Touch() int64
 }
 
+// Orderable is content kept in an order set by hand in the admin, such as the
+// items of a menu, rather than by time. A content type is Orderable when
+// ManuallyOrdered returns true, and Item keeps the position of its content in
+// its Order field. New content is placed after the content saved before it.
+type Orderable interface {
+	ManuallyOrdered() bool
+}
+
+// IsOrderable reports whether it is content of an Orderable type which is
+// manually ordered
+func IsOrderable(it interface{}) bool {
+	o, ok := it.(Orderable)
+	return ok && o.ManuallyOrdered()
+}
+
 // Hookable provides our user with an easy way to intercept or add functionality
 // to the different lifecycles/events a struct may encounter. Item implements
 // Hookable with no-ops so our user can override only whichever ones necessary.
@@ -56,6 +82,22 @@ type Hookable interface {
 
 	BeforeReject(http.ResponseWriter, *http.Request) error
 	AfterReject(http.ResponseWriter, *http.Request) error
+
+	// BeforeAPIResponse is passed the json of a content API response for
+	// the type, in its {"data": [...]} envelope and already translated into
+	// the locale requested, and returns the json to write in its place, such
+	// as with fields hidden or computed. A response built from more of the
+	// request than its URL, Accept and Accept-Language headers should have its
+	// Cache-Control set to private, so it isn't shared from the response
+	// cache. An error stops the response, which the hook should write itself.
+	BeforeAPIResponse(http.ResponseWriter, *http.Request, []byte) ([]byte, error)
+}
+
+// Countable lets a user increment counter fields of content through the API,
+// such as a view or like count. Counters returns the json tag names of the
+// fields which can be incremented, which must hold whole numbers.
+type Countable interface {
+	Counters() []string
 }
 
 // Hideable lets a user keep items hidden
@@ -79,6 +121,252 @@ type Omittable interface {
 	Omit() []string
 }
 
+// Sanitizable lets a content type declare the fields which hold HTML, such as
+// those edited with editor.Richtext, so that they are run through the
+// allowlist of the sanitize package before being saved. All items in the slice
+// should be the json tag names of the struct fields to which they correspond.
+type Sanitizable interface {
+	Sanitize() []string
+}
+
+// Sitemappable lets a content type be listed in the sitemap at /sitemap.xml, by
+// giving the URL at which each item is found on the public site. Types which
+// don't implement it are left out of the sitemap, as their URLs are unknown.
+type Sitemappable interface {
+	// SitemapURL returns the URL of the content, either absolute or a path on
+	// the configured domain, or "" to leave the content out of the sitemap
+	SitemapURL() string
+}
+
+// SitemapHinter lets a Sitemappable content type tell search engines how often
+// its content changes, as one of "always", "hourly", "daily", "weekly",
+// "monthly", "yearly" or "never", and its priority from 0.0 to 1.0 relative to
+// the rest of the site. An empty change frequency or a negative priority is
+// left out of the sitemap.
+type SitemapHinter interface {
+	SitemapChangeFreq() string
+	SitemapPriority() float64
+}
+
+// FeedEntry is an item of content as it appears in an RSS or Atom feed. Link is
+// either absolute or a path on the configured domain, and Published is in unix
+// milliseconds, with 0 using the publish time or timestamp of the content.
+type FeedEntry struct {
+	Title       string
+	Description string
+	Link        string
+	Published   int64
+}
+
+// Feedable lets a content type be served as a feed from /api/feed, by mapping
+// its fields to those of a feed entry
+type Feedable interface {
+	Feed() FeedEntry
+}
+
+// Schedulable lets content be kept out of the public API until a publish time
+// in the future. Item implements Schedulable using its PublishAt field.
+type Schedulable interface {
+	// PublishTime returns the unix time in milliseconds at which the content
+	// becomes public, with 0 meaning it is public immediately
+	PublishTime() int64
+}
+
+// Workflow states of content. Content moves from a draft, to pending review by
+// an editor, to published, and only published content is public.
+const (
+	StateDraft     = "draft"
+	StateReview    = "pending-review"
+	StatePublished = "published"
+)
+
+// States are all of the workflow states of content, in order
+var States = []string{StateDraft, StateReview, StatePublished}
+
+// StateChange records content being moved to State by the admin user with
+// email User, at Time in unix milliseconds
+type StateChange struct {
+	State string `json:"state"`
+	User  string `json:"user"`
+	Time  int64  `json:"time"`
+}
+
+// Workflowable lets content move through the approval workflow, and be kept
+// out of the public API until it is published. Item implements Workflowable
+// using its State and StateHistory fields.
+type Workflowable interface {
+	// WorkflowState returns the state of the content, with content which has
+	// never been through the workflow being published
+	WorkflowState() string
+	WorkflowHistory() []StateChange
+}
+
+// Searchable lets content be indexed for full-text search and found from the
+// search API. Item implements Searchable, keeping content out of the search
+// index unless a content type overrides IndexContent to return true.
+type Searchable interface {
+	IndexContent() bool
+}
+
+// Boostable lets a searchable content type rank matches in some fields above
+// others. SearchBoosts maps the json tag names of fields to the factor the
+// score of a match in the field is multiplied by, e.g. 3 for "title" and 2 for
+// "tags", where fields not in the map have a boost of 1. A boost of a field
+// applies to the fields nested within it, unless they have their own. Boosts
+// are applied as content is searched, so they can be changed without
+// rebuilding the search index. Boosts which are not positive are ignored.
+type Boostable interface {
+	SearchBoosts() map[string]float64
+}
+
+// Referenceable lets a content type declare the fields which refer to other
+// content, so that referenced items can be resolved by the API. References
+// maps the json tag names of the fields to the names of the content types they
+// refer to. A reference is stored as the path of the referenced item in the
+// content API, e.g. /api/content?type=Author&id=1, or a list of such paths.
+type Referenceable interface {
+	References() map[string]string
+}
+
+// RowValidator lets the struct used for the rows of a field group, a slice of
+// structs edited with editor.FieldGroup, check each row before the content
+// containing it is saved. Rows left empty in the editor are dropped instead.
+type RowValidator interface {
+	ValidateRow() error
+}
+
+// Validatable lets a content type check the values of its fields before it is
+// saved, whether from the admin editor, the external content API or an import.
+// Validate returns the problems it finds keyed by the json tag names of the
+// fields they are in, and content with any problems is not saved.
+type Validatable interface {
+	Validate() map[string]error
+}
+
+// ValidationError is returned when content is saved which fails its
+// Validatable check, holding the message of each field's problem
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	var names []string
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var msgs []string
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", name, e.Fields[name]))
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Validate runs the Validatable check of post, returning a *ValidationError if
+// any of its fields have problems
+func Validate(post interface{}) error {
+	v, ok := post.(Validatable)
+	if !ok {
+		return nil
+	}
+
+	fields := make(map[string]string)
+	for name, err := range v.Validate() {
+		if err != nil {
+			fields[name] = err.Error()
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Fields: fields}
+}
+
+// FieldErrors returns the message of each field's problem keyed by field name,
+// if err is a *ValidationError, *FieldError or *RowError, so that they can be
+// shown next to the fields
+func FieldErrors(err error) (map[string]string, bool) {
+	switch e := err.(type) {
+	case *ValidationError:
+		return e.Fields, true
+	case *FieldError:
+		return map[string]string{e.Field: e.Err.Error()}, true
+	case *RowError:
+		return map[string]string{e.Field: fmt.Sprintf("row %d: %v", e.Row+1, e.Err)}, true
+	}
+
+	return nil, false
+}
+
+// RowError is returned when content is saved with a field group row which
+// fails its RowValidator check
+type RowError struct {
+	Field string
+	Row   int
+	Err   error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("%s row %d: %v", e.Field, e.Row+1, e.Err)
+}
+
+// FieldError is returned when content is saved with a value which is not
+// valid for its field
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Field, e.Err)
+}
+
+// ReferencePath returns the path by which the content of type t with id is
+// referenced from other content
+func ReferencePath(t string, id int) string {
+	return fmt.Sprintf("/api/content?type=%s&id=%d", t, id)
+}
+
+// ParseReference returns the type and id of the content referred to by path,
+// e.g. /api/content?type=Author&id=1
+func ParseReference(path string) (t, id string, ok bool) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", "", false
+	}
+
+	q := u.Query()
+	t, id = q.Get("type"), q.Get("id")
+
+	return t, id, t != "" && id != ""
+}
+
+// IsScheduled reports whether it is Schedulable content with a publish time
+// after now, meaning it should not yet be public
+func IsScheduled(it interface{}, now time.Time) bool {
+	s, ok := it.(Schedulable)
+	if !ok {
+		return false
+	}
+
+	return s.PublishTime() > now.UnixNano()/int64(time.Millisecond)
+}
+
+// IsPublic reports whether content should be served by the public API at now,
+// meaning it is published if it is Workflowable and not scheduled to be
+// published after now
+func IsPublic(it interface{}, now time.Time) bool {
+	if w, ok := it.(Workflowable); ok && w.WorkflowState() != StatePublished {
+		return false
+	}
+
+	return !IsScheduled(it, now)
+}
+
 // Item should only be embedded into content type structs.
 type Item struct {
 	UUID      uuid.UUID `json:"uuid"`
@@ -86,6 +374,17 @@ type Item struct {
 	Slug      string    `json:"slug"`
 	Timestamp int64     `json:"timestamp"`
 	Updated   int64     `json:"updated"`
+	PublishAt int64     `json:"publish_at"`
+	Version   int       `json:"version"`
+
+	State        string        `json:"state,omitempty"`
+	StateHistory []StateChange `json:"state_history,omitempty"`
+
+	Translations []Translation `json:"translations,omitempty"`
+
+	Order int `json:"order,omitempty"`
+
+	SchemaVersion int `json:"schema_version,omitempty"`
 }
 
 // Time partially implements the Sortable interface
@@ -98,6 +397,43 @@ func (i Item) Touch() int64 {
 	return i.Updated
 }
 
+// PublishTime implements the Schedulable interface
+func (i Item) PublishTime() int64 {
+	return i.PublishAt
+}
+
+// WorkflowState partially implements the Workflowable interface
+func (i Item) WorkflowState() string {
+	if i.State == "" {
+		return StatePublished
+	}
+
+	return i.State
+}
+
+// WorkflowHistory partially implements the Workflowable interface
+func (i Item) WorkflowHistory() []StateChange {
+	return i.StateHistory
+}
+
+// ItemTranslations returns the translations of the fields of Localizable
+// content into other locales
+func (i Item) ItemTranslations() []Translation {
+	return i.Translations
+}
+
+// OrderIndex returns the position of Orderable content in the order set in
+// the admin
+func (i Item) OrderIndex() int {
+	return i.Order
+}
+
+// IndexContent implements the Searchable interface, and can be overridden to
+// return true so that content of the type is added to the search index
+func (i Item) IndexContent() bool {
+	return false
+}
+
 // SetSlug sets the item's slug for its URL
 func (i *Item) SetSlug(slug string) {
 	i.Slug = slug
@@ -182,13 +518,32 @@ func (i Item) AfterReject(res http.ResponseWriter, req *http.Request) error {
 	return nil
 }
 
-// Slug returns a URL friendly string from the title of a post item
+// BeforeAPIResponse returns data as it is to ensure structs which embed Item
+// implement Hookable
+func (i Item) BeforeAPIResponse(res http.ResponseWriter, req *http.Request, data []byte) ([]byte, error) {
+	return data, nil
+}
+
+// Slug returns a URL friendly string from the title of a post item, which is
+// the field named by its SlugField method if it is a SlugSource and the field
+// isn't empty, otherwise the result of its String method
 func Slug(i Identifiable) (string, error) {
 	// get the name of the post item
 	name := strings.TrimSpace(i.String())
+	if src, ok := i.(SlugSource); ok {
+		if v := strings.TrimSpace(stringField(i, src.SlugField())); v != "" {
+			name = v
+		}
+	}
+
+	return Slugify(name)
+}
 
+// Slugify returns a URL friendly form of s, such as for a slug entered in the
+// editor
+func Slugify(s string) (string, error) {
 	// filter out non-alphanumeric character or non-whitespace
-	slug, err := stringToSlug(name)
+	slug, err := stringToSlug(strings.TrimSpace(s))
 	if err != nil {
 		return "", err
 	}
@@ -196,6 +551,32 @@ func Slug(i Identifiable) (string, error) {
 	return slug, nil
 }
 
+// stringField returns the value of the string field of the struct v with the
+// json tag name, looking in embedded structs too, or "" if there is none
+func stringField(v interface{}, name string) string {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return ""
+	}
+
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.Anonymous && rv.Field(i).CanInterface() {
+			if s := stringField(rv.Field(i).Interface(), name); s != "" {
+				return s
+			}
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if tag == name && rv.Field(i).Kind() == reflect.String {
+			return rv.Field(i).String()
+		}
+	}
+
+	return ""
+}
+
 func isMn(r rune) bool {
 	return unicode.Is(unicode.Mn, r) // Mn: nonspacing marks
 }
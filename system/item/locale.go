@@ -0,0 +1,49 @@
+package item
+
+import (
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Translation is the value of a field of content in a locale other than the
+// default one content is saved in
+type Translation struct {
+	Locale string `json:"locale"`
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+}
+
+// Localizable lets the values of some fields of content be translated into the
+// locales configured in the admin, which the API serves to clients asking for
+// a locale in place of the values saved in the default locale. Localize
+// returns the json tag names of the fields which can be translated, which must
+// hold strings. Item keeps the translations of content in its Translations
+// field.
+type Localizable interface {
+	Localize() []string
+}
+
+// Translate returns the content json data with the values of fields replaced
+// by their translations into locale, and without its translations. Fields
+// without a translation into locale keep their value in the default locale.
+func Translate(data []byte, fields []string, locale string) ([]byte, error) {
+	localized := make(map[string]bool)
+	for _, f := range fields {
+		localized[f] = true
+	}
+
+	var err error
+	for _, t := range gjson.GetBytes(data, "translations").Array() {
+		field, value := t.Get("field").String(), t.Get("value").String()
+		if t.Get("locale").String() != locale || !localized[field] || value == "" {
+			continue
+		}
+
+		data, err = sjson.SetBytes(data, field, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sjson.DeleteBytes(data, "translations")
+}
@@ -0,0 +1,43 @@
+package item
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidLocation is returned when the coordinates of a Location are out of
+// range
+var ErrInvalidLocation = errors.New("latitude must be between -90 and 90, and longitude between -180 and 180")
+
+// earthRadius is the mean radius of the Earth in kilometers
+const earthRadius = 6371.0
+
+// Location is a geographic point, stored as {"lat": 0, "lng": 0}. Fields of
+// type Location are validated when content is saved, and can be edited using
+// the editor.Geolocation field.
+type Location struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// Valid returns ErrInvalidLocation if the coordinates of l are out of range
+func (l Location) Valid() error {
+	if math.IsNaN(l.Lat) || math.IsNaN(l.Lng) ||
+		l.Lat < -90 || l.Lat > 90 || l.Lng < -180 || l.Lng > 180 {
+		return ErrInvalidLocation
+	}
+
+	return nil
+}
+
+// Distance returns the great-circle distance in kilometers between l and to
+func (l Location) Distance(to Location) float64 {
+	lat1, lat2 := l.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (to.Lng - l.Lng) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
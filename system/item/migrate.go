@@ -0,0 +1,68 @@
+package item
+
+import (
+	"encoding/json"
+)
+
+// Migration updates an item of content saved before a change to its content
+// type, decoded from json into a map as by encoding/json, to the shape of the
+// type after the change, such as by renaming, adding or removing fields. It
+// returns the updated item, which may be old itself.
+type Migration func(old map[string]interface{}) map[string]interface{}
+
+// Migratable lets a content type keep the content saved before changes to its
+// struct compatible with it. Migrations returns a migration for each change,
+// oldest first, and only ever has migrations added to its end: content records
+// the number of them it has been through as its schema version, so each is
+// applied once to content saved before it was added. Content is migrated as it
+// is read, and changed in the db by the ponzu migrate command.
+type Migratable interface {
+	Migrations() []Migration
+}
+
+// Migrate applies the migrations which the content json data hasn't been
+// through yet, by its schema version, and returns it at the version of the
+// last. changed holds the index of each migration applied which changed the
+// content. Content up to date is returned as it is.
+func Migrate(data []byte, migrations []Migration) (migrated []byte, changed []int, err error) {
+	var m map[string]interface{}
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	version, _ := m["schema_version"].(float64)
+	if int(version) >= len(migrations) {
+		return data, nil, nil
+	}
+
+	for i := int(version); i < len(migrations); i++ {
+		before, err := json.Marshal(m)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		m = migrations[i](m)
+		if m == nil {
+			m = make(map[string]interface{})
+		}
+
+		after, err := json.Marshal(m)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if string(before) != string(after) {
+			changed = append(changed, i)
+		}
+	}
+
+	m["schema_version"] = len(migrations)
+
+	migrated, err = json.Marshal(m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return migrated, changed, nil
+}
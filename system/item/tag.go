@@ -0,0 +1,10 @@
+package item
+
+// Taggable is content with fields holding tags from the canonical list of tags
+// kept by the db, so that content of any type can be found by tag. TagFields
+// returns the json tag names of the fields, which must hold []string and can be
+// edited with editor.Tags. Tags are saved in the spelling of the canonical tag
+// matching them regardless of case, and new tags join the canonical list.
+type Taggable interface {
+	TagFields() []string
+}
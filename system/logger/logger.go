@@ -0,0 +1,230 @@
+// Package logger provides the leveled logging used throughout Ponzu. By
+// default messages are written with the standard library's log package, as
+// they always have been, and a different Logger, such as one writing JSON for
+// log aggregation, can be set at startup.
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/requestid"
+)
+
+// Level is how important a message is. Messages below the level a Logger is
+// made with are discarded.
+type Level int
+
+const (
+	// LevelDebug is for detail only useful when tracking down a problem
+	LevelDebug Level = iota
+	// LevelInfo is for the normal running of Ponzu, such as servers starting
+	LevelInfo
+	// LevelWarn is for something unexpected which Ponzu can carry on from
+	LevelWarn
+	// LevelError is for a request or task which failed
+	LevelError
+)
+
+var levels = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+
+	return levels[l]
+}
+
+// ParseLevel returns the Level named s, one of debug, info, warn or error
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levels {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return Level(i), nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("unknown log level %q, expected one of %s", s, strings.Join(levels, ", "))
+}
+
+// Logger writes messages at each level. Arguments are formatted as they are
+// by log.Println.
+type Logger interface {
+	Debug(v ...interface{})
+	Info(v ...interface{})
+	Warn(v ...interface{})
+	Error(v ...interface{})
+}
+
+// FieldLogger is a Logger which can add a field, such as the ID of the request
+// being handled, to each message it writes. Loggers which aren't FieldLoggers
+// have fields added to the start of their messages as key=value.
+type FieldLogger interface {
+	Logger
+	With(key, value string) Logger
+}
+
+var (
+	mu     sync.RWMutex
+	active Logger = New(LevelInfo)
+)
+
+// Set replaces the Logger used by Ponzu, and should be called at startup
+// before any servers are run
+func Set(l Logger) {
+	mu.Lock()
+	active = l
+	mu.Unlock()
+}
+
+func current() Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return active
+}
+
+// With returns l adding the field key to each message it writes
+func With(l Logger, key, value string) Logger {
+	if fl, ok := l.(FieldLogger); ok {
+		return fl.With(key, value)
+	}
+
+	return prefixLogger{l: l, prefix: key + "=" + value}
+}
+
+// For returns the Logger to use while handling req, which adds its request ID
+// to each message so that they can be tied to its analytics record and audit
+// entries
+func For(req *http.Request) Logger {
+	id := requestid.Get(req)
+	if id == "" {
+		return current()
+	}
+
+	return With(current(), "request_id", id)
+}
+
+// Debug logs v at LevelDebug
+func Debug(v ...interface{}) { current().Debug(v...) }
+
+// Info logs v at LevelInfo
+func Info(v ...interface{}) { current().Info(v...) }
+
+// Warn logs v at LevelWarn
+func Warn(v ...interface{}) { current().Warn(v...) }
+
+// Error logs v at LevelError
+func Error(v ...interface{}) { current().Error(v...) }
+
+// Fatal logs v at LevelError and exits. It is only for failures at startup
+// which Ponzu can't serve without, such as its db not opening.
+func Fatal(v ...interface{}) {
+	current().Error(v...)
+	os.Exit(1)
+}
+
+// stdLogger writes messages with the standard library's log package, without
+// marking their level, so that the output is the same as it was before levels
+type stdLogger struct {
+	min    Level
+	prefix string
+}
+
+// New returns a Logger which writes messages at min or above with the standard
+// library's log package, so its output, prefix and flags apply
+func New(min Level) Logger {
+	return stdLogger{min: min}
+}
+
+func (l stdLogger) output(level Level, v []interface{}) {
+	if level < l.min {
+		return
+	}
+
+	log.Print(l.prefix + fmt.Sprintln(v...))
+}
+
+func (l stdLogger) With(key, value string) Logger {
+	l.prefix += key + "=" + value + " "
+	return l
+}
+
+func (l stdLogger) Debug(v ...interface{}) { l.output(LevelDebug, v) }
+func (l stdLogger) Info(v ...interface{})  { l.output(LevelInfo, v) }
+func (l stdLogger) Warn(v ...interface{})  { l.output(LevelWarn, v) }
+func (l stdLogger) Error(v ...interface{}) { l.output(LevelError, v) }
+
+// jsonLogger writes each message as a line of JSON
+type jsonLogger struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	min    Level
+	fields map[string]string
+}
+
+// NewJSON returns a Logger which writes messages at min or above to w, each as
+// a line of JSON with its time, level and message, such as:
+// {"level":"error","msg":"Error saving content: timeout","time":"2017-05-01T15:04:05Z"}
+// Fields added with With are written alongside them.
+func NewJSON(w io.Writer, min Level) Logger {
+	return &jsonLogger{mu: &sync.Mutex{}, w: w, min: min}
+}
+
+func (l *jsonLogger) output(level Level, v []interface{}) {
+	if level < l.min {
+		return
+	}
+
+	e := make(map[string]string, len(l.fields)+3)
+	for k, v := range l.fields {
+		e[k] = v
+	}
+	e["time"] = time.Now().UTC().Format(time.RFC3339)
+	e["level"] = level.String()
+	e["msg"] = strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+
+	j, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.w.Write(append(j, '\n'))
+}
+
+func (l *jsonLogger) Debug(v ...interface{}) { l.output(LevelDebug, v) }
+func (l *jsonLogger) Info(v ...interface{})  { l.output(LevelInfo, v) }
+func (l *jsonLogger) Warn(v ...interface{})  { l.output(LevelWarn, v) }
+func (l *jsonLogger) Error(v ...interface{}) { l.output(LevelError, v) }
+
+func (l *jsonLogger) With(key, value string) Logger {
+	fields := make(map[string]string, len(l.fields)+1)
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	fields[key] = value
+
+	return &jsonLogger{mu: l.mu, w: l.w, min: l.min, fields: fields}
+}
+
+// prefixLogger adds a field to the start of the messages of a Logger which
+// isn't a FieldLogger
+type prefixLogger struct {
+	l      Logger
+	prefix string
+}
+
+func (p prefixLogger) Debug(v ...interface{}) { p.l.Debug(append([]interface{}{p.prefix}, v...)...) }
+func (p prefixLogger) Info(v ...interface{})  { p.l.Info(append([]interface{}{p.prefix}, v...)...) }
+func (p prefixLogger) Warn(v ...interface{})  { p.l.Warn(append([]interface{}{p.prefix}, v...)...) }
+func (p prefixLogger) Error(v ...interface{}) { p.l.Error(append([]interface{}{p.prefix}, v...)...) }
@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewJSON(&buf, LevelWarn)
+
+	l.Info("not written")
+	l.Error("Error saving content:", 42)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d: %q", len(lines), buf.String())
+	}
+
+	var e map[string]string
+	err := json.Unmarshal([]byte(lines[0]), &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e["level"] != "error" || e["msg"] != "Error saving content: 42" {
+		t.Errorf("unexpected entry %v", e)
+	}
+
+	buf.Reset()
+	With(l, "request_id", "abc").Warn("Slow request")
+
+	err = json.Unmarshal(buf.Bytes(), &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e["request_id"] != "abc" || e["msg"] != "Slow request" {
+		t.Errorf("unexpected entry with field %v", e)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("WARN")
+	if err != nil || l != LevelWarn {
+		t.Errorf("expected warn, got %v, %v", l, err)
+	}
+
+	_, err = ParseLevel("loud")
+	if err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
@@ -0,0 +1,136 @@
+// Package maintenance provides the middleware which takes the public site and
+// API offline while maintenance mode is on, such as during a deploy or data
+// migration, while the admin stays reachable to turn it back off.
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+// DefaultRetryAfter is the number of seconds clients are told to wait before
+// retrying, if maintenance_retry_after_seconds is not configured
+const DefaultRetryAfter = 300
+
+// DefaultMessage is the message sent to clients while maintenance mode is on,
+// if maintenance_message is not configured
+const DefaultMessage = "This site is down for maintenance. Please try again soon."
+
+// FlagFile is the path of the file which turns maintenance mode on for as long
+// as it exists, relative to the directory Ponzu is run from. It can be created
+// and removed by deploy scripts without access to the admin.
+var FlagFile = "maintenance.flag"
+
+// flagCheckInterval is how often the existence of FlagFile is checked, so that
+// it isn't checked on every request
+const flagCheckInterval = time.Second
+
+// flag is the last result of checking for FlagFile
+var flag = struct {
+	sync.Mutex
+	on      bool
+	checked time.Time
+}{}
+
+// On reports whether maintenance mode is on, either in the config or by
+// FlagFile
+func On() bool {
+	if on, _ := db.ConfigCache("maintenance_mode").(bool); on {
+		return true
+	}
+
+	flag.Lock()
+	defer flag.Unlock()
+
+	if time.Since(flag.checked) >= flagCheckInterval {
+		_, err := os.Stat(FlagFile)
+		flag.on = err == nil
+		flag.checked = time.Now()
+	}
+
+	return flag.on
+}
+
+// exempt reports whether req is served while maintenance mode is on: requests
+// to the admin, so maintenance mode can be turned off, to the health
+// endpoints, so the server isn't restarted as if it had failed, and to uploads
+// by admin users with an active session, so the admin can show them
+func exempt(req *http.Request) bool {
+	path := req.URL.Path
+	if path == "/admin" || strings.HasPrefix(path, "/admin/") ||
+		path == "/healthz" || path == "/readyz" {
+		return true
+	}
+
+	if strings.HasPrefix(path, "/api/uploads/") {
+		_, err := db.CurrentSession(req)
+		return err == nil
+	}
+
+	return false
+}
+
+// Handler responds to every request which is not exempt with 503
+// Service Unavailable while maintenance mode is on, and passes them to next
+// otherwise. API requests get the maintenance_message as json, and other
+// requests the maintenance_page if one is configured, or the message as text.
+// The response has a Retry-After header of maintenance_retry_after_seconds,
+// which is left out if it is negative.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !On() || exempt(req) {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		if after := retryAfter(); after >= 0 {
+			res.Header().Set("Retry-After", strconv.Itoa(after))
+		}
+		res.Header().Set("Cache-Control", "no-store")
+
+		message := DefaultMessage
+		if m, ok := db.ConfigCache("maintenance_message").(string); ok && strings.TrimSpace(m) != "" {
+			message = m
+		}
+
+		if strings.HasPrefix(req.URL.Path, "/api/") {
+			j, err := json.Marshal(map[string]string{"error": message})
+			if err != nil {
+				res.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			res.Header().Set("Content-Type", "application/json")
+			res.WriteHeader(http.StatusServiceUnavailable)
+			res.Write(j)
+			return
+		}
+
+		if page, ok := db.ConfigCache("maintenance_page").(string); ok && strings.TrimSpace(page) != "" {
+			res.Header().Set("Content-Type", "text/html; charset=utf-8")
+			res.WriteHeader(http.StatusServiceUnavailable)
+			res.Write([]byte(page))
+			return
+		}
+
+		res.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		res.WriteHeader(http.StatusServiceUnavailable)
+		res.Write([]byte(message))
+	})
+}
+
+// retryAfter returns the seconds clients are told to wait before retrying
+func retryAfter() int {
+	if n, ok := db.ConfigCache("maintenance_retry_after_seconds").(float64); ok && n != 0 {
+		return int(n)
+	}
+
+	return DefaultRetryAfter
+}
@@ -0,0 +1,82 @@
+package maintenance
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ponzu-maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	FlagFile = filepath.Join(dir, "maintenance.flag")
+	defer func() { FlagFile = "maintenance.flag" }()
+
+	h := Handler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		// check for the flag file again on every request
+		flag.Lock()
+		flag.checked = time.Time{}
+		flag.Unlock()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := serve("/api/contents"); rec.Code != http.StatusOK {
+		t.Errorf("expected requests to be served without the flag file, got %d", rec.Code)
+	}
+
+	err = ioutil.WriteFile(FlagFile, nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := serve("/api/contents")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "300" {
+		t.Errorf("expected 503 with the default Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec.Header().Get("Content-Type") != "application/json" || rec.Body.String() != `{"error":"`+DefaultMessage+`"}` {
+		t.Errorf("expected the message as json for the API, got %q", rec.Body.String())
+	}
+
+	rec = serve("/")
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != DefaultMessage {
+		t.Errorf("expected the message as text outside the API, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	for _, path := range []string{"/admin", "/admin/configure", "/healthz", "/readyz"} {
+		if rec := serve(path); rec.Code != http.StatusOK {
+			t.Errorf("%s: expected to be served in maintenance mode, got %d", path, rec.Code)
+		}
+	}
+
+	if rec := serve("/administrator"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected only the admin to be served, got %d", rec.Code)
+	}
+
+	if rec := serve("/api/uploads/2017/01/image.png"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected uploads to be served only with an admin session, got %d", rec.Code)
+	}
+
+	err = os.Remove(FlagFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := serve("/api/contents"); rec.Code != http.StatusOK {
+		t.Errorf("expected requests to be served once the flag file is removed, got %d", rec.Code)
+	}
+}
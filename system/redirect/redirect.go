@@ -0,0 +1,36 @@
+// Package redirect provides the middleware which sends requests for paths in
+// the redirect table, managed in the admin, to where they have moved.
+package redirect
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+)
+
+// Handler checks each request against the redirect table before passing it to
+// next, and redirects it if its path matches. Requests to the admin are never
+// redirected, so a bad redirect can't lock users out of fixing it.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		path := req.URL.Path
+		if path == "/admin" || strings.HasPrefix(path, "/admin/") {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		r, ok := db.MatchRedirect(path)
+		if !ok {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		to := r.Target(path)
+		if req.URL.RawQuery != "" && !strings.Contains(to, "?") {
+			to += "?" + req.URL.RawQuery
+		}
+
+		http.Redirect(res, req, to, r.Status)
+	})
+}
@@ -0,0 +1,84 @@
+// Package requestid gives each request a correlation ID, taken from its
+// X-Request-ID header or generated, so that the log lines, analytics record
+// and audit entries for a request can be tied together.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header is the request and response header holding the request ID
+const Header = "X-Request-ID"
+
+// maxLength is the longest request ID accepted from a client
+const maxLength = 128
+
+// key stores the request ID in the request context
+type key struct{}
+
+// Handler gives each request handled by next an ID, using the X-Request-ID
+// header of the request if it is set to a valid ID, so that IDs from a proxy
+// or load balancer in front of Ponzu are kept, and otherwise generating one.
+// The ID is set in the X-Request-ID header of the response.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(Header)
+		if !valid(id) {
+			id = generate()
+		}
+
+		res.Header().Set(Header, id)
+		next.ServeHTTP(res, req.WithContext(WithID(req.Context(), id)))
+	})
+}
+
+// WithID returns a copy of ctx holding the request ID id
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, key{}, id)
+}
+
+// FromContext returns the request ID held by ctx, or "" if it has none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(key{}).(string)
+	return id
+}
+
+// Get returns the ID of req, or "" if it wasn't handled by Handler
+func Get(req *http.Request) string {
+	if req == nil {
+		return ""
+	}
+
+	return FromContext(req.Context())
+}
+
+// valid reports whether id can be used as a request ID. IDs are written to
+// logs and headers, so only printable ASCII without spaces or quotes is kept.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if c <= ' ' || c > '~' || c == '"' || c == '\\' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// generate returns a new random request ID
+func generate() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
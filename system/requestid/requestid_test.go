@@ -0,0 +1,40 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var seen string
+	h := Handler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		seen = Get(req)
+	}))
+
+	cases := []struct {
+		header string
+		keep   bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"has space", false},
+	}
+
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/contents", nil)
+		if c.header != "" {
+			req.Header.Set(Header, c.header)
+		}
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if seen == "" || rec.Header().Get(Header) != seen {
+			t.Errorf("%q: expected the response to echo the request ID, got %q and %q", c.header, seen, rec.Header().Get(Header))
+		}
+		if (seen == c.header) != c.keep {
+			t.Errorf("%q: expected keep to be %t, got ID %q", c.header, c.keep, seen)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+// Package sanitize strips HTML, such as the output of the rich text editor, of
+// any tags and attributes not in an allowlist, so that it is safe to store and
+// render as-is.
+package sanitize
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	mu sync.RWMutex
+
+	// tags maps the allowed tags to the attributes allowed on them
+	tags = map[string][]string{
+		"a":          {"href", "target", "rel", "name"},
+		"b":          nil,
+		"blockquote": {"cite"},
+		"br":         nil,
+		"code":       nil,
+		"div":        nil,
+		"em":         nil,
+		"font":       {"color", "face", "size"},
+		"h1":         nil,
+		"h2":         nil,
+		"h3":         nil,
+		"h4":         nil,
+		"h5":         nil,
+		"h6":         nil,
+		"hr":         nil,
+		"i":          nil,
+		"img":        {"src", "alt", "width", "height"},
+		"li":         nil,
+		"ol":         {"start"},
+		"p":          nil,
+		"pre":        nil,
+		"s":          nil,
+		"small":      nil,
+		"span":       nil,
+		"strike":     nil,
+		"strong":     nil,
+		"sub":        nil,
+		"sup":        nil,
+		"table":      nil,
+		"tbody":      nil,
+		"td":         {"colspan", "rowspan"},
+		"th":         {"colspan", "rowspan"},
+		"thead":      nil,
+		"tr":         nil,
+		"u":          nil,
+		"ul":         nil,
+	}
+
+	// global are the attributes allowed on any allowed tag
+	global = []string{"class", "title", "style", "dir"}
+
+	// schemes are the URL schemes allowed in href and src attributes, besides
+	// relative URLs
+	schemes = []string{"http", "https", "mailto"}
+
+	// styles are the CSS properties allowed in style attributes
+	styles = []string{
+		"background-color", "color", "font-family", "font-size", "font-style",
+		"font-weight", "line-height", "margin-left", "text-align",
+		"text-decoration", "vertical-align", "width", "height", "float",
+	}
+)
+
+// dropped are the tags whose content, not just the tag itself, is removed
+// when they are not allowed
+var dropped = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"iframe":   true,
+	"object":   true,
+	"embed":    true,
+	"noscript": true,
+	"noembed":  true,
+	"noframes": true,
+	"template": true,
+	"textarea": true,
+	"title":    true,
+	"xmp":      true,
+}
+
+// void tags have no end tag
+var void = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+var safeStyleValue = regexp.MustCompile(`^[#\w\s.,%'"-]*(\([\d\s.,%]*\))?[#\w\s.,%'"-]*$`)
+
+// Allow adds tag to the allowlist, along with the attributes allowed on it.
+// Attributes are added to those already allowed if the tag is in the list.
+// It should be called before content is saved, i.e. from an init func:
+//	func init() {
+//		sanitize.Allow("iframe", "src", "width", "height", "allowfullscreen")
+//	}
+func Allow(tag string, attrs ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	tag = strings.ToLower(tag)
+	for _, a := range attrs {
+		tags[tag] = append(tags[tag], strings.ToLower(a))
+	}
+	if _, ok := tags[tag]; !ok {
+		tags[tag] = nil
+	}
+}
+
+// AllowURLScheme adds scheme to the URL schemes allowed in href and src
+// attributes
+func AllowURLScheme(scheme string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	schemes = append(schemes, strings.ToLower(scheme))
+}
+
+// AllowStyle adds CSS properties to those allowed in style attributes
+func AllowStyle(properties ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, p := range properties {
+		styles = append(styles, strings.ToLower(p))
+	}
+}
+
+// SafeHTML sanitizes s and returns it as template.HTML, so that it is
+// rendered without escaping by html/template, i.e. in a template.FuncMap:
+//	template.FuncMap{"html": sanitize.SafeHTML}
+func SafeHTML(s string) template.HTML {
+	return template.HTML(HTML(s))
+}
+
+// HTML returns s with any tags, attributes and URLs not in the allowlist
+// removed. The text of removed tags is kept, except for tags such as <script>
+// whose content is removed along with them.
+func HTML(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	out := &bytes.Buffer{}
+	var open []string
+
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			out.WriteString(escapeText(s))
+			break
+		}
+		out.WriteString(escapeText(s[:i]))
+		s = s[i:]
+
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			s = skipPast(s[4:], "-->")
+
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			s = skipPast(s[2:], ">")
+
+		case strings.HasPrefix(s, "</") && len(s) > 2 && isLetter(s[2]):
+			name, rest := readName(s[2:])
+			s = skipPast(rest, ">")
+
+			// close the most recent matching open tag, and any left open
+			// inside of it
+			for j := len(open) - 1; j >= 0; j-- {
+				if open[j] == name {
+					for k := len(open) - 1; k >= j; k-- {
+						out.WriteString("</" + open[k] + ">")
+					}
+					open = open[:j]
+					break
+				}
+			}
+
+		case len(s) > 1 && isLetter(s[1]):
+			name, attrs, rest := readTag(s[1:])
+			s = rest
+
+			allowed, ok := tags[name]
+			if !ok {
+				if dropped[name] {
+					s = skipRawText(s, name)
+				}
+				continue
+			}
+
+			out.WriteString("<" + name)
+			for _, a := range attrs {
+				if !contains(allowed, a.name) && !contains(global, a.name) {
+					continue
+				}
+
+				val, ok := cleanAttr(a.name, a.val)
+				if !ok {
+					continue
+				}
+
+				out.WriteString(" " + a.name + `="` + html.EscapeString(val) + `"`)
+			}
+			out.WriteString(">")
+
+			if !void[name] {
+				open = append(open, name)
+			}
+
+		default:
+			out.WriteString("&lt;")
+			s = s[1:]
+		}
+	}
+
+	for j := len(open) - 1; j >= 0; j-- {
+		out.WriteString("</" + open[j] + ">")
+	}
+
+	return out.String()
+}
+
+type attr struct {
+	name string
+	val  string
+}
+
+// readTag reads the name and attributes of the start tag at the beginning of
+// s, returning what follows it
+func readTag(s string) (string, []attr, string) {
+	name, s := readName(s)
+
+	var attrs []attr
+	for {
+		s = strings.TrimLeft(s, " \t\n\r\f/")
+		if s == "" {
+			return name, attrs, s
+		}
+		if s[0] == '>' {
+			return name, attrs, s[1:]
+		}
+
+		n := strings.IndexAny(s, " \t\n\r\f/>=")
+		if n == 0 {
+			// a stray = with no attribute name
+			s = s[1:]
+			continue
+		}
+		if n < 0 {
+			n = len(s)
+		}
+		a := attr{name: strings.ToLower(s[:n])}
+		s = strings.TrimLeft(s[n:], " \t\n\r\f")
+
+		if strings.HasPrefix(s, "=") {
+			s = strings.TrimLeft(s[1:], " \t\n\r\f")
+
+			if s != "" && (s[0] == '"' || s[0] == '\'') {
+				end := strings.IndexByte(s[1:], s[0])
+				if end < 0 {
+					a.val, s = s[1:], ""
+				} else {
+					a.val, s = s[1:end+1], s[end+2:]
+				}
+			} else {
+				end := strings.IndexAny(s, " \t\n\r\f>")
+				if end < 0 {
+					end = len(s)
+				}
+				a.val, s = s[:end], s[end:]
+			}
+
+			a.val = html.UnescapeString(a.val)
+		}
+
+		attrs = append(attrs, a)
+	}
+}
+
+// readName reads a tag name from the beginning of s
+func readName(s string) (string, string) {
+	n := strings.IndexAny(s, " \t\n\r\f/>")
+	if n < 0 {
+		n = len(s)
+	}
+
+	return strings.ToLower(s[:n]), s[n:]
+}
+
+// cleanAttr returns the value of an allowed attribute, and false if the value
+// itself is not safe
+func cleanAttr(name, val string) (string, bool) {
+	switch {
+	case name == "href" || name == "src" || name == "cite":
+		return val, safeURL(val)
+
+	case name == "style":
+		val = cleanStyle(val)
+		return val, val != ""
+
+	case strings.HasPrefix(name, "on"):
+		return "", false
+	}
+
+	return val, true
+}
+
+// safeURL reports whether u is relative or has an allowed scheme
+func safeURL(u string) bool {
+	// browsers ignore whitespace and control characters within a scheme
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, u)
+
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 || strings.ContainsAny(u[:colon], "/?#") {
+		return true
+	}
+
+	return contains(schemes, strings.ToLower(u[:colon]))
+}
+
+// cleanStyle keeps only the declarations of allowed properties with simple
+// values in the CSS of a style attribute
+func cleanStyle(css string) string {
+	var kept []string
+	for _, decl := range strings.Split(css, ";") {
+		kv := strings.SplitN(decl, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		prop := strings.ToLower(strings.TrimSpace(kv[0]))
+		val := strings.TrimSpace(kv[1])
+		if !contains(styles, prop) || !safeStyleValue.MatchString(val) {
+			continue
+		}
+
+		kept = append(kept, prop+": "+val)
+	}
+
+	return strings.Join(kept, "; ")
+}
+
+// skipRawText returns s after the end tag of name, removing the content of
+// a dropped tag
+func skipRawText(s, name string) string {
+	lower := strings.ToLower(s)
+	i := strings.Index(lower, "</"+name)
+	if i < 0 {
+		return ""
+	}
+
+	return skipPast(s[i:], ">")
+}
+
+// skipPast returns s after the first occurence of sep, or nothing if sep is
+// not found
+func skipPast(s, sep string) string {
+	i := strings.Index(s, sep)
+	if i < 0 {
+		return ""
+	}
+
+	return s[i+len(sep):]
+}
+
+// escapeText escapes text between tags, leaving entities already in it intact
+func escapeText(s string) string {
+	return html.EscapeString(html.UnescapeString(s))
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
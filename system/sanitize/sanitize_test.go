@@ -0,0 +1,45 @@
+package sanitize
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{`<p>Hello <b>world</b></p>`, `<p>Hello <b>world</b></p>`},
+		{`<p onclick="steal()" class="lead">x</p>`, `<p class="lead">x</p>`},
+		{`<script>alert("x")</script><p>ok</p>`, `<p>ok</p>`},
+		{`<SCRIPT src=//evil.js></SCRIPT >ok`, `ok`},
+		{`<a href="javascript:alert(1)">link</a>`, `<a>link</a>`},
+		{`<a href=" java	script:alert(1)">link</a>`, `<a>link</a>`},
+		{`<a href="/docs?a=1&amp;b=2" target="_blank">docs</a>`, `<a href="/docs?a=1&amp;b=2" target="_blank">docs</a>`},
+		{`<img src="https://example.com/x.png" onerror="x()">`, `<img src="https://example.com/x.png">`},
+		{`<span style="color: red; background: url(javascript:x)">red</span>`, `<span style="color: red">red</span>`},
+		{`<svg><circle/></svg>text`, `text`},
+		{`<!-- comment --><p>unclosed <em>tags`, `<p>unclosed <em>tags</em></p>`},
+		{`1 < 2 &amp; 3 > 2`, `1 &lt; 2 &amp; 3 &gt; 2`},
+		{`<p title="a &quot;quote&quot;">x</p>`, `<p title="a &#34;quote&#34;">x</p>`},
+	}
+
+	for _, c := range cases {
+		got := HTML(c.in)
+		if got != c.want {
+			t.Errorf("HTML(%q):\nexpected %q\ngot      %q", c.in, c.want, got)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	in := `<iframe src="https://www.youtube.com/embed/x" width="560"></iframe>`
+	if got := HTML(in); got != "" {
+		t.Fatalf("expected iframe to be removed, got %q", got)
+	}
+
+	Allow("iframe", "src", "width")
+	defer delete(tags, "iframe")
+
+	if got := HTML(in); got != in {
+		t.Errorf("expected %q, got %q", in, got)
+	}
+}
@@ -0,0 +1,205 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a job runs
+type Schedule interface {
+	// Next returns the time the job is next due after t
+	Next(t time.Time) time.Time
+	String() string
+}
+
+// every is a Schedule of a fixed interval
+type every time.Duration
+
+// Every returns a Schedule due each time d has passed since the last time
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+func (e every) String() string {
+	return "every " + time.Duration(e).String()
+}
+
+// everyFunc is a Schedule of an interval which may change, such as one read
+// from the admin config
+type everyFunc func() time.Duration
+
+// EveryFunc returns a Schedule due each time the interval returned by d has
+// passed since the last time, calling d for each interval
+func EveryFunc(d func() time.Duration) Schedule {
+	return everyFunc(d)
+}
+
+func (e everyFunc) Next(t time.Time) time.Time {
+	return t.Add(e())
+}
+
+func (e everyFunc) String() string {
+	return "every " + e().String()
+}
+
+// cron is a Schedule of a cron expression, with the minutes, hours, days of
+// the month, months and days of the week it is due in
+type cron struct {
+	expr                          string
+	minute, hour, dom, month, dow map[int]bool
+	anyDOM, anyDOW                bool
+}
+
+// cronFields are the names and bounds of the fields of a cron expression
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Cron returns the Schedule of the cron expression expr, of five fields:
+// minute, hour, day of the month, month and day of the week (0 is Sunday).
+// Each field is *, a number, a range such as 1-5, a step such as */15 or
+// 0-30/10, or a comma separated list of them. As in cron, a time is due when
+// both the day of the month and the day of the week match if either is *, or
+// else when either matches. Times are in the local time zone.
+func Cron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", expr, len(cronFields))
+	}
+
+	sets := make([]map[int]bool, len(fields))
+	for i, f := range fields {
+		set, err := parseCronField(f, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in cron expression %q: %v", cronFields[i].name, expr, err)
+		}
+
+		sets[i] = set
+	}
+
+	return cron{
+		expr:   expr,
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDOM: fields[2] == "*",
+		anyDOW: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField returns the values from min to max which the cron field f
+// matches
+func parseCronField(f string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(f, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("bad step in %q", part)
+			}
+
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			a, err1 := strconv.Atoi(bounds[0])
+			b, err2 := strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil || a > b {
+				return nil, fmt.Errorf("bad range %q", part)
+			}
+
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("bad value %q", part)
+			}
+
+			lo, hi = n, n
+			if step > 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// Next returns the first whole minute after t which the expression matches,
+// skipping whole months, days and hours which don't match. An expression which
+// never matches, such as one for February 30, is never due.
+func (c cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// every combination of months and days recurs within a few years
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !c.day(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	// far enough that the job never runs
+	return t.AddDate(100, 0, 0)
+}
+
+// day reports whether the day of t matches the expression
+func (c cron) day(t time.Time) bool {
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	if c.anyDOM || c.anyDOW {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+func (c cron) String() string {
+	return c.expr
+}
@@ -0,0 +1,264 @@
+// Package scheduler runs the periodic jobs of Ponzu, and of content and addon
+// code, such as publishing scheduled content and pruning old analytics. Each
+// job runs on its own schedule, an interval or a cron expression, and a run
+// never overlaps another run of the same job. The status of each job is kept
+// for the admin dashboard and metrics.
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+var (
+	// ErrRunning is returned by Run when the job is already running
+	ErrRunning = errors.New("The job is already running")
+	// ErrNotFound is returned by Run for a job which isn't registered
+	ErrNotFound = errors.New("No job is registered with that name")
+)
+
+// Status is the state of a registered job and the outcome of its last run
+type Status struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Running  bool   `json:"running"`
+
+	// Runs counts the runs which finished, Failures those which returned an
+	// error, and Skipped the times the job was due while still running
+	Runs     int `json:"runs"`
+	Failures int `json:"failures"`
+	Skipped  int `json:"skipped"`
+
+	LastRun      time.Time     `json:"last_run"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+	NextRun      time.Time     `json:"next_run"`
+}
+
+// job is a registered job and its status
+type job struct {
+	run      func() error
+	schedule Schedule
+
+	mu     sync.Mutex
+	status Status
+
+	// stop ends the loop running the job on its schedule, which closes
+	// stopped when it returns, and runs waits for a run in progress
+	stop    chan struct{}
+	stopped chan struct{}
+	runs    sync.WaitGroup
+}
+
+// jobs are the registered jobs, by name
+var jobs = struct {
+	sync.Mutex
+	m map[string]*job
+}{m: make(map[string]*job)}
+
+// Register starts running the job named name on schedule s, first at the time
+// s gives after now, replacing any job registered with the same name. A run
+// due while the last is still going is skipped. An error returned by run, or a
+// panic, is logged and kept as the last error of the job.
+func Register(name string, s Schedule, run func() error) {
+	Unregister(name)
+
+	j := &job{
+		run:      run,
+		schedule: s,
+		status:   Status{Name: name, Schedule: s.String()},
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	jobs.Lock()
+	jobs.m[name] = j
+	jobs.Unlock()
+
+	go j.loop()
+}
+
+// Unregister stops running the job named name on its schedule, and waits for a
+// run of it in progress to finish
+func Unregister(name string) {
+	jobs.Lock()
+	j, ok := jobs.m[name]
+	delete(jobs.m, name)
+	jobs.Unlock()
+
+	if !ok {
+		return
+	}
+
+	close(j.stop)
+	<-j.stopped
+	j.runs.Wait()
+}
+
+// Run runs the job named name now, outside of its schedule, and returns the
+// error it returns. It returns ErrRunning without running the job if it is
+// already running.
+func Run(name string) error {
+	jobs.Lock()
+	j, ok := jobs.m[name]
+	jobs.Unlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+
+	if !j.start() {
+		return ErrRunning
+	}
+
+	return j.execute()
+}
+
+// Jobs returns the status of every registered job, in order of name
+func Jobs() []Status {
+	jobs.Lock()
+	var all []*job
+	for _, j := range jobs.m {
+		all = append(all, j)
+	}
+	jobs.Unlock()
+
+	statuses := make([]Status, 0, len(all))
+	for _, j := range all {
+		j.mu.Lock()
+		statuses = append(statuses, j.status)
+		j.mu.Unlock()
+	}
+
+	sort.Slice(statuses, func(a, b int) bool {
+		return statuses[a].Name < statuses[b].Name
+	})
+
+	return statuses
+}
+
+// loop runs the job each time it is due until it is stopped
+func (j *job) loop() {
+	defer close(j.stopped)
+
+	for {
+		next := j.schedule.Next(time.Now())
+
+		j.mu.Lock()
+		j.status.NextRun = next
+		j.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+			if !j.start() {
+				j.mu.Lock()
+				j.status.Skipped++
+				j.mu.Unlock()
+
+				logger.Warn("Skipped scheduled job", j.status.Name, "which is still running from its last run")
+				continue
+			}
+
+			go j.execute()
+
+		case <-j.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// start marks the job running, reporting false if it already is
+func (j *job) start() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.status.Running {
+		return false
+	}
+
+	j.status.Running = true
+	j.runs.Add(1)
+
+	return true
+}
+
+// execute runs the job, which start has marked running, and records the outcome
+func (j *job) execute() (err error) {
+	defer j.runs.Done()
+
+	begin := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+
+		j.mu.Lock()
+		j.status.Running = false
+		j.status.Runs++
+		j.status.LastRun = begin
+		j.status.LastDuration = time.Since(begin)
+		j.status.LastError = ""
+		if err != nil {
+			j.status.Failures++
+			j.status.LastError = err.Error()
+		}
+		name := j.status.Name
+		j.mu.Unlock()
+
+		if err != nil {
+			logger.Error("Scheduled job", name, "failed:", err)
+			return
+		}
+
+		logger.Debug("Scheduled job", name, "finished in", time.Since(begin))
+	}()
+
+	return j.run()
+}
+
+// WriteMetrics writes the runs, failures, skipped runs and last run duration of
+// each job in the Prometheus text exposition format
+func WriteMetrics(w io.Writer) {
+	statuses := Jobs()
+
+	metrics := []struct {
+		name, help, kind string
+		value            func(s Status) string
+	}{
+		{"ponzu_job_runs_total", "Runs of a scheduled job which finished.", "counter", func(s Status) string {
+			return strconv.Itoa(s.Runs)
+		}},
+		{"ponzu_job_failures_total", "Runs of a scheduled job which failed.", "counter", func(s Status) string {
+			return strconv.Itoa(s.Failures)
+		}},
+		{"ponzu_job_skipped_total", "Runs of a scheduled job skipped as it was still running.", "counter", func(s Status) string {
+			return strconv.Itoa(s.Skipped)
+		}},
+		{"ponzu_job_last_duration_seconds", "Duration of the last run of a scheduled job.", "gauge", func(s Status) string {
+			return strconv.FormatFloat(s.LastDuration.Seconds(), 'f', -1, 64)
+		}},
+		{"ponzu_job_running", "Whether a scheduled job is running.", "gauge", func(s Status) string {
+			if s.Running {
+				return "1"
+			}
+			return "0"
+		}},
+	}
+
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+		for _, s := range statuses {
+			fmt.Fprintf(w, "%s{job=%s} %s\n", m.name, strconv.Quote(s.Name), m.value(s))
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package scheduler
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2024, time.March, 15, 10, 7, 30, 0, time.UTC) // a Friday
+
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.March, 15, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.March, 15, 10, 15, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2024, time.March, 16, 3, 0, 0, 0, time.UTC)},
+		{"30 9 1 * *", time.Date(2024, time.April, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 1", time.Date(2024, time.March, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1-5 6 *", time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)},
+		// either the day of the month or the day of the week
+		{"0 0 20 * 6", time.Date(2024, time.March, 16, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range cases {
+		s, err := Cron(c.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expr, err)
+		}
+
+		if got := s.Next(from); !got.Equal(c.want) {
+			t.Errorf("%s: expected %v, got %v", c.expr, c.want, got)
+		}
+	}
+
+	for _, bad := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := Cron(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestRegisterAndRun(t *testing.T) {
+	ran := make(chan struct{}, 10)
+	Register("test.every", Every(10*time.Millisecond), func() error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	defer Unregister("test.every")
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the job to run on its schedule")
+	}
+
+	Register("test.fail", Every(time.Hour), func() error {
+		return errors.New("broken")
+	})
+	defer Unregister("test.fail")
+
+	if err := Run("test.fail"); err == nil || err.Error() != "broken" {
+		t.Errorf("Expected the error of the job, got: %v", err)
+	}
+
+	var failed Status
+	for _, s := range Jobs() {
+		if s.Name == "test.fail" {
+			failed = s
+		}
+	}
+	if failed.Runs != 1 || failed.Failures != 1 || failed.LastError != "broken" || failed.Schedule != "every 1h0m0s" {
+		t.Errorf("Unexpected status of a failed job: %+v", failed)
+	}
+
+	if err := Run("test.missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	WriteMetrics(buf)
+	if !strings.Contains(buf.String(), `ponzu_job_failures_total{job="test.fail"} 1`) {
+		t.Errorf("Expected the failure in the metrics, got:\n%s", buf.String())
+	}
+}
+
+func TestRunDoesNotOverlap(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	Register("test.slow", Every(time.Hour), func() error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	go Run("test.slow")
+	<-started
+
+	if err := Run("test.slow"); err != ErrRunning {
+		t.Errorf("Expected ErrRunning while the job runs, got: %v", err)
+	}
+
+	close(release)
+	Unregister("test.slow")
+
+	for _, s := range Jobs() {
+		if s.Name == "test.slow" {
+			t.Error("Expected the job to be unregistered")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// markup matches html tags, which are removed from content before indexing so
+// that rich text fields are searched by their text only
+var markup = regexp.MustCompile(`<[^>]*>`)
+
+// skipFields are the json keys of content fields which are never indexed
+var skipFields = map[string]bool{
+	"uuid": true,
+}
+
+// tokenize splits text into lowercase words, separated by any character which
+// is not a letter or number
+func tokenize(text string) []string {
+	text = markup.ReplaceAllString(text, " ")
+
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// analyze returns the count of each term in the string fields of the content
+// json data, as made by the analyzer of each field in an, keyed by term and
+// then by field. Nested fields are named by their dot separated path.
+func analyze(data []byte, an analysis) (map[string]map[string]int, error) {
+	var content map[string]interface{}
+	err := json.Unmarshal(data, &content)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := make(map[string]map[string]int)
+	for k, v := range content {
+		if skipFields[k] {
+			continue
+		}
+
+		walk(k, v, terms, an)
+	}
+
+	return terms, nil
+}
+
+// walk adds the terms in v, and any values it contains, to terms for field
+func walk(field string, v interface{}, terms map[string]map[string]int, an analysis) {
+	switch val := v.(type) {
+	case string:
+		for _, word := range an.terms(field, val) {
+			if terms[word] == nil {
+				terms[word] = make(map[string]int)
+			}
+
+			terms[word][field]++
+		}
+
+	case []interface{}:
+		for i := range val {
+			walk(field, val[i], terms, an)
+		}
+
+	case map[string]interface{}:
+		for k := range val {
+			walk(field+"."+k, val[k], terms, an)
+		}
+	}
+}
+
+// facetValues returns the values of the string, number and boolean fields of
+// the content json data, including those within lists, keyed by field. Nested
+// fields are named by their dot separated path.
+func facetValues(data []byte) (map[string][]string, error) {
+	var content map[string]interface{}
+	err := json.Unmarshal(data, &content)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]string)
+	for k, v := range content {
+		if skipFields[k] {
+			continue
+		}
+
+		collect(k, v, values)
+	}
+
+	return values, nil
+}
+
+// collect adds the scalar values in v to values for field
+func collect(field string, v interface{}, values map[string][]string) {
+	switch val := v.(type) {
+	case string:
+		values[field] = append(values[field], val)
+
+	case float64:
+		values[field] = append(values[field], strconv.FormatFloat(val, 'f', -1, 64))
+
+	case bool:
+		values[field] = append(values[field], strconv.FormatBool(val))
+
+	case []interface{}:
+		for i := range val {
+			collect(field, val[i], values)
+		}
+
+	case map[string]interface{}:
+		for k := range val {
+			collect(field+"."+k, val[k], values)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// Operators which compare a field value to the value of a Filter
+const (
+	OpEqual        = "eq"
+	OpGreater      = "gt"
+	OpGreaterEqual = "gte"
+	OpLess         = "lt"
+	OpLessEqual    = "lte"
+)
+
+// dateLayouts are the formats in which values are compared as dates, if they
+// are not numbers
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+
+// Filter matches content where the value of Field compares to Value by Op.
+// Nested fields are named by their dot separated path, and a list field
+// matches if any of its values match.
+type Filter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// ValidOp reports whether op is a supported Filter operator
+func ValidOp(op string) bool {
+	switch op {
+	case OpEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+		return true
+	}
+
+	return false
+}
+
+// Match reports whether the field value v satisfies the filter. Equality is
+// exact. Other operators compare the values as numbers if both are numbers,
+// or as dates if both are dates in RFC 3339 or YYYY-MM-DD form, and otherwise
+// as strings.
+func (f Filter) Match(v string) bool {
+	if f.Op == OpEqual || f.Op == "" {
+		return v == f.Value
+	}
+
+	c := compare(v, f.Value)
+	switch f.Op {
+	case OpGreater:
+		return c > 0
+	case OpGreaterEqual:
+		return c >= 0
+	case OpLess:
+		return c < 0
+	case OpLessEqual:
+		return c <= 0
+	}
+
+	return false
+}
+
+// compare returns -1, 0 or 1 as a is less than, equal to or greater than b
+func compare(a, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+
+		return 0
+	}
+
+	if s, ok := parseDate(a); ok {
+		if t, ok := parseDate(b); ok {
+			switch {
+			case s.Before(t):
+				return -1
+			case s.After(t):
+				return 1
+			}
+
+			return 0
+		}
+	}
+
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// parseDate parses v in any of dateLayouts
+func parseDate(v string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, v)
+		if err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// FilterIDs returns the ids of content of typeName matching every one of
+// filters, read from the index of field values rather than the content, in no
+// particular order
+func FilterIDs(typeName string, filters []Filter) ([]string, error) {
+	if !Searchable(typeName) {
+		return nil, ErrNotSearchable
+	}
+
+	for _, f := range filters {
+		if f.Op != "" && !ValidOp(f.Op) {
+			return nil, fmt.Errorf("Unsupported filter operator: %s", f.Op)
+		}
+	}
+
+	var ids = []string{}
+	if store == nil {
+		return ids, nil
+	}
+
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(typeName))
+		if b == nil {
+			return nil
+		}
+
+		fieldBucket := b.Bucket(fieldsBucket)
+		if fieldBucket == nil {
+			return nil
+		}
+
+		var matched map[string]bool
+		for _, f := range filters {
+			found := filterField(fieldBucket, f)
+
+			// every filter must match, so keep only ids found by all
+			if matched != nil {
+				for id := range matched {
+					if !found[id] {
+						delete(matched, id)
+					}
+				}
+			} else {
+				matched = found
+			}
+
+			if len(matched) == 0 {
+				break
+			}
+		}
+
+		for id := range matched {
+			// translations are filtered with the item they translate
+			if _, locale := splitID(id); locale != "" {
+				continue
+			}
+
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// filterField returns the ids in the fields bucket b with a value of f.Field
+// matching f. Equality seeks directly to the value, and other operators scan
+// only the keys of the field.
+func filterField(b *bolt.Bucket, f Filter) map[string]bool {
+	found := make(map[string]bool)
+
+	prefix := []byte(f.Field + "\x00")
+	if f.Op == OpEqual || f.Op == "" {
+		prefix = []byte(f.Field + "\x00" + f.Value + "\x00")
+	}
+
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		rest := k[len(f.Field)+1:]
+		sep := bytes.LastIndexByte(rest, 0)
+		if sep < 0 {
+			continue
+		}
+
+		if f.Match(string(rest[:sep])) {
+			found[string(rest[sep+1:])] = true
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,293 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFragments is the most fragments returned for each field by Highlight
+const maxFragments = 3
+
+// Highlight returns fragments of the string fields of the content json data
+// which contain words of query or their synonyms, with each matching word
+// wrapped in <mark> tags, keyed by field. Nested fields are named by their dot
+// separated path, and the fields "*" highlights every field with a match.
+// Fragments are the text within about size characters around the matches, or
+// the whole text of the field if size is 0 or less, and markup is removed from
+// them so they can be added to a page as html. Fields without a match are left
+// out.
+func Highlight(data []byte, query string, fields []string, size int) (map[string][]string, error) {
+	return HighlightWithOptions(data, query, QueryOptions{}, fields, size)
+}
+
+// HighlightWithOptions returns highlighted fragments of data as Highlight does,
+// marking the words matching query in the mode of opts
+func HighlightWithOptions(data []byte, query string, opts QueryOptions, fields []string, size int) (map[string][]string, error) {
+	return HighlightType("", data, query, opts, fields, size)
+}
+
+// HighlightType returns highlighted fragments of data, content of typeName, as
+// HighlightWithOptions does, marking the words which match query under the
+// analyzers of the fields of the type set by SetAnalyzers, so that e.g. a
+// query for "sauce" marks "sauces" in a field analyzed as english
+func HighlightType(typeName string, data []byte, query string, opts QueryOptions, fields []string, size int) (map[string][]string, error) {
+	highlights := make(map[string][]string)
+
+	terms := queryTerms(tokenize(query))
+	if len(terms) == 0 || len(fields) == 0 {
+		return highlights, nil
+	}
+
+	var content map[string]interface{}
+	err := json.Unmarshal(data, &content)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make(map[string][]string)
+	for k, v := range content {
+		if skipFields[k] {
+			continue
+		}
+
+		collectText(k, v, texts)
+	}
+
+	all := false
+	wanted := make(map[string]bool)
+	for _, f := range fields {
+		if f == "*" {
+			all = true
+		}
+		wanted[f] = true
+	}
+
+	an := analysisOf(typeName, opts.Locale)
+	matchers := make(map[*analyzer]func(word string) bool)
+	for field, values := range texts {
+		if !all && !wanted[field] {
+			continue
+		}
+
+		a := an.field(field)
+		match, ok := matchers[a]
+		if !ok {
+			match = matcher(an, a, terms, opts)
+			matchers[a] = match
+		}
+
+		var frags []string
+		for _, v := range values {
+			frags = append(frags, fragments(v, match, size, maxFragments-len(frags))...)
+			if len(frags) >= maxFragments {
+				break
+			}
+		}
+
+		if len(frags) > 0 {
+			highlights[field] = frags
+		}
+	}
+
+	return highlights, nil
+}
+
+// collectText adds the string values in v to texts for field
+func collectText(field string, v interface{}, texts map[string][]string) {
+	switch val := v.(type) {
+	case string:
+		texts[field] = append(texts[field], val)
+
+	case []interface{}:
+		for i := range val {
+			collectText(field, val[i], texts)
+		}
+
+	case map[string]interface{}:
+		for k := range val {
+			collectText(field+"."+k, val[k], texts)
+		}
+	}
+}
+
+// span is the byte offsets of a word in a text
+type span struct {
+	start, end int
+}
+
+// matcher returns a func reporting whether a lower case word of content is
+// matched by any of the terms of a query in the mode of opts, as both are
+// analyzed by a
+func matcher(an analysis, a *analyzer, terms []queryTerm, opts QueryOptions) func(word string) bool {
+	words := make(map[string]bool)
+	for _, term := range terms {
+		if t, ok := an.term(a, term.word); ok {
+			words[t] = true
+		}
+	}
+
+	return func(word string) bool {
+		word, ok := an.term(a, word)
+		if !ok {
+			return false
+		}
+
+		if words[word] {
+			return true
+		}
+
+		if opts.Mode == "" || opts.Mode == ModeTerm {
+			return false
+		}
+
+		for q := range words {
+			if _, ok := opts.match(q, word); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// fragments returns up to max highlighted fragments of text around its words
+// which match
+func fragments(text string, match func(word string) bool, size, max int) []string {
+	text = strings.Join(strings.Fields(html.UnescapeString(markup.ReplaceAllString(text, " "))), " ")
+
+	var matches []span
+	for _, s := range wordSpans(text) {
+		if match(strings.ToLower(text[s.start:s.end])) {
+			matches = append(matches, s)
+		}
+	}
+
+	if len(matches) == 0 || max < 1 {
+		return nil
+	}
+
+	if size <= 0 {
+		return []string{mark(text, 0, len(text), matches)}
+	}
+
+	var frags []string
+	for i := 0; i < len(matches) && len(frags) < max; {
+		start, end := window(text, matches[i], size)
+
+		// the next fragment starts after the last match in this one
+		j := i
+		for j < len(matches) && matches[j].end <= end {
+			j++
+		}
+		if j == i {
+			j = i + 1
+		}
+
+		frags = append(frags, mark(text, start, end, matches[i:j]))
+		i = j
+	}
+
+	return frags
+}
+
+// wordSpans returns the spans of the words of text, as tokenize splits them
+func wordSpans(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		if inWord && start < 0 {
+			start = i
+		}
+
+		if !inWord && start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+
+	return spans
+}
+
+// window returns the offsets of about size bytes of text centred on match,
+// moved out to the nearest spaces so that no word is cut in half
+func window(text string, match span, size int) (int, int) {
+	pad := (size - (match.end - match.start)) / 2
+	if pad < 0 {
+		pad = 0
+	}
+
+	start := match.start - pad
+	end := match.end + pad
+	if start < 0 {
+		end -= start
+		start = 0
+	}
+	if end > len(text) {
+		start -= end - len(text)
+		end = len(text)
+	}
+	if start < 0 {
+		start = 0
+	}
+
+	if start > 0 {
+		if i := strings.IndexByte(text[start:match.start], ' '); i >= 0 {
+			start += i + 1
+		} else {
+			start = match.start
+		}
+	}
+
+	if end < len(text) {
+		if i := strings.LastIndexByte(text[match.end:end], ' '); i >= 0 {
+			end = match.end + i
+		} else {
+			end = match.end
+		}
+	}
+
+	// never split a multibyte character
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+
+	return start, end
+}
+
+// mark returns the html escaped text from start to end, with each of matches
+// within it wrapped in <mark> tags, and an ellipsis where text is cut off
+func mark(text string, start, end int, matches []span) string {
+	buf := &bytes.Buffer{}
+	if start > 0 {
+		buf.WriteString("…")
+	}
+
+	at := start
+	for _, m := range matches {
+		if m.start < at || m.end > end {
+			continue
+		}
+
+		buf.WriteString(html.EscapeString(text[at:m.start]))
+		buf.WriteString("<mark>")
+		buf.WriteString(html.EscapeString(text[m.start:m.end]))
+		buf.WriteString("</mark>")
+		at = m.end
+	}
+
+	buf.WriteString(html.EscapeString(text[at:end]))
+	if end < len(text) {
+		buf.WriteString("…")
+	}
+
+	return buf.String()
+}
@@ -0,0 +1,269 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/boltdb/bolt"
+)
+
+// Each content type has a bucket in the index db named by the type, containing
+// four buckets:
+//
+//   - terms, with a key for every word in every item made by postingKey, and a
+//     value of the json encoded count of the word in each field of the item
+//   - docs, with a key for every indexed item id, and a value of the json
+//     encoded list of its words, used to remove its terms when it changes
+//   - values, with a key for every indexed item id, and a value of the json
+//     encoded facetValues of the item, used to count facets of query results
+//   - fields, with an empty value for every key made by fieldKey from the
+//     facetValues of every item, used to filter items by field value
+var (
+	termsBucket  = []byte("terms")
+	docsBucket   = []byte("docs")
+	valuesBucket = []byte("values")
+	fieldsBucket = []byte("fields")
+)
+
+// LocaleID returns the id the translation of the content item id into locale
+// is indexed with, which is searched in place of the item by queries in the
+// locale
+func LocaleID(id, locale string) string {
+	return id + "@" + locale
+}
+
+// splitID returns the id of the content item indexed as id, and the locale it
+// is translated into, which is "" for the item in its default locale
+func splitID(id string) (string, string) {
+	i := strings.IndexByte(id, '@')
+	if i < 0 {
+		return id, ""
+	}
+
+	return id[:i], id[i+1:]
+}
+
+// postingKey returns the terms bucket key for word in the item id. The zero
+// byte separator sorts all keys for a word together, ahead of longer words.
+func postingKey(word, id string) []byte {
+	return []byte(word + "\x00" + id)
+}
+
+// fieldKey returns the fields bucket key for value of field in the item id
+func fieldKey(field, value, id string) []byte {
+	return []byte(field + "\x00" + value + "\x00" + id)
+}
+
+// UpdateIndex adds the content item typeName:id to the search index, replacing
+// anything previously indexed for it. data is the json encoded content. If the
+// type is not searchable, or the index has not been opened with Init, nothing
+// is indexed.
+func UpdateIndex(typeName, id string, data []byte) error {
+	if store == nil || !Searchable(typeName) {
+		return nil
+	}
+
+	_, locale := splitID(id)
+	terms, err := analyze(data, analysisOf(typeName, locale))
+	if err != nil {
+		return err
+	}
+
+	values, err := facetValues(data)
+	if err != nil {
+		return err
+	}
+
+	return write(typeName, id, func(tx *bolt.Tx) error {
+		return indexDoc(tx, typeName, id, terms, values)
+	})
+}
+
+// indexDoc adds the terms and facet values of the content item typeName:id to
+// the index in the transaction tx, replacing anything indexed for it
+func indexDoc(tx *bolt.Tx, typeName, id string, terms map[string]map[string]int, values map[string][]string) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(typeName))
+	if err != nil {
+		return err
+	}
+
+	err = removeDoc(b, id)
+	if err != nil {
+		return err
+	}
+
+	termBucket, err := b.CreateBucketIfNotExists(termsBucket)
+	if err != nil {
+		return err
+	}
+
+	var words []string
+	for word, fields := range terms {
+		j, err := json.Marshal(fields)
+		if err != nil {
+			return err
+		}
+
+		err = termBucket.Put(postingKey(word, id), j)
+		if err != nil {
+			return err
+		}
+
+		words = append(words, word)
+	}
+
+	docBucket, err := b.CreateBucketIfNotExists(docsBucket)
+	if err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(words)
+	if err != nil {
+		return err
+	}
+
+	err = docBucket.Put([]byte(id), j)
+	if err != nil {
+		return err
+	}
+
+	valueBucket, err := b.CreateBucketIfNotExists(valuesBucket)
+	if err != nil {
+		return err
+	}
+
+	j, err = json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	err = valueBucket.Put([]byte(id), j)
+	if err != nil {
+		return err
+	}
+
+	fieldBucket, err := b.CreateBucketIfNotExists(fieldsBucket)
+	if err != nil {
+		return err
+	}
+
+	for field, vals := range values {
+		for _, val := range vals {
+			err := fieldBucket.Put(fieldKey(field, val, id), []byte{})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Drop removes the whole index of the content type typeName, such as once it
+// is no longer searchable
+func Drop(typeName string) error {
+	if store == nil {
+		return nil
+	}
+
+	return store.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte(typeName))
+		if err == bolt.ErrBucketNotFound {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// DeleteIndex removes the content item typeName:id from the search index,
+// along with its translations into other locales
+func DeleteIndex(typeName, id string) error {
+	if store == nil {
+		return nil
+	}
+
+	return write(typeName, id, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(typeName))
+		if b == nil {
+			return nil
+		}
+
+		ids := []string{id}
+		if docBucket := b.Bucket(docsBucket); docBucket != nil {
+			prefix := []byte(LocaleID(id, ""))
+			c := docBucket.Cursor()
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				ids = append(ids, string(k))
+			}
+		}
+
+		for _, id := range ids {
+			err := removeDoc(b, id)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// removeDoc deletes the terms, docs, values and fields entries of id from the
+// type bucket b
+func removeDoc(b *bolt.Bucket, id string) error {
+	if valueBucket := b.Bucket(valuesBucket); valueBucket != nil {
+		if v := valueBucket.Get([]byte(id)); v != nil {
+			var values map[string][]string
+			err := json.Unmarshal(v, &values)
+			if err != nil {
+				return err
+			}
+
+			if fieldBucket := b.Bucket(fieldsBucket); fieldBucket != nil {
+				for field, vals := range values {
+					for _, val := range vals {
+						err := fieldBucket.Delete(fieldKey(field, val, id))
+						if err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+
+		err := valueBucket.Delete([]byte(id))
+		if err != nil {
+			return err
+		}
+	}
+
+	docBucket := b.Bucket(docsBucket)
+	if docBucket == nil {
+		return nil
+	}
+
+	v := docBucket.Get([]byte(id))
+	if v == nil {
+		return nil
+	}
+
+	var words []string
+	err := json.Unmarshal(v, &words)
+	if err != nil {
+		return err
+	}
+
+	termBucket := b.Bucket(termsBucket)
+	if termBucket != nil {
+		for _, word := range words {
+			err := termBucket.Delete(postingKey(word, id))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return docBucket.Delete([]byte(id))
+}
@@ -0,0 +1,407 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// analyzer turns the words of text into the terms they are indexed and
+// searched by, leaving out the stop words of its language and reducing the
+// rest to their stem, so that e.g. "sauces" finds "sauce"
+type analyzer struct {
+	stopWords map[string]bool
+	stem      func(word string) string
+}
+
+// standard is the analyzer of content without one set by SetAnalyzers, which
+// indexes every word as it is
+var standard = &analyzer{stem: func(word string) string { return word }}
+
+// languages are the analyzers which can be set by SetAnalyzers, by name
+var languages = map[string]*analyzer{
+	"standard": standard,
+	"english": {
+		stopWords: wordSet("a an and are as at be but by for from has have he her his i if in into is it its me my no not of on or our she so than that the their them then there these they this to was we were what when which who will with you your s t"),
+		stem:      stemEnglish,
+	},
+	"french": {
+		stopWords: wordSet("à au aux avec c ce ces d dans de des du elle en est et été eux il ils j je l la le les leur lui m ma mais me même mes moi mon n ne nos notre nous on ou où par pas pour qu que qui s sa se ses son sont sur t ta te tes toi ton tu un une vos votre vous y"),
+		stem:      stemFrench,
+	},
+	"german": {
+		stopWords: wordSet("aber als am an auch auf aus bei bin bis da das dass dem den der des die doch du ein eine einem einen einer eines er es für hat ich ihr im in ist ja kein mit nach nicht noch nur oder sie sind so über um und uns von vor war was wie wir zu zum zur"),
+		stem:      stemGerman,
+	},
+	"spanish": {
+		stopWords: wordSet("a al algo con de del el ella ellos en entre era es esta este esto está ha la las le les lo los más me mi muy no nos o para pero por que qué se sin sobre su sus también te tu un una uno unos y ya yo"),
+		stem:      stemSpanish,
+	},
+	"italian": {
+		stopWords: wordSet("a ad al alla alle anche che chi ci come con da dal dalla degli dei del della delle di e è gli ha i il in io la le lei lo lui ma mi ne nel nella non o per più quello questo se si sono su sua suo tra tu un una uno"),
+		stem:      stemItalian,
+	},
+	"portuguese": {
+		stopWords: wordSet("a à ao aos as com como da das de do dos e é ela ele eles em entre era essa esse esta este eu foi já mais mas me meu na não nas no nos o os ou para pela pelo por que se sem seu são sua também te um uma uns"),
+		stem:      stemPortuguese,
+	},
+}
+
+// analyzerKey is what an analyzer is set for by SetAnalyzers: a content type,
+// or "*" for every type, and optionally a field of it and a locale
+type analyzerKey struct {
+	typeName, field, locale string
+}
+
+// analyzers are the analyzers set by SetAnalyzers and the stop words set by
+// SetStopWords. Both maps are replaced rather than changed, so that a copy of
+// them can be read without the lock.
+var analyzers = struct {
+	sync.RWMutex
+	rules     map[analyzerKey]*analyzer
+	stopWords map[string]bool
+}{}
+
+// SetAnalyzers sets the language analyzers of the content searched, replacing
+// those set before, from rules of the form
+//
+//	Type=language
+//	Type.field=language
+//	Type@locale=language
+//
+// where Type is a content type, or * for every type, field is the dot separated
+// path of a nested field and locale a locale content is translated into, e.g.
+//
+//	Post=english
+//	Post.title@fr=french
+//	*@de=german
+//
+// The languages are english, french, german, spanish, italian and portuguese,
+// and standard, the default, which indexes every word as it is. A field without
+// a rule of its own uses that of the closest field it is nested within, and
+// then that of its type. A rule for a locale applies before the rules without
+// one, and a rule for a type before those for every type.
+//
+// Analyzers apply to content as it is indexed, so the index of the content
+// types they change should be rebuilt. An error is returned for the first
+// invalid rule, but every valid rule is set.
+func SetAnalyzers(rules []string) error {
+	parsed := make(map[analyzerKey]*analyzer)
+	var invalid error
+	for _, rule := range rules {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		key, a, ok := parseAnalyzer(rule)
+		if !ok {
+			if invalid == nil {
+				invalid = fmt.Errorf("invalid search analyzer rule: %q", rule)
+			}
+			continue
+		}
+
+		parsed[key] = a
+	}
+
+	analyzers.Lock()
+	analyzers.rules = parsed
+	analyzers.Unlock()
+
+	return invalid
+}
+
+// parseAnalyzer returns what a single rule of SetAnalyzers sets, and the
+// analyzer it sets it to
+func parseAnalyzer(rule string) (analyzerKey, *analyzer, bool) {
+	var key analyzerKey
+	i := strings.IndexByte(rule, '=')
+	if i < 0 {
+		return key, nil, false
+	}
+
+	a, ok := languages[strings.ToLower(strings.TrimSpace(rule[i+1:]))]
+	if !ok {
+		return key, nil, false
+	}
+
+	target := strings.TrimSpace(rule[:i])
+	if j := strings.IndexByte(target, '@'); j >= 0 {
+		target, key.locale = target[:j], target[j+1:]
+		if key.locale == "" {
+			return key, nil, false
+		}
+	}
+
+	key.typeName = target
+	if j := strings.IndexByte(target, '.'); j >= 0 {
+		key.typeName, key.field = target[:j], target[j+1:]
+		if key.field == "" {
+			return key, nil, false
+		}
+	}
+
+	return key, a, key.typeName != ""
+}
+
+// SetStopWords sets words which are left out of the index and of queries by
+// every analyzer, in addition to the stop words of its language, replacing
+// those set before. The index of every content type should be rebuilt once
+// they change.
+func SetStopWords(words []string) {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		for _, word := range tokenize(w) {
+			m[word] = true
+		}
+	}
+
+	analyzers.Lock()
+	analyzers.stopWords = m
+	analyzers.Unlock()
+}
+
+// analysis is the analyzers of the fields of a content type in a locale
+type analysis struct {
+	rules     map[analyzerKey]*analyzer
+	stopWords map[string]bool
+	typeName  string
+	locale    string
+}
+
+// analysisOf returns the analysis of the content of typeName translated into
+// locale, or in the default locale if it is empty. An empty typeName analyzes
+// every field with the standard analyzer.
+func analysisOf(typeName, locale string) analysis {
+	analyzers.RLock()
+	defer analyzers.RUnlock()
+
+	an := analysis{stopWords: analyzers.stopWords, typeName: typeName, locale: locale}
+	if typeName != "" {
+		an.rules = analyzers.rules
+	}
+
+	return an
+}
+
+// field returns the analyzer of field
+func (an analysis) field(field string) *analyzer {
+	if len(an.rules) == 0 {
+		return standard
+	}
+
+	locales := []string{an.locale}
+	if an.locale != "" {
+		locales = append(locales, "")
+	}
+
+	for _, l := range locales {
+		for _, t := range []string{an.typeName, "*"} {
+			for f := field; ; {
+				if a, ok := an.rules[analyzerKey{t, f, l}]; ok {
+					return a
+				}
+
+				if f == "" {
+					break
+				}
+
+				i := strings.LastIndexByte(f, '.')
+				if i < 0 {
+					i = 0
+				}
+				f = f[:i]
+			}
+		}
+	}
+
+	return standard
+}
+
+// used returns every analyzer a field of the type could be analyzed by
+func (an analysis) used() []*analyzer {
+	seen := map[*analyzer]bool{standard: true}
+	found := []*analyzer{standard}
+	for key, a := range an.rules {
+		if key.typeName != an.typeName && key.typeName != "*" {
+			continue
+		}
+
+		if key.locale != an.locale && key.locale != "" {
+			continue
+		}
+
+		if !seen[a] {
+			seen[a] = true
+			found = append(found, a)
+		}
+	}
+
+	return found
+}
+
+// term returns the term a lower case word is indexed and searched by with a,
+// or false if it is a stop word
+func (an analysis) term(a *analyzer, word string) (string, bool) {
+	if an.stopWords[word] || a.stopWords[word] {
+		return "", false
+	}
+
+	return a.stem(word), true
+}
+
+// terms returns the terms of the words of text in field
+func (an analysis) terms(field, text string) []string {
+	a := an.field(field)
+
+	var terms []string
+	for _, word := range tokenize(text) {
+		if t, ok := an.term(a, word); ok {
+			terms = append(terms, t)
+		}
+	}
+
+	return terms
+}
+
+// wordSet returns the space separated words as a set
+func wordSet(words string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		m[w] = true
+	}
+
+	return m
+}
+
+// accents replaces the accented letters of latin scripts with the letters they
+// are based on, so that a word is found whether or not it is written with them
+var accents = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c",
+	"è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i",
+	"ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u",
+	"ý", "y", "ÿ", "y",
+	"ß", "ss",
+)
+
+// The stemmers below are light stemmers: they remove the inflections which
+// most often differ between forms of the same word, such as plurals and
+// gender, rather than reducing words to their linguistic root. This keeps
+// unrelated words apart at the cost of finding fewer forms of each word.
+
+// stemEnglish removes the plural, -ing, -ed and final e of an english word
+func stemEnglish(w string) string {
+	switch {
+	case strings.HasSuffix(w, "sses"):
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		w = w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "s") && len(w) > 3 &&
+		!strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is"):
+		w = w[:len(w)-1]
+	}
+
+	for _, suffix := range []string{"ing", "ed"} {
+		stem := strings.TrimSuffix(w, suffix)
+		if stem != w && len(stem) >= 3 && strings.ContainsAny(stem, "aeiouy") {
+			w = stem
+
+			// running => run
+			n := len(w)
+			if w[n-1] == w[n-2] && !strings.ContainsRune("aeiouylsz", rune(w[n-1])) {
+				w = w[:n-1]
+			}
+			break
+		}
+	}
+
+	if strings.HasSuffix(w, "e") && len(w) > 3 {
+		w = w[:len(w)-1]
+	}
+
+	return w
+}
+
+// stemFrench removes the plural and feminine endings of a french word
+func stemFrench(w string) string {
+	w = accents.Replace(w)
+
+	switch {
+	case strings.HasSuffix(w, "aux") && len(w) > 5:
+		w = w[:len(w)-3] + "al"
+	case (strings.HasSuffix(w, "s") || strings.HasSuffix(w, "x")) && len(w) > 3:
+		w = w[:len(w)-1]
+	}
+
+	for strings.HasSuffix(w, "e") && len(w) > 3 {
+		w = w[:len(w)-1]
+	}
+
+	return w
+}
+
+// stemGerman removes the plural and case endings of a german word
+func stemGerman(w string) string {
+	w = accents.Replace(w)
+
+	// a final s or n is only removed from longer words, since it is more
+	// often part of the word itself
+	for _, s := range []struct {
+		suffix string
+		min    int
+	}{{"ern", 3}, {"em", 3}, {"en", 3}, {"er", 3}, {"es", 3}, {"e", 3}, {"s", 4}, {"n", 4}} {
+		if strings.HasSuffix(w, s.suffix) && len(w)-len(s.suffix) >= s.min {
+			return w[:len(w)-len(s.suffix)]
+		}
+	}
+
+	return w
+}
+
+// stemSpanish removes the plural and gender endings of a spanish word
+func stemSpanish(w string) string {
+	w = accents.Replace(w)
+
+	switch {
+	case strings.HasSuffix(w, "es") && len(w) > 4 && !strings.ContainsRune("aeiou", rune(w[len(w)-3])):
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "s") && len(w) > 3:
+		w = w[:len(w)-1]
+	}
+
+	return trimVowel(w, "aoe")
+}
+
+// stemItalian removes the plural and gender endings of an italian word
+func stemItalian(w string) string {
+	return trimVowel(accents.Replace(w), "aeio")
+}
+
+// stemPortuguese removes the plural and gender endings of a portuguese word
+func stemPortuguese(w string) string {
+	w = accents.Replace(w)
+
+	switch {
+	case (strings.HasSuffix(w, "res") || strings.HasSuffix(w, "zes")) && len(w) > 4:
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "s") && len(w) > 3:
+		w = w[:len(w)-1]
+	}
+
+	return trimVowel(w, "aoe")
+}
+
+// trimVowel removes the last letter of w if it is one of vowels, unless w is
+// short
+func trimVowel(w, vowels string) string {
+	if len(w) > 3 && strings.ContainsRune(vowels, rune(w[len(w)-1])) {
+		return w[:len(w)-1]
+	}
+
+	return w
+}
@@ -0,0 +1,214 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/boltdb/bolt"
+)
+
+// Mode is how the words of a query match the words of content
+type Mode string
+
+const (
+	// ModeTerm matches words exactly, ignoring case. It is the default, and
+	// the fastest and most precise mode.
+	ModeTerm Mode = "term"
+
+	// ModePrefix matches words which start with a word of the query, so that
+	// results can be suggested as the query is typed. Each word of the query
+	// is looked up with a single seek in the sorted index, which keeps it
+	// nearly as fast as ModeTerm, but a short word matches many and only the
+	// first maxExpansions of them, in alphabetical order, are searched.
+	ModePrefix Mode = "prefix"
+
+	// ModeFuzzy also matches words within an edit distance of a word of the
+	// query, so that typos still find content. Every distinct word of the
+	// type is compared with each word of the query, which makes it the
+	// slowest mode, and it finds more loosely related content.
+	ModeFuzzy Mode = "fuzzy"
+)
+
+// DefaultFuzziness and MaxFuzziness are the default and largest edit distance
+// of words matched in ModeFuzzy
+const (
+	DefaultFuzziness = 1
+	MaxFuzziness     = 2
+)
+
+// maxExpansions is the most words of content matched by each word of a query
+// in ModePrefix and ModeFuzzy
+const maxExpansions = 64
+
+// minFuzzyLength is the length in characters below which words of a query are
+// only matched exactly in ModeFuzzy, since almost any short word is within an
+// edit or two of another
+const minFuzzyLength = 3
+
+// ErrInvalidQuery is returned for QueryOptions of an unknown Mode or a
+// Fuzziness out of range
+var ErrInvalidQuery = errors.New("Invalid search mode or fuzziness")
+
+// QueryOptions are how a query is matched against content
+type QueryOptions struct {
+	// Locale is the locale content is searched in, or the default locale if
+	// empty
+	Locale string
+
+	// Mode is how words are matched, ModeTerm if empty
+	Mode Mode
+
+	// Fuzziness is the largest number of single character insertions,
+	// deletions or substitutions between words matched in ModeFuzzy, up to
+	// MaxFuzziness. DefaultFuzziness is used if 0.
+	Fuzziness int
+}
+
+// Valid reports whether o has a known Mode and a Fuzziness in range
+func (o QueryOptions) Valid() bool {
+	switch o.Mode {
+	case "", ModeTerm, ModePrefix, ModeFuzzy:
+	default:
+		return false
+	}
+
+	return o.Fuzziness >= 0 && o.Fuzziness <= MaxFuzziness
+}
+
+// fuzziness returns the edit distance words are matched within
+func (o QueryOptions) fuzziness() int {
+	if o.Fuzziness == 0 {
+		return DefaultFuzziness
+	}
+
+	return o.Fuzziness
+}
+
+// match reports whether the word of content matches the word of a query, and
+// how closely as a weight from 0 to 1 applied to its score, 1 being an exact
+// match
+func (o QueryOptions) match(query, word string) (float64, bool) {
+	if query == word {
+		return 1, true
+	}
+
+	switch o.Mode {
+	case ModePrefix:
+		if len(word) > len(query) && word[:len(query)] == query {
+			return float64(len(query)) / float64(len(word)), true
+		}
+
+	case ModeFuzzy:
+		if utf8.RuneCountInString(query) < minFuzzyLength {
+			return 0, false
+		}
+
+		if d := distance(query, word, o.fuzziness()); d >= 0 {
+			return 1 / float64(1+d), true
+		}
+	}
+
+	return 0, false
+}
+
+// expansion is a word of content matched by a word of a query
+type expansion struct {
+	word   string
+	weight float64
+}
+
+// expand returns the words in the terms bucket b matched by the word of a
+// query, skipping over the postings of each word so that every distinct word
+// is read once
+func (o QueryOptions) expand(b *bolt.Bucket, query string) []expansion {
+	switch o.Mode {
+	case ModePrefix, ModeFuzzy:
+	default:
+		return []expansion{{word: query, weight: 1}}
+	}
+
+	start := []byte(nil)
+	if o.Mode == ModePrefix {
+		start = []byte(query)
+	}
+
+	var found []expansion
+	c := b.Cursor()
+	k, _ := c.First()
+	if start != nil {
+		k, _ = c.Seek(start)
+	}
+
+	for ; k != nil && len(found) < maxExpansions; k, _ = c.Seek([]byte(string(k) + "\x01")) {
+		if start != nil && !bytes.HasPrefix(k, start) {
+			break
+		}
+
+		if i := bytes.IndexByte(k, 0); i >= 0 {
+			k = k[:i]
+		}
+
+		word := string(k)
+		if w, ok := o.match(query, word); ok {
+			found = append(found, expansion{word: word, weight: w})
+		}
+	}
+
+	return found
+}
+
+// distance returns the Levenshtein distance between a and b, or -1 if it is
+// more than max
+func distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if n := len(ra) - len(rb); n > max || -n > max {
+		return -1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		best := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if cur[j] < best {
+				best = cur[j]
+			}
+		}
+
+		// every path through the rest of the table costs at least best
+		if best > max {
+			return -1
+		}
+
+		prev, cur = cur, prev
+	}
+
+	if prev[len(rb)] > max {
+		return -1
+	}
+
+	return prev[len(rb)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+
+	return a
+}
@@ -0,0 +1,390 @@
+package search
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+
+	"github.com/boltdb/bolt"
+)
+
+// Result is a content item matching a search query
+type Result struct {
+	Type  string  `json:"type"`
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// TypeQuery returns the ids of content of typeName matching query, most
+// relevant first. Content matches if it contains any word in the query. count
+// and offset paginate the results as in db.Query: a count of -1 returns every
+// match, and offset is a multiplier of count.
+func TypeQuery(typeName, query string, count, offset int) ([]string, error) {
+	return TypeQueryLocale(typeName, query, "", count, offset)
+}
+
+// TypeQueryLocale returns the ids of content of typeName matching query in
+// locale, as TypeQuery does. Content translated into locale is matched by its
+// translated values, and content which isn't by its values in the default
+// locale. An empty locale is the default locale.
+func TypeQueryLocale(typeName, query, locale string, count, offset int) ([]string, error) {
+	return TypeQueryWithOptions(typeName, query, QueryOptions{Locale: locale}, count, offset)
+}
+
+// TypeQueryWithOptions returns the ids of content of typeName matching query
+// as opts selects, as TypeQueryLocale does
+func TypeQueryWithOptions(typeName, query string, opts QueryOptions, count, offset int) ([]string, error) {
+	if !Searchable(typeName) {
+		return nil, ErrNotSearchable
+	}
+
+	if !opts.Valid() {
+		return nil, ErrInvalidQuery
+	}
+
+	results, err := score(typeName, tokenize(query), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rank(results)
+
+	var ids = []string{}
+	for _, r := range paginate(results, count, offset) {
+		ids = append(ids, r.ID)
+	}
+
+	return ids, nil
+}
+
+// SearchAll queries the index of every searchable content type, returning the
+// matches of all types merged into a single list, most relevant first. count
+// and offset paginate the merged results as in TypeQuery.
+func SearchAll(query string, count, offset int) ([]Result, error) {
+	return SearchAllLocale(query, "", count, offset)
+}
+
+// SearchAllLocale queries the index of every searchable content type in
+// locale, as SearchAll does, matching content as TypeQueryLocale does
+func SearchAllLocale(query, locale string, count, offset int) ([]Result, error) {
+	return SearchAllWithOptions(query, QueryOptions{Locale: locale}, count, offset)
+}
+
+// SearchAllWithOptions queries the index of every searchable content type as
+// opts selects, as SearchAllLocale does
+func SearchAllWithOptions(query string, opts QueryOptions, count, offset int) ([]Result, error) {
+	if !opts.Valid() {
+		return nil, ErrInvalidQuery
+	}
+
+	words := tokenize(query)
+
+	var results = []Result{}
+	for t := range item.Types {
+		if !Searchable(t) {
+			continue
+		}
+
+		r, err := score(t, words, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, r...)
+	}
+
+	rank(results)
+
+	return paginate(results, count, offset), nil
+}
+
+// Facets returns, for each of fields, the number of content items of typeName
+// matching query which have each value of the field. Fields holding strings,
+// numbers or booleans can be faceted, as can lists of them, where each distinct
+// value in the list is counted once per item. Nested fields are named by their
+// dot separated path, e.g. "author.name". Numbers are formatted in the shortest
+// decimal form, and booleans as "true" or "false". Long text fields can be
+// faceted, but every distinct text is its own value.
+func Facets(typeName, query string, fields []string) (map[string]map[string]int, error) {
+	return FacetsWithOptions(typeName, query, QueryOptions{}, fields)
+}
+
+// FacetsWithOptions counts the values of fields of the content matching query
+// as opts selects, as Facets does
+func FacetsWithOptions(typeName, query string, opts QueryOptions, fields []string) (map[string]map[string]int, error) {
+	if !Searchable(typeName) {
+		return nil, ErrNotSearchable
+	}
+
+	if !opts.Valid() {
+		return nil, ErrInvalidQuery
+	}
+
+	facets := make(map[string]map[string]int)
+	for _, f := range fields {
+		facets[f] = make(map[string]int)
+	}
+
+	results, err := score(typeName, tokenize(query), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 || len(fields) == 0 {
+		return facets, nil
+	}
+
+	err = store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(typeName))
+		if b == nil {
+			return nil
+		}
+
+		valueBucket := b.Bucket(valuesBucket)
+		if valueBucket == nil {
+			return nil
+		}
+
+		for _, r := range results {
+			v := valueBucket.Get([]byte(r.ID))
+			if v == nil {
+				continue
+			}
+
+			var values map[string][]string
+			err := json.Unmarshal(v, &values)
+			if err != nil {
+				return err
+			}
+
+			for field, counts := range facets {
+				seen := make(map[string]bool)
+				for _, val := range values[field] {
+					if seen[val] {
+						continue
+					}
+					seen[val] = true
+
+					counts[val]++
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// score returns every item of typeName containing any of words, or of their
+// synonyms, in the locale of opts, scored by the sum over the words it
+// contains of the word's count in the item weighted by how rare the word is
+// among all items of the type, and by how closely it matches in the mode of
+// opts. Words are matched by their terms in the analyzers of the fields of the
+// type, and items are read from their translation into the locale if they
+// have one.
+func score(typeName string, words []string, opts QueryOptions) ([]Result, error) {
+	locale := opts.Locale
+	boost := boosts(typeName)
+
+	// fields are analyzed by the analyzers of the locale they are indexed in,
+	// which is the default locale for items without a translation
+	analyses := analysisOf(typeName, locale)
+	defaults := analysisOf(typeName, "")
+	fieldAnalyzers := make(map[[2]string]*analyzer)
+	analyzerOf := func(l, field string) *analyzer {
+		key := [2]string{l, field}
+		a, ok := fieldAnalyzers[key]
+		if !ok {
+			a = analyses.field(field)
+			if l == "" {
+				a = defaults.field(field)
+			}
+			fieldAnalyzers[key] = a
+		}
+
+		return a
+	}
+
+	var results = []Result{}
+	if store == nil || len(words) == 0 {
+		return results, nil
+	}
+
+	scores := make(map[string]float64)
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(typeName))
+		if b == nil {
+			return nil
+		}
+
+		termBucket := b.Bucket(termsBucket)
+		docBucket := b.Bucket(docsBucket)
+		if termBucket == nil || docBucket == nil {
+			return nil
+		}
+
+		n := float64(docBucket.Stats().KeyN)
+		terms := queryTerms(words)
+
+		// each word of the query is searched by its term in every analyzer
+		// of the type, and matches the fields analyzed by the same one
+		for _, a := range analyses.used() {
+			// a term of content matched by more than one word of the query,
+			// or synonym, is weighted by its closest match
+			seen := make(map[string]int)
+			var matched []expansion
+			for _, term := range terms {
+				t, ok := analyses.term(a, term.word)
+				if !ok {
+					continue
+				}
+
+				for _, e := range opts.expand(termBucket, t) {
+					e.weight *= term.weight
+					if i, ok := seen[e.word]; ok {
+						if e.weight > matched[i].weight {
+							matched[i].weight = e.weight
+						}
+						continue
+					}
+
+					seen[e.word] = len(matched)
+					matched = append(matched, e)
+				}
+			}
+
+			for _, e := range matched {
+				counts := make(map[string]float64)
+				prefix := []byte(e.word + "\x00")
+				c := termBucket.Cursor()
+				for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+					var fields map[string]int
+					err := json.Unmarshal(v, &fields)
+					if err != nil {
+						return err
+					}
+
+					id, l := splitID(string(k[len(prefix):]))
+					if l != locale && l != "" {
+						continue
+					}
+
+					// the item is searched by its translation instead
+					if l == "" && locale != "" && docBucket.Get([]byte(LocaleID(id, locale))) != nil {
+						continue
+					}
+
+					for field, count := range fields {
+						if analyzerOf(l, field) == a {
+							counts[id] += float64(count) * boost(field)
+						}
+					}
+				}
+
+				if len(counts) == 0 {
+					continue
+				}
+
+				idf := math.Log(1 + n/float64(len(counts)))
+				for id, count := range counts {
+					scores[id] += count * idf * e.weight
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for id, s := range scores {
+		results = append(results, Result{Type: typeName, ID: id, Score: s})
+	}
+
+	return results, nil
+}
+
+// boosts returns a func giving the boost of a field of typeName, which is the
+// boost of the field or of the closest field it is nested within, or 1
+func boosts(typeName string) func(field string) float64 {
+	var declared map[string]float64
+	if it, ok := item.Types[typeName]; ok {
+		if b, ok := it().(item.Boostable); ok {
+			declared = b.SearchBoosts()
+		}
+	}
+
+	return func(field string) float64 {
+		if len(declared) == 0 {
+			return 1
+		}
+
+		for f := field; f != ""; {
+			if b, ok := declared[f]; ok && b > 0 {
+				return b
+			}
+
+			i := strings.LastIndexByte(f, '.')
+			if i < 0 {
+				break
+			}
+			f = f[:i]
+		}
+
+		return 1
+	}
+}
+
+// rank sorts results by score, highest first. Equal scores are ordered by type
+// and then by id so that pagination is stable.
+func rank(results []Result) {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+
+		// ids are integers, so shorter ids are smaller
+		if len(a.ID) != len(b.ID) {
+			return len(a.ID) < len(b.ID)
+		}
+
+		return a.ID < b.ID
+	})
+}
+
+// paginate returns the page of results selected by count and offset
+func paginate(results []Result, count, offset int) []Result {
+	if count < 0 {
+		return results
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	start := count * offset
+	if start >= len(results) {
+		return []Result{}
+	}
+
+	end := start + count
+	if end > len(results) {
+		end = len(results)
+	}
+
+	return results[start:end]
+}
@@ -0,0 +1,206 @@
+package search
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/boltdb/bolt"
+)
+
+// ErrRebuilding is returned when the index of a content type is rebuilt while
+// it is already being rebuilt
+var ErrRebuilding = errors.New("The search index of this content type is already being rebuilt")
+
+// rebuild is the temporary index a content type is rebuilt into. Items which
+// change while it is rebuilt are indexed in both it and the live index, and
+// marked dirty so that what is read from the content store before they
+// changed doesn't replace them.
+type rebuild struct {
+	sync.Mutex
+	store *bolt.DB
+	dirty map[string]bool
+}
+
+// rebuilds are the rebuilds in progress, by content type
+var rebuilds = struct {
+	sync.Mutex
+	m map[string]*rebuild
+}{m: make(map[string]*rebuild)}
+
+// write applies the change fn makes to the index of the item typeName:id, to
+// the live index and to the index it is being rebuilt into, if it is
+func write(typeName, id string, fn func(tx *bolt.Tx) error) error {
+	rebuilds.Lock()
+	r := rebuilds.m[typeName]
+	rebuilds.Unlock()
+
+	if r == nil {
+		return store.Update(fn)
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	err := store.Update(fn)
+	if err != nil {
+		return err
+	}
+
+	r.dirty[id] = true
+	return r.store.Update(fn)
+}
+
+// Rebuild replaces the index of the content type typeName with one built from
+// scratch, from the items each calls add with. The new index is built in a
+// temporary db and swapped in once complete, so searches keep using the
+// current index until then and items changed meanwhile are kept up to date.
+func Rebuild(typeName string, each func(add func(id string, data []byte) error) error) error {
+	if store == nil {
+		return bolt.ErrDatabaseNotOpen
+	}
+
+	if !Searchable(typeName) {
+		return ErrNotSearchable
+	}
+
+	path := store.Path() + "." + typeName + ".rebuild"
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := open(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	defer tmp.Close()
+
+	// the temporary index is thrown away if anything fails, so it needn't
+	// survive a crash
+	tmp.NoSync = true
+
+	r := &rebuild{store: tmp, dirty: make(map[string]bool)}
+
+	rebuilds.Lock()
+	if _, ok := rebuilds.m[typeName]; ok {
+		rebuilds.Unlock()
+		return ErrRebuilding
+	}
+	rebuilds.m[typeName] = r
+	rebuilds.Unlock()
+
+	defer func() {
+		rebuilds.Lock()
+		delete(rebuilds.m, typeName)
+		rebuilds.Unlock()
+	}()
+
+	err = each(func(id string, data []byte) error {
+		_, locale := splitID(id)
+		terms, err := analyze(data, analysisOf(typeName, locale))
+		if err != nil {
+			return err
+		}
+
+		values, err := facetValues(data)
+		if err != nil {
+			return err
+		}
+
+		r.Lock()
+		defer r.Unlock()
+
+		// the item has changed since it was read, and been indexed already
+		base, _ := splitID(id)
+		if r.dirty[id] || r.dirty[base] {
+			return nil
+		}
+
+		return tmp.Update(func(tx *bolt.Tx) error {
+			return indexDoc(tx, typeName, id, terms, values)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	return tmp.View(func(src *bolt.Tx) error {
+		return store.Update(func(tx *bolt.Tx) error {
+			name := []byte(typeName)
+			if tx.Bucket(name) != nil {
+				err := tx.DeleteBucket(name)
+				if err != nil {
+					return err
+				}
+			}
+
+			dst, err := tx.CreateBucket(name)
+			if err != nil {
+				return err
+			}
+
+			b := src.Bucket(name)
+			if b == nil {
+				// there is no content of the type
+				return nil
+			}
+
+			return copyBucket(dst, b)
+		})
+	})
+}
+
+// copyBucket puts every key in src into dst, including nested buckets
+func copyBucket(dst, src *bolt.Bucket) error {
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+
+		child, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+
+		return copyBucket(child, src.Bucket(k))
+	})
+}
+
+// Indexed returns the number of items of the content type typeName in the
+// search index, not counting their translations
+func Indexed(typeName string) (int, error) {
+	if store == nil {
+		return 0, bolt.ErrDatabaseNotOpen
+	}
+
+	var n int
+	err := store.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(typeName))
+		if b == nil {
+			return nil
+		}
+
+		docs := b.Bucket(docsBucket)
+		if docs == nil {
+			return nil
+		}
+
+		return docs.ForEach(func(k, v []byte) error {
+			if _, locale := splitID(string(k)); locale == "" {
+				n++
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,103 @@
+// Package search maintains a full-text index of content which implements
+// item.Searchable, and queries it to find content by the words it contains.
+package search
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+	"github.com/ponzu-cms/ponzu/system/logger"
+
+	"github.com/boltdb/bolt"
+)
+
+var store *bolt.DB
+
+// disabled are the content types left out of the search index although they
+// implement item.Searchable, as set by SetDisabled
+var disabled = struct {
+	sync.RWMutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+// ErrNotSearchable is returned when a query is made against a content type
+// which is not registered or does not have its content indexed
+var ErrNotSearchable = errors.New("Content type is not searchable")
+
+// Init opens the search index db, creating it if needed
+func Init() {
+	if store != nil {
+		return
+	}
+
+	var err error
+	store, err = open("search.db")
+	if err != nil {
+		logger.Fatal("Failed to open search index.", err)
+	}
+}
+
+// open opens or creates the bolt db at path to use as the search index
+func open(path string) (*bolt.DB, error) {
+	return bolt.Open(path, 0666, nil)
+}
+
+// Close closes the search index db. Should be called with defer after call to
+// Init() from the same place.
+func Close() {
+	if store == nil {
+		return
+	}
+
+	err := store.Close()
+	if err != nil {
+		logger.Error(err)
+	}
+
+	store = nil
+}
+
+// Ping reports whether the search index db is open and can be read
+func Ping() error {
+	if store == nil {
+		return bolt.ErrDatabaseNotOpen
+	}
+
+	return store.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+// Searchable reports whether the content type typeName is registered and
+// has its content added to the search index
+func Searchable(typeName string) bool {
+	it, ok := item.Types[typeName]
+	if !ok {
+		return false
+	}
+
+	s, ok := it().(item.Searchable)
+	if !ok || !s.IndexContent() {
+		return false
+	}
+
+	disabled.RLock()
+	defer disabled.RUnlock()
+
+	return !disabled.m[typeName]
+}
+
+// SetDisabled sets the content types which are not searchable, and so have
+// nothing added to the search index, even if they implement item.Searchable.
+// It replaces the types previously disabled.
+func SetDisabled(types []string) {
+	m := make(map[string]bool, len(types))
+	for _, t := range types {
+		m[t] = true
+	}
+
+	disabled.Lock()
+	disabled.m = m
+	disabled.Unlock()
+}
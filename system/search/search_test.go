@@ -0,0 +1,661 @@
+package search
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ponzu-cms/ponzu/system/item"
+)
+
+type testPost struct {
+	item.Item
+
+	Title string   `json:"title"`
+	Body  string   `json:"body"`
+	Tags  []string `json:"tags"`
+}
+
+func (p *testPost) IndexContent() bool { return true }
+
+type testPage struct {
+	item.Item
+
+	Title string `json:"title"`
+}
+
+func (p *testPage) IndexContent() bool { return true }
+
+type testBoosted struct {
+	testPost
+}
+
+func (p *testBoosted) SearchBoosts() map[string]float64 {
+	return map[string]float64{"title": 3, "body": -1}
+}
+
+type testSecret struct {
+	item.Item
+
+	Title string `json:"title"`
+}
+
+func setup(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "ponzu-search")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = open(filepath.Join(dir, "search.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item.Types["TestPost"] = func() interface{} { return new(testPost) }
+	item.Types["TestPage"] = func() interface{} { return new(testPage) }
+	item.Types["TestSecret"] = func() interface{} { return new(testSecret) }
+
+	return func() {
+		delete(item.Types, "TestPost")
+		delete(item.Types, "TestPage")
+		delete(item.Types, "TestSecret")
+		Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func index(t *testing.T, typeName, id string, v interface{}) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = UpdateIndex(typeName, id, j)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTypeQuery(t *testing.T) {
+	defer setup(t)()
+
+	index(t, "TestPost", "1", testPost{Title: "Making Ponzu", Body: "<p>Soy sauce and <b>citrus</b></p>"})
+	index(t, "TestPost", "2", testPost{Title: "Citrus", Body: "Citrus, citrus everywhere", Tags: []string{"fruit"}})
+	index(t, "TestPost", "3", testPost{Title: "Unrelated"})
+
+	ids, err := TypeQuery("TestPost", "CITRUS", -1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 2 || ids[0] != "2" || ids[1] != "1" {
+		t.Errorf("expected ids [2 1], got %v", ids)
+	}
+
+	// markup is not indexed
+	ids, _ = TypeQuery("TestPost", "b", -1, 0)
+	if len(ids) != 0 {
+		t.Errorf("expected no matches for markup, got %v", ids)
+	}
+
+	ids, _ = TypeQuery("TestPost", "fruit", -1, 0)
+	if len(ids) != 1 || ids[0] != "2" {
+		t.Errorf("expected ids [2] for tag, got %v", ids)
+	}
+
+	// updating an item replaces its terms
+	index(t, "TestPost", "2", testPost{Title: "Lemons"})
+	ids, _ = TypeQuery("TestPost", "citrus", -1, 0)
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("expected ids [1] after update, got %v", ids)
+	}
+
+	err = DeleteIndex("TestPost", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, _ = TypeQuery("TestPost", "citrus", -1, 0)
+	if len(ids) != 0 {
+		t.Errorf("expected no ids after delete, got %v", ids)
+	}
+
+	_, err = TypeQuery("TestSecret", "citrus", -1, 0)
+	if err != ErrNotSearchable {
+		t.Errorf("expected ErrNotSearchable, got %v", err)
+	}
+}
+
+func TestTypeQueryLocale(t *testing.T) {
+	defer setup(t)()
+
+	index(t, "TestPost", "1", testPost{Title: "Lemon tart"})
+	index(t, "TestPost", LocaleID("1", "fr"), testPost{Title: "Tarte au citron"})
+	index(t, "TestPost", "2", testPost{Title: "Lemon sorbet"})
+
+	ids, _ := TypeQueryLocale("TestPost", "lemon", "fr", -1, 0)
+	if len(ids) != 1 || ids[0] != "2" {
+		t.Errorf("expected untranslated ids [2] in fr, got %v", ids)
+	}
+
+	ids, _ = TypeQueryLocale("TestPost", "citron", "fr", -1, 0)
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("expected translated ids [1] in fr, got %v", ids)
+	}
+
+	ids, _ = TypeQuery("TestPost", "citron", -1, 0)
+	if len(ids) != 0 {
+		t.Errorf("expected no translations matched in default locale, got %v", ids)
+	}
+
+	n, _ := Indexed("TestPost")
+	if n != 2 {
+		t.Errorf("expected 2 items indexed without translations, got %d", n)
+	}
+
+	err := DeleteIndex("TestPost", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, _ = TypeQueryLocale("TestPost", "citron", "fr", -1, 0)
+	if len(ids) != 0 {
+		t.Errorf("expected translations deleted with item, got %v", ids)
+	}
+}
+
+func TestSearchAll(t *testing.T) {
+	defer setup(t)()
+
+	index(t, "TestPost", "1", testPost{Title: "Citrus and soy"})
+	index(t, "TestPost", "2", testPost{Title: "Soy"})
+	index(t, "TestPage", "1", testPage{Title: "Citrus citrus"})
+	index(t, "TestSecret", "1", testSecret{Title: "Citrus"})
+
+	results, err := SearchAll("citrus soy", -1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %v", results)
+	}
+
+	for i := 1; i < len(results); i++ {
+		if results[i].Score > results[i-1].Score {
+			t.Errorf("results not ranked by score: %v", results)
+		}
+	}
+
+	for _, r := range results {
+		if r.Type == "TestSecret" {
+			t.Errorf("unsearchable type in results: %v", r)
+		}
+	}
+
+	page, err := SearchAll("citrus soy", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page) != 1 || page[0] != results[2] {
+		t.Errorf("expected second page %v, got %v", results[2:], page)
+	}
+}
+
+func TestFacets(t *testing.T) {
+	defer setup(t)()
+
+	index(t, "TestPost", "1", testPost{Title: "Citrus", Tags: []string{"fruit", "sour", "fruit"}})
+	index(t, "TestPost", "2", testPost{Title: "Citrus soda", Tags: []string{"drink", "sour"}})
+	index(t, "TestPost", "3", testPost{Title: "Soy", Tags: []string{"sauce"}})
+
+	facets, err := Facets("TestPost", "citrus", []string{"tags", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := facets["tags"]
+	if len(tags) != 3 || tags["fruit"] != 1 || tags["sour"] != 2 || tags["drink"] != 1 {
+		t.Errorf("unexpected tag facets: %v", tags)
+	}
+
+	if m, ok := facets["missing"]; !ok || len(m) != 0 {
+		t.Errorf("expected empty facet for missing field, got %v", facets)
+	}
+}
+
+func TestFilterIDs(t *testing.T) {
+	defer setup(t)()
+
+	index(t, "TestPost", "1", testPost{Item: item.Item{Timestamp: 100}, Title: "news", Tags: []string{"a", "b"}})
+	index(t, "TestPost", "2", testPost{Item: item.Item{Timestamp: 200}, Title: "news", Tags: []string{"b"}})
+	index(t, "TestPost", "3", testPost{Item: item.Item{Timestamp: 300}, Title: "sport", Tags: []string{"a"}})
+
+	cases := []struct {
+		filters []Filter
+		want    []string
+	}{
+		{[]Filter{{Field: "title", Op: OpEqual, Value: "news"}}, []string{"1", "2"}},
+		{[]Filter{{Field: "title", Op: OpEqual, Value: "news"}, {Field: "tags", Op: OpEqual, Value: "a"}}, []string{"1"}},
+		{[]Filter{{Field: "timestamp", Op: OpGreater, Value: "100"}}, []string{"2", "3"}},
+		{[]Filter{{Field: "timestamp", Op: OpLessEqual, Value: "200"}, {Field: "tags", Op: OpEqual, Value: "b"}}, []string{"1", "2"}},
+		{[]Filter{{Field: "title", Op: OpEqual, Value: "weather"}}, []string{}},
+	}
+
+	for _, c := range cases {
+		ids, err := FilterIDs("TestPost", c.filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sort.Strings(ids)
+		if strings.Join(ids, ",") != strings.Join(c.want, ",") {
+			t.Errorf("filters %v: expected %v, got %v", c.filters, c.want, ids)
+		}
+	}
+
+	_, err := FilterIDs("TestPost", []Filter{{Field: "title", Op: "like", Value: "news"}})
+	if err == nil {
+		t.Error("expected error for unsupported operator")
+	}
+}
+
+func TestFilterMatchDates(t *testing.T) {
+	f := Filter{Field: "date", Op: OpLess, Value: "2017-06-01"}
+	if !f.Match("2017-05-31T23:00:00Z") {
+		t.Error("expected earlier date to match lt")
+	}
+
+	if f.Match("2017-06-02") {
+		t.Error("expected later date not to match lt")
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	defer setup(t)()
+
+	index(t, "TestPost", "1", testPost{Title: "Citrus"})
+	index(t, "TestPost", "2", testPost{Title: "Stale citrus"})
+	index(t, "TestPage", "1", testPage{Title: "Citrus page"})
+
+	stored := map[string]testPost{
+		"1": {Title: "Citrus"},
+		"3": {Title: "Old citrus"},
+	}
+
+	err := Rebuild("TestPost", func(add func(id string, data []byte) error) error {
+		// an item changing while the index is rebuilt is kept as it is now,
+		// not as it was read before it changed
+		index(t, "TestPost", "3", testPost{Title: "Lemons"})
+
+		for id, p := range stored {
+			j, err := json.Marshal(p)
+			if err != nil {
+				return err
+			}
+
+			err = add(id, j)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids, _ := TypeQuery("TestPost", "citrus", -1, 0)
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("expected ids [1] after rebuild, got %v", ids)
+	}
+
+	ids, _ = TypeQuery("TestPost", "lemons", -1, 0)
+	if len(ids) != 1 || ids[0] != "3" {
+		t.Errorf("expected ids [3] changed during rebuild, got %v", ids)
+	}
+
+	n, err := Indexed("TestPost")
+	if err != nil || n != 2 {
+		t.Errorf("expected 2 items indexed, got %d %v", n, err)
+	}
+
+	// other types are left as they are
+	ids, _ = TypeQuery("TestPage", "citrus", -1, 0)
+	if len(ids) != 1 {
+		t.Errorf("expected other type to be untouched, got %v", ids)
+	}
+
+	err = Rebuild("TestSecret", func(add func(id string, data []byte) error) error { return nil })
+	if err != ErrNotSearchable {
+		t.Errorf("expected ErrNotSearchable, got %v", err)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	j, err := json.Marshal(testPost{
+		Title: "Citrus & soy",
+		Body:  "<p>Ponzu is a <b>citrus</b> based sauce. It is thin and tart, and is made by simmering mirin, rice vinegar and katsuobushi, then adding citrus juice.</p>",
+		Tags:  []string{"sauce", "citrus"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := Highlight(j, "CITRUS", []string{"title", "body"}, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(h["title"]) != 1 || h["title"][0] != "<mark>Citrus</mark> &amp; soy" {
+		t.Errorf("expected the escaped title with the match marked, got %q", h["title"])
+	}
+
+	if len(h["body"]) != 2 {
+		t.Fatalf("expected a fragment around each distant match in body, got %q", h["body"])
+	}
+
+	for _, frag := range h["body"] {
+		if !strings.Contains(frag, "<mark>citrus</mark>") || strings.Contains(frag, "<b>") || len(frag) > 80 {
+			t.Errorf("expected a short fragment without markup, got %q", frag)
+		}
+	}
+
+	if _, ok := h["tags"]; ok {
+		t.Errorf("expected only the requested fields, got %v", h)
+	}
+
+	h, _ = Highlight(j, "sauce", []string{"*"}, 0)
+	if len(h["tags"]) != 1 || h["tags"][0] != "<mark>sauce</mark>" || len(h["body"]) != 1 {
+		t.Errorf("expected every field with a match highlighted, got %q", h)
+	}
+
+	h, _ = Highlight(j, "lemon", []string{"*"}, 40)
+	if len(h) != 0 {
+		t.Errorf("expected no highlights without a match, got %q", h)
+	}
+}
+
+func TestQueryModes(t *testing.T) {
+	defer setup(t)()
+
+	index(t, "TestPost", "1", testPost{Title: "Citrus ponzu"})
+	index(t, "TestPost", "2", testPost{Title: "Citron tart"})
+	index(t, "TestPost", "3", testPost{Title: "Soy sauce"})
+
+	ids, _ := TypeQuery("TestPost", "citr", -1, 0)
+	if len(ids) != 0 {
+		t.Errorf("expected no term matches for a partial word, got %v", ids)
+	}
+
+	ids, _ = TypeQueryWithOptions("TestPost", "citr", QueryOptions{Mode: ModePrefix}, -1, 0)
+	if len(ids) != 2 {
+		t.Errorf("expected prefix matches [1 2], got %v", ids)
+	}
+
+	// a closer match scores higher
+	ids, _ = TypeQueryWithOptions("TestPost", "citrus", QueryOptions{Mode: ModePrefix}, -1, 0)
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("expected prefix matches [1] for a whole word, got %v", ids)
+	}
+
+	ids, _ = TypeQueryWithOptions("TestPost", "sause", QueryOptions{Mode: ModeFuzzy}, -1, 0)
+	if len(ids) != 1 || ids[0] != "3" {
+		t.Errorf("expected fuzzy matches [3] for a typo, got %v", ids)
+	}
+
+	ids, _ = TypeQueryWithOptions("TestPost", "citris", QueryOptions{Mode: ModeFuzzy}, -1, 0)
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("expected fuzzy matches [1] within 1 edit, got %v", ids)
+	}
+
+	ids, _ = TypeQueryWithOptions("TestPost", "citris", QueryOptions{Mode: ModeFuzzy, Fuzziness: 2}, -1, 0)
+	if len(ids) != 2 || ids[0] != "1" {
+		t.Errorf("expected fuzzy matches [1 2] within 2 edits, closest first, got %v", ids)
+	}
+
+	_, err := TypeQueryWithOptions("TestPost", "citrus", QueryOptions{Mode: "regex"}, -1, 0)
+	if err != ErrInvalidQuery {
+		t.Errorf("expected ErrInvalidQuery for an unknown mode, got %v", err)
+	}
+
+	_, err = TypeQueryWithOptions("TestPost", "citrus", QueryOptions{Mode: ModeFuzzy, Fuzziness: 3}, -1, 0)
+	if err != ErrInvalidQuery {
+		t.Errorf("expected ErrInvalidQuery for too much fuzziness, got %v", err)
+	}
+
+	j, _ := json.Marshal(testPost{Title: "Citrus ponzu"})
+	h, _ := HighlightWithOptions(j, "pon", QueryOptions{Mode: ModePrefix}, []string{"title"}, 0)
+	if len(h["title"]) != 1 || h["title"][0] != "Citrus <mark>ponzu</mark>" {
+		t.Errorf("expected the prefix match highlighted, got %q", h)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{"sauce", "sauce", 2, 0},
+		{"sause", "sauce", 2, 1},
+		{"citris", "citron", 2, 2},
+		{"citris", "citron", 1, -1},
+		{"café", "cafe", 1, 1},
+		{"soy", "soybean", 2, -1},
+	}
+
+	for _, c := range cases {
+		if got := distance(c.a, c.b, c.max); got != c.want {
+			t.Errorf("distance(%q, %q, %d): expected %d, got %d", c.a, c.b, c.max, c.want, got)
+		}
+	}
+}
+
+func TestSearchBoosts(t *testing.T) {
+	defer setup(t)()
+
+	item.Types["TestBoosted"] = func() interface{} { return new(testBoosted) }
+	defer delete(item.Types, "TestBoosted")
+
+	for _, typeName := range []string{"TestPost", "TestBoosted"} {
+		index(t, typeName, "1", testPost{Title: "Citrus"})
+		index(t, typeName, "2", testPost{Title: "Ponzu", Body: "citrus and more citrus"})
+	}
+
+	ids, _ := TypeQuery("TestPost", "citrus", -1, 0)
+	if len(ids) != 2 || ids[0] != "2" {
+		t.Errorf("expected the most matches first without boosts, got %v", ids)
+	}
+
+	// the negative boost of body is ignored
+	ids, _ = TypeQuery("TestBoosted", "citrus", -1, 0)
+	if len(ids) != 2 || ids[0] != "1" {
+		t.Errorf("expected the boosted title match first, got %v", ids)
+	}
+
+	boost := boosts("TestBoosted")
+	if boost("title") != 3 || boost("title.en") != 3 || boost("body") != 1 || boost("tags") != 1 {
+		t.Errorf("unexpected boosts: title %v, title.en %v, body %v, tags %v", boost("title"), boost("title.en"), boost("body"), boost("tags"))
+	}
+}
+
+func TestSetDisabled(t *testing.T) {
+	defer setup(t)()
+
+	index(t, "TestPost", "1", testPost{Title: "Citrus"})
+
+	SetDisabled([]string{"TestPost"})
+	defer SetDisabled(nil)
+
+	if Searchable("TestPost") || !Searchable("TestPage") {
+		t.Error("expected only the disabled type to not be searchable")
+	}
+
+	_, err := TypeQuery("TestPost", "citrus", -1, 0)
+	if err != ErrNotSearchable {
+		t.Errorf("expected ErrNotSearchable for a disabled type, got %v", err)
+	}
+
+	// content of a disabled type is not indexed
+	index(t, "TestPost", "2", testPost{Title: "Citrus"})
+
+	err = Drop("TestPost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetDisabled(nil)
+	n, err := Indexed("TestPost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 0 {
+		t.Errorf("expected the dropped index to be empty, got %d items", n)
+	}
+}
+
+func TestSynonyms(t *testing.T) {
+	defer setup(t)()
+	defer SetSynonyms("")
+
+	index(t, "TestPost", "1", testPost{Title: "Car for sale"})
+	index(t, "TestPost", "2", testPost{Title: "Automobile repairs and car parts"})
+	index(t, "TestPost", "3", testPost{Title: "Running shoes"})
+	index(t, "TestPost", "4", testPost{Title: "Sneakers"})
+
+	err := SetSynonyms("# vehicles\ncar, automobile\n\nsneakers => shoes\nbroken =>")
+	if err == nil || !strings.Contains(err.Error(), "line 5") {
+		t.Errorf("expected an error for the invalid rule, got %v", err)
+	}
+
+	ids, _ := TypeQuery("TestPost", "automobile", -1, 0)
+	if len(ids) != 2 || ids[0] != "2" {
+		t.Errorf("expected both ways of a rule to match, exact first, got %v", ids)
+	}
+
+	ids, _ = TypeQuery("TestPost", "car", -1, 0)
+	if len(ids) != 2 {
+		t.Errorf("expected matches [1 2] for a synonym, got %v", ids)
+	}
+
+	ids, _ = TypeQuery("TestPost", "sneakers", -1, 0)
+	if len(ids) != 2 || ids[0] != "4" {
+		t.Errorf("expected the directional synonym to match after the exact word, got %v", ids)
+	}
+
+	ids, _ = TypeQuery("TestPost", "shoes", -1, 0)
+	if len(ids) != 1 || ids[0] != "3" {
+		t.Errorf("expected a directional rule to not match the other way, got %v", ids)
+	}
+
+	j, _ := json.Marshal(testPost{Title: "Sneakers and shoes"})
+	h, _ := Highlight(j, "sneakers", []string{"title"}, 0)
+	if len(h["title"]) != 1 || h["title"][0] != "<mark>Sneakers</mark> and <mark>shoes</mark>" {
+		t.Errorf("expected synonyms highlighted, got %q", h)
+	}
+
+	SetSynonyms("")
+	ids, _ = TypeQuery("TestPost", "sneakers", -1, 0)
+	if len(ids) != 1 {
+		t.Errorf("expected no synonyms once they are cleared, got %v", ids)
+	}
+}
+
+func TestAnalyzers(t *testing.T) {
+	defer setup(t)()
+	defer SetAnalyzers(nil)
+	defer SetStopWords(nil)
+
+	err := SetAnalyzers([]string{"TestPost=english", "TestPost.title@fr=french", "TestPost=klingon"})
+	if err == nil || !strings.Contains(err.Error(), "klingon") {
+		t.Errorf("expected an error for the unknown language, got %v", err)
+	}
+	SetStopWords([]string{"Ponzu"})
+
+	index(t, "TestPost", "1", testPost{Title: "The ponzu", Body: "Sauces and running"})
+	index(t, "TestPost", "2", testPost{Title: "Tarts"})
+	index(t, "TestPost", LocaleID("2", "fr"), testPost{Title: "Les tartes", Body: "Sauces"})
+	index(t, "TestPage", "1", testPage{Title: "Sauces"})
+
+	ids, _ := TypeQuery("TestPost", "sauce", -1, 0)
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("expected the stemmed word to match, got %v", ids)
+	}
+
+	ids, _ = TypeQuery("TestPost", "runs", -1, 0)
+	if len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("expected another form of the word to match, got %v", ids)
+	}
+
+	for _, q := range []string{"the", "ponzu"} {
+		ids, _ = TypeQuery("TestPost", q, -1, 0)
+		if len(ids) != 0 {
+			t.Errorf("expected no matches for the stop word %q, got %v", q, ids)
+		}
+	}
+
+	ids, _ = TypeQuery("TestPage", "sauce", -1, 0)
+	if len(ids) != 0 {
+		t.Errorf("expected a type without an analyzer to match words as they are, got %v", ids)
+	}
+
+	ids, _ = TypeQueryLocale("TestPost", "tarte", "fr", -1, 0)
+	if len(ids) != 1 || ids[0] != "2" {
+		t.Errorf("expected the french title to match, got %v", ids)
+	}
+
+	// the body of the translation is still analyzed as english
+	ids, _ = TypeQueryLocale("TestPost", "sauce", "fr", -1, 0)
+	if len(ids) != 2 {
+		t.Errorf("expected both bodies to match, got %v", ids)
+	}
+
+	j, _ := json.Marshal(testPost{Title: "The ponzu", Body: "Sauces and running"})
+	h, _ := HighlightType("TestPost", j, "sauce run", QueryOptions{}, []string{"*"}, 0)
+	if len(h["body"]) != 1 || h["body"][0] != "<mark>Sauces</mark> and <mark>running</mark>" || len(h["title"]) != 0 {
+		t.Errorf("expected the forms of the words highlighted, got %q", h)
+	}
+}
+
+func TestStemmers(t *testing.T) {
+	cases := []struct {
+		language string
+		words    []string
+	}{
+		{"english", []string{"sauce", "sauces"}},
+		{"english", []string{"run", "runs", "running"}},
+		{"english", []string{"taste", "tasted", "tasting"}},
+		{"english", []string{"cherry", "cherries"}},
+		{"english", []string{"box", "boxes"}},
+		{"french", []string{"cheval", "chevaux"}},
+		{"french", []string{"aimé", "aimée", "aimées"}},
+		{"german", []string{"haus", "häuser", "häusern"}},
+		{"german", []string{"frau", "frauen"}},
+		{"spanish", []string{"niño", "niña", "niños"}},
+		{"spanish", []string{"flor", "flores"}},
+		{"italian", []string{"libro", "libri"}},
+		{"portuguese", []string{"livro", "livros"}},
+	}
+
+	for _, c := range cases {
+		stem := languages[c.language].stem
+		want := stem(c.words[0])
+		for _, w := range c.words[1:] {
+			if got := stem(w); got != want {
+				t.Errorf("%s: expected %q to stem to %q like %q, got %q", c.language, w, want, c.words[0], got)
+			}
+		}
+	}
+
+	if stemEnglish("bus") != "bus" || stemGerman("haus") != "haus" {
+		t.Error("expected short words to keep their final letters")
+	}
+}
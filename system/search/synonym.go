@@ -0,0 +1,156 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// synonymWeight is the weight of a match of a synonym of a word of a query,
+// relative to a match of the word itself, so that content with the words
+// searched for ranks above content with only their synonyms
+const synonymWeight = 0.5
+
+// synonym expands a query containing any of the phrases in from with the words
+// in to
+type synonym struct {
+	from [][]string
+	to   []string
+}
+
+// synonyms are the rules set by SetSynonyms
+var synonyms = struct {
+	sync.RWMutex
+	rules []synonym
+}{}
+
+// SetSynonyms sets the synonyms words of queries are expanded with, replacing
+// those set before, from rules of one rule per line. A rule of comma separated
+// words or phrases makes each a synonym of the others, e.g.
+//
+//	car, automobile, auto
+//
+// and a rule with => expands the words or phrases on the left with those on
+// the right, but not the other way around, e.g.
+//
+//	sneakers => shoes
+//
+// Empty lines and lines starting with # are ignored. Rules apply to queries
+// from the next search, as the index is unchanged. An error is returned for
+// the first invalid line, but every valid rule is set.
+func SetSynonyms(rules string) error {
+	var parsed []synonym
+	var invalid error
+	for n, line := range strings.Split(rules, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		s, ok := parseSynonym(line)
+		if !ok {
+			if invalid == nil {
+				invalid = fmt.Errorf("invalid synonym rule on line %d: %q", n+1, line)
+			}
+			continue
+		}
+
+		parsed = append(parsed, s)
+	}
+
+	synonyms.Lock()
+	synonyms.rules = parsed
+	synonyms.Unlock()
+
+	return invalid
+}
+
+// parseSynonym returns the synonym of a single rule
+func parseSynonym(line string) (synonym, bool) {
+	var s synonym
+	left, right := line, ""
+	directional := strings.Contains(line, "=>")
+	if directional {
+		parts := strings.SplitN(line, "=>", 2)
+		left, right = parts[0], parts[1]
+	}
+
+	for _, phrase := range strings.Split(left, ",") {
+		words := tokenize(phrase)
+		if len(words) > 0 {
+			s.from = append(s.from, words)
+		}
+	}
+
+	if !directional {
+		for _, words := range s.from {
+			s.to = append(s.to, words...)
+		}
+
+		return s, len(s.from) > 1
+	}
+
+	for _, phrase := range strings.Split(right, ",") {
+		s.to = append(s.to, tokenize(phrase)...)
+	}
+
+	return s, len(s.from) > 0 && len(s.to) > 0
+}
+
+// queryTerm is a word searched for and the weight of its matches
+type queryTerm struct {
+	word   string
+	weight float64
+}
+
+// queryTerms returns the words of a query, followed by the synonyms they are
+// expanded with at synonymWeight, each once
+func queryTerms(words []string) []queryTerm {
+	seen := make(map[string]bool)
+	var terms []queryTerm
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, queryTerm{word: w, weight: 1})
+		}
+	}
+
+	synonyms.RLock()
+	defer synonyms.RUnlock()
+
+	for _, s := range synonyms.rules {
+		if !s.matches(words) {
+			continue
+		}
+
+		for _, w := range s.to {
+			if !seen[w] {
+				seen[w] = true
+				terms = append(terms, queryTerm{word: w, weight: synonymWeight})
+			}
+		}
+	}
+
+	return terms
+}
+
+// matches reports whether any phrase the synonym expands is in words
+func (s synonym) matches(words []string) bool {
+	for _, phrase := range s.from {
+		for i := 0; i+len(phrase) <= len(words); i++ {
+			found := true
+			for j := range phrase {
+				if words[i+j] != phrase[j] {
+					found = false
+					break
+				}
+			}
+
+			if found {
+				return true
+			}
+		}
+	}
+
+	return false
+}
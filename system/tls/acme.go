@@ -0,0 +1,74 @@
+package tls
+
+import (
+	"os"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"golang.org/x/crypto/acme"
+)
+
+// ACME challenges which can be configured as acme_challenge to prove control of
+// the domain to the certificate authority
+const (
+	// ChallengeHTTP01 serves a token over HTTP on port 80, and is the default
+	ChallengeHTTP01 = "http-01"
+	// ChallengeDNS01 creates a TXT record with a DNSProvider, which is needed
+	// for wildcard certificates and hosts that can't be reached publicly
+	ChallengeDNS01 = "dns-01"
+)
+
+// Credential returns the value of a setting for certificates, such as the
+// credentials of a DNS provider, from the environment variable named for key
+// in upper case with the prefix PONZU_, e.g. PONZU_ACME_DNS_EXEC for
+// acme_dns_exec, or else from the config. The environment is checked first so
+// that secrets don't need to be stored in the db.
+func Credential(key string) string {
+	if v := os.Getenv("PONZU_" + strings.ToUpper(key)); v != "" {
+		return v
+	}
+
+	v, _ := db.ConfigCache(key).(string)
+	return v
+}
+
+// challenge returns the configured ACME challenge
+func challenge() string {
+	if Credential("acme_challenge") == ChallengeDNS01 {
+		return ChallengeDNS01
+	}
+
+	return ChallengeHTTP01
+}
+
+// directoryURL returns the directory of the ACME certificate authority, which
+// is Let's Encrypt unless acme_directory_url is configured, such as for its
+// staging environment or a private CA
+func directoryURL() string {
+	if u := Credential("acme_directory_url"); u != "" {
+		return u
+	}
+
+	return acme.LetsEncryptURL
+}
+
+// certDomains returns the names certificates are issued for, which are host
+// and any configured in acme_domains, such as *.example.com
+func certDomains(host string) []string {
+	domains := []string{host}
+	seen := map[string]bool{host: true}
+
+	vals, _ := db.ConfigCache("acme_domains").([]interface{})
+	for _, v := range vals {
+		d, _ := v.(string)
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d == "" || seen[d] {
+			continue
+		}
+
+		seen[d] = true
+		domains = append(domains, d)
+	}
+
+	return domains
+}
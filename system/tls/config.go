@@ -0,0 +1,118 @@
+package tls
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// versions are the TLS versions which can be configured as tls_min_version
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// defaultMinVersion is the oldest TLS version accepted, if tls_min_version
+// is not configured
+const defaultMinVersion = "1.2"
+
+// minVersion returns the oldest TLS version the server accepts
+func minVersion() uint16 {
+	v, _ := db.ConfigCache("tls_min_version").(string)
+	if v == "" {
+		v = defaultMinVersion
+	}
+
+	version, ok := versions[v]
+	if !ok {
+		logger.Warn("Unknown TLS version", v, "configured, using", defaultMinVersion)
+		return versions[defaultMinVersion]
+	}
+
+	return version
+}
+
+// cipherSuites returns the IDs of the cipher suites configured by name in
+// tls_cipher_suites, or nil to use the secure defaults of Go. Unknown names
+// are logged and left out, and insecure suites are allowed but logged. The
+// suites of TLS 1.3 are not configurable, and are always enabled.
+func cipherSuites() []uint16 {
+	vals, _ := db.ConfigCache("tls_cipher_suites").([]interface{})
+	if len(vals) == 0 {
+		return nil
+	}
+
+	known := make(map[string]uint16)
+	for _, s := range tls.CipherSuites() {
+		known[s.Name] = s.ID
+	}
+
+	insecure := make(map[string]uint16)
+	for _, s := range tls.InsecureCipherSuites() {
+		insecure[s.Name] = s.ID
+	}
+
+	var ids []uint16
+	for _, v := range vals {
+		name, _ := v.(string)
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if id, ok := known[name]; ok {
+			ids = append(ids, id)
+			continue
+		}
+
+		if id, ok := insecure[name]; ok {
+			logger.Warn("Insecure TLS cipher suite", name, "is enabled")
+			ids = append(ids, id)
+			continue
+		}
+
+		logger.Warn("Unknown TLS cipher suite", name, "configured, ignoring it")
+	}
+
+	return ids
+}
+
+// http2Enabled reports whether HTTP/2 is offered to clients, which it is
+// unless disabled with http2_disabled
+func http2Enabled() bool {
+	disabled, _ := db.ConfigCache("http2_disabled").(bool)
+	return !disabled
+}
+
+// newServer returns a server for HTTPS requests on addr, which serves them
+// with handler, using the configured TLS version, cipher suites and protocols.
+// getCertificate is used to choose a certificate for each connection, if it is
+// not nil.
+func newServer(addr string, handler http.Handler, getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)) *http.Server {
+	cfg := &tls.Config{
+		MinVersion:     minVersion(),
+		CipherSuites:   cipherSuites(),
+		GetCertificate: getCertificate,
+	}
+
+	server := &http.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: cfg,
+	}
+
+	if http2Enabled() {
+		cfg.NextProtos = []string{"h2", "http/1.1"}
+	} else {
+		// a non-nil, empty map stops net/http from configuring HTTP/2
+		cfg.NextProtos = []string{"http/1.1"}
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+
+	return server
+}
@@ -0,0 +1,359 @@
+package tls
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	// defaultPropagationSeconds is how long to wait after creating a TXT record
+	// before asking the certificate authority to check it, if
+	// acme_dns_propagation_seconds is not configured
+	defaultPropagationSeconds = 60
+	// renewBefore is how long before a certificate expires it is renewed
+	renewBefore = time.Hour * 24 * 30
+	// renewCheckInterval is how often the certificate is checked for renewal,
+	// and how long to wait before trying again if obtaining one fails
+	renewCheckInterval = time.Hour * 12
+)
+
+// DNSProvider creates and removes the TXT records which prove control of a
+// domain for the DNS-01 challenge. fqdn is the name of the record, such as
+// "_acme-challenge.example.com.", and value is its content.
+type DNSProvider interface {
+	Present(ctx context.Context, fqdn, value string) error
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// dnsProviders are the DNS providers which can be configured by name as
+// acme_dns_provider
+var dnsProviders = struct {
+	sync.RWMutex
+	m map[string]DNSProvider
+}{m: map[string]DNSProvider{"exec": execProvider{}}}
+
+// RegisterDNSProvider makes p available to configure as acme_dns_provider with
+// name, and should be called from an init function. Providers should read
+// their credentials with Credential.
+func RegisterDNSProvider(name string, p DNSProvider) {
+	dnsProviders.Lock()
+	dnsProviders.m[name] = p
+	dnsProviders.Unlock()
+}
+
+// execProvider is the built-in DNS provider, which runs the program configured
+// as acme_dns_exec with the arguments "present" or "cleanup", the fqdn and the
+// value of the record, so that any DNS API can be used from a script. The
+// program is given the environment of Ponzu, to read its credentials from.
+type execProvider struct{}
+
+func (execProvider) run(ctx context.Context, action, fqdn, value string) error {
+	program := Credential("acme_dns_exec")
+	if program == "" {
+		return errors.New("acme_dns_exec is not configured for the exec DNS provider")
+	}
+
+	out, err := exec.CommandContext(ctx, program, action, fqdn, value).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %v: %s", program, action, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+func (p execProvider) Present(ctx context.Context, fqdn, value string) error {
+	return p.run(ctx, "present", fqdn, value)
+}
+
+func (p execProvider) CleanUp(ctx context.Context, fqdn, value string) error {
+	return p.run(ctx, "cleanup", fqdn, value)
+}
+
+// dnsManager obtains and renews a certificate for all of its domains with the
+// DNS-01 challenge, keeping it in the cert cache directory
+type dnsManager struct {
+	client   *acme.Client
+	provider DNSProvider
+	domains  []string
+	email    string
+	dir      string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// newDNSManager returns a dnsManager for domains using the DNS provider
+// configured as acme_dns_provider, which must be registered
+func newDNSManager(dir, email string, domains []string) (*dnsManager, error) {
+	name := Credential("acme_dns_provider")
+	if name == "" {
+		name = "exec"
+	}
+
+	dnsProviders.RLock()
+	p, ok := dnsProviders.m[name]
+	dnsProviders.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("DNS provider %q is not registered", name)
+	}
+
+	key, err := accountKey(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dnsManager{
+		client:   &acme.Client{Key: key, DirectoryURL: directoryURL()},
+		provider: p,
+		domains:  domains,
+		email:    email,
+		dir:      dir,
+	}, nil
+}
+
+// accountKey returns the key of the ACME account, creating it in dir if there
+// isn't one yet
+func accountKey(dir string) (crypto.Signer, error) {
+	path := filepath.Join(dir, "acme_account.key")
+	b, err := ioutil.ReadFile(path)
+	if err == nil {
+		block, _ := pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("no key found in %s", path)
+		}
+
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// certPath returns the file the certificate and its key are kept in
+func (d *dnsManager) certPath() string {
+	name := strings.Replace(d.domains[0], "*", "_wildcard", -1)
+	return filepath.Join(d.dir, "dns01-"+name+".pem")
+}
+
+// GetCertificate returns the certificate for every connection, as it covers
+// all of the domains
+func (d *dnsManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.cert == nil {
+		return nil, errors.New("certificate has not been issued yet")
+	}
+
+	return d.cert, nil
+}
+
+// load reads the certificate kept in the cert cache directory, if there is one
+func (d *dnsManager) load() error {
+	b, err := ioutil.ReadFile(d.certPath())
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.X509KeyPair(b, b)
+	if err != nil {
+		return err
+	}
+
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.cert = &cert
+	d.mu.Unlock()
+
+	return nil
+}
+
+// due reports whether there is no certificate, or it expires soon
+func (d *dnsManager) due() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.cert == nil || time.Until(d.cert.Leaf.NotAfter) < renewBefore
+}
+
+// run loads the kept certificate, then obtains a new one whenever it is due,
+// until the process exits
+func (d *dnsManager) run() {
+	if err := d.load(); err != nil && !os.IsNotExist(err) {
+		logger.Error("Error loading certificate:", err)
+	}
+
+	for {
+		if d.due() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute*10)
+			err := d.obtain(ctx)
+			cancel()
+			if err != nil {
+				logger.Error("Error obtaining certificate with the DNS-01 challenge:", err)
+			}
+		}
+
+		time.Sleep(renewCheckInterval)
+	}
+}
+
+// obtain orders a certificate for the domains, proving control of each with a
+// TXT record, and keeps it in the cert cache directory
+func (d *dnsManager) obtain(ctx context.Context) error {
+	_, err := d.client.Register(ctx, &acme.Account{Contact: []string{"mailto:" + d.email}}, acme.AcceptTOS)
+	if err != nil && err != acme.ErrAccountAlreadyExists {
+		return err
+	}
+
+	order, err := d.client.AuthorizeOrder(ctx, acme.DomainIDs(d.domains...))
+	if err != nil {
+		return err
+	}
+
+	for _, u := range order.AuthzURLs {
+		err := d.authorize(ctx, u)
+		if err != nil {
+			return err
+		}
+	}
+
+	order, err = d.client.WaitOrder(ctx, order.URI)
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: d.domains[0]},
+		DNSNames: d.domains,
+	}, key)
+	if err != nil {
+		return err
+	}
+
+	chain, _, err := d.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	for _, c := range chain {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c})...)
+	}
+
+	err = ioutil.WriteFile(d.certPath(), data, 0600)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Obtained certificate for", strings.Join(d.domains, ", "))
+	return d.load()
+}
+
+// authorize completes the DNS-01 challenge of the authorization at url
+func (d *dnsManager) authorize(ctx context.Context, url string) error {
+	z, err := d.client.GetAuthorization(ctx, url)
+	if err != nil {
+		return err
+	}
+	if z.Status == acme.StatusValid {
+		return nil
+	}
+
+	var chal *acme.Challenge
+	for _, c := range z.Challenges {
+		if c.Type == ChallengeDNS01 {
+			chal = c
+		}
+	}
+	if chal == nil {
+		return fmt.Errorf("no DNS-01 challenge offered for %s", z.Identifier.Value)
+	}
+
+	value, err := d.client.DNS01ChallengeRecord(chal.Token)
+	if err != nil {
+		return err
+	}
+
+	// the record of a wildcard is on the domain it covers
+	fqdn := "_acme-challenge." + strings.TrimPrefix(z.Identifier.Value, "*.") + "."
+	err = d.provider.Present(ctx, fqdn, value)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := d.provider.CleanUp(context.Background(), fqdn, value)
+		if err != nil {
+			logger.Error("Error removing DNS-01 challenge record", fqdn, err)
+		}
+	}()
+
+	wait := defaultPropagationSeconds
+	if n, ok := db.ConfigCache("acme_dns_propagation_seconds").(float64); ok && n > 0 {
+		wait = int(n)
+	}
+
+	select {
+	case <-time.After(time.Duration(wait) * time.Second):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	_, err = d.client.Accept(ctx, chal)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.WaitAuthorization(ctx, z.URI)
+	return err
+}
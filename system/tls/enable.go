@@ -3,30 +3,35 @@ package tls
 import (
 	"crypto/tls"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
 
 	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+	"golang.org/x/crypto/acme"
 	"golang.org/x/crypto/acme/autocert"
 )
 
 var m autocert.Manager
 
-// setup attempts to locate or create the cert cache directory and the certs for TLS encryption
-func setup() {
+// setup attempts to locate or create the cert cache directory and the certs for
+// TLS encryption, and returns the function which chooses the certificate for
+// each connection. Certificates are obtained with the HTTP-01 challenge unless
+// acme_challenge is configured as dns-01.
+func setup() func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
 	pwd, err := os.Getwd()
 	if err != nil {
-		log.Fatalln("Couldn't find working directory to locate or save certificates.")
+		logger.Fatal("Couldn't find working directory to locate or save certificates.")
 	}
 
 	cache := autocert.DirCache(filepath.Join(pwd, "system", "tls", "certs"))
 	if _, err := os.Stat(string(cache)); os.IsNotExist(err) {
 		err := os.MkdirAll(string(cache), os.ModePerm|os.ModeDir)
 		if err != nil {
-			log.Fatalln("Couldn't create cert directory at", cache)
+			logger.Fatal("Couldn't create cert directory at", cache)
 		}
 	}
 
@@ -35,44 +40,101 @@ func setup() {
 	// and sending incomplete requests is wasteful and guarenteed to fail its check
 	host, err := db.Config("domain")
 	if err != nil {
-		log.Fatalln("Error identifying host/domain during TLS set-up.", err)
+		logger.Fatal("Error identifying host/domain during TLS set-up.", err)
 	}
 
 	if host == nil {
-		log.Fatalln("No 'domain' field set in Configuration. Please add a domain before attempting to make certificates.")
+		logger.Fatal("No 'domain' field set in Configuration. Please add a domain before attempting to make certificates.")
 	}
 	fmt.Println("Using", string(host), "as host/domain for certificate...")
 	fmt.Println("NOTE: if the host/domain is not configured properly or is unreachable, HTTPS set-up will fail.")
 
 	email, err := db.Config("admin_email")
 	if err != nil {
-		log.Fatalln("Error identifying admin email during TLS set-up.", err)
+		logger.Fatal("Error identifying admin email during TLS set-up.", err)
 	}
 
 	if email == nil {
-		log.Fatalln("No 'admin_email' field set in Configuration. Please add an admin email before attempting to make certificates.")
+		logger.Fatal("No 'admin_email' field set in Configuration. Please add an admin email before attempting to make certificates.")
 	}
 	fmt.Println("Using", string(email), "as contact email for certificate...")
 
+	domains := certDomains(string(host))
+	fmt.Println("Using", directoryURL(), "as certificate authority...")
+
+	if challenge() == ChallengeDNS01 {
+		d, err := newDNSManager(string(cache), string(email), domains)
+		if err != nil {
+			logger.Fatal("Error setting up the DNS-01 challenge:", err)
+		}
+		fmt.Println("Using the DNS-01 challenge for", strings.Join(domains, ", "), "...")
+
+		go d.run()
+		return d.GetCertificate
+	}
+
+	// wildcards can only be issued with the DNS-01 challenge
+	var hosts []string
+	for _, d := range domains {
+		if strings.HasPrefix(d, "*.") {
+			logger.Warn("Skipping", d, "as wildcard certificates need the DNS-01 challenge")
+			continue
+		}
+
+		hosts = append(hosts, d)
+	}
+
 	m = autocert.Manager{
 		Prompt:      autocert.AcceptTOS,
 		Cache:       cache,
-		HostPolicy:  autocert.HostWhitelist(string(host)),
-		RenewBefore: time.Hour * 24 * 30,
+		HostPolicy:  autocert.HostWhitelist(hosts...),
+		RenewBefore: renewBefore,
 		Email:       string(email),
+		Client:      &acme.Client{DirectoryURL: directoryURL()},
 	}
 
+	return m.GetCertificate
+}
+
+// HTTPHandler answers the HTTP-01 challenges of the certificate authority
+// for requests on the HTTP port, passing every other request to fallback
+func HTTPHandler(fallback http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if challenge() != ChallengeHTTP01 || !strings.HasPrefix(req.URL.Path, "/.well-known/acme-challenge/") {
+			fallback.ServeHTTP(res, req)
+			return
+		}
+
+		m.HTTPHandler(fallback).ServeHTTP(res, req)
+	})
 }
 
 // Enable runs the setup for creating or locating production certificates and
-// starts the TLS server
-func Enable() {
-	setup()
+// starts the TLS server, serving requests with handler. The server is returned
+// so that it can be shut down.
+func Enable(handler http.Handler) *http.Server {
+	getCertificate := setup()
+
+	host, _ := db.ConfigCache("bind_addr").(string)
+	addr := net.JoinHostPort(host, db.ConfigCache("https_port").(string))
+	server := newServer(addr, handler, getCertificate)
 
-	server := &http.Server{
-		Addr:      fmt.Sprintf(":%s", db.ConfigCache("https_port").(string)),
-		TLSConfig: &tls.Config{GetCertificate: m.GetCertificate},
+	// let the certificate authority verify the domain with the tls-alpn-01
+	// challenge too, when using autocert
+	if challenge() == ChallengeHTTP01 {
+		server.TLSConfig.NextProtos = append(server.TLSConfig.NextProtos, "acme-tls/1")
 	}
 
-	log.Fatalln(server.ListenAndServeTLS("", ""))
+	go serve(server, "", "")
+
+	return server
+}
+
+// serve listens for HTTPS requests to server until it is shut down, using the
+// certificate and key files given, if any
+func serve(server *http.Server, cert, key string) {
+	err := server.ListenAndServeTLS(cert, key)
+	if err != nil && err != http.ErrServerClosed {
+		logger.Fatal(err)
+	}
 }
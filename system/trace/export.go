@@ -0,0 +1,322 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/db"
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+const (
+	// DefaultEndpoint is where the otlp exporter sends spans if no endpoint
+	// is configured, the OTLP/HTTP port of a collector on the same host
+	DefaultEndpoint = "http://localhost:4318/v1/traces"
+
+	// batchSize is the most spans exported at once
+	batchSize = 512
+	// flushInterval is how often queued spans are exported
+	flushInterval = 5 * time.Second
+	// queueSize is the most spans waiting to be exported, after which spans
+	// are dropped rather than slowing requests down
+	queueSize = 4096
+)
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	Export(spans []SpanData) error
+}
+
+// exporters make the Exporters which can be configured by name
+var exporters = map[string]func() (Exporter, error){
+	"otlp": newOTLP,
+}
+
+// RegisterExporter makes an Exporter available to configure with the name
+// name in tracing_exporter. The built-in otlp exporter sends spans to an
+// OpenTelemetry collector with OTLP over HTTP.
+func RegisterExporter(name string, fn func() (Exporter, error)) {
+	exporters[name] = fn
+}
+
+var (
+	mu      sync.RWMutex
+	pending chan SpanData
+	stopped chan struct{}
+)
+
+// Init starts recording and exporting spans with the exporter configured by
+// tracing_exporter, or the OTEL_TRACES_EXPORTER environment variable. Tracing
+// stays disabled if neither is set.
+func Init() error {
+	name, _ := db.ConfigCache("tracing_exporter").(string)
+	if name == "" {
+		name = os.Getenv("OTEL_TRACES_EXPORTER")
+	}
+	if name == "" || name == "none" {
+		return nil
+	}
+
+	fn, ok := exporters[name]
+	if !ok {
+		return fmt.Errorf("unknown tracing exporter: %s", name)
+	}
+
+	exp, err := fn()
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	pending = make(chan SpanData, queueSize)
+	stopped = make(chan struct{})
+	mu.Unlock()
+
+	go export(exp, pending, stopped)
+	atomic.StoreInt32(&enabled, 1)
+
+	return nil
+}
+
+// Close stops recording spans and exports those still queued. Should be
+// called with defer after Init from the same place.
+func Close() {
+	atomic.StoreInt32(&enabled, 0)
+
+	mu.Lock()
+	if pending == nil {
+		mu.Unlock()
+		return
+	}
+	close(pending)
+	pending = nil
+	done := stopped
+	mu.Unlock()
+
+	<-done
+}
+
+// queue puts s to be exported, dropping it if the queue is full
+func queue(s SpanData) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if pending == nil {
+		return
+	}
+
+	select {
+	case pending <- s:
+	default:
+	}
+}
+
+// export sends spans from queue to exp in batches until queue is closed, then
+// closes done
+func export(exp Exporter, queue chan SpanData, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		err := exp.Export(batch)
+		if err != nil {
+			logger.Warn("Error exporting", len(batch), "trace spans:", err)
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case s, ok := <-queue:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// otlp exports spans to an OpenTelemetry collector as OTLP/HTTP JSON
+type otlp struct {
+	endpoint string
+	service  string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// newOTLP makes the otlp exporter, which sends spans to tracing_endpoint, or
+// the endpoint in the standard OTEL_EXPORTER_OTLP_* environment variables,
+// with the service name tracing_service_name or OTEL_SERVICE_NAME
+func newOTLP() (Exporter, error) {
+	endpoint, _ := db.ConfigCache("tracing_endpoint").(string)
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	}
+	if endpoint == "" {
+		if base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); base != "" {
+			endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+		}
+	}
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+
+	service, _ := db.ConfigCache("tracing_service_name").(string)
+	if service == "" {
+		service = os.Getenv("OTEL_SERVICE_NAME")
+	}
+	if service == "" {
+		service = "ponzu"
+	}
+
+	// headers such as for authenticating with a hosted collector, as
+	// key=value pairs separated by commas
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) != "" {
+			headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	return &otlp{
+		endpoint: endpoint,
+		service:  service,
+		headers:  headers,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// otlpAttribute is a key and string value in OTLP JSON
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+func otlpAttributes(attrs map[string]string) []otlpAttribute {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]otlpAttribute, 0, len(keys))
+	for _, k := range keys {
+		a := otlpAttribute{Key: k}
+		a.Value.StringValue = attrs[k]
+		out = append(out, a)
+	}
+
+	return out
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       otlpStatus      `json:"status"`
+}
+
+// Export sends spans to the collector in an ExportTraceServiceRequest
+func (o *otlp) Export(spans []SpanData) error {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:    hex.EncodeToString(s.TraceID[:]),
+			SpanID:     hex.EncodeToString(s.SpanID[:]),
+			Name:       s.Name,
+			Kind:       s.Kind,
+			Start:      strconv.FormatInt(s.Start.UnixNano(), 10),
+			End:        strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes: otlpAttributes(s.Attributes),
+		}
+		if s.ParentID != ([8]byte{}) {
+			span.ParentSpanID = hex.EncodeToString(s.ParentID[:])
+		}
+		if s.Error != "" {
+			// STATUS_CODE_ERROR
+			span.Status = otlpStatus{Code: 2, Message: s.Error}
+		}
+
+		out = append(out, span)
+	}
+
+	body := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]string{"service.name": o.service}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": "github.com/ponzu-cms/ponzu"},
+						"spans": out,
+					},
+				},
+			},
+		},
+	}
+
+	j, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, o.endpoint, bytes.NewReader(j))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range o.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("collector responded with %s", res.Status)
+	}
+
+	return nil
+}
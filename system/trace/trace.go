@@ -0,0 +1,276 @@
+// Package trace records spans of the work done to handle requests, such as
+// reading content from the db or querying the search index, and exports them
+// to an OpenTelemetry collector. Tracing is off unless an exporter is
+// configured, in which case Start and Handler do nothing.
+package trace
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/requestid"
+)
+
+// Kinds of span, matching those of OpenTelemetry
+const (
+	KindInternal = 1
+	KindServer   = 2
+)
+
+// enabled is 1 while spans are recorded
+var enabled int32
+
+// Enabled reports whether spans are recorded
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// SpanData is a finished span, as given to an Exporter
+type SpanData struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	ParentID   [8]byte
+	Name       string
+	Kind       int
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+	Error      string
+}
+
+// Span is an operation being traced. A nil *Span is returned while tracing is
+// disabled, and its methods do nothing, so callers needn't check.
+type Span struct {
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SetAttribute records the attribute key with value on s
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]string)
+	}
+	s.data.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+// SetError marks s as failed with err, if err is not nil
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.data.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes s and queues it to be exported. Calling End again does nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	queue(data)
+}
+
+// spanKey stores the current span in a context
+type spanKey struct{}
+
+// remoteKey stores the span context of an incoming request which isn't traced
+// in this process, such as from a traceparent header
+type remoteKey struct{}
+
+// remote identifies a span in another process
+type remote struct {
+	traceID [16]byte
+	spanID  [8]byte
+}
+
+// Start begins a span named name as a child of the span in ctx, and returns a
+// context holding the new span along with it. End must be called on the span
+// once the operation is done. While tracing is disabled, or if ctx holds no
+// span, such as for a request which isn't sampled, it returns ctx and a nil
+// *Span.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	if _, ok := ctx.Value(spanKey{}).(*Span); !ok {
+		return ctx, nil
+	}
+
+	return start(ctx, name, KindInternal)
+}
+
+func start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	s := &Span{data: SpanData{Name: name, Kind: kind, Start: time.Now()}}
+
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		s.data.TraceID = parent.data.TraceID
+		s.data.ParentID = parent.data.SpanID
+	} else if r, ok := ctx.Value(remoteKey{}).(remote); ok {
+		s.data.TraceID = r.traceID
+		s.data.ParentID = r.spanID
+	} else {
+		rand.Read(s.data.TraceID[:])
+	}
+	rand.Read(s.data.SpanID[:])
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Handler records a span for each request handled by next, continuing the
+// trace of a W3C traceparent header on the request, so that Ponzu's spans
+// join those of the service which called it. Requests the caller chose not to
+// sample aren't traced. While tracing is disabled next is called directly.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if !Enabled() {
+			next.ServeHTTP(res, req)
+			return
+		}
+
+		ctx := req.Context()
+		if header := req.Header.Get("traceparent"); header != "" {
+			r, sampled, ok := parseTraceparent(header)
+			if ok && !sampled {
+				next.ServeHTTP(res, req)
+				return
+			}
+			if ok {
+				ctx = context.WithValue(ctx, remoteKey{}, r)
+			}
+		}
+
+		ctx, span := start(ctx, req.Method+" "+req.URL.Path, KindServer)
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.target", req.URL.RequestURI())
+		span.SetAttribute("http.host", req.Host)
+		if id := requestid.Get(req); id != "" {
+			span.SetAttribute("ponzu.request_id", id)
+		}
+
+		sw := &statusWriter{ResponseWriter: res, status: http.StatusOK}
+		defer func() {
+			span.SetAttribute("http.status_code", strconv.Itoa(sw.status))
+			if sw.status >= http.StatusInternalServerError {
+				span.SetError(errors.New(http.StatusText(sw.status)))
+			}
+			span.End()
+		}()
+
+		next.ServeHTTP(sw, req.WithContext(ctx))
+	})
+}
+
+// parseTraceparent reads a W3C traceparent header, version-traceid-spanid-flags
+func parseTraceparent(header string) (remote, bool, bool) {
+	var r remote
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return r, false, false
+	}
+
+	tid, err := hex.DecodeString(parts[1])
+	if err != nil || len(tid) != 16 {
+		return r, false, false
+	}
+	sid, err := hex.DecodeString(parts[2])
+	if err != nil || len(sid) != 8 {
+		return r, false, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return r, false, false
+	}
+
+	copy(r.traceID[:], tid)
+	copy(r.spanID[:], sid)
+	if r.traceID == ([16]byte{}) || r.spanID == ([8]byte{}) {
+		return r, false, false
+	}
+
+	return r, flags[0]&1 == 1, true
+}
+
+// Inject sets the traceparent header of an outgoing request in h from the
+// span in ctx, so that the service called continues the trace
+func Inject(ctx context.Context, h http.Header) {
+	s, ok := ctx.Value(spanKey{}).(*Span)
+	if !ok {
+		return
+	}
+
+	h.Set("traceparent", "00-"+hex.EncodeToString(s.data.TraceID[:])+"-"+
+		hex.EncodeToString(s.data.SpanID[:])+"-01")
+}
+
+// statusWriter records the status of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("trace: response does not support hijacking")
+	}
+
+	w.status, w.wrote = http.StatusSwitchingProtocols, true
+	return hj.Hijack()
+}
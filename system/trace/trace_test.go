@@ -0,0 +1,61 @@
+package trace
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	atomic.StoreInt32(&enabled, 1)
+	defer atomic.StoreInt32(&enabled, 0)
+
+	pending = make(chan SpanData, 10)
+	defer func() { pending = nil }()
+
+	h := Handler(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		_, span := Start(req.Context(), "db.Content")
+		span.End()
+
+		res.WriteHeader(http.StatusNotFound)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/content?type=Post&id=1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(pending))
+	}
+
+	child, root := <-pending, <-pending
+	if root.Kind != KindServer || root.Attributes["http.status_code"] != "404" {
+		t.Errorf("unexpected root span %+v", root)
+	}
+	if root.TraceID != child.TraceID || child.ParentID != root.SpanID {
+		t.Error("expected db.Content to be a child of the request span")
+	}
+	if remote, _, _ := parseTraceparent(req.Header.Get("traceparent")); root.TraceID != remote.traceID || root.ParentID != remote.spanID {
+		t.Error("expected the request span to continue the incoming trace")
+	}
+
+	// requests the caller didn't sample aren't traced
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if len(pending) != 0 {
+		t.Errorf("expected no spans for an unsampled request, got %d", len(pending))
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/contents?type=Post", nil)
+	ctx, span := Start(req.Context(), "db.Query")
+	if span != nil || ctx != req.Context() {
+		t.Error("expected no span while tracing is disabled")
+	}
+
+	// a nil span's methods do nothing
+	span.SetAttribute("ponzu.type", "Post")
+	span.End()
+}
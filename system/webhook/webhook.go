@@ -0,0 +1,121 @@
+// Package webhook delivers signed notifications of content changes to URLs
+// registered by admins, retrying failed deliveries with backoff.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ponzu-cms/ponzu/system/logger"
+)
+
+// Events which a webhook can be registered for
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+	EventDelete = "delete"
+)
+
+// Events lists every event a webhook can be registered for
+var Events = []string{EventCreate, EventUpdate, EventDelete}
+
+// SignatureHeader is the request header holding the hex encoded HMAC-SHA256 of
+// the request body, keyed by the webhook's secret, prefixed with "sha256="
+const SignatureHeader = "X-Ponzu-Signature"
+
+var (
+	// MaxAttempts is the number of times a delivery is tried before it is
+	// abandoned
+	MaxAttempts = 5
+
+	// Backoff is the delay before the first retry of a failed delivery, which
+	// doubles before each further retry
+	Backoff = time.Second
+
+	client = &http.Client{Timeout: 10 * time.Second}
+)
+
+// Payload is the json body POSTed to a webhook's URL
+type Payload struct {
+	Event     string          `json:"event"`
+	Type      string          `json:"type"`
+	ID        string          `json:"id"`
+	Timestamp int64           `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Sign returns the value of SignatureHeader for body signed with secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send delivers p to url in its own goroutine, so it never blocks the caller.
+// If secret is not empty the body is signed with it. Deliveries which fail, or
+// get a response status other than 2xx, are logged and retried up to
+// MaxAttempts times.
+func Send(url, secret string, p Payload) {
+	if p.Timestamp == 0 {
+		p.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		logger.Error("Error encoding webhook payload:", err)
+		return
+	}
+
+	go deliver(url, secret, body)
+}
+
+// deliver POSTs body to url, retrying with exponential backoff
+func deliver(url, secret string, body []byte) {
+	wait := Backoff
+	for attempt := 1; attempt <= MaxAttempts; attempt++ {
+		err := post(url, secret, body)
+		if err == nil {
+			return
+		}
+
+		logger.Warn(fmt.Sprintf("Webhook delivery to %s failed (attempt %d of %d): %v", url, attempt, MaxAttempts, err))
+
+		if attempt < MaxAttempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+}
+
+// post makes a single delivery of body to url
+func post(url, secret string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Ponzu-Webhook")
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("response status %d", res.StatusCode)
+	}
+
+	return nil
+}
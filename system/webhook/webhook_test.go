@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendRetriesAndSigns(t *testing.T) {
+	Backoff = time.Millisecond
+	defer func() { Backoff = time.Second }()
+
+	received := make(chan Payload, 1)
+	attempts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		attempts++
+		if attempts < 3 {
+			res.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(req.Body)
+		if req.Header.Get(SignatureHeader) != Sign("secret", body) {
+			t.Errorf("bad signature: %s", req.Header.Get(SignatureHeader))
+		}
+
+		var p Payload
+		json.Unmarshal(body, &p)
+		received <- p
+	}))
+	defer srv.Close()
+
+	Send(srv.URL, "secret", Payload{
+		Event: EventUpdate,
+		Type:  "Post",
+		ID:    "1",
+		Data:  json.RawMessage(`{"title":"hi"}`),
+	})
+
+	select {
+	case p := <-received:
+		if p.Event != EventUpdate || p.Type != "Post" || p.ID != "1" || p.Timestamp == 0 {
+			t.Errorf("unexpected payload: %+v", p)
+		}
+
+		if attempts != 3 {
+			t.Errorf("expected 3 attempts, got %d", attempts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}